@@ -8,6 +8,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/spf13/viper"
 )
 
@@ -18,8 +19,11 @@ func main() {
 
 	app.Use(cors.New())
 
+	// Every request gets an X-Request-ID, or keeps the one it came with, audit log entries carry it
+	app.Use(requestid.New())
+
 	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${method} ${path} [${status}] ${latency}\n",
+		Format: "[${time}] ${locals:requestid} ${method} ${path} [${status}] ${latency}\n",
 	}))
 
 	db, err := databases.PostgresConnection()
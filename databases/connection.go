@@ -2,7 +2,9 @@ package databases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"shuttle/logger"
 	"sync"
 	"time"
@@ -21,8 +23,9 @@ var once sync.Once
 
 func init() {
 	viper.SetConfigFile(".env")
+	// Without a .env (e.g. in package tests) the settings stay empty and a connection attempt fails instead
 	err := viper.ReadInConfig()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		panic(err)
 	}
 }
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_attempts (
+    attempt_scope VARCHAR(20) NOT NULL,
+    attempt_key VARCHAR(255) NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (attempt_scope, attempt_key)
+);
+
+CREATE TABLE account_lockouts (
+    lockout_id BIGINT PRIMARY KEY,
+    lockout_uuid UUID UNIQUE NOT NULL,
+    user_uuid UUID NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    lockout_scope VARCHAR(20) NOT NULL,
+    lockout_key VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64),
+    failed_count INT NOT NULL,
+    locked_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMPTZ NOT NULL,
+    unlocked_at TIMESTAMPTZ,
+    unlocked_by VARCHAR(255)
+);
+
+CREATE INDEX idx_account_lockouts_user_uuid ON account_lockouts(user_uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS account_lockouts;
+DROP TABLE IF EXISTS login_attempts;
+-- +goose StatementEnd
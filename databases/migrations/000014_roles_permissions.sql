@@ -0,0 +1,91 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE roles (
+    role_code VARCHAR(5) PRIMARY KEY,
+    role_name VARCHAR(50) UNIQUE NOT NULL,
+    role_base VARCHAR(20) NOT NULL,
+    role_description TEXT,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255)
+);
+
+CREATE TABLE permissions (
+    permission_code VARCHAR(100) PRIMARY KEY,
+    permission_description TEXT
+);
+
+CREATE TABLE role_permissions (
+    role_code VARCHAR(5) NOT NULL REFERENCES roles(role_code) ON DELETE CASCADE,
+    permission_code VARCHAR(100) NOT NULL REFERENCES permissions(permission_code) ON DELETE CASCADE,
+    PRIMARY KEY (role_code, permission_code)
+);
+
+INSERT INTO roles (role_code, role_name, role_base, role_description, is_system) VALUES
+    ('SA', 'Super Admin', 'superadmin', 'Platform administrator', TRUE),
+    ('AS', 'School Admin', 'schooladmin', 'Administrator of a single school', TRUE),
+    ('P', 'Parent', 'parent', 'Parent or guardian of a student', TRUE),
+    ('D', 'Driver', 'driver', 'Shuttle driver', TRUE);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('area:superadmin', 'Access the /api/superadmin endpoints'),
+    ('area:school', 'Access the /api/school endpoints'),
+    ('area:parent', 'Access the /api/parent endpoints'),
+    ('area:driver', 'Access the /api/driver endpoints'),
+    ('user:read', 'View users'),
+    ('user:write', 'Create, update and delete users'),
+    ('user:unlock', 'Unlock accounts locked after failed logins'),
+    ('role:manage', 'Manage roles and their permissions'),
+    ('school:read', 'View schools'),
+    ('school:write', 'Create, update and delete schools'),
+    ('vehicle:read', 'View vehicles'),
+    ('vehicle:write', 'Create, update and delete vehicles'),
+    ('driver:read', 'View drivers'),
+    ('student:read', 'View students'),
+    ('student:write', 'Create, update and delete students'),
+    ('route:read', 'View routes'),
+    ('route:write', 'Create and update routes'),
+    ('children:read', 'View own children'),
+    ('children:write', 'Update own children'),
+    ('shuttle:read', 'View shuttle status'),
+    ('shuttle:write', 'Create and update shuttle status');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('SA', 'area:superadmin'),
+    ('SA', 'user:read'),
+    ('SA', 'user:write'),
+    ('SA', 'user:unlock'),
+    ('SA', 'role:manage'),
+    ('SA', 'school:read'),
+    ('SA', 'school:write'),
+    ('SA', 'vehicle:read'),
+    ('SA', 'vehicle:write'),
+    ('SA', 'driver:read'),
+    ('AS', 'area:school'),
+    ('AS', 'driver:read'),
+    ('AS', 'student:read'),
+    ('AS', 'student:write'),
+    ('AS', 'route:read'),
+    ('AS', 'route:write'),
+    ('P', 'area:parent'),
+    ('P', 'children:read'),
+    ('P', 'children:write'),
+    ('P', 'shuttle:read'),
+    ('D', 'area:driver'),
+    ('D', 'shuttle:write');
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_user_role_code FOREIGN KEY (user_role_code) REFERENCES roles(role_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP CONSTRAINT fk_user_role_code;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
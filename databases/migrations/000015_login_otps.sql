@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_otps (
+    otp_id BIGINT PRIMARY KEY,
+    otp_uuid UUID UNIQUE NOT NULL,
+    user_uuid UUID REFERENCES users(user_uuid) ON DELETE CASCADE,
+    otp_phone VARCHAR(50) NOT NULL,
+    otp_code_hash VARCHAR(255),
+    ip_address VARCHAR(45) NOT NULL,
+    verify_attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_otps_phone ON login_otps(otp_phone, created_at);
+CREATE INDEX idx_login_otps_ip ON login_otps(ip_address, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_otps;
+-- +goose StatementEnd
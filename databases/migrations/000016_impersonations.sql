@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE impersonations (
+    impersonation_id BIGINT PRIMARY KEY,
+    impersonation_uuid UUID UNIQUE NOT NULL,
+    admin_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    target_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    impersonation_reason TEXT,
+    ip_address VARCHAR(45),
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ
+);
+
+CREATE TABLE impersonation_requests (
+    request_id BIGINT PRIMARY KEY,
+    impersonation_uuid UUID NOT NULL REFERENCES impersonations(impersonation_uuid) ON DELETE CASCADE,
+    request_method VARCHAR(10) NOT NULL,
+    request_path TEXT NOT NULL,
+    response_status INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impersonations_admin ON impersonations(admin_uuid);
+CREATE INDEX idx_impersonations_target ON impersonations(target_uuid);
+CREATE INDEX idx_impersonation_requests_session ON impersonation_requests(impersonation_uuid, created_at);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('user:impersonate', 'Sign in as another user for support');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('SA', 'user:impersonate');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code = 'user:impersonate';
+
+DROP TABLE IF EXISTS impersonation_requests;
+DROP TABLE IF EXISTS impersonations;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_student_uuid ON students(student_uuid);
+
+CREATE TABLE student_guardians (
+    student_guardian_id BIGINT PRIMARY KEY,
+    student_uuid UUID NOT NULL REFERENCES students(student_uuid) ON DELETE CASCADE,
+    guardian_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    guardian_relationship VARCHAR(20) NOT NULL DEFAULT 'guardian',
+    can_pickup BOOLEAN NOT NULL DEFAULT TRUE,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    UNIQUE (student_uuid, guardian_uuid)
+);
+
+CREATE INDEX idx_student_guardians_guardian ON student_guardians(guardian_uuid);
+CREATE UNIQUE INDEX idx_student_guardians_primary ON student_guardians(student_uuid) WHERE is_primary;
+
+INSERT INTO student_guardians (student_guardian_id, student_uuid, guardian_uuid, guardian_relationship, can_pickup, is_primary, created_at, created_by)
+SELECT student_id, student_uuid, parent_uuid, 'guardian', TRUE, TRUE, created_at, created_by
+FROM students
+WHERE parent_uuid IS NOT NULL;
+
+-- Orphaned parents are now soft deleted by the application when their last student goes
+DROP TRIGGER IF EXISTS check_and_delete_parent_with_no_associated_student ON students;
+DROP FUNCTION IF EXISTS delete_parent_with_no_associated_student;
+
+ALTER TABLE students DROP COLUMN parent_uuid;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE students ADD COLUMN parent_uuid UUID REFERENCES users(user_uuid) ON DELETE SET NULL;
+
+UPDATE students s
+SET parent_uuid = sg.guardian_uuid
+FROM student_guardians sg
+WHERE sg.student_uuid = s.student_uuid AND sg.is_primary;
+
+CREATE OR REPLACE FUNCTION delete_parent_with_no_associated_student()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM students WHERE parent_uuid = OLD.parent_uuid
+    ) THEN
+        DELETE FROM users WHERE user_uuid = OLD.parent_uuid;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_and_delete_parent_with_no_associated_student
+AFTER DELETE ON students
+FOR EACH ROW
+EXECUTE FUNCTION delete_parent_with_no_associated_student();
+
+DROP TABLE IF EXISTS student_guardians;
+-- +goose StatementEnd
@@ -0,0 +1,108 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE academic_years (
+    academic_year_id BIGINT PRIMARY KEY,
+    academic_year_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    academic_year_name VARCHAR(50) NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    is_current BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK (ends_on > starts_on)
+);
+
+CREATE INDEX idx_academic_years_school ON academic_years(school_uuid);
+CREATE UNIQUE INDEX idx_academic_years_current ON academic_years(school_uuid) WHERE is_current AND deleted_at IS NULL;
+
+CREATE TABLE academic_terms (
+    academic_term_id BIGINT PRIMARY KEY,
+    academic_term_uuid UUID UNIQUE NOT NULL,
+    academic_year_uuid UUID NOT NULL REFERENCES academic_years(academic_year_uuid) ON DELETE CASCADE,
+    academic_term_name VARCHAR(50) NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    CHECK (ends_on > starts_on)
+);
+
+CREATE INDEX idx_academic_terms_year ON academic_terms(academic_year_uuid);
+
+-- The level with the highest order is the final grade, its students graduate on promotion
+CREATE TABLE grade_levels (
+    grade_level_id BIGINT PRIMARY KEY,
+    grade_level_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    grade_name VARCHAR(10) NOT NULL,
+    grade_order INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE UNIQUE INDEX idx_grade_levels_name ON grade_levels(school_uuid, LOWER(grade_name)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_grade_levels_order ON grade_levels(school_uuid, grade_order) WHERE deleted_at IS NULL;
+
+ALTER TABLE students ADD COLUMN grade_level_uuid UUID REFERENCES grade_levels(grade_level_uuid) ON DELETE SET NULL;
+ALTER TABLE students ADD COLUMN graduated_at TIMESTAMPTZ;
+
+CREATE TABLE grade_promotions (
+    grade_promotion_id BIGINT PRIMARY KEY,
+    grade_promotion_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    from_academic_year_uuid UUID REFERENCES academic_years(academic_year_uuid) ON DELETE SET NULL,
+    to_academic_year_uuid UUID NOT NULL REFERENCES academic_years(academic_year_uuid) ON DELETE CASCADE,
+    promotion_status VARCHAR(20) NOT NULL DEFAULT 'applied',
+    promoted_count INT NOT NULL DEFAULT 0,
+    graduated_count INT NOT NULL DEFAULT 0,
+    applied_at TIMESTAMPTZ NOT NULL,
+    applied_by VARCHAR(255),
+    rollback_until TIMESTAMPTZ NOT NULL,
+    rolled_back_at TIMESTAMPTZ,
+    rolled_back_by VARCHAR(255)
+);
+
+CREATE INDEX idx_grade_promotions_school ON grade_promotions(school_uuid);
+
+-- What every student looked like before the promotion, used to roll it back
+CREATE TABLE grade_promotion_items (
+    grade_promotion_uuid UUID NOT NULL REFERENCES grade_promotions(grade_promotion_uuid) ON DELETE CASCADE,
+    student_uuid UUID NOT NULL REFERENCES students(student_uuid) ON DELETE CASCADE,
+    from_grade VARCHAR(10) NOT NULL,
+    from_grade_level_uuid UUID,
+    to_grade VARCHAR(10),
+    to_grade_level_uuid UUID,
+    graduated BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (grade_promotion_uuid, student_uuid)
+);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('academic:read', 'View academic years, grade levels and promotions'),
+    ('academic:write', 'Manage academic years and grade levels, run and roll back promotions');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'academic:read'),
+    ('AS', 'academic:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('academic:read', 'academic:write');
+
+DROP TABLE IF EXISTS grade_promotion_items;
+DROP TABLE IF EXISTS grade_promotions;
+
+ALTER TABLE students DROP COLUMN IF EXISTS graduated_at;
+ALTER TABLE students DROP COLUMN IF EXISTS grade_level_uuid;
+
+DROP TABLE IF EXISTS grade_levels;
+DROP TABLE IF EXISTS academic_terms;
+DROP TABLE IF EXISTS academic_years;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('driver:write', 'Create, update and deactivate the drivers of own school');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'driver:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code = 'driver:write';
+-- +goose StatementEnd
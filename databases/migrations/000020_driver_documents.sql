@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE driver_documents (
+    document_id BIGINT PRIMARY KEY,
+    document_uuid UUID UNIQUE NOT NULL,
+    driver_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    document_type VARCHAR(30) NOT NULL CHECK (document_type IN ('license', 'background_check', 'health_certificate')),
+    document_number VARCHAR(100) NOT NULL,
+    issued_on DATE NOT NULL,
+    expires_on DATE,
+    document_file VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK (expires_on IS NULL OR expires_on > issued_on)
+);
+
+CREATE INDEX idx_driver_documents_driver ON driver_documents(driver_uuid);
+CREATE INDEX idx_driver_documents_expiry ON driver_documents(expires_on) WHERE deleted_at IS NULL;
+
+-- One row per warning sent, so a restart or a second instance never warns twice for the same expiry date
+CREATE TABLE driver_document_alerts (
+    document_uuid UUID NOT NULL REFERENCES driver_documents(document_uuid) ON DELETE CASCADE,
+    days_before INT NOT NULL,
+    expires_on DATE NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (document_uuid, days_before, expires_on)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS driver_document_alerts;
+DROP TABLE IF EXISTS driver_documents;
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE vehicle_maintenances (
+    maintenance_id BIGINT PRIMARY KEY,
+    maintenance_uuid UUID UNIQUE NOT NULL,
+    vehicle_uuid UUID NOT NULL REFERENCES vehicles(vehicle_uuid) ON DELETE CASCADE,
+    service_date DATE NOT NULL,
+    odometer_km INT NOT NULL CHECK (odometer_km >= 0),
+    cost NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (cost >= 0),
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE INDEX idx_vehicle_maintenances_vehicle ON vehicle_maintenances(vehicle_uuid, service_date DESC);
+
+-- Inspection, registration and insurance are all mandatory, a vehicle whose documents of one
+-- of these types have all expired is taken out of service
+CREATE TABLE vehicle_documents (
+    document_id BIGINT PRIMARY KEY,
+    document_uuid UUID UNIQUE NOT NULL,
+    vehicle_uuid UUID NOT NULL REFERENCES vehicles(vehicle_uuid) ON DELETE CASCADE,
+    document_type VARCHAR(30) NOT NULL CHECK (document_type IN ('inspection', 'registration', 'insurance')),
+    document_number VARCHAR(100) NOT NULL,
+    issued_on DATE NOT NULL,
+    expires_on DATE NOT NULL,
+    document_file VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK (expires_on > issued_on)
+);
+
+CREATE INDEX idx_vehicle_documents_vehicle ON vehicle_documents(vehicle_uuid);
+
+-- Set while the vehicle is out of service because of a lapsed document, holds the status to return to
+ALTER TABLE vehicles ADD COLUMN vehicle_status_before_lapse VARCHAR(20);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE vehicles SET vehicle_status = vehicle_status_before_lapse WHERE vehicle_status_before_lapse IS NOT NULL;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS vehicle_status_before_lapse;
+
+DROP TABLE IF EXISTS vehicle_documents;
+DROP TABLE IF EXISTS vehicle_maintenances;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- History of which driver had which vehicle. vehicles.driver_uuid and driver_details.vehicle_uuid
+-- stay as a copy of the open assignments so existing queries keep working.
+CREATE TABLE vehicle_assignments (
+    assignment_id BIGINT PRIMARY KEY,
+    assignment_uuid UUID UNIQUE NOT NULL,
+    driver_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    vehicle_uuid UUID NOT NULL REFERENCES vehicles(vehicle_uuid) ON DELETE CASCADE,
+    assigned_from TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    assigned_to TIMESTAMPTZ,
+    assigned_by VARCHAR(255),
+    unassigned_by VARCHAR(255),
+    CHECK (assigned_to IS NULL OR assigned_to >= assigned_from)
+);
+
+-- One open assignment per driver and per vehicle
+CREATE UNIQUE INDEX idx_vehicle_assignments_active_driver ON vehicle_assignments(driver_uuid) WHERE assigned_to IS NULL;
+CREATE UNIQUE INDEX idx_vehicle_assignments_active_vehicle ON vehicle_assignments(vehicle_uuid) WHERE assigned_to IS NULL;
+CREATE INDEX idx_vehicle_assignments_vehicle ON vehicle_assignments(vehicle_uuid, assigned_from);
+CREATE INDEX idx_vehicle_assignments_driver ON vehicle_assignments(driver_uuid, assigned_from);
+
+-- Only links both columns agree on are carried over, their real start is unknown
+INSERT INTO vehicle_assignments (assignment_id, assignment_uuid, driver_uuid, vehicle_uuid, assigned_from, assigned_by)
+SELECT v.vehicle_id, gen_random_uuid(), d.user_uuid, v.vehicle_uuid, COALESCE(v.updated_at, v.created_at, CURRENT_TIMESTAMP), 'system'
+FROM driver_details d
+JOIN users u ON u.user_uuid = d.user_uuid AND u.deleted_at IS NULL
+JOIN vehicles v ON v.vehicle_uuid = d.vehicle_uuid AND v.driver_uuid = d.user_uuid AND v.deleted_at IS NULL;
+
+UPDATE driver_details d SET vehicle_uuid = NULL
+WHERE d.vehicle_uuid IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM vehicle_assignments a WHERE a.driver_uuid = d.user_uuid AND a.assigned_to IS NULL);
+
+UPDATE vehicles v SET driver_uuid = NULL
+WHERE v.driver_uuid IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM vehicle_assignments a WHERE a.vehicle_uuid = v.vehicle_uuid AND a.assigned_to IS NULL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS vehicle_assignments;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Routes used to live in MongoDB keyed by an ObjectID school, they now belong to a school like everything else
+CREATE TABLE routes (
+    route_id BIGINT PRIMARY KEY,
+    route_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    route_name VARCHAR(100) NOT NULL,
+    route_description TEXT,
+    route_points JSONB NOT NULL DEFAULT '[]',
+    route_status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (route_status IN ('active', 'inactive')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE UNIQUE INDEX idx_routes_school_name ON routes(school_uuid, route_name) WHERE deleted_at IS NULL;
+
+ALTER TABLE vehicles ADD COLUMN route_uuid UUID REFERENCES routes(route_uuid) ON DELETE SET NULL;
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('vehicle:status', 'Update the operational status and route of the vehicles of own school');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'vehicle:read'),
+    ('AS', 'vehicle:status');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM role_permissions WHERE role_code = 'AS' AND permission_code = 'vehicle:read';
+DELETE FROM permissions WHERE permission_code = 'vehicle:status';
+
+ALTER TABLE vehicles DROP COLUMN IF EXISTS route_uuid;
+DROP TABLE IF EXISTS routes;
+-- +goose StatementEnd
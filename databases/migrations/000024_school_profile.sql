@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Arrivals within the geofence radius (in meters) of the school count as at school
+ALTER TABLE schools ADD COLUMN school_latitude DOUBLE PRECISION CHECK (school_latitude BETWEEN -90 AND 90);
+ALTER TABLE schools ADD COLUMN school_longitude DOUBLE PRECISION CHECK (school_longitude BETWEEN -180 AND 180);
+ALTER TABLE schools ADD COLUMN school_geofence_radius INT NOT NULL DEFAULT 100 CHECK (school_geofence_radius > 0);
+ALTER TABLE schools ADD COLUMN school_timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta';
+
+-- Weekdays follow ISO 8601, 1 is Monday and 7 is Sunday. A school with hours on some days is
+-- closed on the others, a school without any hours is open every day.
+CREATE TABLE school_operating_hours (
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+    starts_at TIME NOT NULL,
+    ends_at TIME NOT NULL,
+    PRIMARY KEY (school_uuid, weekday),
+    CHECK (ends_at > starts_at)
+);
+
+-- Holidays and other days off, both ends included
+CREATE TABLE school_closures (
+    closure_id BIGINT PRIMARY KEY,
+    closure_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    closure_name VARCHAR(100) NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX idx_school_closures_school ON school_closures(school_uuid, starts_on);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('school:profile', 'View and update the location, hours and calendar of own school');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'school:profile');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code = 'school:profile';
+
+DROP TABLE IF EXISTS school_closures;
+DROP TABLE IF EXISTS school_operating_hours;
+
+ALTER TABLE schools DROP COLUMN IF EXISTS school_timezone;
+ALTER TABLE schools DROP COLUMN IF EXISTS school_geofence_radius;
+ALTER TABLE schools DROP COLUMN IF EXISTS school_longitude;
+ALTER TABLE schools DROP COLUMN IF EXISTS school_latitude;
+-- +goose StatementEnd
@@ -0,0 +1,98 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A recurring trip of a school. Weekdays follow ISO 8601 like the operating hours, the
+-- departure time is in the school's timezone and effective_to is open ended when null.
+CREATE TABLE trip_schedules (
+    schedule_id BIGINT PRIMARY KEY,
+    schedule_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    route_uuid UUID NOT NULL REFERENCES routes(route_uuid) ON DELETE CASCADE,
+    vehicle_uuid UUID NOT NULL REFERENCES vehicles(vehicle_uuid) ON DELETE CASCADE,
+    driver_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    direction VARCHAR(20) NOT NULL CHECK (direction IN ('to_school', 'to_home')),
+    departure_time TIME NOT NULL,
+    weekdays SMALLINT[] NOT NULL CHECK (CARDINALITY(weekdays) > 0 AND weekdays <@ ARRAY[1, 2, 3, 4, 5, 6, 7]::SMALLINT[]),
+    effective_from DATE NOT NULL,
+    effective_to DATE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK (effective_to IS NULL OR effective_to >= effective_from)
+);
+
+CREATE INDEX idx_trip_schedules_school ON trip_schedules(school_uuid);
+
+CREATE TABLE trip_schedule_students (
+    schedule_uuid UUID NOT NULL REFERENCES trip_schedules(schedule_uuid) ON DELETE CASCADE,
+    student_uuid UUID NOT NULL REFERENCES students(student_uuid) ON DELETE CASCADE,
+    PRIMARY KEY (schedule_uuid, student_uuid)
+);
+
+-- One trip per schedule and day, generating a day twice finds the trips already there
+CREATE TABLE trips (
+    trip_id BIGINT PRIMARY KEY,
+    trip_uuid UUID UNIQUE NOT NULL,
+    schedule_uuid UUID NOT NULL REFERENCES trip_schedules(schedule_uuid) ON DELETE CASCADE,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    route_uuid UUID REFERENCES routes(route_uuid) ON DELETE SET NULL,
+    vehicle_uuid UUID REFERENCES vehicles(vehicle_uuid) ON DELETE SET NULL,
+    driver_uuid UUID REFERENCES users(user_uuid) ON DELETE SET NULL,
+    direction VARCHAR(20) NOT NULL,
+    trip_date DATE NOT NULL,
+    departs_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    UNIQUE (schedule_uuid, trip_date)
+);
+
+CREATE INDEX idx_trips_school_date ON trips(school_uuid, trip_date);
+CREATE INDEX idx_trips_driver_date ON trips(driver_uuid, trip_date);
+
+-- Shuttles generated for a trip point to it, those created by drivers do not
+ALTER TABLE shuttle ADD COLUMN trip_uuid UUID REFERENCES trips(trip_uuid) ON DELETE CASCADE;
+ALTER TABLE shuttle ALTER COLUMN student_destination_name DROP NOT NULL;
+CREATE UNIQUE INDEX idx_shuttle_trip_student ON shuttle(trip_uuid, student_uuid) WHERE trip_uuid IS NOT NULL;
+
+-- Days a parent reports their child will not ride
+CREATE TABLE student_absences (
+    absence_id BIGINT PRIMARY KEY,
+    absence_uuid UUID UNIQUE NOT NULL,
+    student_uuid UUID NOT NULL REFERENCES students(student_uuid) ON DELETE CASCADE,
+    absent_on DATE NOT NULL,
+    absence_reason VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE UNIQUE INDEX idx_student_absences_day ON student_absences(student_uuid, absent_on) WHERE deleted_at IS NULL;
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('schedule:read', 'View the trip schedules and trips of own school'),
+    ('schedule:write', 'Create, update and delete the trip schedules of own school and generate its trips'),
+    ('absence:write', 'Report the days own children will not ride');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'schedule:read'),
+    ('AS', 'schedule:write'),
+    ('D', 'schedule:read'),
+    ('P', 'absence:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('schedule:read', 'schedule:write', 'absence:write');
+
+DROP TABLE IF EXISTS student_absences;
+
+DROP INDEX IF EXISTS idx_shuttle_trip_student;
+ALTER TABLE shuttle DROP COLUMN IF EXISTS trip_uuid;
+
+DROP TABLE IF EXISTS trips;
+DROP TABLE IF EXISTS trip_schedule_students;
+DROP TABLE IF EXISTS trip_schedules;
+-- +goose StatementEnd
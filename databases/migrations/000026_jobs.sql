@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Background work queue, workers of every replica claim jobs with FOR UPDATE SKIP LOCKED
+CREATE TABLE jobs (
+    job_id BIGINT PRIMARY KEY,
+    job_uuid UUID UNIQUE NOT NULL,
+    job_name VARCHAR(100) NOT NULL,
+    job_payload JSONB NOT NULL DEFAULT '{}',
+    job_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (job_status IN ('pending', 'running', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMPTZ,
+    locked_by VARCHAR(255),
+    last_error TEXT,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255)
+);
+
+CREATE INDEX idx_jobs_runnable ON jobs(run_at) WHERE job_status = 'pending';
+CREATE INDEX idx_jobs_running ON jobs(locked_at) WHERE job_status = 'running';
+CREATE INDEX idx_jobs_name ON jobs(job_name, created_at DESC);
+
+-- Recurring jobs, the replica that locks a due row enqueues its next run
+CREATE TABLE job_schedules (
+    schedule_name VARCHAR(100) PRIMARY KEY,
+    schedule_spec VARCHAR(100) NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_job_uuid UUID
+);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('job:read', 'View background jobs and their schedules'),
+    ('job:write', 'Retry failed background jobs');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('SA', 'job:read'),
+    ('SA', 'job:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('job:read', 'job:write');
+
+DROP TABLE IF EXISTS job_schedules;
+DROP TABLE IF EXISTS jobs;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+-- In-app inbox, one row per recipient with the title and body already rendered in their language
+CREATE TABLE notifications (
+    notification_id BIGINT PRIMARY KEY,
+    notification_uuid UUID UNIQUE NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    notification_event VARCHAR(100) NOT NULL,
+    notification_title VARCHAR(255) NOT NULL,
+    notification_body TEXT NOT NULL,
+    notification_data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_uuid, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_uuid) WHERE read_at IS NULL;
+
+CREATE TABLE notification_settings (
+    user_uuid UUID PRIMARY KEY REFERENCES users(user_uuid) ON DELETE CASCADE,
+    notification_language VARCHAR(5) NOT NULL DEFAULT 'id' CHECK (notification_language IN ('id', 'en')),
+    updated_at TIMESTAMPTZ
+);
+
+-- Every channel of every event is on unless a row turns it off
+CREATE TABLE notification_preferences (
+    user_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    notification_event VARCHAR(100) NOT NULL,
+    notification_channel VARCHAR(20) NOT NULL CHECK (notification_channel IN ('inbox', 'websocket', 'push')),
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_uuid, notification_event, notification_channel)
+);
+
+-- Phones and browsers that receive push notifications
+CREATE TABLE push_devices (
+    device_id BIGINT PRIMARY KEY,
+    device_uuid UUID UNIQUE NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    device_token TEXT UNIQUE NOT NULL,
+    device_platform VARCHAR(20) NOT NULL CHECK (device_platform IN ('android', 'ios', 'web')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_push_devices_user ON push_devices(user_uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS push_devices;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Transactional outbox, events are written in the same transaction as the change they describe
+-- and relayed to subscribers once committed
+CREATE TABLE domain_events (
+    event_id BIGINT PRIMARY KEY,
+    event_uuid UUID UNIQUE NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_uuid UUID NOT NULL,
+    school_uuid UUID,
+    event_payload JSONB NOT NULL DEFAULT '{}',
+    dedup_key VARCHAR(255) UNIQUE NOT NULL,
+    event_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (event_status IN ('pending', 'published', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMPTZ,
+    locked_by VARCHAR(255),
+    last_error TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_domain_events_pending ON domain_events(next_attempt_at) WHERE event_status = 'pending';
+CREATE INDEX idx_domain_events_aggregate ON domain_events(aggregate_type, aggregate_uuid, occurred_at);
+
+-- Subscribers that already handled an event, a retried event skips them
+CREATE TABLE domain_event_deliveries (
+    event_id BIGINT NOT NULL REFERENCES domain_events(event_id) ON DELETE CASCADE,
+    subscriber_name VARCHAR(100) NOT NULL,
+    delivered_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, subscriber_name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS domain_event_deliveries;
+DROP TABLE IF EXISTS domain_events;
+-- +goose StatementEnd
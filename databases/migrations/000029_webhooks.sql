@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Endpoints of a school's own systems that receive its domain events
+CREATE TABLE webhook_endpoints (
+    endpoint_id BIGINT PRIMARY KEY,
+    endpoint_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    endpoint_url TEXT NOT NULL,
+    endpoint_description VARCHAR(255),
+    endpoint_secret VARCHAR(100) NOT NULL,
+    event_types VARCHAR(100)[] NOT NULL CHECK (CARDINALITY(event_types) > 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE INDEX idx_webhook_endpoints_school ON webhook_endpoints(school_uuid) WHERE deleted_at IS NULL;
+
+-- One delivery per endpoint and event, its body is kept so a redelivery sends the same payload
+CREATE TABLE webhook_deliveries (
+    delivery_id BIGINT PRIMARY KEY,
+    delivery_uuid UUID UNIQUE NOT NULL,
+    endpoint_uuid UUID NOT NULL REFERENCES webhook_endpoints(endpoint_uuid) ON DELETE CASCADE,
+    event_uuid UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    request_body JSONB NOT NULL,
+    delivery_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (delivery_status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT,
+    response_body TEXT,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    redelivered_at TIMESTAMPTZ,
+    redelivered_by VARCHAR(255),
+    UNIQUE (endpoint_uuid, event_uuid)
+);
+
+CREATE INDEX idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_uuid, created_at DESC);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('webhook:read', 'View the webhook endpoints of a school and their deliveries'),
+    ('webhook:write', 'Create, update and delete the webhook endpoints of a school and redeliver events');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('SA', 'webhook:read'),
+    ('SA', 'webhook:write'),
+    ('AS', 'webhook:read'),
+    ('AS', 'webhook:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('webhook:read', 'webhook:write');
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+-- +goose StatementEnd
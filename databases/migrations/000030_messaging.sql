@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A thread about a student or a trip, school admins of the school can read every thread for moderation
+CREATE TABLE conversations (
+    conversation_id BIGINT PRIMARY KEY,
+    conversation_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    student_uuid UUID REFERENCES students(student_uuid) ON DELETE CASCADE,
+    trip_uuid UUID REFERENCES trips(trip_uuid) ON DELETE CASCADE,
+    last_message_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    CHECK (student_uuid IS NOT NULL OR trip_uuid IS NOT NULL)
+);
+
+CREATE INDEX idx_conversations_school ON conversations(school_uuid, last_message_at DESC);
+
+-- last_read_at is the read receipt, every message sent up to then has been seen by the participant
+CREATE TABLE conversation_participants (
+    conversation_uuid UUID NOT NULL REFERENCES conversations(conversation_uuid) ON DELETE CASCADE,
+    user_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    last_read_at TIMESTAMPTZ,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_uuid, user_uuid)
+);
+
+CREATE INDEX idx_conversation_participants_user ON conversation_participants(user_uuid);
+
+CREATE TABLE messages (
+    message_id BIGINT PRIMARY KEY,
+    message_uuid UUID UNIQUE NOT NULL,
+    conversation_uuid UUID NOT NULL REFERENCES conversations(conversation_uuid) ON DELETE CASCADE,
+    sender_uuid UUID REFERENCES users(user_uuid) ON DELETE SET NULL,
+    message_body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255)
+);
+
+CREATE INDEX idx_messages_conversation ON messages(conversation_uuid, created_at DESC);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('message:read', 'Read own conversations'),
+    ('message:write', 'Start conversations and send messages'),
+    ('message:moderate', 'Read every conversation of own school and remove messages');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'message:read'),
+    ('AS', 'message:write'),
+    ('AS', 'message:moderate'),
+    ('D', 'message:read'),
+    ('D', 'message:write'),
+    ('P', 'message:read'),
+    ('P', 'message:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('message:read', 'message:write', 'message:moderate');
+
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_participants;
+DROP TABLE IF EXISTS conversations;
+-- +goose StatementEnd
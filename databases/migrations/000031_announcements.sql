@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A message from a school to the guardians of a whole school, a route, a grade level or a trip.
+-- target_uuid is the route, grade level or trip and is null for the whole school.
+CREATE TABLE announcements (
+    announcement_id BIGINT PRIMARY KEY,
+    announcement_uuid UUID UNIQUE NOT NULL,
+    school_uuid UUID NOT NULL REFERENCES schools(school_uuid) ON DELETE CASCADE,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('school', 'route', 'grade', 'trip')),
+    target_uuid UUID,
+    announcement_title VARCHAR(150) NOT NULL,
+    announcement_body TEXT NOT NULL,
+    publish_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ,
+    published_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMPTZ,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMPTZ,
+    deleted_by VARCHAR(255),
+    CHECK ((target_type = 'school') = (target_uuid IS NULL)),
+    CHECK (expires_at IS NULL OR expires_at > publish_at)
+);
+
+CREATE INDEX idx_announcements_school ON announcements(school_uuid, publish_at DESC);
+
+-- The guardians targeted when the announcement was published, read_at feeds the read statistics
+CREATE TABLE announcement_recipients (
+    announcement_uuid UUID NOT NULL REFERENCES announcements(announcement_uuid) ON DELETE CASCADE,
+    user_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    notified_at TIMESTAMPTZ,
+    read_at TIMESTAMPTZ,
+    PRIMARY KEY (announcement_uuid, user_uuid)
+);
+
+CREATE INDEX idx_announcement_recipients_user ON announcement_recipients(user_uuid);
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('announcement:read', 'View the announcements of own school and their read statistics'),
+    ('announcement:write', 'Create, update and delete the announcements of own school');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('AS', 'announcement:read'),
+    ('AS', 'announcement:write');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code IN ('announcement:read', 'announcement:write');
+
+DROP TABLE IF EXISTS announcement_recipients;
+DROP TABLE IF EXISTS announcements;
+-- +goose StatementEnd
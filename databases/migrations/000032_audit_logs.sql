@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per change made through the API. before_data and after_data only hold the columns that
+-- changed, everything for a create or a delete. impersonator_uuid is the admin behind an impersonated request.
+CREATE TABLE audit_logs (
+    audit_id BIGINT PRIMARY KEY,
+    audit_uuid UUID UNIQUE NOT NULL,
+    actor_uuid UUID,
+    actor_role_code VARCHAR(20),
+    actor_username VARCHAR(255),
+    impersonator_uuid UUID,
+    audit_action VARCHAR(30) NOT NULL,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_uuid UUID,
+    before_data JSONB NOT NULL DEFAULT '{}',
+    after_data JSONB NOT NULL DEFAULT '{}',
+    ip_address VARCHAR(64),
+    user_agent VARCHAR(512),
+    request_id VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_entity ON audit_logs(entity_type, entity_uuid, created_at DESC);
+CREATE INDEX idx_audit_logs_actor ON audit_logs(actor_uuid, created_at DESC);
+CREATE INDEX idx_audit_logs_created ON audit_logs(created_at DESC);
+
+-- The log is append only, rows can be added but never changed or removed
+CREATE OR REPLACE FUNCTION prevent_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW
+EXECUTE FUNCTION prevent_audit_log_change();
+
+INSERT INTO permissions (permission_code, permission_description) VALUES
+    ('audit:read', 'View the audit log of every change made through the API');
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+    ('SA', 'audit:read');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE permission_code = 'audit:read';
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS prevent_audit_log_change;
+DROP TABLE IF EXISTS audit_logs;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Parent accounts created while adding or importing a student. Only these are soft deleted once
+-- their last student goes, parents that registered or were added on their own are kept.
+ALTER TABLE users ADD COLUMN created_with_student BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS created_with_student;
+-- +goose StatementEnd
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AcademicHandlerInterface interface {
+	GetAllAcademicYears(c *fiber.Ctx) error
+	GetSpecAcademicYear(c *fiber.Ctx) error
+	AddAcademicYear(c *fiber.Ctx) error
+	UpdateAcademicYear(c *fiber.Ctx) error
+	DeleteAcademicYear(c *fiber.Ctx) error
+
+	GetAllGradeLevels(c *fiber.Ctx) error
+	AddGradeLevel(c *fiber.Ctx) error
+	UpdateGradeLevel(c *fiber.Ctx) error
+	DeleteGradeLevel(c *fiber.Ctx) error
+
+	GetAllPromotions(c *fiber.Ctx) error
+	PreviewPromotion(c *fiber.Ctx) error
+	ApplyPromotion(c *fiber.Ctx) error
+	RollbackPromotion(c *fiber.Ctx) error
+}
+
+type academicHandler struct {
+	academicService services.AcademicService
+}
+
+func NewAcademicHttpHandler(academicService services.AcademicService) AcademicHandlerInterface {
+	return &academicHandler{
+		academicService: academicService,
+	}
+}
+
+func (handler *academicHandler) GetAllAcademicYears(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	years, err := handler.academicService.GetAcademicYears(schoolUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch academic years", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Academic years fetched successfully", years)
+}
+
+func (handler *academicHandler) GetSpecAcademicYear(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	year, err := handler.academicService.GetSpecAcademicYear(schoolUUID, id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch academic year", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Academic year fetched successfully", year)
+}
+
+func (handler *academicHandler) AddAcademicYear(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	year := new(dto.AcademicYearRequestDTO)
+	if err := c.BodyParser(year); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, year); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.academicService.AddAcademicYear(schoolUUID, *year, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add academic year", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Academic year added successfully", nil)
+}
+
+func (handler *academicHandler) UpdateAcademicYear(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	year := new(dto.AcademicYearRequestDTO)
+	if err := c.BodyParser(year); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, year); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.academicService.UpdateAcademicYear(schoolUUID, id, *year, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update academic year", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Academic year updated successfully", nil)
+}
+
+func (handler *academicHandler) DeleteAcademicYear(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.academicService.DeleteAcademicYear(schoolUUID, id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete academic year", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Academic year deleted successfully", nil)
+}
+
+func (handler *academicHandler) GetAllGradeLevels(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	levels, err := handler.academicService.GetGradeLevels(schoolUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch grade levels", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Grade levels fetched successfully", levels)
+}
+
+func (handler *academicHandler) AddGradeLevel(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	level := new(dto.GradeLevelRequestDTO)
+	if err := c.BodyParser(level); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	level.Name = strings.TrimSpace(level.Name)
+	if err := utils.ValidateStruct(c, level); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.academicService.AddGradeLevel(schoolUUID, *level, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add grade level", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Grade level added successfully", nil)
+}
+
+func (handler *academicHandler) UpdateGradeLevel(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	level := new(dto.GradeLevelRequestDTO)
+	if err := c.BodyParser(level); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	level.Name = strings.TrimSpace(level.Name)
+	if err := utils.ValidateStruct(c, level); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.academicService.UpdateGradeLevel(schoolUUID, id, *level, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update grade level", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Grade level updated successfully", nil)
+}
+
+func (handler *academicHandler) DeleteGradeLevel(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.academicService.DeleteGradeLevel(schoolUUID, id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete grade level", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Grade level deleted successfully", nil)
+}
+
+func (handler *academicHandler) GetAllPromotions(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	promotions, err := handler.academicService.GetPromotions(schoolUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch promotions", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Promotions fetched successfully", promotions)
+}
+
+func (handler *academicHandler) PreviewPromotion(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	promotion := new(dto.PromotionRequestDTO)
+	if err := c.BodyParser(promotion); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, promotion); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	preview, err := handler.academicService.PreviewPromotion(schoolUUID, *promotion)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to preview promotion", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Promotion preview generated successfully", preview)
+}
+
+func (handler *academicHandler) ApplyPromotion(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	promotion := new(dto.PromotionRequestDTO)
+	if err := c.BodyParser(promotion); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, promotion); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	applied, err := handler.academicService.ApplyPromotion(schoolUUID, *promotion, username)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to apply promotion", map[string]interface{}{"school_uuid": schoolUUID})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Promotion applied successfully", applied)
+}
+
+func (handler *academicHandler) RollbackPromotion(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.academicService.RollbackPromotion(schoolUUID, id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to roll back promotion", map[string]interface{}{"promotion_uuid": id})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Promotion rolled back successfully", nil)
+}
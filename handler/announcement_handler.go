@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AnnouncementHandlerInterface interface {
+	GetAllAnnouncements(c *fiber.Ctx) error
+	GetSpecAnnouncement(c *fiber.Ctx) error
+	AddAnnouncement(c *fiber.Ctx) error
+	UpdateAnnouncement(c *fiber.Ctx) error
+	DeleteAnnouncement(c *fiber.Ctx) error
+
+	GetMyAnnouncements(c *fiber.Ctx) error
+	MarkRead(c *fiber.Ctx) error
+}
+
+type announcementHandler struct {
+	announcementService services.AnnouncementService
+}
+
+func NewAnnouncementHttpHandler(announcementService services.AnnouncementService) AnnouncementHandlerInterface {
+	return &announcementHandler{
+		announcementService: announcementService,
+	}
+}
+
+func (handler *announcementHandler) GetAllAnnouncements(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	announcements, totalItems, err := handler.announcementService.GetAnnouncements(schoolUUID, page, limit, c.Query("status"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated announcements", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(announcements) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(announcements) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": announcements,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Announcements fetched successfully", response)
+}
+
+func (handler *announcementHandler) GetSpecAnnouncement(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	id := c.Params("id")
+
+	announcement, err := handler.announcementService.GetSpecAnnouncement(schoolUUID, id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch announcement", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Announcement fetched successfully", announcement)
+}
+
+func (handler *announcementHandler) AddAnnouncement(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	announcement := new(dto.AnnouncementRequestDTO)
+	if err := c.BodyParser(announcement); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, announcement); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	created, err := handler.announcementService.AddAnnouncement(schoolUUID, *announcement, username)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add announcement", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Announcement created successfully", created)
+}
+
+func (handler *announcementHandler) UpdateAnnouncement(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+	id := c.Params("id")
+
+	announcement := new(dto.AnnouncementRequestDTO)
+	if err := c.BodyParser(announcement); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, announcement); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.announcementService.UpdateAnnouncement(schoolUUID, id, *announcement, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update announcement", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Announcement updated successfully", nil)
+}
+
+func (handler *announcementHandler) DeleteAnnouncement(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+	id := c.Params("id")
+
+	if err := handler.announcementService.DeleteAnnouncement(schoolUUID, id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete announcement", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Announcement deleted successfully", nil)
+}
+
+func (handler *announcementHandler) GetMyAnnouncements(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	announcements, totalItems, err := handler.announcementService.GetMyAnnouncements(userUUID, page, limit)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch announcements", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(announcements) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(announcements) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": announcements,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Announcements fetched successfully", response)
+}
+
+func (handler *announcementHandler) MarkRead(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	id := c.Params("id")
+
+	if err := handler.announcementService.MarkRead(userUUID, id); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to mark announcement as read", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Announcement marked as read", nil)
+}
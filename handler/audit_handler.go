@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandlerInterface interface {
+	GetAllAuditLogs(c *fiber.Ctx) error
+}
+
+type auditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHttpHandler(auditService services.AuditService) AuditHandlerInterface {
+	return &auditHandler{
+		auditService: auditService,
+	}
+}
+
+// Who is making the request, for the audit log entries of the changes it makes. Under impersonation
+// the user is the impersonated one and the impersonator is the admin behind the token.
+func auditActor(c *fiber.Ctx) entity.AuditActor {
+	actor := entity.AuditActor{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+
+	actor.UserUUID, _ = c.Locals("userUUID").(string)
+	actor.RoleCode, _ = c.Locals("role_code").(string)
+	actor.Username, _ = c.Locals("user_name").(string)
+	actor.ImpersonatorUUID, _ = c.Locals("actorUUID").(string)
+	actor.RequestID, _ = c.Locals("requestid").(string)
+
+	return actor
+}
+
+func (handler *auditHandler) GetAllAuditLogs(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	query := dto.AuditLogQueryDTO{
+		EntityType: c.Query("entity_type"),
+		EntityUUID: c.Query("entity_uuid"),
+		ActorUUID:  c.Query("actor_uuid"),
+		Action:     c.Query("action"),
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+	}
+
+	logs, totalItems, err := handler.auditService.GetAuditLogs(query, page, limit)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated audit logs", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(logs) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(logs) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": logs,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Audit logs fetched successfully", response)
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"fmt"
+	"math"
+	"shuttle/errors"
 	"shuttle/logger"
 	"shuttle/models/dto"
 	"shuttle/services"
 	"shuttle/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,15 +20,22 @@ type AuthHandlerInterface interface {
 	Logout(c *fiber.Ctx) error
 	GetMyProfile(c *fiber.Ctx) error
 	IssueNewAccessToken(c *fiber.Ctx) error
+	UnlockAccount(c *fiber.Ctx) error
+	RequestLoginOTP(c *fiber.Ctx) error
+	VerifyLoginOTP(c *fiber.Ctx) error
+	GetJWKS(c *fiber.Ctx) error
+	Impersonate(c *fiber.Ctx) error
 }
 
 type authHandler struct {
-	authService services.AuthService
+	authService          services.AuthService
+	impersonationService services.ImpersonationService
 }
 
-func NewAuthHttpHandler(authService services.AuthService) AuthHandlerInterface {
+func NewAuthHttpHandler(authService services.AuthService, impersonationService services.ImpersonationService) AuthHandlerInterface {
 	return &authHandler{
-		authService: authService,
+		authService:          authService,
+		impersonationService: impersonationService,
 	}
 }
 
@@ -34,8 +45,19 @@ func (handler *authHandler) Login(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid request data", nil)
 	}
 
-	userDataOnLogin, err := handler.authService.Login(loginRequest.Email, loginRequest.Password)
+	userDataOnLogin, retryAfter, err := handler.authService.Login(loginRequest.Email, loginRequest.Password, c.IP())
+	if retryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		return utils.TooManyRequestsResponse(c, "Too many failed login attempts, please try again later", nil)
+	}
+
 	if err != nil {
+		if _, ok := err.(*errors.CustomError); !ok {
+			logger.LogError(err, "Failed to check login attempts", map[string]interface{}{
+				"email": loginRequest.Email,
+			})
+			return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+		}
 		logger.LogError(err, "Failed to login", map[string]interface{}{
 			"email": loginRequest.Email,
 		})
@@ -47,6 +69,78 @@ func (handler *authHandler) Login(c *fiber.Ctx) error {
 		"email": loginRequest.Email,
 	})
 
+	return handler.issueTokenPair(c, userDataOnLogin)
+}
+
+func (handler *authHandler) RequestLoginOTP(c *fiber.Ctx) error {
+	otpRequest := new(dto.OTPLoginRequestDTO)
+	if err := c.BodyParser(otpRequest); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, otpRequest); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	retryAfter, err := handler.authService.CheckLoginOTPThrottle(otpRequest.Phone, c.IP())
+	if err != nil {
+		logger.LogError(err, "Failed to check login code requests", map[string]interface{}{
+			"phone": otpRequest.Phone,
+		})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if retryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		return utils.TooManyRequestsResponse(c, "Too many login code requests, please try again later", nil)
+	}
+
+	expiresIn, err := handler.authService.RequestLoginOTP(otpRequest.Phone, c.IP())
+	if err != nil {
+		logger.LogError(err, "Failed to send login code", map[string]interface{}{
+			"phone": otpRequest.Phone,
+		})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	responseData := map[string]interface{}{
+		"expires_in": int(expiresIn.Seconds()),
+	}
+
+	return utils.SuccessResponse(c, "If the phone number is registered, a login code has been sent", responseData)
+}
+
+func (handler *authHandler) VerifyLoginOTP(c *fiber.Ctx) error {
+	otpRequest := new(dto.OTPVerifyRequestDTO)
+	if err := c.BodyParser(otpRequest); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, otpRequest); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	userDataOnLogin, err := handler.authService.VerifyLoginOTP(otpRequest.Phone, otpRequest.Code)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to verify login code", map[string]interface{}{
+			"phone": otpRequest.Phone,
+		})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	logger.LogInfo("User logged in with a login code", map[string]interface{}{
+		"id":    userDataOnLogin.UserID,
+		"phone": otpRequest.Phone,
+	})
+
+	return handler.issueTokenPair(c, userDataOnLogin)
+}
+
+// Issues the access and refresh token pair shared by every login flow
+func (handler *authHandler) issueTokenPair(c *fiber.Ctx, userDataOnLogin dto.UserDataOnLoginDTO) error {
 	// Access token (short expiration)
 	accessToken, err := utils.GenerateToken(fmt.Sprintf("%d", userDataOnLogin.UserID), userDataOnLogin.UserUUID, userDataOnLogin.Username, userDataOnLogin.RoleCode)
 	if err != nil {
@@ -88,6 +182,20 @@ func (handler *authHandler) Logout(c *fiber.Ctx) error {
 		return utils.UnauthorizedResponse(c, "Token is invalid", nil)
 	}
 
+	// Leaving an impersonation only ends that session, the user's own sessions stay untouched
+	if impersonationUUID, ok := c.Locals("impersonationUUID").(string); ok {
+		if err := handler.impersonationService.EndImpersonation(impersonationUUID); err != nil {
+			logger.LogError(err, "Failed to end impersonation", map[string]interface{}{
+				"impersonation_uuid": impersonationUUID,
+			})
+			return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+		}
+
+		utils.InvalidateToken(c.Get("Authorization"))
+
+		return utils.SuccessResponse(c, "Impersonation ended successfully", nil)
+	}
+
 	// Delete WebSocket connection if exists
 	conn, exists := utils.GetConnection(userUUID)
 	if exists {
@@ -138,6 +246,17 @@ func (handler *authHandler) GetMyProfile(c *fiber.Ctx) error {
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
 
+	if impersonationUUID, ok := c.Locals("impersonationUUID").(string); ok {
+		if profile, ok := user.(dto.UserResponseDTO); ok {
+			profile.ImpersonatedBy = &dto.ImpersonatorDTO{
+				UUID:              c.Locals("actorUUID").(string),
+				Username:          c.Locals("actorName").(string),
+				ImpersonationUUID: impersonationUUID,
+			}
+			user = profile
+		}
+	}
+
 	return utils.SuccessResponse(c, "User profile retrieved", user)
 }
 
@@ -162,6 +281,10 @@ func (handler *authHandler) IssueNewAccessToken(c *fiber.Ctx) error {
 		return utils.UnauthorizedResponse(c, "Invalid refresh token", nil)
 	}
 
+	if _, impersonated := claims["act"]; impersonated {
+		return utils.UnauthorizedResponse(c, "Impersonation tokens cannot be refreshed", nil)
+	}
+
 	userID := claims["sub"].(string)
 	userUUID := claims["user_uuid"].(string)
 
@@ -198,3 +321,68 @@ func (handler *authHandler) IssueNewAccessToken(c *fiber.Ctx) error {
 		"reissued_access_token": accessToken,
 	})
 }
+
+func (handler *authHandler) UnlockAccount(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.authService.UnlockAccount(id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to unlock account", map[string]interface{}{
+			"user_uuid": id,
+		})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Account unlocked successfully", nil)
+}
+
+// Public keys used to verify tokens, served as a plain JWK set so other services can consume it directly
+func (handler *authHandler) GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
+
+func (handler *authHandler) Impersonate(c *fiber.Ctx) error {
+	targetUUID := c.Params("user_uuid")
+	adminUUID := c.Locals("userUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	if _, impersonating := c.Locals("impersonationUUID").(string); impersonating {
+		return utils.ForbiddenResponse(c, "You cannot start an impersonation while impersonating", nil)
+	}
+
+	impersonationReq := new(dto.ImpersonationRequestDTO)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(impersonationReq); err != nil {
+			return utils.BadRequestResponse(c, "Invalid request data", nil)
+		}
+	}
+
+	if err := utils.ValidateStruct(c, impersonationReq); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	impersonation, err := handler.impersonationService.StartImpersonation(adminUUID, username, targetUUID, impersonationReq.Reason, c.IP())
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to start impersonation", map[string]interface{}{
+			"target_uuid": targetUUID,
+		})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	logger.LogWarn("Impersonation started", map[string]interface{}{
+		"impersonation_uuid": impersonation.ImpersonationUUID,
+		"admin_uuid":         adminUUID,
+		"admin_name":         username,
+		"target_uuid":        targetUUID,
+		"reason":             impersonationReq.Reason,
+	})
+
+	return utils.SuccessResponse(c, "Impersonation started successfully", impersonation)
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ConversationHandlerInterface interface {
+	GetContacts(c *fiber.Ctx) error
+	GetMyConversations(c *fiber.Ctx) error
+	StartConversation(c *fiber.Ctx) error
+	GetMessages(c *fiber.Ctx) error
+	SendMessage(c *fiber.Ctx) error
+	MarkRead(c *fiber.Ctx) error
+
+	GetSchoolConversations(c *fiber.Ctx) error
+	GetSchoolMessages(c *fiber.Ctx) error
+	DeleteMessage(c *fiber.Ctx) error
+}
+
+type conversationHandler struct {
+	conversationService services.ConversationService
+}
+
+func NewConversationHttpHandler(conversationService services.ConversationService) ConversationHandlerInterface {
+	return &conversationHandler{
+		conversationService: conversationService,
+	}
+}
+
+func (handler *conversationHandler) GetContacts(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	roleCode := c.Locals("role_code").(string)
+
+	contacts, err := handler.conversationService.GetContacts(userUUID, roleCode, c.Query("student_uuid"), c.Query("trip_uuid"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch conversation contacts", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Contacts fetched successfully", contacts)
+}
+
+func (handler *conversationHandler) GetMyConversations(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	conversations, totalItems, err := handler.conversationService.GetMyConversations(userUUID, page, limit)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch paginated conversations", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(conversations) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(conversations) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": conversations,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Conversations fetched successfully", response)
+}
+
+func (handler *conversationHandler) StartConversation(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	roleCode := c.Locals("role_code").(string)
+	username := c.Locals("user_name").(string)
+
+	conversation := new(dto.ConversationRequestDTO)
+	if err := c.BodyParser(conversation); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, conversation); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	created, err := handler.conversationService.StartConversation(userUUID, roleCode, username, *conversation)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to start conversation", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Message sent successfully", created)
+}
+
+func (handler *conversationHandler) GetMessages(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	id := c.Params("id")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	messages, totalItems, err := handler.conversationService.GetMessages(userUUID, id, page, limit)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated messages", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(messages) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(messages) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": messages,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Messages fetched successfully", response)
+}
+
+func (handler *conversationHandler) SendMessage(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	roleCode := c.Locals("role_code").(string)
+	id := c.Params("id")
+
+	message := new(dto.MessageRequestDTO)
+	if err := c.BodyParser(message); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, message); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	sent, err := handler.conversationService.SendMessage(userUUID, roleCode, id, *message)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to send message", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Message sent successfully", sent)
+}
+
+func (handler *conversationHandler) MarkRead(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+	id := c.Params("id")
+
+	if err := handler.conversationService.MarkRead(userUUID, id); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to mark conversation as read", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Conversation marked as read", nil)
+}
+
+func (handler *conversationHandler) GetSchoolConversations(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	conversations, totalItems, err := handler.conversationService.GetSchoolConversations(schoolUUID, c.Query("student_uuid"), page, limit)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated school conversations", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(conversations) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(conversations) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": conversations,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Conversations fetched successfully", response)
+}
+
+func (handler *conversationHandler) GetSchoolMessages(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	id := c.Params("id")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	messages, totalItems, err := handler.conversationService.GetSchoolMessages(schoolUUID, id, page, limit)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated school messages", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(messages) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(messages) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": messages,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Messages fetched successfully", response)
+}
+
+func (handler *conversationHandler) DeleteMessage(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+	id := c.Params("id")
+	messageID := c.Params("message_id")
+
+	if err := handler.conversationService.DeleteMessage(schoolUUID, id, messageID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete message", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Message deleted successfully", nil)
+}
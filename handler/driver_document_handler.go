@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DriverDocumentHandlerInterface interface {
+	GetDriverDocuments(c *fiber.Ctx) error
+	GetDriverDocumentFile(c *fiber.Ctx) error
+	AddDriverDocument(c *fiber.Ctx) error
+	UpdateDriverDocument(c *fiber.Ctx) error
+	DeleteDriverDocument(c *fiber.Ctx) error
+}
+
+type driverDocumentHandler struct {
+	driverDocumentService services.DriverDocumentService
+}
+
+func NewDriverDocumentHttpHandler(driverDocumentService services.DriverDocumentService) DriverDocumentHandlerInterface {
+	return &driverDocumentHandler{
+		driverDocumentService: driverDocumentService,
+	}
+}
+
+// Requests through the school group only reach the drivers of that school,
+// super admins have no schoolUUID local and reach every driver
+func (handler *driverDocumentHandler) GetDriverDocuments(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID, _ := c.Locals("schoolUUID").(string)
+
+	documents, err := handler.driverDocumentService.GetDriverDocuments(id, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch driver documents", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Documents fetched successfully", documents)
+}
+
+func (handler *driverDocumentHandler) GetDriverDocumentFile(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+	schoolUUID, _ := c.Locals("schoolUUID").(string)
+
+	path, err := handler.driverDocumentService.GetDriverDocumentFile(id, documentUUID, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch driver document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if err := c.SendFile(path); err != nil {
+		logger.LogError(err, "Failed to send driver document", map[string]interface{}{"document_uuid": documentUUID})
+		return utils.NotFoundResponse(c, "Document file not found", nil)
+	}
+
+	return nil
+}
+
+func (handler *driverDocumentHandler) AddDriverDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	schoolUUID, _ := c.Locals("schoolUUID").(string)
+
+	document := new(dto.DriverDocumentRequestDTO)
+	if err := c.BodyParser(document); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, document); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	file, err := utils.HandleUploadedDocument(c, "file")
+	if err == nil {
+		err = handler.driverDocumentService.AddDriverDocument(id, schoolUUID, *document, file, username)
+	}
+
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add driver document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document added successfully", nil)
+}
+
+// The file is optional here, the stored scan is kept when none is sent
+func (handler *driverDocumentHandler) UpdateDriverDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+	username := c.Locals("user_name").(string)
+	schoolUUID, _ := c.Locals("schoolUUID").(string)
+
+	document := new(dto.DriverDocumentRequestDTO)
+	if err := c.BodyParser(document); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, document); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	var file string
+	var err error
+	if _, formErr := c.FormFile("file"); formErr == nil {
+		file, err = utils.HandleUploadedDocument(c, "file")
+	}
+
+	if err == nil {
+		err = handler.driverDocumentService.UpdateDriverDocument(id, documentUUID, schoolUUID, *document, file, username)
+	}
+
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update driver document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document updated successfully", nil)
+}
+
+func (handler *driverDocumentHandler) DeleteDriverDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+	username := c.Locals("user_name").(string)
+	schoolUUID, _ := c.Locals("schoolUUID").(string)
+
+	if err := handler.driverDocumentService.DeleteDriverDocument(id, documentUUID, schoolUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete driver document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document deleted successfully", nil)
+}
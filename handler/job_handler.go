@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobHandlerInterface interface {
+	GetAllJobs(c *fiber.Ctx) error
+	GetSpecJob(c *fiber.Ctx) error
+	RetryJob(c *fiber.Ctx) error
+	GetJobSchedules(c *fiber.Ctx) error
+}
+
+type jobHandler struct {
+	jobService services.JobService
+}
+
+func NewJobHttpHandler(jobService services.JobService) JobHandlerInterface {
+	return &jobHandler{
+		jobService: jobService,
+	}
+}
+
+func (handler *jobHandler) GetAllJobs(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	jobs, totalItems, err := handler.jobService.GetJobs(page, limit, c.Query("status"), c.Query("name"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated jobs", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(jobs) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(jobs) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": jobs,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Jobs fetched successfully", response)
+}
+
+func (handler *jobHandler) GetSpecJob(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	job, err := handler.jobService.GetSpecJob(id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch job", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Job fetched successfully", job)
+}
+
+func (handler *jobHandler) RetryJob(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.jobService.RetryJob(id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to retry job", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Job queued for another run", nil)
+}
+
+func (handler *jobHandler) GetJobSchedules(c *fiber.Ctx) error {
+	schedules, err := handler.jobService.GetSchedules()
+	if err != nil {
+		logger.LogError(err, "Failed to fetch job schedules", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Job schedules fetched successfully", schedules)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandlerInterface interface {
+	GetMyNotifications(c *fiber.Ctx) error
+	GetUnreadCount(c *fiber.Ctx) error
+	MarkRead(c *fiber.Ctx) error
+	MarkAllRead(c *fiber.Ctx) error
+
+	GetPreferences(c *fiber.Ctx) error
+	UpdatePreferences(c *fiber.Ctx) error
+	AddPushDevice(c *fiber.Ctx) error
+	DeletePushDevice(c *fiber.Ctx) error
+}
+
+type notificationHandler struct {
+	notificationService services.NotificationService
+}
+
+func NewNotificationHttpHandler(notificationService services.NotificationService) NotificationHandlerInterface {
+	return &notificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+func (handler *notificationHandler) GetMyNotifications(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	unreadOnly := c.QueryBool("unread", false)
+
+	notifications, totalItems, err := handler.notificationService.GetNotifications(userUUID, page, limit, unreadOnly)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch notifications", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	unreadCount, err := handler.notificationService.GetUnreadCount(userUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to count unread notifications", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(notifications) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(notifications) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": notifications,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"unread_count":   unreadCount,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Notifications fetched successfully", response)
+}
+
+func (handler *notificationHandler) GetUnreadCount(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	unreadCount, err := handler.notificationService.GetUnreadCount(userUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to count unread notifications", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Unread notifications counted successfully", fiber.Map{"unread_count": unreadCount})
+}
+
+func (handler *notificationHandler) MarkRead(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userUUID := c.Locals("userUUID").(string)
+
+	if err := handler.notificationService.MarkRead(userUUID, id); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to mark notification read", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Notification marked as read", nil)
+}
+
+func (handler *notificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	if err := handler.notificationService.MarkAllRead(userUUID); err != nil {
+		logger.LogError(err, "Failed to mark notifications read", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "All notifications marked as read", nil)
+}
+
+func (handler *notificationHandler) GetPreferences(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	preferences, err := handler.notificationService.GetPreferences(userUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch notification preferences", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Notification preferences fetched successfully", preferences)
+}
+
+func (handler *notificationHandler) UpdatePreferences(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	preferences := new(dto.NotificationPreferencesRequestDTO)
+	if err := c.BodyParser(preferences); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, preferences); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.notificationService.UpdatePreferences(userUUID, *preferences); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update notification preferences", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Notification preferences updated successfully", nil)
+}
+
+func (handler *notificationHandler) AddPushDevice(c *fiber.Ctx) error {
+	userUUID := c.Locals("userUUID").(string)
+
+	device := new(dto.PushDeviceRequestDTO)
+	if err := c.BodyParser(device); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, device); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	saved, err := handler.notificationService.AddPushDevice(userUUID, *device)
+	if err != nil {
+		logger.LogError(err, "Failed to register push device", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Device registered successfully", saved)
+}
+
+func (handler *notificationHandler) DeletePushDevice(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userUUID := c.Locals("userUUID").(string)
+
+	if err := handler.notificationService.DeletePushDevice(userUUID, id); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to remove push device", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Device removed successfully", nil)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PermissionHandlerInterface interface {
+	GetAllRoles(c *fiber.Ctx) error
+	GetSpecRole(c *fiber.Ctx) error
+	AddRole(c *fiber.Ctx) error
+	UpdateRole(c *fiber.Ctx) error
+	DeleteRole(c *fiber.Ctx) error
+	GetAllPermissions(c *fiber.Ctx) error
+}
+
+type permissionHandler struct {
+	permissionService services.PermissionService
+}
+
+func NewPermissionHttpHandler(permissionService services.PermissionService) PermissionHandlerInterface {
+	return &permissionHandler{
+		permissionService: permissionService,
+	}
+}
+
+func (handler *permissionHandler) GetAllRoles(c *fiber.Ctx) error {
+	roles, err := handler.permissionService.GetAllRoles()
+	if err != nil {
+		logger.LogError(err, "Failed to fetch all roles", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Roles fetched successfully", roles)
+}
+
+func (handler *permissionHandler) GetSpecRole(c *fiber.Ctx) error {
+	code := c.Params("code")
+
+	role, err := handler.permissionService.GetSpecRole(code)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch specific role", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Role fetched successfully", role)
+}
+
+func (handler *permissionHandler) AddRole(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+
+	role := new(dto.AccessRoleRequestDTO)
+	if err := c.BodyParser(role); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, role); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.permissionService.AddRole(*role, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add role", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Role added successfully", nil)
+}
+
+func (handler *permissionHandler) UpdateRole(c *fiber.Ctx) error {
+	code := c.Params("code")
+	username := c.Locals("user_name").(string)
+
+	role := new(dto.AccessRoleRequestDTO)
+	if err := c.BodyParser(role); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	// The code in the path is authoritative, the body only has to pass validation.
+	role.Code = code
+
+	if err := utils.ValidateStruct(c, role); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.permissionService.UpdateRole(code, *role, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update role", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Role updated successfully", nil)
+}
+
+func (handler *permissionHandler) DeleteRole(c *fiber.Ctx) error {
+	code := c.Params("code")
+
+	if err := handler.permissionService.DeleteRole(code); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete role", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Role deleted successfully", nil)
+}
+
+func (handler *permissionHandler) GetAllPermissions(c *fiber.Ctx) error {
+	permissions, err := handler.permissionService.GetAllPermissions()
+	if err != nil {
+		logger.LogError(err, "Failed to fetch all permissions", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Permissions fetched successfully", permissions)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RouteHandlerInterface interface {
+	GetAllRoutes(c *fiber.Ctx) error
+	GetSpecRoute(c *fiber.Ctx) error
+	AddRoute(c *fiber.Ctx) error
+	UpdateRoute(c *fiber.Ctx) error
+	DeleteRoute(c *fiber.Ctx) error
+}
+
+type routeHandler struct {
+	routeService services.RouteService
+}
+
+func NewRouteHttpHandler(routeService services.RouteService) RouteHandlerInterface {
+	return &routeHandler{
+		routeService: routeService,
+	}
+}
+
+func (handler *routeHandler) GetAllRoutes(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	routes, err := handler.routeService.GetRoutes(schoolUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch routes", map[string]interface{}{"school_uuid": schoolUUID})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Routes fetched successfully", routes)
+}
+
+func (handler *routeHandler) GetSpecRoute(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	route, err := handler.routeService.GetSpecRoute(id, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch route", map[string]interface{}{"route_uuid": id})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Route fetched successfully", route)
+}
+
+func (handler *routeHandler) AddRoute(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	route := new(dto.RouteRequestDTO)
+	if err := c.BodyParser(route); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, route); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.routeService.AddRoute(schoolUUID, *route, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add route", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Route created successfully", nil)
+}
+
+func (handler *routeHandler) UpdateRoute(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	route := new(dto.RouteRequestDTO)
+	if err := c.BodyParser(route); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, route); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.routeService.UpdateRoute(id, schoolUUID, *route, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update route", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Route updated successfully", nil)
+}
+
+func (handler *routeHandler) DeleteRoute(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	if err := handler.routeService.DeleteRoute(id, schoolUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete route", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Route deleted successfully", nil)
+}
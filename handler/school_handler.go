@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"shuttle/errors"
 	"shuttle/logger"
 	"shuttle/models/dto"
 	"shuttle/services"
@@ -19,7 +20,9 @@ type SchoolHandlerInterface interface {
 	GetSpecSchool(c *fiber.Ctx) error
 	AddSchool(c *fiber.Ctx) error
 	UpdateSchool(c *fiber.Ctx) error
+	GetDeletionPlan(c *fiber.Ctx) error
 	DeleteSchool(c *fiber.Ctx) error
+	RestoreSchool(c *fiber.Ctx) error
 }
 
 type schoolHandler struct {
@@ -54,6 +57,13 @@ func (handler *schoolHandler) GetAllSchools(c *fiber.Ctx) error {
         return utils.BadRequestResponse(c, "Invalid sort field", nil)
     }
 
+    format := c.Query("format")
+    if format != "" {
+    	return utils.ExportResponse(c, format, "schools", func(page, limit int) (interface{}, int, error) {
+    		return handler.schoolService.GetAllSchools(page, limit, sortField, sortDirection)
+    	})
+    }
+
     schools, totalItems, err := handler.schoolService.GetAllSchools(page, limit, sortField, sortDirection)
     if err != nil {
         logger.LogError(err, "Failed to fetch paginated schools", nil)
@@ -113,7 +123,7 @@ func (handler *schoolHandler) GetSpecSchool(c *fiber.Ctx) error {
 }
 
 func (handler *schoolHandler) AddSchool(c *fiber.Ctx) error {
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	school := new(dto.SchoolRequestDTO)
 	if err := c.BodyParser(school); err != nil {
@@ -124,7 +134,7 @@ func (handler *schoolHandler) AddSchool(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
-	if err := handler.schoolService.AddSchool(*school, username); err != nil {
+	if err := handler.schoolService.AddSchool(*school, actor); err != nil {
 		logger.LogError(err, "Failed to create school", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
@@ -134,7 +144,7 @@ func (handler *schoolHandler) AddSchool(c *fiber.Ctx) error {
 
 func (handler *schoolHandler) UpdateSchool(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	school := new(dto.SchoolRequestDTO)
 	if err := c.BodyParser(school); err != nil {
@@ -145,7 +155,7 @@ func (handler *schoolHandler) UpdateSchool(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
-	if err := handler.schoolService.UpdateSchool(id, *school, username); err != nil {
+	if err := handler.schoolService.UpdateSchool(id, *school, actor); err != nil {
 		logger.LogError(err, "Failed to update school", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
@@ -153,28 +163,65 @@ func (handler *schoolHandler) UpdateSchool(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "School updated successfully", nil)
 }
 
+func (handler *schoolHandler) GetDeletionPlan(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	plan, err := handler.schoolService.GetDeletionPlan(id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch school deletion plan", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "School deletion plan fetched successfully", plan)
+}
+
 func (handler *schoolHandler) DeleteSchool(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	force_delete := c.Query("force_delete")
 
-	existingSchool, err := handler.schoolService.GetSpecSchool(id)
+	plan, err := handler.schoolService.GetDeletionPlan(id)
 	if err != nil {
-		logger.LogError(err, "Failed to fetch specific school", nil)
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch school deletion plan", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
 
-	if (existingSchool.AdminUUID != "N/A" && existingSchool.AdminUUID != "") && force_delete != "true" {
-		return utils.BadRequestResponse(c, "Warning: By deleting this school, the school admin will also be deleted, continue?", nil)
+	if plan.Total > 0 && force_delete != "true" {
+		return utils.BadRequestResponse(c, "Warning: By deleting this school, everything that belongs to it will also be deleted, continue?", plan)
 	}
 
-	if err := handler.schoolService.DeleteSchool(id, username, existingSchool.AdminUUID); err != nil {
+	if err := handler.schoolService.DeleteSchool(id, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
 		logger.LogError(err, "Failed to delete school", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
 
-	return utils.SuccessResponse(c, "School deleted successfully", nil)
+	return utils.SuccessResponse(c, "School deleted successfully", plan)
+}
+
+func (handler *schoolHandler) RestoreSchool(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+
+	restored, err := handler.schoolService.RestoreSchool(id, actor)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to restore school", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "School restored successfully", restored)
 }
 
 func isValidSortFieldForSchools(field string) bool {
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SchoolProfileHandlerInterface interface {
+	GetSchoolProfile(c *fiber.Ctx) error
+	UpdateSchoolProfile(c *fiber.Ctx) error
+
+	GetClosures(c *fiber.Ctx) error
+	AddClosure(c *fiber.Ctx) error
+	UpdateClosure(c *fiber.Ctx) error
+	DeleteClosure(c *fiber.Ctx) error
+}
+
+type schoolProfileHandler struct {
+	schoolProfileService services.SchoolProfileService
+}
+
+func NewSchoolProfileHttpHandler(schoolProfileService services.SchoolProfileService) SchoolProfileHandlerInterface {
+	return &schoolProfileHandler{
+		schoolProfileService: schoolProfileService,
+	}
+}
+
+// School admins always work on their own school, super admins pick one with the :id param
+func profileSchoolUUID(c *fiber.Ctx) string {
+	if schoolUUID, ok := c.Locals("schoolUUID").(string); ok {
+		return schoolUUID
+	}
+
+	return c.Params("id")
+}
+
+func (handler *schoolProfileHandler) GetSchoolProfile(c *fiber.Ctx) error {
+	profile, err := handler.schoolProfileService.GetSchoolProfile(profileSchoolUUID(c))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch school profile", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "School profile fetched successfully", profile)
+}
+
+func (handler *schoolProfileHandler) UpdateSchoolProfile(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+
+	profile := new(dto.SchoolProfileRequestDTO)
+	if err := c.BodyParser(profile); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, profile); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.schoolProfileService.UpdateSchoolProfile(profileSchoolUUID(c), *profile, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update school profile", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "School profile updated successfully", nil)
+}
+
+func (handler *schoolProfileHandler) GetClosures(c *fiber.Ctx) error {
+	closures, err := handler.schoolProfileService.GetClosures(profileSchoolUUID(c), c.Query("from"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch school closures", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Closures fetched successfully", closures)
+}
+
+func (handler *schoolProfileHandler) AddClosure(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+
+	closure := new(dto.SchoolClosureRequestDTO)
+	if err := c.BodyParser(closure); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, closure); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.schoolProfileService.AddClosure(profileSchoolUUID(c), *closure, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add school closure", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Closure created successfully", nil)
+}
+
+func (handler *schoolProfileHandler) UpdateClosure(c *fiber.Ctx) error {
+	closureUUID := c.Params("closure_id")
+	username := c.Locals("user_name").(string)
+
+	closure := new(dto.SchoolClosureRequestDTO)
+	if err := c.BodyParser(closure); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, closure); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.schoolProfileService.UpdateClosure(profileSchoolUUID(c), closureUUID, *closure, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update school closure", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Closure updated successfully", nil)
+}
+
+func (handler *schoolProfileHandler) DeleteClosure(c *fiber.Ctx) error {
+	closureUUID := c.Params("closure_id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.schoolProfileService.DeleteClosure(profileSchoolUUID(c), closureUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete school closure", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Closure deleted successfully", nil)
+}
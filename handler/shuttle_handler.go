@@ -4,6 +4,7 @@ import (
 	// "log"
 	"log"
 	"net/http"
+	customerrors "shuttle/errors"
 	"shuttle/models/dto"
 	"shuttle/services"
 	"shuttle/utils"
@@ -33,7 +34,6 @@ func (h *ShuttleHandler) GetShuttleStatusByParent(c *fiber.Ctx) error {
 	if !ok || userUUID == "" {
 		return utils.BadRequestResponse(c, "Invalid or missing userUUID", nil)
 	}
-	log.Printf("awokwawk",userUUID)
 	// Parse userUUID ke uuid.UUID
 	parentUUID, err := uuid.Parse(userUUID)
 	if err != nil {
@@ -79,7 +79,11 @@ func (h *ShuttleHandler) AddShuttle(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 	log.Println("woi", shuttleReq)
-	if err := h.ShuttleService.AddShuttle(*shuttleReq, driverUUID.String(), username); err != nil {
+	if err := h.ShuttleService.AddShuttle(*shuttleReq, driverUUID.String(), auditActor(c)); err != nil {
+		var customErr *customerrors.CustomError
+		if errors.As(err, &customErr) {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
 		return utils.InternalServerErrorResponse(c, "Failed to add shuttle", nil)
 	}
 
@@ -109,7 +113,7 @@ func (h *ShuttleHandler) EditShuttle(c *fiber.Ctx) error {
 	log.Println("Editing shuttle:", id, "with status:", statusReq.Status)
 
 	// Panggil service untuk update
-	if err := h.ShuttleService.EditShuttleStatus(id, statusReq.Status); err != nil {
+	if err := h.ShuttleService.EditShuttleStatus(id, statusReq.Status, auditActor(c)); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return utils.NotFoundResponse(c, "Shuttle not found", nil)
 		}
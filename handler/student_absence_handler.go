@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StudentAbsenceHandlerInterface interface {
+	GetAbsences(c *fiber.Ctx) error
+	AddAbsence(c *fiber.Ctx) error
+	DeleteAbsence(c *fiber.Ctx) error
+}
+
+type studentAbsenceHandler struct {
+	studentAbsenceService services.StudentAbsenceService
+}
+
+func NewStudentAbsenceHttpHandler(studentAbsenceService services.StudentAbsenceService) StudentAbsenceHandlerInterface {
+	return &studentAbsenceHandler{
+		studentAbsenceService: studentAbsenceService,
+	}
+}
+
+func (handler *studentAbsenceHandler) GetAbsences(c *fiber.Ctx) error {
+	id := c.Params("id")
+	parentUUID, ok := c.Locals("userUUID").(string)
+	if !ok || parentUUID == "" {
+		return utils.BadRequestResponse(c, "Invalid or missing userUUID", nil)
+	}
+
+	absences, err := handler.studentAbsenceService.GetAbsences(parentUUID, id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch student absences", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Absences fetched successfully", absences)
+}
+
+func (handler *studentAbsenceHandler) AddAbsence(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+	parentUUID, ok := c.Locals("userUUID").(string)
+	if !ok || parentUUID == "" {
+		return utils.BadRequestResponse(c, "Invalid or missing userUUID", nil)
+	}
+
+	absence := new(dto.StudentAbsenceRequestDTO)
+	if err := c.BodyParser(absence); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, absence); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.studentAbsenceService.AddAbsence(parentUUID, id, *absence, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add student absence", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Absence reported successfully", nil)
+}
+
+func (handler *studentAbsenceHandler) DeleteAbsence(c *fiber.Ctx) error {
+	id := c.Params("id")
+	absenceUUID := c.Params("absence_id")
+	username := c.Locals("user_name").(string)
+	parentUUID, ok := c.Locals("userUUID").(string)
+	if !ok || parentUUID == "" {
+		return utils.BadRequestResponse(c, "Invalid or missing userUUID", nil)
+	}
+
+	if err := handler.studentAbsenceService.DeleteAbsence(parentUUID, id, absenceUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete student absence", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Absence cancelled successfully", nil)
+}
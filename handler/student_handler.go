@@ -1,11 +1,11 @@
 package handler
 
 import (
-	// "fmt"
-
-	// "strconv"
+	"fmt"
+	"strconv"
 	"strings"
 
+	"shuttle/errors"
 	"shuttle/logger"
 	"shuttle/models/dto"
 	"shuttle/services"
@@ -15,7 +15,16 @@ import (
 )
 
 type StudentHandlerInterface interface {
+	GetAllStudentWithParents(c *fiber.Ctx) error
+	GetSpecStudentWithParents(c *fiber.Ctx) error
 	AddStudentWithParent(c *fiber.Ctx) error
+	ImportStudents(c *fiber.Ctx) error
+	UpdateStudent(c *fiber.Ctx) error
+	DeleteStudent(c *fiber.Ctx) error
+	GetStudentSiblings(c *fiber.Ctx) error
+	AddStudentGuardian(c *fiber.Ctx) error
+	UpdateStudentGuardian(c *fiber.Ctx) error
+	RemoveStudentGuardian(c *fiber.Ctx) error
 }
 
 type studentHandler struct {
@@ -28,9 +37,102 @@ func NewStudentHttpHandler(studentService services.StudentService) StudentHandle
 	}
 }
 
+func (handler *studentHandler) GetAllStudentWithParents(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	sortField := c.Query("sort_by", "student_id")
+	sortDirection := c.Query("direction", "desc")
+	search := strings.TrimSpace(c.Query("search"))
+	grade := strings.TrimSpace(c.Query("grade"))
+
+	if sortDirection != "asc" && sortDirection != "desc" {
+		return utils.BadRequestResponse(c, "Invalid sort direction, use 'asc' or 'desc'", nil)
+	}
+
+	if !isValidSortFieldForStudents(sortField) {
+		return utils.BadRequestResponse(c, "Invalid sort field", nil)
+	}
+
+	format := c.Query("format")
+	if format != "" {
+		return utils.ExportResponse(c, format, "students", func(page, limit int) (interface{}, int, error) {
+			return handler.studentService.GetAllPermittedSchoolStudents(schoolUUID, page, limit, sortField, sortDirection, search, grade)
+		})
+	}
+
+	students, totalItems, err := handler.studentService.GetAllPermittedSchoolStudents(schoolUUID, page, limit, sortField, sortDirection, search, grade)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch paginated students", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(students) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(students) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": students,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Students fetched successfully", response)
+}
+
+func (handler *studentHandler) GetSpecStudentWithParents(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	student, err := handler.studentService.GetSpecPermittedSchoolStudent(id, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch specific student", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Student fetched successfully", student)
+}
+
 func (handler *studentHandler) AddStudentWithParent(c *fiber.Ctx) error {
-	// Mengambil username dari context (asumsi dari middleware)
-	createdBy := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	// Parsing body request
 	req := new(dto.AddStudentWithParentRequestDTO)
@@ -38,14 +140,30 @@ func (handler *studentHandler) AddStudentWithParent(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid request data", nil)
 	}
 
+	// Sekolah selalu diambil dari admin yang login, bukan dari request
+	req.Student.SchoolUUID = c.Locals("schoolUUID").(string)
+
+	// Akun baru yang dibuat dari request ini selalu berperan sebagai parent
+	if req.Parent != nil {
+		req.Parent.Role = dto.Parent
+	}
+	for i := range req.Guardians {
+		if req.Guardians[i].Parent != nil {
+			req.Guardians[i].Parent.Role = dto.Parent
+		}
+	}
+
 	// Validasi data menggunakan utils
 	if err := utils.ValidateStruct(c, req); err != nil {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
 	// Memanggil service untuk menambahkan student dan parent
-	studentUUID, parentUUID, err := handler.studentService.AddPermittedSchoolStudentWithParents(*req, createdBy)
+	studentUUID, guardianUUIDs, err := handler.studentService.AddPermittedSchoolStudentWithParents(*req, actor)
 	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
 		logger.LogError(err, "Failed to add student and parent", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
@@ -53,137 +171,184 @@ func (handler *studentHandler) AddStudentWithParent(c *fiber.Ctx) error {
 	// Menyusun response sukses
 	response := fiber.Map{
 		"message":      "Student and parent added successfully",
-		"student_uuid": studentUUID.String(),
-		"parent_uuid":  parentUUID.String(),
+		"student_uuid":   studentUUID.String(),
+		"parent_uuid":    guardianUUIDs[0].String(),
+		"guardian_uuids": guardianUUIDs,
 	}
 	return utils.SuccessResponse(c, "Student and parent added successfully", response)
 }
 
+// Accepts a .csv or .xlsx file in the "file" form field, ?dry_run=true only reports what would happen
+func (handler *studentHandler) ImportStudents(c *fiber.Ctx) error {
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+	dryRun := c.QueryBool("dry_run", false)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.BadRequestResponse(c, "A .csv or .xlsx file is required", nil)
+	}
+
+	rows, err := utils.ReadSpreadsheet(file)
+	if err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	result, err := handler.studentService.ImportPermittedSchoolStudents(schoolUUID, rows, dryRun, actor)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to import students", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if dryRun {
+		return utils.SuccessResponse(c, "Import checked, nothing was saved", result)
+	}
+
+	return utils.SuccessResponse(c, "Students imported successfully", result)
+}
+
+func (handler *studentHandler) UpdateStudent(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	student := new(dto.StudentRequestDTO)
+	if err := c.BodyParser(student); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	student.SchoolUUID = schoolUUID
+
+	if err := utils.ValidateStruct(c, student); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.studentService.UpdatePermittedSchoolStudent(id, schoolUUID, *student, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update student", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Student updated successfully", nil)
+}
+
+func (handler *studentHandler) DeleteStudent(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.studentService.DeletePermittedSchoolStudent(id, schoolUUID, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete student", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Student deleted successfully", nil)
+}
+
+func (handler *studentHandler) GetStudentSiblings(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	siblings, err := handler.studentService.GetStudentSiblings(id, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch student siblings", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Siblings fetched successfully", siblings)
+}
+
+func (handler *studentHandler) AddStudentGuardian(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
 
+	guardian := new(dto.GuardianRequestDTO)
+	if err := c.BodyParser(guardian); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
 
+	if guardian.Parent != nil {
+		guardian.Parent.Role = dto.Parent
+	}
 
+	if err := utils.ValidateStruct(c, guardian); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
 
+	guardianUUID, err := handler.studentService.AddStudentGuardian(id, schoolUUID, *guardian, actor)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add student guardian", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
 
+	return utils.SuccessResponse(c, "Guardian added successfully", fiber.Map{"parent_uuid": guardianUUID.String()})
+}
 
+func (handler *studentHandler) UpdateStudentGuardian(c *fiber.Ctx) error {
+	id := c.Params("id")
+	guardianUUID := c.Params("guardian_id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
 
+	guardian := new(dto.GuardianUpdateRequestDTO)
+	if err := c.BodyParser(guardian); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
 
+	if err := utils.ValidateStruct(c, guardian); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
 
+	if err := handler.studentService.UpdateStudentGuardian(id, guardianUUID, schoolUUID, *guardian, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update student guardian", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
 
-// import (
-// 	"shuttle/errors"
-// 	"shuttle/logger"
-// 	"shuttle/models"
-// 	"shuttle/services"
-// 	"shuttle/utils"
-// 	"strings"
-
-// 	"github.com/gofiber/fiber/v2"
-// 	"go.mongodb.org/mongo-driver/bson/primitive"
-// )
-
-// func GetAllStudentWithParents(c *fiber.Ctx) error {
-// 	SchoolObjID, err := primitive.ObjectIDFromHex(c.Locals("schoolId").(string))
-// 	if err != nil {
-// 		logger.LogError(err, "Failed to convert school id", map[string]interface{}{
-// 			"school_id": c.Locals("schoolId"),
-// 		})
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	students, err := services.GetAllPermitedSchoolStudentsWithParents(SchoolObjID)
-// 	if err != nil {
-// 		logger.LogError(err, "Failed to fetch all students", nil)
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	return c.Status(fiber.StatusOK).JSON(students)
-// }
-
-// func AddSchoolStudentWithParents(c *fiber.Ctx) error {
-// 	username := c.Locals("username").(string)
-// 	SchoolObjID, err := primitive.ObjectIDFromHex(c.Locals("schoolId").(string))
-// 	if err != nil {
-// 		logger.LogError(err, "Failed to convert school id", map[string]interface{}{
-// 			"school_id": c.Locals("schoolId"),
-// 		})
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-	
-// 	student := new(models.SchoolStudentRequest)
-// 	if err := c.BodyParser(student); err != nil {
-// 		return utils.BadRequestResponse(c, "Invalid request data", nil)
-// 	}
-
-// 	if err := utils.ValidateStruct(c, student); err != nil {
-// 		return utils.BadRequestResponse(c, err.Error(), nil)
-// 	}
-
-// 	if (models.User{}) == student.Parent {
-// 		return utils.BadRequestResponse(c, "Parent details are required", nil)
-// 	}
-
-// 	if student.Parent.Phone == "" || student.Parent.Address == "" || student.Parent.Email == "" {
-// 		return utils.BadRequestResponse(c, "Parent details are required", nil)
-// 	}
-
-// 	if err := services.AddPermittedSchoolStudentWithParents(*student, SchoolObjID, username); err != nil {
-// 		if customErr, ok := err.(*errors.CustomError); ok {
-// 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
-// 		}
-// 		logger.LogError(err, "Failed to add student", nil)
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	return utils.SuccessResponse(c, "Student created successfully", nil)
-// }
-
-// func UpdateSchoolStudentWithParents(c *fiber.Ctx) error {
-// 	id := c.Params("id")
-// 	SchoolObjID, err := primitive.ObjectIDFromHex(c.Locals("schoolId").(string))
-// 	if err != nil {
-// 		logger.LogError(err, "Failed to convert school id", map[string]interface{}{
-// 			"school_id": c.Locals("schoolId"),
-// 		})
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	student := new(models.SchoolStudentRequest)
-// 	if err := c.BodyParser(student); err != nil {
-// 		return utils.BadRequestResponse(c, "Invalid request data", nil)
-// 	}
-
-// 	if err := utils.ValidateStruct(c, student); err != nil {
-// 		return utils.BadRequestResponse(c, err.Error(), nil)
-// 	}
-
-// 	if err := services.UpdatePermittedSchoolStudentWithParents(id, *student, SchoolObjID); err != nil {
-// 		if customErr, ok := err.(*errors.CustomError); ok {
-// 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
-// 		}
-// 		logger.LogError(err, "Failed to update student", nil)
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	return utils.SuccessResponse(c, "Student updated successfully", nil)
-// }
-
-// func DeleteSchoolStudentWithParents(c *fiber.Ctx) error {
-// 	id := c.Params("id")
-// 	SchoolObjID, err := primitive.ObjectIDFromHex(c.Locals("schoolId").(string))
-// 	if err != nil {
-// 		logger.LogError(err, "Failed to convert school id", map[string]interface{}{
-// 			"school_id": c.Locals("schoolId"),
-// 		})
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	if err := services.DeletePermittedSchoolStudentWithParents(id, SchoolObjID); err != nil {
-// 		if customErr, ok := err.(*errors.CustomError); ok {
-// 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
-// 		}
-// 		logger.LogError(err, "Failed to delete student", nil)
-// 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
-// 	}
-
-// 	return utils.SuccessResponse(c, "Student deleted successfully", nil)
-// }
\ No newline at end of file
+	return utils.SuccessResponse(c, "Guardian updated successfully", nil)
+}
+
+func (handler *studentHandler) RemoveStudentGuardian(c *fiber.Ctx) error {
+	id := c.Params("id")
+	guardianUUID := c.Params("guardian_id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.studentService.RemoveStudentGuardian(id, guardianUUID, schoolUUID, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to remove student guardian", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Guardian removed successfully", nil)
+}
+
+func isValidSortFieldForStudents(field string) bool {
+	allowedFields := map[string]bool{
+		"student_id":         true,
+		"student_first_name": true,
+		"student_last_name":  true,
+		"student_grade":      true,
+		"created_at":         true,
+	}
+	return allowedFields[field]
+}
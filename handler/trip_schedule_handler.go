@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TripScheduleHandlerInterface interface {
+	GetAllSchedules(c *fiber.Ctx) error
+	GetSpecSchedule(c *fiber.Ctx) error
+	AddSchedule(c *fiber.Ctx) error
+	UpdateSchedule(c *fiber.Ctx) error
+	DeleteSchedule(c *fiber.Ctx) error
+
+	GetSchoolTrips(c *fiber.Ctx) error
+	GetDriverTrips(c *fiber.Ctx) error
+	GenerateTrips(c *fiber.Ctx) error
+}
+
+type tripScheduleHandler struct {
+	tripScheduleService services.TripScheduleService
+}
+
+func NewTripScheduleHttpHandler(tripScheduleService services.TripScheduleService) TripScheduleHandlerInterface {
+	return &tripScheduleHandler{
+		tripScheduleService: tripScheduleService,
+	}
+}
+
+func (handler *tripScheduleHandler) GetAllSchedules(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	schedules, err := handler.tripScheduleService.GetSchedules(schoolUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch trip schedules", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Schedules fetched successfully", schedules)
+}
+
+func (handler *tripScheduleHandler) GetSpecSchedule(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	schedule, err := handler.tripScheduleService.GetSpecSchedule(schoolUUID, id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch trip schedule", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Schedule fetched successfully", schedule)
+}
+
+func (handler *tripScheduleHandler) AddSchedule(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	schedule := new(dto.TripScheduleRequestDTO)
+	if err := c.BodyParser(schedule); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, schedule); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.tripScheduleService.AddSchedule(schoolUUID, *schedule, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add trip schedule", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Schedule created successfully", nil)
+}
+
+func (handler *tripScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	schedule := new(dto.TripScheduleRequestDTO)
+	if err := c.BodyParser(schedule); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, schedule); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.tripScheduleService.UpdateSchedule(schoolUUID, id, *schedule, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update trip schedule", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Schedule updated successfully", nil)
+}
+
+func (handler *tripScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	if err := handler.tripScheduleService.DeleteSchedule(schoolUUID, id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete trip schedule", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Schedule deleted successfully", nil)
+}
+
+func (handler *tripScheduleHandler) GetSchoolTrips(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	trips, err := handler.tripScheduleService.GetTrips(schoolUUID, "", c.Query("date"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch trips", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Trips fetched successfully", trips)
+}
+
+func (handler *tripScheduleHandler) GetDriverTrips(c *fiber.Ctx) error {
+	driverUUID, ok := c.Locals("userUUID").(string)
+	if !ok || driverUUID == "" {
+		return utils.BadRequestResponse(c, "Invalid or missing userUUID", nil)
+	}
+
+	trips, err := handler.tripScheduleService.GetTrips("", driverUUID, c.Query("date"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch driver trips", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Trips fetched successfully", trips)
+}
+
+func (handler *tripScheduleHandler) GenerateTrips(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+	username := c.Locals("user_name").(string)
+
+	req := new(dto.TripGenerationRequestDTO)
+	if err := c.BodyParser(req); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, req); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	result, err := handler.tripScheduleService.GenerateTrips(schoolUUID, *req, username)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to generate trips", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Trips generated successfully", result)
+}
@@ -30,17 +30,23 @@ type UserHandlerInterface interface {
 	DeleteSuperAdmin(c *fiber.Ctx) error
 	DeleteSchoolAdmin(c *fiber.Ctx) error
 	DeleteDriver(c *fiber.Ctx) error
+
+	AddSchoolDriver(c *fiber.Ctx) error
+	UpdateSchoolDriver(c *fiber.Ctx) error
+	DeleteSchoolDriver(c *fiber.Ctx) error
 }
 
 type userHandler struct {
-	userService   services.UserService
-	schoolService services.SchoolService
+	userService       services.UserService
+	schoolService     services.SchoolService
+	permissionService services.PermissionService
 }
 
-func NewUserHttpHandler(userService services.UserService, schoolService services.SchoolService) UserHandlerInterface {
+func NewUserHttpHandler(userService services.UserService, schoolService services.SchoolService, permissionService services.PermissionService) UserHandlerInterface {
 	return &userHandler{
-		userService:   userService,
-		schoolService: schoolService,
+		userService:       userService,
+		schoolService:     schoolService,
+		permissionService: permissionService,
 	}
 }
 
@@ -66,6 +72,13 @@ func (handler *userHandler) GetAllSuperAdmin(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid sort field", nil)
 	}
 
+	format := c.Query("format")
+	if format != "" {
+		return utils.ExportResponse(c, format, "super-admins", func(page, limit int) (interface{}, int, error) {
+			return handler.userService.GetAllSuperAdmin(page, limit, sortField, sortDirection)
+		})
+	}
+
 	users, totalItems, err := handler.userService.GetAllSuperAdmin(page, limit, sortField, sortDirection)
 	if err != nil {
 		logger.LogError(err, "Failed to fetch paginated super admins", nil)
@@ -133,6 +146,13 @@ func (handler *userHandler) GetAllSchoolAdmin(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid sort field", nil)
 	}
 
+	format := c.Query("format")
+	if format != "" {
+		return utils.ExportResponse(c, format, "school-admins", func(page, limit int) (interface{}, int, error) {
+			return handler.userService.GetAllSchoolAdmin(page, limit, sortField, sortDirection)
+		})
+	}
+
 	users, totalItems, err := handler.userService.GetAllSchoolAdmin(page, limit, sortField, sortDirection)
 	if err != nil {
 		logger.LogError(err, "Failed to fetch paginated school admins", nil)
@@ -179,7 +199,9 @@ func (handler *userHandler) GetAllSchoolAdmin(c *fiber.Ctx) error {
 }
 
 func (handler *userHandler) GetAllPermittedDriver(c *fiber.Ctx) error {
-	role := c.Locals("role_code").(string)
+	// Requests coming through the school group carry the school of the admin,
+	// whatever role code they hold, and only see the drivers of that school.
+	schoolUUID, scoped := c.Locals("schoolUUID").(string)
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil || page < 1 {
@@ -202,8 +224,16 @@ func (handler *userHandler) GetAllPermittedDriver(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid sort field", nil)
 	}
 
-	switch role {
-	case "SA":
+	format := c.Query("format")
+
+	switch {
+	case !scoped:
+		if format != "" {
+			return utils.ExportResponse(c, format, "drivers", func(page, limit int) (interface{}, int, error) {
+				return handler.userService.GetAllDriverFromAllSchools(page, limit, sortField, sortDirection)
+			})
+		}
+
 		users, totalItems, err := handler.userService.GetAllDriverFromAllSchools(page, limit, sortField, sortDirection)
 		if err != nil {
 			logger.LogError(err, "Failed to fetch all drivers", nil)
@@ -247,10 +277,11 @@ func (handler *userHandler) GetAllPermittedDriver(c *fiber.Ctx) error {
 		}
 
 		return utils.SuccessResponse(c, "Users fetched successfully", response)
-	case "AS":
-		schoolUUID, ok := c.Locals("schoolUUID").(string)
-		if !ok {
-			return utils.BadRequestResponse(c, "Token is invalid", nil)
+	case schoolUUID != "":
+		if format != "" {
+			return utils.ExportResponse(c, format, "drivers", func(page, limit int) (interface{}, int, error) {
+				return handler.userService.GetAllDriverForPermittedSchool(page, limit, sortField, sortDirection, schoolUUID)
+			})
 		}
 
 		users, totalItems, err := handler.userService.GetAllDriverForPermittedSchool(page, limit, sortField, sortDirection, schoolUUID)
@@ -300,26 +331,24 @@ func (handler *userHandler) GetAllPermittedDriver(c *fiber.Ctx) error {
 
 func (handler *userHandler) GetSpecPermittedDriver(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("role_code").(string)
+	schoolUUID, scoped := c.Locals("schoolUUID").(string)
 
 	var user dto.UserResponseDTO
 	var err error
 
-	switch role {
-	case "SA":
+	switch {
+	case !scoped:
 		user, err = handler.userService.GetSpecDriverFromAllSchools(id)
-	// case "AS":
-	// 	schoolUUID, ok := c.Locals("schoolUUID").(string)
-	// 	if !ok {
-	// 		return utils.BadRequestResponse(c, "Token is invalid", nil)
-	// 	}
-
-	// 	user, err = handler.userService.GetSpecDriverForPermittedSchool(id, schoolUUID)
+	case schoolUUID != "":
+		user, err = handler.userService.GetSpecDriverForPermittedSchool(id, schoolUUID)
 	default:
 		return utils.BadRequestResponse(c, "Invalid role", nil)
 	}
 
 	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
 		logger.LogError(err, "Failed to fetch specific driver", nil)
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
@@ -329,7 +358,7 @@ func (handler *userHandler) GetSpecPermittedDriver(c *fiber.Ctx) error {
 
 func (handler *userHandler) DeleteDriver(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	forceDelete := c.Query("force_delete")
 
@@ -348,7 +377,7 @@ func (handler *userHandler) DeleteDriver(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Warning: This driver is still associated with a school, continue?", nil)
 	}
 
-	err := handler.userService.DeleteDriver(id, username)
+	err := handler.userService.DeleteDriver(id, actor)
 	if err != nil {
 		return utils.InternalServerErrorResponse(c, "Failed to delete driver", nil)
 	}
@@ -356,6 +385,104 @@ func (handler *userHandler) DeleteDriver(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Driver deleted successfully", nil)
 }
 
+func (handler *userHandler) AddSchoolDriver(c *fiber.Ctx) error {
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	userReqDTO := new(dto.UserRequestsDTO)
+	if err := c.BodyParser(userReqDTO); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	userReqDTO.Role = dto.Driver
+
+	if err := utils.ValidateStruct(c, userReqDTO); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := validateUserRoleDetails(c, userReqDTO, *handler); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := utils.ValidateStruct(c, userReqDTO.Details); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	driverUUID, err := handler.userService.AddSchoolDriver(schoolUUID, *userReqDTO, actor)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to create driver", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Driver created successfully", fiber.Map{"user_uuid": driverUUID.String()})
+}
+
+func (handler *userHandler) UpdateSchoolDriver(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	userReqDTO := new(dto.UserRequestsDTO)
+	if err := c.BodyParser(userReqDTO); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	existingUser, err := handler.userService.GetSpecUserWithDetails(id)
+	if err != nil {
+		return utils.NotFoundResponse(c, "Driver not found", nil)
+	}
+
+	userReqDTO.Role = dto.Driver
+	userReqDTO.Password = existingUser.Password
+
+	if err := utils.ValidateStruct(c, userReqDTO); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := validateUserRoleDetails(c, userReqDTO, *handler); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := utils.ValidateStruct(c, userReqDTO.Details); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	detailsMap, err := convertToMap(existingUser.Details)
+	if err != nil {
+		logger.LogError(err, "Failed to convert details to map", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if err := handler.userService.UpdateSchoolDriver(id, schoolUUID, *userReqDTO, actor, detailsMap); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update driver", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Driver updated successfully", nil)
+}
+
+func (handler *userHandler) DeleteSchoolDriver(c *fiber.Ctx) error {
+	id := c.Params("id")
+	actor := auditActor(c)
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	if err := handler.userService.DeleteSchoolDriver(id, schoolUUID, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to deactivate driver", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Driver deactivated successfully", nil)
+}
+
 func (handler *userHandler) GetSpecSuperAdmin(c *fiber.Ctx) error {
 	id := c.Params("id")
 	user, err := handler.userService.GetSpecSuperAdmin(id)
@@ -436,7 +563,7 @@ func (handler *userHandler) GetSpecSchoolAdmin(c *fiber.Ctx) error {
 // }
 
 func (handler *userHandler) AddUser(c *fiber.Ctx) error {
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	userReqDTO := new(dto.UserRequestsDTO)
 	if err := c.BodyParser(userReqDTO); err != nil {
@@ -451,7 +578,7 @@ func (handler *userHandler) AddUser(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
-	if _, err := handler.userService.AddUser(*userReqDTO, username); err != nil {
+	if _, err := handler.userService.AddUser(*userReqDTO, actor); err != nil {
 		if customErr, ok := err.(*errors.CustomError); ok {
 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
 		}
@@ -464,7 +591,7 @@ func (handler *userHandler) AddUser(c *fiber.Ctx) error {
 
 func (handler *userHandler) UpdateUser(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	userReqDTO := new(dto.UserRequestsDTO)
 	if err := c.BodyParser(userReqDTO); err != nil {
@@ -493,7 +620,7 @@ func (handler *userHandler) UpdateUser(c *fiber.Ctx) error {
 		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 	}
 
-	if err := handler.userService.UpdateUser(id, *userReqDTO, username, detailsMap, nil); err != nil {
+	if err := handler.userService.UpdateUser(id, *userReqDTO, actor, detailsMap, nil); err != nil {
 		if customErr, ok := err.(*errors.CustomError); ok {
 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
 		}
@@ -506,9 +633,9 @@ func (handler *userHandler) UpdateUser(c *fiber.Ctx) error {
 
 func (handler *userHandler) DeleteSuperAdmin(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
-	if err := handler.userService.DeleteSuperAdmin(id, username); err != nil {
+	if err := handler.userService.DeleteSuperAdmin(id, actor); err != nil {
 		if customErr, ok := err.(*errors.CustomError); ok {
 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
 		}
@@ -521,7 +648,7 @@ func (handler *userHandler) DeleteSuperAdmin(c *fiber.Ctx) error {
 
 func (handler *userHandler) DeleteSchoolAdmin(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	forceDelete := c.Query("force_delete")
 
@@ -540,7 +667,7 @@ func (handler *userHandler) DeleteSchoolAdmin(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Warning: This school admin is still associated with a school, continue?", nil)
 	}
 
-	err := handler.userService.DeleteSchoolAdmin(id, username)
+	err := handler.userService.DeleteSchoolAdmin(id, actor)
 	if err != nil {
 		return utils.InternalServerErrorResponse(c, "Failed to delete school admin", nil)
 	}
@@ -585,6 +712,8 @@ func (handler *userHandler) DeleteSchoolAdmin(c *fiber.Ctx) error {
 // }
 
 func validateUserRoleDetails(c *fiber.Ctx, user *dto.UserRequestsDTO, handler userHandler) error {
+	requestedRoleCode := strings.ToUpper(user.RoleCode)
+
 	switch user.Role {
 	case dto.SuperAdmin:
 		user.RoleCode = "SA"
@@ -645,6 +774,22 @@ func validateUserRoleDetails(c *fiber.Ctx, user *dto.UserRequestsDTO, handler us
 	default:
 		return errors.New("invalid role specified", 400)
 	}
+
+	// A custom role code may be picked as long as it is built on the same
+	// base role, the base decides which details table the user lives in.
+	if requestedRoleCode != "" && requestedRoleCode != user.RoleCode {
+		base, err := handler.permissionService.GetRoleBase(requestedRoleCode)
+		if err != nil {
+			return err
+		}
+
+		if base != user.Role {
+			return errors.New("role code "+requestedRoleCode+" is not a "+string(user.Role)+" role", 400)
+		}
+
+		user.RoleCode = requestedRoleCode
+	}
+
 	return nil
 }
 
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VehicleAssignmentHandlerInterface interface {
+	GetVehicleAssignments(c *fiber.Ctx) error
+	GetDriverAssignments(c *fiber.Ctx) error
+	AssignVehicle(c *fiber.Ctx) error
+	UnassignVehicle(c *fiber.Ctx) error
+}
+
+type vehicleAssignmentHandler struct {
+	vehicleAssignmentService services.VehicleAssignmentService
+}
+
+func NewVehicleAssignmentHttpHandler(vehicleAssignmentService services.VehicleAssignmentService) VehicleAssignmentHandlerInterface {
+	return &vehicleAssignmentHandler{
+		vehicleAssignmentService: vehicleAssignmentService,
+	}
+}
+
+// ?at=2024-01-31 or ?at=2024-01-31T07:30:00+07:00 answers who drove the vehicle then
+func (handler *vehicleAssignmentHandler) GetVehicleAssignments(c *fiber.Ctx) error {
+	id := c.Params("id")
+	at := c.Query("at")
+
+	assignments, err := handler.vehicleAssignmentService.GetVehicleAssignments(id, at)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch vehicle assignments", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Assignments fetched successfully", assignments)
+}
+
+func (handler *vehicleAssignmentHandler) GetDriverAssignments(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	assignments, err := handler.vehicleAssignmentService.GetDriverAssignments(id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch driver assignments", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Assignments fetched successfully", assignments)
+}
+
+func (handler *vehicleAssignmentHandler) AssignVehicle(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+
+	assignment := new(dto.VehicleAssignmentRequestDTO)
+	if err := c.BodyParser(assignment); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, assignment); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.vehicleAssignmentService.AssignVehicle(*assignment, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to assign vehicle", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Vehicle assigned successfully", nil)
+}
+
+func (handler *vehicleAssignmentHandler) UnassignVehicle(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.vehicleAssignmentService.UnassignVehicle(id, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to unassign vehicle", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Vehicle unassigned successfully", nil)
+}
@@ -20,6 +20,10 @@ type VehicleHandlerInterface interface {
 	AddVehicle(c *fiber.Ctx) error
 	UpdateVehicle(c *fiber.Ctx) error
 	DeleteVehicle(c *fiber.Ctx) error
+
+	GetSchoolVehicles(c *fiber.Ctx) error
+	GetSpecSchoolVehicle(c *fiber.Ctx) error
+	UpdateSchoolVehicle(c *fiber.Ctx) error
 }
 
 type vehicleHandler struct {
@@ -54,6 +58,13 @@ func (handler *vehicleHandler) GetAllVehicles(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, "Invalid sort field", nil)
 	}
 
+	format := c.Query("format")
+	if format != "" {
+		return utils.ExportResponse(c, format, "vehicles", func(page, limit int) (interface{}, int, error) {
+			return handler.vehicleService.GetAllVehicles(page, limit, sortField, sortDirection)
+		})
+	}
+
 	vehicles, totalItems, err := handler.vehicleService.GetAllVehicles(page, limit, sortField, sortDirection)
 	if err != nil {
 		logger.LogError(err, "Failed to fetch paginated vehicle", nil)
@@ -120,7 +131,7 @@ func (handler *vehicleHandler) AddVehicle(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
-	if err := handler.vehicleService.AddVehicle(*vehicle); err != nil {
+	if err := handler.vehicleService.AddVehicle(*vehicle, auditActor(c)); err != nil {
 		if customErr, ok := err.(*errors.CustomError); ok {
 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
 		}
@@ -132,7 +143,7 @@ func (handler *vehicleHandler) AddVehicle(c *fiber.Ctx) error {
 
 func (handler *vehicleHandler) UpdateVehicle(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
 	vehicle := new(dto.VehicleRequestDTO)
 	if err := c.BodyParser(vehicle); err != nil {
@@ -143,7 +154,7 @@ func (handler *vehicleHandler) UpdateVehicle(c *fiber.Ctx) error {
 		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
 	}
 
-	if err := handler.vehicleService.UpdateVehicle(id, *vehicle, username); err != nil {
+	if err := handler.vehicleService.UpdateVehicle(id, *vehicle, actor); err != nil {
 		if customErr, ok := err.(*errors.CustomError); ok {
 			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
 		}
@@ -155,9 +166,9 @@ func (handler *vehicleHandler) UpdateVehicle(c *fiber.Ctx) error {
 
 func (handler *vehicleHandler) DeleteVehicle(c *fiber.Ctx) error {
 	id := c.Params("id")
-	username := c.Locals("user_name").(string)
+	actor := auditActor(c)
 
-	if err := handler.vehicleService.DeleteVehicle(id, username); err != nil {
+	if err := handler.vehicleService.DeleteVehicle(id, actor); err != nil {
 		logger.LogError(err, "Failed to delete vehicle", nil)
 		return utils.ErrorResponse(c, http.StatusInternalServerError, "Something went wrong, please try again later", nil)
 	}
@@ -165,6 +176,117 @@ func (handler *vehicleHandler) DeleteVehicle(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Vehicle deleted successfully", nil)
 }
 
+func (handler *vehicleHandler) GetSchoolVehicles(c *fiber.Ctx) error {
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	sortField := c.Query("sort_by", "vehicle_id")
+	sortDirection := c.Query("direction", "asc")
+
+	if sortDirection != "asc" && sortDirection != "desc" {
+		return utils.BadRequestResponse(c, "Invalid sort direction, use 'asc' or 'desc'", nil)
+	}
+
+	if !isValidSortFieldForVehicles(sortField) {
+		return utils.BadRequestResponse(c, "Invalid sort field", nil)
+	}
+
+	vehicles, totalItems, err := handler.vehicleService.GetSchoolVehicles(schoolUUID, page, limit, sortField, sortDirection)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch school vehicles", map[string]interface{}{"school_uuid": schoolUUID})
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(vehicles) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(vehicles) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": vehicles,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Vehicles fetched successfully", response)
+}
+
+func (handler *vehicleHandler) GetSpecSchoolVehicle(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+
+	vehicle, err := handler.vehicleService.GetSpecSchoolVehicle(id, schoolUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch school vehicle", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Vehicle fetched successfully", vehicle)
+}
+
+// School admins can only change the status and route, creating and moving vehicles stays with super admins
+func (handler *vehicleHandler) UpdateSchoolVehicle(c *fiber.Ctx) error {
+	id := c.Params("id")
+	schoolUUID := c.Locals("schoolUUID").(string)
+	actor := auditActor(c)
+
+	vehicle := new(dto.SchoolVehicleRequestDTO)
+	if err := c.BodyParser(vehicle); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, vehicle); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.vehicleService.UpdateSchoolVehicle(id, schoolUUID, *vehicle, actor); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update school vehicle", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Vehicle updated successfully", nil)
+}
+
 func isValidSortFieldForVehicles(field string) bool {
 	allowedFields := map[string]bool{
 		"vehicle_id":     true,
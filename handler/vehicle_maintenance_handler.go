@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VehicleMaintenanceHandlerInterface interface {
+	GetMaintenances(c *fiber.Ctx) error
+	AddMaintenance(c *fiber.Ctx) error
+	UpdateMaintenance(c *fiber.Ctx) error
+	DeleteMaintenance(c *fiber.Ctx) error
+
+	GetVehicleDocuments(c *fiber.Ctx) error
+	GetVehicleDocumentFile(c *fiber.Ctx) error
+	AddVehicleDocument(c *fiber.Ctx) error
+	UpdateVehicleDocument(c *fiber.Ctx) error
+	DeleteVehicleDocument(c *fiber.Ctx) error
+}
+
+type vehicleMaintenanceHandler struct {
+	vehicleMaintenanceService services.VehicleMaintenanceService
+}
+
+func NewVehicleMaintenanceHttpHandler(vehicleMaintenanceService services.VehicleMaintenanceService) VehicleMaintenanceHandlerInterface {
+	return &vehicleMaintenanceHandler{
+		vehicleMaintenanceService: vehicleMaintenanceService,
+	}
+}
+
+func (handler *vehicleMaintenanceHandler) GetMaintenances(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	maintenances, err := handler.vehicleMaintenanceService.GetMaintenances(id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch vehicle maintenances", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Maintenance records fetched successfully", maintenances)
+}
+
+func (handler *vehicleMaintenanceHandler) AddMaintenance(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+
+	maintenance := new(dto.VehicleMaintenanceRequestDTO)
+	if err := c.BodyParser(maintenance); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, maintenance); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.vehicleMaintenanceService.AddMaintenance(id, *maintenance, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add vehicle maintenance", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Maintenance record added successfully", nil)
+}
+
+func (handler *vehicleMaintenanceHandler) UpdateMaintenance(c *fiber.Ctx) error {
+	id := c.Params("id")
+	maintenanceUUID := c.Params("maintenance_id")
+	username := c.Locals("user_name").(string)
+
+	maintenance := new(dto.VehicleMaintenanceRequestDTO)
+	if err := c.BodyParser(maintenance); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, maintenance); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.vehicleMaintenanceService.UpdateMaintenance(id, maintenanceUUID, *maintenance, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update vehicle maintenance", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Maintenance record updated successfully", nil)
+}
+
+func (handler *vehicleMaintenanceHandler) DeleteMaintenance(c *fiber.Ctx) error {
+	id := c.Params("id")
+	maintenanceUUID := c.Params("maintenance_id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.vehicleMaintenanceService.DeleteMaintenance(id, maintenanceUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete vehicle maintenance", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Maintenance record deleted successfully", nil)
+}
+
+func (handler *vehicleMaintenanceHandler) GetVehicleDocuments(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	documents, err := handler.vehicleMaintenanceService.GetVehicleDocuments(id)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch vehicle documents", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Documents fetched successfully", documents)
+}
+
+func (handler *vehicleMaintenanceHandler) GetVehicleDocumentFile(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+
+	path, err := handler.vehicleMaintenanceService.GetVehicleDocumentFile(id, documentUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch vehicle document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if err := c.SendFile(path); err != nil {
+		logger.LogError(err, "Failed to send vehicle document", map[string]interface{}{"document_uuid": documentUUID})
+		return utils.NotFoundResponse(c, "Document file not found", nil)
+	}
+
+	return nil
+}
+
+func (handler *vehicleMaintenanceHandler) AddVehicleDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	username := c.Locals("user_name").(string)
+
+	document := new(dto.VehicleDocumentRequestDTO)
+	if err := c.BodyParser(document); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, document); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	file, err := utils.HandleUploadedDocument(c, "file")
+	if err == nil {
+		err = handler.vehicleMaintenanceService.AddVehicleDocument(id, *document, file, username)
+	}
+
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add vehicle document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document added successfully", nil)
+}
+
+// The file is optional here, the stored scan is kept when none is sent
+func (handler *vehicleMaintenanceHandler) UpdateVehicleDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+	username := c.Locals("user_name").(string)
+
+	document := new(dto.VehicleDocumentRequestDTO)
+	if err := c.BodyParser(document); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, document); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	var file string
+	var err error
+	if _, formErr := c.FormFile("file"); formErr == nil {
+		file, err = utils.HandleUploadedDocument(c, "file")
+	}
+
+	if err == nil {
+		err = handler.vehicleMaintenanceService.UpdateVehicleDocument(id, documentUUID, *document, file, username)
+	}
+
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update vehicle document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document updated successfully", nil)
+}
+
+func (handler *vehicleMaintenanceHandler) DeleteVehicleDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	documentUUID := c.Params("document_id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.vehicleMaintenanceService.DeleteVehicleDocument(id, documentUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete vehicle document", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Document deleted successfully", nil)
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/services"
+	"shuttle/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandlerInterface interface {
+	GetAllEndpoints(c *fiber.Ctx) error
+	GetSpecEndpoint(c *fiber.Ctx) error
+	AddEndpoint(c *fiber.Ctx) error
+	UpdateEndpoint(c *fiber.Ctx) error
+	DeleteEndpoint(c *fiber.Ctx) error
+
+	GetDeliveries(c *fiber.Ctx) error
+	RedeliverDelivery(c *fiber.Ctx) error
+}
+
+type webhookHandler struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookHttpHandler(webhookService services.WebhookService) WebhookHandlerInterface {
+	return &webhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (handler *webhookHandler) GetAllEndpoints(c *fiber.Ctx) error {
+	endpoints, err := handler.webhookService.GetEndpoints(profileSchoolUUID(c))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch webhook endpoints", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook endpoints fetched successfully", endpoints)
+}
+
+func (handler *webhookHandler) GetSpecEndpoint(c *fiber.Ctx) error {
+	endpointUUID := c.Params("endpoint_id")
+
+	endpoint, err := handler.webhookService.GetSpecEndpoint(profileSchoolUUID(c), endpointUUID)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch webhook endpoint", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook endpoint fetched successfully", endpoint)
+}
+
+func (handler *webhookHandler) AddEndpoint(c *fiber.Ctx) error {
+	username := c.Locals("user_name").(string)
+
+	endpoint := new(dto.WebhookEndpointRequestDTO)
+	if err := c.BodyParser(endpoint); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, endpoint); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	created, err := handler.webhookService.AddEndpoint(profileSchoolUUID(c), *endpoint, username)
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to add webhook endpoint", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook endpoint created successfully, store the secret now as it is not shown again", created)
+}
+
+func (handler *webhookHandler) UpdateEndpoint(c *fiber.Ctx) error {
+	endpointUUID := c.Params("endpoint_id")
+	username := c.Locals("user_name").(string)
+
+	endpoint := new(dto.WebhookEndpointRequestDTO)
+	if err := c.BodyParser(endpoint); err != nil {
+		return utils.BadRequestResponse(c, "Invalid request data", nil)
+	}
+
+	if err := utils.ValidateStruct(c, endpoint); err != nil {
+		return utils.BadRequestResponse(c, strings.ToUpper(err.Error()[0:1])+err.Error()[1:], nil)
+	}
+
+	if err := handler.webhookService.UpdateEndpoint(profileSchoolUUID(c), endpointUUID, *endpoint, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to update webhook endpoint", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook endpoint updated successfully", nil)
+}
+
+func (handler *webhookHandler) DeleteEndpoint(c *fiber.Ctx) error {
+	endpointUUID := c.Params("endpoint_id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.webhookService.DeleteEndpoint(profileSchoolUUID(c), endpointUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to delete webhook endpoint", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook endpoint deleted successfully", nil)
+}
+
+func (handler *webhookHandler) GetDeliveries(c *fiber.Ctx) error {
+	endpointUUID := c.Params("endpoint_id")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return utils.BadRequestResponse(c, "Invalid page number", nil)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 {
+		return utils.BadRequestResponse(c, "Invalid limit number", nil)
+	}
+
+	deliveries, totalItems, err := handler.webhookService.GetDeliveries(profileSchoolUUID(c), endpointUUID, page, limit, c.Query("status"))
+	if err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to fetch paginated webhook deliveries", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	if page > totalPages {
+		if totalItems > 0 {
+			return utils.BadRequestResponse(c, "Page number out of range", nil)
+		} else {
+			page = 1
+		}
+	}
+
+	start := (page-1)*limit + 1
+	if totalItems == 0 || start > totalItems {
+		start = 0
+	}
+
+	end := start + len(deliveries) - 1
+	if end > totalItems {
+		end = totalItems
+	}
+
+	if len(deliveries) == 0 {
+		start = 0
+		end = 0
+	}
+
+	response := fiber.Map{
+		"data": deliveries,
+		"meta": fiber.Map{
+			"current_page":   page,
+			"total_pages":    totalPages,
+			"per_page_items": limit,
+			"total_items":    totalItems,
+			"showing":        fmt.Sprintf("Showing %d-%d of %d", start, end, totalItems),
+		},
+	}
+
+	return utils.SuccessResponse(c, "Webhook deliveries fetched successfully", response)
+}
+
+func (handler *webhookHandler) RedeliverDelivery(c *fiber.Ctx) error {
+	endpointUUID := c.Params("endpoint_id")
+	deliveryUUID := c.Params("delivery_id")
+	username := c.Locals("user_name").(string)
+
+	if err := handler.webhookService.RedeliverDelivery(profileSchoolUUID(c), endpointUUID, deliveryUUID, username); err != nil {
+		if customErr, ok := err.(*errors.CustomError); ok {
+			return utils.ErrorResponse(c, customErr.StatusCode, strings.ToUpper(string(customErr.Message[0]))+customErr.Message[1:], nil)
+		}
+		logger.LogError(err, "Failed to redeliver webhook delivery", nil)
+		return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	return utils.SuccessResponse(c, "Webhook delivery queued for redelivery", nil)
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"shuttle/errors"
 	"shuttle/logger"
 	"shuttle/utils"
 	"shuttle/services"
@@ -78,30 +79,97 @@ func AuthenticationMiddleware() fiber.Handler {
 		c.Locals("role_code", role_code)
 		c.Locals("user_name", user_name)
 
+		// Impersonation tokens name the admin behind the request in the act claim
+		if actor, ok := claims["act"].(map[string]interface{}); ok {
+			actorUUID, _ := actor["sub"].(string)
+			actorName, _ := actor["user_name"].(string)
+			impersonationUUID, _ := claims["sid"].(string)
+			if actorUUID == "" || impersonationUUID == "" {
+				logger.LogWarn("Impersonation claims are missing or invalid", map[string]interface{}{"claims": claims})
+				return utils.UnauthorizedResponse(c, "Token is invalid", nil)
+			}
+
+			c.Locals("actorUUID", actorUUID)
+			c.Locals("actorName", actorName)
+			c.Locals("impersonationUUID", impersonationUUID)
+		}
+
 		return c.Next()
 	}
 }
 
-func AuthorizationMiddleware(allowedRoles []string) fiber.Handler {
+// ImpersonationMiddleware rejects tokens of ended impersonation sessions and
+// records every request made under one.
+func ImpersonationMiddleware(service services.ImpersonationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		impersonationUUID, ok := c.Locals("impersonationUUID").(string)
+		if !ok {
+			return c.Next()
+		}
+
+		if err := service.CheckActiveImpersonation(impersonationUUID); err != nil {
+			if _, ok := err.(*errors.CustomError); ok {
+				return utils.UnauthorizedResponse(c, "Impersonation session has ended", nil)
+			}
+			logger.LogError(err, "Failed to check impersonation session", map[string]interface{}{"impersonation_uuid": impersonationUUID})
+			return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+		}
+
+		err := c.Next()
+
+		logger.LogInfo("Impersonated request", map[string]interface{}{
+			"impersonation_uuid": impersonationUUID,
+			"actor_uuid":         c.Locals("actorUUID"),
+			"actor_name":         c.Locals("actorName"),
+			"user_uuid":          c.Locals("userUUID"),
+			"method":             c.Method(),
+			"path":               c.OriginalURL(),
+			"status":             c.Response().StatusCode(),
+		})
+
+		if recordErr := service.RecordImpersonatedRequest(impersonationUUID, c.Method(), c.OriginalURL(), c.Response().StatusCode()); recordErr != nil {
+			logger.LogError(recordErr, "Failed to record impersonated request", map[string]interface{}{"impersonation_uuid": impersonationUUID})
+		}
+
+		return err
+	}
+}
+
+// PermissionMiddleware resolves the permissions granted to the role code
+// carried by the token and keeps them in the request locals.
+func PermissionMiddleware(service services.PermissionService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		role_code, ok := c.Locals("role_code").(string)
 		if !ok || role_code == "" {
 			return utils.UnauthorizedResponse(c, "Role code is missing or invalid", nil)
 		}
 
-		if !contains(allowedRoles, role_code) {
-			return utils.ForbiddenResponse(c, "You don't have permission to access this resource", nil)
+		permissions, err := service.GetRolePermissions(role_code)
+		if err != nil {
+			if _, ok := err.(*errors.CustomError); ok {
+				return utils.ForbiddenResponse(c, "You don't have permission to access this resource", nil)
+			}
+			logger.LogError(err, "Failed to resolve role permissions", map[string]interface{}{"role_code": role_code})
+			return utils.InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
 		}
 
+		c.Locals("permissions", permissions)
+
 		return c.Next()
 	}
 }
 
-func contains(slice []string, item string) bool {
-	for _, a := range slice {
-		if a == item {
-			return true
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		permissions, ok := c.Locals("permissions").(map[string]struct{})
+		if !ok {
+			return utils.UnauthorizedResponse(c, "Role code is missing or invalid", nil)
+		}
+
+		if _, granted := permissions[permission]; !granted {
+			return utils.ForbiddenResponse(c, "You don't have permission to access this resource", nil)
 		}
+
+		return c.Next()
 	}
-	return false
 }
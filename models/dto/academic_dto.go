@@ -0,0 +1,89 @@
+package dto
+
+// Dates are sent and returned as YYYY-MM-DD
+type AcademicYearRequestDTO struct {
+	Name      string                   `json:"academic_year_name" validate:"required,max=50"`
+	StartsOn  string                   `json:"starts_on" validate:"required"`
+	EndsOn    string                   `json:"ends_on" validate:"required"`
+	IsCurrent bool                     `json:"is_current"`
+	Terms     []AcademicTermRequestDTO `json:"terms" validate:"omitempty,dive"`
+}
+
+type AcademicTermRequestDTO struct {
+	Name     string `json:"term_name" validate:"required,max=50"`
+	StartsOn string `json:"starts_on" validate:"required"`
+	EndsOn   string `json:"ends_on" validate:"required"`
+}
+
+type AcademicYearResponseDTO struct {
+	UUID      string                    `json:"academic_year_uuid"`
+	Name      string                    `json:"academic_year_name"`
+	StartsOn  string                    `json:"starts_on"`
+	EndsOn    string                    `json:"ends_on"`
+	IsCurrent bool                      `json:"is_current"`
+	Terms     []AcademicTermResponseDTO `json:"terms"`
+	CreatedAt string                    `json:"created_at,omitempty"`
+	CreatedBy string                    `json:"created_by,omitempty"`
+	UpdatedAt string                    `json:"updated_at,omitempty"`
+	UpdatedBy string                    `json:"updated_by,omitempty"`
+}
+
+type AcademicTermResponseDTO struct {
+	UUID     string `json:"term_uuid"`
+	Name     string `json:"term_name"`
+	StartsOn string `json:"starts_on"`
+	EndsOn   string `json:"ends_on"`
+}
+
+type GradeLevelRequestDTO struct {
+	Name  string `json:"grade_name" validate:"required,max=10"`
+	Order int    `json:"grade_order" validate:"required,min=1"`
+}
+
+type GradeLevelResponseDTO struct {
+	UUID      string `json:"grade_level_uuid"`
+	Name      string `json:"grade_name"`
+	Order     int    `json:"grade_order"`
+	IsFinal   bool   `json:"is_final"`
+	Students  int    `json:"students"`
+	CreatedAt string `json:"created_at,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+type PromotionRequestDTO struct {
+	ToAcademicYearUUID string `json:"to_academic_year_uuid" validate:"required,uuid4"`
+}
+
+type PromotionPreviewDTO struct {
+	ToAcademicYearUUID string                `json:"to_academic_year_uuid"`
+	Promoted           int                   `json:"promoted"`
+	Graduated          int                   `json:"graduated"`
+	Unmatched          int                   `json:"unmatched"` // Students whose grade is not one of the grade levels, they are left as they are
+	Students           []PromotionStudentDTO `json:"students"`
+}
+
+type PromotionStudentDTO struct {
+	StudentUUID string `json:"student_uuid"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	FromGrade   string `json:"from_grade"`
+	ToGrade     string `json:"to_grade,omitempty"`
+	Action      string `json:"action"` // promote, graduate or skip
+}
+
+type PromotionResponseDTO struct {
+	UUID                 string `json:"promotion_uuid"`
+	FromAcademicYearUUID string `json:"from_academic_year_uuid,omitempty"`
+	ToAcademicYearUUID   string `json:"to_academic_year_uuid"`
+	Status               string `json:"status"`
+	Promoted             int    `json:"promoted"`
+	Graduated            int    `json:"graduated"`
+	AppliedAt            string `json:"applied_at"`
+	AppliedBy            string `json:"applied_by,omitempty"`
+	RollbackUntil        string `json:"rollback_until"`
+	CanRollback          bool   `json:"can_rollback"`
+	RolledBackAt         string `json:"rolled_back_at,omitempty"`
+	RolledBackBy         string `json:"rolled_back_by,omitempty"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+// target_uuid is the route, grade level or trip, it is left out for the whole school. publish_at
+// defaults to now and expires_at to never, both are RFC 3339 timestamps.
+type AnnouncementRequestDTO struct {
+	TargetType string `json:"target_type" validate:"required,oneof=school route grade trip"`
+	TargetUUID string `json:"target_uuid" validate:"omitempty,uuid"`
+	Title      string `json:"announcement_title" validate:"required,max=150"`
+	Body       string `json:"announcement_body" validate:"required,max=2000"`
+	PublishAt  string `json:"publish_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type AnnouncementResponseDTO struct {
+	UUID           string  `json:"announcement_uuid"`
+	TargetType     string  `json:"target_type"`
+	TargetUUID     string  `json:"target_uuid,omitempty"`
+	Title          string  `json:"announcement_title"`
+	Body           string  `json:"announcement_body"`
+	Status         string  `json:"announcement_status"`
+	PublishAt      string  `json:"publish_at"`
+	ExpiresAt      string  `json:"expires_at,omitempty"`
+	PublishedAt    string  `json:"published_at,omitempty"`
+	RecipientCount int     `json:"recipient_count"`
+	ReadCount      int     `json:"read_count"`
+	ReadRate       float64 `json:"read_rate"`
+	CreatedAt      string  `json:"created_at,omitempty"`
+	CreatedBy      string  `json:"created_by,omitempty"`
+	UpdatedAt      string  `json:"updated_at,omitempty"`
+	UpdatedBy      string  `json:"updated_by,omitempty"`
+}
+
+// What a guardian sees of an announcement sent to them
+type MyAnnouncementResponseDTO struct {
+	UUID        string `json:"announcement_uuid"`
+	Title       string `json:"announcement_title"`
+	Body        string `json:"announcement_body"`
+	PublishedAt string `json:"published_at"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	Read        bool   `json:"read"`
+	ReadAt      string `json:"read_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "encoding/json"
+
+type AuditLogResponseDTO struct {
+	UUID             string          `json:"audit_uuid"`
+	ActorUUID        string          `json:"actor_uuid,omitempty"`
+	ActorRoleCode    string          `json:"actor_role_code,omitempty"`
+	ActorUsername    string          `json:"actor_username,omitempty"`
+	ImpersonatorUUID string          `json:"impersonator_uuid,omitempty"`
+	Action           string          `json:"audit_action"`
+	EntityType       string          `json:"entity_type"`
+	EntityUUID       string          `json:"entity_uuid,omitempty"`
+	Before           json.RawMessage `json:"before"`
+	After            json.RawMessage `json:"after"`
+	IPAddress        string          `json:"ip_address,omitempty"`
+	UserAgent        string          `json:"user_agent,omitempty"`
+	RequestID        string          `json:"request_id,omitempty"`
+	CreatedAt        string          `json:"created_at"`
+}
+
+// Filters of the audit log list, from and to are dates and both days are included
+type AuditLogQueryDTO struct {
+	EntityType string
+	EntityUUID string
+	ActorUUID  string
+	Action     string
+	From       string
+	To         string
+}
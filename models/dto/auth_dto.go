@@ -12,3 +12,12 @@ type UserDataOnLoginDTO struct {
 	RoleCode  string `json:"user_role_code"`
 	Password  string `json:"user_password"`
 }
+
+type OTPLoginRequestDTO struct {
+	Phone string `json:"phone" validate:"required,phone"`
+}
+
+type OTPVerifyRequestDTO struct {
+	Phone string `json:"phone" validate:"required,phone"`
+	Code  string `json:"code" validate:"required,len=6,numeric"`
+}
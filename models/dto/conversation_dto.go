@@ -0,0 +1,52 @@
+package dto
+
+// A conversation is about a student, a trip or a student on a trip
+type ConversationRequestDTO struct {
+	StudentUUID    string   `json:"student_uuid" validate:"omitempty,uuid"`
+	TripUUID       string   `json:"trip_uuid" validate:"omitempty,uuid"`
+	RecipientUUIDs []string `json:"recipient_uuids" validate:"required,min=1,max=20,dive,uuid"`
+	Body           string   `json:"message_body" validate:"required,max=2000"`
+}
+
+type MessageRequestDTO struct {
+	Body string `json:"message_body" validate:"required,max=2000"`
+}
+
+type ConversationParticipantDTO struct {
+	UserUUID   string `json:"user_uuid"`
+	Name       string `json:"user_name"`
+	RoleCode   string `json:"role_code"`
+	LastReadAt string `json:"last_read_at,omitempty"`
+}
+
+type ConversationResponseDTO struct {
+	UUID          string                       `json:"conversation_uuid"`
+	StudentUUID   string                       `json:"student_uuid,omitempty"`
+	StudentName   string                       `json:"student_name,omitempty"`
+	TripUUID      string                       `json:"trip_uuid,omitempty"`
+	Participants  []ConversationParticipantDTO `json:"participants"`
+	UnreadCount   int                          `json:"unread_count"`
+	LastMessageAt string                       `json:"last_message_at"`
+	CreatedAt     string                       `json:"created_at,omitempty"`
+	CreatedBy     string                       `json:"created_by,omitempty"`
+}
+
+// ReadBy lists the other participants that have read up to the message
+type MessageResponseDTO struct {
+	UUID             string   `json:"message_uuid"`
+	ConversationUUID string   `json:"conversation_uuid"`
+	SenderUUID       string   `json:"sender_uuid,omitempty"`
+	SenderName       string   `json:"sender_name,omitempty"`
+	Body             string   `json:"message_body"`
+	IsDeleted        bool     `json:"is_deleted"`
+	ReadBy           []string `json:"read_by"`
+	CreatedAt        string   `json:"created_at"`
+	DeletedBy        string   `json:"deleted_by,omitempty"`
+}
+
+type ConversationContactDTO struct {
+	UserUUID string `json:"user_uuid"`
+	Name     string `json:"user_name"`
+	RoleCode string `json:"role_code"`
+	Relation string `json:"relation"`
+}
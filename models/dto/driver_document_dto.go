@@ -0,0 +1,23 @@
+package dto
+
+// Sent as multipart form data together with the scanned file, dates are YYYY-MM-DD
+type DriverDocumentRequestDTO struct {
+	Type      string `json:"document_type" form:"document_type" validate:"required,document_type"`
+	Number    string `json:"document_number" form:"document_number" validate:"required,max=100"`
+	IssuedOn  string `json:"issued_on" form:"issued_on" validate:"required"`
+	ExpiresOn string `json:"expires_on" form:"expires_on"`
+}
+
+type DriverDocumentResponseDTO struct {
+	UUID       string `json:"document_uuid"`
+	DriverUUID string `json:"driver_uuid"`
+	Type       string `json:"document_type"`
+	Number     string `json:"document_number"`
+	IssuedOn   string `json:"issued_on"`
+	ExpiresOn  string `json:"expires_on,omitempty"`
+	Expired    bool   `json:"expired"`
+	CreatedAt  string `json:"created_at,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+	UpdatedBy  string `json:"updated_by,omitempty"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+type ImpersonationRequestDTO struct {
+	Reason string `json:"reason" validate:"omitempty,max=255"`
+}
+
+type ImpersonationResponseDTO struct {
+	AccessToken       string `json:"access_token"`
+	ImpersonationUUID string `json:"impersonation_uuid"`
+	ExpiresAt         string `json:"expires_at"`
+}
+
+type ImpersonatorDTO struct {
+	UUID              string `json:"user_uuid"`
+	Username          string `json:"user_username"`
+	ImpersonationUUID string `json:"impersonation_uuid"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "encoding/json"
+
+type JobResponseDTO struct {
+	UUID        string          `json:"job_uuid"`
+	Name        string          `json:"job_name"`
+	Payload     json.RawMessage `json:"job_payload"`
+	Status      string          `json:"job_status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       string          `json:"run_at"`
+	LockedAt    string          `json:"locked_at,omitempty"`
+	LockedBy    string          `json:"locked_by,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	FinishedAt  string          `json:"finished_at,omitempty"`
+	CreatedAt   string          `json:"created_at,omitempty"`
+	CreatedBy   string          `json:"created_by,omitempty"`
+}
+
+type JobScheduleResponseDTO struct {
+	Name          string `json:"schedule_name"`
+	Spec          string `json:"schedule_spec"`
+	NextRunAt     string `json:"next_run_at"`
+	LastRunAt     string `json:"last_run_at,omitempty"`
+	LastJobUUID   string `json:"last_job_uuid,omitempty"`
+	LastJobStatus string `json:"last_job_status,omitempty"`
+}
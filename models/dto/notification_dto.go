@@ -0,0 +1,47 @@
+package dto
+
+import "encoding/json"
+
+type NotificationResponseDTO struct {
+	UUID      string          `json:"notification_uuid"`
+	Event     string          `json:"event"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data"`
+	Read      bool            `json:"read"`
+	ReadAt    string          `json:"read_at,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// Sent over the WebSocket connection when a notification arrives
+type NotificationMessageDTO struct {
+	Type         string                  `json:"type"`
+	Notification NotificationResponseDTO `json:"notification"`
+	UnreadCount  int                     `json:"unread_count"`
+}
+
+type NotificationPreferenceDTO struct {
+	Event   string `json:"event" validate:"required"`
+	Channel string `json:"channel" validate:"required,oneof=inbox websocket push"`
+	Enabled *bool  `json:"enabled" validate:"required"`
+}
+
+type NotificationPreferencesRequestDTO struct {
+	Language    string                      `json:"language" validate:"omitempty,oneof=id en"`
+	Preferences []NotificationPreferenceDTO `json:"preferences" validate:"dive"`
+}
+
+type NotificationPreferencesResponseDTO struct {
+	Language    string                      `json:"language"`
+	Preferences []NotificationPreferenceDTO `json:"preferences"`
+}
+
+type PushDeviceRequestDTO struct {
+	Token    string `json:"device_token" validate:"required,max=4096"`
+	Platform string `json:"device_platform" validate:"required,oneof=android ios web"`
+}
+
+type PushDeviceResponseDTO struct {
+	UUID     string `json:"device_uuid"`
+	Platform string `json:"device_platform"`
+}
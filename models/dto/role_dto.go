@@ -0,0 +1,27 @@
+package dto
+
+type AccessRoleRequestDTO struct {
+	Code        string   `json:"role_code" validate:"required,alphanum,max=5"`
+	Name        string   `json:"role_name" validate:"required,max=50"`
+	Base        Role     `json:"role_base" validate:"required,role"`
+	Description string   `json:"role_description" validate:"omitempty,max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type AccessRoleResponseDTO struct {
+	Code        string   `json:"role_code"`
+	Name        string   `json:"role_name"`
+	Base        Role     `json:"role_base"`
+	Description string   `json:"role_description,omitempty"`
+	IsSystem    bool     `json:"is_system"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	CreatedBy   string   `json:"created_by,omitempty"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+	UpdatedBy   string   `json:"updated_by,omitempty"`
+}
+
+type PermissionResponseDTO struct {
+	Code        string `json:"permission_code"`
+	Description string `json:"permission_description,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+type RoutePointDTO struct {
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+}
+
+type RouteRequestDTO struct {
+	Name        string          `json:"route_name" validate:"required,max=100"`
+	Description string          `json:"route_description" validate:"max=1000"`
+	Points      []RoutePointDTO `json:"points" validate:"required,min=2,dive"`
+	Status      string          `json:"route_status" validate:"omitempty,oneof=active inactive"`
+}
+
+type RouteResponseDTO struct {
+	UUID         string          `json:"route_uuid"`
+	Name         string          `json:"route_name"`
+	Description  string          `json:"route_description,omitempty"`
+	Points       []RoutePointDTO `json:"points"`
+	Status       string          `json:"route_status"`
+	VehicleCount int             `json:"vehicle_count"`
+	CreatedAt    string          `json:"created_at,omitempty"`
+	CreatedBy    string          `json:"created_by,omitempty"`
+	UpdatedAt    string          `json:"updated_at,omitempty"`
+	UpdatedBy    string          `json:"updated_by,omitempty"`
+}
@@ -22,3 +22,60 @@ type SchoolResponseDTO struct {
 	UpdatedAt      string `json:"updated_at,omitempty"`
 	UpdatedBy      string `json:"updated_by,omitempty"`
 }
+
+type SchoolDeletionPlanResponseDTO struct {
+	SchoolAdmins       int `json:"school_admins"`
+	Drivers            int `json:"drivers"`
+	Students           int `json:"students"`
+	Parents            int `json:"parents"`
+	Vehicles           int `json:"vehicles"`
+	VehicleAssignments int `json:"vehicle_assignments"`
+	Routes             int `json:"routes"`
+	AcademicYears      int `json:"academic_years"`
+	GradeLevels        int `json:"grade_levels"`
+	Total              int `json:"total"`
+}
+
+// The geofence radius is in meters, timezone is an IANA name like Asia/Jakarta
+type SchoolProfileRequestDTO struct {
+	Latitude       *float64                 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude      *float64                 `json:"longitude" validate:"required,min=-180,max=180"`
+	GeofenceRadius int                      `json:"geofence_radius" validate:"required,min=10,max=5000"`
+	Timezone       string                   `json:"timezone" validate:"required,max=64"`
+	OperatingHours []SchoolOperatingHourDTO `json:"operating_hours" validate:"omitempty,dive"`
+}
+
+// Times are HH:MM, days without hours are days off
+type SchoolOperatingHourDTO struct {
+	Weekday  string `json:"weekday" validate:"required,oneof=monday tuesday wednesday thursday friday saturday sunday"`
+	StartsAt string `json:"starts_at" validate:"required"`
+	EndsAt   string `json:"ends_at" validate:"required"`
+}
+
+type SchoolProfileResponseDTO struct {
+	UUID           string                   `json:"school_uuid"`
+	Name           string                   `json:"school_name"`
+	Latitude       *float64                 `json:"latitude"`
+	Longitude      *float64                 `json:"longitude"`
+	GeofenceRadius int                      `json:"geofence_radius"`
+	Timezone       string                   `json:"timezone"`
+	OperatingHours []SchoolOperatingHourDTO `json:"operating_hours"`
+}
+
+// Dates are sent and returned as YYYY-MM-DD, both ends included
+type SchoolClosureRequestDTO struct {
+	Name     string `json:"closure_name" validate:"required,max=100"`
+	StartsOn string `json:"starts_on" validate:"required"`
+	EndsOn   string `json:"ends_on" validate:"required"`
+}
+
+type SchoolClosureResponseDTO struct {
+	UUID      string `json:"closure_uuid"`
+	Name      string `json:"closure_name"`
+	StartsOn  string `json:"starts_on"`
+	EndsOn    string `json:"ends_on"`
+	CreatedAt string `json:"created_at,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
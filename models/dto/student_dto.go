@@ -1,8 +1,56 @@
 package dto
 
 type AddStudentWithParentRequestDTO struct {
-	Student StudentRequestDTO `json:"student"` // Information about the student
-	Parent  UserRequestsDTO   `json:"parent"`  // Information about the parent
+	Student   StudentRequestDTO    `json:"student"`                                 // Information about the student
+	Parent    *UserRequestsDTO     `json:"parent,omitempty"`                        // Information about the parent
+	Guardians []GuardianRequestDTO `json:"guardians,omitempty" validate:"omitempty,dive"` // Every guardian of the student, the parent above is the first one
+}
+
+// An existing parent is picked by parent_uuid, email or phone, otherwise a new parent account is created
+// from the parent field
+type GuardianRequestDTO struct {
+	ParentUUID   string           `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"`
+	Email        string           `json:"email,omitempty" validate:"omitempty,email"`
+	Phone        string           `json:"phone,omitempty" validate:"omitempty,phone"`
+	Parent       *UserRequestsDTO `json:"parent,omitempty"`
+	Relationship string           `json:"relationship" validate:"required,relationship"`
+	CanPickup    *bool            `json:"can_pickup,omitempty"`
+	IsPrimary    bool             `json:"is_primary"`
+	// Set by the import when the parent username was derived from the email, a taken one then gets a suffix
+	GeneratedUsername bool `json:"-"`
+}
+
+type StudentImportResultDTO struct {
+	DryRun         bool                       `json:"dry_run"`
+	TotalRows      int                        `json:"total_rows"`
+	ImportedRows   int                        `json:"imported_rows"`
+	FailedRows     int                        `json:"failed_rows"`
+	CreatedParents int                        `json:"created_parents"`
+	LinkedParents  int                        `json:"linked_parents"`
+	Errors         []StudentImportRowErrorDTO `json:"errors"`
+}
+
+type StudentImportRowErrorDTO struct {
+	Row     int    `json:"row"` // Line number in the uploaded file, the header is line 1
+	Message string `json:"message"`
+}
+
+type GuardianUpdateRequestDTO struct {
+	Relationship string `json:"relationship" validate:"required,relationship"`
+	CanPickup    *bool  `json:"can_pickup" validate:"required"`
+	IsPrimary    bool   `json:"is_primary"`
+}
+
+type GuardianResponseDTO struct {
+	ParentUUID   string `json:"parent_uuid"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Address      string `json:"address"`
+	Relationship string `json:"relationship"`
+	CanPickup    bool   `json:"can_pickup"`
+	IsPrimary    bool   `json:"is_primary"`
 }
 
 type StudentRequestDTO struct {
@@ -10,8 +58,8 @@ type StudentRequestDTO struct {
 	LastName   string `json:"last_name" validate:"required,max=255"`
 	Grade      string `json:"grade" validate:"required,max=50"`
 	Gender     string `json:"gender" validate:"required,max=50"`
-	ParentUUID string `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"` // For linking existing parent
-	SchoolUUID string `json:"school_uuid" validate:"required,uuid4"`
+	ParentUUID string `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"` // Links an existing parent when adding, guardians have their own endpoints afterwards
+	SchoolUUID string `json:"school_uuid,omitempty" validate:"omitempty,uuid4"` // Always replaced by the school of the admin
 }
 
 type StudentResponseDTO struct {
@@ -23,6 +71,7 @@ type StudentResponseDTO struct {
 	ParentUUID    string `json:"parent_uuid,omitempty"`
 	SchoolUUID    string `json:"school_uuid"`
 	SchoolName    string `json:"school_name,omitempty"`
+	Guardians     []GuardianResponseDTO `json:"guardians,omitempty"`
 	CreatedAt     string `json:"created_at,omitempty"`
 	CreatedBy     string `json:"created_by,omitempty"`
 	UpdatedAt     string `json:"updated_at,omitempty"`
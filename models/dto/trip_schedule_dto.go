@@ -0,0 +1,71 @@
+package dto
+
+// Dates are YYYY-MM-DD and the departure time HH:MM in the school's timezone
+type TripScheduleRequestDTO struct {
+	RouteUUID     string   `json:"route_uuid" validate:"required,uuid4"`
+	VehicleUUID   string   `json:"vehicle_uuid" validate:"required,uuid4"`
+	DriverUUID    string   `json:"driver_uuid" validate:"required,uuid4"`
+	Direction     string   `json:"direction" validate:"required,oneof=to_school to_home"`
+	DepartureTime string   `json:"departure_time" validate:"required"`
+	Weekdays      []string `json:"weekdays" validate:"required,min=1"`
+	EffectiveFrom string   `json:"effective_from" validate:"required"`
+	EffectiveTo   string   `json:"effective_to"`
+	StudentUUIDs  []string `json:"student_uuids" validate:"required,min=1,dive,uuid4"`
+}
+
+type TripScheduleResponseDTO struct {
+	UUID          string   `json:"schedule_uuid"`
+	RouteUUID     string   `json:"route_uuid"`
+	RouteName     string   `json:"route_name"`
+	VehicleUUID   string   `json:"vehicle_uuid"`
+	VehicleNumber string   `json:"vehicle_number"`
+	DriverUUID    string   `json:"driver_uuid"`
+	DriverName    string   `json:"driver_name"`
+	Direction     string   `json:"direction"`
+	DepartureTime string   `json:"departure_time"`
+	Weekdays      []string `json:"weekdays"`
+	EffectiveFrom string   `json:"effective_from"`
+	EffectiveTo   string   `json:"effective_to,omitempty"`
+	StudentUUIDs  []string `json:"student_uuids"`
+	CreatedAt     string   `json:"created_at,omitempty"`
+	CreatedBy     string   `json:"created_by,omitempty"`
+	UpdatedAt     string   `json:"updated_at,omitempty"`
+	UpdatedBy     string   `json:"updated_by,omitempty"`
+}
+
+type TripGenerationRequestDTO struct {
+	TripDate string `json:"trip_date" validate:"required"`
+}
+
+type TripGenerationResponseDTO struct {
+	TripDate string `json:"trip_date"`
+	Closed   bool   `json:"school_closed"`
+	Trips    int    `json:"trips"`
+	Shuttles int64  `json:"shuttles"`
+	Skipped  int    `json:"skipped_schedules"` // Driver licence expired or vehicle not in service
+}
+
+type TripResponseDTO struct {
+	UUID          string `json:"trip_uuid"`
+	ScheduleUUID  string `json:"schedule_uuid"`
+	RouteName     string `json:"route_name"`
+	VehicleNumber string `json:"vehicle_number"`
+	DriverName    string `json:"driver_name"`
+	Direction     string `json:"direction"`
+	TripDate      string `json:"trip_date"`
+	DepartsAt     string `json:"departs_at"`
+	Students      int    `json:"students"`
+}
+
+type StudentAbsenceRequestDTO struct {
+	AbsentOn string `json:"absent_on" validate:"required"`
+	Reason   string `json:"absence_reason" validate:"max=255"`
+}
+
+type StudentAbsenceResponseDTO struct {
+	UUID      string `json:"absence_uuid"`
+	AbsentOn  string `json:"absent_on"`
+	Reason    string `json:"absence_reason,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
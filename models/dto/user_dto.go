@@ -57,6 +57,8 @@ type UserResponseDTO struct {
 	CreatedBy  string      `json:"created_by,omitempty"`
 	UpdatedAt  string      `json:"updated_at,omitempty"`
 	UpdatedBy  string      `json:"updated_by,omitempty"`
+
+	ImpersonatedBy *ImpersonatorDTO `json:"impersonated_by,omitempty"`
 }
 
 type SuperAdminDetailsResponseDTO struct {
@@ -0,0 +1,20 @@
+package dto
+
+type VehicleAssignmentRequestDTO struct {
+	DriverUUID  string `json:"driver_uuid" validate:"required,uuid4"`
+	VehicleUUID string `json:"vehicle_uuid" validate:"required,uuid4"`
+}
+
+type VehicleAssignmentResponseDTO struct {
+	UUID          string `json:"assignment_uuid"`
+	DriverUUID    string `json:"driver_uuid"`
+	DriverName    string `json:"driver_name"`
+	VehicleUUID   string `json:"vehicle_uuid"`
+	VehicleName   string `json:"vehicle_name"`
+	VehicleNumber string `json:"vehicle_number"`
+	AssignedFrom  string `json:"assigned_from"`
+	AssignedTo    string `json:"assigned_to,omitempty"`
+	AssignedBy    string `json:"assigned_by"`
+	UnassignedBy  string `json:"unassigned_by,omitempty"`
+	Active        bool   `json:"active"`
+}
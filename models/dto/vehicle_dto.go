@@ -29,3 +29,26 @@ type VehicleResponseDTO struct {
 	UpdatedAt  string `json:"updated_at,omitempty"`
 	UpdatedBy  string `json:"updated_by,omitempty"`
 }
+
+// School admins only change how a vehicle is used, super admins own the rest
+type SchoolVehicleRequestDTO struct {
+	Status    string `json:"vehicle_status" validate:"required,oneof=active maintenance retired"`
+	RouteUUID string `json:"route_uuid" validate:"omitempty,uuid4"`
+}
+
+type SchoolVehicleResponseDTO struct {
+	UUID       string `json:"vehicle_uuid"`
+	Name       string `json:"vehicle_name"`
+	Number     string `json:"vehicle_number"`
+	Type       string `json:"vehicle_type"`
+	Color      string `json:"vehicle_color"`
+	Seats      int    `json:"vehicle_seats"`
+	Status     string `json:"vehicle_status"`
+	DriverUUID string `json:"driver_uuid,omitempty"`
+	DriverName string `json:"driver_name"`
+	RouteUUID  string `json:"route_uuid,omitempty"`
+	RouteName  string `json:"route_name"`
+	CreatedAt  string `json:"created_at,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+	UpdatedBy  string `json:"updated_by,omitempty"`
+}
@@ -0,0 +1,44 @@
+package dto
+
+// Dates are sent and returned as YYYY-MM-DD
+type VehicleMaintenanceRequestDTO struct {
+	ServiceDate string  `json:"service_date" validate:"required"`
+	OdometerKm  int     `json:"odometer_km" validate:"min=0"`
+	Cost        float64 `json:"cost" validate:"min=0"`
+	Notes       string  `json:"notes" validate:"max=2000"`
+}
+
+type VehicleMaintenanceResponseDTO struct {
+	UUID        string  `json:"maintenance_uuid"`
+	VehicleUUID string  `json:"vehicle_uuid"`
+	ServiceDate string  `json:"service_date"`
+	OdometerKm  int     `json:"odometer_km"`
+	Cost        float64 `json:"cost"`
+	Notes       string  `json:"notes,omitempty"`
+	CreatedAt   string  `json:"created_at,omitempty"`
+	CreatedBy   string  `json:"created_by,omitempty"`
+	UpdatedAt   string  `json:"updated_at,omitempty"`
+	UpdatedBy   string  `json:"updated_by,omitempty"`
+}
+
+// Sent as multipart form data together with the scanned file
+type VehicleDocumentRequestDTO struct {
+	Type      string `json:"document_type" form:"document_type" validate:"required,vehicle_document_type"`
+	Number    string `json:"document_number" form:"document_number" validate:"required,max=100"`
+	IssuedOn  string `json:"issued_on" form:"issued_on" validate:"required"`
+	ExpiresOn string `json:"expires_on" form:"expires_on" validate:"required"`
+}
+
+type VehicleDocumentResponseDTO struct {
+	UUID        string `json:"document_uuid"`
+	VehicleUUID string `json:"vehicle_uuid"`
+	Type        string `json:"document_type"`
+	Number      string `json:"document_number"`
+	IssuedOn    string `json:"issued_on"`
+	ExpiresOn   string `json:"expires_on"`
+	Expired     bool   `json:"expired"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	CreatedBy   string `json:"created_by,omitempty"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+	UpdatedBy   string `json:"updated_by,omitempty"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+type WebhookEndpointRequestDTO struct {
+	URL         string   `json:"endpoint_url" validate:"required,url,max=2000"`
+	Description string   `json:"endpoint_description" validate:"max=255"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,oneof=shuttle.status_changed student.created trip.completed"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+type WebhookEndpointResponseDTO struct {
+	UUID        string   `json:"endpoint_uuid"`
+	URL         string   `json:"endpoint_url"`
+	Description string   `json:"endpoint_description,omitempty"`
+	Secret      string   `json:"endpoint_secret,omitempty"`
+	EventTypes  []string `json:"event_types"`
+	IsActive    bool     `json:"is_active"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	CreatedBy   string   `json:"created_by,omitempty"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+	UpdatedBy   string   `json:"updated_by,omitempty"`
+}
+
+type WebhookDeliveryResponseDTO struct {
+	UUID           string `json:"delivery_uuid"`
+	EventUUID      string `json:"event_uuid"`
+	EventType      string `json:"event_type"`
+	Status         string `json:"delivery_status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	ResponseBody   string `json:"response_body,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+	DeliveredAt    string `json:"delivered_at,omitempty"`
+	CreatedAt      string `json:"created_at,omitempty"`
+	RedeliveredAt  string `json:"redelivered_at,omitempty"`
+	RedeliveredBy  string `json:"redelivered_by,omitempty"`
+}
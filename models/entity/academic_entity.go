@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AcademicYear struct {
+	ID         int64          `db:"academic_year_id"`
+	UUID       uuid.UUID      `db:"academic_year_uuid"`
+	SchoolUUID uuid.UUID      `db:"school_uuid"`
+	Name       string         `db:"academic_year_name"`
+	StartsOn   time.Time      `db:"starts_on"`
+	EndsOn     time.Time      `db:"ends_on"`
+	IsCurrent  bool           `db:"is_current"`
+	Terms      []AcademicTerm `db:"-"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	DeletedAt  sql.NullTime   `db:"deleted_at"`
+	DeletedBy  sql.NullString `db:"deleted_by"`
+}
+
+type AcademicTerm struct {
+	ID               int64     `db:"academic_term_id"`
+	UUID             uuid.UUID `db:"academic_term_uuid"`
+	AcademicYearUUID uuid.UUID `db:"academic_year_uuid"`
+	Name             string    `db:"academic_term_name"`
+	StartsOn         time.Time `db:"starts_on"`
+	EndsOn           time.Time `db:"ends_on"`
+}
+
+type GradeLevel struct {
+	ID         int64          `db:"grade_level_id"`
+	UUID       uuid.UUID      `db:"grade_level_uuid"`
+	SchoolUUID uuid.UUID      `db:"school_uuid"`
+	Name       string         `db:"grade_name"`
+	Order      int            `db:"grade_order"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	DeletedAt  sql.NullTime   `db:"deleted_at"`
+	DeletedBy  sql.NullString `db:"deleted_by"`
+}
+
+type PromotionStatus string
+
+const (
+	PromotionApplied    PromotionStatus = "applied"
+	PromotionRolledBack PromotionStatus = "rolled_back"
+)
+
+type GradePromotion struct {
+	ID                   int64           `db:"grade_promotion_id"`
+	UUID                 uuid.UUID       `db:"grade_promotion_uuid"`
+	SchoolUUID           uuid.UUID       `db:"school_uuid"`
+	FromAcademicYearUUID uuid.NullUUID   `db:"from_academic_year_uuid"`
+	ToAcademicYearUUID   uuid.UUID       `db:"to_academic_year_uuid"`
+	Status               PromotionStatus `db:"promotion_status"`
+	PromotedCount        int             `db:"promoted_count"`
+	GraduatedCount       int             `db:"graduated_count"`
+	AppliedAt            time.Time       `db:"applied_at"`
+	AppliedBy            sql.NullString  `db:"applied_by"`
+	RollbackUntil        time.Time       `db:"rollback_until"`
+	RolledBackAt         sql.NullTime    `db:"rolled_back_at"`
+	RolledBackBy         sql.NullString  `db:"rolled_back_by"`
+}
+
+type GradePromotionItem struct {
+	PromotionUUID      uuid.UUID      `db:"grade_promotion_uuid"`
+	StudentUUID        uuid.UUID      `db:"student_uuid"`
+	FromGrade          string         `db:"from_grade"`
+	FromGradeLevelUUID uuid.NullUUID  `db:"from_grade_level_uuid"`
+	ToGrade            sql.NullString `db:"to_grade"`
+	ToGradeLevelUUID   uuid.NullUUID  `db:"to_grade_level_uuid"`
+	Graduated          bool           `db:"graduated"`
+}
+
+// A student as the promotion sees it, the grade level is matched by name for students
+// that were added before the school set up its levels
+type PromotionCandidate struct {
+	StudentUUID    uuid.UUID     `db:"student_uuid"`
+	FirstName      string        `db:"student_first_name"`
+	LastName       string        `db:"student_last_name"`
+	Grade          string        `db:"student_grade"`
+	GradeLevelUUID uuid.NullUUID `db:"grade_level_uuid"`
+}
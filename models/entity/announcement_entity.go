@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AnnouncementTargetSchool = "school"
+	AnnouncementTargetRoute  = "route"
+	AnnouncementTargetGrade  = "grade"
+	AnnouncementTargetTrip   = "trip"
+)
+
+const (
+	AnnouncementScheduled = "scheduled"
+	AnnouncementPublished = "published"
+	AnnouncementExpired   = "expired"
+)
+
+// RecipientCount and ReadCount are only filled for school admins, ReadAt only for a recipient
+type Announcement struct {
+	ID             int64          `db:"announcement_id"`
+	UUID           uuid.UUID      `db:"announcement_uuid"`
+	SchoolUUID     uuid.UUID      `db:"school_uuid"`
+	TargetType     string         `db:"target_type"`
+	TargetUUID     uuid.NullUUID  `db:"target_uuid"`
+	Title          string         `db:"announcement_title"`
+	Body           string         `db:"announcement_body"`
+	PublishAt      time.Time      `db:"publish_at"`
+	ExpiresAt      sql.NullTime   `db:"expires_at"`
+	PublishedAt    sql.NullTime   `db:"published_at"`
+	RecipientCount int            `db:"recipient_count"`
+	ReadCount      int            `db:"read_count"`
+	ReadAt         sql.NullTime   `db:"read_at"`
+	CreatedAt      sql.NullTime   `db:"created_at"`
+	CreatedBy      sql.NullString `db:"created_by"`
+	UpdatedAt      sql.NullTime   `db:"updated_at"`
+	UpdatedBy      sql.NullString `db:"updated_by"`
+	DeletedAt      sql.NullTime   `db:"deleted_at"`
+	DeletedBy      sql.NullString `db:"deleted_by"`
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+
+	// Guardian changes are logged against the student
+	AuditGuardianAdd    = "guardian.add"
+	AuditGuardianUpdate = "guardian.update"
+	AuditGuardianRemove = "guardian.remove"
+)
+
+const (
+	AuditEntityUser    = "user"
+	AuditEntitySchool  = "school"
+	AuditEntityVehicle = "vehicle"
+	AuditEntityStudent = "student"
+	AuditEntityShuttle = "shuttle"
+)
+
+// Who made a change and from where, taken from the request by the handler
+type AuditActor struct {
+	UserUUID         string
+	RoleCode         string
+	Username         string
+	ImpersonatorUUID string
+	IPAddress        string
+	UserAgent        string
+	RequestID        string
+}
+
+type AuditLog struct {
+	ID               int64           `db:"audit_id"`
+	UUID             uuid.UUID       `db:"audit_uuid"`
+	ActorUUID        uuid.NullUUID   `db:"actor_uuid"`
+	ActorRoleCode    sql.NullString  `db:"actor_role_code"`
+	ActorUsername    sql.NullString  `db:"actor_username"`
+	ImpersonatorUUID uuid.NullUUID   `db:"impersonator_uuid"`
+	Action           string          `db:"audit_action"`
+	EntityType       string          `db:"entity_type"`
+	EntityUUID       uuid.NullUUID   `db:"entity_uuid"`
+	Before           json.RawMessage `db:"before_data"`
+	After            json.RawMessage `db:"after_data"`
+	IPAddress        sql.NullString  `db:"ip_address"`
+	UserAgent        sql.NullString  `db:"user_agent"`
+	RequestID        sql.NullString  `db:"request_id"`
+	CreatedAt        time.Time       `db:"created_at"`
+}
+
+// Empty fields and zero times are not filtered on, To is exclusive
+type AuditLogFilter struct {
+	EntityType string
+	EntityUUID string
+	ActorUUID  string
+	Action     string
+	From       time.Time
+	To         time.Time
+}
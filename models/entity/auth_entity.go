@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,3 +24,38 @@ type RefreshToken struct {
 	Revoked      bool      `db:"is_revoked"`
 	LastUsedAt   *time.Time `db:"last_used_at"`
 }
+
+type LoginAttempt struct {
+	Scope        string     `db:"attempt_scope"`
+	Key          string     `db:"attempt_key"`
+	FailedCount  int        `db:"failed_count"`
+	LastFailedAt *time.Time `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+}
+
+type AccountLockout struct {
+	ID          int64          `db:"lockout_id"`
+	UUID        uuid.UUID      `db:"lockout_uuid"`
+	UserUUID    *uuid.UUID     `db:"user_uuid"`
+	Scope       string         `db:"lockout_scope"`
+	Key         string         `db:"lockout_key"`
+	IPAddress   string         `db:"ip_address"`
+	FailedCount int            `db:"failed_count"`
+	LockedAt    time.Time      `db:"locked_at"`
+	LockedUntil time.Time      `db:"locked_until"`
+	UnlockedAt  *time.Time     `db:"unlocked_at"`
+	UnlockedBy  sql.NullString `db:"unlocked_by"`
+}
+
+type LoginOTP struct {
+	ID             int64          `db:"otp_id"`
+	UUID           uuid.UUID      `db:"otp_uuid"`
+	UserUUID       *uuid.UUID     `db:"user_uuid"`
+	Phone          string         `db:"otp_phone"`
+	CodeHash       sql.NullString `db:"otp_code_hash"`
+	IPAddress      string         `db:"ip_address"`
+	VerifyAttempts int            `db:"verify_attempts"`
+	ExpiresAt      time.Time      `db:"expires_at"`
+	ConsumedAt     *time.Time     `db:"consumed_at"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How a contact is related to the student or trip of a conversation
+const (
+	ContactGuardian    = "guardian"
+	ContactDriver      = "driver"
+	ContactSchoolAdmin = "school_admin"
+)
+
+type Conversation struct {
+	ID               int64          `db:"conversation_id"`
+	UUID             uuid.UUID      `db:"conversation_uuid"`
+	SchoolUUID       uuid.UUID      `db:"school_uuid"`
+	StudentUUID      uuid.NullUUID  `db:"student_uuid"`
+	TripUUID         uuid.NullUUID  `db:"trip_uuid"`
+	StudentFirstName sql.NullString `db:"student_first_name"`
+	StudentLastName  sql.NullString `db:"student_last_name"`
+	LastMessageAt    time.Time      `db:"last_message_at"`
+	UnreadCount      int            `db:"unread_count"`
+	CreatedAt        sql.NullTime   `db:"created_at"`
+	CreatedBy        sql.NullString `db:"created_by"`
+}
+
+type ConversationParticipant struct {
+	ConversationUUID uuid.UUID      `db:"conversation_uuid"`
+	UserUUID         uuid.UUID      `db:"user_uuid"`
+	RoleCode         sql.NullString `db:"user_role_code"`
+	Role             sql.NullString `db:"user_role"`
+	FirstName        sql.NullString `db:"user_first_name"`
+	LastName         sql.NullString `db:"user_last_name"`
+	LastReadAt       sql.NullTime   `db:"last_read_at"`
+	JoinedAt         time.Time      `db:"joined_at"`
+}
+
+type Message struct {
+	ID               int64          `db:"message_id"`
+	UUID             uuid.UUID      `db:"message_uuid"`
+	ConversationUUID uuid.UUID      `db:"conversation_uuid"`
+	SenderUUID       uuid.NullUUID  `db:"sender_uuid"`
+	SenderFirstName  sql.NullString `db:"user_first_name"`
+	SenderLastName   sql.NullString `db:"user_last_name"`
+	Body             string         `db:"message_body"`
+	CreatedAt        time.Time      `db:"created_at"`
+	DeletedAt        sql.NullTime   `db:"deleted_at"`
+	DeletedBy        sql.NullString `db:"deleted_by"`
+}
+
+// Someone the student or trip of a conversation puts a user in touch with
+type ConversationContact struct {
+	UserUUID  uuid.UUID      `db:"user_uuid"`
+	RoleCode  sql.NullString `db:"user_role_code"`
+	FirstName sql.NullString `db:"user_first_name"`
+	LastName  sql.NullString `db:"user_last_name"`
+	Relation  string         `db:"relation"`
+}
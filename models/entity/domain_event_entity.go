@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DomainEventPending   = "pending"
+	DomainEventPublished = "published"
+	DomainEventFailed    = "failed"
+)
+
+const (
+	EventShuttleStatusChanged = "shuttle.status_changed"
+	EventStudentCreated       = "student.created"
+	EventDriverAssigned       = "driver.assigned"
+	EventDriverUnassigned     = "driver.unassigned"
+	EventTripCompleted        = "trip.completed"
+)
+
+type DomainEvent struct {
+	ID            int64           `db:"event_id"`
+	UUID          uuid.UUID       `db:"event_uuid"`
+	Type          string          `db:"event_type"`
+	AggregateType string          `db:"aggregate_type"`
+	AggregateUUID uuid.UUID       `db:"aggregate_uuid"`
+	SchoolUUID    uuid.NullUUID   `db:"school_uuid"`
+	Payload       json.RawMessage `db:"event_payload"`
+	DedupKey      string          `db:"dedup_key"`
+	Status        string          `db:"event_status"`
+	Attempts      int             `db:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	LockedAt      sql.NullTime    `db:"locked_at"`
+	LockedBy      sql.NullString  `db:"locked_by"`
+	LastError     sql.NullString  `db:"last_error"`
+	OccurredAt    time.Time       `db:"occurred_at"`
+	PublishedAt   sql.NullTime    `db:"published_at"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DocumentType string
+
+const (
+	DocumentLicense           DocumentType = "license"
+	DocumentBackgroundCheck   DocumentType = "background_check"
+	DocumentHealthCertificate DocumentType = "health_certificate"
+)
+
+type DriverDocument struct {
+	ID         int64          `db:"document_id"`
+	UUID       uuid.UUID      `db:"document_uuid"`
+	DriverUUID uuid.UUID      `db:"driver_uuid"`
+	Type       DocumentType   `db:"document_type"`
+	Number     string         `db:"document_number"`
+	IssuedOn   time.Time      `db:"issued_on"`
+	ExpiresOn  sql.NullTime   `db:"expires_on"`
+	File       string         `db:"document_file"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	DeletedAt  sql.NullTime   `db:"deleted_at"`
+	DeletedBy  sql.NullString `db:"deleted_by"`
+}
+
+// A document close to its expiry date together with who has to hear about it
+type ExpiringDriverDocument struct {
+	DocumentUUID    uuid.UUID    `db:"document_uuid"`
+	Type            DocumentType `db:"document_type"`
+	Number          string       `db:"document_number"`
+	ExpiresOn       time.Time    `db:"expires_on"`
+	DaysLeft        int          `db:"days_left"`
+	DriverFirstName string       `db:"user_first_name"`
+	DriverLastName  string       `db:"user_last_name"`
+	SchoolUUID      uuid.UUID    `db:"school_uuid"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Impersonation struct {
+	ID         int64          `db:"impersonation_id"`
+	UUID       uuid.UUID      `db:"impersonation_uuid"`
+	AdminUUID  uuid.UUID      `db:"admin_uuid"`
+	TargetUUID uuid.UUID      `db:"target_uuid"`
+	Reason     sql.NullString `db:"impersonation_reason"`
+	IPAddress  string         `db:"ip_address"`
+	StartedAt  time.Time      `db:"started_at"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	EndedAt    *time.Time     `db:"ended_at"`
+}
+
+type ImpersonationRequest struct {
+	ID                int64     `db:"request_id"`
+	ImpersonationUUID uuid.UUID `db:"impersonation_uuid"`
+	Method            string    `db:"request_method"`
+	Path              string    `db:"request_path"`
+	Status            int       `db:"response_status"`
+	CreatedAt         time.Time `db:"created_at"`
+}
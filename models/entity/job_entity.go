@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+type Job struct {
+	ID          int64           `db:"job_id"`
+	UUID        uuid.UUID       `db:"job_uuid"`
+	Name        string          `db:"job_name"`
+	Payload     json.RawMessage `db:"job_payload"`
+	Status      string          `db:"job_status"`
+	Attempts    int             `db:"attempts"`
+	MaxAttempts int             `db:"max_attempts"`
+	RunAt       time.Time       `db:"run_at"`
+	LockedAt    sql.NullTime    `db:"locked_at"`
+	LockedBy    sql.NullString  `db:"locked_by"`
+	LastError   sql.NullString  `db:"last_error"`
+	FinishedAt  sql.NullTime    `db:"finished_at"`
+	CreatedAt   sql.NullTime    `db:"created_at"`
+	CreatedBy   sql.NullString  `db:"created_by"`
+}
+
+type JobSchedule struct {
+	Name        string         `db:"schedule_name"`
+	Spec        string         `db:"schedule_spec"`
+	NextRunAt   time.Time      `db:"next_run_at"`
+	LastRunAt   sql.NullTime   `db:"last_run_at"`
+	LastJobUUID uuid.NullUUID  `db:"last_job_uuid"`
+	LastStatus  sql.NullString `db:"job_status"`
+}
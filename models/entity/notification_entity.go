@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NotificationShuttleStarted       = "shuttle.started"
+	NotificationShuttleStatusChanged = "shuttle.status_changed"
+	NotificationSchoolAnnouncement   = "school.announcement"
+)
+
+const (
+	NotificationChannelInbox     = "inbox"
+	NotificationChannelWebSocket = "websocket"
+	NotificationChannelPush      = "push"
+)
+
+type Notification struct {
+	ID        int64           `db:"notification_id"`
+	UUID      uuid.UUID       `db:"notification_uuid"`
+	UserUUID  uuid.UUID       `db:"user_uuid"`
+	Event     string          `db:"notification_event"`
+	Title     string          `db:"notification_title"`
+	Body      string          `db:"notification_body"`
+	Data      json.RawMessage `db:"notification_data"`
+	ReadAt    sql.NullTime    `db:"read_at"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+type NotificationPreference struct {
+	UserUUID uuid.UUID `db:"user_uuid"`
+	Event    string    `db:"notification_event"`
+	Channel  string    `db:"notification_channel"`
+	Enabled  bool      `db:"enabled"`
+}
+
+// Language and disabled channels of one recipient
+type NotificationRecipient struct {
+	UserUUID         uuid.UUID
+	Language         string
+	DisabledChannels map[string]bool
+}
+
+type PushDevice struct {
+	ID         int64     `db:"device_id"`
+	UUID       uuid.UUID `db:"device_uuid"`
+	UserUUID   uuid.UUID `db:"user_uuid"`
+	Token      string    `db:"device_token"`
+	Platform   string    `db:"device_platform"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+}
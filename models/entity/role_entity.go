@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+type AccessRole struct {
+	Code        string         `db:"role_code"`
+	Name        string         `db:"role_name"`
+	Base        Role           `db:"role_base"`
+	Description sql.NullString `db:"role_description"`
+	IsSystem    bool           `db:"is_system"`
+	Permissions []string
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+}
+
+type Permission struct {
+	Code        string         `db:"permission_code"`
+	Description sql.NullString `db:"permission_description"`
+}
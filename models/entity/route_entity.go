@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type Route struct {
+	ID           int64          `db:"route_id"`
+	UUID         uuid.UUID      `db:"route_uuid"`
+	SchoolUUID   uuid.UUID      `db:"school_uuid"`
+	Name         string         `db:"route_name"`
+	Description  sql.NullString `db:"route_description"`
+	Points       string         `db:"route_points"` // JSON array of {latitude, longitude}
+	Status       string         `db:"route_status"`
+	VehicleCount int            `db:"vehicle_count"`
+	CreatedAt    sql.NullTime   `db:"created_at"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	UpdatedAt    sql.NullTime   `db:"updated_at"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	DeletedAt    sql.NullTime   `db:"deleted_at"`
+	DeletedBy    sql.NullString `db:"deleted_by"`
+}
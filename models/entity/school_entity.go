@@ -2,22 +2,63 @@ package entity
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type School struct {
-	ID          int64          `db:"school_id"`
-	UUID        uuid.UUID      `db:"school_uuid"`
-	Name        string         `db:"school_name"`
-	Address     string         `db:"school_address"`
-	Contact     string         `db:"school_contact"`
-	Email       string         `db:"school_email"`
-	Description string         `db:"school_description"`
-	CreatedAt   sql.NullTime   `db:"created_at"`
-	CreatedBy   sql.NullString `db:"created_by"`
-	UpdatedAt   sql.NullTime   `db:"updated_at"`
-	UpdatedBy   sql.NullString `db:"updated_by"`
-	DeletedAt   sql.NullTime   `db:"deleted_at"`
-	DeletedBy   sql.NullString `db:"deleted_by"`
+	ID          int64           `db:"school_id"`
+	UUID        uuid.UUID       `db:"school_uuid"`
+	Name        string          `db:"school_name"`
+	Address     string          `db:"school_address"`
+	Contact     string          `db:"school_contact"`
+	Email       string          `db:"school_email"`
+	Description string          `db:"school_description"`
+	Latitude    sql.NullFloat64 `db:"school_latitude"`
+	Longitude   sql.NullFloat64 `db:"school_longitude"`
+	Geofence    int             `db:"school_geofence_radius"`
+	Timezone    string          `db:"school_timezone"`
+	CreatedAt   sql.NullTime    `db:"created_at"`
+	CreatedBy   sql.NullString  `db:"created_by"`
+	UpdatedAt   sql.NullTime    `db:"updated_at"`
+	UpdatedBy   sql.NullString  `db:"updated_by"`
+	DeletedAt   sql.NullTime    `db:"deleted_at"`
+	DeletedBy   sql.NullString  `db:"deleted_by"`
+}
+
+// Counts the records that are deleted together with a school, or restored together with it
+type SchoolDeletionPlan struct {
+	SchoolAdmins       int `db:"school_admins"`
+	Drivers            int `db:"drivers"`
+	Students           int `db:"students"`
+	Parents            int `db:"parents"`
+	Vehicles           int `db:"vehicles"`
+	VehicleAssignments int `db:"vehicle_assignments"`
+	Routes             int `db:"routes"`
+	AcademicYears      int `db:"academic_years"`
+	GradeLevels        int `db:"grade_levels"`
+}
+
+// Weekday follows ISO 8601, 1 is Monday and 7 is Sunday. Times are HH:MM in the school's timezone.
+type SchoolOperatingHour struct {
+	SchoolUUID uuid.UUID `db:"school_uuid"`
+	Weekday    int       `db:"weekday"`
+	StartsAt   string    `db:"starts_at"`
+	EndsAt     string    `db:"ends_at"`
+}
+
+type SchoolClosure struct {
+	ID         int64          `db:"closure_id"`
+	UUID       uuid.UUID      `db:"closure_uuid"`
+	SchoolUUID uuid.UUID      `db:"school_uuid"`
+	Name       string         `db:"closure_name"`
+	StartsOn   time.Time      `db:"starts_on"`
+	EndsOn     time.Time      `db:"ends_on"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	DeletedAt  sql.NullTime   `db:"deleted_at"`
+	DeletedBy  sql.NullString `db:"deleted_by"`
 }
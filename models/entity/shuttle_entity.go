@@ -18,3 +18,11 @@ type Shuttle struct {
 	DeletedAt    sql.NullTime   `db:"deleted_at"`
 	DeletedBy    sql.NullString `db:"deleted_by"`
 }
+
+type ShuttleStudent struct {
+	ShuttleUUID      uuid.UUID `db:"shuttle_uuid"`
+	StudentUUID      uuid.UUID `db:"student_uuid"`
+	StudentFirstName string    `db:"student_first_name"`
+	StudentLastName  string    `db:"student_last_name"`
+	Status           string    `db:"status"`
+}
@@ -13,8 +13,6 @@ type Student struct {
 	LastName  string         `db:"last_name"`
 	Grade     string         `db:"student_grade"`
 	Gender     string         `db:"student_gender"`
-	ParentID  sql.NullInt64  `db:"parent_id"`
-	ParentUUID sql.NullString `db:"parent_uuid"`
 	SchoolID  int64          `db:"school_id"`
 	SchoolUUID uuid.UUID     `db:"school_uuid"`
     SchoolName string  
@@ -26,6 +24,26 @@ type Student struct {
 	DeletedBy sql.NullString `db:"deleted_by"`
 }
 
+type StudentGuardian struct {
+	ID           int64          `db:"student_guardian_id"`
+	StudentUUID  uuid.UUID      `db:"student_uuid"`
+	GuardianUUID uuid.UUID      `db:"guardian_uuid"`
+	Relationship string         `db:"guardian_relationship"`
+	CanPickup    bool           `db:"can_pickup"`
+	IsPrimary    bool           `db:"is_primary"`
+	CreatedAt    sql.NullTime   `db:"created_at"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	UpdatedAt    sql.NullTime   `db:"updated_at"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+
+	// Filled from users and parent_details when guardians are fetched
+	Email     string `db:"user_email"`
+	FirstName string `db:"user_first_name"`
+	LastName  string `db:"user_last_name"`
+	Phone     string `db:"user_phone"`
+	Address   string `db:"user_address"`
+}
+
 // type Parent struct {
 // 	ID        int64          `db:"parent_id"`
 // 	UUID      uuid.UUID      `db:"parent_uuid"`
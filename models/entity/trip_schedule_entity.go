@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	TripToSchool = "to_school"
+	TripToHome   = "to_home"
+)
+
+// Weekdays follow ISO 8601, 1 is Monday and 7 is Sunday. DepartureTime is HH:MM in the school's timezone.
+type TripSchedule struct {
+	ID              int64          `db:"schedule_id"`
+	UUID            uuid.UUID      `db:"schedule_uuid"`
+	SchoolUUID      uuid.UUID      `db:"school_uuid"`
+	RouteUUID       uuid.UUID      `db:"route_uuid"`
+	VehicleUUID     uuid.UUID      `db:"vehicle_uuid"`
+	DriverUUID      uuid.UUID      `db:"driver_uuid"`
+	Direction       string         `db:"direction"`
+	DepartureTime   string         `db:"departure_time"`
+	Weekdays        pq.Int64Array  `db:"weekdays"`
+	EffectiveFrom   time.Time      `db:"effective_from"`
+	EffectiveTo     sql.NullTime   `db:"effective_to"`
+	RouteName       sql.NullString `db:"route_name"`
+	VehicleNumber   sql.NullString `db:"vehicle_number"`
+	DriverFirstName sql.NullString `db:"user_first_name"`
+	DriverLastName  sql.NullString `db:"user_last_name"`
+	Timezone        string         `db:"school_timezone"`
+	StudentUUIDs    []string       `db:"-"`
+	CreatedAt       sql.NullTime   `db:"created_at"`
+	CreatedBy       sql.NullString `db:"created_by"`
+	UpdatedAt       sql.NullTime   `db:"updated_at"`
+	UpdatedBy       sql.NullString `db:"updated_by"`
+	DeletedAt       sql.NullTime   `db:"deleted_at"`
+	DeletedBy       sql.NullString `db:"deleted_by"`
+
+	// Only filled by FetchRunnableSchedules, trips are not generated while either is true
+	LicenseExpired     bool `db:"license_expired"`
+	VehicleUnavailable bool `db:"vehicle_unavailable"`
+}
+
+// Whether the route, vehicle and driver of a schedule are active and belong to the school
+type TripScheduleReferences struct {
+	RouteFound   bool `db:"route_found"`
+	VehicleFound bool `db:"vehicle_found"`
+	DriverFound  bool `db:"driver_found"`
+}
+
+// One day of a schedule, the details are copied so later changes to the schedule leave it alone
+type Trip struct {
+	ID              int64          `db:"trip_id"`
+	UUID            uuid.UUID      `db:"trip_uuid"`
+	ScheduleUUID    uuid.UUID      `db:"schedule_uuid"`
+	SchoolUUID      uuid.UUID      `db:"school_uuid"`
+	RouteUUID       uuid.NullUUID  `db:"route_uuid"`
+	VehicleUUID     uuid.NullUUID  `db:"vehicle_uuid"`
+	DriverUUID      uuid.NullUUID  `db:"driver_uuid"`
+	Direction       string         `db:"direction"`
+	TripDate        time.Time      `db:"trip_date"`
+	DepartsAt       time.Time      `db:"departs_at"`
+	RouteName       sql.NullString `db:"route_name"`
+	VehicleNumber   sql.NullString `db:"vehicle_number"`
+	DriverFirstName sql.NullString `db:"user_first_name"`
+	DriverLastName  sql.NullString `db:"user_last_name"`
+	StudentCount    int            `db:"student_count"`
+	CreatedAt       sql.NullTime   `db:"created_at"`
+	CreatedBy       sql.NullString `db:"created_by"`
+}
+
+type StudentAbsence struct {
+	ID          int64          `db:"absence_id"`
+	UUID        uuid.UUID      `db:"absence_uuid"`
+	StudentUUID uuid.UUID      `db:"student_uuid"`
+	AbsentOn    time.Time      `db:"absent_on"`
+	Reason      sql.NullString `db:"absence_reason"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	DeletedAt   sql.NullTime   `db:"deleted_at"`
+	DeletedBy   sql.NullString `db:"deleted_by"`
+}
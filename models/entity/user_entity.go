@@ -36,6 +36,9 @@ type User struct {
 	UpdatedBy  sql.NullString `db:"updated_by"`
 	DeletedAt  sql.NullTime   `db:"deleted_at"`
 	DeletedBy  sql.NullString `db:"deleted_by"`
+
+	// Set for parents created while adding or importing a student
+	CreatedWithStudent bool `db:"created_with_student"`
 }
 
 type SuperAdminDetails struct {
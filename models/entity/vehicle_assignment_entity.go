@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An open assignment has no AssignedTo
+type VehicleAssignment struct {
+	ID              int64          `db:"assignment_id"`
+	UUID            uuid.UUID      `db:"assignment_uuid"`
+	DriverUUID      uuid.UUID      `db:"driver_uuid"`
+	VehicleUUID     uuid.UUID      `db:"vehicle_uuid"`
+	AssignedFrom    time.Time      `db:"assigned_from"`
+	AssignedTo      sql.NullTime   `db:"assigned_to"`
+	AssignedBy      sql.NullString `db:"assigned_by"`
+	UnassignedBy    sql.NullString `db:"unassigned_by"`
+	DriverFirstName sql.NullString `db:"user_first_name"`
+	DriverLastName  sql.NullString `db:"user_last_name"`
+	VehicleName     string         `db:"vehicle_name"`
+	VehicleNumber   string         `db:"vehicle_number"`
+}
@@ -24,3 +24,13 @@ type Vehicle struct {
 	DeletedAt     sql.NullTime   `db:"deleted_at"`
 	DeletedBy     sql.NullString `db:"deleted_by"`
 }
+
+// A vehicle as its school sees it, with the driver and route it is on
+type SchoolVehicle struct {
+	Vehicle
+	RouteUUID         *uuid.UUID     `db:"route_uuid"`
+	RouteName         sql.NullString `db:"route_name"`
+	DriverFirstName   sql.NullString `db:"driver_first_name"`
+	DriverLastName    sql.NullString `db:"driver_last_name"`
+	StatusBeforeLapse sql.NullString `db:"vehicle_status_before_lapse"`
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set on vehicle_status by the system when a mandatory document lapses
+const VehicleOutOfService = "out_of_service"
+
+type VehicleDocumentType string
+
+const (
+	VehicleInspection   VehicleDocumentType = "inspection"
+	VehicleRegistration VehicleDocumentType = "registration"
+	VehicleInsurance    VehicleDocumentType = "insurance"
+)
+
+type VehicleMaintenance struct {
+	ID          int64          `db:"maintenance_id"`
+	UUID        uuid.UUID      `db:"maintenance_uuid"`
+	VehicleUUID uuid.UUID      `db:"vehicle_uuid"`
+	ServiceDate time.Time      `db:"service_date"`
+	OdometerKm  int            `db:"odometer_km"`
+	Cost        float64        `db:"cost"`
+	Notes       sql.NullString `db:"notes"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+	DeletedAt   sql.NullTime   `db:"deleted_at"`
+	DeletedBy   sql.NullString `db:"deleted_by"`
+}
+
+type VehicleDocument struct {
+	ID          int64               `db:"document_id"`
+	UUID        uuid.UUID           `db:"document_uuid"`
+	VehicleUUID uuid.UUID           `db:"vehicle_uuid"`
+	Type        VehicleDocumentType `db:"document_type"`
+	Number      string              `db:"document_number"`
+	IssuedOn    time.Time           `db:"issued_on"`
+	ExpiresOn   time.Time           `db:"expires_on"`
+	File        string              `db:"document_file"`
+	CreatedAt   sql.NullTime        `db:"created_at"`
+	CreatedBy   sql.NullString      `db:"created_by"`
+	UpdatedAt   sql.NullTime        `db:"updated_at"`
+	UpdatedBy   sql.NullString      `db:"updated_by"`
+	DeletedAt   sql.NullTime        `db:"deleted_at"`
+	DeletedBy   sql.NullString      `db:"deleted_by"`
+}
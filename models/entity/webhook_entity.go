@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookEndpoint struct {
+	ID          int64          `db:"endpoint_id"`
+	UUID        uuid.UUID      `db:"endpoint_uuid"`
+	SchoolUUID  uuid.UUID      `db:"school_uuid"`
+	URL         string         `db:"endpoint_url"`
+	Description sql.NullString `db:"endpoint_description"`
+	Secret      string         `db:"endpoint_secret"`
+	EventTypes  pq.StringArray `db:"event_types"`
+	IsActive    bool           `db:"is_active"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+	DeletedAt   sql.NullTime   `db:"deleted_at"`
+	DeletedBy   sql.NullString `db:"deleted_by"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `db:"delivery_id"`
+	UUID           uuid.UUID       `db:"delivery_uuid"`
+	EndpointUUID   uuid.UUID       `db:"endpoint_uuid"`
+	EventUUID      uuid.UUID       `db:"event_uuid"`
+	EventType      string          `db:"event_type"`
+	Body           json.RawMessage `db:"request_body"`
+	Status         string          `db:"delivery_status"`
+	Attempts       int             `db:"attempts"`
+	ResponseStatus sql.NullInt32   `db:"response_status"`
+	ResponseBody   sql.NullString  `db:"response_body"`
+	LastError      sql.NullString  `db:"last_error"`
+	NextAttemptAt  sql.NullTime    `db:"next_attempt_at"`
+	DeliveredAt    sql.NullTime    `db:"delivered_at"`
+	CreatedAt      sql.NullTime    `db:"created_at"`
+	RedeliveredAt  sql.NullTime    `db:"redelivered_at"`
+	RedeliveredBy  sql.NullString  `db:"redelivered_by"`
+}
@@ -0,0 +1,567 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type AcademicRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchAcademicYears(schoolUUID string) ([]entity.AcademicYear, error)
+	FetchSpecAcademicYear(schoolUUID, yearUUID string) (entity.AcademicYear, error)
+	FetchCurrentAcademicYear(tx *sqlx.Tx, schoolUUID string) (entity.AcademicYear, error)
+	SaveAcademicYear(tx *sqlx.Tx, year entity.AcademicYear) error
+	UpdateAcademicYear(tx *sqlx.Tx, year entity.AcademicYear) error
+	ReplaceAcademicTerms(tx *sqlx.Tx, yearUUID string, terms []entity.AcademicTerm) error
+	ClearCurrentAcademicYear(tx *sqlx.Tx, schoolUUID string) error
+	SetCurrentAcademicYear(tx *sqlx.Tx, schoolUUID, yearUUID string) error
+	DeleteAcademicYear(schoolUUID, yearUUID, username string) error
+
+	FetchGradeLevels(schoolUUID string) ([]entity.GradeLevel, error)
+	FetchSpecGradeLevel(schoolUUID, levelUUID string) (entity.GradeLevel, error)
+	CountStudentsPerGradeLevel(schoolUUID string) (map[string]int, error)
+	SaveGradeLevel(tx *sqlx.Tx, level entity.GradeLevel) error
+	UpdateGradeLevel(tx *sqlx.Tx, level entity.GradeLevel) error
+	LinkStudentsToGradeLevel(tx *sqlx.Tx, level entity.GradeLevel, username string) error
+	DeleteGradeLevel(schoolUUID, levelUUID, username string) error
+
+	FetchPromotionCandidates(tx *sqlx.Tx, schoolUUID string) ([]entity.PromotionCandidate, error)
+	FetchPromotions(schoolUUID string) ([]entity.GradePromotion, error)
+	FetchSpecPromotion(tx *sqlx.Tx, schoolUUID, promotionUUID string) (entity.GradePromotion, error)
+	FetchLatestPromotionUUID(tx *sqlx.Tx, schoolUUID string) (string, error)
+	CountAppliedPromotionsToYear(tx *sqlx.Tx, schoolUUID, yearUUID string) (int, error)
+	SavePromotion(tx *sqlx.Tx, promotion entity.GradePromotion, rollbackWindowSeconds int) (entity.GradePromotion, error)
+	SavePromotionItems(tx *sqlx.Tx, items []entity.GradePromotionItem) error
+	PromoteStudents(tx *sqlx.Tx, items []entity.GradePromotionItem, username string) error
+	GraduateStudents(tx *sqlx.Tx, studentUUIDs []string, username string) error
+	RollbackPromotion(tx *sqlx.Tx, promotionUUID, username string) error
+}
+
+type AcademicRepository struct {
+	db *sqlx.DB
+}
+
+func NewAcademicRepository(db *sqlx.DB) AcademicRepositoryInterface {
+	return &AcademicRepository{
+		db: db,
+	}
+}
+
+func (repository *AcademicRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *AcademicRepository) FetchAcademicYears(schoolUUID string) ([]entity.AcademicYear, error) {
+	var years []entity.AcademicYear
+
+	query := `
+		SELECT academic_year_id, academic_year_uuid, school_uuid, academic_year_name, starts_on, ends_on, is_current,
+			created_at, created_by, updated_at, updated_by
+		FROM academic_years
+		WHERE school_uuid = $1 AND deleted_at IS NULL
+		ORDER BY starts_on DESC
+	`
+
+	if err := repository.db.Select(&years, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	if len(years) == 0 {
+		return years, nil
+	}
+
+	yearUUIDs := make([]string, 0, len(years))
+	for _, year := range years {
+		yearUUIDs = append(yearUUIDs, year.UUID.String())
+	}
+
+	var terms []entity.AcademicTerm
+
+	query = `
+		SELECT academic_term_id, academic_term_uuid, academic_year_uuid, academic_term_name, starts_on, ends_on
+		FROM academic_terms
+		WHERE academic_year_uuid = ANY($1::UUID[])
+		ORDER BY starts_on ASC
+	`
+
+	if err := repository.db.Select(&terms, query, pq.Array(yearUUIDs)); err != nil {
+		return nil, err
+	}
+
+	termsByYear := make(map[string][]entity.AcademicTerm)
+	for _, term := range terms {
+		termsByYear[term.AcademicYearUUID.String()] = append(termsByYear[term.AcademicYearUUID.String()], term)
+	}
+
+	for i := range years {
+		years[i].Terms = termsByYear[years[i].UUID.String()]
+	}
+
+	return years, nil
+}
+
+func (repository *AcademicRepository) FetchSpecAcademicYear(schoolUUID, yearUUID string) (entity.AcademicYear, error) {
+	var year entity.AcademicYear
+
+	query := `
+		SELECT academic_year_id, academic_year_uuid, school_uuid, academic_year_name, starts_on, ends_on, is_current,
+			created_at, created_by, updated_at, updated_by
+		FROM academic_years
+		WHERE school_uuid = $1 AND academic_year_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&year, query, schoolUUID, yearUUID); err != nil {
+		return entity.AcademicYear{}, err
+	}
+
+	query = `
+		SELECT academic_term_id, academic_term_uuid, academic_year_uuid, academic_term_name, starts_on, ends_on
+		FROM academic_terms
+		WHERE academic_year_uuid = $1
+		ORDER BY starts_on ASC
+	`
+
+	if err := repository.db.Select(&year.Terms, query, yearUUID); err != nil {
+		return entity.AcademicYear{}, err
+	}
+
+	return year, nil
+}
+
+func (repository *AcademicRepository) FetchCurrentAcademicYear(tx *sqlx.Tx, schoolUUID string) (entity.AcademicYear, error) {
+	var year entity.AcademicYear
+
+	query := `
+		SELECT academic_year_id, academic_year_uuid, school_uuid, academic_year_name, starts_on, ends_on, is_current,
+			created_at, created_by, updated_at, updated_by
+		FROM academic_years
+		WHERE school_uuid = $1 AND is_current AND deleted_at IS NULL
+	`
+
+	if err := tx.Get(&year, query, schoolUUID); err != nil {
+		return entity.AcademicYear{}, err
+	}
+
+	return year, nil
+}
+
+func (repository *AcademicRepository) SaveAcademicYear(tx *sqlx.Tx, year entity.AcademicYear) error {
+	query := `
+		INSERT INTO academic_years (academic_year_id, academic_year_uuid, school_uuid, academic_year_name, starts_on, ends_on,
+			is_current, created_by)
+		VALUES (:academic_year_id, :academic_year_uuid, :school_uuid, :academic_year_name, :starts_on, :ends_on,
+			:is_current, :created_by)
+	`
+
+	_, err := tx.NamedExec(query, year)
+	return err
+}
+
+func (repository *AcademicRepository) UpdateAcademicYear(tx *sqlx.Tx, year entity.AcademicYear) error {
+	query := `
+		UPDATE academic_years
+		SET academic_year_name = :academic_year_name, starts_on = :starts_on, ends_on = :ends_on,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE academic_year_uuid = :academic_year_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := tx.NamedExec(query, year)
+	return err
+}
+
+func (repository *AcademicRepository) ReplaceAcademicTerms(tx *sqlx.Tx, yearUUID string, terms []entity.AcademicTerm) error {
+	if _, err := tx.Exec(`DELETE FROM academic_terms WHERE academic_year_uuid = $1`, yearUUID); err != nil {
+		return err
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO academic_terms (academic_term_id, academic_term_uuid, academic_year_uuid, academic_term_name, starts_on, ends_on)
+		VALUES (:academic_term_id, :academic_term_uuid, :academic_year_uuid, :academic_term_name, :starts_on, :ends_on)
+	`
+
+	_, err := tx.NamedExec(query, terms)
+	return err
+}
+
+func (repository *AcademicRepository) ClearCurrentAcademicYear(tx *sqlx.Tx, schoolUUID string) error {
+	query := `
+		UPDATE academic_years
+		SET is_current = FALSE
+		WHERE school_uuid = $1 AND is_current
+	`
+
+	_, err := tx.Exec(query, schoolUUID)
+	return err
+}
+
+func (repository *AcademicRepository) SetCurrentAcademicYear(tx *sqlx.Tx, schoolUUID, yearUUID string) error {
+	query := `
+		UPDATE academic_years
+		SET is_current = TRUE
+		WHERE school_uuid = $1 AND academic_year_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(query, schoolUUID, yearUUID)
+	return err
+}
+
+func (repository *AcademicRepository) DeleteAcademicYear(schoolUUID, yearUUID, username string) error {
+	query := `
+		UPDATE academic_years
+		SET deleted_at = NOW(), deleted_by = $1
+		WHERE school_uuid = $2 AND academic_year_uuid = $3 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, username, schoolUUID, yearUUID)
+	return err
+}
+
+func (repository *AcademicRepository) FetchGradeLevels(schoolUUID string) ([]entity.GradeLevel, error) {
+	var levels []entity.GradeLevel
+
+	query := `
+		SELECT grade_level_id, grade_level_uuid, school_uuid, grade_name, grade_order,
+			created_at, created_by, updated_at, updated_by
+		FROM grade_levels
+		WHERE school_uuid = $1 AND deleted_at IS NULL
+		ORDER BY grade_order ASC
+	`
+
+	if err := repository.db.Select(&levels, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (repository *AcademicRepository) FetchSpecGradeLevel(schoolUUID, levelUUID string) (entity.GradeLevel, error) {
+	var level entity.GradeLevel
+
+	query := `
+		SELECT grade_level_id, grade_level_uuid, school_uuid, grade_name, grade_order,
+			created_at, created_by, updated_at, updated_by
+		FROM grade_levels
+		WHERE school_uuid = $1 AND grade_level_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&level, query, schoolUUID, levelUUID); err != nil {
+		return entity.GradeLevel{}, err
+	}
+
+	return level, nil
+}
+
+func (repository *AcademicRepository) CountStudentsPerGradeLevel(schoolUUID string) (map[string]int, error) {
+	var rows []struct {
+		GradeLevelUUID string `db:"grade_level_uuid"`
+		Count          int    `db:"count"`
+	}
+
+	query := `
+		SELECT grade_level_uuid, COUNT(student_id) AS count
+		FROM students
+		WHERE school_uuid = $1 AND grade_level_uuid IS NOT NULL AND deleted_at IS NULL
+		GROUP BY grade_level_uuid
+	`
+
+	if err := repository.db.Select(&rows, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.GradeLevelUUID] = row.Count
+	}
+
+	return counts, nil
+}
+
+func (repository *AcademicRepository) SaveGradeLevel(tx *sqlx.Tx, level entity.GradeLevel) error {
+	query := `
+		INSERT INTO grade_levels (grade_level_id, grade_level_uuid, school_uuid, grade_name, grade_order, created_by)
+		VALUES (:grade_level_id, :grade_level_uuid, :school_uuid, :grade_name, :grade_order, :created_by)
+	`
+
+	_, err := tx.NamedExec(query, level)
+	return err
+}
+
+func (repository *AcademicRepository) UpdateGradeLevel(tx *sqlx.Tx, level entity.GradeLevel) error {
+	query := `
+		UPDATE grade_levels
+		SET grade_name = :grade_name, grade_order = :grade_order, updated_at = NOW(), updated_by = :updated_by
+		WHERE grade_level_uuid = :grade_level_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := tx.NamedExec(query, level)
+	return err
+}
+
+// Keeps students.student_grade in line with the level name and links the students that were
+// added with the same grade text before the level existed
+func (repository *AcademicRepository) LinkStudentsToGradeLevel(tx *sqlx.Tx, level entity.GradeLevel, username string) error {
+	query := `
+		UPDATE students
+		SET grade_level_uuid = $1, student_grade = $2, updated_at = NOW(), updated_by = $3
+		WHERE school_uuid = $4 AND deleted_at IS NULL
+			AND (grade_level_uuid = $1 OR (grade_level_uuid IS NULL AND LOWER(student_grade) = LOWER($2)))
+			AND (grade_level_uuid IS DISTINCT FROM $1 OR student_grade <> $2)
+	`
+
+	_, err := tx.Exec(query, level.UUID, level.Name, username, level.SchoolUUID)
+	return err
+}
+
+func (repository *AcademicRepository) DeleteGradeLevel(schoolUUID, levelUUID, username string) error {
+	query := `
+		UPDATE grade_levels
+		SET deleted_at = NOW(), deleted_by = $1
+		WHERE school_uuid = $2 AND grade_level_uuid = $3 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, username, schoolUUID, levelUUID)
+	return err
+}
+
+func (repository *AcademicRepository) FetchPromotionCandidates(tx *sqlx.Tx, schoolUUID string) ([]entity.PromotionCandidate, error) {
+	var candidates []entity.PromotionCandidate
+
+	query := `
+		SELECT s.student_uuid, s.student_first_name, s.student_last_name, s.student_grade,
+			COALESCE(s.grade_level_uuid, gl.grade_level_uuid) AS grade_level_uuid
+		FROM students s
+		LEFT JOIN grade_levels gl ON gl.school_uuid = s.school_uuid AND LOWER(gl.grade_name) = LOWER(s.student_grade)
+			AND gl.deleted_at IS NULL
+		WHERE s.school_uuid = $1 AND s.deleted_at IS NULL
+		ORDER BY s.student_grade ASC, s.student_first_name ASC
+		FOR UPDATE OF s
+	`
+
+	if err := tx.Select(&candidates, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func (repository *AcademicRepository) FetchPromotions(schoolUUID string) ([]entity.GradePromotion, error) {
+	var promotions []entity.GradePromotion
+
+	query := `
+		SELECT grade_promotion_id, grade_promotion_uuid, school_uuid, from_academic_year_uuid, to_academic_year_uuid,
+			promotion_status, promoted_count, graduated_count, applied_at, applied_by, rollback_until,
+			rolled_back_at, rolled_back_by
+		FROM grade_promotions
+		WHERE school_uuid = $1
+		ORDER BY applied_at DESC
+	`
+
+	if err := repository.db.Select(&promotions, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+func (repository *AcademicRepository) FetchSpecPromotion(tx *sqlx.Tx, schoolUUID, promotionUUID string) (entity.GradePromotion, error) {
+	var promotion entity.GradePromotion
+
+	query := `
+		SELECT grade_promotion_id, grade_promotion_uuid, school_uuid, from_academic_year_uuid, to_academic_year_uuid,
+			promotion_status, promoted_count, graduated_count, applied_at, applied_by, rollback_until,
+			rolled_back_at, rolled_back_by
+		FROM grade_promotions
+		WHERE school_uuid = $1 AND grade_promotion_uuid = $2
+		FOR UPDATE
+	`
+
+	if err := tx.Get(&promotion, query, schoolUUID, promotionUUID); err != nil {
+		return entity.GradePromotion{}, err
+	}
+
+	return promotion, nil
+}
+
+func (repository *AcademicRepository) FetchLatestPromotionUUID(tx *sqlx.Tx, schoolUUID string) (string, error) {
+	var promotionUUID string
+
+	query := `
+		SELECT grade_promotion_uuid
+		FROM grade_promotions
+		WHERE school_uuid = $1 AND promotion_status = 'applied'
+		ORDER BY applied_at DESC
+		LIMIT 1
+	`
+
+	if err := tx.Get(&promotionUUID, query, schoolUUID); err != nil {
+		return "", err
+	}
+
+	return promotionUUID, nil
+}
+
+func (repository *AcademicRepository) CountAppliedPromotionsToYear(tx *sqlx.Tx, schoolUUID, yearUUID string) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(grade_promotion_id)
+		FROM grade_promotions
+		WHERE school_uuid = $1 AND to_academic_year_uuid = $2 AND promotion_status = 'applied'
+	`
+
+	if err := tx.Get(&count, query, schoolUUID, yearUUID); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// applied_at is the transaction time, the graduated students and their shuttles are stamped with
+// the same NOW() so a rollback can find exactly what this promotion archived
+func (repository *AcademicRepository) SavePromotion(tx *sqlx.Tx, promotion entity.GradePromotion, rollbackWindowSeconds int) (entity.GradePromotion, error) {
+	query := `
+		INSERT INTO grade_promotions (grade_promotion_id, grade_promotion_uuid, school_uuid, from_academic_year_uuid,
+			to_academic_year_uuid, promotion_status, promoted_count, graduated_count, applied_at, applied_by, rollback_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), $9, NOW() + make_interval(secs => $10))
+		RETURNING applied_at, rollback_until
+	`
+
+	err := tx.QueryRowx(query, promotion.ID, promotion.UUID, promotion.SchoolUUID, promotion.FromAcademicYearUUID,
+		promotion.ToAcademicYearUUID, promotion.Status, promotion.PromotedCount, promotion.GraduatedCount,
+		promotion.AppliedBy, rollbackWindowSeconds).Scan(&promotion.AppliedAt, &promotion.RollbackUntil)
+	if err != nil {
+		return entity.GradePromotion{}, err
+	}
+
+	return promotion, nil
+}
+
+func (repository *AcademicRepository) SavePromotionItems(tx *sqlx.Tx, items []entity.GradePromotionItem) error {
+	query := `
+		INSERT INTO grade_promotion_items (grade_promotion_uuid, student_uuid, from_grade, from_grade_level_uuid,
+			to_grade, to_grade_level_uuid, graduated)
+		VALUES (:grade_promotion_uuid, :student_uuid, :from_grade, :from_grade_level_uuid,
+			:to_grade, :to_grade_level_uuid, :graduated)
+	`
+
+	// Keeps every statement well under the bind parameter limit of Postgres
+	for start := 0; start < len(items); start += 1000 {
+		end := start + 1000
+		if end > len(items) {
+			end = len(items)
+		}
+		if _, err := tx.NamedExec(query, items[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repository *AcademicRepository) PromoteStudents(tx *sqlx.Tx, items []entity.GradePromotionItem, username string) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	studentUUIDs := make([]string, 0, len(items))
+	grades := make([]string, 0, len(items))
+	levels := make([]string, 0, len(items))
+	for _, item := range items {
+		studentUUIDs = append(studentUUIDs, item.StudentUUID.String())
+		grades = append(grades, item.ToGrade.String)
+		levels = append(levels, item.ToGradeLevelUUID.UUID.String())
+	}
+
+	query := `
+		UPDATE students s
+		SET student_grade = v.grade, grade_level_uuid = v.level, updated_at = NOW(), updated_by = $4
+		FROM UNNEST($1::UUID[], $2::VARCHAR[], $3::UUID[]) AS v(student_uuid, grade, level)
+		WHERE s.student_uuid = v.student_uuid
+	`
+
+	_, err := tx.Exec(query, pq.Array(studentUUIDs), pq.Array(grades), pq.Array(levels), username)
+	return err
+}
+
+func (repository *AcademicRepository) GraduateStudents(tx *sqlx.Tx, studentUUIDs []string, username string) error {
+	if len(studentUUIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE students
+		SET graduated_at = NOW(), deleted_at = NOW(), deleted_by = $2
+		WHERE student_uuid = ANY($1::UUID[]) AND deleted_at IS NULL
+	`
+
+	if _, err := tx.Exec(query, pq.Array(studentUUIDs), username); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE shuttle
+		SET deleted_at = NOW()
+		WHERE student_uuid = ANY($1::UUID[]) AND deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(query, pq.Array(studentUUIDs))
+	return err
+}
+
+// Puts the grades back for students nobody edited since, and restores the graduated students
+// and the shuttles that were archived together with them
+func (repository *AcademicRepository) RollbackPromotion(tx *sqlx.Tx, promotionUUID, username string) error {
+	query := `
+		UPDATE students s
+		SET student_grade = i.from_grade, grade_level_uuid = i.from_grade_level_uuid, updated_at = NOW(), updated_by = $2
+		FROM grade_promotion_items i
+		WHERE i.grade_promotion_uuid = $1 AND i.student_uuid = s.student_uuid AND NOT i.graduated
+			AND s.deleted_at IS NULL AND s.student_grade = i.to_grade
+	`
+
+	if _, err := tx.Exec(query, promotionUUID, username); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE students s
+		SET graduated_at = NULL, deleted_at = NULL, deleted_by = NULL, updated_at = NOW(), updated_by = $2
+		FROM grade_promotion_items i
+		JOIN grade_promotions p ON p.grade_promotion_uuid = i.grade_promotion_uuid
+		WHERE i.grade_promotion_uuid = $1 AND i.student_uuid = s.student_uuid AND i.graduated
+			AND s.graduated_at = p.applied_at
+	`
+
+	if _, err := tx.Exec(query, promotionUUID, username); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE shuttle sh
+		SET deleted_at = NULL
+		FROM grade_promotion_items i
+		JOIN grade_promotions p ON p.grade_promotion_uuid = i.grade_promotion_uuid
+		WHERE i.grade_promotion_uuid = $1 AND i.student_uuid = sh.student_uuid AND i.graduated
+			AND sh.deleted_at = p.applied_at
+	`
+
+	if _, err := tx.Exec(query, promotionUUID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE grade_promotions
+		SET promotion_status = 'rolled_back', rolled_back_at = NOW(), rolled_back_by = $2
+		WHERE grade_promotion_uuid = $1
+	`
+
+	_, err := tx.Exec(query, promotionUUID, username)
+	return err
+}
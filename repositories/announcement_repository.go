@@ -0,0 +1,333 @@
+package repositories
+
+import (
+	"fmt"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type AnnouncementRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	CheckTargetExists(schoolUUID, targetType, targetUUID string) (bool, error)
+	FetchAnnouncements(schoolUUID string, offset, limit int, status string) ([]entity.Announcement, error)
+	CountAnnouncements(schoolUUID, status string) (int, error)
+	FetchSpecAnnouncement(schoolUUID, announcementUUID string) (entity.Announcement, error)
+	SaveAnnouncement(tx *sqlx.Tx, announcement entity.Announcement) error
+	UpdateAnnouncement(tx *sqlx.Tx, announcement entity.Announcement) (bool, error)
+	DeleteAnnouncement(schoolUUID, announcementUUID, username string) error
+
+	FetchAnnouncementForPublish(tx *sqlx.Tx, announcementUUID string) (entity.Announcement, error)
+	SaveRecipients(tx *sqlx.Tx, announcement entity.Announcement) error
+	MarkPublished(tx *sqlx.Tx, announcementUUID string) error
+	FetchPendingRecipients(announcementUUID string, limit int) ([]string, error)
+	MarkRecipientsNotified(announcementUUID string, userUUIDs []string) error
+
+	FetchMyAnnouncements(userUUID string, offset, limit int) ([]entity.Announcement, error)
+	CountMyAnnouncements(userUUID string) (int, error)
+	MarkAnnouncementRead(userUUID, announcementUUID string) (bool, error)
+}
+
+type AnnouncementRepository struct {
+	db *sqlx.DB
+}
+
+func NewAnnouncementRepository(db *sqlx.DB) AnnouncementRepositoryInterface {
+	return &AnnouncementRepository{
+		db: db,
+	}
+}
+
+const announcementColumns = `
+	a.announcement_id, a.announcement_uuid, a.school_uuid, a.target_type, a.target_uuid, a.announcement_title,
+	a.announcement_body, a.publish_at, a.expires_at, a.published_at, a.created_at, a.created_by, a.updated_at, a.updated_by
+`
+
+const announcementStatColumns = `
+	(SELECT COUNT(*) FROM announcement_recipients r WHERE r.announcement_uuid = a.announcement_uuid) AS recipient_count,
+	(SELECT COUNT(r.read_at) FROM announcement_recipients r WHERE r.announcement_uuid = a.announcement_uuid) AS read_count
+`
+
+// The announcements of a status, the status of an announcement is worked out the same way in the service
+var announcementStatusConditions = map[string]string{
+	"":                           "TRUE",
+	entity.AnnouncementScheduled: "a.published_at IS NULL AND (a.expires_at IS NULL OR a.expires_at > NOW())",
+	entity.AnnouncementPublished: "a.published_at IS NOT NULL AND (a.expires_at IS NULL OR a.expires_at > NOW())",
+	entity.AnnouncementExpired:   "a.expires_at <= NOW()",
+}
+
+// The students an announcement is about, $1 is the school and $2 the route, grade level or trip
+var announcementTargetStudents = map[string]string{
+	entity.AnnouncementTargetSchool: `SELECT student_uuid FROM students WHERE school_uuid = $1`,
+	entity.AnnouncementTargetGrade:  `SELECT student_uuid FROM students WHERE school_uuid = $1 AND grade_level_uuid = $2`,
+	entity.AnnouncementTargetRoute: `
+		SELECT tss.student_uuid FROM trip_schedule_students tss
+		JOIN trip_schedules ts ON ts.schedule_uuid = tss.schedule_uuid
+		WHERE ts.school_uuid = $1 AND ts.route_uuid = $2 AND ts.deleted_at IS NULL
+			AND (ts.effective_to IS NULL OR ts.effective_to >= CURRENT_DATE)
+	`,
+	entity.AnnouncementTargetTrip: `
+		SELECT sh.student_uuid FROM shuttle sh
+		JOIN trips t ON t.trip_uuid = sh.trip_uuid
+		WHERE t.school_uuid = $1 AND sh.trip_uuid = $2 AND sh.deleted_at IS NULL
+	`,
+}
+
+func (repository *AnnouncementRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *AnnouncementRepository) CheckTargetExists(schoolUUID, targetType, targetUUID string) (bool, error) {
+	var exists bool
+
+	var query string
+	switch targetType {
+	case entity.AnnouncementTargetRoute:
+		query = `SELECT EXISTS (SELECT 1 FROM routes WHERE school_uuid = $1 AND route_uuid = $2 AND deleted_at IS NULL)`
+	case entity.AnnouncementTargetGrade:
+		query = `SELECT EXISTS (SELECT 1 FROM grade_levels WHERE school_uuid = $1 AND grade_level_uuid = $2 AND deleted_at IS NULL)`
+	case entity.AnnouncementTargetTrip:
+		query = `SELECT EXISTS (SELECT 1 FROM trips WHERE school_uuid = $1 AND trip_uuid = $2)`
+	default:
+		return false, nil
+	}
+
+	if err := repository.db.Get(&exists, query, schoolUUID, targetUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Empty status means any, newest first
+func (repository *AnnouncementRepository) FetchAnnouncements(schoolUUID string, offset, limit int, status string) ([]entity.Announcement, error) {
+	announcements := []entity.Announcement{}
+
+	query := `
+		SELECT ` + announcementColumns + `, ` + announcementStatColumns + `
+		FROM announcements a
+		WHERE a.school_uuid = $1 AND a.deleted_at IS NULL AND ` + announcementStatusConditions[status] + `
+		ORDER BY a.publish_at DESC, a.announcement_id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	if err := repository.db.Select(&announcements, query, schoolUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+func (repository *AnnouncementRepository) CountAnnouncements(schoolUUID, status string) (int, error) {
+	var total int
+
+	query := `
+		SELECT COUNT(*) FROM announcements a
+		WHERE a.school_uuid = $1 AND a.deleted_at IS NULL AND ` + announcementStatusConditions[status]
+
+	if err := repository.db.Get(&total, query, schoolUUID); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (repository *AnnouncementRepository) FetchSpecAnnouncement(schoolUUID, announcementUUID string) (entity.Announcement, error) {
+	var announcement entity.Announcement
+
+	query := `
+		SELECT ` + announcementColumns + `, ` + announcementStatColumns + `
+		FROM announcements a
+		WHERE a.school_uuid = $1 AND a.announcement_uuid = $2 AND a.deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&announcement, query, schoolUUID, announcementUUID); err != nil {
+		return entity.Announcement{}, err
+	}
+
+	return announcement, nil
+}
+
+func (repository *AnnouncementRepository) SaveAnnouncement(tx *sqlx.Tx, announcement entity.Announcement) error {
+	query := `
+		INSERT INTO announcements (announcement_id, announcement_uuid, school_uuid, target_type, target_uuid, announcement_title,
+			announcement_body, publish_at, expires_at, created_by)
+		VALUES (:announcement_id, :announcement_uuid, :school_uuid, :target_type, :target_uuid, :announcement_title,
+			:announcement_body, :publish_at, :expires_at, :created_by)
+	`
+
+	_, err := tx.NamedExec(query, announcement)
+	return err
+}
+
+// False means the announcement has been published in the meantime and was left as it is
+func (repository *AnnouncementRepository) UpdateAnnouncement(tx *sqlx.Tx, announcement entity.Announcement) (bool, error) {
+	query := `
+		UPDATE announcements
+		SET target_type = :target_type, target_uuid = :target_uuid, announcement_title = :announcement_title,
+			announcement_body = :announcement_body, publish_at = :publish_at, expires_at = :expires_at,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE school_uuid = :school_uuid AND announcement_uuid = :announcement_uuid AND published_at IS NULL AND deleted_at IS NULL
+	`
+
+	res, err := tx.NamedExec(query, announcement)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (repository *AnnouncementRepository) DeleteAnnouncement(schoolUUID, announcementUUID, username string) error {
+	query := `
+		UPDATE announcements
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE school_uuid = $1 AND announcement_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, schoolUUID, announcementUUID, username)
+	return err
+}
+
+// Locks the announcement until the transaction ends, deleted announcements are included
+func (repository *AnnouncementRepository) FetchAnnouncementForPublish(tx *sqlx.Tx, announcementUUID string) (entity.Announcement, error) {
+	var announcement entity.Announcement
+
+	query := `
+		SELECT ` + announcementColumns + `, a.deleted_at, a.deleted_by
+		FROM announcements a
+		WHERE a.announcement_uuid = $1
+		FOR UPDATE
+	`
+
+	if err := tx.Get(&announcement, query, announcementUUID); err != nil {
+		return entity.Announcement{}, err
+	}
+
+	return announcement, nil
+}
+
+// Takes the guardians of the targeted students as the recipients, the announcement UUID follows
+// the target arguments since the whole school has no target UUID
+func (repository *AnnouncementRepository) SaveRecipients(tx *sqlx.Tx, announcement entity.Announcement) error {
+	args := []interface{}{announcement.SchoolUUID}
+	if announcement.TargetUUID.Valid {
+		args = append(args, announcement.TargetUUID.UUID)
+	}
+	args = append(args, announcement.UUID)
+
+	query := `
+		INSERT INTO announcement_recipients (announcement_uuid, user_uuid)
+		SELECT DISTINCT ` + fmt.Sprintf("$%d", len(args)) + `::UUID, sg.guardian_uuid
+		FROM student_guardians sg
+		JOIN students s ON s.student_uuid = sg.student_uuid
+		JOIN users u ON u.user_uuid = sg.guardian_uuid
+		WHERE s.student_uuid IN (` + announcementTargetStudents[announcement.TargetType] + `)
+			AND s.deleted_at IS NULL AND s.graduated_at IS NULL AND u.deleted_at IS NULL
+		ON CONFLICT (announcement_uuid, user_uuid) DO NOTHING
+	`
+
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+func (repository *AnnouncementRepository) MarkPublished(tx *sqlx.Tx, announcementUUID string) error {
+	query := `UPDATE announcements SET published_at = NOW() WHERE announcement_uuid = $1`
+
+	_, err := tx.Exec(query, announcementUUID)
+	return err
+}
+
+func (repository *AnnouncementRepository) FetchPendingRecipients(announcementUUID string, limit int) ([]string, error) {
+	recipients := []string{}
+
+	query := `
+		SELECT user_uuid FROM announcement_recipients
+		WHERE announcement_uuid = $1 AND notified_at IS NULL
+		ORDER BY user_uuid
+		LIMIT $2
+	`
+
+	if err := repository.db.Select(&recipients, query, announcementUUID, limit); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+func (repository *AnnouncementRepository) MarkRecipientsNotified(announcementUUID string, userUUIDs []string) error {
+	query := `
+		UPDATE announcement_recipients
+		SET notified_at = NOW()
+		WHERE announcement_uuid = $1 AND user_uuid = ANY($2::UUID[])
+	`
+
+	_, err := repository.db.Exec(query, announcementUUID, pq.Array(userUUIDs))
+	return err
+}
+
+// The announcements sent to the user that are still up, newest first
+func (repository *AnnouncementRepository) FetchMyAnnouncements(userUUID string, offset, limit int) ([]entity.Announcement, error) {
+	announcements := []entity.Announcement{}
+
+	query := `
+		SELECT ` + announcementColumns + `, r.read_at
+		FROM announcements a
+		JOIN announcement_recipients r ON r.announcement_uuid = a.announcement_uuid
+		WHERE r.user_uuid = $1 AND a.deleted_at IS NULL AND ` + announcementStatusConditions[entity.AnnouncementPublished] + `
+		ORDER BY a.published_at DESC, a.announcement_id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	if err := repository.db.Select(&announcements, query, userUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+func (repository *AnnouncementRepository) CountMyAnnouncements(userUUID string) (int, error) {
+	var total int
+
+	query := `
+		SELECT COUNT(*)
+		FROM announcements a
+		JOIN announcement_recipients r ON r.announcement_uuid = a.announcement_uuid
+		WHERE r.user_uuid = $1 AND a.deleted_at IS NULL AND ` + announcementStatusConditions[entity.AnnouncementPublished]
+
+	if err := repository.db.Get(&total, query, userUUID); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Keeps the first read, false means the announcement was not sent to the user or is no longer up
+func (repository *AnnouncementRepository) MarkAnnouncementRead(userUUID, announcementUUID string) (bool, error) {
+	query := `
+		UPDATE announcement_recipients r
+		SET read_at = COALESCE(r.read_at, NOW())
+		FROM announcements a
+		WHERE r.user_uuid = $1 AND r.announcement_uuid = $2 AND a.announcement_uuid = r.announcement_uuid
+			AND a.deleted_at IS NULL AND ` + announcementStatusConditions[entity.AnnouncementPublished]
+
+	res, err := repository.db.Exec(query, userUUID, announcementUUID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
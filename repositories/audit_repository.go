@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepositoryInterface interface {
+	SaveAuditLog(tx *sqlx.Tx, log entity.AuditLog) error
+	FetchAuditLogs(filter entity.AuditLogFilter, offset, limit int) ([]entity.AuditLog, error)
+	CountAuditLogs(filter entity.AuditLogFilter) (int, error)
+}
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) AuditRepositoryInterface {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+const auditColumns = `
+	audit_id, audit_uuid, actor_uuid, actor_role_code, actor_username, impersonator_uuid, audit_action,
+	entity_type, entity_uuid, before_data, after_data, ip_address, user_agent, request_id, created_at
+`
+
+// Builds the WHERE clause shared by the audit log list and its count. Only the filters that are set
+// end up in the query so the entity and actor indexes can be used.
+func auditFilter(filter entity.AuditLogFilter) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityUUID != "" {
+		add("entity_uuid = $%d", filter.EntityUUID)
+	}
+	if filter.ActorUUID != "" {
+		add("actor_uuid = $%d", filter.ActorUUID)
+	}
+	if filter.Action != "" {
+		add("audit_action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// Without a transaction the entry is saved on its own, with one it is only kept when the change it
+// describes is committed
+func (repository *AuditRepository) SaveAuditLog(tx *sqlx.Tx, log entity.AuditLog) error {
+	var db sqlx.Ext = repository.db
+	if tx != nil {
+		db = tx
+	}
+
+	query := `
+		INSERT INTO audit_logs (
+			audit_id, audit_uuid, actor_uuid, actor_role_code, actor_username, impersonator_uuid, audit_action,
+			entity_type, entity_uuid, before_data, after_data, ip_address, user_agent, request_id
+		) VALUES (
+			:audit_id, :audit_uuid, :actor_uuid, :actor_role_code, :actor_username, :impersonator_uuid, :audit_action,
+			:entity_type, :entity_uuid, :before_data, :after_data, :ip_address, :user_agent, :request_id
+		)
+	`
+
+	_, err := sqlx.NamedExec(db, query, log)
+	return err
+}
+
+func (repository *AuditRepository) FetchAuditLogs(filter entity.AuditLogFilter, offset, limit int) ([]entity.AuditLog, error) {
+	logs := []entity.AuditLog{}
+
+	where, args := auditFilter(filter)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT `+auditColumns+`
+		FROM audit_logs
+		WHERE %s
+		ORDER BY created_at DESC, audit_id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args))
+
+	if err := repository.db.Select(&logs, query, args...); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+func (repository *AuditRepository) CountAuditLogs(filter entity.AuditLogFilter) (int, error) {
+	var total int
+
+	where, args := auditFilter(filter)
+	query := `SELECT COUNT(*) FROM audit_logs WHERE ` + where
+
+	if err := repository.db.Get(&total, query, args...); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
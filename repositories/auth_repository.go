@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"shuttle/models/entity"
 	"time"
 
@@ -14,6 +15,39 @@ type AuthRepositoryInterface interface {
 	DeleteRefreshToken(ctx context.Context, userUUID string) error
 	UpdateUserStatus(userUUID, status string, lastActive time.Time) error
 	UpdateRefreshToken(userUUID, refreshToken string) (time.Time, error)
+
+	FetchLoginAttempt(scope, key string) (entity.LoginAttempt, error)
+	ReserveLoginAttempt(scope, key string, policy LoginAttemptPolicy) (entity.LoginAttempt, bool, error)
+	ReleaseLoginAttempt(scope, key string, maxAttempts int) error
+	ResetLoginAttempts(scope, key string) error
+	SaveAccountLockout(lockout entity.AccountLockout) error
+	ReleaseAccountLockouts(userUUID string, unlockedBy string) ([]entity.AccountLockout, error)
+
+	FetchParentLoginByPhone(phone, countryCode string) ([]entity.UserDataOnLogin, error)
+	FetchLoginOTPRequestTimes(field, value string, since time.Time) ([]time.Time, error)
+	FetchActiveLoginOTP(phone string) (entity.LoginOTP, error)
+	SaveLoginOTP(otp entity.LoginOTP) error
+	ReserveLoginOTPAttempt(otpID int64, maxAttempts int) (bool, error)
+	ConsumeLoginOTP(otpID int64) (bool, error)
+
+	DeleteExpiredRefreshTokens() (int64, error)
+	DeleteLoginOTPsBefore(before time.Time) (int64, error)
+}
+
+const (
+	LoginOTPFieldPhone = "phone"
+	LoginOTPFieldIP    = "ip"
+)
+
+// How many attempts a login scope gets within Window before it is locked for LockoutDuration, and
+// how the wait between attempts grows once BackoffAfter attempts were made
+type LoginAttemptPolicy struct {
+	Window          time.Duration
+	MaxAttempts     int
+	LockoutDuration time.Duration
+	BackoffAfter    int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
 }
 
 type authRepository struct {
@@ -129,4 +163,248 @@ func (r *authRepository) UpdateRefreshToken(userUUID, refreshToken string) (time
 	}
 
 	return lastUsedAt, nil
-}
\ No newline at end of file
+}
+
+func (r *authRepository) FetchLoginAttempt(scope, key string) (entity.LoginAttempt, error) {
+	query := `
+		SELECT attempt_scope, attempt_key, failed_count, last_failed_at, locked_until
+		FROM login_attempts
+		WHERE attempt_scope = $1 AND attempt_key = $2
+	`
+
+	var attempt entity.LoginAttempt
+	err := r.DB.Get(&attempt, query, scope, key)
+	if err == sql.ErrNoRows {
+		return entity.LoginAttempt{Scope: scope, Key: key}, nil
+	}
+	if err != nil {
+		return entity.LoginAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// Counts an attempt before its password is checked and locks the scope once MaxAttempts is reached,
+// all in one statement so parallel attempts cannot pass a check made before any of them was counted.
+// The count starts over when the previous attempt is older than the window. Nothing is counted and
+// false is returned while the scope is locked or the backoff since the last attempt has not passed.
+func (r *authRepository) ReserveLoginAttempt(scope, key string, policy LoginAttemptPolicy) (entity.LoginAttempt, bool, error) {
+	query := `
+		INSERT INTO login_attempts AS a (attempt_scope, attempt_key, failed_count, last_failed_at, locked_until)
+		VALUES ($1, $2, 1, NOW(), CASE WHEN $4::INT <= 1 THEN NOW() + make_interval(secs => $5) END)
+		ON CONFLICT (attempt_scope, attempt_key)
+		DO UPDATE SET
+			failed_count = CASE
+				WHEN a.last_failed_at < NOW() - make_interval(secs => $3) THEN 1
+				ELSE a.failed_count + 1
+			END,
+			last_failed_at = NOW(),
+			locked_until = CASE
+				WHEN a.last_failed_at < NOW() - make_interval(secs => $3) AND $4::INT > 1 THEN NULL
+				WHEN a.last_failed_at < NOW() - make_interval(secs => $3) OR a.failed_count + 1 >= $4::INT
+				THEN NOW() + make_interval(secs => $5)
+			END
+		WHERE (a.locked_until IS NULL OR a.locked_until <= NOW())
+			AND (a.last_failed_at IS NULL OR a.failed_count < $6::INT
+				OR a.last_failed_at + make_interval(secs => LEAST($7::FLOAT8 * power(2, a.failed_count - $6::INT), $8::FLOAT8)) <= NOW())
+		RETURNING attempt_scope, attempt_key, failed_count, last_failed_at, locked_until
+	`
+
+	var attempt entity.LoginAttempt
+	err := r.DB.Get(&attempt, query, scope, key, policy.Window.Seconds(), policy.MaxAttempts, policy.LockoutDuration.Seconds(),
+		policy.BackoffAfter, policy.BackoffBase.Seconds(), policy.BackoffMax.Seconds())
+	if err == sql.ErrNoRows {
+		return entity.LoginAttempt{}, false, nil
+	}
+	if err != nil {
+		return entity.LoginAttempt{}, false, err
+	}
+
+	return attempt, true, nil
+}
+
+// Takes back an attempt that turned out not to be a failure, lifting the lock it may have set
+func (r *authRepository) ReleaseLoginAttempt(scope, key string, maxAttempts int) error {
+	query := `
+		UPDATE login_attempts
+		SET failed_count = GREATEST(failed_count - 1, 0),
+			locked_until = CASE WHEN failed_count - 1 < $3 THEN NULL ELSE locked_until END
+		WHERE attempt_scope = $1 AND attempt_key = $2
+	`
+
+	_, err := r.DB.Exec(query, scope, key, maxAttempts)
+	return err
+}
+
+func (r *authRepository) ResetLoginAttempts(scope, key string) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE attempt_scope = $1 AND attempt_key = $2
+	`
+
+	_, err := r.DB.Exec(query, scope, key)
+	return err
+}
+
+func (r *authRepository) SaveAccountLockout(lockout entity.AccountLockout) error {
+	query := `
+		INSERT INTO account_lockouts (lockout_id, lockout_uuid, user_uuid, lockout_scope, lockout_key, ip_address, failed_count, locked_until)
+		VALUES (:lockout_id, :lockout_uuid, :user_uuid, :lockout_scope, :lockout_key, :ip_address, :failed_count, :locked_until)
+	`
+
+	_, err := r.DB.NamedExec(query, lockout)
+	return err
+}
+
+// Ends the open lockouts of the user and returns them, so the caller can clear the scopes they locked
+func (r *authRepository) ReleaseAccountLockouts(userUUID string, unlockedBy string) ([]entity.AccountLockout, error) {
+	query := `
+		UPDATE account_lockouts
+		SET unlocked_at = NOW(), unlocked_by = $1
+		WHERE user_uuid = $2 AND unlocked_at IS NULL
+		RETURNING lockout_scope, lockout_key
+	`
+
+	var lockouts []entity.AccountLockout
+	if err := r.DB.Select(&lockouts, query, unlockedBy, userUUID); err != nil {
+		return nil, err
+	}
+
+	return lockouts, nil
+}
+
+// Parents are matched on the digits of their phone number with a local leading 0 replaced by the
+// country code, so "+62 812-...", "62812..." and "0812..." are the same. More than one row means the
+// number is shared and the caller should not pick one.
+func (r *authRepository) FetchParentLoginByPhone(phone, countryCode string) ([]entity.UserDataOnLogin, error) {
+	query := `
+		SELECT u.user_id, u.user_uuid, u.user_username, u.user_role_code, u.user_password
+		FROM users u
+		JOIN parent_details pd ON pd.user_uuid = u.user_uuid
+		WHERE regexp_replace(regexp_replace(regexp_replace(pd.user_phone, '[^0-9]', '', 'g'), '^00', ''), '^0', $2) = $1
+			AND u.user_role = 'parent' AND u.deleted_at IS NULL
+		ORDER BY u.user_id ASC
+		LIMIT 2
+	`
+
+	var users []entity.UserDataOnLogin
+	if err := r.DB.Select(&users, query, phone, countryCode); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *authRepository) FetchLoginOTPRequestTimes(field, value string, since time.Time) ([]time.Time, error) {
+	column := "otp_phone"
+	if field == LoginOTPFieldIP {
+		column = "ip_address"
+	}
+
+	query := `
+		SELECT created_at
+		FROM login_otps
+		WHERE ` + column + ` = $1 AND created_at >= $2
+		ORDER BY created_at ASC
+	`
+
+	var times []time.Time
+	if err := r.DB.Select(&times, query, value, since); err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
+
+func (r *authRepository) FetchActiveLoginOTP(phone string) (entity.LoginOTP, error) {
+	query := `
+		SELECT otp_id, otp_uuid, user_uuid, otp_phone, otp_code_hash, ip_address, verify_attempts,
+			expires_at, consumed_at, created_at
+		FROM login_otps
+		WHERE otp_phone = $1 AND otp_code_hash IS NOT NULL
+			AND consumed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var otp entity.LoginOTP
+	if err := r.DB.Get(&otp, query, phone); err != nil {
+		return entity.LoginOTP{}, err
+	}
+
+	return otp, nil
+}
+
+func (r *authRepository) SaveLoginOTP(otp entity.LoginOTP) error {
+	query := `
+		INSERT INTO login_otps (otp_id, otp_uuid, user_uuid, otp_phone, otp_code_hash, ip_address, expires_at)
+		VALUES (:otp_id, :otp_uuid, :user_uuid, :otp_phone, :otp_code_hash, :ip_address, :expires_at)
+	`
+
+	_, err := r.DB.NamedExec(query, otp)
+	return err
+}
+
+// Counts a verification attempt before the code is compared, false means the code has no attempts
+// left. Doing it in one statement keeps concurrent guesses from all passing the limit check.
+func (r *authRepository) ReserveLoginOTPAttempt(otpID int64, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE login_otps
+		SET verify_attempts = verify_attempts + 1
+		WHERE otp_id = $1 AND verify_attempts < $2 AND consumed_at IS NULL
+		RETURNING verify_attempts
+	`
+
+	var attempts int
+	if err := r.DB.Get(&attempts, query, otpID, maxAttempts); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Marks the code as used, false means another request consumed it first
+func (r *authRepository) ConsumeLoginOTP(otpID int64) (bool, error) {
+	query := `
+		UPDATE login_otps
+		SET consumed_at = NOW()
+		WHERE otp_id = $1 AND consumed_at IS NULL
+	`
+
+	res, err := r.DB.Exec(query, otpID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *authRepository) DeleteExpiredRefreshTokens() (int64, error) {
+	query := `DELETE FROM refresh_tokens WHERE expired_at < NOW() OR is_revoked`
+
+	res, err := r.DB.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *authRepository) DeleteLoginOTPsBefore(before time.Time) (int64, error) {
+	query := `DELETE FROM login_otps WHERE created_at < $1`
+
+	res, err := r.DB.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -32,12 +32,12 @@ func (repositories *childernRepository) FetchAllChilderns(id string) ([]entity.S
             s.student_last_name,
             s.student_gender,
             s.student_grade,
-            s.parent_uuid,
             s.school_uuid,
             sc.school_name
         FROM students s
+        JOIN student_guardians sg ON s.student_uuid = sg.student_uuid
         JOIN schools sc ON s.school_uuid = sc.school_uuid
-        WHERE s.parent_uuid = $1
+        WHERE sg.guardian_uuid = $1 AND s.deleted_at IS NULL
     `
 
 	rows, err := repositories.DB.Queryx(query, id)
@@ -50,7 +50,7 @@ func (repositories *childernRepository) FetchAllChilderns(id string) ([]entity.S
 		var childern entity.Student
 		var schoolName string
 
-		if err := rows.Scan(&childern.UUID, &childern.FirstName, &childern.LastName, &childern.Gender, &childern.Grade, &childern.ParentUUID, &childern.SchoolUUID, &schoolName); err != nil {
+		if err := rows.Scan(&childern.UUID, &childern.FirstName, &childern.LastName, &childern.Gender, &childern.Grade, &childern.SchoolUUID, &schoolName); err != nil {
 			return nil, err
 		}
 
@@ -72,7 +72,6 @@ func (repositories *childernRepository) FetchSpecChildern(id string) (entity.Stu
             s.student_last_name,
             s.student_gender,
             s.student_grade,
-            s.parent_uuid,
             s.school_uuid,
             sc.school_name
         FROM students s
@@ -86,7 +85,6 @@ func (repositories *childernRepository) FetchSpecChildern(id string) (entity.Stu
 		&childern.LastName, 
 		&childern.Gender, 
 		&childern.Grade, 
-		&childern.ParentUUID, 
 		&childern.SchoolUUID,
 		&childern.SchoolName,
 	)
@@ -0,0 +1,401 @@
+package repositories
+
+import (
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ConversationRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchStudentSchool(studentUUID string) (uuid.UUID, error)
+	FetchTripSchool(tripUUID string) (uuid.UUID, error)
+	CheckStudentOnTrip(tripUUID, studentUUID string) (bool, error)
+	FetchGuardianContacts(studentUUID, tripUUID string) ([]entity.ConversationContact, error)
+	FetchDriverContacts(studentUUID, tripUUID string) ([]entity.ConversationContact, error)
+	FetchSchoolAdminContacts(schoolUUID string) ([]entity.ConversationContact, error)
+
+	FetchConversations(userUUID string, offset, limit int) ([]entity.Conversation, error)
+	CountConversations(userUUID string) (int, error)
+	FetchSchoolConversations(schoolUUID, studentUUID string, offset, limit int) ([]entity.Conversation, error)
+	CountSchoolConversations(schoolUUID, studentUUID string) (int, error)
+	FetchSpecConversation(conversationUUID string) (entity.Conversation, error)
+	FindConversation(studentUUID, tripUUID uuid.NullUUID, participantUUIDs []string) (uuid.UUID, error)
+	FetchParticipants(conversationUUIDs []string) ([]entity.ConversationParticipant, error)
+	SaveConversation(tx *sqlx.Tx, conversation entity.Conversation, participantUUIDs []string) error
+
+	FetchMessages(conversationUUID string, offset, limit int) ([]entity.Message, error)
+	CountMessages(conversationUUID string) (int, error)
+	SaveMessage(tx *sqlx.Tx, message entity.Message) (time.Time, error)
+	MarkConversationRead(tx *sqlx.Tx, conversationUUID, userUUID string, readAt time.Time) (time.Time, error)
+	DeleteMessage(conversationUUID, messageUUID, username string) (bool, error)
+}
+
+type ConversationRepository struct {
+	db *sqlx.DB
+}
+
+func NewConversationRepository(db *sqlx.DB) ConversationRepositoryInterface {
+	return &ConversationRepository{
+		db: db,
+	}
+}
+
+// Users keep their names in the details table of their role
+const userNameJoins = `
+	LEFT JOIN parent_details pd ON pd.user_uuid = u.user_uuid
+	LEFT JOIN driver_details dd ON dd.user_uuid = u.user_uuid
+	LEFT JOIN school_admin_details sad ON sad.user_uuid = u.user_uuid
+`
+
+const userNameColumns = `
+	COALESCE(pd.user_first_name, dd.user_first_name, sad.user_first_name) AS user_first_name,
+	COALESCE(pd.user_last_name, dd.user_last_name, sad.user_last_name) AS user_last_name
+`
+
+const conversationColumns = `
+	c.conversation_id, c.conversation_uuid, c.school_uuid, c.student_uuid, c.trip_uuid,
+	s.student_first_name, s.student_last_name, c.last_message_at, c.created_at, c.created_by
+`
+
+func (repository *ConversationRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *ConversationRepository) FetchStudentSchool(studentUUID string) (uuid.UUID, error) {
+	var schoolUUID uuid.UUID
+
+	query := `SELECT school_uuid FROM students WHERE student_uuid = $1 AND deleted_at IS NULL`
+
+	if err := repository.db.Get(&schoolUUID, query, studentUUID); err != nil {
+		return uuid.Nil, err
+	}
+
+	return schoolUUID, nil
+}
+
+func (repository *ConversationRepository) FetchTripSchool(tripUUID string) (uuid.UUID, error) {
+	var schoolUUID uuid.UUID
+
+	query := `SELECT school_uuid FROM trips WHERE trip_uuid = $1`
+
+	if err := repository.db.Get(&schoolUUID, query, tripUUID); err != nil {
+		return uuid.Nil, err
+	}
+
+	return schoolUUID, nil
+}
+
+func (repository *ConversationRepository) CheckStudentOnTrip(tripUUID, studentUUID string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM shuttle WHERE trip_uuid = $1 AND student_uuid = $2 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, tripUUID, studentUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *ConversationRepository) fetchContacts(relation, userUUIDs string, args ...interface{}) ([]entity.ConversationContact, error) {
+	contacts := []entity.ConversationContact{}
+
+	query := `
+		SELECT u.user_uuid, u.user_role_code, ` + userNameColumns + `, '` + relation + `' AS relation
+		FROM users u
+		` + userNameJoins + `
+		WHERE u.deleted_at IS NULL AND u.user_uuid IN (` + userUUIDs + `)
+	`
+
+	if err := repository.db.Select(&contacts, query, args...); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// The guardians of the student, or of every student on the trip when no student is given
+func (repository *ConversationRepository) FetchGuardianContacts(studentUUID, tripUUID string) ([]entity.ConversationContact, error) {
+	if studentUUID != "" {
+		return repository.fetchContacts(entity.ContactGuardian, `SELECT guardian_uuid FROM student_guardians WHERE student_uuid = $1`, studentUUID)
+	}
+
+	return repository.fetchContacts(entity.ContactGuardian, `
+		SELECT sg.guardian_uuid FROM student_guardians sg
+		JOIN shuttle sh ON sh.student_uuid = sg.student_uuid
+		WHERE sh.trip_uuid = $1 AND sh.deleted_at IS NULL
+	`, tripUUID)
+}
+
+// The drivers currently assigned: the driver of the trip as long as it has not passed, or for a
+// student the drivers of today's shuttles and of the schedules the student rides on today
+func (repository *ConversationRepository) FetchDriverContacts(studentUUID, tripUUID string) ([]entity.ConversationContact, error) {
+	if tripUUID != "" {
+		return repository.fetchContacts(entity.ContactDriver, `
+			SELECT driver_uuid FROM trips WHERE trip_uuid = $1 AND trip_date >= CURRENT_DATE
+		`, tripUUID)
+	}
+
+	return repository.fetchContacts(entity.ContactDriver, `
+		SELECT sh.driver_uuid FROM shuttle sh
+		LEFT JOIN trips t ON t.trip_uuid = sh.trip_uuid
+		WHERE sh.student_uuid = $1 AND sh.deleted_at IS NULL AND COALESCE(t.trip_date, DATE(sh.created_at)) = CURRENT_DATE
+		UNION
+		SELECT ts.driver_uuid FROM trip_schedules ts
+		JOIN trip_schedule_students tss ON tss.schedule_uuid = ts.schedule_uuid
+		WHERE tss.student_uuid = $1 AND ts.deleted_at IS NULL
+			AND ts.effective_from <= CURRENT_DATE AND (ts.effective_to IS NULL OR ts.effective_to >= CURRENT_DATE)
+	`, studentUUID)
+}
+
+func (repository *ConversationRepository) FetchSchoolAdminContacts(schoolUUID string) ([]entity.ConversationContact, error) {
+	return repository.fetchContacts(entity.ContactSchoolAdmin, `SELECT user_uuid FROM school_admin_details WHERE school_uuid = $1`, schoolUUID)
+}
+
+// The user's conversations with the number of messages from others sent after the user last read it
+func (repository *ConversationRepository) FetchConversations(userUUID string, offset, limit int) ([]entity.Conversation, error) {
+	conversations := []entity.Conversation{}
+
+	query := `
+		SELECT ` + conversationColumns + `,
+			(
+				SELECT COUNT(*) FROM messages m
+				WHERE m.conversation_uuid = c.conversation_uuid AND m.deleted_at IS NULL
+					AND m.sender_uuid IS DISTINCT FROM p.user_uuid AND m.created_at > COALESCE(p.last_read_at, '-infinity')
+			) AS unread_count
+		FROM conversation_participants p
+		JOIN conversations c ON c.conversation_uuid = p.conversation_uuid
+		LEFT JOIN students s ON s.student_uuid = c.student_uuid
+		WHERE p.user_uuid = $1
+		ORDER BY c.last_message_at DESC, c.conversation_id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	if err := repository.db.Select(&conversations, query, userUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+func (repository *ConversationRepository) CountConversations(userUUID string) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM conversation_participants WHERE user_uuid = $1`
+
+	if err := repository.db.Get(&total, query, userUUID); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Empty studentUUID lists every conversation of the school
+func (repository *ConversationRepository) FetchSchoolConversations(schoolUUID, studentUUID string, offset, limit int) ([]entity.Conversation, error) {
+	conversations := []entity.Conversation{}
+
+	query := `
+		SELECT ` + conversationColumns + `
+		FROM conversations c
+		LEFT JOIN students s ON s.student_uuid = c.student_uuid
+		WHERE c.school_uuid = $1 AND ($2 = '' OR c.student_uuid = NULLIF($2, '')::UUID)
+		ORDER BY c.last_message_at DESC, c.conversation_id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	if err := repository.db.Select(&conversations, query, schoolUUID, studentUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+func (repository *ConversationRepository) CountSchoolConversations(schoolUUID, studentUUID string) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM conversations WHERE school_uuid = $1 AND ($2 = '' OR student_uuid = NULLIF($2, '')::UUID)`
+
+	if err := repository.db.Get(&total, query, schoolUUID, studentUUID); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (repository *ConversationRepository) FetchSpecConversation(conversationUUID string) (entity.Conversation, error) {
+	var conversation entity.Conversation
+
+	query := `
+		SELECT ` + conversationColumns + `
+		FROM conversations c
+		LEFT JOIN students s ON s.student_uuid = c.student_uuid
+		WHERE c.conversation_uuid = $1
+	`
+
+	if err := repository.db.Get(&conversation, query, conversationUUID); err != nil {
+		return entity.Conversation{}, err
+	}
+
+	return conversation, nil
+}
+
+// The conversation about the same student and trip between exactly the same people, participantUUIDs
+// must be sorted. Returns sql.ErrNoRows when there is none yet.
+func (repository *ConversationRepository) FindConversation(studentUUID, tripUUID uuid.NullUUID, participantUUIDs []string) (uuid.UUID, error) {
+	var conversationUUID uuid.UUID
+
+	query := `
+		SELECT c.conversation_uuid
+		FROM conversations c
+		WHERE c.student_uuid IS NOT DISTINCT FROM $1 AND c.trip_uuid IS NOT DISTINCT FROM $2
+			AND ARRAY(
+				SELECT p.user_uuid FROM conversation_participants p
+				WHERE p.conversation_uuid = c.conversation_uuid
+				ORDER BY p.user_uuid
+			) = $3::UUID[]
+		ORDER BY c.last_message_at DESC
+		LIMIT 1
+	`
+
+	if err := repository.db.Get(&conversationUUID, query, studentUUID, tripUUID, pq.Array(participantUUIDs)); err != nil {
+		return uuid.Nil, err
+	}
+
+	return conversationUUID, nil
+}
+
+func (repository *ConversationRepository) FetchParticipants(conversationUUIDs []string) ([]entity.ConversationParticipant, error) {
+	participants := []entity.ConversationParticipant{}
+
+	query := `
+		SELECT p.conversation_uuid, p.user_uuid, u.user_role_code, u.user_role, ` + userNameColumns + `, p.last_read_at, p.joined_at
+		FROM conversation_participants p
+		JOIN users u ON u.user_uuid = p.user_uuid
+		` + userNameJoins + `
+		WHERE p.conversation_uuid = ANY($1::UUID[])
+		ORDER BY p.joined_at, p.user_uuid
+	`
+
+	if err := repository.db.Select(&participants, query, pq.Array(conversationUUIDs)); err != nil {
+		return nil, err
+	}
+
+	return participants, nil
+}
+
+func (repository *ConversationRepository) SaveConversation(tx *sqlx.Tx, conversation entity.Conversation, participantUUIDs []string) error {
+	query := `
+		INSERT INTO conversations (conversation_id, conversation_uuid, school_uuid, student_uuid, trip_uuid, created_by)
+		VALUES (:conversation_id, :conversation_uuid, :school_uuid, :student_uuid, :trip_uuid, :created_by)
+	`
+	if _, err := tx.NamedExec(query, conversation); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO conversation_participants (conversation_uuid, user_uuid)
+		SELECT $1, UNNEST($2::UUID[])
+	`
+	_, err := tx.Exec(query, conversation.UUID, pq.Array(participantUUIDs))
+	return err
+}
+
+// Newest first, removed messages are included so moderators can still see them
+func (repository *ConversationRepository) FetchMessages(conversationUUID string, offset, limit int) ([]entity.Message, error) {
+	messages := []entity.Message{}
+
+	query := `
+		SELECT m.message_id, m.message_uuid, m.conversation_uuid, m.sender_uuid, ` + userNameColumns + `,
+			m.message_body, m.created_at, m.deleted_at, m.deleted_by
+		FROM messages m
+		LEFT JOIN users u ON u.user_uuid = m.sender_uuid
+		` + userNameJoins + `
+		WHERE m.conversation_uuid = $1
+		ORDER BY m.created_at DESC, m.message_id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	if err := repository.db.Select(&messages, query, conversationUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (repository *ConversationRepository) CountMessages(conversationUUID string) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM messages WHERE conversation_uuid = $1`
+
+	if err := repository.db.Get(&total, query, conversationUUID); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Returns when the database received the message, read receipts are compared against it
+func (repository *ConversationRepository) SaveMessage(tx *sqlx.Tx, message entity.Message) (time.Time, error) {
+	var createdAt time.Time
+
+	query := `
+		INSERT INTO messages (message_id, message_uuid, conversation_uuid, sender_uuid, message_body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	if err := tx.Get(&createdAt, query, message.ID, message.UUID, message.ConversationUUID, message.SenderUUID, message.Body); err != nil {
+		return time.Time{}, err
+	}
+
+	query = `UPDATE conversations SET last_message_at = $2 WHERE conversation_uuid = $1`
+	if _, err := tx.Exec(query, message.ConversationUUID, createdAt); err != nil {
+		return time.Time{}, err
+	}
+
+	return createdAt, nil
+}
+
+// Moves the participant's read receipt forward to readAt, it never moves back. Returns the receipt.
+func (repository *ConversationRepository) MarkConversationRead(tx *sqlx.Tx, conversationUUID, userUUID string, readAt time.Time) (time.Time, error) {
+	var lastReadAt time.Time
+
+	query := `
+		UPDATE conversation_participants
+		SET last_read_at = GREATEST(COALESCE(last_read_at, $3), $3)
+		WHERE conversation_uuid = $1 AND user_uuid = $2
+		RETURNING last_read_at
+	`
+
+	if err := tx.Get(&lastReadAt, query, conversationUUID, userUUID, readAt); err != nil {
+		return time.Time{}, err
+	}
+
+	return lastReadAt, nil
+}
+
+// False means the message is not in the conversation or was already removed
+func (repository *ConversationRepository) DeleteMessage(conversationUUID, messageUUID, username string) (bool, error) {
+	query := `
+		UPDATE messages
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE conversation_uuid = $1 AND message_uuid = $2 AND deleted_at IS NULL
+	`
+
+	res, err := repository.db.Exec(query, conversationUUID, messageUUID, username)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DriverDocumentRepositoryInterface interface {
+	FetchDriverSchool(driverUUID string) (sql.NullString, error)
+	FetchDriverDocuments(driverUUID string) ([]entity.DriverDocument, error)
+	FetchSpecDriverDocument(driverUUID, documentUUID string) (entity.DriverDocument, error)
+	HasExpiredLicense(driverUUID string) (bool, error)
+
+	SaveDriverDocument(document entity.DriverDocument) error
+	UpdateDriverDocument(document entity.DriverDocument) error
+	DeleteDriverDocument(driverUUID, documentUUID, username string) error
+
+	FetchExpiringDocuments(withinDays int) ([]entity.ExpiringDriverDocument, error)
+	SaveDocumentAlert(document entity.ExpiringDriverDocument, daysBefore int) (bool, error)
+	FetchSchoolAdminPhones(schoolUUID string) ([]string, error)
+}
+
+type DriverDocumentRepository struct {
+	db *sqlx.DB
+}
+
+func NewDriverDocumentRepository(db *sqlx.DB) DriverDocumentRepositoryInterface {
+	return &DriverDocumentRepository{
+		db: db,
+	}
+}
+
+// Returns sql.ErrNoRows when the user is not an active driver, the school is null for drivers not bound to one
+func (repository *DriverDocumentRepository) FetchDriverSchool(driverUUID string) (sql.NullString, error) {
+	var schoolUUID sql.NullString
+
+	query := `
+		SELECT d.school_uuid
+		FROM users u
+		JOIN driver_details d ON u.user_uuid = d.user_uuid
+		WHERE u.user_uuid = $1 AND u.user_role = 'driver' AND u.deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&schoolUUID, query, driverUUID); err != nil {
+		return sql.NullString{}, err
+	}
+
+	return schoolUUID, nil
+}
+
+func (repository *DriverDocumentRepository) FetchDriverDocuments(driverUUID string) ([]entity.DriverDocument, error) {
+	documents := []entity.DriverDocument{}
+
+	query := `
+		SELECT document_id, document_uuid, driver_uuid, document_type, document_number, issued_on, expires_on, document_file,
+			created_at, created_by, updated_at, updated_by
+		FROM driver_documents
+		WHERE driver_uuid = $1 AND deleted_at IS NULL
+		ORDER BY document_type, issued_on DESC
+	`
+
+	if err := repository.db.Select(&documents, query, driverUUID); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (repository *DriverDocumentRepository) FetchSpecDriverDocument(driverUUID, documentUUID string) (entity.DriverDocument, error) {
+	var document entity.DriverDocument
+
+	query := `
+		SELECT document_id, document_uuid, driver_uuid, document_type, document_number, issued_on, expires_on, document_file,
+			created_at, created_by, updated_at, updated_by
+		FROM driver_documents
+		WHERE driver_uuid = $1 AND document_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&document, query, driverUUID, documentUUID); err != nil {
+		return entity.DriverDocument{}, err
+	}
+
+	return document, nil
+}
+
+// A driver is blocked once every licence on file has expired. Drivers without any licence
+// document are let through, they predate document tracking and only have user_license_number.
+func (repository *DriverDocumentRepository) HasExpiredLicense(driverUUID string) (bool, error) {
+	var expired bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM driver_documents
+			WHERE driver_uuid = $1 AND document_type = 'license' AND deleted_at IS NULL
+		) AND NOT EXISTS (
+			SELECT 1 FROM driver_documents
+			WHERE driver_uuid = $1 AND document_type = 'license' AND deleted_at IS NULL
+				AND (expires_on IS NULL OR expires_on >= CURRENT_DATE)
+		)
+	`
+
+	if err := repository.db.Get(&expired, query, driverUUID); err != nil {
+		return false, err
+	}
+
+	return expired, nil
+}
+
+func (repository *DriverDocumentRepository) SaveDriverDocument(document entity.DriverDocument) error {
+	query := `
+		INSERT INTO driver_documents (document_id, document_uuid, driver_uuid, document_type, document_number, issued_on, expires_on, document_file, created_by)
+		VALUES (:document_id, :document_uuid, :driver_uuid, :document_type, :document_number, :issued_on, :expires_on, :document_file, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, document)
+	return err
+}
+
+func (repository *DriverDocumentRepository) UpdateDriverDocument(document entity.DriverDocument) error {
+	query := `
+		UPDATE driver_documents
+		SET document_type = :document_type, document_number = :document_number, issued_on = :issued_on, expires_on = :expires_on,
+			document_file = :document_file, updated_at = NOW(), updated_by = :updated_by
+		WHERE document_uuid = :document_uuid AND driver_uuid = :driver_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, document)
+	return err
+}
+
+func (repository *DriverDocumentRepository) DeleteDriverDocument(driverUUID, documentUUID, username string) error {
+	query := `
+		UPDATE driver_documents
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE driver_uuid = $1 AND document_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, driverUUID, documentUUID, username)
+	return err
+}
+
+func (repository *DriverDocumentRepository) FetchExpiringDocuments(withinDays int) ([]entity.ExpiringDriverDocument, error) {
+	documents := []entity.ExpiringDriverDocument{}
+
+	query := `
+		SELECT doc.document_uuid, doc.document_type, doc.document_number, doc.expires_on, doc.expires_on - CURRENT_DATE AS days_left,
+			d.user_first_name, d.user_last_name, d.school_uuid
+		FROM driver_documents doc
+		JOIN users u ON u.user_uuid = doc.driver_uuid AND u.deleted_at IS NULL
+		JOIN driver_details d ON d.user_uuid = doc.driver_uuid
+		WHERE doc.deleted_at IS NULL AND d.school_uuid IS NOT NULL
+			AND doc.expires_on BETWEEN CURRENT_DATE AND CURRENT_DATE + $1::int
+		ORDER BY doc.expires_on
+	`
+
+	if err := repository.db.Select(&documents, query, withinDays); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// Records that a warning goes out, false means it was already sent for this expiry date
+func (repository *DriverDocumentRepository) SaveDocumentAlert(document entity.ExpiringDriverDocument, daysBefore int) (bool, error) {
+	query := `
+		INSERT INTO driver_document_alerts (document_uuid, days_before, expires_on)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := repository.db.Exec(query, document.DocumentUUID, daysBefore, document.ExpiresOn.Format(time.DateOnly))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (repository *DriverDocumentRepository) FetchSchoolAdminPhones(schoolUUID string) ([]string, error) {
+	phones := []string{}
+
+	query := `
+		SELECT a.user_phone
+		FROM school_admin_details a
+		JOIN users u ON u.user_uuid = a.user_uuid
+		WHERE a.school_uuid = $1 AND u.deleted_at IS NULL AND a.user_phone <> ''
+	`
+
+	if err := repository.db.Select(&phones, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return phones, nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ImpersonationRepositoryInterface interface {
+	FetchImpersonation(impersonationUUID string) (entity.Impersonation, error)
+	SaveImpersonation(impersonation entity.Impersonation) error
+	EndImpersonation(impersonationUUID string) error
+	SaveImpersonationRequest(request entity.ImpersonationRequest) error
+}
+
+type ImpersonationRepository struct {
+	db *sqlx.DB
+}
+
+func NewImpersonationRepository(db *sqlx.DB) ImpersonationRepositoryInterface {
+	return &ImpersonationRepository{
+		db: db,
+	}
+}
+
+func (repository *ImpersonationRepository) FetchImpersonation(impersonationUUID string) (entity.Impersonation, error) {
+	var impersonation entity.Impersonation
+
+	query := `
+		SELECT impersonation_id, impersonation_uuid, admin_uuid, target_uuid, impersonation_reason,
+			COALESCE(ip_address, '') AS ip_address, started_at, expires_at, ended_at
+		FROM impersonations
+		WHERE impersonation_uuid = $1
+	`
+
+	if err := repository.db.Get(&impersonation, query, impersonationUUID); err != nil {
+		return entity.Impersonation{}, err
+	}
+
+	return impersonation, nil
+}
+
+func (repository *ImpersonationRepository) SaveImpersonation(impersonation entity.Impersonation) error {
+	query := `
+		INSERT INTO impersonations (impersonation_id, impersonation_uuid, admin_uuid, target_uuid, impersonation_reason, ip_address, expires_at)
+		VALUES (:impersonation_id, :impersonation_uuid, :admin_uuid, :target_uuid, :impersonation_reason, :ip_address, :expires_at)
+	`
+
+	if _, err := repository.db.NamedExec(query, impersonation); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ImpersonationRepository) EndImpersonation(impersonationUUID string) error {
+	query := `
+		UPDATE impersonations
+		SET ended_at = NOW()
+		WHERE impersonation_uuid = $1 AND ended_at IS NULL
+	`
+
+	if _, err := repository.db.Exec(query, impersonationUUID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ImpersonationRepository) SaveImpersonationRequest(request entity.ImpersonationRequest) error {
+	query := `
+		INSERT INTO impersonation_requests (request_id, impersonation_uuid, request_method, request_path, response_status)
+		VALUES (:request_id, :impersonation_uuid, :request_method, :request_path, :response_status)
+	`
+
+	if _, err := repository.db.NamedExec(query, request); err != nil {
+		return err
+	}
+
+	return nil
+}
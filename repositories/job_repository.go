@@ -0,0 +1,305 @@
+package repositories
+
+import (
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type JobRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchJobs(offset, limit int, status, name string) ([]entity.Job, error)
+	CountJobs(status, name string) (int, error)
+	FetchSpecJob(jobUUID string) (entity.Job, error)
+	RequeueJob(jobUUID string) (bool, error)
+
+	SaveJob(tx *sqlx.Tx, job entity.Job) error
+	ClaimJob(workerID string, lockTimeout time.Duration) (entity.Job, error)
+	CompleteJob(jobID int64, workerID string, attempt int) (bool, error)
+	RetryJobLater(jobID int64, workerID string, attempt int, lastError string, runAt time.Time) (bool, error)
+	FailJob(jobID int64, workerID string, attempt int, lastError string) (bool, error)
+	FailAbandonedJobs(lockTimeout time.Duration) (int64, error)
+	DeleteFinishedJobs(before time.Time) (int64, error)
+
+	FetchSchedules() ([]entity.JobSchedule, error)
+	SyncSchedule(name, spec string, nextRunAt time.Time) error
+	FetchDueSchedules(tx *sqlx.Tx, names []string) ([]entity.JobSchedule, error)
+	CheckJobActive(tx *sqlx.Tx, name string) (bool, error)
+	UpdateScheduleRun(tx *sqlx.Tx, name string, nextRunAt time.Time, jobUUID string) error
+}
+
+type JobRepository struct {
+	db *sqlx.DB
+}
+
+func NewJobRepository(db *sqlx.DB) JobRepositoryInterface {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+const jobColumns = `
+	job_id, job_uuid, job_name, job_payload, job_status, attempts, max_attempts, run_at,
+	locked_at, locked_by, last_error, finished_at, created_at, created_by
+`
+
+func (repository *JobRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+// Empty status or name means any
+func (repository *JobRepository) FetchJobs(offset, limit int, status, name string) ([]entity.Job, error) {
+	jobs := []entity.Job{}
+
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE ($1 = '' OR job_status = $1) AND ($2 = '' OR job_name = $2)
+		ORDER BY created_at DESC, job_id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	if err := repository.db.Select(&jobs, query, status, name, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repository *JobRepository) CountJobs(status, name string) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM jobs WHERE ($1 = '' OR job_status = $1) AND ($2 = '' OR job_name = $2)`
+
+	if err := repository.db.Get(&total, query, status, name); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (repository *JobRepository) FetchSpecJob(jobUUID string) (entity.Job, error) {
+	var job entity.Job
+
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE job_uuid = $1`
+
+	if err := repository.db.Get(&job, query, jobUUID); err != nil {
+		return entity.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Gives a failed job a fresh set of attempts, false means the job was not failed
+func (repository *JobRepository) RequeueJob(jobUUID string) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET job_status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL
+		WHERE job_uuid = $1 AND job_status = 'failed'
+	`
+
+	res, err := repository.db.Exec(query, jobUUID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Without a transaction the job is saved on its own, with one it only exists once the caller commits
+func (repository *JobRepository) SaveJob(tx *sqlx.Tx, job entity.Job) error {
+	var db sqlx.Ext = repository.db
+	if tx != nil {
+		db = tx
+	}
+
+	query := `
+		INSERT INTO jobs (job_id, job_uuid, job_name, job_payload, max_attempts, run_at, created_by)
+		VALUES (:job_id, :job_uuid, :job_name, :job_payload, :max_attempts, :run_at, :created_by)
+	`
+
+	_, err := sqlx.NamedExec(db, query, job)
+	return err
+}
+
+// Locks the oldest job that is due, or whose worker has not reported back within lockTimeout.
+// SKIP LOCKED lets the workers of every replica poll the same table without taking the same job.
+func (repository *JobRepository) ClaimJob(workerID string, lockTimeout time.Duration) (entity.Job, error) {
+	var job entity.Job
+
+	query := `
+		UPDATE jobs
+		SET job_status = 'running', attempts = attempts + 1, locked_at = NOW(), locked_by = $1
+		WHERE job_id = (
+			SELECT job_id FROM jobs
+			WHERE (job_status = 'pending' AND run_at <= NOW())
+				OR (job_status = 'running' AND locked_at < NOW() - $2::FLOAT8 * INTERVAL '1 second' AND attempts < max_attempts)
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	if err := repository.db.Get(&job, query, workerID, lockTimeout.Seconds()); err != nil {
+		return entity.Job{}, err
+	}
+
+	return job, nil
+}
+
+// The result of an attempt is only stored while the worker still holds the job. False means the lock
+// timed out and the job was claimed again, the new attempt owns it now. Attempt tells two claims of
+// the same worker apart.
+func (repository *JobRepository) CompleteJob(jobID int64, workerID string, attempt int) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET job_status = 'succeeded', finished_at = NOW(), locked_at = NULL, locked_by = NULL
+		WHERE job_id = $1 AND locked_by = $2 AND attempts = $3 AND job_status = 'running'
+	`
+
+	return repository.execOwned(query, jobID, workerID, attempt)
+}
+
+func (repository *JobRepository) RetryJobLater(jobID int64, workerID string, attempt int, lastError string, runAt time.Time) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET job_status = 'pending', last_error = $4, run_at = $5, locked_at = NULL, locked_by = NULL
+		WHERE job_id = $1 AND locked_by = $2 AND attempts = $3 AND job_status = 'running'
+	`
+
+	return repository.execOwned(query, jobID, workerID, attempt, lastError, runAt)
+}
+
+func (repository *JobRepository) FailJob(jobID int64, workerID string, attempt int, lastError string) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET job_status = 'failed', last_error = $4, finished_at = NOW(), locked_at = NULL, locked_by = NULL
+		WHERE job_id = $1 AND locked_by = $2 AND attempts = $3 AND job_status = 'running'
+	`
+
+	return repository.execOwned(query, jobID, workerID, attempt, lastError)
+}
+
+func (repository *JobRepository) execOwned(query string, args ...interface{}) (bool, error) {
+	res, err := repository.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Jobs whose worker died during the last attempt are never claimed again, they are marked failed
+func (repository *JobRepository) FailAbandonedJobs(lockTimeout time.Duration) (int64, error) {
+	query := `
+		UPDATE jobs
+		SET job_status = 'failed', finished_at = NOW(), locked_at = NULL,
+			last_error = 'worker ' || COALESCE(locked_by, '') || ' stopped responding during the last attempt'
+		WHERE job_status = 'running' AND locked_at < NOW() - $1::FLOAT8 * INTERVAL '1 second' AND attempts >= max_attempts
+	`
+
+	res, err := repository.db.Exec(query, lockTimeout.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (repository *JobRepository) DeleteFinishedJobs(before time.Time) (int64, error) {
+	query := `DELETE FROM jobs WHERE job_status IN ('succeeded', 'failed') AND finished_at < $1`
+
+	res, err := repository.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (repository *JobRepository) FetchSchedules() ([]entity.JobSchedule, error) {
+	schedules := []entity.JobSchedule{}
+
+	query := `
+		SELECT s.schedule_name, s.schedule_spec, s.next_run_at, s.last_run_at, s.last_job_uuid, j.job_status
+		FROM job_schedules s
+		LEFT JOIN jobs j ON j.job_uuid = s.last_job_uuid
+		ORDER BY s.schedule_name
+	`
+
+	if err := repository.db.Select(&schedules, query); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// A new schedule runs right away, an existing one only moves to nextRunAt when its spec changed
+func (repository *JobRepository) SyncSchedule(name, spec string, nextRunAt time.Time) error {
+	query := `
+		INSERT INTO job_schedules (schedule_name, schedule_spec, next_run_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (schedule_name) DO UPDATE
+		SET schedule_spec = EXCLUDED.schedule_spec, next_run_at = $3
+		WHERE job_schedules.schedule_spec <> EXCLUDED.schedule_spec
+	`
+
+	_, err := repository.db.Exec(query, name, spec, nextRunAt)
+	return err
+}
+
+// Locks the due schedules, a replica that finds them locked leaves them to the one holding the lock
+func (repository *JobRepository) FetchDueSchedules(tx *sqlx.Tx, names []string) ([]entity.JobSchedule, error) {
+	schedules := []entity.JobSchedule{}
+
+	query := `
+		SELECT schedule_name, schedule_spec, next_run_at, last_run_at, last_job_uuid
+		FROM job_schedules
+		WHERE next_run_at <= NOW() AND schedule_name = ANY($1)
+		FOR UPDATE SKIP LOCKED
+	`
+
+	if err := tx.Select(&schedules, query, pq.Array(names)); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (repository *JobRepository) CheckJobActive(tx *sqlx.Tx, name string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM jobs WHERE job_name = $1 AND job_status IN ('pending', 'running'))`
+
+	if err := tx.Get(&exists, query, name); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// An empty jobUUID keeps the previous one, the run was skipped
+func (repository *JobRepository) UpdateScheduleRun(tx *sqlx.Tx, name string, nextRunAt time.Time, jobUUID string) error {
+	query := `
+		UPDATE job_schedules
+		SET next_run_at = $2, last_run_at = NOW(), last_job_uuid = COALESCE(NULLIF($3, '')::UUID, last_job_uuid)
+		WHERE schedule_name = $1
+	`
+
+	_, err := tx.Exec(query, name, nextRunAt, jobUUID)
+	return err
+}
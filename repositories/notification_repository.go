@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type NotificationRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchNotifications(userUUID string, offset, limit int, unreadOnly bool) ([]entity.Notification, error)
+	CountNotifications(userUUID string, unreadOnly bool) (int, error)
+	MarkNotificationRead(userUUID, notificationUUID string) (bool, error)
+	MarkAllNotificationsRead(userUUID string) (int64, error)
+	SaveNotification(notification entity.Notification) error
+
+	FetchRecipients(userUUIDs []string, event string) ([]entity.NotificationRecipient, error)
+	FetchStudentGuardianUUIDs(studentUUID string) ([]string, error)
+
+	FetchNotificationLanguage(userUUID string) (string, error)
+	FetchNotificationPreferences(userUUID string) ([]entity.NotificationPreference, error)
+	SaveNotificationLanguage(tx *sqlx.Tx, userUUID, language string) error
+	SaveNotificationPreference(tx *sqlx.Tx, preference entity.NotificationPreference) error
+
+	FetchPushDevices(userUUID string) ([]entity.PushDevice, error)
+	SavePushDevice(device entity.PushDevice) (uuid.UUID, error)
+	DeletePushDevice(userUUID, deviceUUID string) (bool, error)
+}
+
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepository(db *sqlx.DB) NotificationRepositoryInterface {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (repository *NotificationRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *NotificationRepository) FetchNotifications(userUUID string, offset, limit int, unreadOnly bool) ([]entity.Notification, error) {
+	notifications := []entity.Notification{}
+
+	query := `
+		SELECT notification_id, notification_uuid, user_uuid, notification_event, notification_title,
+			notification_body, notification_data, read_at, created_at
+		FROM notifications
+		WHERE user_uuid = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, notification_id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	if err := repository.db.Select(&notifications, query, userUUID, unreadOnly, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (repository *NotificationRepository) CountNotifications(userUUID string, unreadOnly bool) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM notifications WHERE user_uuid = $1 AND (NOT $2 OR read_at IS NULL)`
+
+	if err := repository.db.Get(&total, query, userUUID, unreadOnly); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// False means the notification does not belong to the user, reading it twice is fine
+func (repository *NotificationRepository) MarkNotificationRead(userUUID, notificationUUID string) (bool, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE user_uuid = $1 AND notification_uuid = $2
+	`
+
+	res, err := repository.db.Exec(query, userUUID, notificationUUID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (repository *NotificationRepository) MarkAllNotificationsRead(userUUID string) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_uuid = $1 AND read_at IS NULL`
+
+	res, err := repository.db.Exec(query, userUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (repository *NotificationRepository) SaveNotification(notification entity.Notification) error {
+	query := `
+		INSERT INTO notifications (notification_id, notification_uuid, user_uuid, notification_event, notification_title, notification_body, notification_data)
+		VALUES (:notification_id, :notification_uuid, :user_uuid, :notification_event, :notification_title, :notification_body, :notification_data)
+	`
+
+	_, err := repository.db.NamedExec(query, notification)
+	return err
+}
+
+// Active users among userUUIDs with their language and the channels they turned off for the event
+func (repository *NotificationRepository) FetchRecipients(userUUIDs []string, event string) ([]entity.NotificationRecipient, error) {
+	var rows []struct {
+		UserUUID uuid.UUID      `db:"user_uuid"`
+		Language string         `db:"notification_language"`
+		Disabled pq.StringArray `db:"disabled_channels"`
+	}
+
+	query := `
+		SELECT u.user_uuid, COALESCE(s.notification_language, 'id') AS notification_language,
+			ARRAY(
+				SELECT p.notification_channel FROM notification_preferences p
+				WHERE p.user_uuid = u.user_uuid AND p.notification_event = $2 AND NOT p.enabled
+			) AS disabled_channels
+		FROM users u
+		LEFT JOIN notification_settings s ON s.user_uuid = u.user_uuid
+		WHERE u.user_uuid = ANY($1::UUID[]) AND u.deleted_at IS NULL
+	`
+
+	if err := repository.db.Select(&rows, query, pq.Array(userUUIDs), event); err != nil {
+		return nil, err
+	}
+
+	recipients := make([]entity.NotificationRecipient, 0, len(rows))
+	for _, row := range rows {
+		recipient := entity.NotificationRecipient{
+			UserUUID:         row.UserUUID,
+			Language:         row.Language,
+			DisabledChannels: make(map[string]bool),
+		}
+		for _, channel := range row.Disabled {
+			recipient.DisabledChannels[channel] = true
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+func (repository *NotificationRepository) FetchStudentGuardianUUIDs(studentUUID string) ([]string, error) {
+	guardians := []string{}
+
+	query := `SELECT guardian_uuid FROM student_guardians WHERE student_uuid = $1`
+
+	if err := repository.db.Select(&guardians, query, studentUUID); err != nil {
+		return nil, err
+	}
+
+	return guardians, nil
+}
+
+func (repository *NotificationRepository) FetchNotificationLanguage(userUUID string) (string, error) {
+	var language string
+
+	query := `
+		SELECT COALESCE((SELECT notification_language FROM notification_settings WHERE user_uuid = $1), 'id')
+	`
+
+	if err := repository.db.Get(&language, query, userUUID); err != nil {
+		return "", err
+	}
+
+	return language, nil
+}
+
+func (repository *NotificationRepository) FetchNotificationPreferences(userUUID string) ([]entity.NotificationPreference, error) {
+	preferences := []entity.NotificationPreference{}
+
+	query := `
+		SELECT user_uuid, notification_event, notification_channel, enabled
+		FROM notification_preferences
+		WHERE user_uuid = $1
+	`
+
+	if err := repository.db.Select(&preferences, query, userUUID); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (repository *NotificationRepository) SaveNotificationLanguage(tx *sqlx.Tx, userUUID, language string) error {
+	query := `
+		INSERT INTO notification_settings (user_uuid, notification_language, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_uuid) DO UPDATE SET notification_language = EXCLUDED.notification_language, updated_at = NOW()
+	`
+
+	_, err := tx.Exec(query, userUUID, language)
+	return err
+}
+
+func (repository *NotificationRepository) SaveNotificationPreference(tx *sqlx.Tx, preference entity.NotificationPreference) error {
+	query := `
+		INSERT INTO notification_preferences (user_uuid, notification_event, notification_channel, enabled)
+		VALUES (:user_uuid, :notification_event, :notification_channel, :enabled)
+		ON CONFLICT (user_uuid, notification_event, notification_channel)
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+	`
+
+	_, err := tx.NamedExec(query, preference)
+	return err
+}
+
+func (repository *NotificationRepository) FetchPushDevices(userUUID string) ([]entity.PushDevice, error) {
+	devices := []entity.PushDevice{}
+
+	query := `
+		SELECT device_id, device_uuid, user_uuid, device_token, device_platform, created_at, last_seen_at
+		FROM push_devices
+		WHERE user_uuid = $1
+	`
+
+	if err := repository.db.Select(&devices, query, userUUID); err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// A token registered before, by this or another account, moves to the user and keeps its uuid
+func (repository *NotificationRepository) SavePushDevice(device entity.PushDevice) (uuid.UUID, error) {
+	var deviceUUID uuid.UUID
+
+	query := `
+		INSERT INTO push_devices (device_id, device_uuid, user_uuid, device_token, device_platform)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (device_token) DO UPDATE
+		SET user_uuid = EXCLUDED.user_uuid, device_platform = EXCLUDED.device_platform, last_seen_at = NOW()
+		RETURNING device_uuid
+	`
+
+	if err := repository.db.Get(&deviceUUID, query, device.ID, device.UUID, device.UserUUID, device.Token, device.Platform); err != nil {
+		return uuid.Nil, err
+	}
+
+	return deviceUUID, nil
+}
+
+func (repository *NotificationRepository) DeletePushDevice(userUUID, deviceUUID string) (bool, error) {
+	query := `DELETE FROM push_devices WHERE user_uuid = $1 AND device_uuid = $2`
+
+	res, err := repository.db.Exec(query, userUUID, deviceUUID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
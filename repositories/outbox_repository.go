@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"encoding/json"
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type OutboxRepositoryInterface interface {
+	ClaimEvents(workerID string, limit int, lockTimeout time.Duration) ([]entity.DomainEvent, error)
+	FetchDeliveredSubscribers(eventID int64) ([]string, error)
+	SaveDelivery(eventID int64, subscriber string) error
+	PublishEvent(eventID int64) error
+	RetryEventLater(eventID int64, lastError string, nextAttemptAt time.Time) error
+	FailEvent(eventID int64, lastError string) error
+	DeletePublishedEvents(before time.Time) (int64, error)
+}
+
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) OutboxRepositoryInterface {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+const domainEventColumns = `
+	event_id, event_uuid, event_type, aggregate_type, aggregate_uuid, school_uuid, event_payload, dedup_key,
+	event_status, attempts, next_attempt_at, locked_at, locked_by, last_error, occurred_at, published_at
+`
+
+// Writes the event in the transaction of the change it describes, so it only exists once that change
+// is committed. An event whose dedup key was already written is dropped.
+func saveDomainEvent(tx *sqlx.Tx, event entity.DomainEvent, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	eventUUID := uuid.New()
+	query := `
+		INSERT INTO domain_events (event_id, event_uuid, event_type, aggregate_type, aggregate_uuid, school_uuid, event_payload, dedup_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (dedup_key) DO NOTHING
+	`
+
+	_, err = tx.Exec(query, time.Now().UnixMilli()*1e6+int64(eventUUID.ID()%1e6), eventUUID, event.Type, event.AggregateType,
+		event.AggregateUUID, event.SchoolUUID, data, event.DedupKey)
+	return err
+}
+
+// Locks a batch of pending events in the order they were written. Events claimed by a relay that has
+// not reported back within lockTimeout are claimed again, SKIP LOCKED keeps replicas apart.
+func (repository *OutboxRepository) ClaimEvents(workerID string, limit int, lockTimeout time.Duration) ([]entity.DomainEvent, error) {
+	events := []entity.DomainEvent{}
+
+	query := `
+		UPDATE domain_events
+		SET attempts = attempts + 1, locked_at = NOW(), locked_by = $1
+		WHERE event_id IN (
+			SELECT event_id FROM domain_events
+			WHERE event_status = 'pending' AND next_attempt_at <= NOW()
+				AND (locked_at IS NULL OR locked_at < NOW() - $3::FLOAT8 * INTERVAL '1 second')
+			ORDER BY event_id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + domainEventColumns
+
+	if err := repository.db.Select(&events, query, workerID, limit, lockTimeout.Seconds()); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (repository *OutboxRepository) FetchDeliveredSubscribers(eventID int64) ([]string, error) {
+	subscribers := []string{}
+
+	query := `SELECT subscriber_name FROM domain_event_deliveries WHERE event_id = $1`
+
+	if err := repository.db.Select(&subscribers, query, eventID); err != nil {
+		return nil, err
+	}
+
+	return subscribers, nil
+}
+
+func (repository *OutboxRepository) SaveDelivery(eventID int64, subscriber string) error {
+	query := `
+		INSERT INTO domain_event_deliveries (event_id, subscriber_name)
+		VALUES ($1, $2)
+		ON CONFLICT (event_id, subscriber_name) DO NOTHING
+	`
+
+	_, err := repository.db.Exec(query, eventID, subscriber)
+	return err
+}
+
+func (repository *OutboxRepository) PublishEvent(eventID int64) error {
+	query := `
+		UPDATE domain_events
+		SET event_status = 'published', published_at = NOW(), last_error = NULL, locked_at = NULL, locked_by = NULL
+		WHERE event_id = $1
+	`
+
+	_, err := repository.db.Exec(query, eventID)
+	return err
+}
+
+func (repository *OutboxRepository) RetryEventLater(eventID int64, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE domain_events
+		SET last_error = $2, next_attempt_at = $3, locked_at = NULL, locked_by = NULL
+		WHERE event_id = $1
+	`
+
+	_, err := repository.db.Exec(query, eventID, lastError, nextAttemptAt)
+	return err
+}
+
+func (repository *OutboxRepository) FailEvent(eventID int64, lastError string) error {
+	query := `
+		UPDATE domain_events
+		SET event_status = 'failed', last_error = $2, locked_at = NULL, locked_by = NULL
+		WHERE event_id = $1
+	`
+
+	_, err := repository.db.Exec(query, eventID, lastError)
+	return err
+}
+
+func (repository *OutboxRepository) DeletePublishedEvents(before time.Time) (int64, error) {
+	query := `DELETE FROM domain_events WHERE event_status = 'published' AND published_at < $1`
+
+	res, err := repository.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
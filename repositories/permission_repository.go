@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type PermissionRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchAllRoles() ([]entity.AccessRole, error)
+	FetchSpecRole(code string) (entity.AccessRole, error)
+	FetchAllPermissions() ([]entity.Permission, error)
+	FetchRolePermissionsMap() (map[string][]string, error)
+	CountUsersWithRole(code string) (int, error)
+	CountKnownPermissions(codes []string) (int, error)
+
+	SaveRole(tx *sqlx.Tx, role entity.AccessRole) error
+	UpdateRole(tx *sqlx.Tx, role entity.AccessRole) error
+	ReplaceRolePermissions(tx *sqlx.Tx, code string, permissions []string) error
+	DeleteRole(code string) error
+}
+
+type PermissionRepository struct {
+	db *sqlx.DB
+}
+
+func NewPermissionRepository(db *sqlx.DB) PermissionRepositoryInterface {
+	return &PermissionRepository{
+		db: db,
+	}
+}
+
+func (repository *PermissionRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *PermissionRepository) FetchAllRoles() ([]entity.AccessRole, error) {
+	var roles []entity.AccessRole
+
+	query := `
+		SELECT role_code, role_name, role_base, role_description, is_system,
+			created_at, created_by, updated_at, updated_by
+		FROM roles
+		ORDER BY role_code ASC
+	`
+
+	if err := repository.db.Select(&roles, query); err != nil {
+		return nil, err
+	}
+
+	permissions, err := repository.FetchRolePermissionsMap()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		roles[i].Permissions = permissions[roles[i].Code]
+	}
+
+	return roles, nil
+}
+
+func (repository *PermissionRepository) FetchSpecRole(code string) (entity.AccessRole, error) {
+	var role entity.AccessRole
+
+	query := `
+		SELECT role_code, role_name, role_base, role_description, is_system,
+			created_at, created_by, updated_at, updated_by
+		FROM roles
+		WHERE role_code = $1
+	`
+
+	if err := repository.db.Get(&role, query, code); err != nil {
+		return entity.AccessRole{}, err
+	}
+
+	query = `
+		SELECT permission_code
+		FROM role_permissions
+		WHERE role_code = $1
+		ORDER BY permission_code ASC
+	`
+
+	if err := repository.db.Select(&role.Permissions, query, code); err != nil {
+		return entity.AccessRole{}, err
+	}
+
+	return role, nil
+}
+
+func (repository *PermissionRepository) FetchAllPermissions() ([]entity.Permission, error) {
+	var permissions []entity.Permission
+
+	query := `
+		SELECT permission_code, permission_description
+		FROM permissions
+		ORDER BY permission_code ASC
+	`
+
+	if err := repository.db.Select(&permissions, query); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (repository *PermissionRepository) FetchRolePermissionsMap() (map[string][]string, error) {
+	var rows []struct {
+		RoleCode       string `db:"role_code"`
+		PermissionCode string `db:"permission_code"`
+	}
+
+	query := `
+		SELECT role_code, permission_code
+		FROM role_permissions
+		ORDER BY role_code ASC, permission_code ASC
+	`
+
+	if err := repository.db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string][]string)
+	for _, row := range rows {
+		permissions[row.RoleCode] = append(permissions[row.RoleCode], row.PermissionCode)
+	}
+
+	return permissions, nil
+}
+
+func (repository *PermissionRepository) CountUsersWithRole(code string) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(user_id)
+		FROM users
+		WHERE user_role_code = $1 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&count, query, code); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (repository *PermissionRepository) CountKnownPermissions(codes []string) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(permission_code)
+		FROM permissions
+		WHERE permission_code = ANY($1)
+	`
+
+	if err := repository.db.Get(&count, query, pq.Array(codes)); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (repository *PermissionRepository) SaveRole(tx *sqlx.Tx, role entity.AccessRole) error {
+	query := `
+		INSERT INTO roles (role_code, role_name, role_base, role_description, is_system, created_by)
+		VALUES (:role_code, :role_name, :role_base, :role_description, :is_system, :created_by)
+	`
+
+	if _, err := tx.NamedExec(query, role); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *PermissionRepository) UpdateRole(tx *sqlx.Tx, role entity.AccessRole) error {
+	query := `
+		UPDATE roles
+		SET role_name = :role_name, role_base = :role_base, role_description = :role_description,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE role_code = :role_code
+	`
+
+	if _, err := tx.NamedExec(query, role); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *PermissionRepository) ReplaceRolePermissions(tx *sqlx.Tx, code string, permissions []string) error {
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_code = $1`, code); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO role_permissions (role_code, permission_code)
+		SELECT $1, UNNEST($2::VARCHAR[])
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := tx.Exec(query, code, pq.Array(permissions)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *PermissionRepository) DeleteRole(code string) error {
+	query := `
+		DELETE FROM roles
+		WHERE role_code = $1 AND is_system = FALSE
+	`
+
+	if _, err := repository.db.Exec(query, code); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RouteRepositoryInterface interface {
+	FetchRoutes(schoolUUID string) ([]entity.Route, error)
+	FetchSpecRoute(schoolUUID, routeUUID string) (entity.Route, error)
+	CheckRouteNameExists(schoolUUID, routeUUID, name string) (bool, error)
+
+	SaveRoute(route entity.Route) error
+	UpdateRoute(route entity.Route) error
+	DeleteRoute(schoolUUID, routeUUID, username string) error
+}
+
+type RouteRepository struct {
+	db *sqlx.DB
+}
+
+func NewRouteRepository(db *sqlx.DB) RouteRepositoryInterface {
+	return &RouteRepository{
+		db: db,
+	}
+}
+
+func (repository *RouteRepository) FetchRoutes(schoolUUID string) ([]entity.Route, error) {
+	routes := []entity.Route{}
+
+	query := `
+		SELECT r.route_id, r.route_uuid, r.school_uuid, r.route_name, r.route_description, r.route_points, r.route_status,
+			(SELECT COUNT(*) FROM vehicles v WHERE v.route_uuid = r.route_uuid AND v.deleted_at IS NULL) AS vehicle_count,
+			r.created_at, r.created_by, r.updated_at, r.updated_by
+		FROM routes r
+		WHERE r.school_uuid = $1 AND r.deleted_at IS NULL
+		ORDER BY r.route_name
+	`
+
+	if err := repository.db.Select(&routes, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+func (repository *RouteRepository) FetchSpecRoute(schoolUUID, routeUUID string) (entity.Route, error) {
+	var route entity.Route
+
+	query := `
+		SELECT r.route_id, r.route_uuid, r.school_uuid, r.route_name, r.route_description, r.route_points, r.route_status,
+			(SELECT COUNT(*) FROM vehicles v WHERE v.route_uuid = r.route_uuid AND v.deleted_at IS NULL) AS vehicle_count,
+			r.created_at, r.created_by, r.updated_at, r.updated_by
+		FROM routes r
+		WHERE r.school_uuid = $1 AND r.route_uuid = $2 AND r.deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&route, query, schoolUUID, routeUUID); err != nil {
+		return entity.Route{}, err
+	}
+
+	return route, nil
+}
+
+// routeUUID is empty when adding a route
+func (repository *RouteRepository) CheckRouteNameExists(schoolUUID, routeUUID, name string) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM routes
+			WHERE school_uuid = $1 AND route_uuid::text <> $2 AND LOWER(route_name) = LOWER($3) AND deleted_at IS NULL
+		)
+	`
+
+	if err := repository.db.Get(&exists, query, schoolUUID, routeUUID, name); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *RouteRepository) SaveRoute(route entity.Route) error {
+	query := `
+		INSERT INTO routes (route_id, route_uuid, school_uuid, route_name, route_description, route_points, route_status, created_by)
+		VALUES (:route_id, :route_uuid, :school_uuid, :route_name, :route_description, :route_points, :route_status, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, route)
+	return err
+}
+
+func (repository *RouteRepository) UpdateRoute(route entity.Route) error {
+	query := `
+		UPDATE routes
+		SET route_name = :route_name, route_description = :route_description, route_points = :route_points,
+			route_status = :route_status, updated_at = NOW(), updated_by = :updated_by
+		WHERE route_uuid = :route_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, route)
+	return err
+}
+
+// Vehicles on the route are taken off it
+func (repository *RouteRepository) DeleteRoute(schoolUUID, routeUUID, username string) error {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE routes
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE school_uuid = $1 AND route_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if _, err := tx.Exec(query, schoolUUID, routeUUID, username); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE vehicles SET route_uuid = NULL WHERE route_uuid = $1`, routeUUID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,238 @@
+package repositories
+
+import (
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// A school is closed on the local date of $2 when a closure covers it, or when it has operating
+// hours but none for that weekday. Expects the school as s.
+const schoolClosedCondition = `(
+	EXISTS (
+		SELECT 1 FROM school_closures c
+		WHERE c.school_uuid = s.school_uuid AND c.deleted_at IS NULL
+			AND ($2::timestamptz AT TIME ZONE s.school_timezone)::date BETWEEN c.starts_on AND c.ends_on
+	)
+	OR (
+		EXISTS (SELECT 1 FROM school_operating_hours h WHERE h.school_uuid = s.school_uuid)
+		AND NOT EXISTS (
+			SELECT 1 FROM school_operating_hours h
+			WHERE h.school_uuid = s.school_uuid
+				AND h.weekday = EXTRACT(ISODOW FROM $2::timestamptz AT TIME ZONE s.school_timezone)
+		)
+	)
+)`
+
+type SchoolProfileRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchSchoolProfile(schoolUUID string) (entity.School, error)
+	FetchOperatingHours(schoolUUID string) ([]entity.SchoolOperatingHour, error)
+	UpdateSchoolProfile(tx *sqlx.Tx, school entity.School) error
+	ReplaceOperatingHours(tx *sqlx.Tx, schoolUUID string, hours []entity.SchoolOperatingHour) error
+
+	FetchClosures(schoolUUID string, from time.Time) ([]entity.SchoolClosure, error)
+	FetchSpecClosure(schoolUUID, closureUUID string) (entity.SchoolClosure, error)
+	CheckClosureOverlap(schoolUUID, closureUUID string, startsOn, endsOn time.Time) (bool, error)
+	SaveClosure(closure entity.SchoolClosure) error
+	UpdateClosure(closure entity.SchoolClosure) error
+	DeleteClosure(schoolUUID, closureUUID, username string) error
+
+	IsSchoolClosed(schoolUUID string, at time.Time) (bool, error)
+	IsStudentSchoolClosed(studentUUID string, at time.Time) (bool, error)
+}
+
+type SchoolProfileRepository struct {
+	db *sqlx.DB
+}
+
+func NewSchoolProfileRepository(db *sqlx.DB) SchoolProfileRepositoryInterface {
+	return &SchoolProfileRepository{
+		db: db,
+	}
+}
+
+func (repository *SchoolProfileRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *SchoolProfileRepository) FetchSchoolProfile(schoolUUID string) (entity.School, error) {
+	var school entity.School
+
+	query := `
+		SELECT school_id, school_uuid, school_name, school_latitude, school_longitude, school_geofence_radius, school_timezone
+		FROM schools
+		WHERE school_uuid = $1 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&school, query, schoolUUID); err != nil {
+		return entity.School{}, err
+	}
+
+	return school, nil
+}
+
+func (repository *SchoolProfileRepository) FetchOperatingHours(schoolUUID string) ([]entity.SchoolOperatingHour, error) {
+	hours := []entity.SchoolOperatingHour{}
+
+	query := `
+		SELECT school_uuid, weekday, TO_CHAR(starts_at, 'HH24:MI') AS starts_at, TO_CHAR(ends_at, 'HH24:MI') AS ends_at
+		FROM school_operating_hours
+		WHERE school_uuid = $1
+		ORDER BY weekday
+	`
+
+	if err := repository.db.Select(&hours, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+func (repository *SchoolProfileRepository) UpdateSchoolProfile(tx *sqlx.Tx, school entity.School) error {
+	query := `
+		UPDATE schools
+		SET school_latitude = :school_latitude, school_longitude = :school_longitude, school_geofence_radius = :school_geofence_radius,
+			school_timezone = :school_timezone, updated_at = NOW(), updated_by = :updated_by
+		WHERE school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := tx.NamedExec(query, school)
+	return err
+}
+
+func (repository *SchoolProfileRepository) ReplaceOperatingHours(tx *sqlx.Tx, schoolUUID string, hours []entity.SchoolOperatingHour) error {
+	if _, err := tx.Exec(`DELETE FROM school_operating_hours WHERE school_uuid = $1`, schoolUUID); err != nil {
+		return err
+	}
+
+	if len(hours) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO school_operating_hours (school_uuid, weekday, starts_at, ends_at)
+		VALUES (:school_uuid, :weekday, :starts_at, :ends_at)
+	`
+
+	_, err := tx.NamedExec(query, hours)
+	return err
+}
+
+// Closures that have not ended before from
+func (repository *SchoolProfileRepository) FetchClosures(schoolUUID string, from time.Time) ([]entity.SchoolClosure, error) {
+	closures := []entity.SchoolClosure{}
+
+	query := `
+		SELECT closure_id, closure_uuid, school_uuid, closure_name, starts_on, ends_on, created_at, created_by, updated_at, updated_by
+		FROM school_closures
+		WHERE school_uuid = $1 AND ends_on >= $2 AND deleted_at IS NULL
+		ORDER BY starts_on
+	`
+
+	if err := repository.db.Select(&closures, query, schoolUUID, from); err != nil {
+		return nil, err
+	}
+
+	return closures, nil
+}
+
+func (repository *SchoolProfileRepository) FetchSpecClosure(schoolUUID, closureUUID string) (entity.SchoolClosure, error) {
+	var closure entity.SchoolClosure
+
+	query := `
+		SELECT closure_id, closure_uuid, school_uuid, closure_name, starts_on, ends_on, created_at, created_by, updated_at, updated_by
+		FROM school_closures
+		WHERE school_uuid = $1 AND closure_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&closure, query, schoolUUID, closureUUID); err != nil {
+		return entity.SchoolClosure{}, err
+	}
+
+	return closure, nil
+}
+
+// An empty closureUUID checks every closure, otherwise that closure is left out
+func (repository *SchoolProfileRepository) CheckClosureOverlap(schoolUUID, closureUUID string, startsOn, endsOn time.Time) (bool, error) {
+	var overlaps bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM school_closures
+			WHERE school_uuid = $1 AND closure_uuid::text <> $2 AND deleted_at IS NULL
+				AND starts_on <= $4 AND ends_on >= $3
+		)
+	`
+
+	if err := repository.db.Get(&overlaps, query, schoolUUID, closureUUID, startsOn, endsOn); err != nil {
+		return false, err
+	}
+
+	return overlaps, nil
+}
+
+func (repository *SchoolProfileRepository) SaveClosure(closure entity.SchoolClosure) error {
+	query := `
+		INSERT INTO school_closures (closure_id, closure_uuid, school_uuid, closure_name, starts_on, ends_on, created_by)
+		VALUES (:closure_id, :closure_uuid, :school_uuid, :closure_name, :starts_on, :ends_on, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, closure)
+	return err
+}
+
+func (repository *SchoolProfileRepository) UpdateClosure(closure entity.SchoolClosure) error {
+	query := `
+		UPDATE school_closures
+		SET closure_name = :closure_name, starts_on = :starts_on, ends_on = :ends_on, updated_at = NOW(), updated_by = :updated_by
+		WHERE closure_uuid = :closure_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, closure)
+	return err
+}
+
+func (repository *SchoolProfileRepository) DeleteClosure(schoolUUID, closureUUID, username string) error {
+	query := `
+		UPDATE school_closures
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE school_uuid = $1 AND closure_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, schoolUUID, closureUUID, username)
+	return err
+}
+
+func (repository *SchoolProfileRepository) IsSchoolClosed(schoolUUID string, at time.Time) (bool, error) {
+	var closed bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM schools s WHERE s.school_uuid = $1 AND ` + schoolClosedCondition + `)`
+
+	if err := repository.db.Get(&closed, query, schoolUUID, at); err != nil {
+		return false, err
+	}
+
+	return closed, nil
+}
+
+func (repository *SchoolProfileRepository) IsStudentSchoolClosed(studentUUID string, at time.Time) (bool, error) {
+	var closed bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM students st
+			JOIN schools s ON s.school_uuid = st.school_uuid
+			WHERE st.student_uuid = $1 AND ` + schoolClosedCondition + `
+		)
+	`
+
+	if err := repository.db.Get(&closed, query, studentUUID, at); err != nil {
+		return false, err
+	}
+
+	return closed, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"shuttle/models/entity"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -15,8 +16,13 @@ type SchoolRepositoryInterface interface {
 	FetchSpecSchool(uuid string) (entity.School, []entity.SchoolAdminDetails, error)
 	SaveSchool(entity.School) error
 	UpdateSchool(entity.School) error
-	DeleteSchool(entity.School) error
 	CountSchools() (int, error)
+
+	BeginTransaction() (*sqlx.Tx, error)
+	FetchDeletedSchool(schoolUUID string) (entity.School, error)
+	FetchDeletionPlan(schoolUUID string, deletedAt sql.NullTime) (entity.SchoolDeletionPlan, error)
+	DeleteSchool(tx *sqlx.Tx, schoolUUID string, username string) error
+	RestoreSchool(tx *sqlx.Tx, schoolUUID string, deletedAt time.Time, username string) error
 }
 
 type schoolRepository struct {
@@ -248,16 +254,6 @@ func (r *schoolRepository) UpdateSchool(school entity.School) error {
 	return nil
 }
 
-func (r *schoolRepository) DeleteSchool(school entity.School) error {
-	query := `UPDATE schools SET deleted_at = :deleted_at, deleted_by = :deleted_by WHERE school_uuid = :school_uuid`
-	_, err := r.DB.NamedExec(query, school)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (repositories *schoolRepository) CountSchools() (int, error) {
 	var total int
 
@@ -273,3 +269,220 @@ func (repositories *schoolRepository) CountSchools() (int, error) {
 
 	return total, nil
 }
+
+func (r *schoolRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.DB.Beginx()
+}
+
+func (r *schoolRepository) FetchDeletedSchool(schoolUUID string) (entity.School, error) {
+	var school entity.School
+
+	query := `
+		SELECT school_id, school_uuid, school_name, school_address, school_contact, school_email, school_description,
+			created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+		FROM schools
+		WHERE school_uuid = $1 AND deleted_at IS NOT NULL
+	`
+
+	if err := r.DB.Get(&school, query, schoolUUID); err != nil {
+		return entity.School{}, err
+	}
+
+	return school, nil
+}
+
+// A null deletedAt counts what deleting the school would take with it, a deletion time counts what
+// was deleted together with the school at that time and comes back when it is restored
+func (r *schoolRepository) FetchDeletionPlan(schoolUUID string, deletedAt sql.NullTime) (entity.SchoolDeletionPlan, error) {
+	var plan entity.SchoolDeletionPlan
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM school_admin_details a JOIN users u ON u.user_uuid = a.user_uuid
+				WHERE a.school_uuid = $1 AND u.deleted_at IS NOT DISTINCT FROM $2) AS school_admins,
+			(SELECT COUNT(*) FROM driver_details d JOIN users u ON u.user_uuid = d.user_uuid
+				WHERE d.school_uuid = $1 AND u.deleted_at IS NOT DISTINCT FROM $2) AS drivers,
+			(SELECT COUNT(*) FROM students WHERE school_uuid = $1 AND deleted_at IS NOT DISTINCT FROM $2) AS students,
+			(SELECT COUNT(DISTINCT u.user_uuid) FROM users u
+				JOIN student_guardians g ON g.guardian_uuid = u.user_uuid
+				JOIN students s ON s.student_uuid = g.student_uuid
+				WHERE s.school_uuid = $1 AND s.deleted_at IS NOT DISTINCT FROM $2
+					AND u.user_role = 'parent' AND u.deleted_at IS NOT DISTINCT FROM $2
+					AND NOT EXISTS (` + otherSchoolStudentsQuery + `)) AS parents,
+			(SELECT COUNT(*) FROM vehicles WHERE school_uuid = $1 AND deleted_at IS NOT DISTINCT FROM $2) AS vehicles,
+			(SELECT COUNT(*) FROM vehicle_assignments a
+				WHERE a.assigned_to IS NOT DISTINCT FROM $2 AND (` + schoolAssignmentCondition + `)) AS vehicle_assignments,
+			(SELECT COUNT(*) FROM routes WHERE school_uuid = $1 AND deleted_at IS NOT DISTINCT FROM $2) AS routes,
+			(SELECT COUNT(*) FROM academic_years WHERE school_uuid = $1 AND deleted_at IS NOT DISTINCT FROM $2) AS academic_years,
+			(SELECT COUNT(*) FROM grade_levels WHERE school_uuid = $1 AND deleted_at IS NOT DISTINCT FROM $2) AS grade_levels
+	`
+
+	if err := r.DB.Get(&plan, query, schoolUUID, deletedAt); err != nil {
+		return entity.SchoolDeletionPlan{}, err
+	}
+
+	return plan, nil
+}
+
+// Tables whose rows carry the school they belong to and are soft deleted together with it
+var schoolOwnedTables = []string{"students", "vehicles", "routes", "academic_years", "grade_levels"}
+
+// Guardians that still have an active student at another school, they outlive the school
+const otherSchoolStudentsQuery = `
+	SELECT 1 FROM student_guardians og
+	JOIN students os ON os.student_uuid = og.student_uuid
+	WHERE og.guardian_uuid = u.user_uuid AND os.school_uuid <> $1 AND os.deleted_at IS NULL
+`
+
+const schoolAssignmentCondition = `
+	a.vehicle_uuid IN (SELECT vehicle_uuid FROM vehicles WHERE school_uuid = $1)
+	OR a.driver_uuid IN (SELECT user_uuid FROM driver_details WHERE school_uuid = $1)
+`
+
+// Soft deletes the school and everything that belongs to it. Every row is stamped with the
+// transaction time, which is also the deletion time of the school, so a restore can tell them apart
+// from rows that were deleted on their own before.
+func (r *schoolRepository) DeleteSchool(tx *sqlx.Tx, schoolUUID string, username string) error {
+	res, err := tx.Exec(`UPDATE schools SET deleted_at = NOW(), deleted_by = $2 WHERE school_uuid = $1 AND deleted_at IS NULL`, schoolUUID, username)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	var vehicleUUIDs, driverUUIDs []uuid.UUID
+	query := `SELECT vehicle_uuid FROM vehicles WHERE school_uuid = $1 AND deleted_at IS NULL`
+	if err := tx.Select(&vehicleUUIDs, query, schoolUUID); err != nil {
+		return err
+	}
+
+	query = `
+		SELECT d.user_uuid FROM driver_details d JOIN users u ON u.user_uuid = d.user_uuid
+		WHERE d.school_uuid = $1 AND u.deleted_at IS NULL
+	`
+	if err := tx.Select(&driverUUIDs, query, schoolUUID); err != nil {
+		return err
+	}
+
+	for _, vehicleUUID := range vehicleUUIDs {
+		if err := releaseVehicle(tx, vehicleUUID, username); err != nil {
+			return err
+		}
+	}
+
+	for _, driverUUID := range driverUUIDs {
+		if err := releaseDriver(tx, driverUUID, username); err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE users u SET deleted_at = NOW(), deleted_by = $2
+		WHERE u.deleted_at IS NULL AND (
+			u.user_uuid IN (SELECT user_uuid FROM school_admin_details WHERE school_uuid = $1)
+			OR u.user_uuid IN (SELECT user_uuid FROM driver_details WHERE school_uuid = $1)
+		)
+	`
+	if _, err := tx.Exec(query, schoolUUID, username); err != nil {
+		return err
+	}
+
+	for _, table := range schoolOwnedTables {
+		query = fmt.Sprintf(`UPDATE %s SET deleted_at = NOW(), deleted_by = $2 WHERE school_uuid = $1 AND deleted_at IS NULL`, table)
+		if _, err := tx.Exec(query, schoolUUID, username); err != nil {
+			return err
+		}
+	}
+
+	// Same as deleting the last student of a guardian one by one
+	query = `
+		UPDATE users u SET deleted_at = NOW(), deleted_by = $2
+		WHERE u.user_role = 'parent' AND u.deleted_at IS NULL
+			AND EXISTS (
+				SELECT 1 FROM student_guardians g JOIN students s ON s.student_uuid = g.student_uuid
+				WHERE g.guardian_uuid = u.user_uuid AND s.school_uuid = $1 AND s.deleted_at = NOW()
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM student_guardians g JOIN students s ON s.student_uuid = g.student_uuid
+				WHERE g.guardian_uuid = u.user_uuid AND s.deleted_at IS NULL
+			)
+	`
+	_, err = tx.Exec(query, schoolUUID, username)
+	return err
+}
+
+// Undoes DeleteSchool, only rows deleted together with the school come back. Drivers get their
+// vehicles back as long as neither of them was given to someone else in the meantime.
+func (r *schoolRepository) RestoreSchool(tx *sqlx.Tx, schoolUUID string, deletedAt time.Time, username string) error {
+	query := `
+		UPDATE schools SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW(), updated_by = $3
+		WHERE school_uuid = $1 AND deleted_at = $2
+	`
+	res, err := tx.Exec(query, schoolUUID, deletedAt, username)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	// Guardians first, they are found through students that are still deleted
+	query = `
+		UPDATE users u SET deleted_at = NULL, deleted_by = NULL
+		WHERE u.deleted_at = $2 AND (
+			u.user_uuid IN (SELECT user_uuid FROM school_admin_details WHERE school_uuid = $1)
+			OR u.user_uuid IN (SELECT user_uuid FROM driver_details WHERE school_uuid = $1)
+			OR (u.user_role = 'parent' AND u.user_uuid IN (
+				SELECT g.guardian_uuid FROM student_guardians g JOIN students s ON s.student_uuid = g.student_uuid
+				WHERE s.school_uuid = $1 AND s.deleted_at = $2
+			))
+		)
+	`
+	if _, err := tx.Exec(query, schoolUUID, deletedAt); err != nil {
+		return err
+	}
+
+	for _, table := range schoolOwnedTables {
+		query = fmt.Sprintf(`UPDATE %s SET deleted_at = NULL, deleted_by = NULL WHERE school_uuid = $1 AND deleted_at = $2`, table)
+		if _, err := tx.Exec(query, schoolUUID, deletedAt); err != nil {
+			return err
+		}
+	}
+
+	var assignments []entity.VehicleAssignment
+	query = `
+		SELECT a.driver_uuid, a.vehicle_uuid
+		FROM vehicle_assignments a
+		JOIN users u ON u.user_uuid = a.driver_uuid AND u.deleted_at IS NULL
+		JOIN vehicles v ON v.vehicle_uuid = a.vehicle_uuid AND v.deleted_at IS NULL
+		WHERE a.assigned_to = $2 AND (` + schoolAssignmentCondition + `)
+			AND NOT EXISTS (
+				SELECT 1 FROM vehicle_assignments oa
+				WHERE oa.assigned_to IS NULL AND (oa.driver_uuid = a.driver_uuid OR oa.vehicle_uuid = a.vehicle_uuid)
+			)
+	`
+	if err := tx.Select(&assignments, query, schoolUUID, deletedAt); err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		if err := assignVehicle(tx, assignment.DriverUUID, assignment.VehicleUUID, username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,8 +3,8 @@ package repositories
 import (
 	"shuttle/models/dto"
 	"shuttle/models/entity"
-	"database/sql"
-	// "time"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -13,7 +13,11 @@ import (
 type ShuttleRepositoryInterface interface {
     GetShuttleStatusByParent(parentUUID uuid.UUID) ([]dto.ShuttleResponse, error)
     SaveShuttle(shuttle entity.Shuttle) error
-	UpdateShuttleStatus(shuttleUUID uuid.UUID, status string) error
+	BeginTransaction() (*sqlx.Tx, error)
+	UpdateShuttleStatus(tx *sqlx.Tx, shuttleUUID uuid.UUID, status string) (entity.Shuttle, entity.Shuttle, error)
+	FetchShuttle(shuttleUUID uuid.UUID) (entity.Shuttle, error)
+	FetchShuttleStudent(shuttleUUID uuid.UUID) (entity.ShuttleStudent, error)
+	FetchStudentName(studentUUID uuid.UUID) (string, error)
 }
 
 type ShuttleRepository struct {
@@ -33,14 +37,16 @@ func (r *ShuttleRepository) GetShuttleStatusByParent(parentUUID uuid.UUID) ([]dt
 		s.student_last_name,
 		s.student_gender,
 		s.student_grade,
-		s.parent_uuid,
+		sg.guardian_uuid AS parent_uuid,
 		s.school_uuid,
 		sc.school_name,
 		st.status AS shuttle_status
 	FROM students s
+	JOIN student_guardians sg ON s.student_uuid = sg.student_uuid
 	JOIN schools sc ON s.school_uuid = sc.school_uuid
-	LEFT JOIN shuttle st ON s.student_uuid = st.student_uuid AND DATE(st.created_at) = CURRENT_DATE
-	WHERE s.parent_uuid = $1;
+	LEFT JOIN shuttle st ON s.student_uuid = st.student_uuid AND st.deleted_at IS NULL
+		AND COALESCE((SELECT t.trip_date FROM trips t WHERE t.trip_uuid = st.trip_uuid), DATE(st.created_at)) = CURRENT_DATE
+	WHERE sg.guardian_uuid = $1 AND s.deleted_at IS NULL;
 
 	`
 
@@ -61,29 +67,176 @@ func (r *ShuttleRepository) SaveShuttle(shuttle entity.Shuttle) error {
 	return err
 }
 
-func (r *ShuttleRepository) UpdateShuttleStatus(shuttleUUID uuid.UUID, status string) error {
+func (r *ShuttleRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.DB.Beginx()
+}
+
+// Status dan event shuttle.status_changed ditulis dalam transaksi pemanggil, event hanya ada jika
+// perubahan status berhasil di-commit. Baris shuttle sebelum dan sesudah perubahan dikembalikan
+// untuk audit log, keduanya dibaca selama baris terkunci.
+func (r *ShuttleRepository) UpdateShuttleStatus(tx *sqlx.Tx, shuttleUUID uuid.UUID, status string) (before entity.Shuttle, after entity.Shuttle, err error) {
+	// Status lama dikunci agar perubahan bersamaan tidak saling menimpa
+	var current struct {
+		StudentUUID uuid.UUID     `db:"student_uuid"`
+		DriverUUID  uuid.UUID     `db:"driver_uuid"`
+		TripUUID    uuid.NullUUID `db:"trip_uuid"`
+		SchoolUUID  uuid.NullUUID `db:"school_uuid"`
+		Status      string        `db:"status"`
+	}
 	query := `
+		SELECT sh.student_uuid, sh.driver_uuid, sh.trip_uuid, st.school_uuid, sh.status
+		FROM shuttle sh
+		LEFT JOIN students st ON st.student_uuid = sh.student_uuid
+		WHERE sh.shuttle_uuid = $1
+		FOR UPDATE OF sh`
+	if err = tx.Get(&current, query, shuttleUUID); err != nil {
+		return before, after, err
+	}
+
+	query = `
+		SELECT shuttle_id, shuttle_uuid, student_uuid, driver_uuid, status, created_at, updated_at
+		FROM shuttle
+		WHERE shuttle_uuid = $1`
+	if err = tx.Get(&before, query, shuttleUUID); err != nil {
+		return before, after, err
+	}
+
+	query = `
 		UPDATE shuttle
-		SET status = :status, updated_at = NOW()
-		WHERE shuttle_uuid = :shuttle_uuid`
+		SET status = $1, updated_at = NOW()
+		WHERE shuttle_uuid = $2
+		RETURNING shuttle_id, shuttle_uuid, student_uuid, driver_uuid, status, created_at, updated_at`
+	if err = tx.Get(&after, query, status, shuttleUUID); err != nil {
+		return before, after, err
+	}
+
+	// Status yang sama tidak dianggap perubahan
+	if current.Status != status {
+		event := entity.DomainEvent{
+			Type:          entity.EventShuttleStatusChanged,
+			AggregateType: "shuttle",
+			AggregateUUID: shuttleUUID,
+			SchoolUUID:    current.SchoolUUID,
+			DedupKey:      fmt.Sprintf("%s:%s:%d", entity.EventShuttleStatusChanged, shuttleUUID, after.UpdatedAt.Time.UnixMicro()),
+		}
+		payload := map[string]interface{}{
+			"shuttle_uuid":    shuttleUUID,
+			"student_uuid":    current.StudentUUID,
+			"driver_uuid":     current.DriverUUID,
+			"trip_uuid":       current.TripUUID,
+			"previous_status": current.Status,
+			"status":          status,
+		}
+		if err = saveDomainEvent(tx, event, payload); err != nil {
+			return before, after, err
+		}
 
-	// Data untuk query
-	data := map[string]interface{}{
-		"status":       status,
-		"shuttle_uuid": shuttleUUID,
+		if current.TripUUID.Valid {
+			if err = saveTripCompletedEvent(tx, current.TripUUID.UUID, status); err != nil {
+				return before, after, err
+			}
+		}
 	}
 
-	// Eksekusi query
-	result, err := r.DB.NamedExec(query, data)
-	if err != nil {
+	return before, after, nil
+}
+
+// Trip selesai saat shuttle terakhirnya sampai tujuan. Baris trip dikunci agar dua shuttle terakhir
+// yang sampai bersamaan tetap menghasilkan satu event.
+func saveTripCompletedEvent(tx *sqlx.Tx, tripUUID uuid.UUID, status string) error {
+	var trip struct {
+		SchoolUUID  uuid.UUID     `db:"school_uuid"`
+		DriverUUID  uuid.NullUUID `db:"driver_uuid"`
+		VehicleUUID uuid.NullUUID `db:"vehicle_uuid"`
+		Direction   string        `db:"direction"`
+		TripDate    time.Time     `db:"trip_date"`
+	}
+	query := `SELECT school_uuid, driver_uuid, vehicle_uuid, direction, trip_date FROM trips WHERE trip_uuid = $1 FOR UPDATE`
+	if err := tx.Get(&trip, query, tripUUID); err != nil {
 		return err
 	}
 
-	// Periksa apakah row diperbarui
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	arrived := "di rumah"
+	if trip.Direction == entity.TripToSchool {
+		arrived = "di sekolah"
+	}
+	if status != arrived {
+		return nil
+	}
+
+	var remaining, students int
+	query = `
+		SELECT COUNT(*) FILTER (WHERE status <> $2::shuttle_status), COUNT(*)
+		FROM shuttle
+		WHERE trip_uuid = $1 AND deleted_at IS NULL`
+	if err := tx.QueryRowx(query, tripUUID, arrived).Scan(&remaining, &students); err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+
+	event := entity.DomainEvent{
+		Type:          entity.EventTripCompleted,
+		AggregateType: "trip",
+		AggregateUUID: tripUUID,
+		SchoolUUID:    uuid.NullUUID{UUID: trip.SchoolUUID, Valid: true},
+		DedupKey:      entity.EventTripCompleted + ":" + tripUUID.String(),
+	}
+	payload := map[string]interface{}{
+		"trip_uuid":    tripUUID,
+		"school_uuid":  trip.SchoolUUID,
+		"driver_uuid":  trip.DriverUUID,
+		"vehicle_uuid": trip.VehicleUUID,
+		"direction":    trip.Direction,
+		"trip_date":    trip.TripDate.Format(time.DateOnly),
+		"students":     students,
+	}
+
+	return saveDomainEvent(tx, event, payload)
+}
+
+func (r *ShuttleRepository) FetchShuttle(shuttleUUID uuid.UUID) (entity.Shuttle, error) {
+	var shuttle entity.Shuttle
+
+	query := `
+		SELECT shuttle_id, shuttle_uuid, student_uuid, driver_uuid, status, created_at, updated_at
+		FROM shuttle
+		WHERE shuttle_uuid = $1 AND deleted_at IS NULL
+	`
+
+	if err := r.DB.Get(&shuttle, query, shuttleUUID); err != nil {
+		return entity.Shuttle{}, err
+	}
+
+	return shuttle, nil
+}
+
+func (r *ShuttleRepository) FetchShuttleStudent(shuttleUUID uuid.UUID) (entity.ShuttleStudent, error) {
+	var student entity.ShuttleStudent
+
+	query := `
+		SELECT sh.shuttle_uuid, sh.student_uuid, st.student_first_name, st.student_last_name, sh.status
+		FROM shuttle sh
+		JOIN students st ON st.student_uuid = sh.student_uuid
+		WHERE sh.shuttle_uuid = $1
+	`
+
+	if err := r.DB.Get(&student, query, shuttleUUID); err != nil {
+		return entity.ShuttleStudent{}, err
+	}
+
+	return student, nil
+}
+
+func (r *ShuttleRepository) FetchStudentName(studentUUID uuid.UUID) (string, error) {
+	var name string
+
+	query := `SELECT student_first_name || ' ' || student_last_name FROM students WHERE student_uuid = $1`
+
+	if err := r.DB.Get(&name, query, studentUUID); err != nil {
+		return "", err
 	}
 
-	return nil
+	return name, nil
 }
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type StudentAbsenceRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	CheckGuardianOfStudent(guardianUUID, studentUUID string) (bool, error)
+	FetchStudentTimezone(studentUUID string) (string, error)
+	CheckAbsenceExists(studentUUID string, absentOn time.Time) (bool, error)
+
+	FetchAbsences(studentUUID string, from time.Time) ([]entity.StudentAbsence, error)
+	FetchSpecAbsence(studentUUID, absenceUUID string) (entity.StudentAbsence, error)
+	SaveAbsence(tx *sqlx.Tx, absence entity.StudentAbsence) error
+	DeleteAbsence(tx *sqlx.Tx, studentUUID, absenceUUID, username string) error
+
+	SetTripShuttlesSkipped(tx *sqlx.Tx, studentUUID string, tripDate time.Time, skipped bool) error
+}
+
+type StudentAbsenceRepository struct {
+	db *sqlx.DB
+}
+
+func NewStudentAbsenceRepository(db *sqlx.DB) StudentAbsenceRepositoryInterface {
+	return &StudentAbsenceRepository{
+		db: db,
+	}
+}
+
+func (repository *StudentAbsenceRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *StudentAbsenceRepository) CheckGuardianOfStudent(guardianUUID, studentUUID string) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM student_guardians g
+			JOIN students st ON st.student_uuid = g.student_uuid
+			WHERE g.guardian_uuid = $1 AND g.student_uuid = $2 AND st.deleted_at IS NULL
+		)
+	`
+
+	if err := repository.db.Get(&exists, query, guardianUUID, studentUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *StudentAbsenceRepository) FetchStudentTimezone(studentUUID string) (string, error) {
+	var timezone string
+
+	query := `
+		SELECT s.school_timezone
+		FROM students st
+		JOIN schools s ON s.school_uuid = st.school_uuid
+		WHERE st.student_uuid = $1
+	`
+
+	if err := repository.db.Get(&timezone, query, studentUUID); err != nil {
+		return "", err
+	}
+
+	return timezone, nil
+}
+
+func (repository *StudentAbsenceRepository) CheckAbsenceExists(studentUUID string, absentOn time.Time) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM student_absences WHERE student_uuid = $1 AND absent_on = $2 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, studentUUID, absentOn); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Absences on or after from
+func (repository *StudentAbsenceRepository) FetchAbsences(studentUUID string, from time.Time) ([]entity.StudentAbsence, error) {
+	absences := []entity.StudentAbsence{}
+
+	query := `
+		SELECT absence_id, absence_uuid, student_uuid, absent_on, absence_reason, created_at, created_by
+		FROM student_absences
+		WHERE student_uuid = $1 AND absent_on >= $2 AND deleted_at IS NULL
+		ORDER BY absent_on
+	`
+
+	if err := repository.db.Select(&absences, query, studentUUID, from); err != nil {
+		return nil, err
+	}
+
+	return absences, nil
+}
+
+func (repository *StudentAbsenceRepository) FetchSpecAbsence(studentUUID, absenceUUID string) (entity.StudentAbsence, error) {
+	var absence entity.StudentAbsence
+
+	query := `
+		SELECT absence_id, absence_uuid, student_uuid, absent_on, absence_reason, created_at, created_by
+		FROM student_absences
+		WHERE student_uuid = $1 AND absence_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&absence, query, studentUUID, absenceUUID); err != nil {
+		return entity.StudentAbsence{}, err
+	}
+
+	return absence, nil
+}
+
+func (repository *StudentAbsenceRepository) SaveAbsence(tx *sqlx.Tx, absence entity.StudentAbsence) error {
+	query := `
+		INSERT INTO student_absences (absence_id, absence_uuid, student_uuid, absent_on, absence_reason, created_by)
+		VALUES (:absence_id, :absence_uuid, :student_uuid, :absent_on, :absence_reason, :created_by)
+	`
+
+	_, err := tx.NamedExec(query, absence)
+	return err
+}
+
+func (repository *StudentAbsenceRepository) DeleteAbsence(tx *sqlx.Tx, studentUUID, absenceUUID, username string) error {
+	query := `
+		UPDATE student_absences
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE student_uuid = $1 AND absence_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(query, studentUUID, absenceUUID, username)
+	return err
+}
+
+// Takes the student off, or back on, the trips of that day that have already been generated but
+// have not left yet
+func (repository *StudentAbsenceRepository) SetTripShuttlesSkipped(tx *sqlx.Tx, studentUUID string, tripDate time.Time, skipped bool) error {
+	query := `
+		UPDATE shuttle sh
+		SET deleted_at = CASE WHEN $3 THEN NOW() END
+		FROM trips t
+		WHERE t.trip_uuid = sh.trip_uuid AND sh.student_uuid = $1 AND t.trip_date = $2 AND t.departs_at > NOW()
+			AND (sh.deleted_at IS NULL) = $3
+	`
+
+	_, err := tx.Exec(query, studentUUID, tripDate, skipped)
+	return err
+}
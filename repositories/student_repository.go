@@ -2,17 +2,36 @@ package repositories
 
 import (
 	// "database/sql"
-	// "fmt"
+	"fmt"
 	"shuttle/models/entity"
-	// "strings"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type StudentRepositoryInterface interface {
 	BeginTransaction() (*sqlx.Tx, error)
+	Savepoint(tx *sqlx.Tx, name string) error
+	RollbackToSavepoint(tx *sqlx.Tx, name string) error
+	CountPermittedSchoolStudents(schoolUUID, search, grade string) (int, error)
+	FetchPermittedSchoolStudents(schoolUUID string, offset, limit int, sortField, sortDirection, search, grade string) ([]entity.Student, error)
+	FetchSpecPermittedSchoolStudent(studentUUID, schoolUUID string) (entity.Student, error)
+	FetchStudentSiblings(studentUUID, schoolUUID string) ([]entity.Student, error)
+	CountActiveStudentsOfParent(tx *sqlx.Tx, parentUUID string) (int, error)
+	CheckGuardianInSchool(tx *sqlx.Tx, parentUUID, schoolUUID string) (bool, error)
 	SaveStudent(tx *sqlx.Tx, student entity.Student) (uuid.UUID, error)
+	UpdateStudent(tx *sqlx.Tx, student entity.Student, schoolUUID string) error
+	DeleteStudent(tx *sqlx.Tx, studentUUID, schoolUUID, username string) error
+
+	FetchStudentGuardians(studentUUIDs []string) (map[string][]entity.StudentGuardian, error)
+	FetchStudentGuardianUUIDs(tx *sqlx.Tx, studentUUID string) ([]string, error)
+	SaveStudentGuardian(tx *sqlx.Tx, guardian entity.StudentGuardian) error
+	UpdateStudentGuardian(tx *sqlx.Tx, guardian entity.StudentGuardian) (bool, error)
+	ClearPrimaryGuardian(tx *sqlx.Tx, studentUUID string) error
+	PromotePrimaryGuardian(tx *sqlx.Tx, studentUUID string) error
+	DeleteStudentGuardian(tx *sqlx.Tx, studentUUID, guardianUUID string) (bool, error)
 }
 
 type studentRepository struct {
@@ -33,13 +52,26 @@ func (r *studentRepository) BeginTransaction() (*sqlx.Tx, error) {
 	return tx, nil
 }
 
+// Savepoints let a bulk import drop a single failing row without losing the whole transaction
+func (r *studentRepository) Savepoint(tx *sqlx.Tx, name string) error {
+	_, err := tx.Exec("SAVEPOINT " + name)
+	return err
+}
+
+func (r *studentRepository) RollbackToSavepoint(tx *sqlx.Tx, name string) error {
+	_, err := tx.Exec("ROLLBACK TO SAVEPOINT " + name)
+	return err
+}
+
 func (r *studentRepository) SaveStudent(tx *sqlx.Tx, student entity.Student) (uuid.UUID, error) {
 	query := `
 		INSERT INTO students (
-			student_id, student_uuid, student_first_name, student_last_name, student_gender, student_grade, parent_uuid, 
-			school_uuid, created_at, created_by
+			student_id, student_uuid, student_first_name, student_last_name, student_gender, student_grade,
+			grade_level_uuid, school_uuid, created_at, created_by
 		) VALUES (
-			:student_id, :student_uuid, :first_name, :last_name, :student_gender, :student_grade, :parent_uuid, 
+			:student_id, :student_uuid, :first_name, :last_name, :student_gender, :student_grade,
+			(SELECT grade_level_uuid FROM grade_levels
+				WHERE school_uuid = :school_uuid AND LOWER(grade_name) = LOWER(:student_grade) AND deleted_at IS NULL),
 			:school_uuid, NOW(), :created_by
 		)
 		RETURNING student_uuid
@@ -59,6 +91,327 @@ func (r *studentRepository) SaveStudent(tx *sqlx.Tx, student entity.Student) (uu
 			return uuid.Nil, err
 		}
 	}
+	// The connection is busy until the rows are closed
+	if err := rows.Close(); err != nil {
+		return uuid.Nil, err
+	}
+
+	event := entity.DomainEvent{
+		Type:          entity.EventStudentCreated,
+		AggregateType: "student",
+		AggregateUUID: studentUUID,
+		SchoolUUID:    uuid.NullUUID{UUID: student.SchoolUUID, Valid: true},
+		DedupKey:      entity.EventStudentCreated + ":" + studentUUID.String(),
+	}
+	payload := map[string]interface{}{
+		"student_uuid":   studentUUID,
+		"school_uuid":    student.SchoolUUID,
+		"first_name":     student.FirstName,
+		"last_name":      student.LastName,
+		"student_gender": student.Gender,
+		"student_grade":  student.Grade,
+	}
+	if err := saveDomainEvent(tx, event, payload); err != nil {
+		return uuid.Nil, err
+	}
 
 	return studentUUID, nil
-}
\ No newline at end of file
+}
+
+// Builds the WHERE clause shared by the student list and its count, search matches the full name
+// and grade is an exact match
+func studentFilter(schoolUUID, search, grade string) (string, []interface{}) {
+	conditions := []string{"s.school_uuid = $1", "s.deleted_at IS NULL"}
+	args := []interface{}{schoolUUID}
+
+	if search != "" {
+		args = append(args, "%"+search+"%")
+		conditions = append(conditions, fmt.Sprintf("(s.student_first_name || ' ' || s.student_last_name) ILIKE $%d", len(args)))
+	}
+
+	if grade != "" {
+		args = append(args, grade)
+		conditions = append(conditions, fmt.Sprintf("s.student_grade = $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r *studentRepository) CountPermittedSchoolStudents(schoolUUID, search, grade string) (int, error) {
+	var count int
+
+	where, args := studentFilter(schoolUUID, search, grade)
+	query := `
+		SELECT COUNT(s.student_id)
+		FROM students s
+		WHERE ` + where
+
+	if err := r.DB.Get(&count, query, args...); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *studentRepository) FetchPermittedSchoolStudents(schoolUUID string, offset, limit int, sortField, sortDirection, search, grade string) ([]entity.Student, error) {
+	var students []entity.Student
+
+	where, args := studentFilter(schoolUUID, search, grade)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT
+			s.student_uuid, s.school_uuid, s.student_first_name AS first_name, s.student_last_name AS last_name,
+			s.student_gender, s.student_grade, s.created_at, s.created_by, s.updated_at, s.updated_by
+		FROM students s
+		WHERE %s
+		ORDER BY s.%s %s
+		LIMIT $%d OFFSET $%d
+	`, where, sortField, sortDirection, len(args)-1, len(args))
+
+	if err := r.DB.Select(&students, query, args...); err != nil {
+		return nil, err
+	}
+
+	return students, nil
+}
+
+func (r *studentRepository) FetchSpecPermittedSchoolStudent(studentUUID, schoolUUID string) (entity.Student, error) {
+	var student entity.Student
+
+	query := `
+		SELECT
+			s.student_uuid, s.school_uuid, s.student_first_name AS first_name, s.student_last_name AS last_name,
+			s.student_gender, s.student_grade, s.created_at, s.created_by, s.updated_at, s.updated_by
+		FROM students s
+		WHERE s.student_uuid = $1 AND s.school_uuid = $2 AND s.deleted_at IS NULL
+	`
+
+	if err := r.DB.Get(&student, query, studentUUID, schoolUUID); err != nil {
+		return entity.Student{}, err
+	}
+
+	return student, nil
+}
+
+// Siblings are the other students of the same school that share at least one guardian
+func (r *studentRepository) FetchStudentSiblings(studentUUID, schoolUUID string) ([]entity.Student, error) {
+	var students []entity.Student
+
+	query := `
+		SELECT DISTINCT
+			s.student_uuid, s.school_uuid, s.student_first_name AS first_name, s.student_last_name AS last_name,
+			s.student_gender, s.student_grade, s.created_at, s.created_by, s.updated_at, s.updated_by
+		FROM student_guardians own
+		JOIN student_guardians other ON other.guardian_uuid = own.guardian_uuid AND other.student_uuid <> own.student_uuid
+		JOIN students s ON s.student_uuid = other.student_uuid
+		WHERE own.student_uuid = $1 AND s.school_uuid = $2 AND s.deleted_at IS NULL
+		ORDER BY first_name ASC
+	`
+
+	if err := r.DB.Select(&students, query, studentUUID, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return students, nil
+}
+
+func (r *studentRepository) CountActiveStudentsOfParent(tx *sqlx.Tx, parentUUID string) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(s.student_id)
+		FROM student_guardians sg
+		JOIN students s ON s.student_uuid = sg.student_uuid
+		WHERE sg.guardian_uuid = $1 AND s.deleted_at IS NULL
+	`
+
+	if err := tx.Get(&count, query, parentUUID); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Whether the parent is guardian of an active student of the school, links saved earlier in the
+// transaction count
+func (r *studentRepository) CheckGuardianInSchool(tx *sqlx.Tx, parentUUID, schoolUUID string) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM student_guardians sg
+			JOIN students s ON s.student_uuid = sg.student_uuid
+			WHERE sg.guardian_uuid = $1 AND s.school_uuid = $2 AND s.deleted_at IS NULL
+		)
+	`
+
+	if err := tx.Get(&exists, query, parentUUID, schoolUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *studentRepository) UpdateStudent(tx *sqlx.Tx, student entity.Student, schoolUUID string) error {
+	query := `
+		UPDATE students
+		SET student_first_name = $1, student_last_name = $2, student_gender = $3, student_grade = $4,
+			grade_level_uuid = (SELECT grade_level_uuid FROM grade_levels
+				WHERE school_uuid = $7 AND LOWER(grade_name) = LOWER($4) AND deleted_at IS NULL),
+			updated_at = NOW(), updated_by = $5
+		WHERE student_uuid = $6 AND school_uuid = $7 AND deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(query, student.FirstName, student.LastName, student.Gender, student.Grade,
+		student.UpdatedBy, student.UUID, schoolUUID)
+	return err
+}
+
+func (r *studentRepository) DeleteStudent(tx *sqlx.Tx, studentUUID, schoolUUID, username string) error {
+	query := `
+		UPDATE students
+		SET deleted_at = NOW(), deleted_by = $1
+		WHERE student_uuid = $2 AND school_uuid = $3 AND deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(query, username, studentUUID, schoolUUID)
+	return err
+}
+
+// Returns the guardians of every given student keyed by student UUID, primary guardian first
+func (r *studentRepository) FetchStudentGuardians(studentUUIDs []string) (map[string][]entity.StudentGuardian, error) {
+	guardians := make(map[string][]entity.StudentGuardian)
+	if len(studentUUIDs) == 0 {
+		return guardians, nil
+	}
+
+	var rows []entity.StudentGuardian
+
+	query := `
+		SELECT
+			sg.student_guardian_id, sg.student_uuid, sg.guardian_uuid, sg.guardian_relationship, sg.can_pickup, sg.is_primary,
+			sg.created_at, sg.created_by, sg.updated_at, sg.updated_by,
+			u.user_email, COALESCE(pd.user_first_name, '') AS user_first_name, COALESCE(pd.user_last_name, '') AS user_last_name,
+			COALESCE(pd.user_phone, '') AS user_phone, COALESCE(pd.user_address, '') AS user_address
+		FROM student_guardians sg
+		JOIN users u ON u.user_uuid = sg.guardian_uuid AND u.deleted_at IS NULL
+		LEFT JOIN parent_details pd ON pd.user_uuid = u.user_uuid
+		WHERE sg.student_uuid = ANY($1::UUID[])
+		ORDER BY sg.is_primary DESC, sg.created_at ASC
+	`
+
+	if err := r.DB.Select(&rows, query, pq.Array(studentUUIDs)); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		key := row.StudentUUID.String()
+		guardians[key] = append(guardians[key], row)
+	}
+
+	return guardians, nil
+}
+
+func (r *studentRepository) FetchStudentGuardianUUIDs(tx *sqlx.Tx, studentUUID string) ([]string, error) {
+	var guardianUUIDs []string
+
+	query := `
+		SELECT guardian_uuid
+		FROM student_guardians
+		WHERE student_uuid = $1
+	`
+
+	if err := tx.Select(&guardianUUIDs, query, studentUUID); err != nil {
+		return nil, err
+	}
+
+	return guardianUUIDs, nil
+}
+
+func (r *studentRepository) SaveStudentGuardian(tx *sqlx.Tx, guardian entity.StudentGuardian) error {
+	query := `
+		INSERT INTO student_guardians (
+			student_guardian_id, student_uuid, guardian_uuid, guardian_relationship, can_pickup, is_primary,
+			created_at, created_by
+		) VALUES (
+			:student_guardian_id, :student_uuid, :guardian_uuid, :guardian_relationship, :can_pickup, :is_primary,
+			NOW(), :created_by
+		)
+	`
+
+	_, err := tx.NamedExec(query, guardian)
+	return err
+}
+
+func (r *studentRepository) UpdateStudentGuardian(tx *sqlx.Tx, guardian entity.StudentGuardian) (bool, error) {
+	query := `
+		UPDATE student_guardians
+		SET guardian_relationship = :guardian_relationship, can_pickup = :can_pickup, is_primary = :is_primary,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE student_uuid = :student_uuid AND guardian_uuid = :guardian_uuid
+	`
+
+	result, err := tx.NamedExec(query, guardian)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *studentRepository) ClearPrimaryGuardian(tx *sqlx.Tx, studentUUID string) error {
+	query := `
+		UPDATE student_guardians
+		SET is_primary = FALSE
+		WHERE student_uuid = $1 AND is_primary
+	`
+
+	_, err := tx.Exec(query, studentUUID)
+	return err
+}
+
+// Makes the oldest remaining guardian primary when the student has none left
+func (r *studentRepository) PromotePrimaryGuardian(tx *sqlx.Tx, studentUUID string) error {
+	query := `
+		UPDATE student_guardians
+		SET is_primary = TRUE
+		WHERE student_guardian_id = (
+			SELECT student_guardian_id
+			FROM student_guardians
+			WHERE student_uuid = $1
+			ORDER BY created_at ASC, student_guardian_id ASC
+			LIMIT 1
+		) AND NOT EXISTS (
+			SELECT 1 FROM student_guardians WHERE student_uuid = $1 AND is_primary
+		)
+	`
+
+	_, err := tx.Exec(query, studentUUID)
+	return err
+}
+
+func (r *studentRepository) DeleteStudentGuardian(tx *sqlx.Tx, studentUUID, guardianUUID string) (bool, error) {
+	query := `
+		DELETE FROM student_guardians
+		WHERE student_uuid = $1 AND guardian_uuid = $2
+	`
+
+	result, err := tx.Exec(query, studentUUID, guardianUUID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,358 @@
+package repositories
+
+import (
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const tripScheduleQuery = `
+	SELECT ts.schedule_id, ts.schedule_uuid, ts.school_uuid, ts.route_uuid, ts.vehicle_uuid, ts.driver_uuid, ts.direction,
+		TO_CHAR(ts.departure_time, 'HH24:MI') AS departure_time, ts.weekdays, ts.effective_from, ts.effective_to,
+		r.route_name, v.vehicle_number, d.user_first_name, d.user_last_name, s.school_timezone,
+		ts.created_at, ts.created_by, ts.updated_at, ts.updated_by
+	FROM trip_schedules ts
+	JOIN schools s ON s.school_uuid = ts.school_uuid
+	LEFT JOIN routes r ON r.route_uuid = ts.route_uuid
+	LEFT JOIN vehicles v ON v.vehicle_uuid = ts.vehicle_uuid
+	LEFT JOIN driver_details d ON d.user_uuid = ts.driver_uuid
+`
+
+type TripScheduleRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchSchedules(schoolUUID string) ([]entity.TripSchedule, error)
+	FetchSpecSchedule(schoolUUID, scheduleUUID string) (entity.TripSchedule, error)
+	FetchScheduleStudents(scheduleUUIDs []string) (map[string][]string, error)
+	CheckScheduleReferences(schoolUUID, routeUUID, vehicleUUID, driverUUID string) (entity.TripScheduleReferences, error)
+	CountSchoolStudents(schoolUUID string, studentUUIDs []string) (int, error)
+	CheckScheduleConflict(schedule entity.TripSchedule, tripDuration time.Duration) (bool, error)
+	SaveSchedule(tx *sqlx.Tx, schedule entity.TripSchedule) error
+	UpdateSchedule(tx *sqlx.Tx, schedule entity.TripSchedule) error
+	ReplaceScheduleStudents(tx *sqlx.Tx, scheduleUUID uuid.UUID, studentUUIDs []string) error
+	DeleteSchedule(schoolUUID, scheduleUUID, username string) error
+
+	FetchRunnableSchedules(schoolUUID string, tripDate time.Time) ([]entity.TripSchedule, error)
+	SaveTrip(tx *sqlx.Tx, trip entity.Trip) (uuid.UUID, error)
+	FetchTripRiders(tx *sqlx.Tx, scheduleUUID uuid.UUID, tripDate time.Time) ([]uuid.UUID, error)
+	SaveTripShuttle(tx *sqlx.Tx, shuttle entity.Shuttle, tripUUID uuid.UUID, direction string) (int64, error)
+	FetchTrips(schoolUUID, driverUUID string, tripDate time.Time) ([]entity.Trip, error)
+}
+
+type TripScheduleRepository struct {
+	db *sqlx.DB
+}
+
+func NewTripScheduleRepository(db *sqlx.DB) TripScheduleRepositoryInterface {
+	return &TripScheduleRepository{
+		db: db,
+	}
+}
+
+func (repository *TripScheduleRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *TripScheduleRepository) FetchSchedules(schoolUUID string) ([]entity.TripSchedule, error) {
+	schedules := []entity.TripSchedule{}
+
+	query := tripScheduleQuery + `
+		WHERE ts.school_uuid = $1 AND ts.deleted_at IS NULL
+		ORDER BY ts.departure_time, ts.schedule_id
+	`
+
+	if err := repository.db.Select(&schedules, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (repository *TripScheduleRepository) FetchSpecSchedule(schoolUUID, scheduleUUID string) (entity.TripSchedule, error) {
+	var schedule entity.TripSchedule
+
+	query := tripScheduleQuery + `
+		WHERE ts.school_uuid = $1 AND ts.schedule_uuid = $2 AND ts.deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&schedule, query, schoolUUID, scheduleUUID); err != nil {
+		return entity.TripSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+// Returns the active students of every schedule, keyed by schedule
+func (repository *TripScheduleRepository) FetchScheduleStudents(scheduleUUIDs []string) (map[string][]string, error) {
+	var rows []struct {
+		ScheduleUUID string `db:"schedule_uuid"`
+		StudentUUID  string `db:"student_uuid"`
+	}
+
+	query := `
+		SELECT tss.schedule_uuid, tss.student_uuid
+		FROM trip_schedule_students tss
+		JOIN students st ON st.student_uuid = tss.student_uuid
+		WHERE tss.schedule_uuid::text = ANY($1) AND st.deleted_at IS NULL
+		ORDER BY st.student_first_name, st.student_last_name
+	`
+
+	if err := repository.db.Select(&rows, query, pq.Array(scheduleUUIDs)); err != nil {
+		return nil, err
+	}
+
+	students := make(map[string][]string)
+	for _, row := range rows {
+		students[row.ScheduleUUID] = append(students[row.ScheduleUUID], row.StudentUUID)
+	}
+
+	return students, nil
+}
+
+func (repository *TripScheduleRepository) CheckScheduleReferences(schoolUUID, routeUUID, vehicleUUID, driverUUID string) (entity.TripScheduleReferences, error) {
+	var references entity.TripScheduleReferences
+
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM routes WHERE route_uuid = $2 AND school_uuid = $1 AND deleted_at IS NULL) AS route_found,
+			EXISTS (SELECT 1 FROM vehicles WHERE vehicle_uuid = $3 AND school_uuid = $1 AND deleted_at IS NULL) AS vehicle_found,
+			EXISTS (
+				SELECT 1 FROM users u JOIN driver_details d ON d.user_uuid = u.user_uuid
+				WHERE u.user_uuid = $4 AND d.school_uuid = $1 AND u.deleted_at IS NULL
+			) AS driver_found
+	`
+
+	if err := repository.db.Get(&references, query, schoolUUID, routeUUID, vehicleUUID, driverUUID); err != nil {
+		return entity.TripScheduleReferences{}, err
+	}
+
+	return references, nil
+}
+
+func (repository *TripScheduleRepository) CountSchoolStudents(schoolUUID string, studentUUIDs []string) (int, error) {
+	var total int
+
+	query := `
+		SELECT COUNT(*) FROM students
+		WHERE school_uuid = $1 AND student_uuid::text = ANY($2) AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&total, query, schoolUUID, pq.Array(studentUUIDs)); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// A driver or vehicle cannot have two trips on the same day that overlap. Every trip is taken to last
+// tripDuration from its departure, so two departures must be at least that far apart.
+func (repository *TripScheduleRepository) CheckScheduleConflict(schedule entity.TripSchedule, tripDuration time.Duration) (bool, error) {
+	var conflict bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM trip_schedules
+			WHERE deleted_at IS NULL AND schedule_uuid <> $1
+				AND (driver_uuid = $2 OR vehicle_uuid = $3)
+				AND ABS(EXTRACT(EPOCH FROM departure_time) - EXTRACT(EPOCH FROM $4::time)) < $8::FLOAT8
+				AND weekdays && $5::smallint[]
+				AND effective_from <= COALESCE($7::date, 'infinity')
+				AND COALESCE(effective_to, 'infinity') >= $6::date
+		)
+	`
+
+	err := repository.db.Get(&conflict, query, schedule.UUID, schedule.DriverUUID, schedule.VehicleUUID, schedule.DepartureTime,
+		schedule.Weekdays, schedule.EffectiveFrom, schedule.EffectiveTo, tripDuration.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	return conflict, nil
+}
+
+func (repository *TripScheduleRepository) SaveSchedule(tx *sqlx.Tx, schedule entity.TripSchedule) error {
+	query := `
+		INSERT INTO trip_schedules (schedule_id, schedule_uuid, school_uuid, route_uuid, vehicle_uuid, driver_uuid, direction,
+			departure_time, weekdays, effective_from, effective_to, created_by)
+		VALUES (:schedule_id, :schedule_uuid, :school_uuid, :route_uuid, :vehicle_uuid, :driver_uuid, :direction,
+			:departure_time, :weekdays, :effective_from, :effective_to, :created_by)
+	`
+
+	_, err := tx.NamedExec(query, schedule)
+	return err
+}
+
+func (repository *TripScheduleRepository) UpdateSchedule(tx *sqlx.Tx, schedule entity.TripSchedule) error {
+	query := `
+		UPDATE trip_schedules
+		SET route_uuid = :route_uuid, vehicle_uuid = :vehicle_uuid, driver_uuid = :driver_uuid, direction = :direction,
+			departure_time = :departure_time, weekdays = :weekdays, effective_from = :effective_from, effective_to = :effective_to,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE schedule_uuid = :schedule_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	_, err := tx.NamedExec(query, schedule)
+	return err
+}
+
+func (repository *TripScheduleRepository) ReplaceScheduleStudents(tx *sqlx.Tx, scheduleUUID uuid.UUID, studentUUIDs []string) error {
+	if _, err := tx.Exec(`DELETE FROM trip_schedule_students WHERE schedule_uuid = $1`, scheduleUUID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO trip_schedule_students (schedule_uuid, student_uuid)
+		SELECT $1, UNNEST($2::uuid[])
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(query, scheduleUUID, pq.Array(studentUUIDs))
+	return err
+}
+
+// Trips already generated stay as they are
+func (repository *TripScheduleRepository) DeleteSchedule(schoolUUID, scheduleUUID, username string) error {
+	query := `
+		UPDATE trip_schedules
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE school_uuid = $1 AND schedule_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, schoolUUID, scheduleUUID, username)
+	return err
+}
+
+// Schedules whose school, route, vehicle and driver are all still active. An empty schoolUUID returns
+// the schedules of every school. Each schedule says whether its driver's licence has expired by
+// tripDate and whether its vehicle is in maintenance, out of service or retired, the same rules
+// AddShuttle applies.
+func (repository *TripScheduleRepository) FetchRunnableSchedules(schoolUUID string, tripDate time.Time) ([]entity.TripSchedule, error) {
+	schedules := []entity.TripSchedule{}
+
+	query := `
+		SELECT runnable.*,
+			EXISTS (
+				SELECT 1 FROM driver_documents
+				WHERE driver_uuid = runnable.driver_uuid AND document_type = 'license' AND deleted_at IS NULL
+			) AND NOT EXISTS (
+				SELECT 1 FROM driver_documents
+				WHERE driver_uuid = runnable.driver_uuid AND document_type = 'license' AND deleted_at IS NULL
+					AND (expires_on IS NULL OR expires_on >= $3::date)
+			) AS license_expired,
+			EXISTS (
+				SELECT 1 FROM vehicles
+				WHERE vehicle_uuid = runnable.vehicle_uuid AND vehicle_status IN ($2, 'maintenance', 'retired')
+			) AS vehicle_unavailable
+		FROM (` + tripScheduleQuery + `
+			JOIN users u ON u.user_uuid = ts.driver_uuid
+			WHERE ts.deleted_at IS NULL AND s.deleted_at IS NULL AND ($1 = '' OR ts.school_uuid::text = $1)
+				AND r.deleted_at IS NULL AND r.route_uuid IS NOT NULL
+				AND v.deleted_at IS NULL AND v.vehicle_uuid IS NOT NULL
+				AND u.deleted_at IS NULL
+		) runnable
+		ORDER BY runnable.school_uuid, runnable.departure_time
+	`
+
+	if err := repository.db.Select(&schedules, query, schoolUUID, entity.VehicleOutOfService, tripDate.Format(time.DateOnly)); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// Creates the trip unless the schedule already has one that day, returns the trip either way
+func (repository *TripScheduleRepository) SaveTrip(tx *sqlx.Tx, trip entity.Trip) (uuid.UUID, error) {
+	query := `
+		INSERT INTO trips (trip_id, trip_uuid, schedule_uuid, school_uuid, route_uuid, vehicle_uuid, driver_uuid, direction,
+			trip_date, departs_at, created_by)
+		VALUES (:trip_id, :trip_uuid, :schedule_uuid, :school_uuid, :route_uuid, :vehicle_uuid, :driver_uuid, :direction,
+			:trip_date, :departs_at, :created_by)
+		ON CONFLICT (schedule_uuid, trip_date) DO NOTHING
+	`
+
+	if _, err := tx.NamedExec(query, trip); err != nil {
+		return uuid.Nil, err
+	}
+
+	var tripUUID uuid.UUID
+	query = `SELECT trip_uuid FROM trips WHERE schedule_uuid = $1 AND trip_date = $2`
+	if err := tx.Get(&tripUUID, query, trip.ScheduleUUID, trip.TripDate); err != nil {
+		return uuid.Nil, err
+	}
+
+	return tripUUID, nil
+}
+
+// Students of the schedule that are still enrolled and not reported absent that day
+func (repository *TripScheduleRepository) FetchTripRiders(tx *sqlx.Tx, scheduleUUID uuid.UUID, tripDate time.Time) ([]uuid.UUID, error) {
+	var riders []uuid.UUID
+
+	query := `
+		SELECT tss.student_uuid
+		FROM trip_schedule_students tss
+		JOIN students st ON st.student_uuid = tss.student_uuid
+		WHERE tss.schedule_uuid = $1 AND st.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM student_absences a
+				WHERE a.student_uuid = tss.student_uuid AND a.absent_on = $2 AND a.deleted_at IS NULL
+			)
+	`
+
+	if err := tx.Select(&riders, query, scheduleUUID, tripDate); err != nil {
+		return nil, err
+	}
+
+	return riders, nil
+}
+
+// Adds the shuttle of one student to a trip, going to school it heads for the school's location and
+// going home it starts there. Returns 0 when the student already has a shuttle on the trip.
+func (repository *TripScheduleRepository) SaveTripShuttle(tx *sqlx.Tx, shuttle entity.Shuttle, tripUUID uuid.UUID, direction string) (int64, error) {
+	query := `
+		INSERT INTO shuttle (shuttle_id, shuttle_uuid, student_uuid, driver_uuid, status, student_destination_name, student_destination_point, trip_uuid, created_at)
+		SELECT $1, $2, $3, $4, $5::shuttle_status,
+			CASE WHEN $7 = '` + entity.TripToSchool + `' THEN LEFT(s.school_name, 50) ELSE 'home' END,
+			CASE WHEN $7 = '` + entity.TripToSchool + `' AND s.school_latitude IS NOT NULL
+				THEN JSON_BUILD_OBJECT('latitude', s.school_latitude, 'longitude', s.school_longitude) END,
+			t.trip_uuid, NOW()
+		FROM trips t
+		JOIN schools s ON s.school_uuid = t.school_uuid
+		WHERE t.trip_uuid = $6
+		ON CONFLICT (trip_uuid, student_uuid) WHERE trip_uuid IS NOT NULL DO NOTHING
+	`
+
+	res, err := tx.Exec(query, shuttle.ShuttleID, shuttle.ShuttleUUID, shuttle.StudentUUID, shuttle.DriverUUID, shuttle.Status, tripUUID, direction)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Empty schoolUUID or driverUUID do not filter
+func (repository *TripScheduleRepository) FetchTrips(schoolUUID, driverUUID string, tripDate time.Time) ([]entity.Trip, error) {
+	trips := []entity.Trip{}
+
+	query := `
+		SELECT t.trip_id, t.trip_uuid, t.schedule_uuid, t.school_uuid, t.route_uuid, t.vehicle_uuid, t.driver_uuid, t.direction,
+			t.trip_date, t.departs_at, r.route_name, v.vehicle_number, d.user_first_name, d.user_last_name,
+			(SELECT COUNT(*) FROM shuttle sh WHERE sh.trip_uuid = t.trip_uuid AND sh.deleted_at IS NULL) AS student_count,
+			t.created_at, t.created_by
+		FROM trips t
+		LEFT JOIN routes r ON r.route_uuid = t.route_uuid
+		LEFT JOIN vehicles v ON v.vehicle_uuid = t.vehicle_uuid
+		LEFT JOIN driver_details d ON d.user_uuid = t.driver_uuid
+		WHERE t.trip_date = $3 AND ($1 = '' OR t.school_uuid::text = $1) AND ($2 = '' OR t.driver_uuid::text = $2)
+		ORDER BY t.departs_at
+	`
+
+	if err := repository.db.Select(&trips, query, schoolUUID, driverUUID, tripDate); err != nil {
+		return nil, err
+	}
+
+	return trips, nil
+}
@@ -1,7 +1,6 @@
 package repositories
 
 import (
-	"database/sql"
 	"fmt"
 	"shuttle/models/entity"
 
@@ -11,11 +10,16 @@ import (
 
 type UserRepositoryInterface interface {
 	// Might need to move this to a different repository
-	FetchAllDriversForPermittedSchool(offset int, limit int, sortField string, sortDirection string, userUUID string) ([]entity.User, entity.School, entity.Vehicle, error)
+	FetchAllDriversForPermittedSchool(offset int, limit int, sortField string, sortDirection string, schoolUUID string) ([]entity.User, entity.School, entity.Vehicle, error)
+	CountDriversForPermittedSchool(schoolUUID string) (int, error)
+	CheckVehicleInSchool(vehicleUUID string, schoolUUID string) (bool, error)
+	CheckVehicleAssignedToOther(vehicleUUID string, driverUUID string) (bool, error)
 	FetchPermittedSchoolAccess(userUUID string) (string, error)
 	
 	BeginTransaction() (*sqlx.Tx, error)
 	FetchSpecificUser(userUUID string) (entity.User, error)
+	FetchUserByEmail(email string) (entity.User, error)
+	FetchSchoolParentByPhone(schoolUUID, phone, countryCode string) (entity.User, error)
 	CheckEmailExist(uuid string, email string) (bool, error)
 	CheckUsernameExist(uuid string, username string) (bool, error)
 	CountSuperAdmin() (int, error)
@@ -39,17 +43,19 @@ type UserRepositoryInterface interface {
 	SaveSuperAdminDetails(tx *sqlx.Tx, details entity.SuperAdminDetails, userUUID uuid.UUID, params interface{}) error
 	SaveSchoolAdminDetails(tx *sqlx.Tx, details entity.SchoolAdminDetails, userUUID uuid.UUID, params interface{}) error
 	SaveParentDetails(tx *sqlx.Tx, details entity.ParentDetails, userUUID uuid.UUID, params interface{}) error
-	SaveDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, params interface{}) error
+	SaveDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, params interface{}, user_name string) error
+	UpdateDriverUUIDInVehicles(tx *sqlx.Tx, userUUID uuid.UUID, vehicleUUID uuid.UUID, user_name string) error
 
 	UpdateUser(tx *sqlx.Tx, user entity.User, userUUID string) error
 	UpdateSuperAdminDetails(tx *sqlx.Tx, details entity.SuperAdminDetails, userUUID string) error
 	UpdateSchoolAdminDetails(tx *sqlx.Tx, details entity.SchoolAdminDetails, userUUID string) error
 	UpdateParentDetails(tx *sqlx.Tx, details entity.ParentDetails, userUUID string) error
-	UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID) error
+	UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, user_name string) error
 
 	DeleteSuperAdmin(tx *sqlx.Tx, userUUID uuid.UUID, user_name string) error
 	DeleteSchoolAdmin(tx *sqlx.Tx, userUUID uuid.UUID, user_name string) error
 	DeleteDriver(tx *sqlx.Tx, userUUID uuid.UUID, user_name string) error
+	DeleteParent(tx *sqlx.Tx, userUUID uuid.UUID, user_name string) error
 }
 
 type userRepository struct {
@@ -62,7 +68,7 @@ func NewUserRepository(DB *sqlx.DB) UserRepositoryInterface {
 	}
 }
 
-func (r *userRepository) FetchAllDriversForPermittedSchool(offset int, limit int, sortField string, sortDirection string, userUUID string) ([]entity.User, entity.School, entity.Vehicle, error) {
+func (r *userRepository) FetchAllDriversForPermittedSchool(offset int, limit int, sortField string, sortDirection string, schoolUUID string) ([]entity.User, entity.School, entity.Vehicle, error) {
     var users []entity.User
     var user entity.User
     var details entity.DriverDetails
@@ -78,12 +84,12 @@ func (r *userRepository) FetchAllDriversForPermittedSchool(offset int, limit int
         LEFT JOIN driver_details d ON u.user_uuid = d.user_uuid
         LEFT JOIN schools s ON d.school_uuid = s.school_uuid
         LEFT JOIN vehicles v ON d.vehicle_uuid = v.vehicle_uuid
-        WHERE u.user_role = 'driver' AND u.deleted_at IS NULL AND d.school_uuid = (SELECT school_uuid FROM school_admin_details WHERE user_uuid = $1)
+        WHERE u.user_role = 'driver' AND u.deleted_at IS NULL AND d.school_uuid = $1
         ORDER BY %s %s
         LIMIT $2 OFFSET $3
     `, sortField, sortDirection)
 
-    rows, err := r.DB.Queryx(query, userUUID, limit, offset)
+    rows, err := r.DB.Queryx(query, schoolUUID, limit, offset)
     if err != nil {
         return nil, entity.School{}, entity.Vehicle{}, err
     }
@@ -108,6 +114,53 @@ func (r *userRepository) FetchAllDriversForPermittedSchool(offset int, limit int
     return users, school, vehicle, nil
 }
 
+func (r *userRepository) CountDriversForPermittedSchool(schoolUUID string) (int, error) {
+	query := `
+		SELECT COUNT(u.user_id)
+		FROM users u
+		JOIN driver_details d ON u.user_uuid = d.user_uuid
+		WHERE u.user_role = 'driver' AND u.deleted_at IS NULL AND d.school_uuid = $1
+	`
+	var total int
+	err := r.DB.Get(&total, query, schoolUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r *userRepository) CheckVehicleInSchool(vehicleUUID string, schoolUUID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM vehicles WHERE vehicle_uuid = $1 AND school_uuid = $2 AND deleted_at IS NULL
+		)
+	`
+	var exists bool
+	err := r.DB.Get(&exists, query, vehicleUUID, schoolUUID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// driverUUID is empty for a driver that is being created
+func (r *userRepository) CheckVehicleAssignedToOther(vehicleUUID string, driverUUID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM vehicle_assignments WHERE vehicle_uuid = $1 AND driver_uuid::text <> $2 AND assigned_to IS NULL
+		)
+	`
+	var exists bool
+	err := r.DB.Get(&exists, query, vehicleUUID, driverUUID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
 func (r *userRepository) FetchPermittedSchoolAccess(userUUID string) (string, error) {
     query := `SELECT school_uuid FROM school_admin_details WHERE user_uuid = $1`
     var schoolUUID string
@@ -140,6 +193,42 @@ func (r *userRepository) FetchSpecificUser(userUUID string) (entity.User, error)
 	return user, nil
 }
 
+func (r *userRepository) FetchUserByEmail(email string) (entity.User, error) {
+	var user entity.User
+	query := `SELECT * FROM users WHERE LOWER(user_email) = LOWER($1) AND deleted_at IS NULL`
+	if err := r.DB.Get(&user, query, email); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Only parents already guardian of a student of the school are matched. Matches on digits only,
+// with a local leading 0 replaced by the country code, so "+62 812-...", "62812..." and "0812..." are
+// the same number.
+func (r *userRepository) FetchSchoolParentByPhone(schoolUUID, phone, countryCode string) (entity.User, error) {
+	var user entity.User
+	query := `
+		SELECT u.*
+		FROM users u
+		JOIN parent_details pd ON pd.user_uuid = u.user_uuid
+		WHERE regexp_replace(regexp_replace(regexp_replace(pd.user_phone, '[^0-9]', '', 'g'), '^00', ''), '^0', $2) = $1
+			AND u.user_role = 'parent' AND u.deleted_at IS NULL
+			AND EXISTS (
+				SELECT 1
+				FROM student_guardians sg
+				JOIN students s ON s.student_uuid = sg.student_uuid
+				WHERE sg.guardian_uuid = u.user_uuid AND s.school_uuid = $3 AND s.deleted_at IS NULL
+			)
+		ORDER BY u.user_id ASC
+		LIMIT 1`
+	if err := r.DB.Get(&user, query, phone, countryCode, schoolUUID); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
 func (r *userRepository) CheckEmailExist(uuid string, email string) (bool, error) {
 	var count int
 	query := `SELECT COUNT(user_id) FROM users WHERE user_email = $1 AND deleted_at IS NULL`
@@ -158,9 +247,10 @@ func (r *userRepository) CheckEmailExist(uuid string, email string) (bool, error
 	return count > 0, nil
 }
 
+// Deleted users are counted too, the unique constraint on the username still holds their rows
 func (r *userRepository) CheckUsernameExist(uuid string, username string) (bool, error) {
 	var count int
-	query := `SELECT COUNT(user_id) FROM users WHERE user_username = $1 AND deleted_at IS NULL`
+	query := `SELECT COUNT(user_id) FROM users WHERE user_username = $1`
 	
 	if uuid != "" {
 		query += ` AND user_uuid != $2`
@@ -558,11 +648,11 @@ func (r *userRepository) FetchDriverDetails(userUUID uuid.UUID) (entity.DriverDe
 
 func (r *userRepository) SaveUser(tx *sqlx.Tx, userEntity entity.User) (uuid.UUID, error) {
 	query := `
-		INSERT INTO users (user_id, user_uuid, user_username, user_email, user_password, user_role, user_role_code, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		INSERT INTO users (user_id, user_uuid, user_username, user_email, user_password, user_role, user_role_code, created_by, created_with_student)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 		RETURNING user_uuid`
 	var userUUID uuid.UUID
-	err := tx.QueryRow(query, userEntity.ID, userEntity.UUID, userEntity.Username, userEntity.Email, userEntity.Password, userEntity.Role, userEntity.RoleCode, userEntity.CreatedBy).Scan(&userUUID)
+	err := tx.QueryRow(query, userEntity.ID, userEntity.UUID, userEntity.Username, userEntity.Email, userEntity.Password, userEntity.Role, userEntity.RoleCode, userEntity.CreatedBy, userEntity.CreatedWithStudent).Scan(&userUUID)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -607,7 +697,7 @@ func (r *userRepository) SaveParentDetails(tx *sqlx.Tx, details entity.ParentDet
 	return err
 }
 
-func (r *userRepository) SaveDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, params interface{}) error {
+func (r *userRepository) SaveDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, params interface{}, user_name string) error {
 	details.UserUUID = userUUID
 
 	if details.SchoolUUID == nil || *details.SchoolUUID == uuid.Nil {
@@ -629,26 +719,19 @@ func (r *userRepository) SaveDriverDetails(tx *sqlx.Tx, details entity.DriverDet
 	}
 
 	if details.VehicleUUID != nil {
-		return r.UpdateDriverUUIDInVehicles(tx, userUUID, *details.VehicleUUID)
+		return r.UpdateDriverUUIDInVehicles(tx, userUUID, *details.VehicleUUID, user_name)
 	}
 	return nil
 }
 
-func (r *userRepository) UpdateDriverUUIDInVehicles(tx *sqlx.Tx, userUUID uuid.UUID, vehicleUUID uuid.UUID) error {
-	var userUUIDParam interface{}
-    if userUUID == uuid.Nil {
-        userUUIDParam = nil
-    } else {
-        userUUIDParam = userUUID
-    }
+// A nil userUUID frees the vehicle. Goes through the assignment history so vehicles.driver_uuid
+// and driver_details.vehicle_uuid stay in step with it.
+func (r *userRepository) UpdateDriverUUIDInVehicles(tx *sqlx.Tx, userUUID uuid.UUID, vehicleUUID uuid.UUID, user_name string) error {
+	if userUUID == uuid.Nil {
+		return releaseVehicle(tx, vehicleUUID, user_name)
+	}
 
-    query := `
-        UPDATE vehicles
-        SET driver_uuid = $1
-        WHERE vehicle_uuid = $2
-		`
-    _, err := tx.Exec(query, userUUIDParam, vehicleUUID)
-    return err
+	return assignVehicle(tx, userUUID, vehicleUUID, user_name)
 }
 
 func (r *userRepository) UpdateUser(tx *sqlx.Tx, user entity.User, userUUID string) error {
@@ -726,7 +809,7 @@ func (r *userRepository) UpdateParentDetails(tx *sqlx.Tx, details entity.ParentD
 	return nil
 }
 
-func (r *userRepository) UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID) error {
+func (r *userRepository) UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverDetails, userUUID uuid.UUID, user_name string) error {
 	details.UserUUID = userUUID
 
     if details.SchoolUUID == nil || *details.SchoolUUID == uuid.Nil {
@@ -736,20 +819,13 @@ func (r *userRepository) UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverD
         details.VehicleUUID = nil
     }
 
-    var currentVehicleUUID *uuid.UUID
-    if details.VehicleUUID == nil {
-        err := tx.Get(&currentVehicleUUID, `SELECT vehicle_uuid FROM driver_details WHERE user_uuid = $1`, userUUID)
-        if err != nil && err != sql.ErrNoRows {
-            return err
-        }
-    }
-		
+	// vehicle_uuid is left to the assignment history below
 	query := `
         UPDATE driver_details
-        SET school_uuid = $1, vehicle_uuid = $2, user_first_name = $3, user_last_name = $4,
-		user_gender = $5, user_phone = $6, user_address = $7, user_license_number = $8
-		WHERE user_uuid = $9`
-	res, err := tx.Exec(query, details.SchoolUUID, details.VehicleUUID, details.FirstName, details.LastName, details.Gender, details.Phone, details.Address, details.LicenseNumber, details.UserUUID)
+        SET school_uuid = $1, user_first_name = $2, user_last_name = $3,
+		user_gender = $4, user_phone = $5, user_address = $6, user_license_number = $7
+		WHERE user_uuid = $8`
+	res, err := tx.Exec(query, details.SchoolUUID, details.FirstName, details.LastName, details.Gender, details.Phone, details.Address, details.LicenseNumber, details.UserUUID)
 	if err != nil {
 		return err
 	}
@@ -764,14 +840,10 @@ func (r *userRepository) UpdateDriverDetails(tx *sqlx.Tx, details entity.DriverD
 	}
 	
 	if details.VehicleUUID != nil {
-        return r.UpdateDriverUUIDInVehicles(tx, userUUID, *details.VehicleUUID)
+        return r.UpdateDriverUUIDInVehicles(tx, userUUID, *details.VehicleUUID, user_name)
     }
 
-    if currentVehicleUUID != nil {
-        return r.UpdateDriverUUIDInVehicles(tx, uuid.Nil, *currentVehicleUUID)
-    }
-	
-	return nil
+	return releaseDriver(tx, userUUID, user_name)
 }
 
 
@@ -830,5 +902,15 @@ func (r *userRepository) DeleteDriver(tx *sqlx.Tx, userUUID uuid.UUID, user_name
 		return fmt.Errorf("user not found")
 	}
 
+	// A deactivated driver no longer holds their vehicle
+	return releaseDriver(tx, userUUID, user_name)
+}
+
+func (r *userRepository) DeleteParent(tx *sqlx.Tx, userUUID uuid.UUID, user_name string) error {
+	query := `UPDATE users SET deleted_at = NOW(), deleted_by = $1 WHERE user_uuid = $2 AND deleted_at IS NULL`
+	if _, err := tx.Exec(query, user_name, userUUID); err != nil {
+		return err
+	}
+
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"shuttle/models/entity"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const vehicleAssignmentColumns = `
+	a.assignment_id, a.assignment_uuid, a.driver_uuid, a.vehicle_uuid, a.assigned_from, a.assigned_to, a.assigned_by, a.unassigned_by,
+	d.user_first_name, d.user_last_name, v.vehicle_name, v.vehicle_number
+`
+
+type VehicleAssignmentRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	FetchDriverSchool(driverUUID string) (sql.NullString, error)
+	FetchVehicleSchool(vehicleUUID string) (sql.NullString, error)
+	FetchActiveAssignmentByVehicle(vehicleUUID string) (entity.VehicleAssignment, error)
+
+	FetchAssignmentsByVehicle(vehicleUUID string) ([]entity.VehicleAssignment, error)
+	FetchAssignmentsByDriver(driverUUID string) ([]entity.VehicleAssignment, error)
+	FetchAssignmentsDuring(vehicleUUID string, from, to time.Time) ([]entity.VehicleAssignment, error)
+
+	AssignVehicle(tx *sqlx.Tx, driverUUID, vehicleUUID uuid.UUID, username string) error
+	ReleaseVehicle(tx *sqlx.Tx, vehicleUUID uuid.UUID, username string) error
+}
+
+type VehicleAssignmentRepository struct {
+	db *sqlx.DB
+}
+
+func NewVehicleAssignmentRepository(db *sqlx.DB) VehicleAssignmentRepositoryInterface {
+	return &VehicleAssignmentRepository{
+		db: db,
+	}
+}
+
+func (repository *VehicleAssignmentRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+// Returns sql.ErrNoRows when the user is not an active driver
+func (repository *VehicleAssignmentRepository) FetchDriverSchool(driverUUID string) (sql.NullString, error) {
+	var schoolUUID sql.NullString
+
+	query := `
+		SELECT d.school_uuid
+		FROM users u
+		JOIN driver_details d ON u.user_uuid = d.user_uuid
+		WHERE u.user_uuid = $1 AND u.user_role = 'driver' AND u.deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&schoolUUID, query, driverUUID); err != nil {
+		return sql.NullString{}, err
+	}
+
+	return schoolUUID, nil
+}
+
+// Returns sql.ErrNoRows when the vehicle does not exist
+func (repository *VehicleAssignmentRepository) FetchVehicleSchool(vehicleUUID string) (sql.NullString, error) {
+	var schoolUUID sql.NullString
+
+	query := `SELECT school_uuid FROM vehicles WHERE vehicle_uuid = $1 AND deleted_at IS NULL`
+
+	if err := repository.db.Get(&schoolUUID, query, vehicleUUID); err != nil {
+		return sql.NullString{}, err
+	}
+
+	return schoolUUID, nil
+}
+
+func (repository *VehicleAssignmentRepository) FetchActiveAssignmentByVehicle(vehicleUUID string) (entity.VehicleAssignment, error) {
+	var assignment entity.VehicleAssignment
+
+	query := `
+		SELECT ` + vehicleAssignmentColumns + `
+		FROM vehicle_assignments a
+		JOIN vehicles v ON v.vehicle_uuid = a.vehicle_uuid
+		LEFT JOIN driver_details d ON d.user_uuid = a.driver_uuid
+		WHERE a.vehicle_uuid = $1 AND a.assigned_to IS NULL
+	`
+
+	if err := repository.db.Get(&assignment, query, vehicleUUID); err != nil {
+		return entity.VehicleAssignment{}, err
+	}
+
+	return assignment, nil
+}
+
+func (repository *VehicleAssignmentRepository) FetchAssignmentsByVehicle(vehicleUUID string) ([]entity.VehicleAssignment, error) {
+	assignments := []entity.VehicleAssignment{}
+
+	query := `
+		SELECT ` + vehicleAssignmentColumns + `
+		FROM vehicle_assignments a
+		JOIN vehicles v ON v.vehicle_uuid = a.vehicle_uuid
+		LEFT JOIN driver_details d ON d.user_uuid = a.driver_uuid
+		WHERE a.vehicle_uuid = $1
+		ORDER BY a.assigned_from DESC
+	`
+
+	if err := repository.db.Select(&assignments, query, vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (repository *VehicleAssignmentRepository) FetchAssignmentsByDriver(driverUUID string) ([]entity.VehicleAssignment, error) {
+	assignments := []entity.VehicleAssignment{}
+
+	query := `
+		SELECT ` + vehicleAssignmentColumns + `
+		FROM vehicle_assignments a
+		JOIN vehicles v ON v.vehicle_uuid = a.vehicle_uuid
+		LEFT JOIN driver_details d ON d.user_uuid = a.driver_uuid
+		WHERE a.driver_uuid = $1
+		ORDER BY a.assigned_from DESC
+	`
+
+	if err := repository.db.Select(&assignments, query, driverUUID); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// Assignments of the vehicle that overlap [from, to)
+func (repository *VehicleAssignmentRepository) FetchAssignmentsDuring(vehicleUUID string, from, to time.Time) ([]entity.VehicleAssignment, error) {
+	assignments := []entity.VehicleAssignment{}
+
+	query := `
+		SELECT ` + vehicleAssignmentColumns + `
+		FROM vehicle_assignments a
+		JOIN vehicles v ON v.vehicle_uuid = a.vehicle_uuid
+		LEFT JOIN driver_details d ON d.user_uuid = a.driver_uuid
+		WHERE a.vehicle_uuid = $1 AND a.assigned_from < $3 AND (a.assigned_to IS NULL OR a.assigned_to > $2)
+		ORDER BY a.assigned_from
+	`
+
+	if err := repository.db.Select(&assignments, query, vehicleUUID, from, to); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (repository *VehicleAssignmentRepository) AssignVehicle(tx *sqlx.Tx, driverUUID, vehicleUUID uuid.UUID, username string) error {
+	return assignVehicle(tx, driverUUID, vehicleUUID, username)
+}
+
+func (repository *VehicleAssignmentRepository) ReleaseVehicle(tx *sqlx.Tx, vehicleUUID uuid.UUID, username string) error {
+	return releaseVehicle(tx, vehicleUUID, username)
+}
+
+// Every change to a driver-vehicle link goes through assignVehicle, releaseDriver or releaseVehicle
+// so the history and the two link columns never drift apart.
+// assignVehicle ends whatever the driver and the vehicle had before, callers check for conflicts first.
+func assignVehicle(tx *sqlx.Tx, driverUUID, vehicleUUID uuid.UUID, username string) error {
+	var active bool
+	query := `SELECT EXISTS(SELECT 1 FROM vehicle_assignments WHERE driver_uuid = $1 AND vehicle_uuid = $2 AND assigned_to IS NULL)`
+	if err := tx.Get(&active, query, driverUUID, vehicleUUID); err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+
+	if err := releaseDriver(tx, driverUUID, username); err != nil {
+		return err
+	}
+	if err := releaseVehicle(tx, vehicleUUID, username); err != nil {
+		return err
+	}
+
+	assignmentUUID := uuid.New()
+	query = `
+		INSERT INTO vehicle_assignments (assignment_id, assignment_uuid, driver_uuid, vehicle_uuid, assigned_from, assigned_by)
+		VALUES ($1, $2, $3, $4, NOW(), $5)
+	`
+	if _, err := tx.Exec(query, time.Now().UnixMilli()*1e6+int64(assignmentUUID.ID()%1e6), assignmentUUID, driverUUID, vehicleUUID, toNullableString(username)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE vehicles SET driver_uuid = $1 WHERE vehicle_uuid = $2`, driverUUID, vehicleUUID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE driver_details SET vehicle_uuid = $1 WHERE user_uuid = $2`, vehicleUUID, driverUUID); err != nil {
+		return err
+	}
+
+	return saveAssignmentEvent(tx, entity.EventDriverAssigned, assignmentUUID, driverUUID, vehicleUUID)
+}
+
+func releaseDriver(tx *sqlx.Tx, driverUUID uuid.UUID, username string) error {
+	query := `UPDATE vehicle_assignments SET assigned_to = NOW(), unassigned_by = $2 WHERE driver_uuid = $1 AND assigned_to IS NULL`
+	if err := closeAssignments(tx, query, driverUUID, toNullableString(username)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE vehicles SET driver_uuid = NULL WHERE driver_uuid = $1`, driverUUID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`UPDATE driver_details SET vehicle_uuid = NULL WHERE user_uuid = $1`, driverUUID)
+	return err
+}
+
+func releaseVehicle(tx *sqlx.Tx, vehicleUUID uuid.UUID, username string) error {
+	query := `UPDATE vehicle_assignments SET assigned_to = NOW(), unassigned_by = $2 WHERE vehicle_uuid = $1 AND assigned_to IS NULL`
+	if err := closeAssignments(tx, query, vehicleUUID, toNullableString(username)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE driver_details SET vehicle_uuid = NULL WHERE vehicle_uuid = $1`, vehicleUUID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`UPDATE vehicles SET driver_uuid = NULL WHERE vehicle_uuid = $1`, vehicleUUID)
+	return err
+}
+
+// Runs the update that ends open assignments and writes a driver.unassigned event for each of them
+func closeAssignments(tx *sqlx.Tx, query string, args ...interface{}) error {
+	var closed []struct {
+		UUID        uuid.UUID `db:"assignment_uuid"`
+		DriverUUID  uuid.UUID `db:"driver_uuid"`
+		VehicleUUID uuid.UUID `db:"vehicle_uuid"`
+	}
+	if err := tx.Select(&closed, query+` RETURNING assignment_uuid, driver_uuid, vehicle_uuid`, args...); err != nil {
+		return err
+	}
+
+	for _, assignment := range closed {
+		if err := saveAssignmentEvent(tx, entity.EventDriverUnassigned, assignment.UUID, assignment.DriverUUID, assignment.VehicleUUID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Assignment events belong to the school of the vehicle, an assignment changes state once each way
+// so its uuid is enough to dedup
+func saveAssignmentEvent(tx *sqlx.Tx, eventType string, assignmentUUID, driverUUID, vehicleUUID uuid.UUID) error {
+	var schoolUUID uuid.NullUUID
+	if err := tx.Get(&schoolUUID, `SELECT school_uuid FROM vehicles WHERE vehicle_uuid = $1`, vehicleUUID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	event := entity.DomainEvent{
+		Type:          eventType,
+		AggregateType: "vehicle_assignment",
+		AggregateUUID: assignmentUUID,
+		SchoolUUID:    schoolUUID,
+		DedupKey:      eventType + ":" + assignmentUUID.String(),
+	}
+	payload := map[string]interface{}{
+		"assignment_uuid": assignmentUUID,
+		"driver_uuid":     driverUUID,
+		"vehicle_uuid":    vehicleUUID,
+	}
+
+	return saveDomainEvent(tx, event, payload)
+}
+
+func toNullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
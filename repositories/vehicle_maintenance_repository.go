@@ -0,0 +1,266 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// A vehicle has lapsed when, for one of the mandatory document types, documents are on file but
+// none of them is still valid. Vehicles without any document of a type predate document tracking.
+const lapsedVehicleCondition = `
+	EXISTS (
+		SELECT 1 FROM vehicle_documents doc
+		WHERE doc.vehicle_uuid = v.vehicle_uuid AND doc.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM vehicle_documents valid
+				WHERE valid.vehicle_uuid = doc.vehicle_uuid AND valid.document_type = doc.document_type
+					AND valid.deleted_at IS NULL AND valid.expires_on >= CURRENT_DATE
+			)
+	)
+`
+
+type VehicleMaintenanceRepositoryInterface interface {
+	CheckVehicleExists(vehicleUUID string) (bool, error)
+
+	FetchMaintenances(vehicleUUID string) ([]entity.VehicleMaintenance, error)
+	FetchSpecMaintenance(vehicleUUID, maintenanceUUID string) (entity.VehicleMaintenance, error)
+	SaveMaintenance(maintenance entity.VehicleMaintenance) error
+	UpdateMaintenance(maintenance entity.VehicleMaintenance) error
+	DeleteMaintenance(vehicleUUID, maintenanceUUID, username string) error
+
+	FetchVehicleDocuments(vehicleUUID string) ([]entity.VehicleDocument, error)
+	FetchSpecVehicleDocument(vehicleUUID, documentUUID string) (entity.VehicleDocument, error)
+	SaveVehicleDocument(document entity.VehicleDocument) error
+	UpdateVehicleDocument(document entity.VehicleDocument) error
+	DeleteVehicleDocument(vehicleUUID, documentUUID, username string) error
+
+	SyncLapsedVehicles(vehicleUUID string) (int64, int64, error)
+	IsDriverVehicleOutOfService(driverUUID string) (bool, error)
+}
+
+type VehicleMaintenanceRepository struct {
+	db *sqlx.DB
+}
+
+func NewVehicleMaintenanceRepository(db *sqlx.DB) VehicleMaintenanceRepositoryInterface {
+	return &VehicleMaintenanceRepository{
+		db: db,
+	}
+}
+
+func (repository *VehicleMaintenanceRepository) CheckVehicleExists(vehicleUUID string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM vehicles WHERE vehicle_uuid = $1 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, vehicleUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *VehicleMaintenanceRepository) FetchMaintenances(vehicleUUID string) ([]entity.VehicleMaintenance, error) {
+	maintenances := []entity.VehicleMaintenance{}
+
+	query := `
+		SELECT maintenance_id, maintenance_uuid, vehicle_uuid, service_date, odometer_km, cost, notes,
+			created_at, created_by, updated_at, updated_by
+		FROM vehicle_maintenances
+		WHERE vehicle_uuid = $1 AND deleted_at IS NULL
+		ORDER BY service_date DESC, maintenance_id DESC
+	`
+
+	if err := repository.db.Select(&maintenances, query, vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	return maintenances, nil
+}
+
+func (repository *VehicleMaintenanceRepository) FetchSpecMaintenance(vehicleUUID, maintenanceUUID string) (entity.VehicleMaintenance, error) {
+	var maintenance entity.VehicleMaintenance
+
+	query := `
+		SELECT maintenance_id, maintenance_uuid, vehicle_uuid, service_date, odometer_km, cost, notes,
+			created_at, created_by, updated_at, updated_by
+		FROM vehicle_maintenances
+		WHERE vehicle_uuid = $1 AND maintenance_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&maintenance, query, vehicleUUID, maintenanceUUID); err != nil {
+		return entity.VehicleMaintenance{}, err
+	}
+
+	return maintenance, nil
+}
+
+func (repository *VehicleMaintenanceRepository) SaveMaintenance(maintenance entity.VehicleMaintenance) error {
+	query := `
+		INSERT INTO vehicle_maintenances (maintenance_id, maintenance_uuid, vehicle_uuid, service_date, odometer_km, cost, notes, created_by)
+		VALUES (:maintenance_id, :maintenance_uuid, :vehicle_uuid, :service_date, :odometer_km, :cost, :notes, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, maintenance)
+	return err
+}
+
+func (repository *VehicleMaintenanceRepository) UpdateMaintenance(maintenance entity.VehicleMaintenance) error {
+	query := `
+		UPDATE vehicle_maintenances
+		SET service_date = :service_date, odometer_km = :odometer_km, cost = :cost, notes = :notes,
+			updated_at = NOW(), updated_by = :updated_by
+		WHERE maintenance_uuid = :maintenance_uuid AND vehicle_uuid = :vehicle_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, maintenance)
+	return err
+}
+
+func (repository *VehicleMaintenanceRepository) DeleteMaintenance(vehicleUUID, maintenanceUUID, username string) error {
+	query := `
+		UPDATE vehicle_maintenances
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE vehicle_uuid = $1 AND maintenance_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, vehicleUUID, maintenanceUUID, username)
+	return err
+}
+
+func (repository *VehicleMaintenanceRepository) FetchVehicleDocuments(vehicleUUID string) ([]entity.VehicleDocument, error) {
+	documents := []entity.VehicleDocument{}
+
+	query := `
+		SELECT document_id, document_uuid, vehicle_uuid, document_type, document_number, issued_on, expires_on, document_file,
+			created_at, created_by, updated_at, updated_by
+		FROM vehicle_documents
+		WHERE vehicle_uuid = $1 AND deleted_at IS NULL
+		ORDER BY document_type, expires_on DESC
+	`
+
+	if err := repository.db.Select(&documents, query, vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (repository *VehicleMaintenanceRepository) FetchSpecVehicleDocument(vehicleUUID, documentUUID string) (entity.VehicleDocument, error) {
+	var document entity.VehicleDocument
+
+	query := `
+		SELECT document_id, document_uuid, vehicle_uuid, document_type, document_number, issued_on, expires_on, document_file,
+			created_at, created_by, updated_at, updated_by
+		FROM vehicle_documents
+		WHERE vehicle_uuid = $1 AND document_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&document, query, vehicleUUID, documentUUID); err != nil {
+		return entity.VehicleDocument{}, err
+	}
+
+	return document, nil
+}
+
+func (repository *VehicleMaintenanceRepository) SaveVehicleDocument(document entity.VehicleDocument) error {
+	query := `
+		INSERT INTO vehicle_documents (document_id, document_uuid, vehicle_uuid, document_type, document_number, issued_on, expires_on, document_file, created_by)
+		VALUES (:document_id, :document_uuid, :vehicle_uuid, :document_type, :document_number, :issued_on, :expires_on, :document_file, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, document)
+	return err
+}
+
+func (repository *VehicleMaintenanceRepository) UpdateVehicleDocument(document entity.VehicleDocument) error {
+	query := `
+		UPDATE vehicle_documents
+		SET document_type = :document_type, document_number = :document_number, issued_on = :issued_on, expires_on = :expires_on,
+			document_file = :document_file, updated_at = NOW(), updated_by = :updated_by
+		WHERE document_uuid = :document_uuid AND vehicle_uuid = :vehicle_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, document)
+	return err
+}
+
+func (repository *VehicleMaintenanceRepository) DeleteVehicleDocument(vehicleUUID, documentUUID, username string) error {
+	query := `
+		UPDATE vehicle_documents
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE vehicle_uuid = $1 AND document_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, vehicleUUID, documentUUID, username)
+	return err
+}
+
+// Takes lapsed vehicles out of service and gives vehicles whose documents were renewed their old status back.
+// An empty vehicleUUID checks every vehicle. Returns how many vehicles went out of and back into service.
+func (repository *VehicleMaintenanceRepository) SyncLapsedVehicles(vehicleUUID string) (int64, int64, error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE vehicles v
+		SET vehicle_status_before_lapse = COALESCE(v.vehicle_status_before_lapse, v.vehicle_status, ''),
+			vehicle_status = '` + entity.VehicleOutOfService + `', updated_at = NOW(), updated_by = 'system'
+		WHERE v.deleted_at IS NULL AND ($1 = '' OR v.vehicle_uuid::text = $1)
+			AND v.vehicle_status IS DISTINCT FROM '` + entity.VehicleOutOfService + `'
+			AND ` + lapsedVehicleCondition
+
+	res, err := tx.Exec(query, vehicleUUID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lapsed, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query = `
+		UPDATE vehicles v
+		SET vehicle_status = NULLIF(v.vehicle_status_before_lapse, ''), vehicle_status_before_lapse = NULL,
+			updated_at = NOW(), updated_by = 'system'
+		WHERE v.deleted_at IS NULL AND ($1 = '' OR v.vehicle_uuid::text = $1)
+			AND v.vehicle_status_before_lapse IS NOT NULL
+			AND NOT ` + lapsedVehicleCondition
+
+	res, err = tx.Exec(query, vehicleUUID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	restored, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lapsed, restored, tx.Commit()
+}
+
+// Out of service vehicles, whether set by hand or by a lapsed document, and vehicles in maintenance cannot be used for trips
+func (repository *VehicleMaintenanceRepository) IsDriverVehicleOutOfService(driverUUID string) (bool, error) {
+	var outOfService bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM driver_details d
+			JOIN vehicles v ON v.vehicle_uuid = d.vehicle_uuid
+			WHERE d.user_uuid = $1 AND v.deleted_at IS NULL AND v.vehicle_status IN ($2, 'maintenance')
+		)
+	`
+
+	if err := repository.db.Get(&outOfService, query, driverUUID, entity.VehicleOutOfService); err != nil {
+		return false, err
+	}
+
+	return outOfService, nil
+}
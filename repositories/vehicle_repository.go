@@ -18,8 +18,31 @@ type VehicleRepositoryInterface interface {
 	SaveVehicle(vehicle entity.Vehicle) error
 	UpdateVehicle(vehicle entity.Vehicle) error
 	DeleteVehicle(vehicle entity.Vehicle) error
+
+	CheckSchoolExists(schoolUUID string) (bool, error)
+	CheckRouteInSchool(routeUUID, schoolUUID string) (bool, error)
+	CountSchoolVehicles(schoolUUID string) (int, error)
+	FetchSchoolVehicles(schoolUUID string, offset, limit int, sortField, sortDirection string) ([]entity.SchoolVehicle, error)
+	FetchSpecSchoolVehicle(schoolUUID, vehicleUUID string) (entity.SchoolVehicle, error)
+	UpdateSchoolVehicle(vehicle entity.SchoolVehicle) error
 }
 
+const schoolVehicleQuery = `
+	SELECT
+		v.vehicle_uuid, v.school_uuid, v.vehicle_name, v.vehicle_number, v.vehicle_type, v.vehicle_color,
+		v.vehicle_seats, v.vehicle_status, v.vehicle_status_before_lapse, v.created_at, v.updated_at, v.updated_by,
+		CASE WHEN u.deleted_at IS NULL THEN v.driver_uuid END AS driver_uuid,
+		CASE WHEN u.deleted_at IS NULL THEN d.user_first_name END AS driver_first_name,
+		CASE WHEN u.deleted_at IS NULL THEN d.user_last_name END AS driver_last_name,
+		CASE WHEN r.deleted_at IS NULL THEN v.route_uuid END AS route_uuid,
+		CASE WHEN r.deleted_at IS NULL THEN r.route_name END AS route_name
+	FROM vehicles v
+	LEFT JOIN driver_details d ON v.driver_uuid = d.user_uuid
+	LEFT JOIN users u ON d.user_uuid = u.user_uuid
+	LEFT JOIN routes r ON v.route_uuid = r.route_uuid
+	WHERE v.deleted_at IS NULL AND v.school_uuid = $1
+`
+
 type VehicleRepository struct {
 	db *sqlx.DB
 }
@@ -225,7 +248,20 @@ func (repository *VehicleRepository) SaveVehicle(vehicle entity.Vehicle) error {
 	return nil
 }
 
+// Moving a vehicle to another school takes it off its driver and route, both belong to the old school
 func (repository *VehicleRepository) UpdateVehicle(vehicle entity.Vehicle) error {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var transferred bool
+	err = tx.Get(&transferred, `SELECT school_uuid IS DISTINCT FROM $2 FROM vehicles WHERE vehicle_uuid = $1`, vehicle.UUID, vehicle.SchoolUUID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE vehicles
 		SET school_uuid = :school_uuid, vehicle_name = :vehicle_name, vehicle_number = :vehicle_number, vehicle_type = :vehicle_type, vehicle_color = :vehicle_color,
@@ -233,25 +269,138 @@ func (repository *VehicleRepository) UpdateVehicle(vehicle entity.Vehicle) error
 		WHERE vehicle_uuid = :vehicle_uuid
 	`
 
-	_, err := repository.db.NamedExec(query, vehicle)
+	_, err = tx.NamedExec(query, vehicle)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if transferred {
+		if err := releaseVehicle(tx, vehicle.UUID, vehicle.UpdatedBy.String); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE vehicles SET route_uuid = NULL WHERE vehicle_uuid = $1`, vehicle.UUID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
+// A deleted vehicle no longer has a driver, the assignment is closed with it
 func (repository *VehicleRepository) DeleteVehicle(vehicle entity.Vehicle) error {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE vehicles
 		SET deleted_at = :deleted_at, deleted_by = :deleted_by
 		WHERE vehicle_uuid = :vehicle_uuid
 	`
 
-	_, err := repository.db.NamedExec(query, vehicle)
+	_, err = tx.NamedExec(query, vehicle)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
\ No newline at end of file
+	if err := releaseVehicle(tx, vehicle.UUID, vehicle.DeletedBy.String); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *VehicleRepository) CheckSchoolExists(schoolUUID string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM schools WHERE school_uuid = $1 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, schoolUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *VehicleRepository) CheckRouteInSchool(routeUUID, schoolUUID string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM routes WHERE route_uuid = $1 AND school_uuid = $2 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, routeUUID, schoolUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *VehicleRepository) CountSchoolVehicles(schoolUUID string) (int, error) {
+	var count int
+
+	query := `SELECT COUNT(vehicle_id) FROM vehicles WHERE school_uuid = $1 AND deleted_at IS NULL`
+
+	if err := repository.db.Get(&count, query, schoolUUID); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (repository *VehicleRepository) FetchSchoolVehicles(schoolUUID string, offset, limit int, sortField, sortDirection string) ([]entity.SchoolVehicle, error) {
+	vehicles := []entity.SchoolVehicle{}
+
+	query := schoolVehicleQuery + fmt.Sprintf(`
+		ORDER BY v.%s %s
+		LIMIT $2 OFFSET $3
+	`, sortField, sortDirection)
+
+	if err := repository.db.Select(&vehicles, query, schoolUUID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return vehicles, nil
+}
+
+func (repository *VehicleRepository) FetchSpecSchoolVehicle(schoolUUID, vehicleUUID string) (entity.SchoolVehicle, error) {
+	var vehicle entity.SchoolVehicle
+
+	query := schoolVehicleQuery + ` AND v.vehicle_uuid = $2`
+
+	if err := repository.db.Get(&vehicle, query, schoolUUID, vehicleUUID); err != nil {
+		return entity.SchoolVehicle{}, err
+	}
+
+	return vehicle, nil
+}
+
+// While a vehicle is out of service for a lapsed document the new status is kept aside
+// and only applied once its documents are renewed. Retiring a vehicle frees its driver.
+func (repository *VehicleRepository) UpdateSchoolVehicle(vehicle entity.SchoolVehicle) error {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE vehicles
+		SET vehicle_status = CASE WHEN vehicle_status_before_lapse IS NULL THEN :vehicle_status ELSE vehicle_status END,
+			vehicle_status_before_lapse = CASE WHEN vehicle_status_before_lapse IS NULL THEN NULL ELSE :vehicle_status END,
+			route_uuid = :route_uuid, updated_at = NOW(), updated_by = :updated_by
+		WHERE vehicle_uuid = :vehicle_uuid AND school_uuid = :school_uuid AND deleted_at IS NULL
+	`
+
+	if _, err := tx.NamedExec(query, vehicle); err != nil {
+		return err
+	}
+
+	if vehicle.VehicleStatus == "retired" {
+		if err := releaseVehicle(tx, vehicle.UUID, vehicle.UpdatedBy.String); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
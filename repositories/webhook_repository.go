@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"shuttle/models/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type WebhookRepositoryInterface interface {
+	BeginTransaction() (*sqlx.Tx, error)
+
+	CheckSchoolExists(schoolUUID string) (bool, error)
+	FetchEndpoints(schoolUUID string) ([]entity.WebhookEndpoint, error)
+	FetchSpecEndpoint(schoolUUID, endpointUUID string) (entity.WebhookEndpoint, error)
+	SaveEndpoint(endpoint entity.WebhookEndpoint) error
+	UpdateEndpoint(endpoint entity.WebhookEndpoint) error
+	DeleteEndpoint(schoolUUID, endpointUUID, username string) error
+
+	FetchDeliveries(endpointUUID string, offset, limit int, status string) ([]entity.WebhookDelivery, error)
+	CountDeliveries(endpointUUID, status string) (int, error)
+	FetchSpecDelivery(endpointUUID, deliveryUUID string) (entity.WebhookDelivery, error)
+	RequeueDelivery(tx *sqlx.Tx, deliveryUUID, username string) (bool, error)
+
+	FetchSubscribedEndpoints(schoolUUID, eventType string) ([]entity.WebhookEndpoint, error)
+	SaveDelivery(tx *sqlx.Tx, delivery entity.WebhookDelivery) (bool, error)
+	FetchDeliveryTarget(deliveryUUID string) (entity.WebhookDelivery, entity.WebhookEndpoint, error)
+	UpdateDeliveryAttempt(tx *sqlx.Tx, delivery entity.WebhookDelivery) error
+}
+
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) WebhookRepositoryInterface {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+const webhookEndpointColumns = `
+	endpoint_id, endpoint_uuid, school_uuid, endpoint_url, endpoint_description, endpoint_secret, event_types,
+	is_active, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+`
+
+const webhookDeliveryColumns = `
+	delivery_id, delivery_uuid, endpoint_uuid, event_uuid, event_type, request_body, delivery_status, attempts,
+	response_status, response_body, last_error, next_attempt_at, delivered_at, created_at, redelivered_at, redelivered_by
+`
+
+func (repository *WebhookRepository) BeginTransaction() (*sqlx.Tx, error) {
+	return repository.db.Beginx()
+}
+
+func (repository *WebhookRepository) CheckSchoolExists(schoolUUID string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM schools WHERE school_uuid = $1 AND deleted_at IS NULL)`
+
+	if err := repository.db.Get(&exists, query, schoolUUID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (repository *WebhookRepository) FetchEndpoints(schoolUUID string) ([]entity.WebhookEndpoint, error) {
+	endpoints := []entity.WebhookEndpoint{}
+
+	query := `
+		SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE school_uuid = $1 AND deleted_at IS NULL
+		ORDER BY created_at
+	`
+
+	if err := repository.db.Select(&endpoints, query, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (repository *WebhookRepository) FetchSpecEndpoint(schoolUUID, endpointUUID string) (entity.WebhookEndpoint, error) {
+	var endpoint entity.WebhookEndpoint
+
+	query := `
+		SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE school_uuid = $1 AND endpoint_uuid = $2 AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Get(&endpoint, query, schoolUUID, endpointUUID); err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+
+	return endpoint, nil
+}
+
+func (repository *WebhookRepository) SaveEndpoint(endpoint entity.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (endpoint_id, endpoint_uuid, school_uuid, endpoint_url, endpoint_description, endpoint_secret, event_types, is_active, created_by)
+		VALUES (:endpoint_id, :endpoint_uuid, :school_uuid, :endpoint_url, :endpoint_description, :endpoint_secret, :event_types, :is_active, :created_by)
+	`
+
+	_, err := repository.db.NamedExec(query, endpoint)
+	return err
+}
+
+// The secret is never changed here, it is only handed out when the endpoint is created
+func (repository *WebhookRepository) UpdateEndpoint(endpoint entity.WebhookEndpoint) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET endpoint_url = :endpoint_url, endpoint_description = :endpoint_description, event_types = :event_types,
+			is_active = :is_active, updated_at = NOW(), updated_by = :updated_by
+		WHERE school_uuid = :school_uuid AND endpoint_uuid = :endpoint_uuid AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.NamedExec(query, endpoint)
+	return err
+}
+
+func (repository *WebhookRepository) DeleteEndpoint(schoolUUID, endpointUUID, username string) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET deleted_at = NOW(), deleted_by = $3
+		WHERE school_uuid = $1 AND endpoint_uuid = $2 AND deleted_at IS NULL
+	`
+
+	_, err := repository.db.Exec(query, schoolUUID, endpointUUID, username)
+	return err
+}
+
+// Empty status means any
+func (repository *WebhookRepository) FetchDeliveries(endpointUUID string, offset, limit int, status string) ([]entity.WebhookDelivery, error) {
+	deliveries := []entity.WebhookDelivery{}
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_uuid = $1 AND ($2 = '' OR delivery_status = $2)
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	if err := repository.db.Select(&deliveries, query, endpointUUID, status, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (repository *WebhookRepository) CountDeliveries(endpointUUID, status string) (int, error) {
+	var total int
+
+	query := `SELECT COUNT(*) FROM webhook_deliveries WHERE endpoint_uuid = $1 AND ($2 = '' OR delivery_status = $2)`
+
+	if err := repository.db.Get(&total, query, endpointUUID, status); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (repository *WebhookRepository) FetchSpecDelivery(endpointUUID, deliveryUUID string) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE endpoint_uuid = $1 AND delivery_uuid = $2`
+
+	if err := repository.db.Get(&delivery, query, endpointUUID, deliveryUUID); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// Gives a finished delivery a fresh set of attempts, false means it is still being retried
+func (repository *WebhookRepository) RequeueDelivery(tx *sqlx.Tx, deliveryUUID, username string) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET delivery_status = 'pending', attempts = 0, next_attempt_at = NOW(), redelivered_at = NOW(), redelivered_by = $2
+		WHERE delivery_uuid = $1 AND delivery_status <> 'pending'
+	`
+
+	res, err := tx.Exec(query, deliveryUUID, username)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (repository *WebhookRepository) FetchSubscribedEndpoints(schoolUUID, eventType string) ([]entity.WebhookEndpoint, error) {
+	endpoints := []entity.WebhookEndpoint{}
+
+	query := `
+		SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE school_uuid = $1 AND $2 = ANY(event_types) AND is_active AND deleted_at IS NULL
+	`
+
+	if err := repository.db.Select(&endpoints, query, schoolUUID, eventType); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// False means the endpoint already has a delivery for the event
+func (repository *WebhookRepository) SaveDelivery(tx *sqlx.Tx, delivery entity.WebhookDelivery) (bool, error) {
+	query := `
+		INSERT INTO webhook_deliveries (delivery_id, delivery_uuid, endpoint_uuid, event_uuid, event_type, request_body, next_attempt_at)
+		VALUES (:delivery_id, :delivery_uuid, :endpoint_uuid, :event_uuid, :event_type, :request_body, :next_attempt_at)
+		ON CONFLICT (endpoint_uuid, event_uuid) DO NOTHING
+	`
+
+	res, err := tx.NamedExec(query, delivery)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// The delivery with the endpoint it goes to, including an endpoint that was deleted since
+func (repository *WebhookRepository) FetchDeliveryTarget(deliveryUUID string) (entity.WebhookDelivery, entity.WebhookEndpoint, error) {
+	var delivery entity.WebhookDelivery
+	var endpoint entity.WebhookEndpoint
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE delivery_uuid = $1`
+	if err := repository.db.Get(&delivery, query, deliveryUUID); err != nil {
+		return entity.WebhookDelivery{}, entity.WebhookEndpoint{}, err
+	}
+
+	query = `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE endpoint_uuid = $1`
+	if err := repository.db.Get(&endpoint, query, delivery.EndpointUUID); err != nil {
+		return entity.WebhookDelivery{}, entity.WebhookEndpoint{}, err
+	}
+
+	return delivery, endpoint, nil
+}
+
+func (repository *WebhookRepository) UpdateDeliveryAttempt(tx *sqlx.Tx, delivery entity.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET delivery_status = :delivery_status, attempts = :attempts, response_status = :response_status,
+			response_body = :response_body, last_error = :last_error, next_attempt_at = :next_attempt_at, delivered_at = :delivered_at
+		WHERE delivery_id = :delivery_id
+	`
+
+	_, err := tx.NamedExec(query, delivery)
+	return err
+}
@@ -3,9 +3,11 @@ package routes
 import (
 	"shuttle/handler"
 	"shuttle/middleware"
+	"shuttle/models/entity"
 	"shuttle/repositories"
 	"shuttle/services"
 	"shuttle/utils"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
@@ -20,28 +22,101 @@ func Route(r *fiber.App, db *sqlx.DB) {
 	studentRepository := repositories.NewStudentRepository(db)
 	childernRepository := repositories.NewChildernRepository(db)
 	shuttleRepository := repositories.NewShuttleRepository(db)
+	permissionRepository := repositories.NewPermissionRepository(db)
+	impersonationRepository := repositories.NewImpersonationRepository(db)
+	academicRepository := repositories.NewAcademicRepository(db)
+	driverDocumentRepository := repositories.NewDriverDocumentRepository(db)
+	vehicleMaintenanceRepository := repositories.NewVehicleMaintenanceRepository(db)
+	vehicleAssignmentRepository := repositories.NewVehicleAssignmentRepository(db)
+	routeRepository := repositories.NewRouteRepository(db)
+	schoolProfileRepository := repositories.NewSchoolProfileRepository(db)
+	tripScheduleRepository := repositories.NewTripScheduleRepository(db)
+	jobRepository := repositories.NewJobRepository(db)
+	notificationRepository := repositories.NewNotificationRepository(db)
+	studentAbsenceRepository := repositories.NewStudentAbsenceRepository(db)
+	outboxRepository := repositories.NewOutboxRepository(db)
+	webhookRepository := repositories.NewWebhookRepository(db)
+	conversationRepository := repositories.NewConversationRepository(db)
+	announcementRepository := repositories.NewAnnouncementRepository(db)
+	auditRepository := repositories.NewAuditRepository(db)
 
-	userService := services.NewUserService(userRepository)
-	authService := services.NewAuthService(authRepository, userRepository)
-	schoolService := services.NewSchoolService(schoolRepository, userRepository)
-	vehicleService := services.NewVehicleService(vehicleRepository)
-	studentService := services.NewStudentService(studentRepository, userRepository)
+	auditService := services.NewAuditService(auditRepository)
+	jobService := services.NewJobService(jobRepository)
+	outboxService := services.NewOutboxService(outboxRepository)
+	notificationService := services.NewNotificationService(notificationRepository, &jobService, utils.NewPushSender())
+	userService := services.NewUserService(userRepository, &auditService)
+	authService := services.NewAuthService(authRepository, userRepository, utils.NewSMSSender())
+	schoolService := services.NewSchoolService(schoolRepository, &auditService)
+	vehicleService := services.NewVehicleService(vehicleRepository, &auditService)
+	studentService := services.NewStudentService(studentRepository, userRepository, &auditService)
 	childernService := services.NewChildernService(childernRepository)
-	shuttleService := services.NewShuttleService(shuttleRepository)
+	shuttleService := services.NewShuttleService(shuttleRepository, driverDocumentRepository, vehicleMaintenanceRepository, schoolProfileRepository, &notificationService, &auditService)
+	permissionService := services.NewPermissionService(permissionRepository)
+	impersonationService := services.NewImpersonationService(impersonationRepository, userRepository)
+	academicService := services.NewAcademicService(academicRepository)
+	driverDocumentService := services.NewDriverDocumentService(driverDocumentRepository, utils.NewSMSSender())
+	vehicleMaintenanceService := services.NewVehicleMaintenanceService(vehicleMaintenanceRepository)
+	vehicleAssignmentService := services.NewVehicleAssignmentService(vehicleAssignmentRepository)
+	routeService := services.NewRouteService(routeRepository)
+	schoolProfileService := services.NewSchoolProfileService(schoolProfileRepository)
+	tripScheduleService := services.NewTripScheduleService(tripScheduleRepository, schoolProfileRepository)
+	studentAbsenceService := services.NewStudentAbsenceService(studentAbsenceRepository)
+	webhookService := services.NewWebhookService(webhookRepository, &jobService)
+	conversationService := services.NewConversationService(conversationRepository, &permissionService)
+	announcementService := services.NewAnnouncementService(announcementRepository, &jobService, &notificationService)
 
-	authHandler := handler.NewAuthHttpHandler(authService)
-	userHandler := handler.NewUserHttpHandler(userService, schoolService)
+	authHandler := handler.NewAuthHttpHandler(authService, impersonationService)
+	userHandler := handler.NewUserHttpHandler(userService, schoolService, permissionService)
 	schoolHandler := handler.NewSchoolHttpHandler(schoolService)
 	vehicleHandler := handler.NewVehicleHttpHandler(vehicleService)
 	studentHandler := handler.NewStudentHttpHandler(studentService)
 	childernHandler := handler.NewChildernHandler(childernService)
 	shuttleHandler := handler.NewShuttleHandler(shuttleService)
+	permissionHandler := handler.NewPermissionHttpHandler(permissionService)
+	academicHandler := handler.NewAcademicHttpHandler(academicService)
+	driverDocumentHandler := handler.NewDriverDocumentHttpHandler(driverDocumentService)
+	vehicleMaintenanceHandler := handler.NewVehicleMaintenanceHttpHandler(vehicleMaintenanceService)
+	vehicleAssignmentHandler := handler.NewVehicleAssignmentHttpHandler(vehicleAssignmentService)
+	routeHandler := handler.NewRouteHttpHandler(routeService)
+	schoolProfileHandler := handler.NewSchoolProfileHttpHandler(schoolProfileService)
+	tripScheduleHandler := handler.NewTripScheduleHttpHandler(tripScheduleService)
+	studentAbsenceHandler := handler.NewStudentAbsenceHttpHandler(studentAbsenceService)
+	jobHandler := handler.NewJobHttpHandler(jobService)
+	notificationHandler := handler.NewNotificationHttpHandler(notificationService)
+	webhookHandler := handler.NewWebhookHttpHandler(webhookService)
+	conversationHandler := handler.NewConversationHttpHandler(conversationService)
+	announcementHandler := handler.NewAnnouncementHttpHandler(announcementService)
+	auditHandler := handler.NewAuditHttpHandler(auditService)
 
 	wsService := utils.NewWebSocketService(userRepository, authRepository)
 
+	// Warns school admins about driver documents that are about to expire
+	jobService.Schedule("driver_document_alerts", utils.Every(utils.ConfigDuration("DRIVER_DOCUMENT_CHECK_INTERVAL", 24*time.Hour)), driverDocumentService.SendExpiryAlerts)
+	// Takes vehicles with a lapsed inspection, registration or insurance out of service
+	jobService.Schedule("vehicle_lapse_check", utils.Every(utils.ConfigDuration("VEHICLE_DOCUMENT_CHECK_INTERVAL", 24*time.Hour)), vehicleMaintenanceService.CheckLapsedVehicles)
+	// Materialises the next day's trips from the recurring schedules every evening
+	jobService.Schedule("trip_generation", utils.Every(utils.ConfigDuration("TRIP_GENERATION_INTERVAL", time.Hour)), tripScheduleService.GenerateDueTrips)
+	jobService.Schedule("token_cleanup", utils.ConfigSchedule("TOKEN_CLEANUP_SCHEDULE", "0 3 * * *"), authService.CleanupExpiredTokens)
+	// Push notifications are sent by the workers so a provider outage is retried
+	jobService.Register("notification_push", notificationService.SendPush)
+	// One attempt of a webhook delivery, failed attempts queue the next one with backoff
+	jobService.Register("webhook_delivery", webhookService.SendDelivery)
+	// Queued for the publish_at of every announcement, publishes it and notifies the recipients
+	jobService.Register("announcement_publish", announcementService.PublishAnnouncement)
+	jobService.Schedule("outbox_cleanup", utils.ConfigSchedule("OUTBOX_CLEANUP_SCHEDULE", "45 3 * * *"), outboxService.CleanupEvents)
+	go jobService.Run()
+
+	// Domain events written by the repositories are relayed to these subscribers once committed
+	outboxService.Subscribe("shuttle_status_notifications", []string{entity.EventShuttleStatusChanged}, shuttleService.NotifyStatusChanged)
+	outboxService.Subscribe("webhooks", []string{entity.EventShuttleStatusChanged, entity.EventStudentCreated, entity.EventTripCompleted}, webhookService.QueueDeliveries)
+	go outboxService.Run()
+
 	// FOR PUBLIC
 	r.Post("login", authHandler.Login)
+	r.Post("/login/otp/request", authHandler.RequestLoginOTP)
+	r.Post("/login/otp/verify", authHandler.VerifyLoginOTP)
 	r.Post("/refresh-token", authHandler.IssueNewAccessToken)
+	r.Get("/.well-known/jwks.json", authHandler.GetJWKS)
 	r.Static("/assets", "./assets")
 
 	r.Use("/ws", func(c *fiber.Ctx) error {
@@ -55,77 +130,219 @@ func Route(r *fiber.App, db *sqlx.DB) {
 	// FOR AUTHENTICATED
 	protected := r.Group("/api")
 	protected.Use(middleware.AuthenticationMiddleware())
-	protected.Use(middleware.AuthorizationMiddleware([]string{"SA", "AS", "D", "P"}))
+	protected.Use(middleware.ImpersonationMiddleware(impersonationService))
+	protected.Use(middleware.PermissionMiddleware(permissionService))
 
 	protected.Get("/my/profile", authHandler.GetMyProfile)
 	protected.Post("/logout", authHandler.Logout)
 
+	protected.Get("/my/notifications", notificationHandler.GetMyNotifications)
+	protected.Get("/my/notifications/unread-count", notificationHandler.GetUnreadCount)
+	protected.Put("/my/notifications/read/:id", notificationHandler.MarkRead)
+	protected.Put("/my/notifications/read-all", notificationHandler.MarkAllRead)
+	protected.Get("/my/notifications/preferences", notificationHandler.GetPreferences)
+	protected.Put("/my/notifications/preferences/update", notificationHandler.UpdatePreferences)
+	protected.Post("/my/notifications/device/add", notificationHandler.AddPushDevice)
+	protected.Delete("/my/notifications/device/delete/:id", notificationHandler.DeletePushDevice)
+
+	protected.Get("/my/conversations", middleware.RequirePermission("message:read"), conversationHandler.GetMyConversations)
+	protected.Get("/my/conversations/contacts", middleware.RequirePermission("message:write"), conversationHandler.GetContacts)
+	protected.Post("/my/conversations/add", middleware.RequirePermission("message:write"), conversationHandler.StartConversation)
+	protected.Get("/my/conversations/:id/messages", middleware.RequirePermission("message:read"), conversationHandler.GetMessages)
+	protected.Post("/my/conversations/:id/messages/add", middleware.RequirePermission("message:write"), conversationHandler.SendMessage)
+	protected.Put("/my/conversations/read/:id", middleware.RequirePermission("message:read"), conversationHandler.MarkRead)
+
+	protected.Get("/my/announcements", announcementHandler.GetMyAnnouncements)
+	protected.Put("/my/announcements/read/:id", announcementHandler.MarkRead)
+
 	protectedSuperAdmin := protected.Group("/superadmin")
-	protectedSuperAdmin.Use(middleware.AuthorizationMiddleware([]string{"SA"}))
+	protectedSuperAdmin.Use(middleware.RequirePermission("area:superadmin"))
 
 	protectedSchoolAdmin := protected.Group("/school")
-	protectedSchoolAdmin.Use(middleware.AuthorizationMiddleware([]string{"AS"}))
+	protectedSchoolAdmin.Use(middleware.RequirePermission("area:school"))
 	protectedSchoolAdmin.Use(middleware.SchoolAdminMiddleware(userService))
 
 	protectedParent := protected.Group("/parent")
-	protectedParent.Use(middleware.AuthorizationMiddleware([]string{"P"}))
+	protectedParent.Use(middleware.RequirePermission("area:parent"))
 
 	protectedDriver := protected.Group("/driver")
-	protectedDriver.Use(middleware.AuthorizationMiddleware([]string{"D"}))
+	protectedDriver.Use(middleware.RequirePermission("area:driver"))
 
 	// USER FOR SUPERADMIN
-	protectedSuperAdmin.Get("/user/sa/all", userHandler.GetAllSuperAdmin)
-	protectedSuperAdmin.Get("/user/as/all", userHandler.GetAllSchoolAdmin)
-	protectedSuperAdmin.Get("/user/driver/all", userHandler.GetAllPermittedDriver)
-	protectedSuperAdmin.Get("/user/sa/:id", userHandler.GetSpecSuperAdmin)
-	protectedSuperAdmin.Get("/user/as/:id", userHandler.GetSpecSchoolAdmin)
-	protectedSuperAdmin.Get("/user/driver/:id", userHandler.GetSpecPermittedDriver)
-	protectedSuperAdmin.Post("/user/add", userHandler.AddUser)
-	protectedSuperAdmin.Put("/user/update/:id", userHandler.UpdateUser)
-	protectedSuperAdmin.Delete("/user/sa/delete/:id", userHandler.DeleteSuperAdmin)
-	protectedSuperAdmin.Delete("/user/as/delete/:id", userHandler.DeleteSchoolAdmin)
-	protectedSuperAdmin.Delete("/user/driver/delete/:id", userHandler.DeleteDriver)
+	protectedSuperAdmin.Get("/user/sa/all", middleware.RequirePermission("user:read"), userHandler.GetAllSuperAdmin)
+	protectedSuperAdmin.Get("/user/as/all", middleware.RequirePermission("user:read"), userHandler.GetAllSchoolAdmin)
+	protectedSuperAdmin.Get("/user/driver/all", middleware.RequirePermission("driver:read"), userHandler.GetAllPermittedDriver)
+	protectedSuperAdmin.Get("/user/sa/:id", middleware.RequirePermission("user:read"), userHandler.GetSpecSuperAdmin)
+	protectedSuperAdmin.Get("/user/as/:id", middleware.RequirePermission("user:read"), userHandler.GetSpecSchoolAdmin)
+	protectedSuperAdmin.Get("/user/driver/:id", middleware.RequirePermission("driver:read"), userHandler.GetSpecPermittedDriver)
+	protectedSuperAdmin.Post("/user/add", middleware.RequirePermission("user:write"), userHandler.AddUser)
+	protectedSuperAdmin.Put("/user/update/:id", middleware.RequirePermission("user:write"), userHandler.UpdateUser)
+	protectedSuperAdmin.Delete("/user/sa/delete/:id", middleware.RequirePermission("user:write"), userHandler.DeleteSuperAdmin)
+	protectedSuperAdmin.Delete("/user/as/delete/:id", middleware.RequirePermission("user:write"), userHandler.DeleteSchoolAdmin)
+	protectedSuperAdmin.Delete("/user/driver/delete/:id", middleware.RequirePermission("user:write"), userHandler.DeleteDriver)
+	protectedSuperAdmin.Get("/user/driver/:id/document/all", middleware.RequirePermission("driver:read"), driverDocumentHandler.GetDriverDocuments)
+	protectedSuperAdmin.Get("/user/driver/:id/document/:document_id/file", middleware.RequirePermission("driver:read"), driverDocumentHandler.GetDriverDocumentFile)
+	protectedSuperAdmin.Post("/user/driver/:id/document/add", middleware.RequirePermission("user:write"), driverDocumentHandler.AddDriverDocument)
+	protectedSuperAdmin.Put("/user/driver/:id/document/update/:document_id", middleware.RequirePermission("user:write"), driverDocumentHandler.UpdateDriverDocument)
+	protectedSuperAdmin.Delete("/user/driver/:id/document/delete/:document_id", middleware.RequirePermission("user:write"), driverDocumentHandler.DeleteDriverDocument)
+	protectedSuperAdmin.Get("/user/driver/:id/assignment/all", middleware.RequirePermission("driver:read"), vehicleAssignmentHandler.GetDriverAssignments)
+	protectedSuperAdmin.Post("/user/unlock/:id", middleware.RequirePermission("user:unlock"), authHandler.UnlockAccount)
+	protectedSuperAdmin.Post("/impersonate/:user_uuid", middleware.RequirePermission("user:impersonate"), authHandler.Impersonate)
+
+	// ROLE FOR SUPERADMIN
+	protectedSuperAdmin.Get("/role/all", middleware.RequirePermission("role:manage"), permissionHandler.GetAllRoles)
+	protectedSuperAdmin.Get("/role/:code", middleware.RequirePermission("role:manage"), permissionHandler.GetSpecRole)
+	protectedSuperAdmin.Post("/role/add", middleware.RequirePermission("role:manage"), permissionHandler.AddRole)
+	protectedSuperAdmin.Put("/role/update/:code", middleware.RequirePermission("role:manage"), permissionHandler.UpdateRole)
+	protectedSuperAdmin.Delete("/role/delete/:code", middleware.RequirePermission("role:manage"), permissionHandler.DeleteRole)
+	protectedSuperAdmin.Get("/permission/all", middleware.RequirePermission("role:manage"), permissionHandler.GetAllPermissions)
 
 	// SCHOOL FOR SUPERADMIN
-	protectedSuperAdmin.Get("/school/all", schoolHandler.GetAllSchools)
-	protectedSuperAdmin.Get("/school/:id", schoolHandler.GetSpecSchool)
-	protectedSuperAdmin.Post("/school/add", schoolHandler.AddSchool)
-	protectedSuperAdmin.Put("/school/update/:id", schoolHandler.UpdateSchool)
-	protectedSuperAdmin.Delete("/school/delete/:id", schoolHandler.DeleteSchool)
+	protectedSuperAdmin.Get("/school/all", middleware.RequirePermission("school:read"), schoolHandler.GetAllSchools)
+	protectedSuperAdmin.Get("/school/:id", middleware.RequirePermission("school:read"), schoolHandler.GetSpecSchool)
+	protectedSuperAdmin.Post("/school/add", middleware.RequirePermission("school:write"), schoolHandler.AddSchool)
+	protectedSuperAdmin.Put("/school/update/:id", middleware.RequirePermission("school:write"), schoolHandler.UpdateSchool)
+	protectedSuperAdmin.Get("/school/:id/deletion-plan", middleware.RequirePermission("school:read"), schoolHandler.GetDeletionPlan)
+	protectedSuperAdmin.Delete("/school/delete/:id", middleware.RequirePermission("school:write"), schoolHandler.DeleteSchool)
+	protectedSuperAdmin.Post("/school/:id/restore", middleware.RequirePermission("school:write"), schoolHandler.RestoreSchool)
+	protectedSuperAdmin.Get("/school/:id/profile", middleware.RequirePermission("school:read"), schoolProfileHandler.GetSchoolProfile)
+	protectedSuperAdmin.Put("/school/:id/profile/update", middleware.RequirePermission("school:write"), schoolProfileHandler.UpdateSchoolProfile)
+	protectedSuperAdmin.Get("/school/:id/closure/all", middleware.RequirePermission("school:read"), schoolProfileHandler.GetClosures)
+	protectedSuperAdmin.Post("/school/:id/closure/add", middleware.RequirePermission("school:write"), schoolProfileHandler.AddClosure)
+	protectedSuperAdmin.Put("/school/:id/closure/update/:closure_id", middleware.RequirePermission("school:write"), schoolProfileHandler.UpdateClosure)
+	protectedSuperAdmin.Delete("/school/:id/closure/delete/:closure_id", middleware.RequirePermission("school:write"), schoolProfileHandler.DeleteClosure)
+	protectedSuperAdmin.Get("/school/:id/webhook/all", middleware.RequirePermission("webhook:read"), webhookHandler.GetAllEndpoints)
+	protectedSuperAdmin.Get("/school/:id/webhook/:endpoint_id", middleware.RequirePermission("webhook:read"), webhookHandler.GetSpecEndpoint)
+	protectedSuperAdmin.Post("/school/:id/webhook/add", middleware.RequirePermission("webhook:write"), webhookHandler.AddEndpoint)
+	protectedSuperAdmin.Put("/school/:id/webhook/update/:endpoint_id", middleware.RequirePermission("webhook:write"), webhookHandler.UpdateEndpoint)
+	protectedSuperAdmin.Delete("/school/:id/webhook/delete/:endpoint_id", middleware.RequirePermission("webhook:write"), webhookHandler.DeleteEndpoint)
+	protectedSuperAdmin.Get("/school/:id/webhook/:endpoint_id/delivery/all", middleware.RequirePermission("webhook:read"), webhookHandler.GetDeliveries)
+	protectedSuperAdmin.Post("/school/:id/webhook/:endpoint_id/delivery/redeliver/:delivery_id", middleware.RequirePermission("webhook:write"), webhookHandler.RedeliverDelivery)
 
 	// VEHICLE FOR SUPERADMIN
-	protectedSuperAdmin.Get("/vehicle/all", vehicleHandler.GetAllVehicles)
-	protectedSuperAdmin.Get("/vehicle/:id", vehicleHandler.GetSpecVehicle)
-	protectedSuperAdmin.Post("/vehicle/add", vehicleHandler.AddVehicle)
-	protectedSuperAdmin.Put("/vehicle/update/:id", vehicleHandler.UpdateVehicle)
-	protectedSuperAdmin.Delete("/vehicle/delete/:id", vehicleHandler.DeleteVehicle)
+	protectedSuperAdmin.Get("/vehicle/all", middleware.RequirePermission("vehicle:read"), vehicleHandler.GetAllVehicles)
+	protectedSuperAdmin.Get("/vehicle/:id", middleware.RequirePermission("vehicle:read"), vehicleHandler.GetSpecVehicle)
+	protectedSuperAdmin.Post("/vehicle/add", middleware.RequirePermission("vehicle:write"), vehicleHandler.AddVehicle)
+	protectedSuperAdmin.Put("/vehicle/update/:id", middleware.RequirePermission("vehicle:write"), vehicleHandler.UpdateVehicle)
+	protectedSuperAdmin.Delete("/vehicle/delete/:id", middleware.RequirePermission("vehicle:write"), vehicleHandler.DeleteVehicle)
+
+	protectedSuperAdmin.Get("/vehicle/:id/maintenance/all", middleware.RequirePermission("vehicle:read"), vehicleMaintenanceHandler.GetMaintenances)
+	protectedSuperAdmin.Post("/vehicle/:id/maintenance/add", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.AddMaintenance)
+	protectedSuperAdmin.Put("/vehicle/:id/maintenance/update/:maintenance_id", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.UpdateMaintenance)
+	protectedSuperAdmin.Delete("/vehicle/:id/maintenance/delete/:maintenance_id", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.DeleteMaintenance)
+
+	protectedSuperAdmin.Get("/vehicle/:id/document/all", middleware.RequirePermission("vehicle:read"), vehicleMaintenanceHandler.GetVehicleDocuments)
+	protectedSuperAdmin.Get("/vehicle/:id/document/:document_id/file", middleware.RequirePermission("vehicle:read"), vehicleMaintenanceHandler.GetVehicleDocumentFile)
+	protectedSuperAdmin.Post("/vehicle/:id/document/add", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.AddVehicleDocument)
+	protectedSuperAdmin.Put("/vehicle/:id/document/update/:document_id", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.UpdateVehicleDocument)
+	protectedSuperAdmin.Delete("/vehicle/:id/document/delete/:document_id", middleware.RequirePermission("vehicle:write"), vehicleMaintenanceHandler.DeleteVehicleDocument)
+
+	protectedSuperAdmin.Get("/vehicle/:id/assignment/all", middleware.RequirePermission("vehicle:read"), vehicleAssignmentHandler.GetVehicleAssignments)
+	protectedSuperAdmin.Post("/vehicle/assignment/assign", middleware.RequirePermission("vehicle:write"), vehicleAssignmentHandler.AssignVehicle)
+	protectedSuperAdmin.Post("/vehicle/:id/assignment/unassign", middleware.RequirePermission("vehicle:write"), vehicleAssignmentHandler.UnassignVehicle)
+
+	protectedSuperAdmin.Get("/job/all", middleware.RequirePermission("job:read"), jobHandler.GetAllJobs)
+	protectedSuperAdmin.Get("/job/schedule/all", middleware.RequirePermission("job:read"), jobHandler.GetJobSchedules)
+	protectedSuperAdmin.Get("/job/:id", middleware.RequirePermission("job:read"), jobHandler.GetSpecJob)
+	protectedSuperAdmin.Post("/job/retry/:id", middleware.RequirePermission("job:write"), jobHandler.RetryJob)
+
+	protectedSuperAdmin.Get("/audit/all", middleware.RequirePermission("audit:read"), auditHandler.GetAllAuditLogs)
 
 	////////////////////////////////////// SCHOOL ADMIN //////////////////////////////////////
 
-	protectedSchoolAdmin.Get("/user/driver/all", userHandler.GetAllPermittedDriver)
-	// protectedSchoolAdmin.Post("/user/driver/add", handler.AddSchoolDriver)
-	// protectedSchoolAdmin.Put("/user/driver/update/:id", handler.UpdateSchoolDriver)
-	//protectedSchoolAdmin.Delete("/user/driver/delete/:id", handler.DeleteSchoolDriver)
+	protectedSchoolAdmin.Get("/user/driver/all", middleware.RequirePermission("driver:read"), userHandler.GetAllPermittedDriver)
+	protectedSchoolAdmin.Get("/user/driver/:id", middleware.RequirePermission("driver:read"), userHandler.GetSpecPermittedDriver)
+	protectedSchoolAdmin.Post("/user/driver/add", middleware.RequirePermission("driver:write"), userHandler.AddSchoolDriver)
+	protectedSchoolAdmin.Put("/user/driver/update/:id", middleware.RequirePermission("driver:write"), userHandler.UpdateSchoolDriver)
+	protectedSchoolAdmin.Delete("/user/driver/delete/:id", middleware.RequirePermission("driver:write"), userHandler.DeleteSchoolDriver)
+	protectedSchoolAdmin.Get("/user/driver/:id/document/all", middleware.RequirePermission("driver:read"), driverDocumentHandler.GetDriverDocuments)
+	protectedSchoolAdmin.Get("/user/driver/:id/document/:document_id/file", middleware.RequirePermission("driver:read"), driverDocumentHandler.GetDriverDocumentFile)
+	protectedSchoolAdmin.Post("/user/driver/:id/document/add", middleware.RequirePermission("driver:write"), driverDocumentHandler.AddDriverDocument)
+	protectedSchoolAdmin.Put("/user/driver/:id/document/update/:document_id", middleware.RequirePermission("driver:write"), driverDocumentHandler.UpdateDriverDocument)
+	protectedSchoolAdmin.Delete("/user/driver/:id/document/delete/:document_id", middleware.RequirePermission("driver:write"), driverDocumentHandler.DeleteDriverDocument)
+
+	protectedSchoolAdmin.Get("/student/all", middleware.RequirePermission("student:read"), studentHandler.GetAllStudentWithParents)
+	protectedSchoolAdmin.Get("/student/:id", middleware.RequirePermission("student:read"), studentHandler.GetSpecStudentWithParents)
+	protectedSchoolAdmin.Post("/student/add", middleware.RequirePermission("student:write"), studentHandler.AddStudentWithParent)
+	protectedSchoolAdmin.Post("/student/import", middleware.RequirePermission("student:write"), studentHandler.ImportStudents)
+	protectedSchoolAdmin.Put("/student/update/:id", middleware.RequirePermission("student:write"), studentHandler.UpdateStudent)
+	protectedSchoolAdmin.Delete("/student/delete/:id", middleware.RequirePermission("student:write"), studentHandler.DeleteStudent)
+	protectedSchoolAdmin.Get("/student/:id/siblings", middleware.RequirePermission("student:read"), studentHandler.GetStudentSiblings)
+	protectedSchoolAdmin.Post("/student/:id/guardian/add", middleware.RequirePermission("student:write"), studentHandler.AddStudentGuardian)
+	protectedSchoolAdmin.Put("/student/:id/guardian/update/:guardian_id", middleware.RequirePermission("student:write"), studentHandler.UpdateStudentGuardian)
+	protectedSchoolAdmin.Delete("/student/:id/guardian/delete/:guardian_id", middleware.RequirePermission("student:write"), studentHandler.RemoveStudentGuardian)
+
+	protectedSchoolAdmin.Get("/academic-year/all", middleware.RequirePermission("academic:read"), academicHandler.GetAllAcademicYears)
+	protectedSchoolAdmin.Get("/academic-year/:id", middleware.RequirePermission("academic:read"), academicHandler.GetSpecAcademicYear)
+	protectedSchoolAdmin.Post("/academic-year/add", middleware.RequirePermission("academic:write"), academicHandler.AddAcademicYear)
+	protectedSchoolAdmin.Put("/academic-year/update/:id", middleware.RequirePermission("academic:write"), academicHandler.UpdateAcademicYear)
+	protectedSchoolAdmin.Delete("/academic-year/delete/:id", middleware.RequirePermission("academic:write"), academicHandler.DeleteAcademicYear)
+	protectedSchoolAdmin.Get("/grade/all", middleware.RequirePermission("academic:read"), academicHandler.GetAllGradeLevels)
+	protectedSchoolAdmin.Post("/grade/add", middleware.RequirePermission("academic:write"), academicHandler.AddGradeLevel)
+	protectedSchoolAdmin.Put("/grade/update/:id", middleware.RequirePermission("academic:write"), academicHandler.UpdateGradeLevel)
+	protectedSchoolAdmin.Delete("/grade/delete/:id", middleware.RequirePermission("academic:write"), academicHandler.DeleteGradeLevel)
+	protectedSchoolAdmin.Get("/promotion/all", middleware.RequirePermission("academic:read"), academicHandler.GetAllPromotions)
+	protectedSchoolAdmin.Post("/promotion/preview", middleware.RequirePermission("academic:read"), academicHandler.PreviewPromotion)
+	protectedSchoolAdmin.Post("/promotion/apply", middleware.RequirePermission("academic:write"), academicHandler.ApplyPromotion)
+	protectedSchoolAdmin.Post("/promotion/rollback/:id", middleware.RequirePermission("academic:write"), academicHandler.RollbackPromotion)
+
+	protectedSchoolAdmin.Get("/route/all", middleware.RequirePermission("route:read"), routeHandler.GetAllRoutes)
+	protectedSchoolAdmin.Get("/route/:id", middleware.RequirePermission("route:read"), routeHandler.GetSpecRoute)
+	protectedSchoolAdmin.Post("/route/add", middleware.RequirePermission("route:write"), routeHandler.AddRoute)
+	protectedSchoolAdmin.Put("/route/update/:id", middleware.RequirePermission("route:write"), routeHandler.UpdateRoute)
+	protectedSchoolAdmin.Delete("/route/delete/:id", middleware.RequirePermission("route:write"), routeHandler.DeleteRoute)
+
+	protectedSchoolAdmin.Get("/vehicle/all", middleware.RequirePermission("vehicle:read"), vehicleHandler.GetSchoolVehicles)
+	protectedSchoolAdmin.Get("/vehicle/:id", middleware.RequirePermission("vehicle:read"), vehicleHandler.GetSpecSchoolVehicle)
+	protectedSchoolAdmin.Put("/vehicle/update/:id", middleware.RequirePermission("vehicle:status"), vehicleHandler.UpdateSchoolVehicle)
+
+	protectedSchoolAdmin.Get("/profile", middleware.RequirePermission("school:profile"), schoolProfileHandler.GetSchoolProfile)
+	protectedSchoolAdmin.Put("/profile/update", middleware.RequirePermission("school:profile"), schoolProfileHandler.UpdateSchoolProfile)
+	protectedSchoolAdmin.Get("/closure/all", middleware.RequirePermission("school:profile"), schoolProfileHandler.GetClosures)
+	protectedSchoolAdmin.Post("/closure/add", middleware.RequirePermission("school:profile"), schoolProfileHandler.AddClosure)
+	protectedSchoolAdmin.Put("/closure/update/:closure_id", middleware.RequirePermission("school:profile"), schoolProfileHandler.UpdateClosure)
+	protectedSchoolAdmin.Delete("/closure/delete/:closure_id", middleware.RequirePermission("school:profile"), schoolProfileHandler.DeleteClosure)
+	protectedSchoolAdmin.Get("/webhook/all", middleware.RequirePermission("webhook:read"), webhookHandler.GetAllEndpoints)
+	protectedSchoolAdmin.Get("/webhook/:endpoint_id", middleware.RequirePermission("webhook:read"), webhookHandler.GetSpecEndpoint)
+	protectedSchoolAdmin.Post("/webhook/add", middleware.RequirePermission("webhook:write"), webhookHandler.AddEndpoint)
+	protectedSchoolAdmin.Put("/webhook/update/:endpoint_id", middleware.RequirePermission("webhook:write"), webhookHandler.UpdateEndpoint)
+	protectedSchoolAdmin.Delete("/webhook/delete/:endpoint_id", middleware.RequirePermission("webhook:write"), webhookHandler.DeleteEndpoint)
+	protectedSchoolAdmin.Get("/webhook/:endpoint_id/delivery/all", middleware.RequirePermission("webhook:read"), webhookHandler.GetDeliveries)
+	protectedSchoolAdmin.Post("/webhook/:endpoint_id/delivery/redeliver/:delivery_id", middleware.RequirePermission("webhook:write"), webhookHandler.RedeliverDelivery)
+
+	protectedSchoolAdmin.Get("/announcement/all", middleware.RequirePermission("announcement:read"), announcementHandler.GetAllAnnouncements)
+	protectedSchoolAdmin.Get("/announcement/:id", middleware.RequirePermission("announcement:read"), announcementHandler.GetSpecAnnouncement)
+	protectedSchoolAdmin.Post("/announcement/add", middleware.RequirePermission("announcement:write"), announcementHandler.AddAnnouncement)
+	protectedSchoolAdmin.Put("/announcement/update/:id", middleware.RequirePermission("announcement:write"), announcementHandler.UpdateAnnouncement)
+	protectedSchoolAdmin.Delete("/announcement/delete/:id", middleware.RequirePermission("announcement:write"), announcementHandler.DeleteAnnouncement)
 
-	// protectedSchoolAdmin.Get("/student/all", handler.GetAllStudentWithParents)
-	protectedSchoolAdmin.Post("/student/add", studentHandler.AddStudentWithParent)
-	// protectedSchoolAdmin.Put("/student/update/:id", handler.UpdateSchoolStudentWithParents)
-	// protectedSchoolAdmin.Delete("/student/delete/:id", handler.DeleteSchoolStudentWithParents)
+	// CONVERSATION MODERATION FOR SCHOOL ADMIN
+	protectedSchoolAdmin.Get("/conversation/all", middleware.RequirePermission("message:moderate"), conversationHandler.GetSchoolConversations)
+	protectedSchoolAdmin.Get("/conversation/:id/messages", middleware.RequirePermission("message:moderate"), conversationHandler.GetSchoolMessages)
+	protectedSchoolAdmin.Delete("/conversation/:id/message/delete/:message_id", middleware.RequirePermission("message:moderate"), conversationHandler.DeleteMessage)
 
-	protectedSchoolAdmin.Get("/route/all", handler.GetAllRoutes)
-	protectedSchoolAdmin.Get("/route/:id", handler.GetSpecRoute)
-	protectedSchoolAdmin.Post("/route/add", handler.AddRoute)
+	protectedSchoolAdmin.Get("/schedule/all", middleware.RequirePermission("schedule:read"), tripScheduleHandler.GetAllSchedules)
+	protectedSchoolAdmin.Get("/schedule/:id", middleware.RequirePermission("schedule:read"), tripScheduleHandler.GetSpecSchedule)
+	protectedSchoolAdmin.Post("/schedule/add", middleware.RequirePermission("schedule:write"), tripScheduleHandler.AddSchedule)
+	protectedSchoolAdmin.Put("/schedule/update/:id", middleware.RequirePermission("schedule:write"), tripScheduleHandler.UpdateSchedule)
+	protectedSchoolAdmin.Delete("/schedule/delete/:id", middleware.RequirePermission("schedule:write"), tripScheduleHandler.DeleteSchedule)
+	protectedSchoolAdmin.Get("/trip/all", middleware.RequirePermission("schedule:read"), tripScheduleHandler.GetSchoolTrips)
+	protectedSchoolAdmin.Post("/trip/generate", middleware.RequirePermission("schedule:write"), tripScheduleHandler.GenerateTrips)
 
 	///////////////////////////////// PARENT ///////////////////////////////////
 
-	protectedParent.Get("/my/childern/all", childernHandler.GetAllChilderns)
-	protectedParent.Get("/my/childern/:id", childernHandler.GetSpecChildern)
-	protectedParent.Put("/my/childern/update/:id", childernHandler.UpdateChildern)
-	protectedParent.Get("/my/childern/shuttle/inf", shuttleHandler.GetShuttleStatusByParent)
+	protectedParent.Get("/my/childern/all", middleware.RequirePermission("children:read"), childernHandler.GetAllChilderns)
+	protectedParent.Get("/my/childern/:id", middleware.RequirePermission("children:read"), childernHandler.GetSpecChildern)
+	protectedParent.Put("/my/childern/update/:id", middleware.RequirePermission("children:write"), childernHandler.UpdateChildern)
+	protectedParent.Get("/my/childern/shuttle/inf", middleware.RequirePermission("shuttle:read"), shuttleHandler.GetShuttleStatusByParent)
+	protectedParent.Get("/my/childern/:id/absence/all", middleware.RequirePermission("children:read"), studentAbsenceHandler.GetAbsences)
+	protectedParent.Post("/my/childern/:id/absence/add", middleware.RequirePermission("absence:write"), studentAbsenceHandler.AddAbsence)
+	protectedParent.Delete("/my/childern/:id/absence/delete/:absence_id", middleware.RequirePermission("absence:write"), studentAbsenceHandler.DeleteAbsence)
 
 	////////////////////////////// DRIVER😂 /////////////////////////////////////
 
-	protectedDriver.Post("/shuttle/add", shuttleHandler.AddShuttle)
-	protectedDriver.Put("/shuttle/update/:id", shuttleHandler.EditShuttle)
+	protectedDriver.Post("/shuttle/add", middleware.RequirePermission("shuttle:write"), shuttleHandler.AddShuttle)
+	protectedDriver.Put("/shuttle/update/:id", middleware.RequirePermission("shuttle:write"), shuttleHandler.EditShuttle)
+	protectedDriver.Get("/trip/all", middleware.RequirePermission("schedule:read"), tripScheduleHandler.GetDriverTrips)
 
 }
@@ -0,0 +1,674 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const academicDateLayout = "2006-01-02"
+
+type AcademicServiceInterface interface {
+	GetAcademicYears(schoolUUID string) ([]dto.AcademicYearResponseDTO, error)
+	GetSpecAcademicYear(schoolUUID, id string) (dto.AcademicYearResponseDTO, error)
+	AddAcademicYear(schoolUUID string, req dto.AcademicYearRequestDTO, username string) error
+	UpdateAcademicYear(schoolUUID, id string, req dto.AcademicYearRequestDTO, username string) error
+	DeleteAcademicYear(schoolUUID, id, username string) error
+
+	GetGradeLevels(schoolUUID string) ([]dto.GradeLevelResponseDTO, error)
+	AddGradeLevel(schoolUUID string, req dto.GradeLevelRequestDTO, username string) error
+	UpdateGradeLevel(schoolUUID, id string, req dto.GradeLevelRequestDTO, username string) error
+	DeleteGradeLevel(schoolUUID, id, username string) error
+
+	GetPromotions(schoolUUID string) ([]dto.PromotionResponseDTO, error)
+	PreviewPromotion(schoolUUID string, req dto.PromotionRequestDTO) (dto.PromotionPreviewDTO, error)
+	ApplyPromotion(schoolUUID string, req dto.PromotionRequestDTO, username string) (dto.PromotionResponseDTO, error)
+	RollbackPromotion(schoolUUID, id, username string) error
+}
+
+type AcademicService struct {
+	academicRepository repositories.AcademicRepositoryInterface
+}
+
+func NewAcademicService(academicRepository repositories.AcademicRepositoryInterface) AcademicService {
+	return AcademicService{
+		academicRepository: academicRepository,
+	}
+}
+
+func (service *AcademicService) GetAcademicYears(schoolUUID string) ([]dto.AcademicYearResponseDTO, error) {
+	years, err := service.academicRepository.FetchAcademicYears(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	yearsDTO := []dto.AcademicYearResponseDTO{}
+	for _, year := range years {
+		yearsDTO = append(yearsDTO, toAcademicYearDTO(year))
+	}
+
+	return yearsDTO, nil
+}
+
+func (service *AcademicService) GetSpecAcademicYear(schoolUUID, id string) (dto.AcademicYearResponseDTO, error) {
+	year, err := service.fetchAcademicYear(schoolUUID, id)
+	if err != nil {
+		return dto.AcademicYearResponseDTO{}, err
+	}
+
+	return toAcademicYearDTO(year), nil
+}
+
+func (service *AcademicService) AddAcademicYear(schoolUUID string, req dto.AcademicYearRequestDTO, username string) error {
+	yearUUID := uuid.New()
+	year, err := toAcademicYearEntity(yearUUID, schoolUUID, req)
+	if err != nil {
+		return err
+	}
+
+	year.ID = time.Now().UnixMilli()*1e6 + int64(yearUUID.ID()%1e6)
+	year.CreatedBy = toNullString(username)
+
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if req.IsCurrent {
+		if err := service.academicRepository.ClearCurrentAcademicYear(tx, schoolUUID); err != nil {
+			return err
+		}
+	}
+
+	if err := service.academicRepository.SaveAcademicYear(tx, year); err != nil {
+		return err
+	}
+
+	if err := service.academicRepository.ReplaceAcademicTerms(tx, yearUUID.String(), year.Terms); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *AcademicService) UpdateAcademicYear(schoolUUID, id string, req dto.AcademicYearRequestDTO, username string) error {
+	existing, err := service.fetchAcademicYear(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	year, err := toAcademicYearEntity(existing.UUID, schoolUUID, req)
+	if err != nil {
+		return err
+	}
+
+	year.UpdatedBy = toNullString(username)
+
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.academicRepository.UpdateAcademicYear(tx, year); err != nil {
+		return err
+	}
+
+	if err := service.academicRepository.ReplaceAcademicTerms(tx, id, year.Terms); err != nil {
+		return err
+	}
+
+	// The current year is only handed over here or by a promotion, unticking it leaves the school without one
+	if req.IsCurrent != existing.IsCurrent {
+		if err := service.academicRepository.ClearCurrentAcademicYear(tx, schoolUUID); err != nil {
+			return err
+		}
+		if req.IsCurrent {
+			if err := service.academicRepository.SetCurrentAcademicYear(tx, schoolUUID, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (service *AcademicService) DeleteAcademicYear(schoolUUID, id, username string) error {
+	year, err := service.fetchAcademicYear(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	if year.IsCurrent {
+		return errors.New("the current academic year cannot be deleted", 400)
+	}
+
+	return service.academicRepository.DeleteAcademicYear(schoolUUID, id, username)
+}
+
+func (service *AcademicService) GetGradeLevels(schoolUUID string) ([]dto.GradeLevelResponseDTO, error) {
+	levels, err := service.academicRepository.FetchGradeLevels(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := service.academicRepository.CountStudentsPerGradeLevel(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	levelsDTO := []dto.GradeLevelResponseDTO{}
+	for i, level := range levels {
+		levelsDTO = append(levelsDTO, dto.GradeLevelResponseDTO{
+			UUID:      level.UUID.String(),
+			Name:      level.Name,
+			Order:     level.Order,
+			IsFinal:   i == len(levels)-1,
+			Students:  counts[level.UUID.String()],
+			CreatedAt: safeTimeFormat(level.CreatedAt),
+			CreatedBy: safeStringFormat(level.CreatedBy),
+			UpdatedAt: safeTimeFormat(level.UpdatedAt),
+			UpdatedBy: safeStringFormat(level.UpdatedBy),
+		})
+	}
+
+	return levelsDTO, nil
+}
+
+func (service *AcademicService) AddGradeLevel(schoolUUID string, req dto.GradeLevelRequestDTO, username string) error {
+	if err := service.checkGradeLevelConflict(schoolUUID, "", req); err != nil {
+		return err
+	}
+
+	levelUUID := uuid.New()
+	level := entity.GradeLevel{
+		ID:         time.Now().UnixMilli()*1e6 + int64(levelUUID.ID()%1e6),
+		UUID:       levelUUID,
+		SchoolUUID: uuid.MustParse(schoolUUID),
+		Name:       req.Name,
+		Order:      req.Order,
+		CreatedBy:  toNullString(username),
+	}
+
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.academicRepository.SaveGradeLevel(tx, level); err != nil {
+		return err
+	}
+
+	if err := service.academicRepository.LinkStudentsToGradeLevel(tx, level, username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *AcademicService) UpdateGradeLevel(schoolUUID, id string, req dto.GradeLevelRequestDTO, username string) error {
+	level, err := service.fetchGradeLevel(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := service.checkGradeLevelConflict(schoolUUID, id, req); err != nil {
+		return err
+	}
+
+	level.Name = req.Name
+	level.Order = req.Order
+	level.UpdatedBy = toNullString(username)
+
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.academicRepository.UpdateGradeLevel(tx, level); err != nil {
+		return err
+	}
+
+	if err := service.academicRepository.LinkStudentsToGradeLevel(tx, level, username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *AcademicService) DeleteGradeLevel(schoolUUID, id, username string) error {
+	if _, err := service.fetchGradeLevel(schoolUUID, id); err != nil {
+		return err
+	}
+
+	counts, err := service.academicRepository.CountStudentsPerGradeLevel(schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	if counts[id] > 0 {
+		return errors.New("the grade level still has students", 409)
+	}
+
+	return service.academicRepository.DeleteGradeLevel(schoolUUID, id, username)
+}
+
+func (service *AcademicService) GetPromotions(schoolUUID string) ([]dto.PromotionResponseDTO, error) {
+	promotions, err := service.academicRepository.FetchPromotions(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	promotionsDTO := []dto.PromotionResponseDTO{}
+	for _, promotion := range promotions {
+		promotionsDTO = append(promotionsDTO, toPromotionDTO(promotion))
+	}
+
+	return promotionsDTO, nil
+}
+
+func (service *AcademicService) PreviewPromotion(schoolUUID string, req dto.PromotionRequestDTO) (dto.PromotionPreviewDTO, error) {
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return dto.PromotionPreviewDTO{}, err
+	}
+	defer tx.Rollback()
+
+	preview, _, err := service.planPromotion(tx, schoolUUID, req)
+	return preview, err
+}
+
+// Moves every student to the next grade level, graduates the final grade together with its shuttles
+// and makes the target year current. The school admin can roll it back until the window closes.
+func (service *AcademicService) ApplyPromotion(schoolUUID string, req dto.PromotionRequestDTO, username string) (dto.PromotionResponseDTO, error) {
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+	defer tx.Rollback()
+
+	preview, items, err := service.planPromotion(tx, schoolUUID, req)
+	if err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	applied, err := service.academicRepository.CountAppliedPromotionsToYear(tx, schoolUUID, req.ToAcademicYearUUID)
+	if err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+	if applied > 0 {
+		return dto.PromotionResponseDTO{}, errors.New("students were already promoted into this academic year", 409)
+	}
+
+	promotionUUID := uuid.New()
+	promotion := entity.GradePromotion{
+		ID:                 time.Now().UnixMilli()*1e6 + int64(promotionUUID.ID()%1e6),
+		UUID:               promotionUUID,
+		SchoolUUID:         uuid.MustParse(schoolUUID),
+		ToAcademicYearUUID: uuid.MustParse(req.ToAcademicYearUUID),
+		Status:             entity.PromotionApplied,
+		PromotedCount:      preview.Promoted,
+		GraduatedCount:     preview.Graduated,
+		AppliedBy:          toNullString(username),
+	}
+
+	current, err := service.academicRepository.FetchCurrentAcademicYear(tx, schoolUUID)
+	if err != nil && err != sql.ErrNoRows {
+		return dto.PromotionResponseDTO{}, err
+	}
+	if err == nil {
+		promotion.FromAcademicYearUUID = uuid.NullUUID{UUID: current.UUID, Valid: true}
+	}
+
+	window := utils.ConfigDuration("PROMOTION_ROLLBACK_WINDOW", 7*24*time.Hour)
+	promotion, err = service.academicRepository.SavePromotion(tx, promotion, int(window.Seconds()))
+	if err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	var promoted []entity.GradePromotionItem
+	var graduated []string
+	for i := range items {
+		items[i].PromotionUUID = promotionUUID
+		if items[i].Graduated {
+			graduated = append(graduated, items[i].StudentUUID.String())
+		} else {
+			promoted = append(promoted, items[i])
+		}
+	}
+
+	if err := service.academicRepository.SavePromotionItems(tx, items); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	if err := service.academicRepository.PromoteStudents(tx, promoted, username); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	if err := service.academicRepository.GraduateStudents(tx, graduated, username); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	if err := service.academicRepository.ClearCurrentAcademicYear(tx, schoolUUID); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	if err := service.academicRepository.SetCurrentAcademicYear(tx, schoolUUID, req.ToAcademicYearUUID); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dto.PromotionResponseDTO{}, err
+	}
+
+	return toPromotionDTO(promotion), nil
+}
+
+// Only the latest promotion can be rolled back, an older one would undo grades a newer one built on
+func (service *AcademicService) RollbackPromotion(schoolUUID, id, username string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("promotion not found", 404)
+	}
+
+	tx, err := service.academicRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	promotion, err := service.academicRepository.FetchSpecPromotion(tx, schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("promotion not found", 404)
+		}
+		return err
+	}
+
+	if promotion.Status != entity.PromotionApplied {
+		return errors.New("the promotion was already rolled back", 409)
+	}
+
+	if time.Now().After(promotion.RollbackUntil) {
+		return errors.New("the rollback window of this promotion has closed", 409)
+	}
+
+	latest, err := service.academicRepository.FetchLatestPromotionUUID(tx, schoolUUID)
+	if err != nil {
+		return err
+	}
+	if latest != promotion.UUID.String() {
+		return errors.New("only the latest promotion can be rolled back", 409)
+	}
+
+	if err := service.academicRepository.RollbackPromotion(tx, id, username); err != nil {
+		return err
+	}
+
+	if err := service.academicRepository.ClearCurrentAcademicYear(tx, schoolUUID); err != nil {
+		return err
+	}
+
+	if promotion.FromAcademicYearUUID.Valid {
+		if err := service.academicRepository.SetCurrentAcademicYear(tx, schoolUUID, promotion.FromAcademicYearUUID.UUID.String()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Works out what a promotion would do without changing anything. Students are matched to a level by
+// their link or, for older records, by the grade text, students matching no level are skipped.
+func (service *AcademicService) planPromotion(tx *sqlx.Tx, schoolUUID string, req dto.PromotionRequestDTO) (dto.PromotionPreviewDTO, []entity.GradePromotionItem, error) {
+	preview := dto.PromotionPreviewDTO{ToAcademicYearUUID: req.ToAcademicYearUUID, Students: []dto.PromotionStudentDTO{}}
+
+	target, err := service.fetchAcademicYear(schoolUUID, req.ToAcademicYearUUID)
+	if err != nil {
+		return preview, nil, err
+	}
+
+	if target.IsCurrent {
+		return preview, nil, errors.New("the target academic year is already the current one", 400)
+	}
+
+	levels, err := service.academicRepository.FetchGradeLevels(schoolUUID)
+	if err != nil {
+		return preview, nil, err
+	}
+
+	if len(levels) == 0 {
+		return preview, nil, errors.New("set up the grade levels of the school before promoting", 400)
+	}
+
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Order < levels[j].Order })
+
+	position := make(map[uuid.UUID]int, len(levels))
+	for i, level := range levels {
+		position[level.UUID] = i
+	}
+
+	candidates, err := service.academicRepository.FetchPromotionCandidates(tx, schoolUUID)
+	if err != nil {
+		return preview, nil, err
+	}
+
+	var items []entity.GradePromotionItem
+	for _, candidate := range candidates {
+		student := dto.PromotionStudentDTO{
+			StudentUUID: candidate.StudentUUID.String(),
+			FirstName:   candidate.FirstName,
+			LastName:    candidate.LastName,
+			FromGrade:   candidate.Grade,
+		}
+
+		index, ok := position[candidate.GradeLevelUUID.UUID]
+		if !candidate.GradeLevelUUID.Valid || !ok {
+			student.Action = "skip"
+			preview.Unmatched++
+			preview.Students = append(preview.Students, student)
+			continue
+		}
+
+		item := entity.GradePromotionItem{
+			StudentUUID:        candidate.StudentUUID,
+			FromGrade:          candidate.Grade,
+			FromGradeLevelUUID: candidate.GradeLevelUUID,
+		}
+
+		if index == len(levels)-1 {
+			item.Graduated = true
+			student.Action = "graduate"
+			preview.Graduated++
+		} else {
+			next := levels[index+1]
+			item.ToGrade = toNullString(next.Name)
+			item.ToGradeLevelUUID = uuid.NullUUID{UUID: next.UUID, Valid: true}
+			student.ToGrade = next.Name
+			student.Action = "promote"
+			preview.Promoted++
+		}
+
+		items = append(items, item)
+		preview.Students = append(preview.Students, student)
+	}
+
+	return preview, items, nil
+}
+
+func (service *AcademicService) fetchAcademicYear(schoolUUID, id string) (entity.AcademicYear, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.AcademicYear{}, errors.New("academic year not found", 404)
+	}
+
+	year, err := service.academicRepository.FetchSpecAcademicYear(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.AcademicYear{}, errors.New("academic year not found", 404)
+		}
+		return entity.AcademicYear{}, err
+	}
+
+	return year, nil
+}
+
+func (service *AcademicService) fetchGradeLevel(schoolUUID, id string) (entity.GradeLevel, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.GradeLevel{}, errors.New("grade level not found", 404)
+	}
+
+	level, err := service.academicRepository.FetchSpecGradeLevel(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.GradeLevel{}, errors.New("grade level not found", 404)
+		}
+		return entity.GradeLevel{}, err
+	}
+
+	return level, nil
+}
+
+func (service *AcademicService) checkGradeLevelConflict(schoolUUID, id string, req dto.GradeLevelRequestDTO) error {
+	levels, err := service.academicRepository.FetchGradeLevels(schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, level := range levels {
+		if level.UUID.String() == id {
+			continue
+		}
+		if strings.EqualFold(level.Name, req.Name) {
+			return errors.New("a grade level with this name already exists", 409)
+		}
+		if level.Order == req.Order {
+			return errors.New("another grade level already has this order", 409)
+		}
+	}
+
+	return nil
+}
+
+func toAcademicYearEntity(yearUUID uuid.UUID, schoolUUID string, req dto.AcademicYearRequestDTO) (entity.AcademicYear, error) {
+	startsOn, endsOn, err := parseAcademicPeriod(req.StartsOn, req.EndsOn)
+	if err != nil {
+		return entity.AcademicYear{}, err
+	}
+
+	year := entity.AcademicYear{
+		UUID:       yearUUID,
+		SchoolUUID: uuid.MustParse(schoolUUID),
+		Name:       req.Name,
+		StartsOn:   startsOn,
+		EndsOn:     endsOn,
+		IsCurrent:  req.IsCurrent,
+	}
+
+	for _, termReq := range req.Terms {
+		termStartsOn, termEndsOn, err := parseAcademicPeriod(termReq.StartsOn, termReq.EndsOn)
+		if err != nil {
+			return entity.AcademicYear{}, err
+		}
+
+		if termStartsOn.Before(startsOn) || termEndsOn.After(endsOn) {
+			return entity.AcademicYear{}, errors.New("term "+termReq.Name+" must fall within the academic year", 400)
+		}
+
+		termUUID := uuid.New()
+		year.Terms = append(year.Terms, entity.AcademicTerm{
+			ID:               time.Now().UnixMilli()*1e6 + int64(termUUID.ID()%1e6),
+			UUID:             termUUID,
+			AcademicYearUUID: yearUUID,
+			Name:             termReq.Name,
+			StartsOn:         termStartsOn,
+			EndsOn:           termEndsOn,
+		})
+	}
+
+	sort.Slice(year.Terms, func(i, j int) bool { return year.Terms[i].StartsOn.Before(year.Terms[j].StartsOn) })
+	for i := 1; i < len(year.Terms); i++ {
+		if !year.Terms[i].StartsOn.After(year.Terms[i-1].EndsOn) {
+			return entity.AcademicYear{}, errors.New("terms "+year.Terms[i-1].Name+" and "+year.Terms[i].Name+" overlap", 400)
+		}
+	}
+
+	return year, nil
+}
+
+func parseAcademicPeriod(startsOn, endsOn string) (time.Time, time.Time, error) {
+	start, err := time.Parse(academicDateLayout, startsOn)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("starts_on must be a date like 2025-07-14", 400)
+	}
+
+	end, err := time.Parse(academicDateLayout, endsOn)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("ends_on must be a date like 2026-06-20", 400)
+	}
+
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("ends_on must be after starts_on", 400)
+	}
+
+	return start, end, nil
+}
+
+func toAcademicYearDTO(year entity.AcademicYear) dto.AcademicYearResponseDTO {
+	yearDTO := dto.AcademicYearResponseDTO{
+		UUID:      year.UUID.String(),
+		Name:      year.Name,
+		StartsOn:  year.StartsOn.Format(academicDateLayout),
+		EndsOn:    year.EndsOn.Format(academicDateLayout),
+		IsCurrent: year.IsCurrent,
+		Terms:     []dto.AcademicTermResponseDTO{},
+		CreatedAt: safeTimeFormat(year.CreatedAt),
+		CreatedBy: safeStringFormat(year.CreatedBy),
+		UpdatedAt: safeTimeFormat(year.UpdatedAt),
+		UpdatedBy: safeStringFormat(year.UpdatedBy),
+	}
+
+	for _, term := range year.Terms {
+		yearDTO.Terms = append(yearDTO.Terms, dto.AcademicTermResponseDTO{
+			UUID:     term.UUID.String(),
+			Name:     term.Name,
+			StartsOn: term.StartsOn.Format(academicDateLayout),
+			EndsOn:   term.EndsOn.Format(academicDateLayout),
+		})
+	}
+
+	return yearDTO
+}
+
+func toPromotionDTO(promotion entity.GradePromotion) dto.PromotionResponseDTO {
+	promotionDTO := dto.PromotionResponseDTO{
+		UUID:               promotion.UUID.String(),
+		ToAcademicYearUUID: promotion.ToAcademicYearUUID.String(),
+		Status:             string(promotion.Status),
+		Promoted:           promotion.PromotedCount,
+		Graduated:          promotion.GraduatedCount,
+		AppliedAt:          promotion.AppliedAt.Format(time.RFC3339),
+		AppliedBy:          safeStringFormat(promotion.AppliedBy),
+		RollbackUntil:      promotion.RollbackUntil.Format(time.RFC3339),
+		CanRollback:        promotion.Status == entity.PromotionApplied && time.Now().Before(promotion.RollbackUntil),
+		RolledBackAt:       safeTimeFormat(promotion.RolledBackAt),
+		RolledBackBy:       safeStringFormat(promotion.RolledBackBy),
+	}
+
+	if promotion.FromAcademicYearUUID.Valid {
+		promotionDTO.FromAcademicYearUUID = promotion.FromAcademicYearUUID.UUID.String()
+	}
+
+	return promotionDTO
+}
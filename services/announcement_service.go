@@ -0,0 +1,434 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Recipients notified at a time, a publish job that stops halfway carries on with the rest
+const announcementNotifyBatch = 500
+
+type AnnouncementServiceInterface interface {
+	GetAnnouncements(schoolUUID string, page, limit int, status string) ([]dto.AnnouncementResponseDTO, int, error)
+	GetSpecAnnouncement(schoolUUID, id string) (dto.AnnouncementResponseDTO, error)
+	AddAnnouncement(schoolUUID string, req dto.AnnouncementRequestDTO, username string) (dto.AnnouncementResponseDTO, error)
+	UpdateAnnouncement(schoolUUID, id string, req dto.AnnouncementRequestDTO, username string) error
+	DeleteAnnouncement(schoolUUID, id, username string) error
+
+	GetMyAnnouncements(userUUID string, page, limit int) ([]dto.MyAnnouncementResponseDTO, int, error)
+	MarkRead(userUUID, id string) error
+
+	PublishAnnouncement(payload json.RawMessage) error
+}
+
+type AnnouncementService struct {
+	announcementRepository repositories.AnnouncementRepositoryInterface
+	jobService             JobServiceInterface
+	notificationService    NotificationServiceInterface
+}
+
+type announcementPublishJob struct {
+	AnnouncementUUID string `json:"announcement_uuid"`
+}
+
+func NewAnnouncementService(announcementRepository repositories.AnnouncementRepositoryInterface, jobService JobServiceInterface, notificationService NotificationServiceInterface) AnnouncementService {
+	return AnnouncementService{
+		announcementRepository: announcementRepository,
+		jobService:             jobService,
+		notificationService:    notificationService,
+	}
+}
+
+// Empty status lists every announcement, newest first
+func (service *AnnouncementService) GetAnnouncements(schoolUUID string, page, limit int, status string) ([]dto.AnnouncementResponseDTO, int, error) {
+	switch status {
+	case "", entity.AnnouncementScheduled, entity.AnnouncementPublished, entity.AnnouncementExpired:
+	default:
+		return nil, 0, errors.New("invalid status, use 'scheduled', 'published' or 'expired'", 400)
+	}
+
+	offset := (page - 1) * limit
+
+	announcements, err := service.announcementRepository.FetchAnnouncements(schoolUUID, offset, limit, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.announcementRepository.CountAnnouncements(schoolUUID, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	announcementsDTO := []dto.AnnouncementResponseDTO{}
+	for _, announcement := range announcements {
+		announcementsDTO = append(announcementsDTO, toAnnouncementDTO(announcement))
+	}
+
+	return announcementsDTO, total, nil
+}
+
+func (service *AnnouncementService) GetSpecAnnouncement(schoolUUID, id string) (dto.AnnouncementResponseDTO, error) {
+	announcement, err := service.fetchAnnouncement(schoolUUID, id)
+	if err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+
+	return toAnnouncementDTO(announcement), nil
+}
+
+// The announcement is published by an announcement_publish job at publish_at
+func (service *AnnouncementService) AddAnnouncement(schoolUUID string, req dto.AnnouncementRequestDTO, username string) (_ dto.AnnouncementResponseDTO, err error) {
+	announcement, err := service.toAnnouncementEntity(schoolUUID, req)
+	if err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+
+	announcement.UUID = uuid.New()
+	announcement.ID = time.Now().UnixMilli()*1e6 + int64(announcement.UUID.ID()%1e6)
+	announcement.CreatedBy = toNullString(username)
+
+	tx, err := service.announcementRepository.BeginTransaction()
+	if err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = service.announcementRepository.SaveAnnouncement(tx, announcement); err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+
+	if err = service.enqueuePublish(tx, announcement); err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return dto.AnnouncementResponseDTO{}, err
+	}
+
+	return toAnnouncementDTO(announcement), nil
+}
+
+// Only announcements that have not been published yet can be changed. The job queued for the old
+// publish_at finds it moved and leaves it to the one queued here.
+func (service *AnnouncementService) UpdateAnnouncement(schoolUUID, id string, req dto.AnnouncementRequestDTO, username string) (err error) {
+	existing, err := service.fetchAnnouncement(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+	if existing.PublishedAt.Valid {
+		return errors.New("the announcement has already been published", 409)
+	}
+
+	announcement, err := service.toAnnouncementEntity(schoolUUID, req)
+	if err != nil {
+		return err
+	}
+
+	announcement.UUID = existing.UUID
+	announcement.UpdatedBy = toNullString(username)
+
+	tx, err := service.announcementRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	updated, err := service.announcementRepository.UpdateAnnouncement(tx, announcement)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New("the announcement has already been published", 409)
+	}
+
+	if err = service.enqueuePublish(tx, announcement); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Takes the announcement down for the recipients, or keeps it from being published
+func (service *AnnouncementService) DeleteAnnouncement(schoolUUID, id, username string) error {
+	if _, err := service.fetchAnnouncement(schoolUUID, id); err != nil {
+		return err
+	}
+
+	return service.announcementRepository.DeleteAnnouncement(schoolUUID, id, username)
+}
+
+// The announcements sent to the user that have not expired, newest first
+func (service *AnnouncementService) GetMyAnnouncements(userUUID string, page, limit int) ([]dto.MyAnnouncementResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	announcements, err := service.announcementRepository.FetchMyAnnouncements(userUUID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.announcementRepository.CountMyAnnouncements(userUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	announcementsDTO := []dto.MyAnnouncementResponseDTO{}
+	for _, announcement := range announcements {
+		announcementDTO := dto.MyAnnouncementResponseDTO{
+			UUID:        announcement.UUID.String(),
+			Title:       announcement.Title,
+			Body:        announcement.Body,
+			PublishedAt: safeTimeFormat(announcement.PublishedAt),
+			Read:        announcement.ReadAt.Valid,
+		}
+		if announcement.ExpiresAt.Valid {
+			announcementDTO.ExpiresAt = announcement.ExpiresAt.Time.Format(time.RFC3339)
+		}
+		if announcement.ReadAt.Valid {
+			announcementDTO.ReadAt = announcement.ReadAt.Time.Format(time.RFC3339)
+		}
+		announcementsDTO = append(announcementsDTO, announcementDTO)
+	}
+
+	return announcementsDTO, total, nil
+}
+
+func (service *AnnouncementService) MarkRead(userUUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("announcement not found", 404)
+	}
+
+	found, err := service.announcementRepository.MarkAnnouncementRead(userUUID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("announcement not found", 404)
+	}
+
+	return nil
+}
+
+// Runs the announcement_publish job. The recipients are taken when the announcement is published and
+// notified in batches, a retry only notifies those that were not notified yet.
+func (service *AnnouncementService) PublishAnnouncement(payload json.RawMessage) error {
+	var job announcementPublishJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	announcement, deliver, err := service.publish(job.AnnouncementUUID)
+	if err != nil || !deliver {
+		return err
+	}
+
+	data := map[string]string{
+		"announcement_uuid": announcement.UUID.String(),
+		"title":             announcement.Title,
+		"body":              announcement.Body,
+	}
+
+	var lastErr error
+	for {
+		recipients, err := service.announcementRepository.FetchPendingRecipients(job.AnnouncementUUID, announcementNotifyBatch)
+		if err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return lastErr
+		}
+
+		// Notify carries on past a recipient that fails, so the batch counts as notified either way
+		if err := service.notificationService.Notify(entity.NotificationSchoolAnnouncement, recipients, data); err != nil {
+			logger.LogError(err, "Failed to notify announcement recipients", map[string]interface{}{"announcement_uuid": job.AnnouncementUUID})
+			lastErr = err
+		}
+
+		if err := service.announcementRepository.MarkRecipientsNotified(job.AnnouncementUUID, recipients); err != nil {
+			return err
+		}
+	}
+}
+
+// Publishes the announcement when it is due, false means there is nothing to deliver: it was deleted,
+// moved to a later publish_at or expired before it went out
+func (service *AnnouncementService) publish(id string) (_ entity.Announcement, _ bool, err error) {
+	tx, err := service.announcementRepository.BeginTransaction()
+	if err != nil {
+		return entity.Announcement{}, false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	announcement, err := service.announcementRepository.FetchAnnouncementForPublish(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Announcement{}, false, tx.Rollback()
+		}
+		return entity.Announcement{}, false, err
+	}
+
+	now := time.Now()
+	if announcement.DeletedAt.Valid || (announcement.ExpiresAt.Valid && !announcement.ExpiresAt.Time.After(now)) {
+		return entity.Announcement{}, false, tx.Rollback()
+	}
+
+	if !announcement.PublishedAt.Valid {
+		if announcement.PublishAt.After(now) {
+			return entity.Announcement{}, false, tx.Rollback()
+		}
+
+		if err = service.announcementRepository.SaveRecipients(tx, announcement); err != nil {
+			return entity.Announcement{}, false, err
+		}
+
+		if err = service.announcementRepository.MarkPublished(tx, id); err != nil {
+			return entity.Announcement{}, false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.Announcement{}, false, err
+	}
+
+	return announcement, true, nil
+}
+
+func (service *AnnouncementService) enqueuePublish(tx *sqlx.Tx, announcement entity.Announcement) error {
+	return service.jobService.Enqueue(tx, "announcement_publish", announcementPublishJob{AnnouncementUUID: announcement.UUID.String()}, announcement.PublishAt, "system")
+}
+
+func (service *AnnouncementService) fetchAnnouncement(schoolUUID, id string) (entity.Announcement, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.Announcement{}, errors.New("announcement not found", 404)
+	}
+
+	announcement, err := service.announcementRepository.FetchSpecAnnouncement(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Announcement{}, errors.New("announcement not found", 404)
+		}
+		return entity.Announcement{}, err
+	}
+
+	return announcement, nil
+}
+
+// publish_at defaults to now and a time in the past is taken as now
+func (service *AnnouncementService) toAnnouncementEntity(schoolUUID string, req dto.AnnouncementRequestDTO) (entity.Announcement, error) {
+	now := time.Now()
+
+	announcement := entity.Announcement{
+		SchoolUUID: uuid.MustParse(schoolUUID),
+		TargetType: req.TargetType,
+		Title:      req.Title,
+		Body:       req.Body,
+		PublishAt:  now,
+	}
+
+	if req.TargetType == entity.AnnouncementTargetSchool {
+		if req.TargetUUID != "" {
+			return entity.Announcement{}, errors.New("target_uuid must be left out for the whole school", 400)
+		}
+	} else {
+		if req.TargetUUID == "" {
+			return entity.Announcement{}, errors.New("target_uuid is required for a "+req.TargetType, 400)
+		}
+
+		exists, err := service.announcementRepository.CheckTargetExists(schoolUUID, req.TargetType, req.TargetUUID)
+		if err != nil {
+			return entity.Announcement{}, err
+		}
+		if !exists {
+			return entity.Announcement{}, errors.New(req.TargetType+" not found", 404)
+		}
+
+		announcement.TargetUUID = uuid.NullUUID{UUID: uuid.MustParse(req.TargetUUID), Valid: true}
+	}
+
+	if req.PublishAt != "" {
+		publishAt, err := time.Parse(time.RFC3339, req.PublishAt)
+		if err != nil {
+			return entity.Announcement{}, errors.New("publish_at must be a timestamp like 2025-01-31T07:00:00+07:00", 400)
+		}
+		if publishAt.After(now) {
+			announcement.PublishAt = publishAt
+		}
+	}
+
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return entity.Announcement{}, errors.New("expires_at must be a timestamp like 2025-01-31T17:00:00+07:00", 400)
+		}
+		if !expiresAt.After(announcement.PublishAt) {
+			return entity.Announcement{}, errors.New("expires_at must be after publish_at", 400)
+		}
+		announcement.ExpiresAt = sql.NullTime{Time: expiresAt, Valid: true}
+	}
+
+	return announcement, nil
+}
+
+func announcementStatus(announcement entity.Announcement) string {
+	switch {
+	case announcement.ExpiresAt.Valid && !announcement.ExpiresAt.Time.After(time.Now()):
+		return entity.AnnouncementExpired
+	case announcement.PublishedAt.Valid:
+		return entity.AnnouncementPublished
+	default:
+		return entity.AnnouncementScheduled
+	}
+}
+
+func toAnnouncementDTO(announcement entity.Announcement) dto.AnnouncementResponseDTO {
+	announcementDTO := dto.AnnouncementResponseDTO{
+		UUID:           announcement.UUID.String(),
+		TargetType:     announcement.TargetType,
+		Title:          announcement.Title,
+		Body:           announcement.Body,
+		Status:         announcementStatus(announcement),
+		PublishAt:      announcement.PublishAt.Format(time.RFC3339),
+		RecipientCount: announcement.RecipientCount,
+		ReadCount:      announcement.ReadCount,
+		CreatedAt:      safeTimeFormat(announcement.CreatedAt),
+		CreatedBy:      safeStringFormat(announcement.CreatedBy),
+		UpdatedAt:      safeTimeFormat(announcement.UpdatedAt),
+		UpdatedBy:      safeStringFormat(announcement.UpdatedBy),
+	}
+	if announcement.TargetUUID.Valid {
+		announcementDTO.TargetUUID = announcement.TargetUUID.UUID.String()
+	}
+	if announcement.ExpiresAt.Valid {
+		announcementDTO.ExpiresAt = announcement.ExpiresAt.Time.Format(time.RFC3339)
+	}
+	if announcement.PublishedAt.Valid {
+		announcementDTO.PublishedAt = announcement.PublishedAt.Time.Format(time.RFC3339)
+	}
+	if announcement.RecipientCount > 0 {
+		announcementDTO.ReadRate = math.Round(float64(announcement.ReadCount)/float64(announcement.RecipientCount)*1000) / 1000
+	}
+
+	return announcementDTO
+}
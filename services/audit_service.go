@@ -0,0 +1,318 @@
+package services
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns left out of the before and after of an entry. Secrets never belong in the log and the
+// bookkeeping columns change on every write, the entry itself already says who and when.
+var auditIgnoredColumns = map[string]bool{
+	"user_password": true,
+	"created_at":    true,
+	"created_by":    true,
+	"updated_at":    true,
+	"updated_by":    true,
+	"deleted_at":    true,
+	"deleted_by":    true,
+}
+
+type AuditServiceInterface interface {
+	Record(actor entity.AuditActor, action, entityType, entityUUID string, before, after interface{})
+	RecordTx(tx *sqlx.Tx, actor entity.AuditActor, action, entityType, entityUUID string, before, after interface{}) error
+	GetAuditLogs(query dto.AuditLogQueryDTO, page, limit int) ([]dto.AuditLogResponseDTO, int, error)
+}
+
+type AuditService struct {
+	auditRepository repositories.AuditRepositoryInterface
+}
+
+func NewAuditService(auditRepository repositories.AuditRepositoryInterface) AuditService {
+	return AuditService{
+		auditRepository: auditRepository,
+	}
+}
+
+// Writes an entry for a change that is already committed. before is nil for a create and after is
+// nil for a delete, an update only keeps the columns that changed. Failing to write the entry is
+// logged rather than returned since the change itself went through, use RecordTx where the change
+// runs in a transaction so the two cannot get apart.
+func (service *AuditService) Record(actor entity.AuditActor, action, entityType, entityUUID string, before, after interface{}) {
+	if err := service.save(nil, actor, action, entityType, entityUUID, before, after); err != nil {
+		logger.LogError(err, "Failed to save audit log", map[string]interface{}{
+			"action":      action,
+			"entity_type": entityType,
+			"entity_uuid": entityUUID,
+			"actor_uuid":  actor.UserUUID,
+		})
+	}
+}
+
+// Writes the entry in the transaction of the change, the caller rolls the change back when it fails
+func (service *AuditService) RecordTx(tx *sqlx.Tx, actor entity.AuditActor, action, entityType, entityUUID string, before, after interface{}) error {
+	if err := service.save(tx, actor, action, entityType, entityUUID, before, after); err != nil {
+		return fmt.Errorf("error saving audit log: %w", err)
+	}
+	return nil
+}
+
+func (service *AuditService) save(tx *sqlx.Tx, actor entity.AuditActor, action, entityType, entityUUID string, before, after interface{}) error {
+	beforeData, afterData := auditSnapshot(before), auditSnapshot(after)
+	if before != nil && after != nil {
+		beforeData, afterData = auditDiff(beforeData, afterData)
+	}
+
+	beforeJSON, err := json.Marshal(beforeData)
+	if err != nil {
+		return err
+	}
+
+	afterJSON, err := json.Marshal(afterData)
+	if err != nil {
+		return err
+	}
+
+	if len(actor.RequestID) > 64 {
+		actor.RequestID = actor.RequestID[:64]
+	}
+	if len(actor.UserAgent) > 512 {
+		actor.UserAgent = actor.UserAgent[:512]
+	}
+
+	auditUUID := uuid.New()
+	log := entity.AuditLog{
+		ID:               time.Now().UnixMilli()*1e6 + int64(auditUUID.ID()%1e6),
+		UUID:             auditUUID,
+		ActorUUID:        toNullUUID(actor.UserUUID),
+		ActorRoleCode:    toNullString(actor.RoleCode),
+		ActorUsername:    toNullString(actor.Username),
+		ImpersonatorUUID: toNullUUID(actor.ImpersonatorUUID),
+		Action:           action,
+		EntityType:       entityType,
+		EntityUUID:       toNullUUID(entityUUID),
+		Before:           beforeJSON,
+		After:            afterJSON,
+		IPAddress:        toNullString(actor.IPAddress),
+		UserAgent:        toNullString(actor.UserAgent),
+		RequestID:        toNullString(actor.RequestID),
+	}
+
+	return service.auditRepository.SaveAuditLog(tx, log)
+}
+
+func (service *AuditService) GetAuditLogs(query dto.AuditLogQueryDTO, page, limit int) ([]dto.AuditLogResponseDTO, int, error) {
+	filter := entity.AuditLogFilter{
+		EntityType: query.EntityType,
+		Action:     query.Action,
+	}
+
+	if query.EntityUUID != "" {
+		if _, err := uuid.Parse(query.EntityUUID); err != nil {
+			return nil, 0, errors.New("invalid entity_uuid", 400)
+		}
+		filter.EntityUUID = query.EntityUUID
+	}
+
+	if query.ActorUUID != "" {
+		if _, err := uuid.Parse(query.ActorUUID); err != nil {
+			return nil, 0, errors.New("invalid actor_uuid", 400)
+		}
+		filter.ActorUUID = query.ActorUUID
+	}
+
+	if query.From != "" {
+		from, err := time.Parse(time.DateOnly, query.From)
+		if err != nil {
+			return nil, 0, errors.New("invalid from date, use YYYY-MM-DD", 400)
+		}
+		filter.From = from
+	}
+
+	if query.To != "" {
+		to, err := time.Parse(time.DateOnly, query.To)
+		if err != nil {
+			return nil, 0, errors.New("invalid to date, use YYYY-MM-DD", 400)
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, 0, errors.New("from date must not be after to date", 400)
+	}
+
+	offset := (page - 1) * limit
+
+	logs, err := service.auditRepository.FetchAuditLogs(filter, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.auditRepository.CountAuditLogs(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logsDTO := []dto.AuditLogResponseDTO{}
+	for _, log := range logs {
+		logsDTO = append(logsDTO, toAuditLogDTO(log))
+	}
+
+	return logsDTO, total, nil
+}
+
+func toAuditLogDTO(log entity.AuditLog) dto.AuditLogResponseDTO {
+	auditDTO := dto.AuditLogResponseDTO{
+		UUID:          log.UUID.String(),
+		ActorRoleCode: log.ActorRoleCode.String,
+		ActorUsername: log.ActorUsername.String,
+		Action:        log.Action,
+		EntityType:    log.EntityType,
+		Before:        log.Before,
+		After:         log.After,
+		IPAddress:     log.IPAddress.String,
+		UserAgent:     log.UserAgent.String,
+		RequestID:     log.RequestID.String,
+		CreatedAt:     log.CreatedAt.Format(time.RFC3339),
+	}
+
+	if log.ActorUUID.Valid {
+		auditDTO.ActorUUID = log.ActorUUID.UUID.String()
+	}
+	if log.ImpersonatorUUID.Valid {
+		auditDTO.ImpersonatorUUID = log.ImpersonatorUUID.UUID.String()
+	}
+	if log.EntityUUID.Valid {
+		auditDTO.EntityUUID = log.EntityUUID.UUID.String()
+	}
+
+	return auditDTO
+}
+
+func toNullUUID(value string) uuid.NullUUID {
+	parsed, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: parsed, Valid: true}
+}
+
+// Only the columns that differ between before and after are kept on both sides
+func auditDiff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+
+	for column, value := range after {
+		if previous, ok := before[column]; !ok || !reflect.DeepEqual(previous, value) {
+			if ok {
+				changedBefore[column] = previous
+			}
+			changedAfter[column] = value
+		}
+	}
+
+	for column, value := range before {
+		if _, ok := after[column]; !ok {
+			changedBefore[column] = value
+		}
+	}
+
+	return changedBefore, changedAfter
+}
+
+// Flattens an entity into its columns, named by their db tags. Untagged fields such as embedded
+// structs and user details are flattened into the same level.
+func auditSnapshot(data interface{}) map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	if data != nil {
+		flattenAuditValue(reflect.ValueOf(data), snapshot)
+	}
+	return snapshot
+}
+
+func flattenAuditValue(value reflect.Value, snapshot map[string]interface{}) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			column, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+			if column == "-" || auditIgnoredColumns[column] {
+				continue
+			}
+			if column == "" {
+				flattenAuditValue(value.Field(i), snapshot)
+				continue
+			}
+
+			// Details are flattened after the user's own columns, their user_uuid must not replace the user's
+			if _, exists := snapshot[column]; !exists {
+				snapshot[column] = auditValue(value.Field(i))
+			}
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range value.MapKeys() {
+			if !auditIgnoredColumns[key.String()] {
+				snapshot[key.String()] = auditValue(value.MapIndex(key))
+			}
+		}
+	}
+}
+
+// Turns a column into something that reads well as JSON, nulls and pointers are unwrapped
+// and times are written as RFC3339
+func auditValue(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	data := value.Interface()
+	if valuer, ok := data.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		data = driverValue
+	}
+
+	switch v := data.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	case sql.RawBytes:
+		return string(v)
+	}
+
+	return data
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     map[string]interface{}
+		after      map[string]interface{}
+		wantBefore map[string]interface{}
+		wantAfter  map[string]interface{}
+	}{
+		{
+			name:       "create keeps every column",
+			before:     map[string]interface{}{},
+			after:      map[string]interface{}{"student_grade": "3", "student_gender": "female"},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{"student_grade": "3", "student_gender": "female"},
+		},
+		{
+			name:       "delete keeps every column",
+			before:     map[string]interface{}{"student_grade": "3", "student_gender": "female"},
+			after:      map[string]interface{}{},
+			wantBefore: map[string]interface{}{"student_grade": "3", "student_gender": "female"},
+			wantAfter:  map[string]interface{}{},
+		},
+		{
+			name:       "update keeps only the changed columns",
+			before:     map[string]interface{}{"student_grade": "3", "student_gender": "female", "student_first_name": "Siti"},
+			after:      map[string]interface{}{"student_grade": "4", "student_gender": "female", "student_first_name": "Siti"},
+			wantBefore: map[string]interface{}{"student_grade": "3"},
+			wantAfter:  map[string]interface{}{"student_grade": "4"},
+		},
+		{
+			name:       "nothing changed",
+			before:     map[string]interface{}{"student_grade": "3", "tags": []string{"a"}},
+			after:      map[string]interface{}{"student_grade": "3", "tags": []string{"a"}},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{},
+		},
+		{
+			name:       "value set from null",
+			before:     map[string]interface{}{"user_address": nil},
+			after:      map[string]interface{}{"user_address": "Jl. Merdeka 1"},
+			wantBefore: map[string]interface{}{"user_address": nil},
+			wantAfter:  map[string]interface{}{"user_address": "Jl. Merdeka 1"},
+		},
+		{
+			name:       "column added and removed",
+			before:     map[string]interface{}{"vehicle_uuid": "a"},
+			after:      map[string]interface{}{"license_number": "B 1234"},
+			wantBefore: map[string]interface{}{"vehicle_uuid": "a"},
+			wantAfter:  map[string]interface{}{"license_number": "B 1234"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBefore, gotAfter := auditDiff(tt.before, tt.after)
+			if !reflect.DeepEqual(gotBefore, tt.wantBefore) {
+				t.Errorf("before = %v, want %v", gotBefore, tt.wantBefore)
+			}
+			if !reflect.DeepEqual(gotAfter, tt.wantAfter) {
+				t.Errorf("after = %v, want %v", gotAfter, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestAuditSnapshot(t *testing.T) {
+	userUUID := uuid.MustParse("6f1c2a8e-1b5e-4f0e-9a3c-1d2e3f405060")
+	lastActive := time.Date(2026, 3, 2, 7, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		data interface{}
+		want map[string]interface{}
+	}{
+		{name: "nil", data: nil, want: map[string]interface{}{}},
+		{
+			name: "user with details, without password and bookkeeping columns",
+			data: &entity.User{
+				UUID:       userUUID,
+				Username:   "siti",
+				Password:   "hash",
+				Role:       entity.Parent,
+				LastActive: sql.NullTime{Time: lastActive, Valid: true},
+				CreatedBy:  sql.NullString{String: "admin", Valid: true},
+				Details:    entity.ParentDetails{Phone: "0812"},
+			},
+			want: map[string]interface{}{
+				"user_id":              int64(0),
+				"user_uuid":            userUUID.String(),
+				"user_username":        "siti",
+				"user_email":           "",
+				"user_role":            entity.Parent,
+				"user_role_code":       "",
+				"user_status":          "",
+				"user_last_active":     "2026-03-02T07:30:00Z",
+				"created_with_student": false,
+				"user_picture":         "",
+				"user_first_name":      "",
+				"user_last_name":       "",
+				"user_gender":          entity.Gender(""),
+				"user_phone":           "0812",
+				"user_address":         "",
+			},
+		},
+		{
+			name: "map of columns",
+			data: map[string]interface{}{"student_grade": "3", "updated_by": "admin"},
+			want: map[string]interface{}{"student_grade": "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := auditSnapshot(tt.data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditSnapshot() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeAuditRepository struct {
+	repositories.AuditRepositoryInterface
+	err  error
+	logs []entity.AuditLog
+}
+
+func (r *fakeAuditRepository) SaveAuditLog(tx *sqlx.Tx, log entity.AuditLog) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+// A failed write inside the change's transaction is returned so the change is rolled back with it
+func TestRecordTx(t *testing.T) {
+	before := entity.Shuttle{ShuttleUUID: uuid.New(), Status: "menunggu"}
+	after := before
+	after.Status = "di jalan"
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "entry is saved"},
+		{name: "write fails", err: sql.ErrConnDone, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeAuditRepository{err: tt.err}
+			service := NewAuditService(repository)
+
+			err := service.RecordTx(nil, entity.AuditActor{Username: "driver"}, entity.AuditUpdate, entity.AuditEntityShuttle, before.ShuttleUUID.String(), before, after)
+			if tt.wantErr {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("RecordTx() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RecordTx() error = %v", err)
+			}
+			if len(repository.logs) != 1 || string(repository.logs[0].After) != `{"status":"di jalan"}` {
+				t.Errorf("saved logs = %+v, want one entry with the new status", repository.logs)
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"shuttle/errors"
 	"shuttle/logger"
 	"shuttle/models/dto"
+	"shuttle/models/entity"
 	"shuttle/repositories"
+	"shuttle/utils"
 
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
@@ -16,33 +23,49 @@ import (
 )
 
 type AuthServiceInterface interface {
-	Login(email, password string) (userDataa dto.UserDataOnLoginDTO, err error)
+	Login(email, password, ipAddress string) (userDataa dto.UserDataOnLoginDTO, retryAfter time.Duration, err error)
+	UnlockAccount(userUUID, unlockedBy string) error
 	GetMyProfile(userUUID, roleCode string) (interface{}, error)
 	CheckStoredRefreshToken(userID string, refreshToken string) error
 	DeleteRefreshTokenOnLogout(ctx context.Context, userID string) error
 	UpdateUserStatus(userUUID, status string, lastActive time.Time) error
 	UpdateRefreshToken(userUUID, refreshToken string) error
+	CheckLoginOTPThrottle(phone, ipAddress string) (time.Duration, error)
+	RequestLoginOTP(phone, ipAddress string) (time.Duration, error)
+	VerifyLoginOTP(phone, code string) (dto.UserDataOnLoginDTO, error)
+	CleanupExpiredTokens() error
 }
 
 type AuthService struct {
 	authRepository repositories.AuthRepositoryInterface
 	userRepository repositories.UserRepositoryInterface
+	smsSender      utils.SMSSender
 }
 
-func NewAuthService(authRepository repositories.AuthRepositoryInterface, userRepository repositories.UserRepositoryInterface) AuthService {
+func NewAuthService(authRepository repositories.AuthRepositoryInterface, userRepository repositories.UserRepositoryInterface, smsSender utils.SMSSender) AuthService {
 	return AuthService{
 		authRepository: authRepository,
 		userRepository: userRepository,
+		smsSender:      smsSender,
 	}
 }
 
-func (service AuthService) Login(email, password string) (userData dto.UserDataOnLoginDTO, err error) {
+// A non-zero retryAfter means the attempt was refused because the account or the IP is locked or
+// backing off, the password was not checked then
+func (service AuthService) Login(email, password, ipAddress string) (userData dto.UserDataOnLoginDTO, retryAfter time.Duration, err error) {
+	scopes := loginScopes(email, ipAddress)
+	attempts, retryAfter, err := service.reserveLoginAttempts(scopes)
+	if err != nil || retryAfter > 0 {
+		return dto.UserDataOnLoginDTO{}, retryAfter, err
+	}
+
 	user, err := service.authRepository.Login(email)
 	if err != nil {
 		logger.LogError(err, "Failed to login", map[string]interface{}{
 			"email": email,
 		})
-		return dto.UserDataOnLoginDTO{}, errors.New("invalid email or password", 0)
+		service.registerFailedLogin(attempts, ipAddress, nil)
+		return dto.UserDataOnLoginDTO{}, 0, errors.New("invalid email or password", 0)
 	}
 
 	userDataOnLogin := dto.UserDataOnLoginDTO{
@@ -54,10 +77,124 @@ func (service AuthService) Login(email, password string) (userData dto.UserDataO
 	}
 
 	if !validatePassword(password, userDataOnLogin.Password) {
-		return dto.UserDataOnLoginDTO{}, errors.New("invalid email or password", 0)
+		parsedUserUUID, _ := uuid.Parse(user.UUID)
+		service.registerFailedLogin(attempts, ipAddress, &parsedUserUUID)
+		return dto.UserDataOnLoginDTO{}, 0, errors.New("invalid email or password", 0)
 	}
 
-	return userDataOnLogin, nil
+	// The account starts over, the IP only gets this attempt back. Clearing the IP as well would
+	// let anyone holding one valid account reset the limit between guesses at other accounts.
+	for _, scope := range scopes {
+		var err error
+		if scope.name == loginScopeAccount {
+			err = service.authRepository.ResetLoginAttempts(scope.name, scope.key)
+		} else {
+			err = service.authRepository.ReleaseLoginAttempt(scope.name, scope.key, scope.maxAttempts)
+		}
+		if err != nil {
+			logger.LogError(err, "Failed to reset login attempts", map[string]interface{}{
+				"scope": scope.name,
+				"key":   scope.key,
+			})
+		}
+	}
+
+	return userDataOnLogin, 0, nil
+}
+
+// Counts the attempt for the account and the IP before the password is checked. When one of them
+// refuses it, the others get their attempt back and the longest wait is returned.
+func (service *AuthService) reserveLoginAttempts(scopes []loginScope) ([]reservedLoginAttempt, time.Duration, error) {
+	policy := repositories.LoginAttemptPolicy{
+		Window:          utils.ConfigDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		LockoutDuration: utils.ConfigDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		BackoffAfter:    utils.ConfigInt("LOGIN_BACKOFF_AFTER", 3),
+		BackoffBase:     utils.ConfigDuration("LOGIN_BACKOFF_BASE", time.Second),
+		BackoffMax:      utils.ConfigDuration("LOGIN_BACKOFF_MAX", 30*time.Second),
+	}
+
+	var reserved []reservedLoginAttempt
+	var refused []loginScope
+	var reserveErr error
+	for _, scope := range scopes {
+		policy.MaxAttempts = scope.maxAttempts
+		attempt, ok, err := service.authRepository.ReserveLoginAttempt(scope.name, scope.key, policy)
+		if err != nil {
+			reserveErr = err
+			break
+		}
+		if !ok {
+			refused = append(refused, scope)
+			continue
+		}
+		reserved = append(reserved, reservedLoginAttempt{scope: scope, attempt: attempt})
+	}
+
+	if reserveErr == nil && len(refused) == 0 {
+		return reserved, 0, nil
+	}
+
+	for _, attempt := range reserved {
+		if err := service.authRepository.ReleaseLoginAttempt(attempt.scope.name, attempt.scope.key, attempt.scope.maxAttempts); err != nil {
+			logger.LogError(err, "Failed to release login attempt", map[string]interface{}{
+				"scope": attempt.scope.name,
+				"key":   attempt.scope.key,
+			})
+		}
+	}
+	if reserveErr != nil {
+		return nil, 0, reserveErr
+	}
+
+	// The wait is read after the refusal only to fill Retry-After, a lock that ran out in between
+	// still asks for a second so the caller does not read it as a pass
+	retryAfter := time.Second
+	now := time.Now()
+	for _, scope := range refused {
+		attempt, err := service.authRepository.FetchLoginAttempt(scope.name, scope.key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if wait := loginWaitTime(attempt, now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return nil, retryAfter, nil
+}
+
+func (service *AuthService) UnlockAccount(userUUID, unlockedBy string) error {
+	user, err := service.userRepository.FetchSpecificUser(userUUID)
+	if err != nil {
+		return errors.New("user not found", 404)
+	}
+
+	if err := service.authRepository.ResetLoginAttempts(loginScopeAccount, normalizeLoginKey(user.Email)); err != nil {
+		return err
+	}
+
+	// Also clears the IP addresses the user was locked out from, otherwise they stay blocked there
+	lockouts, err := service.authRepository.ReleaseAccountLockouts(user.UUID.String(), unlockedBy)
+	if err != nil {
+		return err
+	}
+
+	for _, lockout := range lockouts {
+		if lockout.Scope == loginScopeAccount {
+			continue
+		}
+		if err := service.authRepository.ResetLoginAttempts(lockout.Scope, lockout.Key); err != nil {
+			return err
+		}
+	}
+
+	logger.LogInfo("Account unlocked", map[string]interface{}{
+		"user_uuid":        user.UUID.String(),
+		"unlocked_by":      unlockedBy,
+		"released_lockout": len(lockouts),
+	})
+
+	return nil
 }
 
 func (service *AuthService) GetMyProfile(userUUID, roleCode string) (interface{}, error) {
@@ -72,8 +209,8 @@ func (service *AuthService) GetMyProfile(userUUID, roleCode string) (interface{}
 		return nil, errors.New("invalid user UUID format", 0)
 	}
 
-	switch user.RoleCode {
-	case "SA":
+	switch user.Role {
+	case entity.SuperAdmin:
 		superAdminDetails, err := service.userRepository.FetchSuperAdminDetails(parsedUserUUID)
 		if err != nil {
 			return nil, err
@@ -110,7 +247,7 @@ func (service *AuthService) GetMyProfile(userUUID, roleCode string) (interface{}
 		}
 
 		return result, nil
-	case "AS":
+	case entity.SchoolAdmin:
 		schoolAdminDetails, err := service.userRepository.FetchSchoolAdminDetails(parsedUserUUID)
 		if err != nil {
 			return nil, err
@@ -146,7 +283,7 @@ func (service *AuthService) GetMyProfile(userUUID, roleCode string) (interface{}
 		}
 
 		return result, nil
-	case "P":
+	case entity.Parent:
 		parentDetails, err := service.userRepository.FetchParentDetails(parsedUserUUID)
 		if err != nil {
 			return nil, err
@@ -182,7 +319,7 @@ func (service *AuthService) GetMyProfile(userUUID, roleCode string) (interface{}
 		}
 
 		return result, nil
-	case "D":
+	case entity.Driver:
 		driverDetails, err := service.userRepository.FetchDriverDetails(parsedUserUUID)
 		if err != nil {
 			return nil, err
@@ -309,4 +446,303 @@ func hashPassword(password string) (string, error) {
 func validatePassword(providedPassword, storedPassword string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(providedPassword))
 	return err == nil
-}
\ No newline at end of file
+}
+
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+)
+
+type loginScope struct {
+	name        string
+	key         string
+	maxAttempts int
+}
+
+func loginScopes(email, ipAddress string) []loginScope {
+	scopes := []loginScope{
+		{name: loginScopeAccount, key: normalizeLoginKey(email), maxAttempts: utils.ConfigInt("LOGIN_MAX_FAILED_ATTEMPTS", 5)},
+	}
+	if ipAddress != "" {
+		scopes = append(scopes, loginScope{name: loginScopeIP, key: ipAddress, maxAttempts: utils.ConfigInt("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", 20)})
+	}
+	return scopes
+}
+
+type reservedLoginAttempt struct {
+	scope   loginScope
+	attempt entity.LoginAttempt
+}
+
+// The attempts were counted when they were reserved, this records the lockouts they caused. The
+// user is kept on the IP lockout too so unlocking the user also frees the address.
+func (service *AuthService) registerFailedLogin(attempts []reservedLoginAttempt, ipAddress string, userUUID *uuid.UUID) {
+	for _, reserved := range attempts {
+		scope, attempt := reserved.scope, reserved.attempt
+		if attempt.LockedUntil == nil {
+			continue
+		}
+
+		lockout := entity.AccountLockout{
+			ID:          time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
+			UUID:        uuid.New(),
+			UserUUID:    userUUID,
+			Scope:       scope.name,
+			Key:         scope.key,
+			IPAddress:   ipAddress,
+			FailedCount: attempt.FailedCount,
+			LockedUntil: *attempt.LockedUntil,
+		}
+
+		if err := service.authRepository.SaveAccountLockout(lockout); err != nil {
+			logger.LogError(err, "Failed to save account lockout", map[string]interface{}{
+				"scope": scope.name,
+				"key":   scope.key,
+			})
+		}
+
+		logger.LogWarn("Login locked after too many failed attempts", map[string]interface{}{
+			"scope":        scope.name,
+			"key":          scope.key,
+			"ip_address":   ipAddress,
+			"failed_count": attempt.FailedCount,
+			"locked_until": attempt.LockedUntil.Format(time.RFC3339),
+		})
+	}
+}
+
+// Active lockouts win, otherwise the delay doubles for every failure past LOGIN_BACKOFF_AFTER
+func loginWaitTime(attempt entity.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+
+	backoffAfter := utils.ConfigInt("LOGIN_BACKOFF_AFTER", 3)
+	if attempt.LastFailedAt == nil || attempt.FailedCount < backoffAfter {
+		return 0
+	}
+
+	base := utils.ConfigDuration("LOGIN_BACKOFF_BASE", time.Second)
+	maxDelay := utils.ConfigDuration("LOGIN_BACKOFF_MAX", 30*time.Second)
+
+	delay := base
+	for i := backoffAfter; i < attempt.FailedCount && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if nextAllowed := attempt.LastFailedAt.Add(delay); nextAllowed.After(now) {
+		return nextAllowed.Sub(now)
+	}
+
+	return 0
+}
+
+func normalizeLoginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Returns how long the caller has to wait before another code may be sent to the phone,
+// covering the resend cooldown and the per phone and per IP limits
+func (service *AuthService) CheckLoginOTPThrottle(phone, ipAddress string) (time.Duration, error) {
+	var retryAfter time.Duration
+	now := time.Now()
+	window := utils.ConfigDuration("OTP_REQUEST_WINDOW", 15*time.Minute)
+	cooldown := utils.ConfigDuration("OTP_RESEND_COOLDOWN", time.Minute)
+
+	limits := []struct {
+		field string
+		value string
+		max   int
+	}{
+		{field: repositories.LoginOTPFieldPhone, value: normalizePhone(phone), max: utils.ConfigInt("OTP_MAX_REQUESTS_PER_PHONE", 3)},
+		{field: repositories.LoginOTPFieldIP, value: ipAddress, max: utils.ConfigInt("OTP_MAX_REQUESTS_PER_IP", 10)},
+	}
+
+	for _, limit := range limits {
+		times, err := service.authRepository.FetchLoginOTPRequestTimes(limit.field, limit.value, now.Add(-window))
+		if err != nil {
+			return 0, err
+		}
+
+		if len(times) == 0 {
+			continue
+		}
+
+		if limit.field == repositories.LoginOTPFieldPhone {
+			if wait := times[len(times)-1].Add(cooldown).Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+
+		// The oldest request still counting towards the limit decides when a slot frees up
+		if len(times) >= limit.max {
+			if wait := times[len(times)-limit.max].Add(window).Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// Sends a one-time login code to the parent owning the phone and returns how long it stays valid.
+// Unknown numbers are answered the same way so the endpoint cannot be used to look up parents.
+func (service *AuthService) RequestLoginOTP(phone, ipAddress string) (time.Duration, error) {
+	ttl := utils.ConfigDuration("OTP_TTL", 5*time.Minute)
+	normalizedPhone := normalizePhone(phone)
+
+	otp := entity.LoginOTP{
+		ID:        time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
+		UUID:      uuid.New(),
+		Phone:     normalizedPhone,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	users, err := service.authRepository.FetchParentLoginByPhone(normalizedPhone, phoneCountryCode())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(users) > 1 {
+		logger.LogWarn("Phone number is shared by more than one parent, login code not sent", map[string]interface{}{
+			"phone": normalizedPhone,
+		})
+	}
+
+	var code string
+	if len(users) == 1 {
+		parsedUserUUID, err := uuid.Parse(users[0].UUID)
+		if err != nil {
+			return 0, err
+		}
+
+		code, err = generateOTPCode()
+		if err != nil {
+			return 0, err
+		}
+
+		hashedCode, err := hashPassword(code)
+		if err != nil {
+			return 0, err
+		}
+
+		otp.UserUUID = &parsedUserUUID
+		otp.CodeHash = toNullString(hashedCode)
+	}
+
+	// Every request is stored, known number or not, so the rate limits apply to both
+	if err := service.authRepository.SaveLoginOTP(otp); err != nil {
+		return 0, err
+	}
+
+	if code != "" {
+		message := fmt.Sprintf("Your Shuttle login code is %s. It expires in %d minutes, do not share it with anyone.", code, int(ttl.Minutes()))
+		if err := service.smsSender.Send(phone, message); err != nil {
+			return 0, err
+		}
+	}
+
+	return ttl, nil
+}
+
+func (service *AuthService) VerifyLoginOTP(phone, code string) (dto.UserDataOnLoginDTO, error) {
+	otp, err := service.authRepository.FetchActiveLoginOTP(normalizePhone(phone))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dto.UserDataOnLoginDTO{}, errors.New("invalid or expired code", 401)
+		}
+		return dto.UserDataOnLoginDTO{}, err
+	}
+
+	// The attempt is counted before the hash is compared so parallel guesses cannot exceed the limit
+	reserved, err := service.authRepository.ReserveLoginOTPAttempt(otp.ID, utils.ConfigInt("OTP_MAX_VERIFY_ATTEMPTS", 5))
+	if err != nil {
+		return dto.UserDataOnLoginDTO{}, err
+	}
+	if !reserved {
+		return dto.UserDataOnLoginDTO{}, errors.New("too many wrong codes, please request a new one", 429)
+	}
+
+	if !validatePassword(code, otp.CodeHash.String) {
+		return dto.UserDataOnLoginDTO{}, errors.New("invalid or expired code", 401)
+	}
+
+	consumed, err := service.authRepository.ConsumeLoginOTP(otp.ID)
+	if err != nil {
+		return dto.UserDataOnLoginDTO{}, err
+	}
+	if !consumed {
+		return dto.UserDataOnLoginDTO{}, errors.New("invalid or expired code", 401)
+	}
+
+	user, err := service.userRepository.FetchSpecificUser(otp.UserUUID.String())
+	if err != nil {
+		return dto.UserDataOnLoginDTO{}, errors.New("invalid or expired code", 401)
+	}
+
+	return dto.UserDataOnLoginDTO{
+		UserID:   user.ID,
+		UserUUID: user.UUID.String(),
+		Username: user.Username,
+		RoleCode: user.RoleCode,
+	}, nil
+}
+
+// Removes refresh tokens that can no longer be used and login codes older than a day, which
+// are past every OTP request window
+func (service *AuthService) CleanupExpiredTokens() error {
+	tokens, err := service.authRepository.DeleteExpiredRefreshTokens()
+	if err != nil {
+		return err
+	}
+
+	otps, err := service.authRepository.DeleteLoginOTPsBefore(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if tokens > 0 || otps > 0 {
+		logger.LogInfo("Expired tokens cleaned up", map[string]interface{}{"refresh_tokens": tokens, "login_otps": otps})
+	}
+	return nil
+}
+
+func generateOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Keeps the digits of a phone number in international form without "+": an international "00" prefix
+// is dropped and a local leading 0 becomes PHONE_COUNTRY_CODE, so "0812...", "+62 812..." and
+// "0062812..." all give "62812...". The SQL in the parent phone lookups does the same.
+func normalizePhone(phone string) string {
+	digits := onlyDigits(phone)
+	digits = strings.TrimPrefix(digits, "00")
+	if strings.HasPrefix(digits, "0") {
+		return phoneCountryCode() + digits[1:]
+	}
+	return digits
+}
+
+func phoneCountryCode() string {
+	if code := onlyDigits(viper.GetString("PHONE_COUNTRY_CODE")); code != "" {
+		return code
+	}
+	return "62"
+}
+
+func onlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
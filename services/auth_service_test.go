@@ -0,0 +1,364 @@
+package services
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// Keeps a single login code in memory, the embedded interface panics on anything else
+type fakeOTPRepository struct {
+	repositories.AuthRepositoryInterface
+	mu  sync.Mutex
+	otp entity.LoginOTP
+}
+
+func (r *fakeOTPRepository) FetchActiveLoginOTP(phone string) (entity.LoginOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.otp.Phone != phone || r.otp.ConsumedAt != nil {
+		return entity.LoginOTP{}, sql.ErrNoRows
+	}
+	return r.otp, nil
+}
+
+func (r *fakeOTPRepository) ReserveLoginOTPAttempt(otpID int64, maxAttempts int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.otp.VerifyAttempts >= maxAttempts || r.otp.ConsumedAt != nil {
+		return false, nil
+	}
+	r.otp.VerifyAttempts++
+	return true, nil
+}
+
+func (r *fakeOTPRepository) ConsumeLoginOTP(otpID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.otp.ConsumedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	r.otp.ConsumedAt = &now
+	return true, nil
+}
+
+type fakeOTPUserRepository struct {
+	repositories.UserRepositoryInterface
+	user entity.User
+}
+
+func (r *fakeOTPUserRepository) FetchSpecificUser(userUUID string) (entity.User, error) {
+	return r.user, nil
+}
+
+func newOTPTestService(t *testing.T, attempts int) (*AuthService, *fakeOTPRepository) {
+	t.Helper()
+
+	hashedCode, err := hashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := entity.User{ID: 1, UUID: uuid.New(), Username: "parent", RoleCode: "P"}
+	repository := &fakeOTPRepository{otp: entity.LoginOTP{
+		ID:             1,
+		UserUUID:       &user.UUID,
+		Phone:          "62812345678",
+		CodeHash:       sql.NullString{String: hashedCode, Valid: true},
+		VerifyAttempts: attempts,
+	}}
+
+	service := NewAuthService(repository, &fakeOTPUserRepository{user: user}, nil)
+	return &service, repository
+}
+
+func TestVerifyLoginOTPAttempts(t *testing.T) {
+	viper.Set("OTP_MAX_VERIFY_ATTEMPTS", 3)
+	defer viper.Set("OTP_MAX_VERIFY_ATTEMPTS", nil)
+
+	tests := []struct {
+		name         string
+		attempts     int
+		code         string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "correct code", attempts: 0, code: "123456", wantStatus: 0, wantAttempts: 1},
+		{name: "correct code on the last attempt", attempts: 2, code: "123456", wantStatus: 0, wantAttempts: 3},
+		{name: "wrong code is counted", attempts: 0, code: "000000", wantStatus: 401, wantAttempts: 1},
+		{name: "wrong code on the last attempt", attempts: 2, code: "000000", wantStatus: 401, wantAttempts: 3},
+		{name: "no attempts left for a wrong code", attempts: 3, code: "000000", wantStatus: 429, wantAttempts: 3},
+		{name: "no attempts left for the correct code", attempts: 3, code: "123456", wantStatus: 429, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repository := newOTPTestService(t, tt.attempts)
+
+			_, err := service.VerifyLoginOTP("0812-345-678", tt.code)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("VerifyLoginOTP() error = %v", err)
+				}
+			} else {
+				customErr, ok := err.(*errors.CustomError)
+				if !ok || customErr.StatusCode != tt.wantStatus {
+					t.Fatalf("VerifyLoginOTP() error = %v, want status %d", err, tt.wantStatus)
+				}
+			}
+
+			if repository.otp.VerifyAttempts != tt.wantAttempts {
+				t.Errorf("verify attempts = %d, want %d", repository.otp.VerifyAttempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestVerifyLoginOTPConcurrentGuesses(t *testing.T) {
+	viper.Set("OTP_MAX_VERIFY_ATTEMPTS", 3)
+	defer viper.Set("OTP_MAX_VERIFY_ATTEMPTS", nil)
+
+	service, repository := newOTPTestService(t, 0)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.VerifyLoginOTP("62812345678", "000000")
+			if customErr, ok := err.(*errors.CustomError); ok && customErr.StatusCode == 401 {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked != 3 {
+		t.Errorf("codes compared = %d, want 3", checked)
+	}
+	if repository.otp.VerifyAttempts != 3 {
+		t.Errorf("verify attempts = %d, want 3", repository.otp.VerifyAttempts)
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		phone       string
+		countryCode string
+		want        string
+	}{
+		{name: "international with plus", phone: "+62 812-3456-789", want: "628123456789"},
+		{name: "international without plus", phone: "628123456789", want: "628123456789"},
+		{name: "local leading zero", phone: "0812 3456 789", want: "628123456789"},
+		{name: "international 00 prefix", phone: "0062 812 3456 789", want: "628123456789"},
+		{name: "configured country code", phone: "06 1234 5678", countryCode: "+31", want: "31612345678"},
+		{name: "no digits", phone: "n/a", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("PHONE_COUNTRY_CODE", tt.countryCode)
+			defer viper.Set("PHONE_COUNTRY_CODE", nil)
+
+			if got := normalizePhone(tt.phone); got != tt.want {
+				t.Errorf("normalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+// Keeps login attempts and lockouts in memory, reservations ignore the backoff
+type fakeLoginRepository struct {
+	repositories.AuthRepositoryInterface
+	mu       sync.Mutex
+	user     entity.UserDataOnLogin
+	attempts map[string]*entity.LoginAttempt
+	lockouts []entity.AccountLockout
+}
+
+func (r *fakeLoginRepository) Login(email string) (entity.UserDataOnLogin, error) {
+	return r.user, nil
+}
+
+func (r *fakeLoginRepository) ReserveLoginAttempt(scope, key string, policy repositories.LoginAttemptPolicy) (entity.LoginAttempt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempt, ok := r.attempts[scope+":"+key]
+	if !ok {
+		attempt = &entity.LoginAttempt{Scope: scope, Key: key}
+		r.attempts[scope+":"+key] = attempt
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now()) {
+		return entity.LoginAttempt{}, false, nil
+	}
+	attempt.FailedCount++
+	attempt.LockedUntil = nil
+	if attempt.FailedCount >= policy.MaxAttempts {
+		lockedUntil := time.Now().Add(policy.LockoutDuration)
+		attempt.LockedUntil = &lockedUntil
+	}
+	return *attempt, true, nil
+}
+
+func (r *fakeLoginRepository) ReleaseLoginAttempt(scope, key string, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt, ok := r.attempts[scope+":"+key]; ok {
+		attempt.FailedCount--
+		if attempt.FailedCount < maxAttempts {
+			attempt.LockedUntil = nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeLoginRepository) ResetLoginAttempts(scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, scope+":"+key)
+	return nil
+}
+
+func (r *fakeLoginRepository) FetchLoginAttempt(scope, key string) (entity.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt, ok := r.attempts[scope+":"+key]; ok {
+		return *attempt, nil
+	}
+	return entity.LoginAttempt{Scope: scope, Key: key}, nil
+}
+
+func (r *fakeLoginRepository) SaveAccountLockout(lockout entity.AccountLockout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lockouts = append(r.lockouts, lockout)
+	return nil
+}
+
+func (r *fakeLoginRepository) ReleaseAccountLockouts(userUUID string, unlockedBy string) ([]entity.AccountLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var released []entity.AccountLockout
+	for _, lockout := range r.lockouts {
+		if lockout.UserUUID != nil && lockout.UserUUID.String() == userUUID {
+			released = append(released, lockout)
+		}
+	}
+	return released, nil
+}
+
+func (r *fakeLoginRepository) failedCount(scope, key string) int {
+	if attempt, ok := r.attempts[scope+":"+key]; ok {
+		return attempt.FailedCount
+	}
+	return 0
+}
+
+func newLoginTestService(t *testing.T) (*AuthService, *fakeLoginRepository) {
+	t.Helper()
+
+	hashedPassword, err := hashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := entity.User{ID: 1, UUID: uuid.New(), Username: "admin", Email: "admin@school.id", RoleCode: "AS"}
+	repository := &fakeLoginRepository{
+		user:     entity.UserDataOnLogin{ID: user.ID, UUID: user.UUID.String(), Username: user.Username, RoleCode: user.RoleCode, Password: hashedPassword},
+		attempts: make(map[string]*entity.LoginAttempt),
+	}
+
+	service := NewAuthService(repository, &fakeOTPUserRepository{user: user}, nil)
+	return &service, repository
+}
+
+func TestLoginConcurrentGuesses(t *testing.T) {
+	viper.Set("LOGIN_MAX_FAILED_ATTEMPTS", 3)
+	defer viper.Set("LOGIN_MAX_FAILED_ATTEMPTS", nil)
+	viper.Set("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", 3)
+	defer viper.Set("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", nil)
+
+	service, repository := newLoginTestService(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked, refused := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, retryAfter, err := service.Login("admin@school.id", "wrong", "10.0.0.1")
+			mu.Lock()
+			defer mu.Unlock()
+			if retryAfter > 0 {
+				refused++
+			} else if err != nil {
+				checked++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked != 3 || refused != 17 {
+		t.Errorf("passwords checked = %d, refused = %d, want 3 and 17", checked, refused)
+	}
+	if len(repository.lockouts) != 2 {
+		t.Errorf("lockouts = %d, want one for the account and one for the IP", len(repository.lockouts))
+	}
+	if count := repository.failedCount(loginScopeIP, "10.0.0.1"); count != 3 {
+		t.Errorf("IP failed count = %d, want 3, refused attempts must be given back", count)
+	}
+}
+
+func TestLoginSuccessAndUnlock(t *testing.T) {
+	viper.Set("LOGIN_MAX_FAILED_ATTEMPTS", 3)
+	defer viper.Set("LOGIN_MAX_FAILED_ATTEMPTS", nil)
+	viper.Set("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", 4)
+	defer viper.Set("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", nil)
+
+	service, repository := newLoginTestService(t)
+
+	if _, _, err := service.Login("admin@school.id", "wrong", "10.0.0.1"); err == nil {
+		t.Fatal("Login() with a wrong password error = nil")
+	}
+	if _, retryAfter, err := service.Login("Admin@School.id", "secret", "10.0.0.1"); err != nil || retryAfter > 0 {
+		t.Fatalf("Login() error = %v, retryAfter = %v", err, retryAfter)
+	}
+	if count := repository.failedCount(loginScopeAccount, "admin@school.id"); count != 0 {
+		t.Errorf("account failed count = %d after a successful login, want 0", count)
+	}
+	if count := repository.failedCount(loginScopeIP, "10.0.0.1"); count != 1 {
+		t.Errorf("IP failed count = %d after a successful login, want the earlier failure only", count)
+	}
+
+	for i := 0; i < 3; i++ {
+		service.Login("admin@school.id", "wrong", "10.0.0.1")
+	}
+	if _, retryAfter, _ := service.Login("admin@school.id", "secret", "10.0.0.1"); retryAfter == 0 {
+		t.Fatal("Login() on a locked account was not refused")
+	}
+
+	if err := service.UnlockAccount(repository.user.UUID, "superadmin"); err != nil {
+		t.Fatalf("UnlockAccount() error = %v", err)
+	}
+	if count := repository.failedCount(loginScopeIP, "10.0.0.1"); count != 0 {
+		t.Errorf("IP failed count = %d after unlock, want 0", count)
+	}
+	if _, retryAfter, err := service.Login("admin@school.id", "secret", "10.0.0.1"); err != nil || retryAfter > 0 {
+		t.Errorf("Login() after unlock error = %v, retryAfter = %v", err, retryAfter)
+	}
+}
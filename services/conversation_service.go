@@ -0,0 +1,706 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+)
+
+// Who a base role may start a conversation with, by how they are related to the student or trip.
+// A sender has to be related themselves in the relation listed first. Custom roles follow the
+// role they are based on.
+var conversationRecipients = map[dto.Role][]string{
+	dto.Parent:      {entity.ContactGuardian, entity.ContactDriver, entity.ContactSchoolAdmin},
+	dto.Driver:      {entity.ContactDriver, entity.ContactGuardian, entity.ContactSchoolAdmin},
+	dto.SchoolAdmin: {entity.ContactSchoolAdmin, entity.ContactGuardian, entity.ContactDriver},
+}
+
+type ConversationServiceInterface interface {
+	GetContacts(userUUID, roleCode, studentUUID, tripUUID string) ([]dto.ConversationContactDTO, error)
+	GetMyConversations(userUUID string, page, limit int) ([]dto.ConversationResponseDTO, int, error)
+	StartConversation(userUUID, roleCode, username string, req dto.ConversationRequestDTO) (dto.ConversationResponseDTO, error)
+	GetMessages(userUUID, id string, page, limit int) ([]dto.MessageResponseDTO, int, error)
+	SendMessage(userUUID, roleCode, id string, req dto.MessageRequestDTO) (dto.MessageResponseDTO, error)
+	MarkRead(userUUID, id string) error
+
+	GetSchoolConversations(schoolUUID, studentUUID string, page, limit int) ([]dto.ConversationResponseDTO, int, error)
+	GetSchoolMessages(schoolUUID, id string, page, limit int) ([]dto.MessageResponseDTO, int, error)
+	DeleteMessage(schoolUUID, id, messageID, username string) error
+}
+
+type ConversationService struct {
+	conversationRepository repositories.ConversationRepositoryInterface
+	permissionService      PermissionServiceInterface
+}
+
+// The student and trip a conversation is about, with the school they belong to
+type conversationContext struct {
+	schoolUUID  uuid.UUID
+	studentUUID uuid.NullUUID
+	tripUUID    uuid.NullUUID
+}
+
+func NewConversationService(conversationRepository repositories.ConversationRepositoryInterface, permissionService PermissionServiceInterface) ConversationService {
+	return ConversationService{
+		conversationRepository: conversationRepository,
+		permissionService:      permissionService,
+	}
+}
+
+// Everyone the user may start a conversation about the student or trip with
+func (service *ConversationService) GetContacts(userUUID, roleCode, studentUUID, tripUUID string) ([]dto.ConversationContactDTO, error) {
+	context, err := service.resolveContext(studentUUID, tripUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.permissionService.GetRoleBase(roleCode)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := service.allowedRecipients(userUUID, role, context)
+	if err != nil {
+		return nil, err
+	}
+
+	contactsDTO := []dto.ConversationContactDTO{}
+	for _, contact := range allowed {
+		contactsDTO = append(contactsDTO, dto.ConversationContactDTO{
+			UserUUID: contact.UserUUID.String(),
+			Name:     strings.TrimSpace(contact.FirstName.String + " " + contact.LastName.String),
+			RoleCode: contact.RoleCode.String,
+			Relation: contact.Relation,
+		})
+	}
+
+	return contactsDTO, nil
+}
+
+func (service *ConversationService) GetMyConversations(userUUID string, page, limit int) ([]dto.ConversationResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	conversations, err := service.conversationRepository.FetchConversations(userUUID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.conversationRepository.CountConversations(userUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conversationsDTO, err := service.toConversationDTOs(conversations)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return conversationsDTO, total, nil
+}
+
+// Starts a conversation with the first message. Writing to the same people about the same student
+// and trip again adds the message to the conversation that already exists.
+func (service *ConversationService) StartConversation(userUUID, roleCode, username string, req dto.ConversationRequestDTO) (dto.ConversationResponseDTO, error) {
+	context, err := service.resolveContext(req.StudentUUID, req.TripUUID)
+	if err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	role, err := service.permissionService.GetRoleBase(roleCode)
+	if err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	allowed, err := service.allowedRecipients(userUUID, role, context)
+	if err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	allowedUUIDs := make(map[string]bool, len(allowed))
+	for _, contact := range allowed {
+		allowedUUIDs[contact.UserUUID.String()] = true
+	}
+
+	participants := map[string]bool{userUUID: true}
+	for _, recipient := range req.RecipientUUIDs {
+		// Normalised so the participants compare and sort the same way the database does
+		parsed, err := uuid.Parse(recipient)
+		if err != nil {
+			return dto.ConversationResponseDTO{}, errors.New("invalid recipient uuid "+recipient, 400)
+		}
+		recipient = parsed.String()
+		if recipient == userUUID {
+			continue
+		}
+		if !allowedUUIDs[recipient] {
+			if role == dto.Parent {
+				return dto.ConversationResponseDTO{}, errors.New("you can only message the drivers currently assigned to your child and the school admins", 403)
+			}
+			return dto.ConversationResponseDTO{}, errors.New("one of the recipients is not related to the student or trip", 403)
+		}
+		participants[recipient] = true
+	}
+	if len(participants) < 2 {
+		return dto.ConversationResponseDTO{}, errors.New("at least one recipient other than yourself is required", 400)
+	}
+
+	participantUUIDs := make([]string, 0, len(participants))
+	for participant := range participants {
+		participantUUIDs = append(participantUUIDs, participant)
+	}
+	sort.Strings(participantUUIDs)
+
+	conversationUUID, err := service.conversationRepository.FindConversation(context.studentUUID, context.tripUUID, participantUUIDs)
+	if err != nil && err != sql.ErrNoRows {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	if err == sql.ErrNoRows {
+		conversationUUID = uuid.New()
+		conversation := entity.Conversation{
+			ID:          time.Now().UnixMilli()*1e6 + int64(conversationUUID.ID()%1e6),
+			UUID:        conversationUUID,
+			SchoolUUID:  context.schoolUUID,
+			StudentUUID: context.studentUUID,
+			TripUUID:    context.tripUUID,
+			CreatedBy:   toNullString(username),
+		}
+
+		tx, err := service.conversationRepository.BeginTransaction()
+		if err != nil {
+			return dto.ConversationResponseDTO{}, err
+		}
+		defer tx.Rollback()
+
+		if err := service.conversationRepository.SaveConversation(tx, conversation, participantUUIDs); err != nil {
+			return dto.ConversationResponseDTO{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return dto.ConversationResponseDTO{}, err
+		}
+	}
+
+	if _, err := service.SendMessage(userUUID, roleCode, conversationUUID.String(), dto.MessageRequestDTO{Body: req.Body}); err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	conversation, err := service.conversationRepository.FetchSpecConversation(conversationUUID.String())
+	if err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	conversationsDTO, err := service.toConversationDTOs([]entity.Conversation{conversation})
+	if err != nil {
+		return dto.ConversationResponseDTO{}, err
+	}
+
+	return conversationsDTO[0], nil
+}
+
+func (service *ConversationService) GetMessages(userUUID, id string, page, limit int) ([]dto.MessageResponseDTO, int, error) {
+	_, participants, err := service.fetchOwnConversation(userUUID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return service.fetchMessages(id, participants, page, limit, false)
+}
+
+// Stores the message and pushes it to the other participants that are connected. A parent can only
+// keep writing while every driver in the conversation is still assigned to the child.
+func (service *ConversationService) SendMessage(userUUID, roleCode, id string, req dto.MessageRequestDTO) (dto.MessageResponseDTO, error) {
+	conversation, participants, err := service.fetchOwnConversation(userUUID, id)
+	if err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return dto.MessageResponseDTO{}, errors.New("message_body cannot be empty", 400)
+	}
+
+	role, err := service.permissionService.GetRoleBase(roleCode)
+	if err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+
+	if role == dto.Parent {
+		if err := service.checkDriversAssigned(conversation, participants); err != nil {
+			return dto.MessageResponseDTO{}, err
+		}
+	}
+
+	messageUUID := uuid.New()
+	message := entity.Message{
+		ID:               time.Now().UnixMilli()*1e6 + int64(messageUUID.ID()%1e6),
+		UUID:             messageUUID,
+		ConversationUUID: conversation.UUID,
+		SenderUUID:       uuid.NullUUID{UUID: uuid.MustParse(userUUID), Valid: true},
+		Body:             body,
+	}
+
+	tx, err := service.conversationRepository.BeginTransaction()
+	if err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+	defer tx.Rollback()
+
+	if message.CreatedAt, err = service.conversationRepository.SaveMessage(tx, message); err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+
+	// The sender has read everything up to their own message
+	if _, err := service.conversationRepository.MarkConversationRead(tx, id, userUUID, message.CreatedAt); err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dto.MessageResponseDTO{}, err
+	}
+
+	for _, participant := range participants {
+		if participant.UserUUID == message.SenderUUID.UUID {
+			message.SenderFirstName = participant.FirstName
+			message.SenderLastName = participant.LastName
+		}
+	}
+
+	messageDTO := toMessageDTO(message, nil, false)
+	service.broadcast(participants, userUUID, map[string]interface{}{"type": "message", "message": messageDTO})
+
+	return messageDTO, nil
+}
+
+// Moves the read receipt to now and tells the other participants that are connected
+func (service *ConversationService) MarkRead(userUUID, id string) error {
+	_, participants, err := service.fetchOwnConversation(userUUID, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := service.conversationRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	readAt, err := service.conversationRepository.MarkConversationRead(tx, id, userUUID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	service.broadcast(participants, userUUID, map[string]interface{}{
+		"type":              "message_read",
+		"conversation_uuid": id,
+		"user_uuid":         userUUID,
+		"read_at":           readAt.Format(time.RFC3339Nano),
+	})
+
+	return nil
+}
+
+// Every conversation of the school for moderation, empty studentUUID lists all of them
+func (service *ConversationService) GetSchoolConversations(schoolUUID, studentUUID string, page, limit int) ([]dto.ConversationResponseDTO, int, error) {
+	if studentUUID != "" {
+		if _, err := uuid.Parse(studentUUID); err != nil {
+			return nil, 0, errors.New("invalid student_uuid", 400)
+		}
+	}
+
+	offset := (page - 1) * limit
+
+	conversations, err := service.conversationRepository.FetchSchoolConversations(schoolUUID, studentUUID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.conversationRepository.CountSchoolConversations(schoolUUID, studentUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conversationsDTO, err := service.toConversationDTOs(conversations)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return conversationsDTO, total, nil
+}
+
+// Moderators also see the text of removed messages
+func (service *ConversationService) GetSchoolMessages(schoolUUID, id string, page, limit int) ([]dto.MessageResponseDTO, int, error) {
+	_, participants, err := service.fetchSchoolConversation(schoolUUID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return service.fetchMessages(id, participants, page, limit, true)
+}
+
+// Hides a message from the participants, it stays visible to moderators
+func (service *ConversationService) DeleteMessage(schoolUUID, id, messageID, username string) error {
+	_, participants, err := service.fetchSchoolConversation(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(messageID); err != nil {
+		return errors.New("message not found", 404)
+	}
+
+	deleted, err := service.conversationRepository.DeleteMessage(id, messageID, username)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("message not found", 404)
+	}
+
+	service.broadcast(participants, "", map[string]interface{}{
+		"type":              "message_deleted",
+		"conversation_uuid": id,
+		"message_uuid":      messageID,
+	})
+
+	return nil
+}
+
+// Checks the student and trip exist and belong together, at least one of them is required
+func (service *ConversationService) resolveContext(studentUUID, tripUUID string) (conversationContext, error) {
+	var context conversationContext
+
+	if studentUUID == "" && tripUUID == "" {
+		return context, errors.New("student_uuid or trip_uuid is required", 400)
+	}
+
+	if studentUUID != "" {
+		parsed, err := uuid.Parse(studentUUID)
+		if err != nil {
+			return context, errors.New("student not found", 404)
+		}
+
+		schoolUUID, err := service.conversationRepository.FetchStudentSchool(studentUUID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return context, errors.New("student not found", 404)
+			}
+			return context, err
+		}
+
+		context.schoolUUID = schoolUUID
+		context.studentUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	if tripUUID != "" {
+		parsed, err := uuid.Parse(tripUUID)
+		if err != nil {
+			return context, errors.New("trip not found", 404)
+		}
+
+		schoolUUID, err := service.conversationRepository.FetchTripSchool(tripUUID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return context, errors.New("trip not found", 404)
+			}
+			return context, err
+		}
+
+		if context.studentUUID.Valid {
+			onTrip, err := service.conversationRepository.CheckStudentOnTrip(tripUUID, studentUUID)
+			if err != nil {
+				return context, err
+			}
+			if !onTrip {
+				return context, errors.New("the student is not on the trip", 400)
+			}
+		}
+
+		context.schoolUUID = schoolUUID
+		context.tripUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	return context, nil
+}
+
+func (service *ConversationService) fetchContacts(context conversationContext) ([]entity.ConversationContact, error) {
+	studentUUID, tripUUID := "", ""
+	if context.studentUUID.Valid {
+		studentUUID = context.studentUUID.UUID.String()
+	}
+	if context.tripUUID.Valid {
+		tripUUID = context.tripUUID.UUID.String()
+	}
+
+	guardians, err := service.conversationRepository.FetchGuardianContacts(studentUUID, tripUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	drivers, err := service.conversationRepository.FetchDriverContacts(studentUUID, tripUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	schoolAdmins, err := service.conversationRepository.FetchSchoolAdminContacts(context.schoolUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(guardians, drivers...), schoolAdmins...), nil
+}
+
+// The contacts the user may write to, the user has to be related to the student or trip in the
+// relation their role starts conversations from
+func (service *ConversationService) allowedRecipients(userUUID string, role dto.Role, context conversationContext) ([]entity.ConversationContact, error) {
+	relations, ok := conversationRecipients[role]
+	if !ok {
+		return nil, errors.New("only parents, drivers and school admins can start conversations", 403)
+	}
+
+	contacts, err := service.fetchContacts(context)
+	if err != nil {
+		return nil, err
+	}
+
+	related := false
+	for _, contact := range contacts {
+		if contact.UserUUID.String() == userUUID && contact.Relation == relations[0] {
+			related = true
+		}
+	}
+	if !related {
+		switch role {
+		case dto.Parent:
+			return nil, errors.New("you can only start conversations about your own children", 403)
+		case dto.Driver:
+			return nil, errors.New("you can only start conversations about students and trips you are assigned to", 403)
+		default:
+			return nil, errors.New("you can only start conversations about your own school", 403)
+		}
+	}
+
+	canReach := make(map[string]bool, len(relations)-1)
+	for _, relation := range relations[1:] {
+		canReach[relation] = true
+	}
+
+	allowed := []entity.ConversationContact{}
+	seen := map[string]bool{userUUID: true}
+	for _, contact := range contacts {
+		if canReach[contact.Relation] && !seen[contact.UserUUID.String()] {
+			seen[contact.UserUUID.String()] = true
+			allowed = append(allowed, contact)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (service *ConversationService) checkDriversAssigned(conversation entity.Conversation, participants []entity.ConversationParticipant) error {
+	context := conversationContext{schoolUUID: conversation.SchoolUUID, studentUUID: conversation.StudentUUID, tripUUID: conversation.TripUUID}
+
+	contacts, err := service.fetchContacts(context)
+	if err != nil {
+		return err
+	}
+
+	assigned := make(map[uuid.UUID]bool)
+	for _, contact := range contacts {
+		if contact.Relation == entity.ContactDriver {
+			assigned[contact.UserUUID] = true
+		}
+	}
+
+	for _, participant := range participants {
+		if participant.Role.String == string(entity.Driver) && !assigned[participant.UserUUID] {
+			return errors.New("the driver is no longer assigned to your child", 403)
+		}
+	}
+
+	return nil
+}
+
+func (service *ConversationService) fetchOwnConversation(userUUID, id string) (entity.Conversation, []entity.ConversationParticipant, error) {
+	conversation, participants, err := service.fetchConversation(id)
+	if err != nil {
+		return entity.Conversation{}, nil, err
+	}
+
+	for _, participant := range participants {
+		if participant.UserUUID.String() == userUUID {
+			return conversation, participants, nil
+		}
+	}
+
+	return entity.Conversation{}, nil, errors.New("conversation not found", 404)
+}
+
+func (service *ConversationService) fetchSchoolConversation(schoolUUID, id string) (entity.Conversation, []entity.ConversationParticipant, error) {
+	conversation, participants, err := service.fetchConversation(id)
+	if err != nil {
+		return entity.Conversation{}, nil, err
+	}
+
+	if conversation.SchoolUUID.String() != schoolUUID {
+		return entity.Conversation{}, nil, errors.New("conversation not found", 404)
+	}
+
+	return conversation, participants, nil
+}
+
+func (service *ConversationService) fetchConversation(id string) (entity.Conversation, []entity.ConversationParticipant, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.Conversation{}, nil, errors.New("conversation not found", 404)
+	}
+
+	conversation, err := service.conversationRepository.FetchSpecConversation(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Conversation{}, nil, errors.New("conversation not found", 404)
+		}
+		return entity.Conversation{}, nil, err
+	}
+
+	participants, err := service.conversationRepository.FetchParticipants([]string{id})
+	if err != nil {
+		return entity.Conversation{}, nil, err
+	}
+
+	return conversation, participants, nil
+}
+
+func (service *ConversationService) fetchMessages(id string, participants []entity.ConversationParticipant, page, limit int, moderator bool) ([]dto.MessageResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	messages, err := service.conversationRepository.FetchMessages(id, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.conversationRepository.CountMessages(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messagesDTO := []dto.MessageResponseDTO{}
+	for _, message := range messages {
+		messagesDTO = append(messagesDTO, toMessageDTO(message, participants, moderator))
+	}
+
+	return messagesDTO, total, nil
+}
+
+func (service *ConversationService) toConversationDTOs(conversations []entity.Conversation) ([]dto.ConversationResponseDTO, error) {
+	conversationUUIDs := make([]string, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationUUIDs = append(conversationUUIDs, conversation.UUID.String())
+	}
+
+	participants, err := service.conversationRepository.FetchParticipants(conversationUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byConversation := make(map[uuid.UUID][]dto.ConversationParticipantDTO)
+	for _, participant := range participants {
+		participantDTO := dto.ConversationParticipantDTO{
+			UserUUID: participant.UserUUID.String(),
+			Name:     strings.TrimSpace(participant.FirstName.String + " " + participant.LastName.String),
+			RoleCode: participant.RoleCode.String,
+		}
+		if participant.LastReadAt.Valid {
+			participantDTO.LastReadAt = participant.LastReadAt.Time.Format(time.RFC3339Nano)
+		}
+		byConversation[participant.ConversationUUID] = append(byConversation[participant.ConversationUUID], participantDTO)
+	}
+
+	conversationsDTO := []dto.ConversationResponseDTO{}
+	for _, conversation := range conversations {
+		conversationDTO := dto.ConversationResponseDTO{
+			UUID:          conversation.UUID.String(),
+			Participants:  byConversation[conversation.UUID],
+			UnreadCount:   conversation.UnreadCount,
+			LastMessageAt: conversation.LastMessageAt.Format(time.RFC3339Nano),
+			CreatedAt:     safeTimeFormat(conversation.CreatedAt),
+			CreatedBy:     safeStringFormat(conversation.CreatedBy),
+		}
+		if conversation.StudentUUID.Valid {
+			conversationDTO.StudentUUID = conversation.StudentUUID.UUID.String()
+			conversationDTO.StudentName = strings.TrimSpace(conversation.StudentFirstName.String + " " + conversation.StudentLastName.String)
+		}
+		if conversation.TripUUID.Valid {
+			conversationDTO.TripUUID = conversation.TripUUID.UUID.String()
+		}
+		conversationsDTO = append(conversationsDTO, conversationDTO)
+	}
+
+	return conversationsDTO, nil
+}
+
+// Pushes the payload over the WebSocket to every connected participant but the one given,
+// participants that are offline see it the next time they load the conversation
+func (service *ConversationService) broadcast(participants []entity.ConversationParticipant, exceptUUID string, payload map[string]interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.LogError(err, "Failed to encode conversation update", nil)
+		return
+	}
+
+	for _, participant := range participants {
+		if participant.UserUUID.String() == exceptUUID {
+			continue
+		}
+		if _, err := utils.SendToUser(participant.UserUUID.String(), data); err != nil {
+			logger.LogError(err, "Failed to send conversation update over websocket", map[string]interface{}{"user_uuid": participant.UserUUID.String()})
+		}
+	}
+}
+
+// Participants only see that a removed message existed, moderators also see what it said
+func toMessageDTO(message entity.Message, participants []entity.ConversationParticipant, moderator bool) dto.MessageResponseDTO {
+	messageDTO := dto.MessageResponseDTO{
+		UUID:             message.UUID.String(),
+		ConversationUUID: message.ConversationUUID.String(),
+		SenderName:       strings.TrimSpace(message.SenderFirstName.String + " " + message.SenderLastName.String),
+		Body:             message.Body,
+		IsDeleted:        message.DeletedAt.Valid,
+		ReadBy:           []string{},
+		CreatedAt:        message.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if message.SenderUUID.Valid {
+		messageDTO.SenderUUID = message.SenderUUID.UUID.String()
+	}
+	if message.DeletedAt.Valid {
+		if moderator {
+			messageDTO.DeletedBy = message.DeletedBy.String
+		} else {
+			messageDTO.Body = ""
+		}
+	}
+
+	for _, participant := range participants {
+		if message.SenderUUID.Valid && participant.UserUUID == message.SenderUUID.UUID {
+			continue
+		}
+		if participant.LastReadAt.Valid && !participant.LastReadAt.Time.Before(message.CreatedAt) {
+			messageDTO.ReadBy = append(messageDTO.ReadBy, participant.UserUUID.String())
+		}
+	}
+
+	return messageDTO
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+)
+
+// School admins are warned this many days before a document expires, once per threshold
+var documentAlertDays = []int{30, 7, 1}
+
+type DriverDocumentServiceInterface interface {
+	GetDriverDocuments(driverUUID, schoolUUID string) ([]dto.DriverDocumentResponseDTO, error)
+	GetDriverDocumentFile(driverUUID, documentUUID, schoolUUID string) (string, error)
+	AddDriverDocument(driverUUID, schoolUUID string, req dto.DriverDocumentRequestDTO, file, username string) error
+	UpdateDriverDocument(driverUUID, documentUUID, schoolUUID string, req dto.DriverDocumentRequestDTO, file, username string) error
+	DeleteDriverDocument(driverUUID, documentUUID, schoolUUID, username string) error
+
+	SendExpiryAlerts() error
+}
+
+type DriverDocumentService struct {
+	driverDocumentRepository repositories.DriverDocumentRepositoryInterface
+	smsSender                utils.SMSSender
+}
+
+func NewDriverDocumentService(driverDocumentRepository repositories.DriverDocumentRepositoryInterface, smsSender utils.SMSSender) DriverDocumentService {
+	return DriverDocumentService{
+		driverDocumentRepository: driverDocumentRepository,
+		smsSender:                smsSender,
+	}
+}
+
+// An empty schoolUUID means the caller may see drivers of every school
+func (service *DriverDocumentService) GetDriverDocuments(driverUUID, schoolUUID string) ([]dto.DriverDocumentResponseDTO, error) {
+	if err := service.checkDriverAccess(driverUUID, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	documents, err := service.driverDocumentRepository.FetchDriverDocuments(driverUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentsDTO := []dto.DriverDocumentResponseDTO{}
+	for _, document := range documents {
+		documentsDTO = append(documentsDTO, toDriverDocumentDTO(document))
+	}
+
+	return documentsDTO, nil
+}
+
+func (service *DriverDocumentService) GetDriverDocumentFile(driverUUID, documentUUID, schoolUUID string) (string, error) {
+	document, err := service.fetchDriverDocument(driverUUID, documentUUID, schoolUUID)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.DocumentPath(document.File), nil
+}
+
+// The uploaded file is removed again when the document cannot be saved
+func (service *DriverDocumentService) AddDriverDocument(driverUUID, schoolUUID string, req dto.DriverDocumentRequestDTO, file, username string) (err error) {
+	defer func() {
+		if err != nil {
+			utils.DeleteDocument(file)
+		}
+	}()
+
+	if err := service.checkDriverAccess(driverUUID, schoolUUID); err != nil {
+		return err
+	}
+
+	document, err := toDriverDocumentEntity(req)
+	if err != nil {
+		return err
+	}
+
+	document.UUID = uuid.New()
+	document.ID = time.Now().UnixMilli()*1e6 + int64(document.UUID.ID()%1e6)
+	document.DriverUUID = uuid.MustParse(driverUUID)
+	document.File = file
+	document.CreatedBy = toNullString(username)
+
+	return service.driverDocumentRepository.SaveDriverDocument(document)
+}
+
+// file is empty when the scan is kept, otherwise the old scan is removed once the new one is saved
+func (service *DriverDocumentService) UpdateDriverDocument(driverUUID, documentUUID, schoolUUID string, req dto.DriverDocumentRequestDTO, file, username string) (err error) {
+	defer func() {
+		if err != nil {
+			utils.DeleteDocument(file)
+		}
+	}()
+
+	existing, err := service.fetchDriverDocument(driverUUID, documentUUID, schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	document, err := toDriverDocumentEntity(req)
+	if err != nil {
+		return err
+	}
+
+	document.UUID = existing.UUID
+	document.DriverUUID = existing.DriverUUID
+	document.File = existing.File
+	document.UpdatedBy = toNullString(username)
+	if file != "" {
+		document.File = file
+	}
+
+	if err := service.driverDocumentRepository.UpdateDriverDocument(document); err != nil {
+		return err
+	}
+
+	if file != "" {
+		if err := utils.DeleteDocument(existing.File); err != nil {
+			logger.LogError(err, "Failed to delete replaced driver document", map[string]interface{}{"file": existing.File})
+		}
+	}
+
+	return nil
+}
+
+func (service *DriverDocumentService) DeleteDriverDocument(driverUUID, documentUUID, schoolUUID, username string) error {
+	if _, err := service.fetchDriverDocument(driverUUID, documentUUID, schoolUUID); err != nil {
+		return err
+	}
+
+	return service.driverDocumentRepository.DeleteDriverDocument(driverUUID, documentUUID, username)
+}
+
+// Warns the school admins about every document that reached one of the alert thresholds.
+// A document found a few days late (e.g. 5 days left) is reported under the next threshold (7),
+// and the alert table makes sure each threshold is only sent once per expiry date.
+func (service *DriverDocumentService) SendExpiryAlerts() error {
+	documents, err := service.driverDocumentRepository.FetchExpiringDocuments(documentAlertDays[0])
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		threshold := documentAlertDays[0]
+		for _, days := range documentAlertDays {
+			if document.DaysLeft <= days {
+				threshold = days
+			}
+		}
+
+		isNew, err := service.driverDocumentRepository.SaveDocumentAlert(document, threshold)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			continue
+		}
+
+		phones, err := service.driverDocumentRepository.FetchSchoolAdminPhones(document.SchoolUUID.String())
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("The %s (%s) of driver %s %s expires on %s, in %d day(s).",
+			documentTypeLabel(document.Type), document.Number, document.DriverFirstName, document.DriverLastName,
+			document.ExpiresOn.Format(time.DateOnly), document.DaysLeft)
+
+		for _, phone := range phones {
+			if err := service.smsSender.Send(phone, message); err != nil {
+				logger.LogError(err, "Failed to send driver document expiry alert", map[string]interface{}{"document_uuid": document.DocumentUUID.String()})
+			}
+		}
+	}
+
+	return nil
+}
+
+func (service *DriverDocumentService) checkDriverAccess(driverUUID, schoolUUID string) error {
+	if _, err := uuid.Parse(driverUUID); err != nil {
+		return errors.New("driver not found", 404)
+	}
+
+	driverSchool, err := service.driverDocumentRepository.FetchDriverSchool(driverUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("driver not found", 404)
+		}
+		return err
+	}
+
+	if schoolUUID != "" && driverSchool.String != schoolUUID {
+		return errors.New("driver not found", 404)
+	}
+
+	return nil
+}
+
+func (service *DriverDocumentService) fetchDriverDocument(driverUUID, documentUUID, schoolUUID string) (entity.DriverDocument, error) {
+	if err := service.checkDriverAccess(driverUUID, schoolUUID); err != nil {
+		return entity.DriverDocument{}, err
+	}
+
+	if _, err := uuid.Parse(documentUUID); err != nil {
+		return entity.DriverDocument{}, errors.New("document not found", 404)
+	}
+
+	document, err := service.driverDocumentRepository.FetchSpecDriverDocument(driverUUID, documentUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.DriverDocument{}, errors.New("document not found", 404)
+		}
+		return entity.DriverDocument{}, err
+	}
+
+	return document, nil
+}
+
+func toDriverDocumentEntity(req dto.DriverDocumentRequestDTO) (entity.DriverDocument, error) {
+	issuedOn, err := time.Parse(time.DateOnly, req.IssuedOn)
+	if err != nil {
+		return entity.DriverDocument{}, errors.New("issued_on must be a date like 2024-01-31", 400)
+	}
+
+	document := entity.DriverDocument{
+		Type:     entity.DocumentType(req.Type),
+		Number:   req.Number,
+		IssuedOn: issuedOn,
+	}
+
+	if req.ExpiresOn != "" {
+		expiresOn, err := time.Parse(time.DateOnly, req.ExpiresOn)
+		if err != nil {
+			return entity.DriverDocument{}, errors.New("expires_on must be a date like 2029-01-31", 400)
+		}
+		if !expiresOn.After(issuedOn) {
+			return entity.DriverDocument{}, errors.New("expires_on must be after issued_on", 400)
+		}
+		document.ExpiresOn = sql.NullTime{Time: expiresOn, Valid: true}
+	} else if document.Type == entity.DocumentLicense {
+		return entity.DriverDocument{}, errors.New("expires_on is required for a license", 400)
+	}
+
+	return document, nil
+}
+
+func toDriverDocumentDTO(document entity.DriverDocument) dto.DriverDocumentResponseDTO {
+	documentDTO := dto.DriverDocumentResponseDTO{
+		UUID:       document.UUID.String(),
+		DriverUUID: document.DriverUUID.String(),
+		Type:       string(document.Type),
+		Number:     document.Number,
+		IssuedOn:   document.IssuedOn.Format(time.DateOnly),
+		CreatedAt:  safeTimeFormat(document.CreatedAt),
+		CreatedBy:  safeStringFormat(document.CreatedBy),
+		UpdatedAt:  safeTimeFormat(document.UpdatedAt),
+		UpdatedBy:  safeStringFormat(document.UpdatedBy),
+	}
+
+	if document.ExpiresOn.Valid {
+		documentDTO.ExpiresOn = document.ExpiresOn.Time.Format(time.DateOnly)
+		today := time.Now().Format(time.DateOnly)
+		documentDTO.Expired = documentDTO.ExpiresOn < today
+	}
+
+	return documentDTO
+}
+
+func documentTypeLabel(documentType entity.DocumentType) string {
+	switch documentType {
+	case entity.DocumentLicense:
+		return "driving licence"
+	case entity.DocumentBackgroundCheck:
+		return "background check"
+	case entity.DocumentHealthCertificate:
+		return "health certificate"
+	default:
+		return string(documentType)
+	}
+}
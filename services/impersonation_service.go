@@ -0,0 +1,122 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+)
+
+type ImpersonationServiceInterface interface {
+	StartImpersonation(adminUUID, adminName, targetUUID, reason, ipAddress string) (dto.ImpersonationResponseDTO, error)
+	CheckActiveImpersonation(impersonationUUID string) error
+	EndImpersonation(impersonationUUID string) error
+	RecordImpersonatedRequest(impersonationUUID, method, path string, status int) error
+}
+
+type ImpersonationService struct {
+	impersonationRepository repositories.ImpersonationRepositoryInterface
+	userRepository          repositories.UserRepositoryInterface
+}
+
+func NewImpersonationService(impersonationRepository repositories.ImpersonationRepositoryInterface, userRepository repositories.UserRepositoryInterface) ImpersonationService {
+	return ImpersonationService{
+		impersonationRepository: impersonationRepository,
+		userRepository:          userRepository,
+	}
+}
+
+// Opens an impersonation session and issues its access token. The token carries the target as
+// the subject and the admin in the act claim, it cannot be refreshed and dies with the session.
+func (service *ImpersonationService) StartImpersonation(adminUUID, adminName, targetUUID, reason, ipAddress string) (dto.ImpersonationResponseDTO, error) {
+	parsedAdminUUID, err := uuid.Parse(adminUUID)
+	if err != nil {
+		return dto.ImpersonationResponseDTO{}, errors.New("invalid admin UUID format", 400)
+	}
+
+	target, err := service.userRepository.FetchSpecificUser(targetUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dto.ImpersonationResponseDTO{}, errors.New("user not found", 404)
+		}
+		return dto.ImpersonationResponseDTO{}, err
+	}
+
+	if target.UUID == parsedAdminUUID {
+		return dto.ImpersonationResponseDTO{}, errors.New("you cannot impersonate yourself", 400)
+	}
+
+	if target.Role == entity.SuperAdmin {
+		return dto.ImpersonationResponseDTO{}, errors.New("super admins cannot be impersonated", 403)
+	}
+
+	impersonation := entity.Impersonation{
+		ID:         time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
+		UUID:       uuid.New(),
+		AdminUUID:  parsedAdminUUID,
+		TargetUUID: target.UUID,
+		Reason:     toNullString(reason),
+		IPAddress:  ipAddress,
+		ExpiresAt:  time.Now().Add(utils.ConfigDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute)),
+	}
+
+	if err := service.impersonationRepository.SaveImpersonation(impersonation); err != nil {
+		return dto.ImpersonationResponseDTO{}, err
+	}
+
+	accessToken, err := utils.GenerateImpersonationToken(fmt.Sprintf("%d", target.ID), target.UUID.String(), target.Username, target.RoleCode,
+		adminUUID, adminName, impersonation.UUID.String(), impersonation.ExpiresAt)
+	if err != nil {
+		return dto.ImpersonationResponseDTO{}, err
+	}
+
+	return dto.ImpersonationResponseDTO{
+		AccessToken:       accessToken,
+		ImpersonationUUID: impersonation.UUID.String(),
+		ExpiresAt:         impersonation.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (service *ImpersonationService) CheckActiveImpersonation(impersonationUUID string) error {
+	impersonation, err := service.impersonationRepository.FetchImpersonation(impersonationUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("impersonation session not found", 401)
+		}
+		return err
+	}
+
+	if impersonation.EndedAt != nil || impersonation.ExpiresAt.Before(time.Now()) {
+		return errors.New("impersonation session has ended", 401)
+	}
+
+	return nil
+}
+
+func (service *ImpersonationService) EndImpersonation(impersonationUUID string) error {
+	return service.impersonationRepository.EndImpersonation(impersonationUUID)
+}
+
+func (service *ImpersonationService) RecordImpersonatedRequest(impersonationUUID, method, path string, status int) error {
+	parsedUUID, err := uuid.Parse(impersonationUUID)
+	if err != nil {
+		return err
+	}
+
+	request := entity.ImpersonationRequest{
+		ID:                time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
+		ImpersonationUUID: parsedUUID,
+		Method:            method,
+		Path:              path,
+		Status:            status,
+	}
+
+	return service.impersonationRepository.SaveImpersonationRequest(request)
+}
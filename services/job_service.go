@@ -0,0 +1,406 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Does the work of a queued job, the payload is the JSON of what was passed to Enqueue.
+// A returned error is retried with backoff until the job runs out of attempts.
+type JobHandler func(payload json.RawMessage) error
+
+type JobServiceInterface interface {
+	GetJobs(page, limit int, status, name string) ([]dto.JobResponseDTO, int, error)
+	GetSpecJob(id string) (dto.JobResponseDTO, error)
+	RetryJob(id, username string) error
+	GetSchedules() ([]dto.JobScheduleResponseDTO, error)
+
+	Register(name string, handler JobHandler)
+	Schedule(name string, schedule utils.Schedule, run func() error)
+	Enqueue(tx *sqlx.Tx, name string, payload interface{}, runAt time.Time, username string) error
+	Run()
+}
+
+type JobService struct {
+	jobRepository repositories.JobRepositoryInterface
+	handlers      map[string]JobHandler
+	schedules     map[string]utils.Schedule
+	workerID      string
+}
+
+func NewJobService(jobRepository repositories.JobRepositoryInterface) JobService {
+	hostname, _ := os.Hostname()
+
+	return JobService{
+		jobRepository: jobRepository,
+		handlers:      make(map[string]JobHandler),
+		schedules:     make(map[string]utils.Schedule),
+		workerID:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// Empty status or name lists every job
+func (service *JobService) GetJobs(page, limit int, status, name string) ([]dto.JobResponseDTO, int, error) {
+	switch status {
+	case "", entity.JobPending, entity.JobRunning, entity.JobSucceeded, entity.JobFailed:
+	default:
+		return nil, 0, errors.New("invalid status, use 'pending', 'running', 'succeeded' or 'failed'", 400)
+	}
+
+	offset := (page - 1) * limit
+
+	jobs, err := service.jobRepository.FetchJobs(offset, limit, status, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.jobRepository.CountJobs(status, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jobsDTO := []dto.JobResponseDTO{}
+	for _, job := range jobs {
+		jobsDTO = append(jobsDTO, toJobDTO(job))
+	}
+
+	return jobsDTO, total, nil
+}
+
+func (service *JobService) GetSpecJob(id string) (dto.JobResponseDTO, error) {
+	job, err := service.fetchJob(id)
+	if err != nil {
+		return dto.JobResponseDTO{}, err
+	}
+
+	return toJobDTO(job), nil
+}
+
+// Only failed jobs can be retried, they get a fresh set of attempts and run right away
+func (service *JobService) RetryJob(id, username string) error {
+	job, err := service.fetchJob(id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != entity.JobFailed {
+		return errors.New("only failed jobs can be retried", 409)
+	}
+
+	requeued, err := service.jobRepository.RequeueJob(id)
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		return errors.New("only failed jobs can be retried", 409)
+	}
+
+	logger.LogInfo("Job retried", map[string]interface{}{"job_uuid": id, "job_name": job.Name, "retried_by": username})
+	return nil
+}
+
+func (service *JobService) GetSchedules() ([]dto.JobScheduleResponseDTO, error) {
+	schedules, err := service.jobRepository.FetchSchedules()
+	if err != nil {
+		return nil, err
+	}
+
+	schedulesDTO := []dto.JobScheduleResponseDTO{}
+	for _, schedule := range schedules {
+		scheduleDTO := dto.JobScheduleResponseDTO{
+			Name:          schedule.Name,
+			Spec:          schedule.Spec,
+			NextRunAt:     schedule.NextRunAt.Format(time.RFC3339),
+			LastJobStatus: schedule.LastStatus.String,
+		}
+		if schedule.LastRunAt.Valid {
+			scheduleDTO.LastRunAt = schedule.LastRunAt.Time.Format(time.RFC3339)
+		}
+		if schedule.LastJobUUID.Valid {
+			scheduleDTO.LastJobUUID = schedule.LastJobUUID.UUID.String()
+		}
+		schedulesDTO = append(schedulesDTO, scheduleDTO)
+	}
+
+	return schedulesDTO, nil
+}
+
+// Handlers and schedules must be registered before Run is started
+func (service *JobService) Register(name string, handler JobHandler) {
+	service.handlers[name] = handler
+}
+
+// Runs the job on the schedule. Whichever replica locks the schedule first enqueues the run,
+// and no run is enqueued while the previous one is still waiting or running.
+func (service *JobService) Schedule(name string, schedule utils.Schedule, run func() error) {
+	service.Register(name, func(json.RawMessage) error {
+		return run()
+	})
+	service.schedules[name] = schedule
+}
+
+// Queues a job for runAt, a zero runAt means right away. With the transaction of a change the job
+// is only queued if that change is committed.
+func (service *JobService) Enqueue(tx *sqlx.Tx, name string, payload interface{}, runAt time.Time, username string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	return service.jobRepository.SaveJob(tx, newJob(name, data, runAt, username))
+}
+
+// Syncs the schedules, starts JOB_WORKERS workers and then enqueues due schedules every
+// JOB_SCHEDULER_INTERVAL. Meant to run in its own goroutine.
+func (service *JobService) Run() {
+	service.Schedule("job_cleanup", utils.ConfigSchedule("JOB_CLEANUP_SCHEDULE", "30 3 * * *"), service.cleanupJobs)
+
+	now := time.Now()
+	for name, schedule := range service.schedules {
+		if err := service.jobRepository.SyncSchedule(name, schedule.String(), schedule.Next(now)); err != nil {
+			logger.LogError(err, "Failed to sync job schedule", map[string]interface{}{"schedule_name": name})
+		}
+	}
+
+	for i := 0; i < utils.ConfigInt("JOB_WORKERS", 2); i++ {
+		go service.work()
+	}
+
+	ticker := time.NewTicker(utils.ConfigDuration("JOB_SCHEDULER_INTERVAL", 30*time.Second))
+	defer ticker.Stop()
+
+	lockTimeout := utils.ConfigDuration("JOB_LOCK_TIMEOUT", 15*time.Minute)
+
+	for {
+		if err := service.enqueueDueSchedules(); err != nil {
+			logger.LogError(err, "Failed to enqueue scheduled jobs", nil)
+		}
+
+		abandoned, err := service.jobRepository.FailAbandonedJobs(lockTimeout)
+		if err != nil {
+			logger.LogError(err, "Failed to check abandoned jobs", nil)
+		} else if abandoned > 0 {
+			logger.LogWarn("Abandoned jobs marked failed", map[string]interface{}{"jobs": abandoned})
+		}
+
+		<-ticker.C
+	}
+}
+
+func (service *JobService) work() {
+	pollInterval := utils.ConfigDuration("JOB_POLL_INTERVAL", 5*time.Second)
+	lockTimeout := utils.ConfigDuration("JOB_LOCK_TIMEOUT", 15*time.Minute)
+
+	for {
+		job, err := service.jobRepository.ClaimJob(service.workerID, lockTimeout)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				logger.LogError(err, "Failed to claim job", nil)
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		service.runJob(job)
+	}
+}
+
+func (service *JobService) runJob(job entity.Job) {
+	details := map[string]interface{}{"job_uuid": job.UUID.String(), "job_name": job.Name, "attempt": job.Attempts}
+
+	jobErr := service.callHandler(job)
+	if jobErr == nil {
+		owned, err := service.jobRepository.CompleteJob(job.ID, service.workerID, job.Attempts)
+		if err != nil {
+			logger.LogError(err, "Failed to mark job succeeded", details)
+		} else if !owned {
+			logger.LogWarn("Job lock lost before it succeeded, the result is discarded", details)
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		logger.LogError(jobErr, "Job failed", details)
+		owned, err := service.jobRepository.FailJob(job.ID, service.workerID, job.Attempts, jobErr.Error())
+		if err != nil {
+			logger.LogError(err, "Failed to mark job failed", details)
+		} else if !owned {
+			logger.LogWarn("Job lock lost before it failed, the result is discarded", details)
+		}
+		return
+	}
+
+	runAt := time.Now().Add(jobRetryDelay(job.Attempts))
+	details["retry_at"] = runAt.Format(time.RFC3339)
+	logger.LogWarn("Job attempt failed: "+jobErr.Error(), details)
+
+	owned, err := service.jobRepository.RetryJobLater(job.ID, service.workerID, job.Attempts, jobErr.Error(), runAt)
+	if err != nil {
+		logger.LogError(err, "Failed to reschedule job", details)
+	} else if !owned {
+		logger.LogWarn("Job lock lost before it was rescheduled, the result is discarded", details)
+	}
+}
+
+// A panicking handler fails the attempt instead of taking the worker down
+func (service *JobService) callHandler(job entity.Job) (err error) {
+	handler, ok := service.handlers[job.Name]
+	if !ok {
+		return fmt.Errorf("no handler registered for job %q", job.Name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(job.Payload)
+}
+
+// Backoff configured by JOB_RETRY_BACKOFF and JOB_RETRY_BACKOFF_MAX
+func jobRetryDelay(attempts int) time.Duration {
+	return retryDelay(attempts, utils.ConfigDuration("JOB_RETRY_BACKOFF", 30*time.Second), utils.ConfigDuration("JOB_RETRY_BACKOFF_MAX", time.Hour))
+}
+
+// base after the first failed attempt, doubling with every further one up to maxDelay
+func retryDelay(attempts int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+func (service *JobService) enqueueDueSchedules() (err error) {
+	names := make([]string, 0, len(service.schedules))
+	for name := range service.schedules {
+		names = append(names, name)
+	}
+
+	tx, err := service.jobRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	due, err := service.jobRepository.FetchDueSchedules(tx, names)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, schedule := range due {
+		active, err := service.jobRepository.CheckJobActive(tx, schedule.Name)
+		if err != nil {
+			return err
+		}
+
+		jobUUID := ""
+		if !active {
+			job := newJob(schedule.Name, json.RawMessage("{}"), now, "system")
+			if err = service.jobRepository.SaveJob(tx, job); err != nil {
+				return err
+			}
+			jobUUID = job.UUID.String()
+		}
+
+		if err = service.jobRepository.UpdateScheduleRun(tx, schedule.Name, service.schedules[schedule.Name].Next(now), jobUUID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Finished jobs are kept for JOB_RETENTION so their outcome can still be looked up
+func (service *JobService) cleanupJobs() error {
+	deleted, err := service.jobRepository.DeleteFinishedJobs(time.Now().Add(-utils.ConfigDuration("JOB_RETENTION", 7*24*time.Hour)))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logger.LogInfo("Finished jobs cleaned up", map[string]interface{}{"jobs": deleted})
+	}
+	return nil
+}
+
+func (service *JobService) fetchJob(id string) (entity.Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.Job{}, errors.New("job not found", 404)
+	}
+
+	job, err := service.jobRepository.FetchSpecJob(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Job{}, errors.New("job not found", 404)
+		}
+		return entity.Job{}, err
+	}
+
+	return job, nil
+}
+
+func newJob(name string, payload json.RawMessage, runAt time.Time, username string) entity.Job {
+	jobUUID := uuid.New()
+
+	return entity.Job{
+		ID:          time.Now().UnixMilli()*1e6 + int64(jobUUID.ID()%1e6),
+		UUID:        jobUUID,
+		Name:        name,
+		Payload:     payload,
+		MaxAttempts: utils.ConfigInt("JOB_MAX_ATTEMPTS", 5),
+		RunAt:       runAt,
+		CreatedBy:   toNullString(username),
+	}
+}
+
+func toJobDTO(job entity.Job) dto.JobResponseDTO {
+	jobDTO := dto.JobResponseDTO{
+		UUID:        job.UUID.String(),
+		Name:        job.Name,
+		Payload:     job.Payload,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt.Format(time.RFC3339),
+		LockedBy:    job.LockedBy.String,
+		LastError:   job.LastError.String,
+		CreatedAt:   safeTimeFormat(job.CreatedAt),
+		CreatedBy:   safeStringFormat(job.CreatedBy),
+	}
+	if job.LockedAt.Valid {
+		jobDTO.LockedAt = job.LockedAt.Time.Format(time.RFC3339)
+	}
+	if job.FinishedAt.Valid {
+		jobDTO.FinishedAt = job.FinishedAt.Time.Format(time.RFC3339)
+	}
+
+	return jobDTO
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"shuttle/models/entity"
+	"shuttle/repositories"
+)
+
+// Records how the attempt was reported, owned decides whether the worker still holds the job
+type fakeJobRepository struct {
+	repositories.JobRepositoryInterface
+	owned    bool
+	status   string
+	workerID string
+	attempt  int
+}
+
+func (r *fakeJobRepository) report(status, workerID string, attempt int) (bool, error) {
+	r.workerID, r.attempt = workerID, attempt
+	if r.owned {
+		r.status = status
+	}
+	return r.owned, nil
+}
+
+func (r *fakeJobRepository) CompleteJob(jobID int64, workerID string, attempt int) (bool, error) {
+	return r.report(entity.JobSucceeded, workerID, attempt)
+}
+
+func (r *fakeJobRepository) RetryJobLater(jobID int64, workerID string, attempt int, lastError string, runAt time.Time) (bool, error) {
+	return r.report(entity.JobPending, workerID, attempt)
+}
+
+func (r *fakeJobRepository) FailJob(jobID int64, workerID string, attempt int, lastError string) (bool, error) {
+	return r.report(entity.JobFailed, workerID, attempt)
+}
+
+func TestRunJobReportsAsLockHolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		handlerErr error
+		attempts   int
+		owned      bool
+		wantStatus string
+	}{
+		{name: "succeeded", attempts: 1, owned: true, wantStatus: entity.JobSucceeded},
+		{name: "retried", handlerErr: stderrors.New("timeout"), attempts: 1, owned: true, wantStatus: entity.JobPending},
+		{name: "failed on the last attempt", handlerErr: stderrors.New("timeout"), attempts: 3, owned: true, wantStatus: entity.JobFailed},
+		{name: "lock lost before success", attempts: 1, owned: false, wantStatus: entity.JobRunning},
+		{name: "lock lost before retry", handlerErr: stderrors.New("timeout"), attempts: 1, owned: false, wantStatus: entity.JobRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeJobRepository{owned: tt.owned, status: entity.JobRunning}
+			service := NewJobService(repository)
+			service.Register("test", func(json.RawMessage) error { return tt.handlerErr })
+
+			service.runJob(entity.Job{ID: 1, Name: "test", Attempts: tt.attempts, MaxAttempts: 3})
+
+			if repository.status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", repository.status, tt.wantStatus)
+			}
+			if repository.workerID != service.workerID || repository.attempt != tt.attempts {
+				t.Errorf("reported as %s attempt %d, want %s attempt %d", repository.workerID, repository.attempt, service.workerID, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		base     time.Duration
+		maxDelay time.Duration
+		want     time.Duration
+	}{
+		{name: "no attempt yet", attempts: 0, base: 30 * time.Second, maxDelay: time.Hour, want: 30 * time.Second},
+		{name: "first attempt", attempts: 1, base: 30 * time.Second, maxDelay: time.Hour, want: 30 * time.Second},
+		{name: "second attempt doubles", attempts: 2, base: 30 * time.Second, maxDelay: time.Hour, want: time.Minute},
+		{name: "fourth attempt", attempts: 4, base: 30 * time.Second, maxDelay: time.Hour, want: 4 * time.Minute},
+		{name: "capped at the maximum", attempts: 8, base: 30 * time.Second, maxDelay: time.Hour, want: time.Hour},
+		{name: "many attempts do not overflow", attempts: 1000, base: time.Minute, maxDelay: 6 * time.Hour, want: 6 * time.Hour},
+		{name: "base above the maximum", attempts: 1, base: 2 * time.Hour, maxDelay: time.Hour, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.attempts, tt.base, tt.maxDelay); got != tt.want {
+				t.Errorf("retryDelay(%d, %s, %s) = %s, want %s", tt.attempts, tt.base, tt.maxDelay, got, tt.want)
+			}
+		})
+	}
+}
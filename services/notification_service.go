@@ -0,0 +1,426 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+)
+
+type notificationTemplate struct {
+	title string
+	body  string
+}
+
+// Title and body of every event in Indonesian and English, rendered with the data given to Notify.
+// The status function turns a shuttle status into words of the recipient's language.
+var notificationTemplates = map[string]map[string]notificationTemplate{
+	entity.NotificationShuttleStarted: {
+		"id": {"Shuttle dimulai", "Shuttle {{.student_name}} sudah dimulai, status: {{status .status}}."},
+		"en": {"Shuttle started", "The shuttle of {{.student_name}} has started, status: {{status .status}}."},
+	},
+	entity.NotificationShuttleStatusChanged: {
+		"id": {"Status shuttle berubah", "{{.student_name}} sekarang {{status .status}}."},
+		"en": {"Shuttle status changed", "{{.student_name}} is now {{status .status}}."},
+	},
+	// Written by the school admin, sent as it is in every language
+	entity.NotificationSchoolAnnouncement: {
+		"id": {"{{.title}}", "{{.body}}"},
+		"en": {"{{.title}}", "{{.body}}"},
+	},
+}
+
+// Shuttle statuses are stored in Indonesian
+var shuttleStatusLabels = map[string]map[string]string{
+	"en": {
+		"di rumah":          "at home",
+		"menunggu dijemput": "waiting to be picked up",
+		"menuju sekolah":    "on the way to school",
+		"di sekolah":        "at school",
+		"menuju rumah":      "on the way home",
+	},
+}
+
+var notificationChannels = []string{
+	entity.NotificationChannelInbox,
+	entity.NotificationChannelWebSocket,
+	entity.NotificationChannelPush,
+}
+
+// Job payload of one push notification, sent to every device of the user
+type pushNotification struct {
+	UserUUID string            `json:"user_uuid"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data"`
+}
+
+type NotificationServiceInterface interface {
+	GetNotifications(userUUID string, page, limit int, unreadOnly bool) ([]dto.NotificationResponseDTO, int, error)
+	GetUnreadCount(userUUID string) (int, error)
+	MarkRead(userUUID, id string) error
+	MarkAllRead(userUUID string) error
+
+	GetPreferences(userUUID string) (dto.NotificationPreferencesResponseDTO, error)
+	UpdatePreferences(userUUID string, req dto.NotificationPreferencesRequestDTO) error
+	AddPushDevice(userUUID string, req dto.PushDeviceRequestDTO) (dto.PushDeviceResponseDTO, error)
+	DeletePushDevice(userUUID, id string) error
+
+	Notify(event string, userUUIDs []string, data map[string]string) error
+	NotifyStudentGuardians(studentUUID, event string, data map[string]string) error
+	SendPush(payload json.RawMessage) error
+}
+
+type NotificationService struct {
+	notificationRepository repositories.NotificationRepositoryInterface
+	jobService             JobServiceInterface
+	pushSender             utils.PushSender
+}
+
+func NewNotificationService(notificationRepository repositories.NotificationRepositoryInterface, jobService JobServiceInterface, pushSender utils.PushSender) NotificationService {
+	return NotificationService{
+		notificationRepository: notificationRepository,
+		jobService:             jobService,
+		pushSender:             pushSender,
+	}
+}
+
+func (service *NotificationService) GetNotifications(userUUID string, page, limit int, unreadOnly bool) ([]dto.NotificationResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	notifications, err := service.notificationRepository.FetchNotifications(userUUID, offset, limit, unreadOnly)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.notificationRepository.CountNotifications(userUUID, unreadOnly)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notificationsDTO := []dto.NotificationResponseDTO{}
+	for _, notification := range notifications {
+		notificationsDTO = append(notificationsDTO, toNotificationDTO(notification))
+	}
+
+	return notificationsDTO, total, nil
+}
+
+func (service *NotificationService) GetUnreadCount(userUUID string) (int, error) {
+	return service.notificationRepository.CountNotifications(userUUID, true)
+}
+
+func (service *NotificationService) MarkRead(userUUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("notification not found", 404)
+	}
+
+	found, err := service.notificationRepository.MarkNotificationRead(userUUID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("notification not found", 404)
+	}
+
+	return nil
+}
+
+func (service *NotificationService) MarkAllRead(userUUID string) error {
+	_, err := service.notificationRepository.MarkAllNotificationsRead(userUUID)
+	return err
+}
+
+// Every channel of every event, on unless the user turned it off
+func (service *NotificationService) GetPreferences(userUUID string) (dto.NotificationPreferencesResponseDTO, error) {
+	language, err := service.notificationRepository.FetchNotificationLanguage(userUUID)
+	if err != nil {
+		return dto.NotificationPreferencesResponseDTO{}, err
+	}
+
+	saved, err := service.notificationRepository.FetchNotificationPreferences(userUUID)
+	if err != nil {
+		return dto.NotificationPreferencesResponseDTO{}, err
+	}
+
+	disabled := make(map[string]bool)
+	for _, preference := range saved {
+		disabled[preference.Event+"/"+preference.Channel] = !preference.Enabled
+	}
+
+	preferences := []dto.NotificationPreferenceDTO{}
+	for _, event := range notificationEvents() {
+		for _, channel := range notificationChannels {
+			enabled := !disabled[event+"/"+channel]
+			preferences = append(preferences, dto.NotificationPreferenceDTO{
+				Event:   event,
+				Channel: channel,
+				Enabled: &enabled,
+			})
+		}
+	}
+
+	return dto.NotificationPreferencesResponseDTO{
+		Language:    language,
+		Preferences: preferences,
+	}, nil
+}
+
+// Only the preferences sent are changed, an empty language keeps the current one
+func (service *NotificationService) UpdatePreferences(userUUID string, req dto.NotificationPreferencesRequestDTO) error {
+	for _, preference := range req.Preferences {
+		if _, ok := notificationTemplates[preference.Event]; !ok {
+			return errors.New(fmt.Sprintf("unknown notification event %s", preference.Event), 400)
+		}
+	}
+
+	tx, err := service.notificationRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if req.Language != "" {
+		if err := service.notificationRepository.SaveNotificationLanguage(tx, userUUID, req.Language); err != nil {
+			return err
+		}
+	}
+
+	for _, preference := range req.Preferences {
+		if err := service.notificationRepository.SaveNotificationPreference(tx, entity.NotificationPreference{
+			UserUUID: uuid.MustParse(userUUID),
+			Event:    preference.Event,
+			Channel:  preference.Channel,
+			Enabled:  *preference.Enabled,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (service *NotificationService) AddPushDevice(userUUID string, req dto.PushDeviceRequestDTO) (dto.PushDeviceResponseDTO, error) {
+	deviceUUID := uuid.New()
+
+	savedUUID, err := service.notificationRepository.SavePushDevice(entity.PushDevice{
+		ID:       time.Now().UnixMilli()*1e6 + int64(deviceUUID.ID()%1e6),
+		UUID:     deviceUUID,
+		UserUUID: uuid.MustParse(userUUID),
+		Token:    req.Token,
+		Platform: req.Platform,
+	})
+	if err != nil {
+		return dto.PushDeviceResponseDTO{}, err
+	}
+
+	return dto.PushDeviceResponseDTO{
+		UUID:     savedUUID.String(),
+		Platform: req.Platform,
+	}, nil
+}
+
+func (service *NotificationService) DeletePushDevice(userUUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("device not found", 404)
+	}
+
+	found, err := service.notificationRepository.DeletePushDevice(userUUID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("device not found", 404)
+	}
+
+	return nil
+}
+
+// Renders the event in each recipient's language and delivers it on the channels they have not
+// turned off. Push notifications go through the job queue so a provider outage is retried.
+// Delivery carries on for the other recipients when one fails, the last error is returned.
+func (service *NotificationService) Notify(event string, userUUIDs []string, data map[string]string) error {
+	if _, ok := notificationTemplates[event]; !ok {
+		return fmt.Errorf("no template for notification event %q", event)
+	}
+	if len(userUUIDs) == 0 {
+		return nil
+	}
+
+	recipients, err := service.notificationRepository.FetchRecipients(userUUIDs, event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, recipient := range recipients {
+		title, body, err := renderNotification(event, recipient.Language, data)
+		if err != nil {
+			return err
+		}
+
+		notificationUUID := uuid.New()
+		notification := entity.Notification{
+			ID:        time.Now().UnixMilli()*1e6 + int64(notificationUUID.ID()%1e6),
+			UUID:      notificationUUID,
+			UserUUID:  recipient.UserUUID,
+			Event:     event,
+			Title:     title,
+			Body:      body,
+			Data:      payload,
+			CreatedAt: time.Now(),
+		}
+
+		if !recipient.DisabledChannels[entity.NotificationChannelInbox] {
+			if err := service.notificationRepository.SaveNotification(notification); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+
+		if !recipient.DisabledChannels[entity.NotificationChannelWebSocket] {
+			service.sendWebSocket(notification)
+		}
+
+		if !recipient.DisabledChannels[entity.NotificationChannelPush] {
+			push := pushNotification{UserUUID: recipient.UserUUID.String(), Title: title, Body: body, Data: data}
+			if err := service.jobService.Enqueue(nil, "notification_push", push, time.Time{}, "system"); err != nil {
+				lastErr = err
+			}
+		}
+	}
+
+	return lastErr
+}
+
+func (service *NotificationService) NotifyStudentGuardians(studentUUID, event string, data map[string]string) error {
+	guardians, err := service.notificationRepository.FetchStudentGuardianUUIDs(studentUUID)
+	if err != nil {
+		return err
+	}
+
+	return service.Notify(event, guardians, data)
+}
+
+// Runs the notification_push job
+func (service *NotificationService) SendPush(payload json.RawMessage) error {
+	var push pushNotification
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return err
+	}
+
+	devices, err := service.notificationRepository.FetchPushDevices(push.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, device := range devices {
+		if err := service.pushSender.Send(device.Token, push.Title, push.Body, push.Data); err != nil {
+			logger.LogError(err, "Failed to send push notification", map[string]interface{}{"device_uuid": device.UUID.String()})
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// Best effort, the notification is still in the inbox when the user is offline or the write fails
+func (service *NotificationService) sendWebSocket(notification entity.Notification) {
+	userUUID := notification.UserUUID.String()
+
+	unread, err := service.notificationRepository.CountNotifications(userUUID, true)
+	if err != nil {
+		logger.LogError(err, "Failed to count unread notifications", map[string]interface{}{"user_uuid": userUUID})
+	}
+
+	message, err := json.Marshal(dto.NotificationMessageDTO{
+		Type:         "notification",
+		Notification: toNotificationDTO(notification),
+		UnreadCount:  unread,
+	})
+	if err != nil {
+		logger.LogError(err, "Failed to marshal notification message", nil)
+		return
+	}
+
+	if _, err := utils.SendToUser(userUUID, message); err != nil {
+		logger.LogError(err, "Failed to push notification over websocket", map[string]interface{}{"user_uuid": userUUID})
+	}
+}
+
+func renderNotification(event, language string, data map[string]string) (string, string, error) {
+	templates := notificationTemplates[event]
+	content, ok := templates[language]
+	if !ok {
+		content = templates["id"]
+	}
+
+	funcs := template.FuncMap{
+		"status": func(status string) string {
+			if label, ok := shuttleStatusLabels[language][status]; ok {
+				return label
+			}
+			return status
+		},
+	}
+
+	var rendered [2]string
+	for i, text := range []string{content.title, content.body} {
+		tmpl, err := template.New(event).Funcs(funcs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return "", "", err
+		}
+
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", "", err
+		}
+		rendered[i] = out.String()
+	}
+
+	return rendered[0], rendered[1], nil
+}
+
+func notificationEvents() []string {
+	events := make([]string, 0, len(notificationTemplates))
+	for event := range notificationTemplates {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	return events
+}
+
+func toNotificationDTO(notification entity.Notification) dto.NotificationResponseDTO {
+	notificationDTO := dto.NotificationResponseDTO{
+		UUID:      notification.UUID.String(),
+		Event:     notification.Event,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		Read:      notification.ReadAt.Valid,
+		CreatedAt: notification.CreatedAt.Format(time.RFC3339),
+	}
+	if notification.ReadAt.Valid {
+		notificationDTO.ReadAt = notification.ReadAt.Time.Format(time.RFC3339)
+	}
+
+	return notificationDTO
+}
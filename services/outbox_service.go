@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"shuttle/logger"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+)
+
+// Handles one domain event. Delivery is at least once: an event can arrive again after a crash or
+// a failed attempt of another subscriber's, so handlers should be idempotent on the event UUID.
+type DomainEventHandler func(event entity.DomainEvent) error
+
+type OutboxServiceInterface interface {
+	Subscribe(name string, eventTypes []string, handler DomainEventHandler)
+	Run()
+	CleanupEvents() error
+}
+
+type outboxSubscriber struct {
+	name       string
+	eventTypes map[string]bool
+	handler    DomainEventHandler
+}
+
+type OutboxService struct {
+	outboxRepository repositories.OutboxRepositoryInterface
+	subscribers      []outboxSubscriber
+	workerID         string
+}
+
+func NewOutboxService(outboxRepository repositories.OutboxRepositoryInterface) OutboxService {
+	hostname, _ := os.Hostname()
+
+	return OutboxService{
+		outboxRepository: outboxRepository,
+		workerID:         fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// Subscribers must be added before Run is started. The name records which subscribers already handled
+// an event, so it has to stay the same across releases. No event types means every event.
+func (service *OutboxService) Subscribe(name string, eventTypes []string, handler DomainEventHandler) {
+	subscriber := outboxSubscriber{name: name, handler: handler}
+	if len(eventTypes) > 0 {
+		subscriber.eventTypes = make(map[string]bool)
+		for _, eventType := range eventTypes {
+			subscriber.eventTypes[eventType] = true
+		}
+	}
+
+	service.subscribers = append(service.subscribers, subscriber)
+}
+
+// Relays committed events to the subscribers, polling every OUTBOX_POLL_INTERVAL while there is
+// nothing to do. Meant to run in its own goroutine.
+func (service *OutboxService) Run() {
+	pollInterval := utils.ConfigDuration("OUTBOX_POLL_INTERVAL", time.Second)
+	batchSize := utils.ConfigInt("OUTBOX_BATCH_SIZE", 100)
+	lockTimeout := utils.ConfigDuration("OUTBOX_LOCK_TIMEOUT", 5*time.Minute)
+
+	for {
+		events, err := service.outboxRepository.ClaimEvents(service.workerID, batchSize, lockTimeout)
+		if err != nil {
+			logger.LogError(err, "Failed to claim domain events", nil)
+		}
+
+		for _, event := range events {
+			service.relayEvent(event)
+		}
+
+		if len(events) < batchSize {
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+// An event is published once every interested subscriber handled it. Subscribers that already did
+// are skipped on the next attempt, the others are retried with backoff.
+func (service *OutboxService) relayEvent(event entity.DomainEvent) {
+	details := map[string]interface{}{"event_uuid": event.UUID.String(), "event_type": event.Type, "attempt": event.Attempts}
+
+	delivered, err := service.outboxRepository.FetchDeliveredSubscribers(event.ID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch domain event deliveries", details)
+		service.retryEvent(event, err, details)
+		return
+	}
+
+	done := make(map[string]bool, len(delivered))
+	for _, name := range delivered {
+		done[name] = true
+	}
+
+	var failed []string
+	for _, subscriber := range service.subscribers {
+		if done[subscriber.name] || (subscriber.eventTypes != nil && !subscriber.eventTypes[event.Type]) {
+			continue
+		}
+
+		if err := callSubscriber(subscriber, event); err != nil {
+			failed = append(failed, subscriber.name+": "+err.Error())
+			continue
+		}
+
+		if err := service.outboxRepository.SaveDelivery(event.ID, subscriber.name); err != nil {
+			failed = append(failed, subscriber.name+": "+err.Error())
+		}
+	}
+
+	if len(failed) == 0 {
+		if err := service.outboxRepository.PublishEvent(event.ID); err != nil {
+			logger.LogError(err, "Failed to mark domain event published", details)
+		}
+		return
+	}
+
+	service.retryEvent(event, fmt.Errorf("%s", strings.Join(failed, "; ")), details)
+}
+
+func (service *OutboxService) retryEvent(event entity.DomainEvent, eventErr error, details map[string]interface{}) {
+	if event.Attempts >= utils.ConfigInt("OUTBOX_MAX_ATTEMPTS", 10) {
+		logger.LogError(eventErr, "Domain event failed", details)
+		if err := service.outboxRepository.FailEvent(event.ID, eventErr.Error()); err != nil {
+			logger.LogError(err, "Failed to mark domain event failed", details)
+		}
+		return
+	}
+
+	delay := retryDelay(event.Attempts, utils.ConfigDuration("OUTBOX_RETRY_BACKOFF", 10*time.Second), utils.ConfigDuration("OUTBOX_RETRY_BACKOFF_MAX", time.Hour))
+	nextAttemptAt := time.Now().Add(delay)
+	details["retry_at"] = nextAttemptAt.Format(time.RFC3339)
+	logger.LogWarn("Domain event delivery failed: "+eventErr.Error(), details)
+
+	if err := service.outboxRepository.RetryEventLater(event.ID, eventErr.Error(), nextAttemptAt); err != nil {
+		logger.LogError(err, "Failed to reschedule domain event", details)
+	}
+}
+
+// A panicking subscriber fails its delivery instead of taking the relay down
+func callSubscriber(subscriber outboxSubscriber, event entity.DomainEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+
+	return subscriber.handler(event)
+}
+
+// Runs the outbox_cleanup job, published events are kept for OUTBOX_RETENTION
+func (service *OutboxService) CleanupEvents() error {
+	deleted, err := service.outboxRepository.DeletePublishedEvents(time.Now().Add(-utils.ConfigDuration("OUTBOX_RETENTION", 7*24*time.Hour)))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logger.LogInfo("Published domain events cleaned up", map[string]interface{}{"events": deleted})
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+)
+
+type PermissionServiceInterface interface {
+	GetRolePermissions(roleCode string) (map[string]struct{}, error)
+	GetRoleBase(roleCode string) (dto.Role, error)
+	GetAllRoles() ([]dto.AccessRoleResponseDTO, error)
+	GetSpecRole(code string) (dto.AccessRoleResponseDTO, error)
+	GetAllPermissions() ([]dto.PermissionResponseDTO, error)
+	AddRole(req dto.AccessRoleRequestDTO, username string) error
+	UpdateRole(code string, req dto.AccessRoleRequestDTO, username string) error
+	DeleteRole(code string) error
+}
+
+type PermissionService struct {
+	permissionRepository repositories.PermissionRepositoryInterface
+	cache                *permissionCache
+}
+
+// permissionCache keeps the role -> permission set lookup in memory so the
+// middleware does not hit the database on every request. It is shared by
+// every copy of PermissionService and dropped whenever a role changes.
+type permissionCache struct {
+	mutex       sync.RWMutex
+	permissions map[string]map[string]struct{}
+	roles       map[string]dto.Role
+	loadedAt    time.Time
+}
+
+func NewPermissionService(permissionRepository repositories.PermissionRepositoryInterface) PermissionService {
+	return PermissionService{
+		permissionRepository: permissionRepository,
+		cache:                &permissionCache{},
+	}
+}
+
+func (service *PermissionService) GetRolePermissions(roleCode string) (map[string]struct{}, error) {
+	if err := service.loadCache(); err != nil {
+		return nil, err
+	}
+
+	service.cache.mutex.RLock()
+	defer service.cache.mutex.RUnlock()
+
+	if _, ok := service.cache.roles[roleCode]; !ok {
+		return nil, errors.New("role is not registered", 403)
+	}
+
+	return service.cache.permissions[roleCode], nil
+}
+
+func (service *PermissionService) GetRoleBase(roleCode string) (dto.Role, error) {
+	if err := service.loadCache(); err != nil {
+		return "", err
+	}
+
+	service.cache.mutex.RLock()
+	defer service.cache.mutex.RUnlock()
+
+	base, ok := service.cache.roles[roleCode]
+	if !ok {
+		return "", errors.New("role code is not registered", 400)
+	}
+
+	return base, nil
+}
+
+func (service *PermissionService) GetAllRoles() ([]dto.AccessRoleResponseDTO, error) {
+	roles, err := service.permissionRepository.FetchAllRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	var rolesDTO []dto.AccessRoleResponseDTO
+	for _, role := range roles {
+		rolesDTO = append(rolesDTO, toAccessRoleDTO(role))
+	}
+
+	return rolesDTO, nil
+}
+
+func (service *PermissionService) GetSpecRole(code string) (dto.AccessRoleResponseDTO, error) {
+	role, err := service.permissionRepository.FetchSpecRole(strings.ToUpper(code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dto.AccessRoleResponseDTO{}, errors.New("role not found", 404)
+		}
+		return dto.AccessRoleResponseDTO{}, err
+	}
+
+	return toAccessRoleDTO(role), nil
+}
+
+func (service *PermissionService) GetAllPermissions() ([]dto.PermissionResponseDTO, error) {
+	permissions, err := service.permissionRepository.FetchAllPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	var permissionsDTO []dto.PermissionResponseDTO
+	for _, permission := range permissions {
+		permissionsDTO = append(permissionsDTO, dto.PermissionResponseDTO{
+			Code:        permission.Code,
+			Description: permission.Description.String,
+		})
+	}
+
+	return permissionsDTO, nil
+}
+
+func (service *PermissionService) AddRole(req dto.AccessRoleRequestDTO, username string) error {
+	code := strings.ToUpper(req.Code)
+
+	if _, err := service.permissionRepository.FetchSpecRole(code); err == nil {
+		return errors.New("role code already exists", 409)
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	if err := service.validatePermissions(req.Permissions); err != nil {
+		return err
+	}
+
+	role := entity.AccessRole{
+		Code:        code,
+		Name:        req.Name,
+		Base:        entity.Role(strings.ToLower(string(req.Base))),
+		Description: toNullString(req.Description),
+		CreatedBy:   toNullString(username),
+	}
+
+	tx, err := service.permissionRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.permissionRepository.SaveRole(tx, role); err != nil {
+		return err
+	}
+
+	if err := service.permissionRepository.ReplaceRolePermissions(tx, code, req.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	service.invalidateCache()
+
+	return nil
+}
+
+func (service *PermissionService) UpdateRole(code string, req dto.AccessRoleRequestDTO, username string) error {
+	existing, err := service.permissionRepository.FetchSpecRole(strings.ToUpper(code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("role not found", 404)
+		}
+		return err
+	}
+
+	base := entity.Role(strings.ToLower(string(req.Base)))
+	if existing.IsSystem && base != existing.Base {
+		return errors.New("the base of a system role cannot be changed", 400)
+	}
+
+	if base != existing.Base {
+		count, err := service.permissionRepository.CountUsersWithRole(existing.Code)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("the base of a role that is assigned to users cannot be changed", 409)
+		}
+	}
+
+	if err := service.validatePermissions(req.Permissions); err != nil {
+		return err
+	}
+
+	role := entity.AccessRole{
+		Code:        existing.Code,
+		Name:        req.Name,
+		Base:        base,
+		Description: toNullString(req.Description),
+		UpdatedBy:   toNullString(username),
+	}
+
+	tx, err := service.permissionRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.permissionRepository.UpdateRole(tx, role); err != nil {
+		return err
+	}
+
+	if err := service.permissionRepository.ReplaceRolePermissions(tx, role.Code, req.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	service.invalidateCache()
+
+	return nil
+}
+
+func (service *PermissionService) DeleteRole(code string) error {
+	role, err := service.permissionRepository.FetchSpecRole(strings.ToUpper(code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("role not found", 404)
+		}
+		return err
+	}
+
+	if role.IsSystem {
+		return errors.New("system roles cannot be deleted", 400)
+	}
+
+	count, err := service.permissionRepository.CountUsersWithRole(role.Code)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("role is still assigned to users", 409)
+	}
+
+	if err := service.permissionRepository.DeleteRole(role.Code); err != nil {
+		return err
+	}
+
+	service.invalidateCache()
+
+	return nil
+}
+
+func (service *PermissionService) validatePermissions(permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	unique := make(map[string]struct{}, len(permissions))
+	for _, permission := range permissions {
+		unique[permission] = struct{}{}
+	}
+
+	codes := make([]string, 0, len(unique))
+	for permission := range unique {
+		codes = append(codes, permission)
+	}
+
+	count, err := service.permissionRepository.CountKnownPermissions(codes)
+	if err != nil {
+		return err
+	}
+
+	if count != len(codes) {
+		return errors.New("one or more permissions are not registered", 400)
+	}
+
+	return nil
+}
+
+func (service *PermissionService) loadCache() error {
+	ttl := utils.ConfigDuration("PERMISSION_CACHE_TTL", time.Minute)
+
+	service.cache.mutex.RLock()
+	fresh := service.cache.roles != nil && time.Since(service.cache.loadedAt) < ttl
+	service.cache.mutex.RUnlock()
+
+	if fresh {
+		return nil
+	}
+
+	roles, err := service.permissionRepository.FetchAllRoles()
+	if err != nil {
+		return err
+	}
+
+	permissions := make(map[string]map[string]struct{}, len(roles))
+	bases := make(map[string]dto.Role, len(roles))
+	for _, role := range roles {
+		set := make(map[string]struct{}, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			set[permission] = struct{}{}
+		}
+		permissions[role.Code] = set
+		bases[role.Code] = dto.Role(role.Base)
+	}
+
+	service.cache.mutex.Lock()
+	service.cache.permissions = permissions
+	service.cache.roles = bases
+	service.cache.loadedAt = time.Now()
+	service.cache.mutex.Unlock()
+
+	return nil
+}
+
+func (service *PermissionService) invalidateCache() {
+	service.cache.mutex.Lock()
+	service.cache.roles = nil
+	service.cache.permissions = nil
+	service.cache.mutex.Unlock()
+}
+
+func toAccessRoleDTO(role entity.AccessRole) dto.AccessRoleResponseDTO {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return dto.AccessRoleResponseDTO{
+		Code:        role.Code,
+		Name:        role.Name,
+		Base:        dto.Role(role.Base),
+		Description: role.Description.String,
+		IsSystem:    role.IsSystem,
+		Permissions: permissions,
+		CreatedAt:   safeTimeFormat(role.CreatedAt),
+		CreatedBy:   safeStringFormat(role.CreatedBy),
+		UpdatedAt:   safeTimeFormat(role.UpdatedAt),
+		UpdatedBy:   safeStringFormat(role.UpdatedBy),
+	}
+}
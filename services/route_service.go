@@ -0,0 +1,168 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+)
+
+type RouteServiceInterface interface {
+	GetRoutes(schoolUUID string) ([]dto.RouteResponseDTO, error)
+	GetSpecRoute(routeUUID, schoolUUID string) (dto.RouteResponseDTO, error)
+	AddRoute(schoolUUID string, req dto.RouteRequestDTO, username string) error
+	UpdateRoute(routeUUID, schoolUUID string, req dto.RouteRequestDTO, username string) error
+	DeleteRoute(routeUUID, schoolUUID, username string) error
+}
+
+type RouteService struct {
+	routeRepository repositories.RouteRepositoryInterface
+}
+
+func NewRouteService(routeRepository repositories.RouteRepositoryInterface) RouteService {
+	return RouteService{
+		routeRepository: routeRepository,
+	}
+}
+
+func (service *RouteService) GetRoutes(schoolUUID string) ([]dto.RouteResponseDTO, error) {
+	routes, err := service.routeRepository.FetchRoutes(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	routesDTO := []dto.RouteResponseDTO{}
+	for _, route := range routes {
+		routesDTO = append(routesDTO, toRouteDTO(route))
+	}
+
+	return routesDTO, nil
+}
+
+func (service *RouteService) GetSpecRoute(routeUUID, schoolUUID string) (dto.RouteResponseDTO, error) {
+	route, err := service.fetchRoute(routeUUID, schoolUUID)
+	if err != nil {
+		return dto.RouteResponseDTO{}, err
+	}
+
+	return toRouteDTO(route), nil
+}
+
+func (service *RouteService) AddRoute(schoolUUID string, req dto.RouteRequestDTO, username string) error {
+	if err := service.checkRouteName(schoolUUID, "", req.Name); err != nil {
+		return err
+	}
+
+	route, err := toRouteEntity(req)
+	if err != nil {
+		return err
+	}
+
+	route.UUID = uuid.New()
+	route.ID = time.Now().UnixMilli()*1e6 + int64(route.UUID.ID()%1e6)
+	route.SchoolUUID = uuid.MustParse(schoolUUID)
+	route.CreatedBy = toNullString(username)
+
+	return service.routeRepository.SaveRoute(route)
+}
+
+func (service *RouteService) UpdateRoute(routeUUID, schoolUUID string, req dto.RouteRequestDTO, username string) error {
+	existing, err := service.fetchRoute(routeUUID, schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	if err := service.checkRouteName(schoolUUID, routeUUID, req.Name); err != nil {
+		return err
+	}
+
+	route, err := toRouteEntity(req)
+	if err != nil {
+		return err
+	}
+
+	route.UUID = existing.UUID
+	route.SchoolUUID = existing.SchoolUUID
+	route.UpdatedBy = toNullString(username)
+
+	return service.routeRepository.UpdateRoute(route)
+}
+
+func (service *RouteService) DeleteRoute(routeUUID, schoolUUID, username string) error {
+	if _, err := service.fetchRoute(routeUUID, schoolUUID); err != nil {
+		return err
+	}
+
+	return service.routeRepository.DeleteRoute(schoolUUID, routeUUID, username)
+}
+
+func (service *RouteService) fetchRoute(routeUUID, schoolUUID string) (entity.Route, error) {
+	if _, err := uuid.Parse(routeUUID); err != nil {
+		return entity.Route{}, errors.New("route not found", 404)
+	}
+
+	route, err := service.routeRepository.FetchSpecRoute(schoolUUID, routeUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Route{}, errors.New("route not found", 404)
+		}
+		return entity.Route{}, err
+	}
+
+	return route, nil
+}
+
+func (service *RouteService) checkRouteName(schoolUUID, routeUUID, name string) error {
+	exists, err := service.routeRepository.CheckRouteNameExists(schoolUUID, routeUUID, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("route with similar name already exists", 409)
+	}
+
+	return nil
+}
+
+func toRouteEntity(req dto.RouteRequestDTO) (entity.Route, error) {
+	points, err := json.Marshal(req.Points)
+	if err != nil {
+		return entity.Route{}, err
+	}
+
+	status := req.Status
+	if status == "" {
+		status = "active"
+	}
+
+	return entity.Route{
+		Name:        req.Name,
+		Description: toNullString(req.Description),
+		Points:      string(points),
+		Status:      status,
+	}, nil
+}
+
+func toRouteDTO(route entity.Route) dto.RouteResponseDTO {
+	points := []dto.RoutePointDTO{}
+	json.Unmarshal([]byte(route.Points), &points)
+
+	return dto.RouteResponseDTO{
+		UUID:         route.UUID.String(),
+		Name:         route.Name,
+		Description:  route.Description.String,
+		Points:       points,
+		Status:       route.Status,
+		VehicleCount: route.VehicleCount,
+		CreatedAt:    safeTimeFormat(route.CreatedAt),
+		CreatedBy:    safeStringFormat(route.CreatedBy),
+		UpdatedAt:    safeTimeFormat(route.UpdatedAt),
+		UpdatedBy:    safeStringFormat(route.UpdatedBy),
+	}
+}
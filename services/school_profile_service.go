@@ -0,0 +1,322 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+	_ "time/tzdata" // timezones are checked against the embedded database, not the one of the host
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+)
+
+const bellTimeLayout = "15:04"
+
+// Index + 1 is the ISO 8601 weekday stored in the database
+var schoolWeekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+type SchoolProfileServiceInterface interface {
+	GetSchoolProfile(schoolUUID string) (dto.SchoolProfileResponseDTO, error)
+	UpdateSchoolProfile(schoolUUID string, req dto.SchoolProfileRequestDTO, username string) error
+
+	GetClosures(schoolUUID, from string) ([]dto.SchoolClosureResponseDTO, error)
+	AddClosure(schoolUUID string, req dto.SchoolClosureRequestDTO, username string) error
+	UpdateClosure(schoolUUID, id string, req dto.SchoolClosureRequestDTO, username string) error
+	DeleteClosure(schoolUUID, id, username string) error
+
+	IsSchoolClosed(schoolUUID string, at time.Time) (bool, error)
+}
+
+type SchoolProfileService struct {
+	schoolProfileRepository repositories.SchoolProfileRepositoryInterface
+}
+
+func NewSchoolProfileService(schoolProfileRepository repositories.SchoolProfileRepositoryInterface) SchoolProfileService {
+	return SchoolProfileService{
+		schoolProfileRepository: schoolProfileRepository,
+	}
+}
+
+func (service *SchoolProfileService) GetSchoolProfile(schoolUUID string) (dto.SchoolProfileResponseDTO, error) {
+	school, err := service.fetchSchoolProfile(schoolUUID)
+	if err != nil {
+		return dto.SchoolProfileResponseDTO{}, err
+	}
+
+	hours, err := service.schoolProfileRepository.FetchOperatingHours(schoolUUID)
+	if err != nil {
+		return dto.SchoolProfileResponseDTO{}, err
+	}
+
+	profile := dto.SchoolProfileResponseDTO{
+		UUID:           school.UUID.String(),
+		Name:           school.Name,
+		GeofenceRadius: school.Geofence,
+		Timezone:       school.Timezone,
+		OperatingHours: []dto.SchoolOperatingHourDTO{},
+	}
+
+	if school.Latitude.Valid && school.Longitude.Valid {
+		profile.Latitude = &school.Latitude.Float64
+		profile.Longitude = &school.Longitude.Float64
+	}
+
+	for _, hour := range hours {
+		profile.OperatingHours = append(profile.OperatingHours, dto.SchoolOperatingHourDTO{
+			Weekday:  schoolWeekdays[hour.Weekday-1],
+			StartsAt: hour.StartsAt,
+			EndsAt:   hour.EndsAt,
+		})
+	}
+
+	return profile, nil
+}
+
+// Replaces the location, timezone and the whole week of operating hours
+func (service *SchoolProfileService) UpdateSchoolProfile(schoolUUID string, req dto.SchoolProfileRequestDTO, username string) error {
+	school, err := service.fetchSchoolProfile(schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := loadSchoolLocation(req.Timezone); err != nil {
+		return err
+	}
+
+	hours, err := toOperatingHourEntities(school.UUID, req.OperatingHours)
+	if err != nil {
+		return err
+	}
+
+	school.Latitude = sql.NullFloat64{Float64: *req.Latitude, Valid: true}
+	school.Longitude = sql.NullFloat64{Float64: *req.Longitude, Valid: true}
+	school.Geofence = req.GeofenceRadius
+	school.Timezone = req.Timezone
+	school.UpdatedBy = toNullString(username)
+
+	tx, err := service.schoolProfileRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.schoolProfileRepository.UpdateSchoolProfile(tx, school); err != nil {
+		return err
+	}
+
+	if err := service.schoolProfileRepository.ReplaceOperatingHours(tx, schoolUUID, hours); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Lists the closures that have not ended before from, today in the school's timezone by default
+func (service *SchoolProfileService) GetClosures(schoolUUID, from string) ([]dto.SchoolClosureResponseDTO, error) {
+	school, err := service.fetchSchoolProfile(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var since time.Time
+	if from != "" {
+		if since, err = time.Parse(time.DateOnly, from); err != nil {
+			return nil, errors.New("from must be a date like 2025-01-31", 400)
+		}
+	} else {
+		location, err := loadSchoolLocation(school.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		since, _ = time.Parse(time.DateOnly, time.Now().In(location).Format(time.DateOnly))
+	}
+
+	closures, err := service.schoolProfileRepository.FetchClosures(schoolUUID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	closuresDTO := []dto.SchoolClosureResponseDTO{}
+	for _, closure := range closures {
+		closuresDTO = append(closuresDTO, dto.SchoolClosureResponseDTO{
+			UUID:      closure.UUID.String(),
+			Name:      closure.Name,
+			StartsOn:  closure.StartsOn.Format(time.DateOnly),
+			EndsOn:    closure.EndsOn.Format(time.DateOnly),
+			CreatedAt: safeTimeFormat(closure.CreatedAt),
+			CreatedBy: safeStringFormat(closure.CreatedBy),
+			UpdatedAt: safeTimeFormat(closure.UpdatedAt),
+			UpdatedBy: safeStringFormat(closure.UpdatedBy),
+		})
+	}
+
+	return closuresDTO, nil
+}
+
+func (service *SchoolProfileService) AddClosure(schoolUUID string, req dto.SchoolClosureRequestDTO, username string) error {
+	school, err := service.fetchSchoolProfile(schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	closure, err := service.toClosureEntity(schoolUUID, "", req)
+	if err != nil {
+		return err
+	}
+
+	closure.UUID = uuid.New()
+	closure.ID = time.Now().UnixMilli()*1e6 + int64(closure.UUID.ID()%1e6)
+	closure.SchoolUUID = school.UUID
+	closure.CreatedBy = toNullString(username)
+
+	return service.schoolProfileRepository.SaveClosure(closure)
+}
+
+func (service *SchoolProfileService) UpdateClosure(schoolUUID, id string, req dto.SchoolClosureRequestDTO, username string) error {
+	existing, err := service.fetchClosure(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	closure, err := service.toClosureEntity(schoolUUID, id, req)
+	if err != nil {
+		return err
+	}
+
+	closure.UUID = existing.UUID
+	closure.SchoolUUID = existing.SchoolUUID
+	closure.UpdatedBy = toNullString(username)
+
+	return service.schoolProfileRepository.UpdateClosure(closure)
+}
+
+func (service *SchoolProfileService) DeleteClosure(schoolUUID, id, username string) error {
+	if _, err := service.fetchClosure(schoolUUID, id); err != nil {
+		return err
+	}
+
+	return service.schoolProfileRepository.DeleteClosure(schoolUUID, id, username)
+}
+
+// Whether no trips should run for the school on the day at falls on in the school's timezone
+func (service *SchoolProfileService) IsSchoolClosed(schoolUUID string, at time.Time) (bool, error) {
+	return service.schoolProfileRepository.IsSchoolClosed(schoolUUID, at)
+}
+
+func (service *SchoolProfileService) fetchSchoolProfile(schoolUUID string) (entity.School, error) {
+	if _, err := uuid.Parse(schoolUUID); err != nil {
+		return entity.School{}, errors.New("school not found", 404)
+	}
+
+	school, err := service.schoolProfileRepository.FetchSchoolProfile(schoolUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.School{}, errors.New("school not found", 404)
+		}
+		return entity.School{}, err
+	}
+
+	return school, nil
+}
+
+func (service *SchoolProfileService) fetchClosure(schoolUUID, id string) (entity.SchoolClosure, error) {
+	if _, err := service.fetchSchoolProfile(schoolUUID); err != nil {
+		return entity.SchoolClosure{}, err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.SchoolClosure{}, errors.New("closure not found", 404)
+	}
+
+	closure, err := service.schoolProfileRepository.FetchSpecClosure(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.SchoolClosure{}, errors.New("closure not found", 404)
+		}
+		return entity.SchoolClosure{}, err
+	}
+
+	return closure, nil
+}
+
+func (service *SchoolProfileService) toClosureEntity(schoolUUID, id string, req dto.SchoolClosureRequestDTO) (entity.SchoolClosure, error) {
+	startsOn, err := time.Parse(time.DateOnly, req.StartsOn)
+	if err != nil {
+		return entity.SchoolClosure{}, errors.New("starts_on must be a date like 2025-12-25", 400)
+	}
+
+	endsOn, err := time.Parse(time.DateOnly, req.EndsOn)
+	if err != nil {
+		return entity.SchoolClosure{}, errors.New("ends_on must be a date like 2025-12-26", 400)
+	}
+
+	if endsOn.Before(startsOn) {
+		return entity.SchoolClosure{}, errors.New("ends_on must not be before starts_on", 400)
+	}
+
+	overlaps, err := service.schoolProfileRepository.CheckClosureOverlap(schoolUUID, id, startsOn, endsOn)
+	if err != nil {
+		return entity.SchoolClosure{}, err
+	}
+	if overlaps {
+		return entity.SchoolClosure{}, errors.New("closure overlaps another closure of the school", 409)
+	}
+
+	return entity.SchoolClosure{
+		Name:     req.Name,
+		StartsOn: startsOn,
+		EndsOn:   endsOn,
+	}, nil
+}
+
+func toOperatingHourEntities(schoolUUID uuid.UUID, hoursDTO []dto.SchoolOperatingHourDTO) ([]entity.SchoolOperatingHour, error) {
+	hours := []entity.SchoolOperatingHour{}
+	seen := make(map[string]struct{})
+
+	for _, hour := range hoursDTO {
+		if _, ok := seen[hour.Weekday]; ok {
+			return nil, errors.New(hour.Weekday+" is listed more than once", 400)
+		}
+		seen[hour.Weekday] = struct{}{}
+
+		startsAt, err := time.Parse(bellTimeLayout, hour.StartsAt)
+		if err != nil {
+			return nil, errors.New("starts_at of "+hour.Weekday+" must be a time like 07:00", 400)
+		}
+
+		endsAt, err := time.Parse(bellTimeLayout, hour.EndsAt)
+		if err != nil {
+			return nil, errors.New("ends_at of "+hour.Weekday+" must be a time like 15:00", 400)
+		}
+
+		if !endsAt.After(startsAt) {
+			return nil, errors.New("ends_at of "+hour.Weekday+" must be after starts_at", 400)
+		}
+
+		for i, weekday := range schoolWeekdays {
+			if weekday == hour.Weekday {
+				hours = append(hours, entity.SchoolOperatingHour{
+					SchoolUUID: schoolUUID,
+					Weekday:    i + 1,
+					StartsAt:   startsAt.Format(bellTimeLayout),
+					EndsAt:     endsAt.Format(bellTimeLayout),
+				})
+			}
+		}
+	}
+
+	return hours, nil
+}
+
+// Local is refused, the database has to understand the name as well
+func loadSchoolLocation(timezone string) (*time.Location, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" || timezone == "" {
+		return nil, errors.New("timezone must be a name like Asia/Jakarta", 400)
+	}
+
+	return location, nil
+}
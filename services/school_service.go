@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"shuttle/errors"
 	"shuttle/models/dto"
 	"shuttle/models/entity"
 	"shuttle/repositories"
@@ -15,20 +16,22 @@ import (
 type SchoolServiceInterface interface {
 	GetAllSchools(page, limit int, sortField, sortDirection string) ([]dto.SchoolResponseDTO, int, error)
 	GetSpecSchool(uuid string) (dto.SchoolResponseDTO, error)
-	AddSchool(req dto.SchoolRequestDTO, username string) error
-	UpdateSchool(id string, req dto.SchoolRequestDTO, username string) error
-	DeleteSchool(id, username, adminUUID string) error
+	AddSchool(req dto.SchoolRequestDTO, actor entity.AuditActor) error
+	UpdateSchool(id string, req dto.SchoolRequestDTO, actor entity.AuditActor) error
+	GetDeletionPlan(id string) (dto.SchoolDeletionPlanResponseDTO, error)
+	DeleteSchool(id string, actor entity.AuditActor) error
+	RestoreSchool(id string, actor entity.AuditActor) (dto.SchoolDeletionPlanResponseDTO, error)
 }
 
 type SchoolService struct {
 	schoolRepository repositories.SchoolRepositoryInterface
-	userRepository   repositories.UserRepositoryInterface
+	auditService     AuditServiceInterface
 }
 
-func NewSchoolService(schoolRepository repositories.SchoolRepositoryInterface, userRepository repositories.UserRepositoryInterface) SchoolService {
+func NewSchoolService(schoolRepository repositories.SchoolRepositoryInterface, auditService AuditServiceInterface) SchoolService {
 	return SchoolService{
 		schoolRepository: schoolRepository,
-		userRepository:   userRepository,
+		auditService:     auditService,
 	}
 }
 
@@ -121,7 +124,7 @@ func (service *SchoolService) GetSpecSchool(id string) (dto.SchoolResponseDTO, e
 	return schoolDTO, nil
 }
 
-func (service *SchoolService) AddSchool(req dto.SchoolRequestDTO, username string) error {
+func (service *SchoolService) AddSchool(req dto.SchoolRequestDTO, actor entity.AuditActor) error {
 	school := entity.School{
 		ID:          time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
 		UUID:        uuid.New(),
@@ -130,22 +133,25 @@ func (service *SchoolService) AddSchool(req dto.SchoolRequestDTO, username strin
 		Contact:     req.Contact,
 		Email:       req.Email,
 		Description: req.Description,
-		CreatedBy:   toNullString(username),
+		CreatedBy:   toNullString(actor.Username),
 	}
 
 	if err := service.schoolRepository.SaveSchool(school); err != nil {
 		return err
 	}
 
+	service.auditService.Record(actor, entity.AuditCreate, entity.AuditEntitySchool, school.UUID.String(), nil, school)
 	return nil
 }
 
-func (service *SchoolService) UpdateSchool(id string, req dto.SchoolRequestDTO, username string) error {
+func (service *SchoolService) UpdateSchool(id string, req dto.SchoolRequestDTO, actor entity.AuditActor) error {
 	parsedUUID, err := uuid.Parse(id)
 	if err != nil {
 		return err
 	}
 
+	before, _ := service.fetchSchool(id)
+
 	school := entity.School{
 		UUID:        parsedUUID,
 		Name:        req.Name,
@@ -154,86 +160,141 @@ func (service *SchoolService) UpdateSchool(id string, req dto.SchoolRequestDTO,
 		Email:       req.Email,
 		Description: req.Description,
 		UpdatedAt:   toNullTime(time.Now()),
-		UpdatedBy:   toNullString(username),
+		UpdatedBy:   toNullString(actor.Username),
 	}
 
 	if err := service.schoolRepository.UpdateSchool(school); err != nil {
 		return err
 	}
 
+	if after, err := service.fetchSchool(id); err == nil {
+		service.auditService.Record(actor, entity.AuditUpdate, entity.AuditEntitySchool, id, before, after)
+	}
 	return nil
 }
 
-func (service *SchoolService) DeleteSchool(id, username, adminUUID string) error {
-	parsedUUID, err := uuid.Parse(id)
+func (service *SchoolService) GetDeletionPlan(id string) (dto.SchoolDeletionPlanResponseDTO, error) {
+	if _, err := service.fetchSchool(id); err != nil {
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
+
+	plan, err := service.schoolRepository.FetchDeletionPlan(id, sql.NullTime{})
 	if err != nil {
-		return err
+		return dto.SchoolDeletionPlanResponseDTO{}, err
 	}
 
-	// Handle multiple admin UUIDs deletion
-	if adminUUID != "N/A" && adminUUID != "" {
-		uuidList := strings.Split(adminUUID, ", ")
+	return toSchoolDeletionPlanDTO(plan), nil
+}
 
-		tx, err := service.userRepository.BeginTransaction()
+// Deletes the school together with its admins, drivers, students, orphaned guardians, vehicles,
+// routes and academic data in one transaction
+func (service *SchoolService) DeleteSchool(id string, actor entity.AuditActor) (err error) {
+	school, err := service.fetchSchool(id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := service.schoolRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if err != nil {
-			return err
+			tx.Rollback()
 		}
-		var transactionErr error
-		defer func() {
-			if transactionErr != nil {
-				tx.Rollback()
-			} else {
-				transactionErr = tx.Commit()
-			}
-		}()
-
-		for _, uuids := range uuidList {
-			parsedAdminUUID, err := uuid.Parse(uuids)
-			if err != nil {
-				continue
-			}
+	}()
 
-			if err := service.userRepository.DeleteSchoolAdmin(tx, parsedAdminUUID, username); err != nil {
-				return err
-			}
+	if err = service.schoolRepository.DeleteSchool(tx, id, actor.Username); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("school not found", 404)
 		}
+		return err
+	}
 
-		school := entity.School{
-			UUID:      parsedUUID,
-			DeletedAt: toNullTime(time.Now()),
-			DeletedBy: toNullString(username),
-		}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	service.auditService.Record(actor, entity.AuditDelete, entity.AuditEntitySchool, id, school, nil)
+	return nil
+}
+
+// Restores the school and everything that was deleted together with it, reports what came back
+func (service *SchoolService) RestoreSchool(id string, actor entity.AuditActor) (_ dto.SchoolDeletionPlanResponseDTO, err error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return dto.SchoolDeletionPlanResponseDTO{}, errors.New("deleted school not found", 404)
+	}
 
-		if err := service.schoolRepository.DeleteSchool(school); err != nil {
-			return err
+	school, err := service.schoolRepository.FetchDeletedSchool(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dto.SchoolDeletionPlanResponseDTO{}, errors.New("deleted school not found", 404)
 		}
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
 
-		return nil
-	} else {
-		tx, err := service.userRepository.BeginTransaction()
+	plan, err := service.schoolRepository.FetchDeletionPlan(id, school.DeletedAt)
+	if err != nil {
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
+
+	tx, err := service.schoolRepository.BeginTransaction()
+	if err != nil {
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
+	defer func() {
 		if err != nil {
-			return err
+			tx.Rollback()
 		}
-		var transactionErr error
-		defer func() {
-			if transactionErr != nil {
-				tx.Rollback()
-			} else {
-				transactionErr = tx.Commit()
-			}
-		}()
+	}()
 
-		// Delete the school
-		school := entity.School{
-			UUID:      parsedUUID,
-			DeletedAt: toNullTime(time.Now()),
-			DeletedBy: toNullString(username),
+	if err = service.schoolRepository.RestoreSchool(tx, id, school.DeletedAt.Time, actor.Username); err != nil {
+		if err == sql.ErrNoRows {
+			return dto.SchoolDeletionPlanResponseDTO{}, errors.New("deleted school not found", 404)
 		}
-		if err := service.schoolRepository.DeleteSchool(school); err != nil {
-			return err
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return dto.SchoolDeletionPlanResponseDTO{}, err
+	}
+
+	if restored, err := service.fetchSchool(id); err == nil {
+		service.auditService.Record(actor, entity.AuditRestore, entity.AuditEntitySchool, id, nil, restored)
+	}
+
+	return toSchoolDeletionPlanDTO(plan), nil
+}
+
+func (service *SchoolService) fetchSchool(id string) (entity.School, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.School{}, errors.New("school not found", 404)
+	}
+
+	school, _, err := service.schoolRepository.FetchSpecSchool(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.School{}, errors.New("school not found", 404)
 		}
+		return entity.School{}, err
+	}
+
+	return school, nil
+}
 
-		return nil
+func toSchoolDeletionPlanDTO(plan entity.SchoolDeletionPlan) dto.SchoolDeletionPlanResponseDTO {
+	return dto.SchoolDeletionPlanResponseDTO{
+		SchoolAdmins:       plan.SchoolAdmins,
+		Drivers:            plan.Drivers,
+		Students:           plan.Students,
+		Parents:            plan.Parents,
+		Vehicles:           plan.Vehicles,
+		VehicleAssignments: plan.VehicleAssignments,
+		Routes:             plan.Routes,
+		AcademicYears:      plan.AcademicYears,
+		GradeLevels:        plan.GradeLevels,
+		Total: plan.SchoolAdmins + plan.Drivers + plan.Students + plan.Parents + plan.Vehicles +
+			plan.VehicleAssignments + plan.Routes + plan.AcademicYears + plan.GradeLevels,
 	}
 }
 
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"encoding/json"
 	"time"
 	"log"
+	"shuttle/errors"
+	"shuttle/logger"
 	"shuttle/models/dto"
 	"shuttle/models/entity"
 	"shuttle/repositories"
@@ -14,18 +17,29 @@ import (
 
 type ShuttleServiceInterface interface {
 	GetShuttleStatusByParent(parentUUID uuid.UUID) ([]dto.ShuttleResponse, error)
-	AddShuttle(req dto.ShuttleRequest, driverUUID, createdBy string) error
-	EditShuttleStatus(shuttleUUID, status string) error
+	AddShuttle(req dto.ShuttleRequest, driverUUID string, actor entity.AuditActor) error
+	EditShuttleStatus(shuttleUUID, status string, actor entity.AuditActor) error
+	NotifyStatusChanged(event entity.DomainEvent) error
 }
 
 type ShuttleService struct {
-	shuttleRepository repositories.ShuttleRepositoryInterface
+	shuttleRepository            repositories.ShuttleRepositoryInterface
+	driverDocumentRepository     repositories.DriverDocumentRepositoryInterface
+	vehicleMaintenanceRepository repositories.VehicleMaintenanceRepositoryInterface
+	schoolProfileRepository      repositories.SchoolProfileRepositoryInterface
+	notificationService          NotificationServiceInterface
+	auditService                 AuditServiceInterface
 }
 
 // NewShuttleService creates a new ShuttleService
-func NewShuttleService(shuttleRepository repositories.ShuttleRepositoryInterface) ShuttleServiceInterface {
+func NewShuttleService(shuttleRepository repositories.ShuttleRepositoryInterface, driverDocumentRepository repositories.DriverDocumentRepositoryInterface, vehicleMaintenanceRepository repositories.VehicleMaintenanceRepositoryInterface, schoolProfileRepository repositories.SchoolProfileRepositoryInterface, notificationService NotificationServiceInterface, auditService AuditServiceInterface) ShuttleServiceInterface {
 	return &ShuttleService{
-		shuttleRepository: shuttleRepository,
+		shuttleRepository:            shuttleRepository,
+		driverDocumentRepository:     driverDocumentRepository,
+		vehicleMaintenanceRepository: vehicleMaintenanceRepository,
+		schoolProfileRepository:      schoolProfileRepository,
+		notificationService:          notificationService,
+		auditService:                 auditService,
 	}
 }
 func (s *ShuttleService) GetShuttleStatusByParent(parentUUID uuid.UUID) ([]dto.ShuttleResponse, error) {
@@ -51,7 +65,7 @@ func (s *ShuttleService) GetShuttleStatusByParent(parentUUID uuid.UUID) ([]dto.S
 	return responses, nil
 }
 
-func (s *ShuttleService) AddShuttle(req dto.ShuttleRequest, driverUUID, createdBy string) error {
+func (s *ShuttleService) AddShuttle(req dto.ShuttleRequest, driverUUID string, actor entity.AuditActor) error {
 	// Validasi StudentUUID
 	studentUUID, err := uuid.Parse(req.StudentUUID)
 	if err != nil {
@@ -66,6 +80,33 @@ func (s *ShuttleService) AddShuttle(req dto.ShuttleRequest, driverUUID, createdB
 		return err
 	}
 
+	// Driver dengan SIM kedaluwarsa tidak boleh menjalankan shuttle
+	expired, err := s.driverDocumentRepository.HasExpiredLicense(driverUUID)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return errors.New("your driving licence has expired, ask your school to upload the renewed one", 403)
+	}
+
+	// Kendaraan yang tidak layak jalan (dokumen habis masa berlaku) tidak boleh dipakai
+	outOfService, err := s.vehicleMaintenanceRepository.IsDriverVehicleOutOfService(driverUUID)
+	if err != nil {
+		return err
+	}
+	if outOfService {
+		return errors.New("your vehicle is out of service or in maintenance, ask your school for another one", 403)
+	}
+
+	// Tidak ada shuttle pada hari libur sekolah
+	closed, err := s.schoolProfileRepository.IsStudentSchoolClosed(req.StudentUUID, time.Now())
+	if err != nil {
+		return err
+	}
+	if closed {
+		return errors.New("the school of this student is closed today", 409)
+	}
+
 	// Menetapkan status default jika tidak diberikan
 	if req.Status == "" {
 		req.Status = "menunggu dijemput"
@@ -93,10 +134,26 @@ func (s *ShuttleService) AddShuttle(req dto.ShuttleRequest, driverUUID, createdB
 
 	// Berhasil
 	log.Println("Shuttle successfully added:", shuttle)
+	s.auditService.Record(actor, entity.AuditCreate, entity.AuditEntityShuttle, shuttle.ShuttleUUID.String(), nil, shuttle)
+
+	// Orang tua diberi tahu, kegagalan notifikasi tidak membatalkan shuttle
+	studentName, err := s.shuttleRepository.FetchStudentName(studentUUID)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch student for notification", map[string]interface{}{"student_uuid": req.StudentUUID})
+		return nil
+	}
+	if err := s.notificationService.NotifyStudentGuardians(req.StudentUUID, entity.NotificationShuttleStarted, map[string]string{
+		"shuttle_uuid": shuttle.ShuttleUUID.String(),
+		"student_uuid": req.StudentUUID,
+		"student_name": studentName,
+		"status":       req.Status,
+	}); err != nil {
+		logger.LogError(err, "Failed to notify guardians", map[string]interface{}{"student_uuid": req.StudentUUID})
+	}
 	return nil
 }
 
-func (s *ShuttleService) EditShuttleStatus(shuttleUUID, status string) error {
+func (s *ShuttleService) EditShuttleStatus(shuttleUUID, status string, actor entity.AuditActor) error {
 	// Parse UUID
 	shuttleUUIDParsed, err := uuid.Parse(shuttleUUID)
 	if err != nil {
@@ -104,13 +161,56 @@ func (s *ShuttleService) EditShuttleStatus(shuttleUUID, status string) error {
 		return err
 	}
 
-	// Update status melalui repository
-	if err := s.shuttleRepository.UpdateShuttleStatus(shuttleUUIDParsed, status); err != nil {
+	tx, err := s.shuttleRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Update status melalui repository, audit log ikut transaksi yang sama
+	before, after, err := s.shuttleRepository.UpdateShuttleStatus(tx, shuttleUUIDParsed, status)
+	if err != nil {
 		log.Println("Failed to update shuttle status:", err)
 		return err
 	}
 
+	if err := s.auditService.RecordTx(tx, actor, entity.AuditUpdate, entity.AuditEntityShuttle, shuttleUUID, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
 	log.Println("Shuttle status updated:", shuttleUUIDParsed, "New status:", status)
+	// Orang tua diberi tahu oleh NotifyStatusChanged setelah event status di-commit
 	return nil
 }
 
+// Subscriber outbox untuk shuttle.status_changed, error membuat event dikirim ulang
+func (s *ShuttleService) NotifyStatusChanged(event entity.DomainEvent) error {
+	var payload struct {
+		ShuttleUUID uuid.UUID `json:"shuttle_uuid"`
+		StudentUUID uuid.UUID `json:"student_uuid"`
+		Status      string    `json:"status"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	student, err := s.shuttleRepository.FetchShuttleStudent(payload.ShuttleUUID)
+	if err != nil {
+		// Shuttle yang sudah dihapus tidak perlu diberitahukan
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	return s.notificationService.NotifyStudentGuardians(payload.StudentUUID.String(), entity.NotificationShuttleStatusChanged, map[string]string{
+		"shuttle_uuid": payload.ShuttleUUID.String(),
+		"student_uuid": payload.StudentUUID.String(),
+		"student_name": student.StudentFirstName + " " + student.StudentLastName,
+		"status":       payload.Status,
+	})
+}
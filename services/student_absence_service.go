@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+)
+
+type StudentAbsenceServiceInterface interface {
+	GetAbsences(guardianUUID, studentUUID string) ([]dto.StudentAbsenceResponseDTO, error)
+	AddAbsence(guardianUUID, studentUUID string, req dto.StudentAbsenceRequestDTO, username string) error
+	DeleteAbsence(guardianUUID, studentUUID, id, username string) error
+}
+
+type StudentAbsenceService struct {
+	studentAbsenceRepository repositories.StudentAbsenceRepositoryInterface
+}
+
+func NewStudentAbsenceService(studentAbsenceRepository repositories.StudentAbsenceRepositoryInterface) StudentAbsenceService {
+	return StudentAbsenceService{
+		studentAbsenceRepository: studentAbsenceRepository,
+	}
+}
+
+// Lists the absences from today on
+func (service *StudentAbsenceService) GetAbsences(guardianUUID, studentUUID string) ([]dto.StudentAbsenceResponseDTO, error) {
+	today, err := service.studentToday(guardianUUID, studentUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	absences, err := service.studentAbsenceRepository.FetchAbsences(studentUUID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	absencesDTO := []dto.StudentAbsenceResponseDTO{}
+	for _, absence := range absences {
+		absencesDTO = append(absencesDTO, dto.StudentAbsenceResponseDTO{
+			UUID:      absence.UUID.String(),
+			AbsentOn:  absence.AbsentOn.Format(time.DateOnly),
+			Reason:    absence.Reason.String,
+			CreatedAt: safeTimeFormat(absence.CreatedAt),
+			CreatedBy: safeStringFormat(absence.CreatedBy),
+		})
+	}
+
+	return absencesDTO, nil
+}
+
+// The student is also taken off the trips of that day that were generated already and have not left
+func (service *StudentAbsenceService) AddAbsence(guardianUUID, studentUUID string, req dto.StudentAbsenceRequestDTO, username string) (err error) {
+	today, err := service.studentToday(guardianUUID, studentUUID)
+	if err != nil {
+		return err
+	}
+
+	absentOn, err := time.Parse(time.DateOnly, req.AbsentOn)
+	if err != nil {
+		return errors.New("absent_on must be a date like 2025-01-31", 400)
+	}
+	if absentOn.Before(today) {
+		return errors.New("absent_on must not be in the past", 400)
+	}
+
+	exists, err := service.studentAbsenceRepository.CheckAbsenceExists(studentUUID, absentOn)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("absence for this day already reported", 409)
+	}
+
+	absenceUUID := uuid.New()
+	absence := entity.StudentAbsence{
+		ID:          time.Now().UnixMilli()*1e6 + int64(absenceUUID.ID()%1e6),
+		UUID:        absenceUUID,
+		StudentUUID: uuid.MustParse(studentUUID),
+		AbsentOn:    absentOn,
+		Reason:      toNullString(req.Reason),
+		CreatedBy:   toNullString(username),
+	}
+
+	tx, err := service.studentAbsenceRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = service.studentAbsenceRepository.SaveAbsence(tx, absence); err != nil {
+		return err
+	}
+
+	if err = service.studentAbsenceRepository.SetTripShuttlesSkipped(tx, studentUUID, absentOn, true); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// The student is put back on the trips of that day that have not left yet
+func (service *StudentAbsenceService) DeleteAbsence(guardianUUID, studentUUID, id, username string) (err error) {
+	if _, err := service.studentToday(guardianUUID, studentUUID); err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("absence not found", 404)
+	}
+
+	absence, err := service.studentAbsenceRepository.FetchSpecAbsence(studentUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("absence not found", 404)
+		}
+		return err
+	}
+
+	tx, err := service.studentAbsenceRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = service.studentAbsenceRepository.DeleteAbsence(tx, studentUUID, id, username); err != nil {
+		return err
+	}
+
+	if err = service.studentAbsenceRepository.SetTripShuttlesSkipped(tx, studentUUID, absence.AbsentOn, false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Checks the guardian may manage the student and returns today's date in the timezone of the
+// student's school
+func (service *StudentAbsenceService) studentToday(guardianUUID, studentUUID string) (time.Time, error) {
+	if _, err := uuid.Parse(studentUUID); err != nil {
+		return time.Time{}, errors.New("student not found", 404)
+	}
+
+	guardian, err := service.studentAbsenceRepository.CheckGuardianOfStudent(guardianUUID, studentUUID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !guardian {
+		return time.Time{}, errors.New("student not found", 404)
+	}
+
+	timezone, err := service.studentAbsenceRepository.FetchStudentTimezone(studentUUID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	location, err := loadSchoolLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	today, _ := time.Parse(time.DateOnly, time.Now().In(location).Format(time.DateOnly))
+	return today, nil
+}
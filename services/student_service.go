@@ -1,81 +1,307 @@
 package services
 
 import (
+	"crypto/rand"
 	"database/sql"
-	// "strings"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
+	"shuttle/errors"
+	"shuttle/logger"
 	"shuttle/models/dto"
 	"shuttle/models/entity"
 	"shuttle/repositories"
+	"shuttle/utils"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type StudentServiceInterface interface {
-	AddPermittedSchoolStudentWithParents(req dto.StudentRequestDTO, username string) error
+	GetAllPermittedSchoolStudents(schoolUUID string, page, limit int, sortField, sortDirection, search, grade string) ([]dto.StudentResponseDTO, int, error)
+	GetSpecPermittedSchoolStudent(id, schoolUUID string) (dto.StudentResponseDTO, error)
+	GetStudentSiblings(id, schoolUUID string) ([]dto.StudentResponseDTO, error)
+	AddPermittedSchoolStudentWithParents(req dto.AddStudentWithParentRequestDTO, actor entity.AuditActor) (uuid.UUID, []uuid.UUID, error)
+	ImportPermittedSchoolStudents(schoolUUID string, rows [][]string, dryRun bool, actor entity.AuditActor) (dto.StudentImportResultDTO, error)
+	UpdatePermittedSchoolStudent(id, schoolUUID string, req dto.StudentRequestDTO, actor entity.AuditActor) error
+	DeletePermittedSchoolStudent(id, schoolUUID string, actor entity.AuditActor) error
+	AddStudentGuardian(id, schoolUUID string, req dto.GuardianRequestDTO, actor entity.AuditActor) (uuid.UUID, error)
+	UpdateStudentGuardian(id, guardianUUID, schoolUUID string, req dto.GuardianUpdateRequestDTO, actor entity.AuditActor) error
+	RemoveStudentGuardian(id, guardianUUID, schoolUUID string, actor entity.AuditActor) error
 }
 
 type StudentService struct {
 	studentRepository repositories.StudentRepositoryInterface
 	userRepository    repositories.UserRepositoryInterface
+	auditService      AuditServiceInterface
 }
 
-func NewStudentService(studentRepository repositories.StudentRepositoryInterface, userRepository repositories.UserRepositoryInterface) StudentService {
+func NewStudentService(studentRepository repositories.StudentRepositoryInterface, userRepository repositories.UserRepositoryInterface, auditService AuditServiceInterface) StudentService {
 	return StudentService{
 		studentRepository: studentRepository,
 		userRepository:    userRepository,
+		auditService:      auditService,
 	}
 }
 
-func (s *StudentService) AddPermittedSchoolStudentWithParents(req dto.AddStudentWithParentRequestDTO, createdBy string) (uuid.UUID, uuid.UUID, error) {
+// Saves the student and links every guardian in one transaction. The legacy parent and parent_uuid
+// fields are treated as the first guardian, existing parents are linked instead of created again.
+// Returns the student UUID and the guardian UUIDs with the primary guardian first.
+func (s *StudentService) AddPermittedSchoolStudentWithParents(req dto.AddStudentWithParentRequestDTO, actor entity.AuditActor) (uuid.UUID, []uuid.UUID, error) {
 	tx, err := s.studentRepository.BeginTransaction()
 	if err != nil {
-		return uuid.Nil, uuid.Nil, fmt.Errorf("error beginning transaction: %w", err)
+		return uuid.Nil, nil, fmt.Errorf("error beginning transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	var transactionErr error
-	defer func() {
-		if transactionErr != nil {
-			tx.Rollback()
-		} else {
-			transactionErr = tx.Commit()
-		}
-	}()
+	studentUUID, guardianUUIDs, _, err := s.saveStudentWithGuardians(tx, req, actor.Username, make(map[string]uuid.UUID))
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
 
-	// Validate parent data
-	parentUUID := uuid.New()
-	hashedPassword, err := hashPassword(req.Parent.Password)
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	s.recordStudent(actor, entity.AuditCreate, studentUUID.String(), req.Student.SchoolUUID, nil)
+	return studentUUID, guardianUUIDs, nil
+}
+
+// Imports one student per spreadsheet row. Every row goes through the same validation and
+// find-or-link rules as a single add, a failing row is rolled back to its savepoint and reported
+// while the valid rows are committed together. A dry run does all the work and rolls it back.
+func (s *StudentService) ImportPermittedSchoolStudents(schoolUUID string, rows [][]string, dryRun bool, actor entity.AuditActor) (dto.StudentImportResultDTO, error) {
+	result := dto.StudentImportResultDTO{DryRun: dryRun, Errors: []dto.StudentImportRowErrorDTO{}}
+
+	if len(rows) < 2 {
+		return result, errors.New("the file has no student rows", 400)
+	}
+
+	maxRows := utils.ConfigInt("STUDENT_IMPORT_MAX_ROWS", 2000)
+	if len(rows)-1 > maxRows {
+		return result, errors.New(fmt.Sprintf("the file has more than %d student rows", maxRows), 400)
+	}
+
+	columns, err := mapImportColumns(rows[0])
 	if err != nil {
-		transactionErr = fmt.Errorf("error hashing parent password: %w", err)
-		return uuid.Nil, uuid.Nil, transactionErr
+		return result, err
 	}
 
-	parentEntity := entity.User{
-		ID:        time.Now().UnixMilli()*1e6 + int64(parentUUID.ID()%1e6),
-		UUID:      parentUUID,
-		Username:  req.Parent.Username, // Assuming email is used as username
-		Email:     req.Parent.Email,
-		Password:  hashedPassword,
-		Role:      entity.Parent,
-		RoleCode:  "P",
-		CreatedBy: sql.NullString{String: createdBy, Valid: createdBy != ""},
-		Details: map[string]interface{}{
-			"first_name": req.Parent.FirstName,
-			"last_name":  req.Parent.LastName,
-			"phone":      req.Parent.Phone,
-			"address":    req.Parent.Address,
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return result, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Parents created or linked earlier in the file, keyed by email and phone
+	known := make(map[string]uuid.UUID)
+	var studentUUIDs []uuid.UUID
+
+	for i, row := range rows[1:] {
+		line := i + 2
+		if isEmptyRow(row) {
+			continue
+		}
+		result.TotalRows++
+
+		req := buildImportRequest(columns, row, schoolUUID)
+		if err := utils.ValidateStruct(nil, &req); err != nil {
+			result.Errors = append(result.Errors, dto.StudentImportRowErrorDTO{Row: line, Message: err.Error()})
+			continue
+		}
+
+		if err := s.studentRepository.Savepoint(tx, "student_import_row"); err != nil {
+			return result, err
+		}
+
+		rowKnown := make(map[string]uuid.UUID, len(known))
+		for key, value := range known {
+			rowKnown[key] = value
+		}
+
+		studentUUID, guardianUUIDs, created, err := s.saveStudentWithGuardians(tx, req, actor.Username, rowKnown)
+		if err != nil {
+			if rollbackErr := s.studentRepository.RollbackToSavepoint(tx, "student_import_row"); rollbackErr != nil {
+				return result, rollbackErr
+			}
+
+			message := "the row could not be saved"
+			if customErr, ok := err.(*errors.CustomError); ok {
+				message = customErr.Message
+			} else {
+				logger.LogError(err, "Failed to import student row", map[string]interface{}{"row": line})
+			}
+
+			result.Errors = append(result.Errors, dto.StudentImportRowErrorDTO{Row: line, Message: message})
+			continue
+		}
+
+		known = rowKnown
+		studentUUIDs = append(studentUUIDs, studentUUID)
+		result.ImportedRows++
+		result.CreatedParents += created
+		result.LinkedParents += len(guardianUUIDs) - created
+	}
+
+	result.FailedRows = len(result.Errors)
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	for _, studentUUID := range studentUUIDs {
+		s.recordStudent(actor, entity.AuditCreate, studentUUID.String(), schoolUUID, nil)
+	}
+
+	return result, nil
+}
+
+var requiredImportColumns = []string{
+	"student_first_name", "student_last_name", "student_grade", "student_gender",
+	"parent_first_name", "parent_last_name", "parent_email", "parent_phone", "parent_gender", "parent_address",
+}
+
+// Maps header names to column positions, headers are matched case insensitive and spaces count as underscores
+func mapImportColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+		if name != "" {
+			columns[name] = i
+		}
+	}
+
+	var missing []string
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing columns: "+strings.Join(missing, ", "), 400)
+	}
+
+	return columns, nil
+}
+
+// Builds the same request a single add would receive. The parent username defaults to the part
+// of the email before the @, with a suffix when it is taken, and the password to a random one,
+// parents can sign in with a phone code.
+func buildImportRequest(columns map[string]int, row []string, schoolUUID string) dto.AddStudentWithParentRequestDTO {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	email := strings.ToLower(value("parent_email"))
+
+	username := value("parent_username")
+	generatedUsername := username == ""
+	if generatedUsername {
+		local, _, _ := strings.Cut(email, "@")
+		username = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+				return r
+			}
+			return '_'
+		}, local)
+	}
+
+	password := value("parent_password")
+	if password == "" {
+		password = generateImportPassword()
+	}
+
+	relationship := strings.ToLower(value("relationship"))
+	if relationship == "" {
+		relationship = "guardian"
+	}
+
+	var canPickup *bool
+	switch strings.ToLower(value("can_pickup")) {
+	case "yes", "y", "true", "1":
+		allowed := true
+		canPickup = &allowed
+	case "no", "n", "false", "0":
+		allowed := false
+		canPickup = &allowed
+	}
+
+	return dto.AddStudentWithParentRequestDTO{
+		Student: dto.StudentRequestDTO{
+			FirstName:  value("student_first_name"),
+			LastName:   value("student_last_name"),
+			Grade:      value("student_grade"),
+			Gender:     strings.ToLower(value("student_gender")),
+			SchoolUUID: schoolUUID,
 		},
+		Guardians: []dto.GuardianRequestDTO{{
+			Relationship:      relationship,
+			CanPickup:         canPickup,
+			IsPrimary:         true,
+			GeneratedUsername: generatedUsername,
+			Parent: &dto.UserRequestsDTO{
+				Username:  username,
+				Email:     email,
+				Password:  password,
+				Role:      dto.Parent,
+				FirstName: value("parent_first_name"),
+				LastName:  value("parent_last_name"),
+				Gender:    dto.Gender(strings.ToLower(value("parent_gender"))),
+				Phone:     value("parent_phone"),
+				Address:   value("parent_address"),
+			},
+		}},
 	}
+}
 
-	parentUUID, err = s.userRepository.SaveUser(tx, parentEntity)
-	if err != nil {
-		transactionErr = fmt.Errorf("error saving parent: %w", err)
-		return uuid.Nil, uuid.Nil, transactionErr
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func generateImportPassword() string {
+	buffer := make([]byte, 12)
+	if _, err := rand.Read(buffer); err != nil {
+		return uuid.NewString()
+	}
+	return hex.EncodeToString(buffer)
+}
+
+// Returns the student UUID, the guardian UUIDs with the primary guardian first and how many
+// parent accounts had to be created
+func (s *StudentService) saveStudentWithGuardians(tx *sqlx.Tx, req dto.AddStudentWithParentRequestDTO, createdBy string, known map[string]uuid.UUID) (uuid.UUID, []uuid.UUID, int, error) {
+	guardians := req.Guardians
+	if req.Student.ParentUUID != "" || req.Parent != nil {
+		legacy := dto.GuardianRequestDTO{
+			ParentUUID:   req.Student.ParentUUID,
+			Parent:       req.Parent,
+			Relationship: "guardian",
+		}
+		guardians = append([]dto.GuardianRequestDTO{legacy}, guardians...)
+	}
+
+	if len(guardians) == 0 {
+		return uuid.Nil, nil, 0, errors.New("a student needs at least one guardian", 400)
+	}
+
+	if err := validateGuardianList(guardians); err != nil {
+		return uuid.Nil, nil, 0, err
 	}
 
-	// Validate student data
 	studentUUID := uuid.New()
 	studentEntity := entity.Student{
 		ID:         time.Now().UnixMilli()*1e6 + int64(studentUUID.ID()%1e6),
@@ -83,345 +309,693 @@ func (s *StudentService) AddPermittedSchoolStudentWithParents(req dto.AddStudent
 		FirstName:  req.Student.FirstName,
 		LastName:   req.Student.LastName,
 		Grade:      req.Student.Grade,
-		Gender:     req.Student.Gender,	
-		ParentUUID: sql.NullString{String: parentUUID.String(), Valid: true},
+		Gender:     req.Student.Gender,
 		SchoolUUID: uuid.MustParse(req.Student.SchoolUUID),
-		CreatedBy:  sql.NullString{String: createdBy, Valid: createdBy != ""},
+		CreatedBy:  toNullString(createdBy),
+	}
+
+	studentUUID, err := s.studentRepository.SaveStudent(tx, studentEntity)
+	if err != nil {
+		return uuid.Nil, nil, 0, fmt.Errorf("error saving student: %w", err)
+	}
+
+	hasPrimary := false
+	for _, guardian := range guardians {
+		hasPrimary = hasPrimary || guardian.IsPrimary
+	}
+
+	created := 0
+	var guardianUUIDs []uuid.UUID
+	for i, guardian := range guardians {
+		guardianUUID, isNew, err := s.resolveGuardian(tx, req.Student.SchoolUUID, guardian, createdBy, known)
+		if err != nil {
+			return uuid.Nil, nil, 0, err
+		}
+
+		for _, linked := range guardianUUIDs {
+			if linked == guardianUUID {
+				return uuid.Nil, nil, 0, errors.New("the same guardian is listed more than once", 400)
+			}
+		}
+
+		if isNew {
+			created++
+		}
+
+		isPrimary := guardian.IsPrimary || (!hasPrimary && i == 0)
+		if err := s.studentRepository.SaveStudentGuardian(tx, toStudentGuardianEntity(studentUUID, guardianUUID, guardian, isPrimary, createdBy)); err != nil {
+			return uuid.Nil, nil, 0, fmt.Errorf("error saving guardian: %w", err)
+		}
+
+		if isPrimary {
+			guardianUUIDs = append([]uuid.UUID{guardianUUID}, guardianUUIDs...)
+		} else {
+			guardianUUIDs = append(guardianUUIDs, guardianUUID)
+		}
+	}
+
+	return studentUUID, guardianUUIDs, created, nil
+}
+
+func (s *StudentService) GetAllPermittedSchoolStudents(schoolUUID string, page, limit int, sortField, sortDirection, search, grade string) ([]dto.StudentResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	students, err := s.studentRepository.FetchPermittedSchoolStudents(schoolUUID, offset, limit, sortField, sortDirection, search, grade)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.studentRepository.CountPermittedSchoolStudents(schoolUUID, search, grade)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	studentUUIDs := make([]string, 0, len(students))
+	for _, student := range students {
+		studentUUIDs = append(studentUUIDs, student.UUID.String())
+	}
+
+	guardians, err := s.studentRepository.FetchStudentGuardians(studentUUIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	studentsDTO := []dto.StudentResponseDTO{}
+	for _, student := range students {
+		studentsDTO = append(studentsDTO, toStudentResponseDTO(student, guardians[student.UUID.String()]))
+	}
+
+	return studentsDTO, total, nil
+}
+
+func (s *StudentService) GetSpecPermittedSchoolStudent(id, schoolUUID string) (dto.StudentResponseDTO, error) {
+	student, err := s.fetchPermittedStudent(id, schoolUUID)
+	if err != nil {
+		return dto.StudentResponseDTO{}, err
+	}
+
+	guardians, err := s.studentRepository.FetchStudentGuardians([]string{student.UUID.String()})
+	if err != nil {
+		return dto.StudentResponseDTO{}, err
+	}
+
+	return toStudentResponseDTO(student, guardians[student.UUID.String()]), nil
+}
+
+func (s *StudentService) GetStudentSiblings(id, schoolUUID string) ([]dto.StudentResponseDTO, error) {
+	if _, err := s.fetchPermittedStudent(id, schoolUUID); err != nil {
+		return nil, err
+	}
+
+	siblings, err := s.studentRepository.FetchStudentSiblings(id, schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	siblingsDTO := []dto.StudentResponseDTO{}
+	for _, sibling := range siblings {
+		siblingsDTO = append(siblingsDTO, toStudentResponseDTO(sibling, nil))
+	}
+
+	return siblingsDTO, nil
+}
+
+// Guardians are managed through their own endpoints, parent_uuid in the request is ignored here
+func (s *StudentService) UpdatePermittedSchoolStudent(id, schoolUUID string, req dto.StudentRequestDTO, actor entity.AuditActor) error {
+	student, err := s.fetchPermittedStudent(id, schoolUUID)
+	if err != nil {
+		return err
+	}
+	before := student
+
+	student.FirstName = req.FirstName
+	student.LastName = req.LastName
+	student.Gender = req.Gender
+	student.Grade = req.Grade
+	student.UpdatedBy = toNullString(actor.Username)
+
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.studentRepository.UpdateStudent(tx, student, schoolUUID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.recordStudent(actor, entity.AuditUpdate, id, schoolUUID, before)
+	return nil
+}
+
+// Soft deletes the student, a guardian account goes with it once the guardian has no active student left
+func (s *StudentService) DeletePermittedSchoolStudent(id, schoolUUID string, actor entity.AuditActor) error {
+	student, err := s.fetchPermittedStudent(id, schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	guardianUUIDs, err := s.studentRepository.FetchStudentGuardianUUIDs(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.studentRepository.DeleteStudent(tx, id, schoolUUID, actor.Username); err != nil {
+		return err
+	}
+
+	for _, guardianUUID := range guardianUUIDs {
+		if err := s.deleteOrphanGuardian(tx, guardianUUID, actor.Username); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.recordStudent(actor, entity.AuditDelete, id, schoolUUID, student)
+	return nil
+}
+
+func (s *StudentService) AddStudentGuardian(id, schoolUUID string, req dto.GuardianRequestDTO, actor entity.AuditActor) (uuid.UUID, error) {
+	student, err := s.fetchPermittedStudent(id, schoolUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := validateGuardianList([]dto.GuardianRequestDTO{req}); err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	guardianUUID, _, err := s.resolveGuardian(tx, student.SchoolUUID.String(), req, actor.Username, make(map[string]uuid.UUID))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	linked, err := s.studentRepository.FetchStudentGuardianUUIDs(tx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, existing := range linked {
+		if existing == guardianUUID.String() {
+			return uuid.Nil, errors.New("the parent is already a guardian of this student", 409)
+		}
+	}
+
+	if req.IsPrimary {
+		if err := s.studentRepository.ClearPrimaryGuardian(tx, id); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err := s.studentRepository.SaveStudentGuardian(tx, toStudentGuardianEntity(student.UUID, guardianUUID, req, req.IsPrimary, actor.Username)); err != nil {
+		return uuid.Nil, err
 	}
 
-	studentUUID, err = s.studentRepository.SaveStudent(tx, studentEntity)
+	if err := s.studentRepository.PromotePrimaryGuardian(tx, id); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+
+	s.auditService.Record(actor, entity.AuditGuardianAdd, entity.AuditEntityStudent, id, nil, s.fetchGuardianLink(id, guardianUUID.String()))
+	return guardianUUID, nil
+}
+
+func (s *StudentService) UpdateStudentGuardian(id, guardianUUID, schoolUUID string, req dto.GuardianUpdateRequestDTO, actor entity.AuditActor) error {
+	student, err := s.fetchPermittedStudent(id, schoolUUID)
+	if err != nil {
+		return err
+	}
+
+	parsedGuardianUUID, err := uuid.Parse(guardianUUID)
+	if err != nil {
+		return errors.New("guardian not found", 404)
+	}
+
+	before := s.fetchGuardianLink(id, guardianUUID)
+
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if req.IsPrimary {
+		if err := s.studentRepository.ClearPrimaryGuardian(tx, id); err != nil {
+			return err
+		}
+	}
+
+	guardian := entity.StudentGuardian{
+		StudentUUID:  student.UUID,
+		GuardianUUID: parsedGuardianUUID,
+		Relationship: req.Relationship,
+		CanPickup:    *req.CanPickup,
+		IsPrimary:    req.IsPrimary,
+		UpdatedBy:    toNullString(actor.Username),
+	}
+
+	updated, err := s.studentRepository.UpdateStudentGuardian(tx, guardian)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New("guardian not found", 404)
+	}
+
+	// Demoting the primary guardian hands the role to the oldest remaining guardian
+	if err := s.studentRepository.PromotePrimaryGuardian(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, entity.AuditGuardianUpdate, entity.AuditEntityStudent, id, before, s.fetchGuardianLink(id, guardianUUID))
+	return nil
+}
+
+func (s *StudentService) RemoveStudentGuardian(id, guardianUUID, schoolUUID string, actor entity.AuditActor) error {
+	if _, err := s.fetchPermittedStudent(id, schoolUUID); err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(guardianUUID); err != nil {
+		return errors.New("guardian not found", 404)
+	}
+
+	before := s.fetchGuardianLink(id, guardianUUID)
+
+	tx, err := s.studentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	linked, err := s.studentRepository.FetchStudentGuardianUUIDs(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if !contains(linked, guardianUUID) {
+		return errors.New("guardian not found", 404)
+	}
+
+	if len(linked) == 1 {
+		return errors.New("a student needs at least one guardian", 400)
+	}
+
+	if _, err := s.studentRepository.DeleteStudentGuardian(tx, id, guardianUUID); err != nil {
+		return err
+	}
+
+	if err := s.studentRepository.PromotePrimaryGuardian(tx, id); err != nil {
+		return err
+	}
+
+	if err := s.deleteOrphanGuardian(tx, guardianUUID, actor.Username); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, entity.AuditGuardianRemove, entity.AuditEntityStudent, id, before, nil)
+	return nil
+}
+
+// The student as it is now is the after of the entry, a deleted student has none
+func (s *StudentService) recordStudent(actor entity.AuditActor, action, id, schoolUUID string, before interface{}) {
+	var after interface{}
+	if action != entity.AuditDelete {
+		if student, err := s.fetchPermittedStudent(id, schoolUUID); err == nil {
+			after = student
+		}
+	}
+
+	s.auditService.Record(actor, action, entity.AuditEntityStudent, id, before, after)
+}
+
+// The link between a student and one of their guardians, nil when there is none
+func (s *StudentService) fetchGuardianLink(studentUUID, guardianUUID string) interface{} {
+	guardians, err := s.studentRepository.FetchStudentGuardians([]string{studentUUID})
+	if err != nil {
+		return nil
+	}
+
+	for _, guardian := range guardians[studentUUID] {
+		if guardian.GuardianUUID.String() == guardianUUID {
+			return guardian
+		}
+	}
+
+	return nil
+}
+
+func (s *StudentService) fetchPermittedStudent(id, schoolUUID string) (entity.Student, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.Student{}, errors.New("student not found", 404)
+	}
+
+	student, err := s.studentRepository.FetchSpecPermittedSchoolStudent(id, schoolUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.Student{}, errors.New("student not found", 404)
+		}
+		return entity.Student{}, err
+	}
+
+	return student, nil
+}
+
+// Finds the parent a guardian entry points at, by UUID first and then by email or phone, and creates
+// a new parent account from the parent details when nothing matches. Only parents already guardian of
+// a student of the school are matched, so another school's parents and their contact details cannot
+// be reached. Known holds the parents already resolved in the same transaction, which the lookups
+// outside of it cannot see yet.
+func (s *StudentService) resolveGuardian(tx *sqlx.Tx, schoolUUID string, req dto.GuardianRequestDTO, createdBy string, known map[string]uuid.UUID) (uuid.UUID, bool, error) {
+	if req.ParentUUID != "" {
+		if _, err := uuid.Parse(req.ParentUUID); err != nil {
+			return uuid.Nil, false, errors.New("parent not found", 404)
+		}
+
+		parent, err := s.userRepository.FetchSpecificUser(req.ParentUUID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return uuid.Nil, false, errors.New("parent not found", 404)
+			}
+			return uuid.Nil, false, err
+		}
+
+		permitted, err := s.permittedGuardian(tx, schoolUUID, parent)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if !permitted {
+			return uuid.Nil, false, errors.New("parent not found", 404)
+		}
+
+		rememberGuardian(known, req, parent.UUID)
+		return parent.UUID, false, nil
+	}
+
+	email, phone := guardianEmail(req), guardianPhone(req)
+
+	if guardianUUID, ok := knownGuardian(known, email, phone); ok {
+		rememberGuardian(known, req, guardianUUID)
+		return guardianUUID, false, nil
+	}
+
+	if email != "" {
+		parent, err := s.userRepository.FetchUserByEmail(email)
+		if err == nil {
+			permitted, err := s.permittedGuardian(tx, schoolUUID, parent)
+			if err != nil {
+				return uuid.Nil, false, err
+			}
+			// Emails are unique, the account cannot be created again either
+			if !permitted {
+				return uuid.Nil, false, errors.New("email "+email+" is already used by another account", 409)
+			}
+
+			rememberGuardian(known, req, parent.UUID)
+			return parent.UUID, false, nil
+		}
+		if err != sql.ErrNoRows {
+			return uuid.Nil, false, err
+		}
+	}
+
+	if phone != "" {
+		parent, err := s.userRepository.FetchSchoolParentByPhone(schoolUUID, phone, phoneCountryCode())
+		if err == nil {
+			rememberGuardian(known, req, parent.UUID)
+			return parent.UUID, false, nil
+		}
+		if err != sql.ErrNoRows {
+			return uuid.Nil, false, err
+		}
+	}
+
+	if req.Parent == nil {
+		return uuid.Nil, false, errors.New("parent not found, send the parent details to create a new account", 404)
+	}
+
+	username, err := s.availableUsername(req.Parent.Username, req.GeneratedUsername, known)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	hashedPassword, err := hashPassword(req.Parent.Password)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("error hashing parent password: %w", err)
+	}
+
+	parentUUID := uuid.New()
+	parentEntity := entity.User{
+		ID:                 time.Now().UnixMilli()*1e6 + int64(parentUUID.ID()%1e6),
+		UUID:               parentUUID,
+		Username:           username,
+		Email:              req.Parent.Email,
+		Password:           hashedPassword,
+		Role:               entity.Parent,
+		RoleCode:           "P",
+		CreatedBy:          toNullString(createdBy),
+		CreatedWithStudent: true,
+	}
+
+	parentUUID, err = s.userRepository.SaveUser(tx, parentEntity)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("error saving parent: %w", err)
+	}
+
+	details := entity.ParentDetails{
+		Picture:   req.Parent.Picture,
+		FirstName: req.Parent.FirstName,
+		LastName:  req.Parent.LastName,
+		Gender:    entity.Gender(req.Parent.Gender),
+		Phone:     req.Parent.Phone,
+		Address:   req.Parent.Address,
+	}
+
+	if err := s.userRepository.SaveParentDetails(tx, details, parentUUID, nil); err != nil {
+		return uuid.Nil, false, fmt.Errorf("error saving parent details: %w", err)
+	}
+
+	rememberGuardian(known, req, parentUUID)
+	known["username:"+strings.ToLower(username)] = parentUUID
+
+	return parentUUID, true, nil
+}
+
+// A username already in use, in the database or earlier in the same transaction, is refused. One
+// derived from the email gets _2, _3 and so on instead, so ana@x.com and ana@y.com can both import.
+func (s *StudentService) availableUsername(username string, generated bool, known map[string]uuid.UUID) (string, error) {
+	candidate := username
+	for suffix := 2; suffix <= 100; suffix++ {
+		_, taken := known["username:"+strings.ToLower(candidate)]
+		if !taken {
+			exists, err := s.userRepository.CheckUsernameExist("", candidate)
+			if err != nil {
+				return "", err
+			}
+			taken = exists
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+		if !generated {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d", username, suffix)
+	}
+
+	return "", errors.New("username "+username+" already exists", 409)
+}
+
+// A parent can only be linked when they are already guardian of a student of the school
+func (s *StudentService) permittedGuardian(tx *sqlx.Tx, schoolUUID string, parent entity.User) (bool, error) {
+	if parent.Role != entity.Parent {
+		return false, nil
+	}
+
+	return s.studentRepository.CheckGuardianInSchool(tx, parent.UUID.String(), schoolUUID)
+}
+
+func knownGuardian(known map[string]uuid.UUID, email, phone string) (uuid.UUID, bool) {
+	if email != "" {
+		if guardianUUID, ok := known["email:"+email]; ok {
+			return guardianUUID, true
+		}
+	}
+	if phone != "" {
+		if guardianUUID, ok := known["phone:"+phone]; ok {
+			return guardianUUID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func rememberGuardian(known map[string]uuid.UUID, req dto.GuardianRequestDTO, guardianUUID uuid.UUID) {
+	if email := guardianEmail(req); email != "" {
+		known["email:"+email] = guardianUUID
+	}
+	if phone := guardianPhone(req); phone != "" {
+		known["phone:"+phone] = guardianUUID
+	}
+}
+
+// Soft deletes a guardian account that no longer has any active student, as long as the account was
+// created while adding or importing a student. Parents that were added on their own are kept.
+func (s *StudentService) deleteOrphanGuardian(tx *sqlx.Tx, guardianUUID, username string) error {
+	remaining, err := s.studentRepository.CountActiveStudentsOfParent(tx, guardianUUID)
+	if err != nil {
+		return err
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+
+	parent, err := s.userRepository.FetchSpecificUser(guardianUUID)
 	if err != nil {
-		transactionErr = fmt.Errorf("error saving student: %w", err)
-		return uuid.Nil, uuid.Nil, transactionErr
-	}
-
-	return studentUUID, parentUUID, nil
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if !parent.CreatedWithStudent {
+		return nil
+	}
+
+	return s.userRepository.DeleteParent(tx, parent.UUID, username)
+}
+
+func validateGuardianList(guardians []dto.GuardianRequestDTO) error {
+	primaries := 0
+	seen := make(map[string]struct{})
+
+	for _, guardian := range guardians {
+		if guardian.ParentUUID == "" && guardian.Email == "" && guardian.Parent == nil {
+			return errors.New("every guardian needs a parent_uuid, an email, or the parent details", 400)
+		}
+
+		if guardian.IsPrimary {
+			primaries++
+		}
+
+		for _, key := range []string{guardian.ParentUUID, guardianEmail(guardian), guardianPhone(guardian)} {
+			if key == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				return errors.New("the same guardian is listed more than once", 400)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	if primaries > 1 {
+		return errors.New("only one guardian can be primary", 400)
+	}
+
+	return nil
+}
+
+func guardianEmail(guardian dto.GuardianRequestDTO) string {
+	if guardian.Email != "" {
+		return strings.ToLower(guardian.Email)
+	}
+	if guardian.Parent != nil {
+		return strings.ToLower(guardian.Parent.Email)
+	}
+	return ""
+}
+
+func guardianPhone(guardian dto.GuardianRequestDTO) string {
+	if guardian.Phone != "" {
+		return normalizePhone(guardian.Phone)
+	}
+	if guardian.Parent != nil {
+		return normalizePhone(guardian.Parent.Phone)
+	}
+	return ""
+}
+
+func toStudentGuardianEntity(studentUUID, guardianUUID uuid.UUID, req dto.GuardianRequestDTO, isPrimary bool, createdBy string) entity.StudentGuardian {
+	canPickup := true
+	if req.CanPickup != nil {
+		canPickup = *req.CanPickup
+	}
+
+	guardianID := uuid.New()
+	return entity.StudentGuardian{
+		ID:           time.Now().UnixMilli()*1e6 + int64(guardianID.ID()%1e6),
+		StudentUUID:  studentUUID,
+		GuardianUUID: guardianUUID,
+		Relationship: req.Relationship,
+		CanPickup:    canPickup,
+		IsPrimary:    isPrimary,
+		CreatedBy:    toNullString(createdBy),
+	}
+}
+
+func toStudentResponseDTO(student entity.Student, guardians []entity.StudentGuardian) dto.StudentResponseDTO {
+	studentDTO := dto.StudentResponseDTO{
+		UUID:       student.UUID.String(),
+		FirstName:  student.FirstName,
+		LastName:   student.LastName,
+		Gender:     student.Gender,
+		Grade:      student.Grade,
+		SchoolUUID: student.SchoolUUID.String(),
+		CreatedAt:  safeTimeFormat(student.CreatedAt),
+		CreatedBy:  safeStringFormat(student.CreatedBy),
+		UpdatedAt:  safeTimeFormat(student.UpdatedAt),
+		UpdatedBy:  safeStringFormat(student.UpdatedBy),
+	}
+
+	for _, guardian := range guardians {
+		if guardian.IsPrimary {
+			studentDTO.ParentUUID = guardian.GuardianUUID.String()
+		}
+
+		studentDTO.Guardians = append(studentDTO.Guardians, dto.GuardianResponseDTO{
+			ParentUUID:   guardian.GuardianUUID.String(),
+			FirstName:    guardian.FirstName,
+			LastName:     guardian.LastName,
+			Email:        guardian.Email,
+			Phone:        guardian.Phone,
+			Address:      guardian.Address,
+			Relationship: guardian.Relationship,
+			CanPickup:    guardian.CanPickup,
+			IsPrimary:    guardian.IsPrimary,
+		})
+	}
+
+	return studentDTO
 }
-
-// package services
-
-// import (
-// 	"context"
-
-// 	// "shuttle/errors"
-// 	"shuttle/databases"
-// 	"shuttle/models"
-
-// 	"github.com/spf13/viper"
-// 	"go.mongodb.org/mongo-driver/bson"
-// 	"go.mongodb.org/mongo-driver/bson/primitive"
-// 	"go.mongodb.org/mongo-driver/mongo"
-// 	"go.mongodb.org/mongo-driver/mongo/options"
-// )
-
-// func GetAllPermitedSchoolStudentsWithParents(schoolID primitive.ObjectID) ([]models.SchoolStudentParentResponse, error) {
-// 	client, err := databases.MongoConnection()
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	collection := client.Database(viper.GetString("MONGO_DB")).Collection("students")
-
-// 	var students []models.Student
-// 	cursor, err := collection.Find(context.Background(), bson.M{"school_id": schoolID})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	defer cursor.Close(context.Background())
-
-// 	for cursor.Next(context.Background()) {
-// 		var student models.Student
-// 		if err := cursor.Decode(&student); err != nil {
-
-// 			return nil, err
-// 		}
-// 		students = append(students, student)
-// 	}
-
-// 	if err := cursor.Err(); err != nil {
-// 		return nil, err
-// 	}
-
-// 	var Parents []models.ParentResponse
-// 	parentCollection := client.Database(viper.GetString("MONGO_DB")).Collection("users")
-// 	for _, student := range students {
-// 		var parent models.ParentResponse
-// 		err := parentCollection.FindOne(context.Background(), bson.M{"_id": student.ParentID}, options.FindOne().SetProjection(bson.M{"password": 0})).Decode(&parent)
-// 		if err != nil {
-
-// 			return nil, err
-// 		}
-// 		Parents = append(Parents, parent)
-// 	}
-
-// 	var schoolStudents []models.SchoolStudentParentResponse
-// 	for i, student := range students {
-// 		schoolStudents = append(schoolStudents, models.SchoolStudentParentResponse{
-// 			Student: student,
-// 			Parent:  Parents[i],
-// 		})
-// 	}
-
-// 	return schoolStudents, nil
-// }
-
-// func AddPermittedSchoolStudentWithParents(student models.SchoolStudentRequest, schoolID primitive.ObjectID, username string) error {
-// 	client, err := databases.MongoConnection()
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	collection := client.Database(viper.GetString("MONGO_DB")).Collection("users")
-// 	var existingParent models.User
-// 	// Email as unique identifier for parent
-// 	err = collection.FindOne(context.Background(), bson.M{"email": student.Parent.Email, "role": models.Parent}).Decode(&existingParent)
-
-// 	// If parent is not yet added, add the parent
-// 	var parentID primitive.ObjectID
-// 	if err == nil {
-// 		parentID = existingParent.ID
-// 	} else if err == mongo.ErrNoDocuments {
-// 		parentUser := student.Parent
-// 		parentUser.Role = models.Parent
-
-// 		parentUser.Details = &models.ParentDetails{
-// 			Children: []primitive.ObjectID{},
-// 		}
-
-// 		parentID, err = AddUser(parentUser, username)
-// 		if err != nil {
-
-// 			return err
-// 		}
-// 	} else {
-// 		return err
-// 	}
-
-// 	studentDocument := bson.D{
-// 		{Key: "first_name", Value: student.Student.FirstName},
-// 		{Key: "last_name", Value: student.Student.LastName},
-// 		{Key: "class", Value: student.Student.Class},
-// 		{Key: "parent_id", Value: parentID},
-// 		{Key: "school_id", Value: schoolID},
-// 	}
-
-// 	studentsCollection := client.Database(viper.GetString("MONGO_DB")).Collection("students")
-// 	result, err := studentsCollection.InsertOne(context.Background(), studentDocument)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	studentID := result.InsertedID.(primitive.ObjectID)
-
-// 	_, err = collection.UpdateOne(
-// 		context.Background(),
-// 		bson.M{"_id": parentID},
-// 		bson.M{"$push": bson.M{"details.children_id": studentID}},
-// 	)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	return nil
-// }
-
-// func UpdatePermittedSchoolStudentWithParents(id string, student models.SchoolStudentRequest, schoolID primitive.ObjectID) error {
-//     client, err := databases.MongoConnection()
-//     if err != nil {
-//         return err
-//     }
-
-//     collection := client.Database(viper.GetString("MONGO_DB")).Collection("students")
-//     objectID, err := primitive.ObjectIDFromHex(id)
-//     if err != nil {
-//         return err
-//     }
-
-// 	if err := CheckStudentAvailability(objectID, schoolID); err != nil {
-//         return err
-//     }
-
-// 	// Pipeline to get the student and parent details
-//     var existingStudent models.SchoolStudentRequest
-//     pipeline := mongo.Pipeline{
-//         bson.D{{Key: "$match", Value: bson.M{"_id": objectID, "school_id": schoolID}}},
-//         bson.D{{Key: "$lookup", Value: bson.M{
-//             "from":         "users",
-//             "localField":   "parent_id",
-//             "foreignField": "_id",
-//             "as":           "parent",
-//         }}},
-//         bson.D{{Key: "$unwind", Value: bson.M{"path": "$parent"}}},
-//     }
-
-// 	// Aggregate the pipeline
-//     cursor, err := collection.Aggregate(context.Background(), pipeline)
-//     if err != nil {
-//         return err
-//     }
-//     defer cursor.Close(context.Background())
-
-//     if cursor.Next(context.Background()) {
-//         if err := cursor.Decode(&existingStudent); err != nil {
-
-//             return err
-//         }
-//     }
-
-//     if (models.User{}) == existingStudent.Parent {
-//         return errors.New("parent details are not available", 404)
-//     }
-
-//     updateStudent := bson.M{
-//         "first_name": student.FirstName,
-//         "last_name":  student.LastName,
-//     }
-
-//     _, err = collection.UpdateOne(context.Background(), bson.M{"_id": objectID, "school_id": schoolID}, bson.M{"$set": updateStudent})
-//     if err != nil {
-//         return err
-//     }
-
-// 	// If the parent details are changed, update the parent details
-//     if (models.User{}) != student.Parent && student.Parent != existingStudent.Parent {
-//         parentCollection := client.Database(viper.GetString("MONGO_DB")).Collection("users")
-//         parentID := existingStudent.Parent.ID
-
-//         updateParent := bson.M{
-//             "first_name": student.Parent.FirstName,
-//             "last_name":  student.Parent.LastName,
-//             "email":      student.Parent.Email,
-// 			"phone":    student.Parent.Phone,
-//             "address":  student.Parent.Address,
-//             "details": bson.M{
-//                 "children": []primitive.ObjectID{objectID},
-//             },
-//         }
-
-//         _, err = parentCollection.UpdateOne(context.Background(), bson.M{"_id": parentID}, bson.M{"$set": updateParent})
-//         if err != nil {
-
-//             return err
-//         }
-//     } else { // Else, parent remains the same
-//         _, err = client.Database(viper.GetString("MONGO_DB")).Collection("users").UpdateOne(
-//             context.Background(),
-//             bson.M{"_id": existingStudent.Parent.ID},
-//             bson.M{"$addToSet": bson.M{"parent_details.children": objectID}},
-//         )
-//         if err != nil {
-
-//             return err
-//         }
-//     }
-
-//     return nil
-// }
-
-// func DeletePermittedSchoolStudentWithParents(id string, schoolID primitive.ObjectID) error {
-// 	client, err := databases.MongoConnection()
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	collection := client.Database(viper.GetString("MONGO_DB")).Collection("students")
-// 	objectID, err := primitive.ObjectIDFromHex(id)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	if err := CheckStudentAvailability(objectID, schoolID); err != nil {
-// 		return err
-// 	}
-
-// 	var student models.Student
-// 	err = collection.FindOne(context.Background(), bson.M{"_id": objectID, "school_id": schoolID}).Decode(&student)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": objectID, "school_id": schoolID})
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	// Remove the student from the parent's children list
-// 	parentCollection := client.Database(viper.GetString("MONGO_DB")).Collection("users")
-// 	_, err = parentCollection.UpdateOne(
-// 		context.Background(),
-// 		bson.M{"_id": student.ParentID},
-// 		bson.M{"$pull": bson.M{"parent_details.children": objectID}},
-// 	)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	var parent models.User
-// 	err = parentCollection.FindOne(context.Background(), bson.M{"_id": student.ParentID}).Decode(&parent)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	// If the children array is empty, delete the parent
-// 	if len(parent.Details.(models.ParentDetails).Children) == 0 {
-// 		_, err = parentCollection.DeleteOne(context.Background(), bson.M{"_id": student.ParentID})
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-
-// 	return nil
-// }
-
-// func CheckPermittedSchoolAccess(userID string) (primitive.ObjectID, error) {
-// 	client, err := databases.MongoConnection()
-// 	if err != nil {
-// 		return primitive.NilObjectID, err
-// 	}
-
-// 	objectID, err := primitive.ObjectIDFromHex(userID)
-// 	if err != nil {
-// 		return primitive.NilObjectID, err
-// 	}
-
-// 	collection := client.Database(viper.GetString("MONGO_DB")).Collection("users")
-
-// 	var user models.User
-// 	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
-// 	if err != nil {
-// 		return primitive.NilObjectID, err
-// 	}
-
-// 	var schoolAdminDetails models.SchoolAdminDetails
-// 	detailsBytes, err := bson.Marshal(user.Details)
-// 	if err != nil {
-// 		return primitive.NilObjectID, err
-// 	}
-
-// 	err = bson.Unmarshal(detailsBytes, &schoolAdminDetails)
-// 	if err != nil || schoolAdminDetails.SchoolID.IsZero() {
-// 		return primitive.NilObjectID, err
-// 	}
-
-// 	return schoolAdminDetails.SchoolID, nil
-// }
-
-// func CheckStudentAvailability(studentID primitive.ObjectID, schoolID primitive.ObjectID) error {
-//     client, err := databases.MongoConnection()
-//     if err != nil {
-//         return err
-//     }
-
-//     collection := client.Database(viper.GetString("MONGO_DB")).Collection("students")
-
-//     var student models.SchoolStudentRequest
-//     err = collection.FindOne(context.Background(), bson.M{"_id": studentID, "school_id": schoolID}).Decode(&student)
-//     if err != nil {
-//         if err == mongo.ErrNoDocuments {
-//             return err
-// 		}
-//         return err
-//     }
-
-//     return nil
-// }
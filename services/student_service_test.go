@@ -0,0 +1,272 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var importTestHeader = []string{
+	"Student First Name", "student_last_name", "student_grade", "student_gender",
+	"parent_first_name", "parent_last_name", "parent_email", "parent_phone", "parent_gender", "parent_address",
+}
+
+// Rows of the same file are matched to a parent created or linked by an earlier row, by email or by
+// phone in any of its written forms
+func TestImportGuardianDeduplication(t *testing.T) {
+	columns, err := mapImportColumns(importTestHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := func(email, phone string) dto.GuardianRequestDTO {
+		req := buildImportRequest(columns, []string{"Adi", "Putra", "3", "male", "Siti", "Aminah", email, phone, "female", "Jl. Merdeka 1"}, uuid.NewString())
+		return req.Guardians[0]
+	}
+
+	firstRow := row("Siti@Example.com", "+62 812-3456-789")
+	parentUUID := uuid.New()
+	known := make(map[string]uuid.UUID)
+	rememberGuardian(known, firstRow, parentUUID)
+
+	tests := []struct {
+		name      string
+		guardian  dto.GuardianRequestDTO
+		wantMatch bool
+	}{
+		{name: "same email in another case", guardian: row("siti@example.COM", "0899 0000 111"), wantMatch: true},
+		{name: "same phone written locally", guardian: row("other@example.com", "0812 3456 789"), wantMatch: true},
+		{name: "same phone with 00 prefix", guardian: row("other@example.com", "0062-812-3456-789"), wantMatch: true},
+		{name: "different parent", guardian: row("budi@example.com", "0813 1111 222"), wantMatch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := knownGuardian(known, guardianEmail(tt.guardian), guardianPhone(tt.guardian))
+			if ok != tt.wantMatch {
+				t.Fatalf("knownGuardian() matched = %v, want %v", ok, tt.wantMatch)
+			}
+			if ok && got != parentUUID {
+				t.Errorf("knownGuardian() = %s, want %s", got, parentUUID)
+			}
+		})
+	}
+}
+
+func TestValidateGuardianList(t *testing.T) {
+	parentUUID := uuid.NewString()
+
+	tests := []struct {
+		name      string
+		guardians []dto.GuardianRequestDTO
+		wantErr   string
+	}{
+		{
+			name: "two different guardians",
+			guardians: []dto.GuardianRequestDTO{
+				{Email: "siti@example.com", IsPrimary: true},
+				{Parent: &dto.UserRequestsDTO{Email: "budi@example.com", Phone: "0813 1111 222"}},
+			},
+		},
+		{
+			name:      "same parent_uuid twice",
+			guardians: []dto.GuardianRequestDTO{{ParentUUID: parentUUID}, {ParentUUID: parentUUID}},
+			wantErr:   "the same guardian is listed more than once",
+		},
+		{
+			name:      "same email in another case",
+			guardians: []dto.GuardianRequestDTO{{Email: "siti@example.com"}, {Email: "SITI@example.com"}},
+			wantErr:   "the same guardian is listed more than once",
+		},
+		{
+			name: "same phone in local and international form",
+			guardians: []dto.GuardianRequestDTO{
+				{Parent: &dto.UserRequestsDTO{Email: "siti@example.com", Phone: "0812 3456 789"}},
+				{Parent: &dto.UserRequestsDTO{Email: "budi@example.com", Phone: "+62 812 3456 789"}},
+			},
+			wantErr: "the same guardian is listed more than once",
+		},
+		{
+			name:      "two primary guardians",
+			guardians: []dto.GuardianRequestDTO{{Email: "siti@example.com", IsPrimary: true}, {Email: "budi@example.com", IsPrimary: true}},
+			wantErr:   "only one guardian can be primary",
+		},
+		{
+			name:      "guardian without parent",
+			guardians: []dto.GuardianRequestDTO{{Relationship: "mother"}},
+			wantErr:   "every guardian needs a parent_uuid, an email, or the parent details",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGuardianList(tt.guardians)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateGuardianList() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validateGuardianList() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapImportColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		wantErr string
+	}{
+		{name: "every required column", header: importTestHeader},
+		{name: "spaces and case", header: append([]string{" Parent Email "}, importTestHeader...)},
+		{
+			name:    "missing columns",
+			header:  []string{"student_first_name", "student_last_name", "student_grade", "student_gender"},
+			wantErr: "missing columns: parent_first_name, parent_last_name, parent_email, parent_phone, parent_gender, parent_address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := mapImportColumns(tt.header)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("mapImportColumns() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mapImportColumns() error = %v", err)
+			}
+			if _, ok := columns["student_first_name"]; !ok {
+				t.Error("student_first_name was not mapped")
+			}
+		})
+	}
+}
+
+type fakeGuardianUserRepository struct {
+	repositories.UserRepositoryInterface
+	parent    entity.User
+	usernames map[string]bool
+}
+
+func (r *fakeGuardianUserRepository) CheckUsernameExist(uuid string, username string) (bool, error) {
+	return r.usernames[username], nil
+}
+
+func (r *fakeGuardianUserRepository) FetchSpecificUser(userUUID string) (entity.User, error) {
+	if userUUID != r.parent.UUID.String() {
+		return entity.User{}, sql.ErrNoRows
+	}
+	return r.parent, nil
+}
+
+func (r *fakeGuardianUserRepository) FetchUserByEmail(email string) (entity.User, error) {
+	if email != r.parent.Email {
+		return entity.User{}, sql.ErrNoRows
+	}
+	return r.parent, nil
+}
+
+type fakeGuardianStudentRepository struct {
+	repositories.StudentRepositoryInterface
+	schoolParents map[string]bool
+}
+
+func (r *fakeGuardianStudentRepository) CheckGuardianInSchool(tx *sqlx.Tx, parentUUID, schoolUUID string) (bool, error) {
+	return r.schoolParents[schoolUUID+":"+parentUUID], nil
+}
+
+// Parents of another school are not linked, even when the submitted name matches theirs
+func TestResolveGuardianSchoolScope(t *testing.T) {
+	parent := entity.User{UUID: uuid.New(), Email: "siti@example.com", Role: entity.Parent}
+	ownSchool, otherSchool := uuid.NewString(), uuid.NewString()
+
+	service := NewStudentService(
+		&fakeGuardianStudentRepository{schoolParents: map[string]bool{ownSchool + ":" + parent.UUID.String(): true}},
+		&fakeGuardianUserRepository{parent: parent},
+		nil,
+	)
+
+	details := &dto.UserRequestsDTO{Email: "siti@example.com", FirstName: "Siti", LastName: "Aminah"}
+
+	tests := []struct {
+		name       string
+		schoolUUID string
+		guardian   dto.GuardianRequestDTO
+		wantStatus int
+	}{
+		{name: "email of a parent of the school", schoolUUID: ownSchool, guardian: dto.GuardianRequestDTO{Email: "siti@example.com"}},
+		{name: "parent_uuid of a parent of the school", schoolUUID: ownSchool, guardian: dto.GuardianRequestDTO{ParentUUID: parent.UUID.String()}},
+		{name: "email of a parent of another school", schoolUUID: otherSchool, guardian: dto.GuardianRequestDTO{Parent: details}, wantStatus: 409},
+		{name: "parent_uuid of a parent of another school", schoolUUID: otherSchool, guardian: dto.GuardianRequestDTO{ParentUUID: parent.UUID.String(), Parent: details}, wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isNew, err := service.resolveGuardian(nil, tt.schoolUUID, tt.guardian, "admin", make(map[string]uuid.UUID))
+			if tt.wantStatus != 0 {
+				customErr, ok := err.(*errors.CustomError)
+				if !ok || customErr.StatusCode != tt.wantStatus {
+					t.Fatalf("resolveGuardian() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveGuardian() error = %v", err)
+			}
+			if got != parent.UUID || isNew {
+				t.Errorf("resolveGuardian() = %s, new %v, want the existing parent %s", got, isNew, parent.UUID)
+			}
+		})
+	}
+}
+
+func TestAvailableUsername(t *testing.T) {
+	service := NewStudentService(nil, &fakeGuardianUserRepository{usernames: map[string]bool{"ana": true, "ana_2": true, "budi": true}}, nil)
+	known := map[string]uuid.UUID{"username:ana_3": uuid.New()}
+
+	tests := []struct {
+		name      string
+		username  string
+		generated bool
+		want      string
+		wantErr   bool
+	}{
+		{name: "free username", username: "siti", want: "siti"},
+		{name: "free generated username", username: "siti", generated: true, want: "siti"},
+		{name: "generated username taken in the database and the file", username: "ana", generated: true, want: "ana_4"},
+		{name: "generated username taken once", username: "budi", generated: true, want: "budi_2"},
+		{name: "chosen username taken", username: "budi", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.availableUsername(tt.username, tt.generated, known)
+			if tt.wantErr {
+				customErr, ok := err.(*errors.CustomError)
+				if !ok || customErr.StatusCode != 409 {
+					t.Fatalf("availableUsername() error = %v, want status 409", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("availableUsername() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("availableUsername() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
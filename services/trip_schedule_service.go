@@ -0,0 +1,589 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type TripScheduleServiceInterface interface {
+	GetSchedules(schoolUUID string) ([]dto.TripScheduleResponseDTO, error)
+	GetSpecSchedule(schoolUUID, id string) (dto.TripScheduleResponseDTO, error)
+	AddSchedule(schoolUUID string, req dto.TripScheduleRequestDTO, username string) error
+	UpdateSchedule(schoolUUID, id string, req dto.TripScheduleRequestDTO, username string) error
+	DeleteSchedule(schoolUUID, id, username string) error
+
+	GetTrips(schoolUUID, driverUUID, date string) ([]dto.TripResponseDTO, error)
+	GenerateTrips(schoolUUID string, req dto.TripGenerationRequestDTO, username string) (dto.TripGenerationResponseDTO, error)
+	GenerateDueTrips() error
+}
+
+type TripScheduleService struct {
+	tripScheduleRepository  repositories.TripScheduleRepositoryInterface
+	schoolProfileRepository repositories.SchoolProfileRepositoryInterface
+}
+
+func NewTripScheduleService(tripScheduleRepository repositories.TripScheduleRepositoryInterface, schoolProfileRepository repositories.SchoolProfileRepositoryInterface) TripScheduleService {
+	return TripScheduleService{
+		tripScheduleRepository:  tripScheduleRepository,
+		schoolProfileRepository: schoolProfileRepository,
+	}
+}
+
+func (service *TripScheduleService) GetSchedules(schoolUUID string) ([]dto.TripScheduleResponseDTO, error) {
+	schedules, err := service.tripScheduleRepository.FetchSchedules(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleUUIDs := []string{}
+	for _, schedule := range schedules {
+		scheduleUUIDs = append(scheduleUUIDs, schedule.UUID.String())
+	}
+
+	students, err := service.tripScheduleRepository.FetchScheduleStudents(scheduleUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	schedulesDTO := []dto.TripScheduleResponseDTO{}
+	for _, schedule := range schedules {
+		schedule.StudentUUIDs = students[schedule.UUID.String()]
+		schedulesDTO = append(schedulesDTO, toTripScheduleDTO(schedule))
+	}
+
+	return schedulesDTO, nil
+}
+
+func (service *TripScheduleService) GetSpecSchedule(schoolUUID, id string) (dto.TripScheduleResponseDTO, error) {
+	schedule, err := service.fetchSchedule(schoolUUID, id)
+	if err != nil {
+		return dto.TripScheduleResponseDTO{}, err
+	}
+
+	students, err := service.tripScheduleRepository.FetchScheduleStudents([]string{schedule.UUID.String()})
+	if err != nil {
+		return dto.TripScheduleResponseDTO{}, err
+	}
+	schedule.StudentUUIDs = students[schedule.UUID.String()]
+
+	return toTripScheduleDTO(schedule), nil
+}
+
+func (service *TripScheduleService) AddSchedule(schoolUUID string, req dto.TripScheduleRequestDTO, username string) error {
+	schedule, err := toTripScheduleEntity(req)
+	if err != nil {
+		return err
+	}
+
+	schedule.UUID = uuid.New()
+	schedule.ID = time.Now().UnixMilli()*1e6 + int64(schedule.UUID.ID()%1e6)
+	schedule.SchoolUUID = uuid.MustParse(schoolUUID)
+	schedule.CreatedBy = toNullString(username)
+
+	if err := service.checkSchedule(schedule, req.StudentUUIDs); err != nil {
+		return err
+	}
+
+	tx, err := service.tripScheduleRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.tripScheduleRepository.SaveSchedule(tx, schedule); err != nil {
+		return err
+	}
+
+	if err := service.tripScheduleRepository.ReplaceScheduleStudents(tx, schedule.UUID, req.StudentUUIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Only trips generated from now on follow the changes
+func (service *TripScheduleService) UpdateSchedule(schoolUUID, id string, req dto.TripScheduleRequestDTO, username string) error {
+	existing, err := service.fetchSchedule(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := toTripScheduleEntity(req)
+	if err != nil {
+		return err
+	}
+
+	schedule.UUID = existing.UUID
+	schedule.SchoolUUID = existing.SchoolUUID
+	schedule.UpdatedBy = toNullString(username)
+
+	if err := service.checkSchedule(schedule, req.StudentUUIDs); err != nil {
+		return err
+	}
+
+	tx, err := service.tripScheduleRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.tripScheduleRepository.UpdateSchedule(tx, schedule); err != nil {
+		return err
+	}
+
+	if err := service.tripScheduleRepository.ReplaceScheduleStudents(tx, schedule.UUID, req.StudentUUIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *TripScheduleService) DeleteSchedule(schoolUUID, id, username string) error {
+	if _, err := service.fetchSchedule(schoolUUID, id); err != nil {
+		return err
+	}
+
+	return service.tripScheduleRepository.DeleteSchedule(schoolUUID, id, username)
+}
+
+// Lists the trips of a day, today by default. Empty schoolUUID or driverUUID do not filter.
+func (service *TripScheduleService) GetTrips(schoolUUID, driverUUID, date string) ([]dto.TripResponseDTO, error) {
+	tripDate, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	if date != "" {
+		var err error
+		if tripDate, err = time.Parse(time.DateOnly, date); err != nil {
+			return nil, errors.New("date must be a date like 2025-01-31", 400)
+		}
+	}
+
+	trips, err := service.tripScheduleRepository.FetchTrips(schoolUUID, driverUUID, tripDate)
+	if err != nil {
+		return nil, err
+	}
+
+	tripsDTO := []dto.TripResponseDTO{}
+	for _, trip := range trips {
+		tripsDTO = append(tripsDTO, dto.TripResponseDTO{
+			UUID:          trip.UUID.String(),
+			ScheduleUUID:  trip.ScheduleUUID.String(),
+			RouteName:     safeStringFormat(trip.RouteName),
+			VehicleNumber: safeStringFormat(trip.VehicleNumber),
+			DriverName:    driverFullName(trip.DriverFirstName, trip.DriverLastName),
+			Direction:     trip.Direction,
+			TripDate:      trip.TripDate.Format(time.DateOnly),
+			DepartsAt:     trip.DepartsAt.Format(time.RFC3339),
+			Students:      trip.StudentCount,
+		})
+	}
+
+	return tripsDTO, nil
+}
+
+// Generates the trips of one school for one day by hand. Running it again only adds what is missing,
+// like students put on a schedule in the meantime.
+func (service *TripScheduleService) GenerateTrips(schoolUUID string, req dto.TripGenerationRequestDTO, username string) (dto.TripGenerationResponseDTO, error) {
+	tripDate, err := time.Parse(time.DateOnly, req.TripDate)
+	if err != nil {
+		return dto.TripGenerationResponseDTO{}, errors.New("trip_date must be a date like 2025-01-31", 400)
+	}
+
+	schedules, err := service.tripScheduleRepository.FetchRunnableSchedules(schoolUUID, tripDate)
+	if err != nil {
+		return dto.TripGenerationResponseDTO{}, err
+	}
+
+	school, err := service.schoolProfileRepository.FetchSchoolProfile(schoolUUID)
+	if err != nil {
+		return dto.TripGenerationResponseDTO{}, err
+	}
+
+	location, err := loadSchoolLocation(school.Timezone)
+	if err != nil {
+		return dto.TripGenerationResponseDTO{}, err
+	}
+
+	return service.generateSchoolTrips(schoolUUID, schedules, tripDate, location, username)
+}
+
+// Generates the trips of the next day for the schools where it is already TRIP_GENERATION_HOUR
+// or later. Generation is idempotent, so schools done on an earlier run are only topped up.
+func (service *TripScheduleService) GenerateDueTrips() error {
+	// Only used to find the schools and their timezones, the schedules of a due school are read
+	// again for its trip date so licences are checked against that day
+	schedules, err := service.tripScheduleRepository.FetchRunnableSchedules("", time.Now())
+	if err != nil {
+		return err
+	}
+
+	generationHour := utils.ConfigInt("TRIP_GENERATION_HOUR", 18)
+
+	schoolTimezones := make(map[string]string)
+	for _, schedule := range schedules {
+		schoolTimezones[schedule.SchoolUUID.String()] = schedule.Timezone
+	}
+
+	var lastErr error
+	for schoolUUID, timezone := range schoolTimezones {
+		location, err := loadSchoolLocation(timezone)
+		if err != nil {
+			logger.LogError(err, "Invalid school timezone", map[string]interface{}{"school_uuid": schoolUUID})
+			continue
+		}
+
+		now := time.Now().In(location)
+		if now.Hour() < generationHour {
+			continue
+		}
+
+		tomorrow, _ := time.Parse(time.DateOnly, now.AddDate(0, 0, 1).Format(time.DateOnly))
+		schedules, err := service.tripScheduleRepository.FetchRunnableSchedules(schoolUUID, tomorrow)
+		if err != nil {
+			logger.LogError(err, "Failed to fetch trip schedules", map[string]interface{}{"school_uuid": schoolUUID})
+			lastErr = err
+			continue
+		}
+
+		result, err := service.generateSchoolTrips(schoolUUID, schedules, tomorrow, location, "system")
+		if err != nil {
+			logger.LogError(err, "Failed to generate trips", map[string]interface{}{"school_uuid": schoolUUID})
+			lastErr = err
+			continue
+		}
+		if result.Shuttles > 0 {
+			logger.LogInfo("Trips generated", map[string]interface{}{"school_uuid": schoolUUID, "trip_date": result.TripDate, "trips": result.Trips, "shuttles": result.Shuttles, "skipped_schedules": result.Skipped})
+		}
+	}
+
+	// The other schools are done, a retry only redoes what is missing
+	return lastErr
+}
+
+func (service *TripScheduleService) generateSchoolTrips(schoolUUID string, schedules []entity.TripSchedule, tripDate time.Time, location *time.Location, username string) (dto.TripGenerationResponseDTO, error) {
+	result := dto.TripGenerationResponseDTO{TripDate: tripDate.Format(time.DateOnly)}
+
+	noon := time.Date(tripDate.Year(), tripDate.Month(), tripDate.Day(), 12, 0, 0, 0, location)
+	closed, err := service.schoolProfileRepository.IsSchoolClosed(schoolUUID, noon)
+	if err != nil {
+		return dto.TripGenerationResponseDTO{}, err
+	}
+	if closed {
+		result.Closed = true
+		return result, nil
+	}
+
+	for _, schedule := range schedules {
+		if !scheduleRunsOn(schedule, tripDate) {
+			continue
+		}
+
+		// The same checks a driver starting a shuttle by hand goes through
+		if schedule.LicenseExpired || schedule.VehicleUnavailable {
+			logger.LogWarn("Trip not generated for schedule", map[string]interface{}{
+				"schedule_uuid":       schedule.UUID.String(),
+				"school_uuid":         schoolUUID,
+				"trip_date":           result.TripDate,
+				"driver_uuid":         schedule.DriverUUID.String(),
+				"vehicle_uuid":        schedule.VehicleUUID.String(),
+				"license_expired":     schedule.LicenseExpired,
+				"vehicle_unavailable": schedule.VehicleUnavailable,
+			})
+			result.Skipped++
+			continue
+		}
+
+		shuttles, err := service.generateTrip(schedule, tripDate, location, username)
+		if err != nil {
+			return dto.TripGenerationResponseDTO{}, err
+		}
+
+		result.Trips++
+		result.Shuttles += shuttles
+	}
+
+	return result, nil
+}
+
+// Creates the trip of the schedule and the shuttles of its riders in one transaction, returns how
+// many shuttles were added
+func (service *TripScheduleService) generateTrip(schedule entity.TripSchedule, tripDate time.Time, location *time.Location, username string) (int64, error) {
+	departure, err := time.Parse(bellTimeLayout, schedule.DepartureTime)
+	if err != nil {
+		return 0, err
+	}
+
+	tripUUID := uuid.New()
+	trip := entity.Trip{
+		ID:           time.Now().UnixMilli()*1e6 + int64(tripUUID.ID()%1e6),
+		UUID:         tripUUID,
+		ScheduleUUID: schedule.UUID,
+		SchoolUUID:   schedule.SchoolUUID,
+		RouteUUID:    uuid.NullUUID{UUID: schedule.RouteUUID, Valid: true},
+		VehicleUUID:  uuid.NullUUID{UUID: schedule.VehicleUUID, Valid: true},
+		DriverUUID:   uuid.NullUUID{UUID: schedule.DriverUUID, Valid: true},
+		Direction:    schedule.Direction,
+		TripDate:     tripDate,
+		DepartsAt:    time.Date(tripDate.Year(), tripDate.Month(), tripDate.Day(), departure.Hour(), departure.Minute(), 0, 0, location),
+		CreatedBy:    toNullString(username),
+	}
+
+	tx, err := service.tripScheduleRepository.BeginTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	tripUUID, err = service.tripScheduleRepository.SaveTrip(tx, trip)
+	if err != nil {
+		return 0, err
+	}
+
+	riders, err := service.tripScheduleRepository.FetchTripRiders(tx, schedule.UUID, tripDate)
+	if err != nil {
+		return 0, err
+	}
+
+	// Students start at home when going to school and at school when going home
+	status := "di rumah"
+	if schedule.Direction == entity.TripToHome {
+		status = "di sekolah"
+	}
+
+	var added int64
+	for _, studentUUID := range riders {
+		shuttleUUID := uuid.New()
+		shuttle := entity.Shuttle{
+			ShuttleID:   time.Now().UnixMilli()*1e6 + int64(shuttleUUID.ID()%1e6),
+			ShuttleUUID: shuttleUUID,
+			StudentUUID: studentUUID,
+			DriverUUID:  schedule.DriverUUID,
+			Status:      status,
+		}
+
+		rows, err := service.tripScheduleRepository.SaveTripShuttle(tx, shuttle, tripUUID, schedule.Direction)
+		if err != nil {
+			return 0, err
+		}
+		added += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+func (service *TripScheduleService) checkSchedule(schedule entity.TripSchedule, studentUUIDs []string) error {
+	references, err := service.tripScheduleRepository.CheckScheduleReferences(schedule.SchoolUUID.String(),
+		schedule.RouteUUID.String(), schedule.VehicleUUID.String(), schedule.DriverUUID.String())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !references.RouteFound:
+		return errors.New("route not found", 404)
+	case !references.VehicleFound:
+		return errors.New("vehicle not found", 404)
+	case !references.DriverFound:
+		return errors.New("driver not found", 404)
+	}
+
+	unique := make(map[string]struct{})
+	for _, studentUUID := range studentUUIDs {
+		unique[studentUUID] = struct{}{}
+	}
+
+	found, err := service.tripScheduleRepository.CountSchoolStudents(schedule.SchoolUUID.String(), studentUUIDs)
+	if err != nil {
+		return err
+	}
+	if found != len(unique) {
+		return errors.New("some students were not found in the school", 404)
+	}
+
+	conflict, err := service.tripScheduleRepository.CheckScheduleConflict(schedule, utils.ConfigDuration("TRIP_DURATION", time.Hour))
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return errors.New("the driver or vehicle already has a trip around this time on one of these days", 409)
+	}
+
+	return nil
+}
+
+func (service *TripScheduleService) fetchSchedule(schoolUUID, id string) (entity.TripSchedule, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.TripSchedule{}, errors.New("schedule not found", 404)
+	}
+
+	schedule, err := service.tripScheduleRepository.FetchSpecSchedule(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.TripSchedule{}, errors.New("schedule not found", 404)
+		}
+		return entity.TripSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func toTripScheduleEntity(req dto.TripScheduleRequestDTO) (entity.TripSchedule, error) {
+	departure, err := time.Parse(bellTimeLayout, req.DepartureTime)
+	if err != nil {
+		return entity.TripSchedule{}, errors.New("departure_time must be a time like 06:30", 400)
+	}
+
+	effectiveFrom, err := time.Parse(time.DateOnly, req.EffectiveFrom)
+	if err != nil {
+		return entity.TripSchedule{}, errors.New("effective_from must be a date like 2025-07-14", 400)
+	}
+
+	var effectiveTo sql.NullTime
+	if req.EffectiveTo != "" {
+		to, err := time.Parse(time.DateOnly, req.EffectiveTo)
+		if err != nil {
+			return entity.TripSchedule{}, errors.New("effective_to must be a date like 2026-06-20", 400)
+		}
+		if to.Before(effectiveFrom) {
+			return entity.TripSchedule{}, errors.New("effective_to must not be before effective_from", 400)
+		}
+		effectiveTo = sql.NullTime{Time: to, Valid: true}
+	}
+
+	routeUUID, err := uuid.Parse(req.RouteUUID)
+	if err != nil {
+		return entity.TripSchedule{}, errors.New("invalid route_uuid", 400)
+	}
+
+	vehicleUUID, err := uuid.Parse(req.VehicleUUID)
+	if err != nil {
+		return entity.TripSchedule{}, errors.New("invalid vehicle_uuid", 400)
+	}
+
+	driverUUID, err := uuid.Parse(req.DriverUUID)
+	if err != nil {
+		return entity.TripSchedule{}, errors.New("invalid driver_uuid", 400)
+	}
+
+	for _, studentUUID := range req.StudentUUIDs {
+		if _, err := uuid.Parse(studentUUID); err != nil {
+			return entity.TripSchedule{}, errors.New("invalid student_uuid "+studentUUID, 400)
+		}
+	}
+
+	if req.Direction != entity.TripToSchool && req.Direction != entity.TripToHome {
+		return entity.TripSchedule{}, errors.New("direction must be 'to_school' or 'to_home'", 400)
+	}
+
+	weekdays := pq.Int64Array{}
+	seen := make(map[int]bool)
+	for _, requested := range req.Weekdays {
+		requested = strings.ToLower(strings.TrimSpace(requested))
+		day := 0
+		for i, weekday := range schoolWeekdays {
+			if requested == weekday {
+				day = i + 1
+				break
+			}
+		}
+		if day == 0 {
+			return entity.TripSchedule{}, errors.New("unknown weekday "+requested+", use monday to sunday", 400)
+		}
+		if !seen[day] {
+			seen[day] = true
+			weekdays = append(weekdays, int64(day))
+		}
+	}
+
+	return entity.TripSchedule{
+		RouteUUID:     routeUUID,
+		VehicleUUID:   vehicleUUID,
+		DriverUUID:    driverUUID,
+		Direction:     req.Direction,
+		DepartureTime: departure.Format(bellTimeLayout),
+		Weekdays:      weekdays,
+		EffectiveFrom: effectiveFrom,
+		EffectiveTo:   effectiveTo,
+	}, nil
+}
+
+func toTripScheduleDTO(schedule entity.TripSchedule) dto.TripScheduleResponseDTO {
+	scheduleDTO := dto.TripScheduleResponseDTO{
+		UUID:          schedule.UUID.String(),
+		RouteUUID:     schedule.RouteUUID.String(),
+		RouteName:     safeStringFormat(schedule.RouteName),
+		VehicleUUID:   schedule.VehicleUUID.String(),
+		VehicleNumber: safeStringFormat(schedule.VehicleNumber),
+		DriverUUID:    schedule.DriverUUID.String(),
+		DriverName:    driverFullName(schedule.DriverFirstName, schedule.DriverLastName),
+		Direction:     schedule.Direction,
+		DepartureTime: schedule.DepartureTime,
+		Weekdays:      []string{},
+		EffectiveFrom: schedule.EffectiveFrom.Format(time.DateOnly),
+		StudentUUIDs:  schedule.StudentUUIDs,
+		CreatedAt:     safeTimeFormat(schedule.CreatedAt),
+		CreatedBy:     safeStringFormat(schedule.CreatedBy),
+		UpdatedAt:     safeTimeFormat(schedule.UpdatedAt),
+		UpdatedBy:     safeStringFormat(schedule.UpdatedBy),
+	}
+
+	if scheduleDTO.StudentUUIDs == nil {
+		scheduleDTO.StudentUUIDs = []string{}
+	}
+
+	if schedule.EffectiveTo.Valid {
+		scheduleDTO.EffectiveTo = schedule.EffectiveTo.Time.Format(time.DateOnly)
+	}
+
+	weekdays := []int{}
+	for _, weekday := range schedule.Weekdays {
+		weekdays = append(weekdays, int(weekday))
+	}
+	sort.Ints(weekdays)
+	for _, weekday := range weekdays {
+		scheduleDTO.Weekdays = append(scheduleDTO.Weekdays, schoolWeekdays[weekday-1])
+	}
+
+	return scheduleDTO
+}
+
+func scheduleRunsOn(schedule entity.TripSchedule, tripDate time.Time) bool {
+	if tripDate.Before(schedule.EffectiveFrom) || (schedule.EffectiveTo.Valid && tripDate.After(schedule.EffectiveTo.Time)) {
+		return false
+	}
+
+	weekday := int64(tripDate.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	for _, scheduled := range schedule.Weekdays {
+		if scheduled == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func driverFullName(firstName, lastName sql.NullString) string {
+	name := strings.TrimSpace(firstName.String + " " + lastName.String)
+	if name == "" {
+		return "N/A"
+	}
+	return name
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+
+	"github.com/lib/pq"
+)
+
+func TestScheduleRunsOn(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	weekdays := entity.TripSchedule{
+		Weekdays:      pq.Int64Array{1, 2, 3, 4, 5},
+		EffectiveFrom: date("2026-01-05"),
+	}
+	weekend := entity.TripSchedule{
+		Weekdays:      pq.Int64Array{6, 7},
+		EffectiveFrom: date("2026-01-05"),
+	}
+	bounded := entity.TripSchedule{
+		Weekdays:      pq.Int64Array{1, 2, 3, 4, 5, 6, 7},
+		EffectiveFrom: date("2026-01-05"),
+		EffectiveTo:   sql.NullTime{Time: date("2026-06-30"), Valid: true},
+	}
+
+	tests := []struct {
+		name     string
+		schedule entity.TripSchedule
+		tripDate string
+		want     bool
+	}{
+		{name: "monday on a weekday schedule", schedule: weekdays, tripDate: "2026-01-05", want: true},
+		{name: "friday on a weekday schedule", schedule: weekdays, tripDate: "2026-01-09", want: true},
+		{name: "saturday on a weekday schedule", schedule: weekdays, tripDate: "2026-01-10", want: false},
+		{name: "sunday counts as 7", schedule: weekend, tripDate: "2026-01-11", want: true},
+		{name: "monday on a weekend schedule", schedule: weekend, tripDate: "2026-01-12", want: false},
+		{name: "before effective_from", schedule: weekdays, tripDate: "2026-01-02", want: false},
+		{name: "no effective_to", schedule: weekdays, tripDate: "2030-03-04", want: true},
+		{name: "on effective_to", schedule: bounded, tripDate: "2026-06-30", want: true},
+		{name: "after effective_to", schedule: bounded, tripDate: "2026-07-01", want: false},
+		{name: "no weekdays", schedule: entity.TripSchedule{EffectiveFrom: date("2026-01-05")}, tripDate: "2026-01-05", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scheduleRunsOn(tt.schedule, date(tt.tripDate)); got != tt.want {
+				t.Errorf("scheduleRunsOn(%s) = %v, want %v", tt.tripDate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTripScheduleWeekdays(t *testing.T) {
+	request := func(weekdays ...string) dto.TripScheduleRequestDTO {
+		return dto.TripScheduleRequestDTO{
+			RouteUUID:     "5b0e6c1e-7f4a-4c53-9b4f-0c6a3e2f1d01",
+			VehicleUUID:   "5b0e6c1e-7f4a-4c53-9b4f-0c6a3e2f1d02",
+			DriverUUID:    "5b0e6c1e-7f4a-4c53-9b4f-0c6a3e2f1d03",
+			Direction:     entity.TripToSchool,
+			DepartureTime: "06:45",
+			Weekdays:      weekdays,
+			EffectiveFrom: "2026-01-05",
+			StudentUUIDs:  []string{"5b0e6c1e-7f4a-4c53-9b4f-0c6a3e2f1d04"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		weekdays []string
+		want     []int64
+		wantErr  bool
+	}{
+		{name: "lower case", weekdays: []string{"monday", "friday"}, want: []int64{1, 5}},
+		{name: "mixed case and spaces", weekdays: []string{"Monday", " TUESDAY "}, want: []int64{1, 2}},
+		{name: "duplicates are dropped", weekdays: []string{"sunday", "Sunday"}, want: []int64{7}},
+		{name: "unknown weekday", weekdays: []string{"funday"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := toTripScheduleEntity(request(tt.weekdays...))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("toTripScheduleEntity() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toTripScheduleEntity() error = %v", err)
+			}
+			if len(schedule.Weekdays) != len(tt.want) {
+				t.Fatalf("weekdays = %v, want %v", schedule.Weekdays, tt.want)
+			}
+			for i, day := range tt.want {
+				if schedule.Weekdays[i] != day {
+					t.Errorf("weekdays = %v, want %v", schedule.Weekdays, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -26,13 +26,18 @@ type UserServiceInterface interface {
 	GetAllDriverFromAllSchools(page int, limit int, sortField string, sortDirection string) ([]dto.UserResponseDTO, error)
 	GetAllDriverForPermittedSchool(page int, limit int, sortField string, sortDirection string, schoolUUID string) ([]dto.UserResponseDTO, int, error)
 	GetSpecDriverFromAllSchools(uuid string) (dto.UserResponseDTO, error)
+	GetSpecDriverForPermittedSchool(id string, schoolUUID string) (dto.UserResponseDTO, error)
 
-	AddUser(user entity.User, user_name string) (uuid.UUID, error)
-	UpdateUser(id string, user dto.UserRequestsDTO, user_name string, file []byte) error
+	AddUser(req dto.UserRequestsDTO, actor entity.AuditActor) (uuid.UUID, error)
+	UpdateUser(id string, req dto.UserRequestsDTO, actor entity.AuditActor, detailsMap map[string]interface{}, file []byte) error
 
-	DeleteSuperAdmin(id string, user_name string) error
-	DeleteSchoolAdmin(id string, user_name string) error
-	DeleteDriver(id string, user_name string) error
+	DeleteSuperAdmin(id string, actor entity.AuditActor) error
+	DeleteSchoolAdmin(id string, actor entity.AuditActor) error
+	DeleteDriver(id string, actor entity.AuditActor) error
+
+	AddSchoolDriver(schoolUUID string, req dto.UserRequestsDTO, actor entity.AuditActor) (uuid.UUID, error)
+	UpdateSchoolDriver(id string, schoolUUID string, req dto.UserRequestsDTO, actor entity.AuditActor, detailsMap map[string]interface{}) error
+	DeleteSchoolDriver(id string, schoolUUID string, actor entity.AuditActor) error
 
 	GetSpecUser(id string) (entity.User, error)
 	GetSpecUserWithDetails(id string) (entity.User, error)
@@ -42,11 +47,13 @@ type UserServiceInterface interface {
 
 type UserService struct {
 	userRepository repositories.UserRepositoryInterface
+	auditService   AuditServiceInterface
 }
 
-func NewUserService(userRepository repositories.UserRepositoryInterface) UserService {
+func NewUserService(userRepository repositories.UserRepositoryInterface, auditService AuditServiceInterface) UserService {
 	return UserService{
 		userRepository: userRepository,
+		auditService:   auditService,
 	}
 }
 
@@ -186,7 +193,7 @@ func (service *UserService) GetAllDriverForPermittedSchool(page int, limit int,
 		return nil, 0, err
 	}
 
-	total, err := service.userRepository.CountSchoolAdmin()
+	total, err := service.userRepository.CountDriversForPermittedSchool(schoolUUID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -349,7 +356,38 @@ func (service *UserService) GetSpecDriverFromAllSchools(id string) (dto.UserResp
 	return userDTO, nil
 }
 
-func (s *UserService) AddUser(req dto.UserRequestsDTO, user_name string) (uuid.UUID, error) {
+// Drivers of other schools are reported as not found so a school admin cannot probe for them
+func (service *UserService) GetSpecDriverForPermittedSchool(id string, schoolUUID string) (dto.UserResponseDTO, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return dto.UserResponseDTO{}, errors.New("driver not found", 404)
+	}
+
+	user, err := service.GetSpecDriverFromAllSchools(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dto.UserResponseDTO{}, errors.New("driver not found", 404)
+		}
+		return dto.UserResponseDTO{}, err
+	}
+
+	if details, ok := user.Details.(dto.DriverDetailsResponseDTO); !ok || details.SchoolUUID != schoolUUID {
+		return dto.UserResponseDTO{}, errors.New("driver not found", 404)
+	}
+
+	return user, nil
+}
+
+func (s *UserService) AddUser(req dto.UserRequestsDTO, actor entity.AuditActor) (uuid.UUID, error) {
+	userUUID, err := s.addUser(req, actor.Username)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	s.recordUser(actor, entity.AuditCreate, userUUID.String(), nil)
+	return userUUID, nil
+}
+
+func (s *UserService) addUser(req dto.UserRequestsDTO, user_name string) (uuid.UUID, error) {
 	exists, err := s.userRepository.CheckEmailExist("", req.Email)
 	if err != nil {
 		return uuid.Nil, err
@@ -411,6 +449,10 @@ func (s *UserService) AddUser(req dto.UserRequestsDTO, user_name string) (uuid.U
 
 	if _, ok := req.Details.(map[string]interface{}); ok {
 		if err := s.saveRoleDetails(tx, userEntity, req); err != nil {
+			if customErr, ok := err.(*errors.CustomError); ok {
+				transactionErr = customErr
+				return uuid.Nil, customErr
+			}
 			transactionErr = fmt.Errorf("error saving role details: %w", err)
 			return uuid.Nil, transactionErr
 		}
@@ -431,6 +473,10 @@ func (s *UserService) AddUser(req dto.UserRequestsDTO, user_name string) (uuid.U
 		}
 
 		if err := s.saveRoleDetails(tx, userEntity, req); err != nil {
+			if customErr, ok := err.(*errors.CustomError); ok {
+				transactionErr = customErr
+				return uuid.Nil, customErr
+			}
 			transactionErr = fmt.Errorf("error saving role details: %w", err)
 			return uuid.Nil, transactionErr
 		}
@@ -439,7 +485,18 @@ func (s *UserService) AddUser(req dto.UserRequestsDTO, user_name string) (uuid.U
 	return userUUID, nil
 }
 
-func (s *UserService) UpdateUser(id string, req dto.UserRequestsDTO, username string, detailsMap map[string]interface{}, file []byte) error {
+func (s *UserService) UpdateUser(id string, req dto.UserRequestsDTO, actor entity.AuditActor, detailsMap map[string]interface{}, file []byte) error {
+	before, _ := s.GetSpecUserWithDetails(id)
+
+	if err := s.updateUser(id, req, actor.Username, detailsMap, file); err != nil {
+		return err
+	}
+
+	s.recordUser(actor, entity.AuditUpdate, id, before)
+	return nil
+}
+
+func (s *UserService) updateUser(id string, req dto.UserRequestsDTO, username string, detailsMap map[string]interface{}, file []byte) (err error) {
 	tx, err := s.userRepository.BeginTransaction()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %w", err)
@@ -484,6 +541,9 @@ func (s *UserService) UpdateUser(id string, req dto.UserRequestsDTO, username st
 
 	if _, ok := userData.Details.(map[string]interface{}); ok {
 		if err := s.updateRoleDetails(tx, userData, req, id); err != nil {
+			if customErr, ok := err.(*errors.CustomError); ok {
+				return customErr
+			}
 			logger.LogError(err, "error saving role details", map[string]interface{}{})
 			return fmt.Errorf("error saving role details: %w", err)
 		}
@@ -492,7 +552,18 @@ func (s *UserService) UpdateUser(id string, req dto.UserRequestsDTO, username st
 	return nil
 }
 
-func (service *UserService) DeleteSuperAdmin(id string, user_name string) error {
+func (service *UserService) DeleteSuperAdmin(id string, actor entity.AuditActor) error {
+	before, _ := service.GetSpecUserWithDetails(id)
+
+	if err := service.deleteSuperAdmin(id, actor.Username); err != nil {
+		return err
+	}
+
+	service.recordUser(actor, entity.AuditDelete, id, before)
+	return nil
+}
+
+func (service *UserService) deleteSuperAdmin(id string, user_name string) error {
 	tx, err := service.userRepository.BeginTransaction()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %w", err)
@@ -519,7 +590,18 @@ func (service *UserService) DeleteSuperAdmin(id string, user_name string) error
 	return nil
 }
 
-func (service *UserService) DeleteSchoolAdmin(id string, user_name string) error {
+func (service *UserService) DeleteSchoolAdmin(id string, actor entity.AuditActor) error {
+	before, _ := service.GetSpecUserWithDetails(id)
+
+	if err := service.deleteSchoolAdmin(id, actor.Username); err != nil {
+		return err
+	}
+
+	service.recordUser(actor, entity.AuditDelete, id, before)
+	return nil
+}
+
+func (service *UserService) deleteSchoolAdmin(id string, user_name string) error {
 	tx, err := service.userRepository.BeginTransaction()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %w", err)
@@ -549,7 +631,18 @@ func (service *UserService) DeleteSchoolAdmin(id string, user_name string) error
 	return nil
 }
 
-func (service *UserService) DeleteDriver(id string, user_name string) error {
+func (service *UserService) DeleteDriver(id string, actor entity.AuditActor) error {
+	before, _ := service.GetSpecUserWithDetails(id)
+
+	if err := service.deleteDriver(id, actor.Username); err != nil {
+		return err
+	}
+
+	service.recordUser(actor, entity.AuditDelete, id, before)
+	return nil
+}
+
+func (service *UserService) deleteDriver(id string, user_name string) error {
 	tx, err := service.userRepository.BeginTransaction()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %w", err)
@@ -579,35 +672,122 @@ func (service *UserService) DeleteDriver(id string, user_name string) error {
 	return nil
 }
 
+func (service *UserService) AddSchoolDriver(schoolUUID string, req dto.UserRequestsDTO, actor entity.AuditActor) (uuid.UUID, error) {
+	details, err := service.bindDriverToSchool(schoolUUID, req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	req.Details = details
+	return service.AddUser(req, actor)
+}
+
+func (service *UserService) UpdateSchoolDriver(id string, schoolUUID string, req dto.UserRequestsDTO, actor entity.AuditActor, detailsMap map[string]interface{}) error {
+	if _, err := service.GetSpecDriverForPermittedSchool(id, schoolUUID); err != nil {
+		return err
+	}
+
+	details, err := service.bindDriverToSchool(schoolUUID, req)
+	if err != nil {
+		return err
+	}
+
+	req.Details = details
+	return service.UpdateUser(id, req, actor, detailsMap, nil)
+}
+
+// Deactivating a driver also frees the vehicle they were driving so it can be given to someone else
+func (service *UserService) DeleteSchoolDriver(id string, schoolUUID string, actor entity.AuditActor) error {
+	if _, err := service.GetSpecDriverForPermittedSchool(id, schoolUUID); err != nil {
+		return err
+	}
+
+	before, _ := service.GetSpecUserWithDetails(id)
+
+	tx, err := service.userRepository.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := service.userRepository.DeleteDriver(tx, uuid.MustParse(id), actor.Username); err != nil {
+		return errors.New("driver not found", 404)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	service.recordUser(actor, entity.AuditDelete, id, before)
+	return nil
+}
+
+// Forces the driver into the school of the admin and checks the vehicle, if any, belongs to that school too
+func (service *UserService) bindDriverToSchool(schoolUUID string, req dto.UserRequestsDTO) (dto.DriverDetailsRequestsDTO, error) {
+	details, ok := req.Details.(dto.DriverDetailsRequestsDTO)
+	if !ok {
+		return dto.DriverDetailsRequestsDTO{}, errors.New("invalid driver details", 400)
+	}
+
+	details.SchoolUUID = schoolUUID
+
+	if details.VehicleUUID != "" {
+		if _, err := uuid.Parse(details.VehicleUUID); err != nil {
+			return dto.DriverDetailsRequestsDTO{}, errors.New("vehicle not found", 404)
+		}
+
+		exists, err := service.userRepository.CheckVehicleInSchool(details.VehicleUUID, schoolUUID)
+		if err != nil {
+			return dto.DriverDetailsRequestsDTO{}, err
+		}
+		if !exists {
+			return dto.DriverDetailsRequestsDTO{}, errors.New("vehicle not found", 404)
+		}
+	}
+
+	return details, nil
+}
+
+// The user as it is now is the after of the entry, a deleted user has none
+func (service *UserService) recordUser(actor entity.AuditActor, action, id string, before interface{}) {
+	var after interface{}
+	if action != entity.AuditDelete {
+		if user, err := service.GetSpecUserWithDetails(id); err == nil {
+			after = user
+		}
+	}
+
+	service.auditService.Record(actor, action, entity.AuditEntityUser, id, before, after)
+}
 
 func (service *UserService) GetSpecUserWithDetails(id string) (entity.User, error) {
 	user, err := service.userRepository.FetchSpecificUser(id)
 	if err != nil {
 		return entity.User{}, err
 	}
-	switch user.RoleCode {
-	case "SA":
+	switch user.Role {
+	case entity.SuperAdmin:
 		superAdminDetails, err := service.userRepository.FetchSuperAdminDetails(user.UUID)
 		if err != nil {
 			return entity.User{}, err
 		}
 		user.Details = superAdminDetails
 		return user, nil
-	case "AS":
+	case entity.SchoolAdmin:
 		schoolAdminDetails, err := service.userRepository.FetchSchoolAdminDetails(user.UUID)
 		if err != nil {
 			return entity.User{}, err
 		}
 		user.Details = schoolAdminDetails
 		return user, nil
-	case "P":
+	case entity.Parent:
 		parentDetails, err := service.userRepository.FetchParentDetails(user.UUID)
 		if err != nil {
 			return entity.User{}, err
 		}
 		user.Details = parentDetails
 		return user, nil
-	case "D":
+	case entity.Driver:
 		driverDetails, err := service.userRepository.FetchDriverDetails(user.UUID)
 		if err != nil {
 			return entity.User{}, err
@@ -709,7 +889,11 @@ func (s *UserService) saveRoleDetails(tx *sqlx.Tx, userEntity entity.User, req d
 			LicenseNumber: req.Details.(dto.DriverDetailsRequestsDTO).LicenseNumber,
 		}
 
-		if err := s.userRepository.SaveDriverDetails(tx, details, userEntity.UUID, params); err != nil {
+		if err := s.checkVehicleFree(details.VehicleUUID, ""); err != nil {
+			return err
+		}
+
+		if err := s.userRepository.SaveDriverDetails(tx, details, userEntity.UUID, params, userEntity.CreatedBy.String); err != nil {
 			return err
 		}
 	default:
@@ -779,7 +963,11 @@ func (s *UserService) updateRoleDetails(tx *sqlx.Tx, userEntity entity.User, req
 			return fmt.Errorf("invalid UUID: %w", err)
 		}
 
-		if err := s.userRepository.UpdateDriverDetails(tx, details, parsedUUID); err != nil {
+		if err := s.checkVehicleFree(details.VehicleUUID, id); err != nil {
+			return err
+		}
+
+		if err := s.userRepository.UpdateDriverDetails(tx, details, parsedUUID, userEntity.UpdatedBy.String); err != nil {
 			return err
 		}
 	default:
@@ -798,4 +986,21 @@ func parseSafeUUID(id string) *uuid.UUID {
 		return nil
 	}
 	return &parsedUUID
-}
\ No newline at end of file
+}
+
+// A vehicle is given to one driver at a time, it has to be freed before someone else gets it
+func (s *UserService) checkVehicleFree(vehicleUUID *uuid.UUID, driverUUID string) error {
+	if vehicleUUID == nil || *vehicleUUID == uuid.Nil {
+		return nil
+	}
+
+	assigned, err := s.userRepository.CheckVehicleAssignedToOther(vehicleUUID.String(), driverUUID)
+	if err != nil {
+		return err
+	}
+	if assigned {
+		return errors.New("vehicle is already assigned to another driver", 409)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+
+	"github.com/google/uuid"
+)
+
+type VehicleAssignmentServiceInterface interface {
+	GetVehicleAssignments(vehicleUUID, at string) ([]dto.VehicleAssignmentResponseDTO, error)
+	GetDriverAssignments(driverUUID string) ([]dto.VehicleAssignmentResponseDTO, error)
+	AssignVehicle(req dto.VehicleAssignmentRequestDTO, username string) error
+	UnassignVehicle(vehicleUUID, username string) error
+}
+
+type VehicleAssignmentService struct {
+	vehicleAssignmentRepository repositories.VehicleAssignmentRepositoryInterface
+}
+
+func NewVehicleAssignmentService(vehicleAssignmentRepository repositories.VehicleAssignmentRepositoryInterface) VehicleAssignmentService {
+	return VehicleAssignmentService{
+		vehicleAssignmentRepository: vehicleAssignmentRepository,
+	}
+}
+
+// at narrows the history down to who had the vehicle at that moment (RFC3339) or during that day (YYYY-MM-DD)
+func (service *VehicleAssignmentService) GetVehicleAssignments(vehicleUUID, at string) ([]dto.VehicleAssignmentResponseDTO, error) {
+	if _, err := service.fetchVehicleSchool(vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	var assignments []entity.VehicleAssignment
+	var err error
+	if at == "" {
+		assignments, err = service.vehicleAssignmentRepository.FetchAssignmentsByVehicle(vehicleUUID)
+	} else {
+		from, to, parseErr := parseAssignmentPeriod(at)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		assignments, err = service.vehicleAssignmentRepository.FetchAssignmentsDuring(vehicleUUID, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentsDTO := []dto.VehicleAssignmentResponseDTO{}
+	for _, assignment := range assignments {
+		assignmentsDTO = append(assignmentsDTO, toVehicleAssignmentDTO(assignment))
+	}
+
+	return assignmentsDTO, nil
+}
+
+func (service *VehicleAssignmentService) GetDriverAssignments(driverUUID string) ([]dto.VehicleAssignmentResponseDTO, error) {
+	if _, err := service.fetchDriverSchool(driverUUID); err != nil {
+		return nil, err
+	}
+
+	assignments, err := service.vehicleAssignmentRepository.FetchAssignmentsByDriver(driverUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentsDTO := []dto.VehicleAssignmentResponseDTO{}
+	for _, assignment := range assignments {
+		assignmentsDTO = append(assignmentsDTO, toVehicleAssignmentDTO(assignment))
+	}
+
+	return assignmentsDTO, nil
+}
+
+// Moves the driver to the vehicle, ending the driver's previous assignment.
+// A vehicle that another driver still has must be unassigned first.
+func (service *VehicleAssignmentService) AssignVehicle(req dto.VehicleAssignmentRequestDTO, username string) error {
+	driverSchool, err := service.fetchDriverSchool(req.DriverUUID)
+	if err != nil {
+		return err
+	}
+
+	vehicleSchool, err := service.fetchVehicleSchool(req.VehicleUUID)
+	if err != nil {
+		return err
+	}
+
+	if driverSchool.Valid && vehicleSchool.Valid && driverSchool.String != vehicleSchool.String {
+		return errors.New("driver and vehicle belong to different schools", 400)
+	}
+
+	current, err := service.vehicleAssignmentRepository.FetchActiveAssignmentByVehicle(req.VehicleUUID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && current.DriverUUID.String() != req.DriverUUID {
+		name := strings.TrimSpace(current.DriverFirstName.String + " " + current.DriverLastName.String)
+		return errors.New("vehicle is already assigned to "+name+", unassign it first", 409)
+	}
+
+	tx, err := service.vehicleAssignmentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.vehicleAssignmentRepository.AssignVehicle(tx, uuid.MustParse(req.DriverUUID), uuid.MustParse(req.VehicleUUID), username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *VehicleAssignmentService) UnassignVehicle(vehicleUUID, username string) error {
+	if _, err := service.fetchVehicleSchool(vehicleUUID); err != nil {
+		return err
+	}
+
+	if _, err := service.vehicleAssignmentRepository.FetchActiveAssignmentByVehicle(vehicleUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("vehicle has no driver assigned", 404)
+		}
+		return err
+	}
+
+	tx, err := service.vehicleAssignmentRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := service.vehicleAssignmentRepository.ReleaseVehicle(tx, uuid.MustParse(vehicleUUID), username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (service *VehicleAssignmentService) fetchDriverSchool(driverUUID string) (sql.NullString, error) {
+	if _, err := uuid.Parse(driverUUID); err != nil {
+		return sql.NullString{}, errors.New("driver not found", 404)
+	}
+
+	schoolUUID, err := service.vehicleAssignmentRepository.FetchDriverSchool(driverUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullString{}, errors.New("driver not found", 404)
+		}
+		return sql.NullString{}, err
+	}
+
+	return schoolUUID, nil
+}
+
+func (service *VehicleAssignmentService) fetchVehicleSchool(vehicleUUID string) (sql.NullString, error) {
+	if _, err := uuid.Parse(vehicleUUID); err != nil {
+		return sql.NullString{}, errors.New("vehicle not found", 404)
+	}
+
+	schoolUUID, err := service.vehicleAssignmentRepository.FetchVehicleSchool(vehicleUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullString{}, errors.New("vehicle not found", 404)
+		}
+		return sql.NullString{}, err
+	}
+
+	return schoolUUID, nil
+}
+
+// A date covers the whole day in server time, a timestamp a single moment
+func parseAssignmentPeriod(at string) (time.Time, time.Time, error) {
+	if day, err := time.ParseInLocation(time.DateOnly, at, time.Local); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+
+	moment, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("at must be a date like 2024-01-31 or a time like 2024-01-31T07:30:00+07:00", 400)
+	}
+
+	return moment, moment.Add(time.Microsecond), nil
+}
+
+func toVehicleAssignmentDTO(assignment entity.VehicleAssignment) dto.VehicleAssignmentResponseDTO {
+	assignmentDTO := dto.VehicleAssignmentResponseDTO{
+		UUID:          assignment.UUID.String(),
+		DriverUUID:    assignment.DriverUUID.String(),
+		DriverName:    strings.TrimSpace(assignment.DriverFirstName.String + " " + assignment.DriverLastName.String),
+		VehicleUUID:   assignment.VehicleUUID.String(),
+		VehicleName:   assignment.VehicleName,
+		VehicleNumber: assignment.VehicleNumber,
+		AssignedFrom:  assignment.AssignedFrom.Format(time.RFC3339),
+		AssignedBy:    safeStringFormat(assignment.AssignedBy),
+		Active:        !assignment.AssignedTo.Valid,
+	}
+
+	if assignment.AssignedTo.Valid {
+		assignmentDTO.AssignedTo = assignment.AssignedTo.Time.Format(time.RFC3339)
+		assignmentDTO.UnassignedBy = safeStringFormat(assignment.UnassignedBy)
+	}
+
+	return assignmentDTO
+}
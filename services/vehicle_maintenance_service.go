@@ -0,0 +1,362 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+)
+
+type VehicleMaintenanceServiceInterface interface {
+	GetMaintenances(vehicleUUID string) ([]dto.VehicleMaintenanceResponseDTO, error)
+	AddMaintenance(vehicleUUID string, req dto.VehicleMaintenanceRequestDTO, username string) error
+	UpdateMaintenance(vehicleUUID, maintenanceUUID string, req dto.VehicleMaintenanceRequestDTO, username string) error
+	DeleteMaintenance(vehicleUUID, maintenanceUUID, username string) error
+
+	GetVehicleDocuments(vehicleUUID string) ([]dto.VehicleDocumentResponseDTO, error)
+	GetVehicleDocumentFile(vehicleUUID, documentUUID string) (string, error)
+	AddVehicleDocument(vehicleUUID string, req dto.VehicleDocumentRequestDTO, file, username string) error
+	UpdateVehicleDocument(vehicleUUID, documentUUID string, req dto.VehicleDocumentRequestDTO, file, username string) error
+	DeleteVehicleDocument(vehicleUUID, documentUUID, username string) error
+
+	CheckLapsedVehicles() error
+}
+
+type VehicleMaintenanceService struct {
+	vehicleMaintenanceRepository repositories.VehicleMaintenanceRepositoryInterface
+}
+
+func NewVehicleMaintenanceService(vehicleMaintenanceRepository repositories.VehicleMaintenanceRepositoryInterface) VehicleMaintenanceService {
+	return VehicleMaintenanceService{
+		vehicleMaintenanceRepository: vehicleMaintenanceRepository,
+	}
+}
+
+func (service *VehicleMaintenanceService) GetMaintenances(vehicleUUID string) ([]dto.VehicleMaintenanceResponseDTO, error) {
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	maintenances, err := service.vehicleMaintenanceRepository.FetchMaintenances(vehicleUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenancesDTO := []dto.VehicleMaintenanceResponseDTO{}
+	for _, maintenance := range maintenances {
+		maintenancesDTO = append(maintenancesDTO, toVehicleMaintenanceDTO(maintenance))
+	}
+
+	return maintenancesDTO, nil
+}
+
+func (service *VehicleMaintenanceService) AddMaintenance(vehicleUUID string, req dto.VehicleMaintenanceRequestDTO, username string) error {
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return err
+	}
+
+	maintenance, err := toVehicleMaintenanceEntity(req)
+	if err != nil {
+		return err
+	}
+
+	maintenance.UUID = uuid.New()
+	maintenance.ID = time.Now().UnixMilli()*1e6 + int64(maintenance.UUID.ID()%1e6)
+	maintenance.VehicleUUID = uuid.MustParse(vehicleUUID)
+	maintenance.CreatedBy = toNullString(username)
+
+	return service.vehicleMaintenanceRepository.SaveMaintenance(maintenance)
+}
+
+func (service *VehicleMaintenanceService) UpdateMaintenance(vehicleUUID, maintenanceUUID string, req dto.VehicleMaintenanceRequestDTO, username string) error {
+	existing, err := service.fetchMaintenance(vehicleUUID, maintenanceUUID)
+	if err != nil {
+		return err
+	}
+
+	maintenance, err := toVehicleMaintenanceEntity(req)
+	if err != nil {
+		return err
+	}
+
+	maintenance.UUID = existing.UUID
+	maintenance.VehicleUUID = existing.VehicleUUID
+	maintenance.UpdatedBy = toNullString(username)
+
+	return service.vehicleMaintenanceRepository.UpdateMaintenance(maintenance)
+}
+
+func (service *VehicleMaintenanceService) DeleteMaintenance(vehicleUUID, maintenanceUUID, username string) error {
+	if _, err := service.fetchMaintenance(vehicleUUID, maintenanceUUID); err != nil {
+		return err
+	}
+
+	return service.vehicleMaintenanceRepository.DeleteMaintenance(vehicleUUID, maintenanceUUID, username)
+}
+
+func (service *VehicleMaintenanceService) GetVehicleDocuments(vehicleUUID string) ([]dto.VehicleDocumentResponseDTO, error) {
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return nil, err
+	}
+
+	documents, err := service.vehicleMaintenanceRepository.FetchVehicleDocuments(vehicleUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentsDTO := []dto.VehicleDocumentResponseDTO{}
+	for _, document := range documents {
+		documentsDTO = append(documentsDTO, toVehicleDocumentDTO(document))
+	}
+
+	return documentsDTO, nil
+}
+
+func (service *VehicleMaintenanceService) GetVehicleDocumentFile(vehicleUUID, documentUUID string) (string, error) {
+	document, err := service.fetchVehicleDocument(vehicleUUID, documentUUID)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.DocumentPath(document.File), nil
+}
+
+// The uploaded file is removed again when the document cannot be saved
+func (service *VehicleMaintenanceService) AddVehicleDocument(vehicleUUID string, req dto.VehicleDocumentRequestDTO, file, username string) (err error) {
+	defer func() {
+		if err != nil {
+			utils.DeleteDocument(file)
+		}
+	}()
+
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return err
+	}
+
+	document, err := toVehicleDocumentEntity(req)
+	if err != nil {
+		return err
+	}
+
+	document.UUID = uuid.New()
+	document.ID = time.Now().UnixMilli()*1e6 + int64(document.UUID.ID()%1e6)
+	document.VehicleUUID = uuid.MustParse(vehicleUUID)
+	document.File = file
+	document.CreatedBy = toNullString(username)
+
+	if err := service.vehicleMaintenanceRepository.SaveVehicleDocument(document); err != nil {
+		return err
+	}
+
+	service.syncVehicleStatus(vehicleUUID)
+	return nil
+}
+
+// file is empty when the scan is kept, otherwise the old scan is removed once the new one is saved
+func (service *VehicleMaintenanceService) UpdateVehicleDocument(vehicleUUID, documentUUID string, req dto.VehicleDocumentRequestDTO, file, username string) (err error) {
+	defer func() {
+		if err != nil {
+			utils.DeleteDocument(file)
+		}
+	}()
+
+	existing, err := service.fetchVehicleDocument(vehicleUUID, documentUUID)
+	if err != nil {
+		return err
+	}
+
+	document, err := toVehicleDocumentEntity(req)
+	if err != nil {
+		return err
+	}
+
+	document.UUID = existing.UUID
+	document.VehicleUUID = existing.VehicleUUID
+	document.File = existing.File
+	document.UpdatedBy = toNullString(username)
+	if file != "" {
+		document.File = file
+	}
+
+	if err := service.vehicleMaintenanceRepository.UpdateVehicleDocument(document); err != nil {
+		return err
+	}
+
+	if file != "" {
+		if err := utils.DeleteDocument(existing.File); err != nil {
+			logger.LogError(err, "Failed to delete replaced vehicle document", map[string]interface{}{"file": existing.File})
+		}
+	}
+
+	service.syncVehicleStatus(vehicleUUID)
+	return nil
+}
+
+func (service *VehicleMaintenanceService) DeleteVehicleDocument(vehicleUUID, documentUUID, username string) error {
+	if _, err := service.fetchVehicleDocument(vehicleUUID, documentUUID); err != nil {
+		return err
+	}
+
+	if err := service.vehicleMaintenanceRepository.DeleteVehicleDocument(vehicleUUID, documentUUID, username); err != nil {
+		return err
+	}
+
+	service.syncVehicleStatus(vehicleUUID)
+	return nil
+}
+
+// Takes every vehicle with a lapsed document out of service and puts renewed ones back
+func (service *VehicleMaintenanceService) CheckLapsedVehicles() error {
+	lapsed, restored, err := service.vehicleMaintenanceRepository.SyncLapsedVehicles("")
+	if err != nil {
+		return err
+	}
+
+	if lapsed > 0 || restored > 0 {
+		logger.LogInfo("Vehicle statuses synced with their documents", map[string]interface{}{"out_of_service": lapsed, "back_in_service": restored})
+	}
+	return nil
+}
+
+// The document change itself is saved already, a failed sync is picked up by the next lapse check
+func (service *VehicleMaintenanceService) syncVehicleStatus(vehicleUUID string) {
+	if _, _, err := service.vehicleMaintenanceRepository.SyncLapsedVehicles(vehicleUUID); err != nil {
+		logger.LogError(err, "Failed to sync vehicle status with its documents", map[string]interface{}{"vehicle_uuid": vehicleUUID})
+	}
+}
+
+func (service *VehicleMaintenanceService) checkVehicle(vehicleUUID string) error {
+	if _, err := uuid.Parse(vehicleUUID); err != nil {
+		return errors.New("vehicle not found", 404)
+	}
+
+	exists, err := service.vehicleMaintenanceRepository.CheckVehicleExists(vehicleUUID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("vehicle not found", 404)
+	}
+
+	return nil
+}
+
+func (service *VehicleMaintenanceService) fetchMaintenance(vehicleUUID, maintenanceUUID string) (entity.VehicleMaintenance, error) {
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return entity.VehicleMaintenance{}, err
+	}
+
+	if _, err := uuid.Parse(maintenanceUUID); err != nil {
+		return entity.VehicleMaintenance{}, errors.New("maintenance record not found", 404)
+	}
+
+	maintenance, err := service.vehicleMaintenanceRepository.FetchSpecMaintenance(vehicleUUID, maintenanceUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.VehicleMaintenance{}, errors.New("maintenance record not found", 404)
+		}
+		return entity.VehicleMaintenance{}, err
+	}
+
+	return maintenance, nil
+}
+
+func (service *VehicleMaintenanceService) fetchVehicleDocument(vehicleUUID, documentUUID string) (entity.VehicleDocument, error) {
+	if err := service.checkVehicle(vehicleUUID); err != nil {
+		return entity.VehicleDocument{}, err
+	}
+
+	if _, err := uuid.Parse(documentUUID); err != nil {
+		return entity.VehicleDocument{}, errors.New("document not found", 404)
+	}
+
+	document, err := service.vehicleMaintenanceRepository.FetchSpecVehicleDocument(vehicleUUID, documentUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.VehicleDocument{}, errors.New("document not found", 404)
+		}
+		return entity.VehicleDocument{}, err
+	}
+
+	return document, nil
+}
+
+func toVehicleMaintenanceEntity(req dto.VehicleMaintenanceRequestDTO) (entity.VehicleMaintenance, error) {
+	serviceDate, err := time.Parse(time.DateOnly, req.ServiceDate)
+	if err != nil {
+		return entity.VehicleMaintenance{}, errors.New("service_date must be a date like 2024-01-31", 400)
+	}
+
+	if serviceDate.After(time.Now()) {
+		return entity.VehicleMaintenance{}, errors.New("service_date cannot be in the future", 400)
+	}
+
+	return entity.VehicleMaintenance{
+		ServiceDate: serviceDate,
+		OdometerKm:  req.OdometerKm,
+		Cost:        req.Cost,
+		Notes:       toNullString(req.Notes),
+	}, nil
+}
+
+func toVehicleMaintenanceDTO(maintenance entity.VehicleMaintenance) dto.VehicleMaintenanceResponseDTO {
+	return dto.VehicleMaintenanceResponseDTO{
+		UUID:        maintenance.UUID.String(),
+		VehicleUUID: maintenance.VehicleUUID.String(),
+		ServiceDate: maintenance.ServiceDate.Format(time.DateOnly),
+		OdometerKm:  maintenance.OdometerKm,
+		Cost:        maintenance.Cost,
+		Notes:       maintenance.Notes.String,
+		CreatedAt:   safeTimeFormat(maintenance.CreatedAt),
+		CreatedBy:   safeStringFormat(maintenance.CreatedBy),
+		UpdatedAt:   safeTimeFormat(maintenance.UpdatedAt),
+		UpdatedBy:   safeStringFormat(maintenance.UpdatedBy),
+	}
+}
+
+func toVehicleDocumentEntity(req dto.VehicleDocumentRequestDTO) (entity.VehicleDocument, error) {
+	issuedOn, err := time.Parse(time.DateOnly, req.IssuedOn)
+	if err != nil {
+		return entity.VehicleDocument{}, errors.New("issued_on must be a date like 2024-01-31", 400)
+	}
+
+	expiresOn, err := time.Parse(time.DateOnly, req.ExpiresOn)
+	if err != nil {
+		return entity.VehicleDocument{}, errors.New("expires_on must be a date like 2025-01-31", 400)
+	}
+	if !expiresOn.After(issuedOn) {
+		return entity.VehicleDocument{}, errors.New("expires_on must be after issued_on", 400)
+	}
+
+	return entity.VehicleDocument{
+		Type:      entity.VehicleDocumentType(req.Type),
+		Number:    req.Number,
+		IssuedOn:  issuedOn,
+		ExpiresOn: expiresOn,
+	}, nil
+}
+
+func toVehicleDocumentDTO(document entity.VehicleDocument) dto.VehicleDocumentResponseDTO {
+	expiresOn := document.ExpiresOn.Format(time.DateOnly)
+
+	return dto.VehicleDocumentResponseDTO{
+		UUID:        document.UUID.String(),
+		VehicleUUID: document.VehicleUUID.String(),
+		Type:        string(document.Type),
+		Number:      document.Number,
+		IssuedOn:    document.IssuedOn.Format(time.DateOnly),
+		ExpiresOn:   expiresOn,
+		Expired:     expiresOn < time.Now().Format(time.DateOnly),
+		CreatedAt:   safeTimeFormat(document.CreatedAt),
+		CreatedBy:   safeStringFormat(document.CreatedBy),
+		UpdatedAt:   safeTimeFormat(document.UpdatedAt),
+		UpdatedBy:   safeStringFormat(document.UpdatedBy),
+	}
+}
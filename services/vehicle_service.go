@@ -1,10 +1,12 @@
 package services
 
 import (
+	"database/sql"
 	"shuttle/errors"
 	"shuttle/models/dto"
 	"shuttle/models/entity"
 	"shuttle/repositories"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,18 +15,24 @@ import (
 type VehicleServiceInterface interface {
 	GetSpecVehicle(uuid string) (dto.VehicleResponseDTO, error)
 	GetAllVehicles(page, limit int, sortField, sortDirection string) ([]dto.VehicleResponseDTO, int, error)
-	AddVehicle(req dto.VehicleRequestDTO) error
-	UpdateVehicle(id string, req dto.VehicleRequestDTO, username string) error
-	DeleteVehicle(id string, username string) error
+	AddVehicle(req dto.VehicleRequestDTO, actor entity.AuditActor) error
+	UpdateVehicle(id string, req dto.VehicleRequestDTO, actor entity.AuditActor) error
+	DeleteVehicle(id string, actor entity.AuditActor) error
+
+	GetSchoolVehicles(schoolUUID string, page, limit int, sortField, sortDirection string) ([]dto.SchoolVehicleResponseDTO, int, error)
+	GetSpecSchoolVehicle(id, schoolUUID string) (dto.SchoolVehicleResponseDTO, error)
+	UpdateSchoolVehicle(id, schoolUUID string, req dto.SchoolVehicleRequestDTO, actor entity.AuditActor) error
 }
 
 type VehicleService struct {
 	vehicleRepository repositories.VehicleRepositoryInterface
+	auditService      AuditServiceInterface
 }
 
-func NewVehicleService(vehicleRepository repositories.VehicleRepositoryInterface) VehicleService {
+func NewVehicleService(vehicleRepository repositories.VehicleRepositoryInterface, auditService AuditServiceInterface) VehicleService {
 	return VehicleService{
 		vehicleRepository: vehicleRepository,
+		auditService:      auditService,
 	}
 }
 
@@ -120,7 +128,7 @@ func (service *VehicleService) GetSpecVehicle(id string) (dto.VehicleResponseDTO
 	return vehicleDTO, nil
 }
 
-func (service *VehicleService) AddVehicle(req dto.VehicleRequestDTO) error {
+func (service *VehicleService) AddVehicle(req dto.VehicleRequestDTO, actor entity.AuditActor) error {
 	vehicle := entity.Vehicle{
 		ID:            time.Now().UnixMilli()*1e6 + int64(uuid.New().ID()%1e6),
 		UUID:          uuid.New(),
@@ -133,7 +141,7 @@ func (service *VehicleService) AddVehicle(req dto.VehicleRequestDTO) error {
 	}
 
 	if req.School != "" {
-		schoolUUID, err := uuid.Parse(req.School)
+		schoolUUID, err := service.parseSchool(req.School)
 		if err != nil {
 			return err
 		}
@@ -156,15 +164,18 @@ func (service *VehicleService) AddVehicle(req dto.VehicleRequestDTO) error {
 		return err
 	}
 
+	service.auditService.Record(actor, entity.AuditCreate, entity.AuditEntityVehicle, vehicle.UUID.String(), nil, vehicle)
 	return nil
 }
 
-func (service *VehicleService) UpdateVehicle(id string, req dto.VehicleRequestDTO, username string) error {
+func (service *VehicleService) UpdateVehicle(id string, req dto.VehicleRequestDTO, actor entity.AuditActor) error {
 	parsedUUID, err := uuid.Parse(id)
 	if err != nil {
 		return err
 	}
 
+	before, _, _, _ := service.vehicleRepository.FetchSpecVehicle(id)
+
 	vehicle := entity.Vehicle{
 		UUID:          parsedUUID,
 		VehicleName:   req.Name,
@@ -174,11 +185,11 @@ func (service *VehicleService) UpdateVehicle(id string, req dto.VehicleRequestDT
 		VehicleSeats:  req.Seats,
 		VehicleStatus: req.Status,
 		UpdatedAt:     toNullTime(time.Now()),
-		UpdatedBy:     toNullString(username),
+		UpdatedBy:     toNullString(actor.Username),
 	}
 
 	if req.School != "" {
-		schoolUUID, err := uuid.Parse(req.School)
+		schoolUUID, err := service.parseSchool(req.School)
 		if err != nil {
 			return err
 		}
@@ -201,19 +212,24 @@ func (service *VehicleService) UpdateVehicle(id string, req dto.VehicleRequestDT
 		return err
 	}
 
+	if after, _, _, err := service.vehicleRepository.FetchSpecVehicle(id); err == nil {
+		service.auditService.Record(actor, entity.AuditUpdate, entity.AuditEntityVehicle, id, before, after)
+	}
 	return nil
 }
 
-func (service *VehicleService) DeleteVehicle(id string, username string) error {
+func (service *VehicleService) DeleteVehicle(id string, actor entity.AuditActor) error {
 	parsedUUID, err := uuid.Parse(id)
 	if err != nil {
 		return err
 	}
 
+	before, _, _, _ := service.vehicleRepository.FetchSpecVehicle(id)
+
 	vehicle := entity.Vehicle{
 		UUID:      parsedUUID,
 		DeletedAt: toNullTime(time.Now()),
-		DeletedBy: toNullString(username),
+		DeletedBy: toNullString(actor.Username),
 	}
 
 	err = service.vehicleRepository.DeleteVehicle(vehicle)
@@ -221,5 +237,129 @@ func (service *VehicleService) DeleteVehicle(id string, username string) error {
 		return err
 	}
 
+	service.auditService.Record(actor, entity.AuditDelete, entity.AuditEntityVehicle, id, before, nil)
 	return nil
 }
+
+func (service *VehicleService) GetSchoolVehicles(schoolUUID string, page, limit int, sortField, sortDirection string) ([]dto.SchoolVehicleResponseDTO, int, error) {
+	offset := (page - 1) * limit
+
+	vehicles, err := service.vehicleRepository.FetchSchoolVehicles(schoolUUID, offset, limit, sortField, sortDirection)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.vehicleRepository.CountSchoolVehicles(schoolUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vehiclesDTO := []dto.SchoolVehicleResponseDTO{}
+	for _, vehicle := range vehicles {
+		vehiclesDTO = append(vehiclesDTO, toSchoolVehicleDTO(vehicle))
+	}
+
+	return vehiclesDTO, total, nil
+}
+
+func (service *VehicleService) GetSpecSchoolVehicle(id, schoolUUID string) (dto.SchoolVehicleResponseDTO, error) {
+	vehicle, err := service.fetchSchoolVehicle(id, schoolUUID)
+	if err != nil {
+		return dto.SchoolVehicleResponseDTO{}, err
+	}
+
+	return toSchoolVehicleDTO(vehicle), nil
+}
+
+func (service *VehicleService) UpdateSchoolVehicle(id, schoolUUID string, req dto.SchoolVehicleRequestDTO, actor entity.AuditActor) error {
+	vehicle, err := service.fetchSchoolVehicle(id, schoolUUID)
+	if err != nil {
+		return err
+	}
+	before := vehicle
+
+	vehicle.RouteUUID = nil
+	if req.RouteUUID != "" {
+		exists, err := service.vehicleRepository.CheckRouteInSchool(req.RouteUUID, schoolUUID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.New("route not found", 404)
+		}
+		routeUUID := uuid.MustParse(req.RouteUUID)
+		vehicle.RouteUUID = &routeUUID
+	}
+
+	vehicle.VehicleStatus = req.Status
+	vehicle.UpdatedBy = toNullString(actor.Username)
+
+	if err := service.vehicleRepository.UpdateSchoolVehicle(vehicle); err != nil {
+		return err
+	}
+
+	if after, err := service.fetchSchoolVehicle(id, schoolUUID); err == nil {
+		service.auditService.Record(actor, entity.AuditUpdate, entity.AuditEntityVehicle, id, before, after)
+	}
+	return nil
+}
+
+func (service *VehicleService) fetchSchoolVehicle(id, schoolUUID string) (entity.SchoolVehicle, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.SchoolVehicle{}, errors.New("vehicle not found", 404)
+	}
+
+	vehicle, err := service.vehicleRepository.FetchSpecSchoolVehicle(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.SchoolVehicle{}, errors.New("vehicle not found", 404)
+		}
+		return entity.SchoolVehicle{}, err
+	}
+
+	return vehicle, nil
+}
+
+func (service *VehicleService) parseSchool(school string) (uuid.UUID, error) {
+	schoolUUID, err := uuid.Parse(school)
+	if err != nil {
+		return uuid.Nil, errors.New("school not found", 404)
+	}
+
+	exists, err := service.vehicleRepository.CheckSchoolExists(school)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, errors.New("school not found", 404)
+	}
+
+	return schoolUUID, nil
+}
+
+func toSchoolVehicleDTO(vehicle entity.SchoolVehicle) dto.SchoolVehicleResponseDTO {
+	vehicleDTO := dto.SchoolVehicleResponseDTO{
+		UUID:       vehicle.UUID.String(),
+		Name:       vehicle.VehicleName,
+		Number:     vehicle.VehicleNumber,
+		Type:       vehicle.VehicleType,
+		Color:      vehicle.VehicleColor,
+		Seats:      vehicle.VehicleSeats,
+		Status:     vehicle.VehicleStatus,
+		DriverName: "N/A",
+		RouteName:  safeStringFormat(vehicle.RouteName),
+		CreatedAt:  safeTimeFormat(vehicle.CreatedAt),
+		UpdatedAt:  safeTimeFormat(vehicle.UpdatedAt),
+		UpdatedBy:  safeStringFormat(vehicle.UpdatedBy),
+	}
+
+	if vehicle.DriverUUID != nil {
+		vehicleDTO.DriverUUID = vehicle.DriverUUID.String()
+		vehicleDTO.DriverName = strings.TrimSpace(vehicle.DriverFirstName.String + " " + vehicle.DriverLastName.String)
+	}
+	if vehicle.RouteUUID != nil {
+		vehicleDTO.RouteUUID = vehicle.RouteUUID.String()
+	}
+
+	return vehicleDTO
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"shuttle/errors"
+	"shuttle/logger"
+	"shuttle/models/dto"
+	"shuttle/models/entity"
+	"shuttle/repositories"
+	"shuttle/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+// Domain events a school can receive on its webhook endpoints
+var webhookEventTypes = []string{entity.EventShuttleStatusChanged, entity.EventStudentCreated, entity.EventTripCompleted}
+
+type WebhookServiceInterface interface {
+	GetEndpoints(schoolUUID string) ([]dto.WebhookEndpointResponseDTO, error)
+	GetSpecEndpoint(schoolUUID, id string) (dto.WebhookEndpointResponseDTO, error)
+	AddEndpoint(schoolUUID string, req dto.WebhookEndpointRequestDTO, username string) (dto.WebhookEndpointResponseDTO, error)
+	UpdateEndpoint(schoolUUID, id string, req dto.WebhookEndpointRequestDTO, username string) error
+	DeleteEndpoint(schoolUUID, id, username string) error
+
+	GetDeliveries(schoolUUID, id string, page, limit int, status string) ([]dto.WebhookDeliveryResponseDTO, int, error)
+	RedeliverDelivery(schoolUUID, id, deliveryID, username string) error
+
+	QueueDeliveries(event entity.DomainEvent) error
+	SendDelivery(payload json.RawMessage) error
+}
+
+type WebhookService struct {
+	webhookRepository repositories.WebhookRepositoryInterface
+	jobService        JobServiceInterface
+}
+
+// What is posted to an endpoint, data is the payload of the domain event
+type webhookBody struct {
+	EventUUID  string          `json:"event_uuid"`
+	EventType  string          `json:"event_type"`
+	SchoolUUID string          `json:"school_uuid"`
+	OccurredAt string          `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type webhookDeliveryJob struct {
+	DeliveryUUID string `json:"delivery_uuid"`
+}
+
+func NewWebhookService(webhookRepository repositories.WebhookRepositoryInterface, jobService JobServiceInterface) WebhookService {
+	return WebhookService{
+		webhookRepository: webhookRepository,
+		jobService:        jobService,
+	}
+}
+
+func (service *WebhookService) GetEndpoints(schoolUUID string) ([]dto.WebhookEndpointResponseDTO, error) {
+	if err := service.checkSchool(schoolUUID); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := service.webhookRepository.FetchEndpoints(schoolUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpointsDTO := []dto.WebhookEndpointResponseDTO{}
+	for _, endpoint := range endpoints {
+		endpointsDTO = append(endpointsDTO, toWebhookEndpointDTO(endpoint))
+	}
+
+	return endpointsDTO, nil
+}
+
+func (service *WebhookService) GetSpecEndpoint(schoolUUID, id string) (dto.WebhookEndpointResponseDTO, error) {
+	endpoint, err := service.fetchEndpoint(schoolUUID, id)
+	if err != nil {
+		return dto.WebhookEndpointResponseDTO{}, err
+	}
+
+	return toWebhookEndpointDTO(endpoint), nil
+}
+
+// The secret that signs the deliveries is only returned here, the school has to store it
+func (service *WebhookService) AddEndpoint(schoolUUID string, req dto.WebhookEndpointRequestDTO, username string) (dto.WebhookEndpointResponseDTO, error) {
+	if err := service.checkSchool(schoolUUID); err != nil {
+		return dto.WebhookEndpointResponseDTO{}, err
+	}
+
+	endpoint, err := toWebhookEndpointEntity(req)
+	if err != nil {
+		return dto.WebhookEndpointResponseDTO{}, err
+	}
+
+	secret, err := utils.NewWebhookSecret()
+	if err != nil {
+		return dto.WebhookEndpointResponseDTO{}, err
+	}
+
+	endpoint.UUID = uuid.New()
+	endpoint.ID = time.Now().UnixMilli()*1e6 + int64(endpoint.UUID.ID()%1e6)
+	endpoint.SchoolUUID = uuid.MustParse(schoolUUID)
+	endpoint.Secret = secret
+	endpoint.CreatedBy = toNullString(username)
+
+	if err := service.webhookRepository.SaveEndpoint(endpoint); err != nil {
+		return dto.WebhookEndpointResponseDTO{}, err
+	}
+
+	endpointDTO := toWebhookEndpointDTO(endpoint)
+	endpointDTO.Secret = secret
+
+	return endpointDTO, nil
+}
+
+func (service *WebhookService) UpdateEndpoint(schoolUUID, id string, req dto.WebhookEndpointRequestDTO, username string) error {
+	existing, err := service.fetchEndpoint(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := toWebhookEndpointEntity(req)
+	if err != nil {
+		return err
+	}
+
+	endpoint.UUID = existing.UUID
+	endpoint.SchoolUUID = existing.SchoolUUID
+	endpoint.UpdatedBy = toNullString(username)
+
+	return service.webhookRepository.UpdateEndpoint(endpoint)
+}
+
+// Deliveries still waiting for a retry are given up once the endpoint is gone
+func (service *WebhookService) DeleteEndpoint(schoolUUID, id, username string) error {
+	if _, err := service.fetchEndpoint(schoolUUID, id); err != nil {
+		return err
+	}
+
+	return service.webhookRepository.DeleteEndpoint(schoolUUID, id, username)
+}
+
+// Empty status lists every delivery, newest first
+func (service *WebhookService) GetDeliveries(schoolUUID, id string, page, limit int, status string) ([]dto.WebhookDeliveryResponseDTO, int, error) {
+	switch status {
+	case "", entity.WebhookDeliveryPending, entity.WebhookDeliverySucceeded, entity.WebhookDeliveryFailed:
+	default:
+		return nil, 0, errors.New("invalid status, use 'pending', 'succeeded' or 'failed'", 400)
+	}
+
+	if _, err := service.fetchEndpoint(schoolUUID, id); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+
+	deliveries, err := service.webhookRepository.FetchDeliveries(id, offset, limit, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := service.webhookRepository.CountDeliveries(id, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveriesDTO := []dto.WebhookDeliveryResponseDTO{}
+	for _, delivery := range deliveries {
+		deliveriesDTO = append(deliveriesDTO, toWebhookDeliveryDTO(delivery))
+	}
+
+	return deliveriesDTO, total, nil
+}
+
+// Sends the stored body of a finished delivery again with a fresh set of attempts. The event UUID
+// stays the same so the receiver can tell it apart from a new event.
+func (service *WebhookService) RedeliverDelivery(schoolUUID, id, deliveryID, username string) (err error) {
+	endpoint, err := service.fetchEndpoint(schoolUUID, id)
+	if err != nil {
+		return err
+	}
+	if !endpoint.IsActive {
+		return errors.New("the endpoint is inactive", 409)
+	}
+
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		return errors.New("delivery not found", 404)
+	}
+
+	if _, err := service.webhookRepository.FetchSpecDelivery(id, deliveryID); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("delivery not found", 404)
+		}
+		return err
+	}
+
+	tx, err := service.webhookRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	requeued, err := service.webhookRepository.RequeueDelivery(tx, deliveryID, username)
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		return errors.New("the delivery is still being retried", 409)
+	}
+
+	if err = service.enqueueDelivery(tx, deliveryID, time.Time{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Outbox subscriber, gives every active endpoint of the school that subscribed to the event its own
+// delivery. An event relayed twice finds the deliveries already there.
+func (service *WebhookService) QueueDeliveries(event entity.DomainEvent) (err error) {
+	if !event.SchoolUUID.Valid {
+		return nil
+	}
+
+	endpoints, err := service.webhookRepository.FetchSubscribedEndpoints(event.SchoolUUID.UUID.String(), event.Type)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	body, err := json.Marshal(webhookBody{
+		EventUUID:  event.UUID.String(),
+		EventType:  event.Type,
+		SchoolUUID: event.SchoolUUID.UUID.String(),
+		OccurredAt: event.OccurredAt.Format(time.RFC3339),
+		Data:       event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := service.webhookRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, endpoint := range endpoints {
+		deliveryUUID := uuid.New()
+		delivery := entity.WebhookDelivery{
+			ID:            time.Now().UnixMilli()*1e6 + int64(deliveryUUID.ID()%1e6),
+			UUID:          deliveryUUID,
+			EndpointUUID:  endpoint.UUID,
+			EventUUID:     event.UUID,
+			EventType:     event.Type,
+			Body:          body,
+			NextAttemptAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}
+
+		created, err := service.webhookRepository.SaveDelivery(tx, delivery)
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+
+		if err = service.enqueueDelivery(tx, deliveryUUID.String(), time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Runs the webhook_delivery job, one attempt of one delivery. A failed attempt queues the next one
+// after WEBHOOK_RETRY_BACKOFF, doubling up to WEBHOOK_RETRY_BACKOFF_MAX, until WEBHOOK_MAX_ATTEMPTS.
+func (service *WebhookService) SendDelivery(payload json.RawMessage) (err error) {
+	var job webhookDeliveryJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	delivery, endpoint, err := service.webhookRepository.FetchDeliveryTarget(job.DeliveryUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	// Already delivered or given up, e.g. a job left over from before a redelivery
+	if delivery.Status != entity.WebhookDeliveryPending {
+		return nil
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = sql.NullTime{}
+
+	if endpoint.DeletedAt.Valid || !endpoint.IsActive {
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.LastError = toNullString("the endpoint was deleted or deactivated")
+	} else {
+		status, responseBody, postErr := utils.PostWebhook(endpoint.URL, endpoint.Secret, map[string]string{
+			"X-Shuttle-Event":    delivery.EventType,
+			"X-Shuttle-Event-ID": delivery.EventUUID.String(),
+			"X-Shuttle-Delivery": delivery.UUID.String(),
+		}, delivery.Body)
+
+		delivery.ResponseStatus = sql.NullInt32{Int32: int32(status), Valid: status != 0}
+		delivery.ResponseBody = toNullString(responseBody)
+
+		switch {
+		case postErr != nil:
+			delivery.LastError = toNullString(postErr.Error())
+		case status < 200 || status > 299:
+			delivery.LastError = toNullString(fmt.Sprintf("the endpoint responded with status %d", status))
+		default:
+			delivery.Status = entity.WebhookDeliverySucceeded
+			delivery.LastError = sql.NullString{}
+			delivery.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+
+		if delivery.Status == entity.WebhookDeliveryPending && delivery.Attempts >= utils.ConfigInt("WEBHOOK_MAX_ATTEMPTS", 8) {
+			delivery.Status = entity.WebhookDeliveryFailed
+		}
+	}
+
+	tx, err := service.webhookRepository.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if delivery.Status == entity.WebhookDeliveryPending {
+		delay := retryDelay(delivery.Attempts, utils.ConfigDuration("WEBHOOK_RETRY_BACKOFF", time.Minute), utils.ConfigDuration("WEBHOOK_RETRY_BACKOFF_MAX", 6*time.Hour))
+		delivery.NextAttemptAt = sql.NullTime{Time: time.Now().Add(delay), Valid: true}
+
+		if err = service.enqueueDelivery(tx, delivery.UUID.String(), delivery.NextAttemptAt.Time); err != nil {
+			return err
+		}
+	}
+
+	if err = service.webhookRepository.UpdateDeliveryAttempt(tx, delivery); err != nil {
+		return err
+	}
+
+	if delivery.Status == entity.WebhookDeliveryFailed {
+		logger.LogWarn("Webhook delivery failed", map[string]interface{}{
+			"delivery_uuid": delivery.UUID.String(),
+			"endpoint_uuid": endpoint.UUID.String(),
+			"attempts":      delivery.Attempts,
+			"last_error":    delivery.LastError.String,
+		})
+	}
+
+	return tx.Commit()
+}
+
+func (service *WebhookService) enqueueDelivery(tx *sqlx.Tx, deliveryUUID string, runAt time.Time) error {
+	return service.jobService.Enqueue(tx, "webhook_delivery", webhookDeliveryJob{DeliveryUUID: deliveryUUID}, runAt, "system")
+}
+
+func (service *WebhookService) checkSchool(schoolUUID string) error {
+	if _, err := uuid.Parse(schoolUUID); err != nil {
+		return errors.New("school not found", 404)
+	}
+
+	exists, err := service.webhookRepository.CheckSchoolExists(schoolUUID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("school not found", 404)
+	}
+
+	return nil
+}
+
+func (service *WebhookService) fetchEndpoint(schoolUUID, id string) (entity.WebhookEndpoint, error) {
+	if err := service.checkSchool(schoolUUID); err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return entity.WebhookEndpoint{}, errors.New("webhook endpoint not found", 404)
+	}
+
+	endpoint, err := service.webhookRepository.FetchSpecEndpoint(schoolUUID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.WebhookEndpoint{}, errors.New("webhook endpoint not found", 404)
+		}
+		return entity.WebhookEndpoint{}, err
+	}
+
+	return endpoint, nil
+}
+
+// Endpoints must use HTTPS, plain HTTP is only accepted with WEBHOOK_ALLOW_HTTP for local testing
+func toWebhookEndpointEntity(req dto.WebhookEndpointRequestDTO) (entity.WebhookEndpoint, error) {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || parsed.Host == "" {
+		return entity.WebhookEndpoint{}, errors.New("endpoint_url is not a valid URL", 400)
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && viper.GetBool("WEBHOOK_ALLOW_HTTP")) {
+		return entity.WebhookEndpoint{}, errors.New("endpoint_url must use https", 400)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := utils.CheckWebhookHost(ctx, parsed.Hostname()); err != nil {
+		if err == utils.ErrWebhookHostNotAllowed {
+			return entity.WebhookEndpoint{}, errors.New("endpoint_url must not point to a private or local address", 400)
+		}
+		return entity.WebhookEndpoint{}, errors.New("endpoint_url host could not be resolved", 400)
+	}
+
+	// Duplicates are dropped, the order of webhookEventTypes is kept
+	requested := make(map[string]bool, len(req.EventTypes))
+	for _, eventType := range req.EventTypes {
+		requested[eventType] = true
+	}
+	eventTypes := []string{}
+	for _, eventType := range webhookEventTypes {
+		if requested[eventType] {
+			eventTypes = append(eventTypes, eventType)
+			delete(requested, eventType)
+		}
+	}
+	if len(requested) > 0 {
+		unknown := make([]string, 0, len(requested))
+		for eventType := range requested {
+			unknown = append(unknown, eventType)
+		}
+		sort.Strings(unknown)
+		return entity.WebhookEndpoint{}, errors.New("unknown event_types: "+strings.Join(unknown, ", "), 400)
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	return entity.WebhookEndpoint{
+		URL:         req.URL,
+		Description: toNullString(req.Description),
+		EventTypes:  eventTypes,
+		IsActive:    isActive,
+	}, nil
+}
+
+func toWebhookEndpointDTO(endpoint entity.WebhookEndpoint) dto.WebhookEndpointResponseDTO {
+	return dto.WebhookEndpointResponseDTO{
+		UUID:        endpoint.UUID.String(),
+		URL:         endpoint.URL,
+		Description: endpoint.Description.String,
+		EventTypes:  endpoint.EventTypes,
+		IsActive:    endpoint.IsActive,
+		CreatedAt:   safeTimeFormat(endpoint.CreatedAt),
+		CreatedBy:   safeStringFormat(endpoint.CreatedBy),
+		UpdatedAt:   safeTimeFormat(endpoint.UpdatedAt),
+		UpdatedBy:   safeStringFormat(endpoint.UpdatedBy),
+	}
+}
+
+func toWebhookDeliveryDTO(delivery entity.WebhookDelivery) dto.WebhookDeliveryResponseDTO {
+	deliveryDTO := dto.WebhookDeliveryResponseDTO{
+		UUID:           delivery.UUID.String(),
+		EventUUID:      delivery.EventUUID.String(),
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: int(delivery.ResponseStatus.Int32),
+		ResponseBody:   delivery.ResponseBody.String,
+		LastError:      delivery.LastError.String,
+		CreatedAt:      safeTimeFormat(delivery.CreatedAt),
+		RedeliveredBy:  delivery.RedeliveredBy.String,
+	}
+	if delivery.NextAttemptAt.Valid {
+		deliveryDTO.NextAttemptAt = delivery.NextAttemptAt.Time.Format(time.RFC3339)
+	}
+	if delivery.DeliveredAt.Valid {
+		deliveryDTO.DeliveredAt = delivery.DeliveredAt.Time.Format(time.RFC3339)
+	}
+	if delivery.RedeliveredAt.Valid {
+		deliveryDTO.RedeliveredAt = delivery.RedeliveredAt.Time.Format(time.RFC3339)
+	}
+
+	return deliveryDTO
+}
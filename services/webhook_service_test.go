@@ -0,0 +1,61 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"shuttle/errors"
+	"shuttle/models/dto"
+)
+
+func TestToWebhookEndpointEntity(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		eventTypes     []string
+		wantEventTypes []string
+		wantMessage    string
+	}{
+		{
+			name:           "public address",
+			url:            "https://93.184.216.34/hooks/shuttle",
+			eventTypes:     []string{"trip.completed", "shuttle.status_changed", "trip.completed"},
+			wantEventTypes: []string{"shuttle.status_changed", "trip.completed"},
+		},
+		{name: "plain http", url: "http://93.184.216.34/hook", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url must use https"},
+		{name: "no host", url: "https:///hook", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url is not a valid URL"},
+		{name: "loopback", url: "https://127.0.0.1:8443/hook", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url must not point to a private or local address"},
+		{name: "cloud metadata", url: "https://169.254.169.254/latest", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url must not point to a private or local address"},
+		{name: "private network", url: "https://10.0.0.5/hook", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url must not point to a private or local address"},
+		{name: "ipv6 loopback", url: "https://[::1]/hook", eventTypes: []string{"trip.completed"}, wantMessage: "endpoint_url must not point to a private or local address"},
+		{
+			name:        "unknown event types",
+			url:         "https://93.184.216.34/hook",
+			eventTypes:  []string{"trip.completed", "user.deleted", "bus.moved"},
+			wantMessage: "unknown event_types: bus.moved, user.deleted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := toWebhookEndpointEntity(dto.WebhookEndpointRequestDTO{URL: tt.url, EventTypes: tt.eventTypes})
+			if tt.wantMessage != "" {
+				customErr, ok := err.(*errors.CustomError)
+				if !ok || customErr.StatusCode != 400 || customErr.Message != tt.wantMessage {
+					t.Fatalf("toWebhookEndpointEntity() error = %v, want 400 %q", err, tt.wantMessage)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("toWebhookEndpointEntity() error = %v", err)
+			}
+			if !reflect.DeepEqual([]string(endpoint.EventTypes), tt.wantEventTypes) {
+				t.Errorf("event types = %v, want %v", endpoint.EventTypes, tt.wantEventTypes)
+			}
+			if !endpoint.IsActive {
+				t.Error("a new endpoint should be active")
+			}
+		})
+	}
+}
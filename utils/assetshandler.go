@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"shuttle/errors"
 	"shuttle/logger"
+	"strings"
 
 	"github.com/disintegration/imaging"
 	"github.com/gofiber/fiber/v2"
@@ -17,6 +19,9 @@ import (
 
 const MaxFileSize = 10 * 1024 * 1024 // 10 MB
 
+// Documents hold personal data, they are kept out of ./assets which is served to anyone
+const DocumentsFolder = "./storage/documents"
+
 func HandleUploadedFile(c *fiber.Ctx) (string, error) {
 	file, err := c.FormFile("picture")
 	if err != nil {
@@ -71,6 +76,81 @@ func HandleAssetsOnUpdate(c *fiber.Ctx, existingPicture string) (string, error)
     return pictureFileName, nil
 }
 
+// Checks and stores a scanned document sent in the given form field, images and PDFs are accepted.
+// Validation failures are returned as *errors.CustomError so handlers can pass them on as they are.
+func HandleUploadedDocument(c *fiber.Ctx, field string) (string, error) {
+	file, err := c.FormFile(field)
+	if err != nil {
+		return "", errors.New("the "+field+" field is required", 400)
+	}
+
+	if !IsValidDocumentExtension(file.Filename) {
+		return "", errors.New("invalid document file extension, use .jpg, .jpeg, .png or .pdf", 400)
+	}
+
+	if !IsValidFileSize(file.Size) {
+		return "", errors.New("the document must be at most 10 MB", 400)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	fileBytes, err := io.ReadAll(src)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.ToLower(filepath.Ext(file.Filename)) == ".pdf" {
+		if !bytes.HasPrefix(fileBytes, []byte("%PDF-")) {
+			return "", errors.New("invalid document file type", 400)
+		}
+	} else if !IsValidImageType(fileBytes) {
+		return "", errors.New("invalid document file type", 400)
+	}
+
+	return SaveDocument(fileBytes, file.Filename)
+}
+
+func IsValidDocumentExtension(fileName string) bool {
+	return contains([]string{".jpg", ".jpeg", ".png", ".pdf"}, strings.ToLower(filepath.Ext(fileName)))
+}
+
+func SaveDocument(fileBytes []byte, fileName string) (string, error) {
+	err := os.MkdirAll(DocumentsFolder, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	sanitizedFileName := fmt.Sprintf("%s%s", uuid.New().String(), strings.ToLower(filepath.Ext(fileName)))
+
+	err = os.WriteFile(filepath.Join(DocumentsFolder, sanitizedFileName), fileBytes, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return sanitizedFileName, nil
+}
+
+func DeleteDocument(fileName string) error {
+	if fileName == "" {
+		return nil
+	}
+
+	err := os.Remove(DocumentPath(fileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func DocumentPath(fileName string) string {
+	return filepath.Join(DocumentsFolder, SanitizeFileName(fileName))
+}
+
 func IsValidImageExtension(fileName string) bool {
 	validExtensions := []string{".jpg", ".jpeg", ".png"}
 	ext := filepath.Ext(fileName)
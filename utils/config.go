@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Reads an integer setting, falling back when the key is unset or not positive
+func ConfigInt(key string, fallback int) int {
+	if value := viper.GetInt(key); value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// Reads a duration setting (e.g. "15m", "30s"), falling back when the key is unset or not positive
+func ConfigDuration(key string, fallback time.Duration) time.Duration {
+	if value := viper.GetDuration(key); value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,348 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"shuttle/logger"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+	ExportPDF  = "pdf"
+)
+
+func IsValidExportFormat(format string) bool {
+	return format == ExportCSV || format == ExportXLSX || format == ExportPDF
+}
+
+// Rows fetched from the list per round trip while an export is written
+func ExportPageSize() int {
+	return ConfigInt("EXPORT_PAGE_SIZE", 1000)
+}
+
+// Largest list exported as a PDF. The PDF is built in memory before it is sent, CSV and XLSX files
+// are written while the rows are read and have no limit.
+func ExportPDFLimit() int {
+	return ConfigInt("EXPORT_PDF_MAX_ROWS", 5000)
+}
+
+// Fetches one page of the list to export and the number of rows matching the request
+type ExportPageFunc func(page, limit int) (interface{}, int, error)
+
+// Writes a list of response DTOs as a downloadable file, reading it a page at a time through fetch
+// so the full result set is never held at once. Columns follow the json tags of the DTO, nested
+// objects become "parent.child" columns and lists are written as JSON. The columns are taken from
+// the first page since the header has to be written before the other pages are read.
+func ExportResponse(c *fiber.Ctx, format, name string, fetch ExportPageFunc) error {
+	if !IsValidExportFormat(format) {
+		return BadRequestResponse(c, "Invalid format, use 'csv', 'xlsx' or 'pdf'", nil)
+	}
+
+	pageSize := ExportPageSize()
+	first, totalItems, err := fetch(1, pageSize)
+	if err != nil {
+		logger.LogError(err, "Failed to fetch export rows", map[string]interface{}{"export": name})
+		return InternalServerErrorResponse(c, "Something went wrong, please try again later", nil)
+	}
+
+	if limit := ExportPDFLimit(); format == ExportPDF && totalItems > limit {
+		return BadRequestResponse(c, fmt.Sprintf("The export has %d rows, more than the PDF limit of %d, narrow the filters or use csv or xlsx", totalItems, limit), nil)
+	}
+
+	header, records := flattenExportRecords(first)
+	rows := exportRows(header, records, fetch, pageSize)
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), format)
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// The status is already sent once a stream writer runs, a failure can only cut the file short
+	logStreamError := func(err error) {
+		if err != nil {
+			logger.LogError(err, "Failed to write export", map[string]interface{}{"export": name, "format": format})
+		}
+	}
+
+	switch format {
+	case ExportCSV:
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			logStreamError(writeExportCSV(w, header, rows))
+		})
+	case ExportXLSX:
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			logStreamError(writeExportWorkbook(w, header, rows))
+		})
+	case ExportPDF:
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		return writeExportPDF(c.Response().BodyWriter(), name, header, rows)
+	}
+
+	return nil
+}
+
+// Calls write for every row of the export, starting with the records of the first page and then
+// fetching the next pages until one comes back short
+type exportRowIterator func(write func(row []string) error) error
+
+func exportRows(header []string, first []map[string]string, fetch ExportPageFunc, pageSize int) exportRowIterator {
+	return func(write func(row []string) error) error {
+		records := first
+		for page := 1; ; page++ {
+			if page > 1 {
+				data, _, err := fetch(page, pageSize)
+				if err != nil {
+					return err
+				}
+				_, records = flattenExportRecords(data)
+			}
+
+			for _, record := range records {
+				if err := write(exportRow(header, record)); err != nil {
+					return err
+				}
+			}
+
+			if len(records) < pageSize {
+				return nil
+			}
+		}
+	}
+}
+
+func writeExportCSV(w io.Writer, header []string, rows exportRowIterator) error {
+	writer := csv.NewWriter(w)
+	writer.Write(escapeCells(header))
+	err := rows(func(row []string) error {
+		return writer.Write(escapeCells(row))
+	})
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// The excelize stream writer keeps large sheets in a temporary file rather than in memory
+func writeExportWorkbook(w io.Writer, header []string, rows exportRowIterator) error {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	sheet := workbook.GetSheetName(0)
+	stream, err := workbook.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	if err := stream.SetRow("A1", toCells(header)); err != nil {
+		return err
+	}
+
+	line := 1
+	err = rows(func(row []string) error {
+		line++
+		cell, _ := excelize.CoordinatesToCellName(1, line)
+		return stream.SetRow(cell, toCells(row))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+
+	return workbook.Write(w)
+}
+
+func writeExportPDF(w io.Writer, name string, header []string, rows exportRowIterator) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 10)
+	pdf.AddPage()
+
+	if len(header) == 0 {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 8, "No data", "", 1, "L", false, 0, "")
+		return pdf.Output(w)
+	}
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := (pageWidth - left - right) / float64(len(header))
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, translate(strings.ToUpper(name[:1])+name[1:]), "", 1, "L", false, 0, "")
+
+	writeRow := func(row []string, style string) {
+		pdf.SetFont("Helvetica", style, 7)
+		for _, value := range row {
+			pdf.CellFormat(width, 6, fitText(pdf, translate(value), width-1), "1", 0, "L", style == "B", 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFillColor(230, 230, 230)
+	writeRow(header, "B")
+	err := rows(func(row []string) error {
+		if pdf.GetY() > 190 {
+			pdf.AddPage()
+			writeRow(header, "B")
+		}
+		writeRow(row, "")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
+
+// Cuts the text so it fits in a table cell of the given width
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func toCells(values []string) []interface{} {
+	cells := make([]interface{}, len(values))
+	for i, value := range escapeCells(values) {
+		cells[i] = value
+	}
+	return cells
+}
+
+func escapeCells(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeCell(value)
+	}
+	return escaped
+}
+
+// Spreadsheet apps run a cell starting with =, +, -, @, a tab or a carriage return as a formula,
+// such cells get a leading ' so they are shown as text. Plain numbers like -5 are kept as they are.
+func escapeCell(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + value
+}
+
+// Turns a slice of structs into records keyed by column and the union of every column seen, in the
+// order it was first seen, since interface fields like user details differ per row
+func flattenExportRecords(data interface{}) ([]string, []map[string]string) {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	var header []string
+	seen := make(map[string]bool)
+	var records []map[string]string
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			record := make(map[string]string)
+			var order []string
+			flattenExportValue("", value.Index(i), record, &order)
+
+			for _, column := range order {
+				if !seen[column] {
+					seen[column] = true
+					header = append(header, column)
+				}
+			}
+			records = append(records, record)
+		}
+	}
+
+	return header, records
+}
+
+// Lines a record up with the header, columns the header does not have are left out
+func exportRow(header []string, record map[string]string) []string {
+	row := make([]string, len(header))
+	for i, column := range header {
+		row[i] = record[column]
+	}
+	return row
+}
+
+func flattenExportValue(prefix string, value reflect.Value, record map[string]string, order *[]string) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	set := func(column, text string) {
+		if _, ok := record[column]; !ok {
+			*order = append(*order, column)
+		}
+		record[column] = text
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+
+			flattenExportValue(name, value.Field(i), record, order)
+		}
+	case reflect.Map, reflect.Slice, reflect.Array:
+		if value.Len() == 0 {
+			set(prefix, "")
+			return
+		}
+		encoded, err := json.Marshal(value.Interface())
+		if err != nil {
+			set(prefix, "")
+			return
+		}
+		set(prefix, string(encoded))
+	default:
+		set(prefix, fmt.Sprint(value.Interface()))
+	}
+}
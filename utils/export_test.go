@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+)
+
+type exportTestDetails struct {
+	Phone string `json:"phone"`
+}
+
+type exportTestRow struct {
+	Name    string      `json:"name"`
+	Secret  string      `json:"-"`
+	Tags    []string    `json:"tags"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func TestEscapeCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "Budi", want: "Budi"},
+		{value: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{value: "+62 812", want: "'+62 812"},
+		{value: "-1+2", want: "'-1+2"},
+		{value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{value: "\tcmd", want: "'\tcmd"},
+		{value: "\rcmd", want: "'\rcmd"},
+		{value: "-5", want: "-5"},
+		{value: "+1.5", want: "+1.5"},
+		{value: "a=b", want: "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := escapeCell(tt.value); got != tt.want {
+				t.Errorf("escapeCell(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenExportRecords(t *testing.T) {
+	data := []exportTestRow{
+		{Name: "first", Secret: "hidden"},
+		{Name: "second", Tags: []string{"a", "b"}, Details: exportTestDetails{Phone: "0812"}},
+	}
+
+	header, records := flattenExportRecords(data)
+
+	wantHeader := []string{"name", "tags", "details.phone"}
+	wantRows := [][]string{
+		{"first", "", ""},
+		{"second", `["a","b"]`, "0812"},
+	}
+
+	if !reflect.DeepEqual(header, wantHeader) {
+		t.Errorf("header = %v, want %v", header, wantHeader)
+	}
+
+	var rows [][]string
+	for _, record := range records {
+		rows = append(rows, exportRow(header, record))
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows = %v, want %v", rows, wantRows)
+	}
+}
+
+// Serves data in pages of the requested size and records which pages were asked for
+func exportTestPages(data []exportTestRow, fetched *[]int) ExportPageFunc {
+	return func(page, limit int) (interface{}, int, error) {
+		*fetched = append(*fetched, page)
+		start := (page - 1) * limit
+		if start > len(data) {
+			start = len(data)
+		}
+		end := start + limit
+		if end > len(data) {
+			end = len(data)
+		}
+		return data[start:end], len(data), nil
+	}
+}
+
+func TestExportResponse(t *testing.T) {
+	viper.Set("EXPORT_PAGE_SIZE", 2)
+	viper.Set("EXPORT_PDF_MAX_ROWS", 3)
+	defer viper.Set("EXPORT_PAGE_SIZE", nil)
+	defer viper.Set("EXPORT_PDF_MAX_ROWS", nil)
+
+	data := []exportTestRow{{Name: "=1+1"}, {Name: "Siti"}, {Name: "Budi"}}
+	wantRows := [][]string{{"name", "tags"}, {"'=1+1", ""}, {"Siti", ""}, {"Budi", ""}}
+
+	tests := []struct {
+		name        string
+		format      string
+		data        []exportTestRow
+		wantStatus  int
+		wantFetched []int
+		read        func(t *testing.T, body []byte) [][]string
+	}{
+		{name: "csv", format: ExportCSV, data: data, wantStatus: 200, wantFetched: []int{1, 2}, read: readExportCSV},
+		{name: "xlsx", format: ExportXLSX, data: data, wantStatus: 200, wantFetched: []int{1, 2}, read: readExportXLSX},
+		{name: "pdf", format: ExportPDF, data: data, wantStatus: 200, wantFetched: []int{1, 2}},
+		{name: "last page is full", format: ExportCSV, data: data[:2], wantStatus: 200, wantFetched: []int{1, 2}},
+		{name: "pdf over the limit", format: ExportPDF, data: append(data, exportTestRow{Name: "Ani"}), wantStatus: 400, wantFetched: []int{1}},
+		{name: "csv has no limit", format: ExportCSV, data: append(data, exportTestRow{Name: "Ani"}), wantStatus: 200, wantFetched: []int{1, 2, 3}},
+		{name: "unknown format", format: "doc", data: data, wantStatus: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched []int
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return ExportResponse(c, tt.format, "students", exportTestPages(tt.data, &fetched))
+			})
+
+			res, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fetched, tt.wantFetched) {
+				t.Errorf("pages fetched = %v, want %v", fetched, tt.wantFetched)
+			}
+			if tt.read == nil {
+				return
+			}
+			if got := tt.read(t, body); !reflect.DeepEqual(got, wantRows) {
+				t.Errorf("rows = %q, want %q", got, wantRows)
+			}
+		})
+	}
+}
+
+func readExportCSV(t *testing.T, body []byte) [][]string {
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func readExportXLSX(t *testing.T, body []byte) [][]string {
+	workbook, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer workbook.Close()
+
+	rows, err := workbook.GetRows(workbook.GetSheetName(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trailing empty cells are not returned
+	for i := range rows {
+		for len(rows[i]) < 2 {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	return rows
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+)
+
+// signingKey is one entry of the key ring, private is nil for keys that are
+// only kept around to verify tokens issued before a rotation
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+type keyRing struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	order  []string
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var tokenKeys *keyRing
+
+// Loads JWT_SIGNING_KEYS ("kid=private.pem,...") and JWT_VERIFICATION_KEYS ("kid=public.pem,...").
+// Returns nil when no signing key is configured, tokens then keep using JWT_SECRET.
+func loadKeyRing() (*keyRing, error) {
+	signing := parseKeyList(viper.GetString("JWT_SIGNING_KEYS"))
+	if len(signing) == 0 {
+		return nil, nil
+	}
+
+	ring := &keyRing{keys: make(map[string]*signingKey)}
+
+	for _, entry := range signing {
+		key, err := loadPrivateKey(entry[0], entry[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := ring.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range parseKeyList(viper.GetString("JWT_VERIFICATION_KEYS")) {
+		key, err := loadPublicKey(entry[0], entry[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := ring.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	activeKID := viper.GetString("JWT_ACTIVE_KID")
+	if activeKID == "" {
+		activeKID = signing[0][0]
+	}
+
+	active, ok := ring.keys[activeKID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID %q is not one of JWT_SIGNING_KEYS", activeKID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+func (ring *keyRing) add(key *signingKey) error {
+	if _, exists := ring.keys[key.kid]; exists {
+		return fmt.Errorf("duplicate JWT key id %q", key.kid)
+	}
+	ring.keys[key.kid] = key
+	ring.order = append(ring.order, key.kid)
+	return nil
+}
+
+func (ring *keyRing) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(ring.active.method, claims)
+	token.Header["kid"] = ring.active.kid
+	return token.SignedString(ring.active.private)
+}
+
+func (ring *keyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// Returns the public half of every key in the ring, the format other services expect
+// at /.well-known/jwks.json
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if tokenKeys == nil {
+		return set
+	}
+
+	for _, kid := range tokenKeys.order {
+		key := tokenKeys.keys[kid]
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func parseKeyList(value string) [][2]string {
+	var entries [][2]string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(item, "=")
+		if !found {
+			kid, path = "", item
+		}
+		entries = append(entries, [2]string{strings.TrimSpace(kid), strings.TrimSpace(path)})
+	}
+	return entries
+}
+
+func readPEM(kid, path string) (*pem.Block, error) {
+	if kid == "" {
+		return nil, fmt.Errorf("JWT key %s has no key id, use kid=path", path)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %q is not PEM encoded", kid)
+	}
+	return block, nil
+}
+
+func loadPrivateKey(kid, path string) (*signingKey, error) {
+	block, err := readPEM(kid, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JWT key %q: %w", kid, err)
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}, nil
+	}
+
+	return nil, fmt.Errorf("JWT key %q must be an RSA or Ed25519 private key", kid)
+}
+
+func loadPublicKey(kid, path string) (*signingKey, error) {
+	block, err := readPEM(kid, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if block.Type == "RSA PUBLIC KEY" {
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JWT key %q: %w", kid, err)
+	}
+
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, public: public}, nil
+	case ed25519.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, public: public}, nil
+	}
+
+	return nil, fmt.Errorf("JWT key %q must be an RSA or Ed25519 public key", kid)
+}
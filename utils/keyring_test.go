@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+)
+
+// Writes an RSA key pair and an Ed25519 key pair and returns the paths of their PEM files
+func writeTestKeys(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPrivate, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := map[string]*pem.Block{
+		"rsa.pem":     {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"rsa.pub.pem": {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+		"ed.pem":      {Type: "PRIVATE KEY", Bytes: edPrivate},
+		"ed.pub.pem":  {Type: "PUBLIC KEY", Bytes: edPublic},
+		"broken.pem":  nil,
+	}
+
+	paths := make(map[string]string, len(blocks))
+	for name, block := range blocks {
+		path := filepath.Join(dir, name)
+		content := []byte("not a key")
+		if block != nil {
+			content = pem.EncodeToMemory(block)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = path
+	}
+
+	return paths
+}
+
+func setKeyRingConfig(signing, verification, activeKID string) {
+	viper.Set("JWT_SIGNING_KEYS", signing)
+	viper.Set("JWT_VERIFICATION_KEYS", verification)
+	viper.Set("JWT_ACTIVE_KID", activeKID)
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	keys := writeTestKeys(t)
+	defer setKeyRingConfig("", "", "")
+
+	tests := []struct {
+		name         string
+		signing      string
+		verification string
+		activeKID    string
+		wantActive   string
+		wantAlg      string
+		wantKIDs     []string
+		wantErr      bool
+	}{
+		{name: "no signing keys"},
+		{
+			name:       "first signing key is active",
+			signing:    "2024-07=" + keys["rsa.pem"] + ", 2025-01=" + keys["ed.pem"],
+			wantActive: "2024-07",
+			wantAlg:    "RS256",
+			wantKIDs:   []string{"2024-07", "2025-01"},
+		},
+		{
+			name:       "JWT_ACTIVE_KID picks the signing key",
+			signing:    "2024-07=" + keys["rsa.pem"] + ",2025-01=" + keys["ed.pem"],
+			activeKID:  "2025-01",
+			wantActive: "2025-01",
+			wantAlg:    "EdDSA",
+			wantKIDs:   []string{"2024-07", "2025-01"},
+		},
+		{
+			name:         "verification keys are added after the signing keys",
+			signing:      "2025-01=" + keys["ed.pem"],
+			verification: "2024-07=" + keys["rsa.pub.pem"],
+			wantActive:   "2025-01",
+			wantAlg:      "EdDSA",
+			wantKIDs:     []string{"2025-01", "2024-07"},
+		},
+		{
+			name:         "active key without a private half",
+			signing:      "2025-01=" + keys["ed.pem"],
+			verification: "2024-07=" + keys["rsa.pub.pem"],
+			activeKID:    "2024-07",
+			wantErr:      true,
+		},
+		{name: "unknown active key", signing: "2025-01=" + keys["ed.pem"], activeKID: "2030-01", wantErr: true},
+		{name: "duplicate key id", signing: "a=" + keys["rsa.pem"] + ",a=" + keys["ed.pem"], wantErr: true},
+		{name: "missing key id", signing: keys["rsa.pem"], wantErr: true},
+		{name: "not PEM encoded", signing: "a=" + keys["broken.pem"], wantErr: true},
+		{name: "public key as signing key", signing: "a=" + keys["ed.pub.pem"], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setKeyRingConfig(tt.signing, tt.verification, tt.activeKID)
+
+			ring, err := loadKeyRing()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadKeyRing() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadKeyRing() error = %v", err)
+			}
+
+			if tt.wantActive == "" {
+				if ring != nil {
+					t.Fatal("loadKeyRing() returned a ring without signing keys")
+				}
+				return
+			}
+
+			if ring.active.kid != tt.wantActive || ring.active.method.Alg() != tt.wantAlg {
+				t.Errorf("active key = %s (%s), want %s (%s)", ring.active.kid, ring.active.method.Alg(), tt.wantActive, tt.wantAlg)
+			}
+			if len(ring.order) != len(tt.wantKIDs) {
+				t.Fatalf("key ids = %v, want %v", ring.order, tt.wantKIDs)
+			}
+			for i, kid := range tt.wantKIDs {
+				if ring.order[i] != kid {
+					t.Errorf("key ids = %v, want %v", ring.order, tt.wantKIDs)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestKeyRingKeyFunc(t *testing.T) {
+	keys := writeTestKeys(t)
+	defer setKeyRingConfig("", "", "")
+
+	// The RSA key was rotated out and is only kept to verify older tokens
+	setKeyRingConfig("2024-07="+keys["rsa.pem"], "", "")
+	oldRing, err := loadKeyRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldRing.sign(jwt.MapClaims{"sub": "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setKeyRingConfig("2025-01="+keys["ed.pem"], "2024-07="+keys["rsa.pub.pem"], "")
+	ring, err := loadKeyRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := ring.sign(jwt.MapClaims{"sub": "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownKID := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"})
+	unknownKID.Header["kid"] = "2030-01"
+	unknownToken, _ := unknownKID.SignedString([]byte("secret"))
+
+	wrongAlg := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"})
+	wrongAlg.Header["kid"] = "2025-01"
+	wrongAlgToken, _ := wrongAlg.SignedString([]byte("secret"))
+
+	noKID, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"}).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantKID string
+		wantErr bool
+	}{
+		{name: "token of the active key", token: newToken, wantKID: "2025-01"},
+		{name: "token of a rotated key", token: oldToken, wantKID: "2024-07"},
+		{name: "unknown key id", token: unknownToken, wantErr: true},
+		{name: "algorithm does not match the key", token: wrongAlgToken, wantErr: true},
+		{name: "no key id", token: noKID, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.Parse(tt.token, ring.keyFunc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("jwt.Parse() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("jwt.Parse() error = %v", err)
+			}
+			if kid := token.Header["kid"]; kid != tt.wantKID {
+				t.Errorf("kid = %v, want %s", kid, tt.wantKID)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"shuttle/logger"
+
+	"github.com/spf13/viper"
+)
+
+// Delivers a notification to one device, e.g. through FCM or APNs
+type PushSender interface {
+	Send(deviceToken, title, body string, data map[string]string) error
+}
+
+// LogPushSender only writes the notification to the log, meant for development
+// where no push provider is configured
+type LogPushSender struct{}
+
+func NewPushSender() PushSender {
+	switch viper.GetString("PUSH_PROVIDER") {
+	default:
+		return LogPushSender{}
+	}
+}
+
+func (sender LogPushSender) Send(deviceToken, title, body string, data map[string]string) error {
+	logger.LogInfo("Push notification sent", map[string]interface{}{
+		"device_token": deviceToken,
+		"title":        title,
+		"body":         body,
+		"data":         data,
+	})
+	return nil
+}
@@ -71,6 +71,16 @@ func NotFoundResponse(c *fiber.Ctx, message string, data interface{}) error {
     })
 }
 
+// Too Many Requests Response (Code 429)
+func TooManyRequestsResponse(c *fiber.Ctx, message string, data interface{}) error {
+    return c.Status(fiber.StatusTooManyRequests).JSON(Response{
+        Code:    fiber.StatusTooManyRequests,
+        Message: message,
+        Status:  false,
+        Data:    data,
+    })
+}
+
 // Internal Server Error Response (Code 500)
 func InternalServerErrorResponse(c *fiber.Ctx, message string, data interface{}) error {
     return c.Status(fiber.StatusInternalServerError).JSON(Response{
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Tells when a recurring job runs next
+type Schedule interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Runs every interval, counted from the previous run
+func Every(interval time.Duration) Schedule {
+	return everySchedule{interval: interval}
+}
+
+func (schedule everySchedule) Next(after time.Time) time.Time {
+	return after.Add(schedule.interval)
+}
+
+func (schedule everySchedule) String() string {
+	return "@every " + schedule.interval.String()
+}
+
+// Minute, hour, day of month, month and day of week as bit sets
+type cronSchedule struct {
+	spec       string
+	minute     uint64
+	hour       uint64
+	day        uint64
+	month      uint64
+	weekday    uint64
+	anyDay     bool
+	anyWeekday bool
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Parses a five field cron spec ("30 18 * * 1-5"), one of @hourly, @daily, @weekly, @monthly
+// or "@every 15m". Times are read in the server's local timezone.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid interval in schedule %q", spec)
+		}
+		return Every(duration), nil
+	}
+
+	fields := strings.Fields(spec)
+	if expanded, ok := cronShortcuts[spec]; ok {
+		fields = strings.Fields(expanded)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields", spec)
+	}
+
+	schedule := cronSchedule{spec: spec}
+	bounds := []struct {
+		target   *uint64
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.day, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.weekday, 0, 7},
+	}
+
+	for i, field := range fields {
+		bits, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		*bounds[i].target = bits
+	}
+
+	// 7 is another way to write Sunday
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+
+	return schedule, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			value, err := strconv.Atoi(stepPart)
+			if err != nil || value < 1 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = value
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			value, err := strconv.Atoi(from)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			start, end = value, value
+			if hasStep {
+				end = max
+			}
+
+			if isRange {
+				value, err := strconv.Atoi(to)
+				if err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// The first whole minute after the given time that matches every field. When both the day of
+// month and the day of week are restricted either one matching is enough, as in cron.
+func (schedule cronSchedule) Next(after time.Time) time.Time {
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if schedule.month&(1<<int(next.Month())) == 0 {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !schedule.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if schedule.hour&(1<<next.Hour()) == 0 {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if schedule.minute&(1<<next.Minute()) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	// Only reachable with a day that never exists, e.g. February 30th
+	return limit
+}
+
+func (schedule cronSchedule) matchesDay(at time.Time) bool {
+	dayMatches := schedule.day&(1<<at.Day()) != 0
+	weekdayMatches := schedule.weekday&(1<<int(at.Weekday())) != 0
+
+	if schedule.anyDay || schedule.anyWeekday {
+		return dayMatches && weekdayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+func (schedule cronSchedule) String() string {
+	return schedule.spec
+}
+
+// Reads a schedule setting, falling back when the key is unset. An invalid value stops the app
+// at startup rather than silently never running the job.
+func ConfigSchedule(key, fallback string) Schedule {
+	spec := viper.GetString(key)
+	if spec == "" {
+		spec = fallback
+	}
+
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", key, err))
+	}
+	return schedule
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"shuttle/logger"
+
+	"github.com/spf13/viper"
+)
+
+type SMSSender interface {
+	Send(phone, message string) error
+}
+
+// LogSMSSender only writes the message to the log, meant for development
+// where no SMS gateway is configured
+type LogSMSSender struct{}
+
+func NewSMSSender() SMSSender {
+	switch viper.GetString("SMS_PROVIDER") {
+	default:
+		return LogSMSSender{}
+	}
+}
+
+func (sender LogSMSSender) Send(phone, message string) error {
+	logger.LogInfo("SMS sent", map[string]interface{}{
+		"phone":   phone,
+		"message": message,
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Reads the first sheet of an uploaded .csv or .xlsx file, the header row included.
+// Cells are trimmed and every row is padded to the width of the header, empty rows are kept
+// so callers can report the line number the user sees in the file.
+func ReadSpreadsheet(file *multipart.FileHeader) ([][]string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var rows [][]string
+
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(src)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		rows, err = reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv file: %w", err)
+		}
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx file: %w", err)
+		}
+		defer workbook.Close()
+
+		rows, err = workbook.GetRows(workbook.GetSheetName(0))
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file type, use .csv or .xlsx")
+	}
+
+	return normalizeRows(rows), nil
+}
+
+func normalizeRows(rows [][]string) [][]string {
+	if len(rows) == 0 {
+		return rows
+	}
+
+	width := len(rows[0])
+	for i, row := range rows {
+		for j := range row {
+			row[j] = strings.TrimSpace(row[j])
+		}
+		for len(row) < width {
+			row = append(row, "")
+		}
+		rows[i] = row
+	}
+
+	return rows
+}
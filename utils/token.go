@@ -7,6 +7,8 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
+	"io/fs"
+	"strings"
 	"time"
 
 	"shuttle/databases"
@@ -16,25 +18,23 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 )
 
 var jwtSecret []byte
 var encryptionKey []byte
-var db *sqlx.DB
 
 func init() {
 	viper.SetConfigFile(".env")
 	err := viper.ReadInConfig()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		panic(err)
 	}
 
 	jwtSecret = []byte(viper.GetString("JWT_SECRET"))
 	encryptionKey = []byte(viper.GetString("ENCRYPTION_KEY"))
 
-	db, err = databases.PostgresConnection()
+	tokenKeys, err = loadKeyRing()
 	if err != nil {
 		panic(err)
 	}
@@ -42,49 +42,61 @@ func init() {
 
 // Signed Access Token
 func GenerateToken(userID, userUUID, username, role_code string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	return signClaims(jwt.MapClaims{
 		"sub":       userID,
 		"user_uuid": userUUID,
 		"user_name": username,
 		"role_code": role_code,
 		"exp":       time.Now().Add(time.Hour * 6).Unix(), // 2 hours expiration
 	})
-
-	signedToken, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
-	}
-
-	encryptedToken, err := encryptToken(signedToken)
-	if err != nil {
-		return "", err
-	}
-
-	return encryptedToken, nil
 }
 
 // Same, but with 15 days expiration time and for reissuing access token
 func GenerateRefreshToken(userID, userUUID, username, role_code string) (string, error) {
-
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	return signClaims(jwt.MapClaims{
 		"sub":       userID,
 		"user_uuid": userUUID,
 		"user_name": username,
 		"role_code": role_code,
 		"exp":       time.Now().Add(time.Hour * 24 * 15).Unix(), // 15 days expiration
 	})
+}
 
-	signedRefreshToken, err := refreshToken.SignedString(jwtSecret)
+// Access token for an impersonation session, the admin rides along in the act claim
+// and no refresh token is ever issued for it
+func GenerateImpersonationToken(userID, userUUID, username, role_code, actorUUID, actorName, sessionUUID string, expiresAt time.Time) (string, error) {
+	return signClaims(jwt.MapClaims{
+		"sub":       userID,
+		"user_uuid": userUUID,
+		"user_name": username,
+		"role_code": role_code,
+		"act": map[string]interface{}{
+			"sub":       actorUUID,
+			"user_name": actorName,
+		},
+		"sid": sessionUUID,
+		"exp": expiresAt.Unix(),
+	})
+}
+
+// Signs with the active key of the ring, or falls back to the legacy HS256 token
+// wrapped in AES-GCM when no signing keys are configured
+func signClaims(claims jwt.MapClaims) (string, error) {
+	if tokenKeys != nil {
+		return tokenKeys.sign(claims)
+	}
+
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
 	if err != nil {
 		return "", err
 	}
 
-	encryptedRefreshToken, err := encryptToken(signedRefreshToken)
+	encryptedToken, err := encryptToken(signedToken)
 	if err != nil {
 		return "", err
 	}
 
-	return encryptedRefreshToken, nil
+	return encryptedToken, nil
 }
 
 // AES encryption for tokens
@@ -139,12 +151,32 @@ func decryptToken(encryptedToken string) (string, error) {
 }
 
 func ValidateToken(encryptedToken string) (jwt.MapClaims, error) {
+	// Plain JWTs come from the key ring, anything else is a legacy encrypted token
+	// that stays valid until it expires
+	if tokenKeys != nil && strings.Count(encryptedToken, ".") == 2 {
+		token, err := jwt.Parse(encryptedToken, tokenKeys.keyFunc)
+		if err != nil {
+			return nil, err
+		}
+		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+			return claims, nil
+		}
+		return nil, errors.New("invalid token")
+	}
+
+	if len(jwtSecret) == 0 {
+		return nil, errors.New("legacy tokens are not accepted")
+	}
+
 	decryptedToken, err := decryptToken(encryptedToken)
 	if err != nil {
 		return nil, err
 	}
 
 	token, err := jwt.Parse(decryptedToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
 		return jwtSecret, nil
 	})
 
@@ -163,7 +195,13 @@ func SaveRefreshToken(userUUID string, refreshToken string) error {
 		return parseErr
 	}
 
-	err := repositories.SaveRefreshToken(*db, entity.RefreshToken{
+	// The connection is shared with the repositories, it is only opened on first use
+	db, err := databases.PostgresConnection()
+	if err != nil {
+		return err
+	}
+
+	err = repositories.SaveRefreshToken(*db, entity.RefreshToken{
 		ID:           ID,
 		UserUUID:     parsedUUID,
 		RefreshToken: refreshToken,
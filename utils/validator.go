@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -26,6 +28,24 @@ func CustomRoleValidator(fl validator.FieldLevel) bool {
 	return regexp.MustCompile(roleRegex).MatchString(value)
 }
 
+func CustomRelationshipValidator(fl validator.FieldLevel) bool {
+	relationshipRegex := `^(mother|father|guardian)$`
+	value := fl.Field().String()
+	return regexp.MustCompile(relationshipRegex).MatchString(value)
+}
+
+func CustomDocumentTypeValidator(fl validator.FieldLevel) bool {
+	documentTypeRegex := `^(license|background_check|health_certificate)$`
+	value := fl.Field().String()
+	return regexp.MustCompile(documentTypeRegex).MatchString(value)
+}
+
+func CustomVehicleDocumentTypeValidator(fl validator.FieldLevel) bool {
+	documentTypeRegex := `^(inspection|registration|insurance)$`
+	value := fl.Field().String()
+	return regexp.MustCompile(documentTypeRegex).MatchString(value)
+}
+
 func CustomGenderValidator(fl validator.FieldLevel) bool {
 	genderRegex := `^(male|female|Male|Female)$`
 	value := fl.Field().String()
@@ -38,6 +58,9 @@ func ValidateStruct(c *fiber.Ctx, v interface{}) error {
 	validate.RegisterValidation("username", CustomUsernameValidator)
 	validate.RegisterValidation("role", CustomRoleValidator)
 	validate.RegisterValidation("gender", CustomGenderValidator)
+	validate.RegisterValidation("relationship", CustomRelationshipValidator)
+	validate.RegisterValidation("document_type", CustomDocumentTypeValidator)
+	validate.RegisterValidation("vehicle_document_type", CustomVehicleDocumentTypeValidator)
 
 	if err := validate.Struct(v); err != nil {
 		for _, err := range err.(validator.ValidationErrors) {
@@ -53,11 +76,38 @@ func ValidateStruct(c *fiber.Ctx, v interface{}) error {
 			case "phone":
 				return fmt.Errorf("the %s field must be a valid phone number", err.Field())
 			case "min":
+				if err.Kind() == reflect.Slice {
+					return fmt.Errorf("the %s field must have at least %s items", err.Field(), err.Param())
+				}
 				return fmt.Errorf("the %s field must be at least %s characters", err.Field(), err.Param())
 			case "max":
+				if err.Kind() == reflect.Slice {
+					return fmt.Errorf("the %s field must have at most %s items", err.Field(), err.Param())
+				}
 				return fmt.Errorf("the %s field must be at most %s characters", err.Field(), err.Param())
+			case "len":
+				return fmt.Errorf("the %s field must be exactly %s characters", err.Field(), err.Param())
+			case "numeric":
+				return fmt.Errorf("the %s field can only contain numbers", err.Field())
+			case "alphanum":
+				return fmt.Errorf("the %s field can only contain letters and numbers", err.Field())
+			case "relationship":
+				return fmt.Errorf("the %s field must be either mother, father, or guardian", err.Field())
+			case "document_type":
+				return fmt.Errorf("the %s field must be either license, background_check, or health_certificate", err.Field())
+			case "vehicle_document_type":
+				return fmt.Errorf("the %s field must be either inspection, registration, or insurance", err.Field())
 			case "role":
 				return fmt.Errorf("the %s field must be either superadmin, schooladmin, driver, or parent", err.Field())
+			case "uuid", "uuid4":
+				return fmt.Errorf("the %s field must be a valid UUID", err.Field())
+			case "oneof":
+				return fmt.Errorf("the %s field must be one of: %s", err.Field(), strings.Join(strings.Fields(err.Param()), ", "))
+			case "url":
+				return fmt.Errorf("the %s field must be a valid URL", err.Field())
+			default:
+				// A tag without its own message still rejects the request
+				return fmt.Errorf("the %s field is invalid", err.Field())
 			}
 		}
 	}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var ErrWebhookHostNotAllowed = errors.New("webhook host resolves to a private or local address")
+
+// Redirects are not followed, an endpoint has to answer at the URL it was registered with. The
+// address is checked again when dialing, a host can resolve differently than when it was registered.
+// Proxies are not used, the check would only see the proxy address.
+var webhookClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if !webhookAddressAllowed(net.ParseIP(host)) {
+					return ErrWebhookHostNotAllowed
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// Carrier-grade NAT space, not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// The part of a response body that is kept in the delivery log
+const webhookResponseLimit = 2048
+
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Resolves the host of an endpoint URL and fails when any of its addresses is loopback, link-local,
+// private, unspecified or multicast. WEBHOOK_ALLOW_PRIVATE_NETWORK=true lifts this for local testing.
+func CheckWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !webhookAddressAllowed(addr.IP) {
+			return ErrWebhookHostNotAllowed
+		}
+	}
+	return nil
+}
+
+func webhookAddressAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if viper.GetBool("WEBHOOK_ALLOW_PRIVATE_NETWORK") {
+		return true
+	}
+
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip) ||
+		ip.Equal(net.IPv4bcast))
+}
+
+// Signs "<timestamp>.<body>" with HMAC-SHA256. Receivers recompute it with their secret and should
+// reject timestamps that are too old to stop replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Posts a signed JSON body and returns the response status and the start of its body. Any status
+// is returned without error, an error means no response was received within WEBHOOK_TIMEOUT.
+func PostWebhook(url, secret string, headers map[string]string, body []byte) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ConfigDuration("WEBHOOK_TIMEOUT", 10*time.Second))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Shuttle-Webhooks/1.0")
+	req.Header.Set("X-Shuttle-Signature", SignWebhook(secret, time.Now().Unix(), body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(res.Body, webhookResponseLimit))
+	if err != nil {
+		return res.StatusCode, "", nil
+	}
+
+	// Postgres text takes neither invalid UTF-8 nor NUL bytes
+	return res.StatusCode, strings.ReplaceAll(strings.ToValidUTF8(string(responseBody), ""), "\x00", ""), nil
+}
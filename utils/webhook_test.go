@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+	}{
+		{name: "json body", secret: "whsec_test", timestamp: 1700000000, body: `{"event_type":"trip.completed"}`},
+		{name: "empty body", secret: "whsec_test", timestamp: 1700000000, body: ""},
+		{name: "other secret", secret: "whsec_other", timestamp: 1700000060, body: `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write([]byte(fmt.Sprintf("%d.%s", tt.timestamp, tt.body)))
+			want := fmt.Sprintf("t=%d,v1=%s", tt.timestamp, hex.EncodeToString(mac.Sum(nil)))
+
+			if got := SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != want {
+				t.Errorf("SignWebhook() = %s, want %s", got, want)
+			}
+		})
+	}
+
+	if SignWebhook("a", 1, []byte("x")) == SignWebhook("a", 2, []byte("x")) {
+		t.Error("the timestamp is not part of the signature")
+	}
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{address: "93.184.216.34", want: true},
+		{address: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{address: "127.0.0.1", want: false},
+		{address: "::1", want: false},
+		{address: "10.1.2.3", want: false},
+		{address: "172.16.0.1", want: false},
+		{address: "192.168.1.1", want: false},
+		{address: "169.254.169.254", want: false},
+		{address: "fe80::1", want: false},
+		{address: "fd00::1", want: false},
+		{address: "0.0.0.0", want: false},
+		{address: "::", want: false},
+		{address: "100.64.0.1", want: false},
+		{address: "224.0.0.1", want: false},
+		{address: "255.255.255.255", want: false},
+		{address: "::ffff:127.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := webhookAddressAllowed(net.ParseIP(tt.address)); got != tt.want {
+				t.Errorf("webhookAddressAllowed(%s) = %v, want %v", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostWebhookDialCheck(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Shuttle-Signature")
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		wantStatus   int
+		wantBlocked  bool
+	}{
+		{name: "loopback is refused when dialing", wantBlocked: true},
+		{name: "loopback with WEBHOOK_ALLOW_PRIVATE_NETWORK", allowPrivate: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("WEBHOOK_ALLOW_PRIVATE_NETWORK", tt.allowPrivate)
+			defer viper.Set("WEBHOOK_ALLOW_PRIVATE_NETWORK", nil)
+			signature = ""
+
+			status, body, err := PostWebhook(server.URL, "whsec_test", nil, []byte(`{}`))
+			if tt.wantBlocked {
+				if !errors.Is(err, ErrWebhookHostNotAllowed) {
+					t.Fatalf("PostWebhook() error = %v, want %v", err, ErrWebhookHostNotAllowed)
+				}
+				if signature != "" {
+					t.Error("the request reached the server")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("PostWebhook() error = %v", err)
+			}
+			if status != tt.wantStatus || body != "ok" {
+				t.Errorf("PostWebhook() = %d %q, want %d %q", status, body, tt.wantStatus, "ok")
+			}
+			if !strings.HasPrefix(signature, "t=") || !strings.Contains(signature, ",v1=") {
+				t.Errorf("X-Shuttle-Signature = %q", signature)
+			}
+		})
+	}
+}
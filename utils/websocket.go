@@ -30,6 +30,7 @@ func NewWebSocketService(userRepository repositories.UserRepositoryInterface, au
 var (
 	activeConnections = make(map[string]*websocket.Conn) // Save active WebSocket connections
 	mutex             = &sync.Mutex{}                    // Ensure atomic operations
+	writeLocks        sync.Map                           // One writer at a time per connection
 )
 
 func AddConnection(ID string, conn *websocket.Conn) {
@@ -51,6 +52,25 @@ func GetConnection(ID string) (*websocket.Conn, bool) {
 	return conn, exists
 }
 
+// Writes to the user's connection, false when the user is not connected
+func SendToUser(ID string, message []byte) (bool, error) {
+	conn, exists := GetConnection(ID)
+	if !exists {
+		return false, nil
+	}
+
+	return true, writeMessage(conn, websocket.TextMessage, message)
+}
+
+// Pushes from other goroutines can happen while the connection answers a location update
+func writeMessage(conn *websocket.Conn, messageType int, message []byte) error {
+	lock, _ := writeLocks.LoadOrStore(conn, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	return conn.WriteMessage(messageType, message)
+}
+
 // Handle WebSocket connection
 func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 	UUID := c.Params("id")
@@ -75,7 +95,7 @@ func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 		logger.LogError(err, "Websocket Error Updating User Status", nil)
 	}
 
-	err = c.WriteMessage(websocket.TextMessage, []byte("Connected to websocket"))
+	err = writeMessage(c, websocket.TextMessage, []byte("Connected to websocket"))
 	if err != nil {
 		logger.LogError(err, "Websocket Error Writing Message", nil)
 		return
@@ -117,7 +137,7 @@ func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 			break
 		}
 
-		err = c.WriteMessage(mt, responseMsg)
+		err = writeMessage(c, mt, responseMsg)
 		if err != nil {
 			logger.LogError(err, "Websocket Error Writing Message", nil)
 			break
@@ -126,6 +146,7 @@ func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 
 	// Disconnect user
 	RemoveConnection(UUID)
+	writeLocks.Delete(c)
 	logger.LogInfo("Websocket Connection Closed", map[string]interface{}{"ID": UUID})
 
 	err = s.authRepository.UpdateUserStatus(UUID, "offline", time.Now())